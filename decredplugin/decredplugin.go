@@ -1,6 +1,11 @@
 package decredplugin
 
-import "encoding/json"
+import (
+	"crypto/sha256"
+	"encoding/json"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+)
 
 // Plugin settings, kinda doesn;t go here but for now it is fine
 const (
@@ -8,7 +13,9 @@ const (
 	ID                       = "decred"
 	CmdAuthorizeVote         = "authorizevote"
 	CmdStartVote             = "startvote"
+	CmdStartVoteRunoff       = "startvoterunoff"
 	CmdVoteDetails           = "votedetails"
+	CmdVoteRunoffDetails     = "voterunoffdetails"
 	CmdVoteSummary           = "votesummary"
 	CmdLoadVoteResults       = "loadvoteresults"
 	CmdBallot                = "ballot"
@@ -24,16 +31,31 @@ const (
 	CmdProposalCommentsLikes = "proposalcommentslikes"
 	CmdInventory             = "inventory"
 	CmdTokenInventory        = "tokeninventory"
+	CmdSearch                = "search"
+	CmdVoteBundle            = "votebundle"
+	CmdScheduleVote          = "schedulevote"
+	CmdScheduledVotes        = "scheduledvotes"
 	MDStreamAuthorizeVote    = 13 // Vote authorization by proposal author
 	MDStreamVoteBits         = 14 // Vote bits and mask
 	MDStreamVoteSnapshot     = 15 // Vote tickets and start/end parameters
+	MDStreamVoteRunoff       = 16 // Runoff vote between RFP submissions
+	MDStreamVoteSchedule     = 17 // Scheduled vote start height
+
+	IndexFile = "index.md" // Record file that holds the proposal
 
 	VoteDurationMin = 2016 // Minimum vote duration (in blocks)
 	VoteDurationMax = 4032 // Maximum vote duration (in blocks)
 
+	ScheduleVoteMaxDelay = 12 // Blocks a scheduled vote may start late
+
 	// Authorize vote actions
 	AuthVoteActionAuthorize = "authorize" // Authorize a proposal vote
 	AuthVoteActionRevoke    = "revoke"    // Revoke a proposal vote authorization
+
+	// Schedule vote actions
+	ScheduleVoteActionSchedule = "schedule" // Schedule a proposal vote
+	ScheduleVoteActionCancel   = "cancel"   // Cancel a scheduled proposal vote
+	ScheduleVoteActionFail     = "fail"     // Fail a scheduled vote that started too late
 )
 
 // CastVote is a signed vote.
@@ -119,9 +141,26 @@ type VoteOption struct {
 	Bits        uint64 `json:"bits"`        // Bits used for this option
 }
 
+// VoteT represents the type of a vote.  The vote type determines how the
+// vote options are validated and how the cast votes are tallied.
+type VoteT int
+
+const (
+	// Vote types
+	VoteTypeInvalid        VoteT = 0 // Not set, treated as approval
+	VoteTypeApproval       VoteT = 1 // Approve or reject the proposal
+	VoteTypeMultipleChoice VoteT = 2 // Choose a single option
+	VoteTypeRankedChoice   VoteT = 3 // Rank the options by preference
+
+	// Approval vote option IDs
+	VoteOptionIDApprove = "yes"
+	VoteOptionIDReject  = "no"
+)
+
 // Vote represents the vote options for vote that is identified by its token.
 type Vote struct {
 	Token            string       `json:"token"`            // Token that identifies vote
+	Type             VoteT        `json:"type"`             // Vote type
 	Mask             uint64       `json:"mask"`             // Valid votebits
 	Duration         uint32       `json:"duration"`         // Duration in blocks
 	QuorumPercentage uint32       `json:"quorumpercentage"` // Percent of eligible votes required for quorum
@@ -207,7 +246,9 @@ func DecodeAuthorizeVoteReply(payload []byte) (*AuthorizeVoteReply, error) {
 
 // StartVote instructs the plugin to commence voting on a proposal with the
 // provided vote bits.
-const VersionStartVote = 1
+//
+// Version 2 adds the vote type.  Start votes of version 1 are approval votes.
+const VersionStartVote = 2
 
 type StartVote struct {
 	// decred plugin only data
@@ -266,6 +307,257 @@ func DecodeStartVoteReply(payload []byte) (*StartVoteReply, error) {
 	return &v, nil
 }
 
+// StartVoteRunoff instructs the plugin to commence a runoff vote between the
+// submissions of an RFP.  The votes of all submissions share the same start
+// block, end block and ticket snapshot.  The runoff is recorded on the RFP
+// record that is identified by Token.
+const VersionStartVoteRunoff = 1
+
+type StartVoteRunoff struct {
+	Version    uint        `json:"version"`    // Version of this structure
+	Token      string      `json:"token"`      // RFP censorship token
+	StartVotes []StartVote `json:"startvotes"` // Start votes of the submissions
+}
+
+// EncodeStartVoteRunoff encodes StartVoteRunoff into a JSON byte slice.
+func EncodeStartVoteRunoff(v StartVoteRunoff) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DecodeStartVoteRunoff decodes a JSON byte slice into a StartVoteRunoff.
+func DecodeStartVoteRunoff(payload []byte) (*StartVoteRunoff, error) {
+	var v StartVoteRunoff
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// StartVoteRunoffReply is the reply to StartVoteRunoff.  The StartVoteReply
+// is shared by all of the runoff submissions.
+type StartVoteRunoffReply struct {
+	StartVoteReply StartVoteReply `json:"startvotereply"` // Shared snapshot
+}
+
+// EncodeStartVoteRunoffReply encodes StartVoteRunoffReply into a JSON byte
+// slice.
+func EncodeStartVoteRunoffReply(v StartVoteRunoffReply) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DecodeStartVoteRunoffReply decodes a JSON byte slice into a
+// StartVoteRunoffReply.
+func DecodeStartVoteRunoffReply(payload []byte) (*StartVoteRunoffReply, error) {
+	var v StartVoteRunoffReply
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// ScheduleVote is an MDStream that is used to schedule the start of a
+// proposal vote at a future block height.  An admin schedules the vote by
+// sending the signed StartVote along with the start height.  The vote is
+// started by politeiawww once the start height has been reached.  The ticket
+// snapshot is taken at the start height minus the ticket maturity so the
+// snapshot height is known in advance.  The proposal author can cancel the
+// scheduled vote before the start height by setting the Action field to
+// cancel.  The StartVote is empty when the schedule is cancelled.
+//
+// A scheduled vote can not be started more than ScheduleVoteMaxDelay blocks
+// after its start height because the voting period would be shortened.
+// politeiawww fails such a schedule by setting the Action field to fail.  The
+// fail action is not signed by a user; the decred plugin only accepts it once
+// the start height plus ScheduleVoteMaxDelay has been passed.  A failed vote
+// can be rescheduled or started by an admin.
+const VersionScheduleVote = 1
+
+type ScheduleVote struct {
+	// Generated by decredplugin
+	Version   uint   `json:"version"`   // Version of this structure
+	Receipt   string `json:"receipt"`   // Server signature of client signature
+	Timestamp int64  `json:"timestamp"` // Received UNIX timestamp
+
+	// Generated by client
+	Action      string    `json:"action"`      // Schedule or cancel
+	Token       string    `json:"token"`       // Proposal censorship token
+	StartHeight uint32    `json:"startheight"` // Block height of vote start
+	StartVote   StartVote `json:"startvote"`   // Vote to start
+	Signature   string    `json:"signature"`   // Signature of token+startheight+action
+	PublicKey   string    `json:"publickey"`   // Pubkey used for signature
+}
+
+// EncodeScheduleVote encodes ScheduleVote into a JSON byte slice.
+func EncodeScheduleVote(sv ScheduleVote) ([]byte, error) {
+	return json.Marshal(sv)
+}
+
+// DecodeScheduleVote decodes a JSON byte slice into a ScheduleVote.
+func DecodeScheduleVote(payload []byte) (*ScheduleVote, error) {
+	var sv ScheduleVote
+	err := json.Unmarshal(payload, &sv)
+	if err != nil {
+		return nil, err
+	}
+	return &sv, nil
+}
+
+// ScheduleVoteReply returns the schedule vote action that was executed and
+// the receipt for the action.  The receipt is the server side signature of
+// ScheduleVote.Signature.
+type ScheduleVoteReply struct {
+	Action         string `json:"action"`         // Schedule or cancel
+	StartHeight    uint32 `json:"startheight"`    // Block height of vote start
+	SnapshotHeight uint32 `json:"snapshotheight"` // Block height of ticket snapshot
+	Receipt        string `json:"receipt"`        // Server signature of client signature
+	Timestamp      int64  `json:"timestamp"`      // Received UNIX timestamp
+}
+
+// EncodeScheduleVoteReply encodes ScheduleVoteReply into a JSON byte slice.
+func EncodeScheduleVoteReply(svr ScheduleVoteReply) ([]byte, error) {
+	return json.Marshal(svr)
+}
+
+// DecodeScheduleVoteReply decodes a JSON byte slice into a
+// ScheduleVoteReply.
+func DecodeScheduleVoteReply(payload []byte) (*ScheduleVoteReply, error) {
+	var svr ScheduleVoteReply
+	err := json.Unmarshal(payload, &svr)
+	if err != nil {
+		return nil, err
+	}
+	return &svr, nil
+}
+
+// ScheduledVotes requests the votes that are scheduled and have not been
+// started yet.  This command is only implemented by the cache.
+type ScheduledVotes struct{}
+
+// EncodeScheduledVotes encodes ScheduledVotes into a JSON byte slice.
+func EncodeScheduledVotes(sv ScheduledVotes) ([]byte, error) {
+	return json.Marshal(sv)
+}
+
+// DecodeScheduledVotes decodes a JSON byte slice into a ScheduledVotes.
+func DecodeScheduledVotes(payload []byte) (*ScheduledVotes, error) {
+	var sv ScheduledVotes
+
+	err := json.Unmarshal(payload, &sv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sv, nil
+}
+
+// ScheduledVotesReply is the reply to ScheduledVotes.
+type ScheduledVotesReply struct {
+	ScheduleVotes []ScheduleVote `json:"schedulevotes"` // Scheduled votes
+}
+
+// EncodeScheduledVotesReply encodes ScheduledVotesReply into a JSON byte
+// slice.
+func EncodeScheduledVotesReply(svr ScheduledVotesReply) ([]byte, error) {
+	return json.Marshal(svr)
+}
+
+// DecodeScheduledVotesReply decodes a JSON byte slice into a
+// ScheduledVotesReply.
+func DecodeScheduledVotesReply(payload []byte) (*ScheduledVotesReply, error) {
+	var svr ScheduledVotesReply
+
+	err := json.Unmarshal(payload, &svr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svr, nil
+}
+
+// VoteRunoff is an MDStream that is saved on the RFP record when a runoff
+// vote is started.  It lists the submissions that are part of the runoff.
+const VersionVoteRunoff = 1
+
+type VoteRunoff struct {
+	Version          uint     `json:"version"`          // Version of this structure
+	Token            string   `json:"token"`            // RFP censorship token
+	Tokens           []string `json:"tokens"`           // Submission censorship tokens
+	StartBlockHeight string   `json:"startblockheight"` // Block height
+	StartBlockHash   string   `json:"startblockhash"`   // Block hash
+	EndHeight        string   `json:"endheight"`        // Height of vote end
+}
+
+// EncodeVoteRunoff encodes VoteRunoff into a JSON byte slice.
+func EncodeVoteRunoff(v VoteRunoff) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DecodeVoteRunoff decodes a JSON byte slice into a VoteRunoff.
+func DecodeVoteRunoff(payload []byte) (*VoteRunoff, error) {
+	var v VoteRunoff
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// VoteRunoffDetails is used to retrieve the runoff vote of an RFP.
+type VoteRunoffDetails struct {
+	Token string `json:"token"` // RFP censorship token
+}
+
+// EncodeVoteRunoffDetails encodes VoteRunoffDetails into a JSON byte slice.
+func EncodeVoteRunoffDetails(vrd VoteRunoffDetails) ([]byte, error) {
+	return json.Marshal(vrd)
+}
+
+// DecodeVoteRunoffDetails decodes a JSON byte slice into a
+// VoteRunoffDetails.
+func DecodeVoteRunoffDetails(payload []byte) (*VoteRunoffDetails, error) {
+	var vrd VoteRunoffDetails
+
+	err := json.Unmarshal(payload, &vrd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vrd, nil
+}
+
+// VoteRunoffDetailsReply is the reply to VoteRunoffDetails.  VoteRunoff is
+// empty when no runoff vote has been started for the RFP.
+type VoteRunoffDetailsReply struct {
+	VoteRunoff VoteRunoff `json:"voterunoff"` // Runoff vote
+}
+
+// EncodeVoteRunoffDetailsReply encodes VoteRunoffDetailsReply into a JSON
+// byte slice.
+func EncodeVoteRunoffDetailsReply(vrdr VoteRunoffDetailsReply) ([]byte, error) {
+	return json.Marshal(vrdr)
+}
+
+// DecodeVoteRunoffDetailsReply decodes a JSON byte slice into a
+// VoteRunoffDetailsReply.
+func DecodeVoteRunoffDetailsReply(payload []byte) (*VoteRunoffDetailsReply, error) {
+	var vrdr VoteRunoffDetailsReply
+
+	err := json.Unmarshal(payload, &vrdr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vrdr, nil
+}
+
 // VoteDetails is used to retrieve the voting period details for a record.
 type VoteDetails struct {
 	Token string `json:"token"` // Censorship token
@@ -288,9 +580,11 @@ func DecodeVoteDetails(payload []byte) (*VoteDetails, error) {
 	return &vd, nil
 }
 
-// VoteDetailsReply is the reply to VoteDetails.
+// VoteDetailsReply is the reply to VoteDetails.  ScheduleVote is empty when
+// the vote has never been scheduled.
 type VoteDetailsReply struct {
 	AuthorizeVote  AuthorizeVote  `json:"authorizevote"`  // Vote authorization
+	ScheduleVote   ScheduleVote   `json:"schedulevote"`   // Vote schedule
 	StartVote      StartVote      `json:"startvote"`      // Vote ballot
 	StartVoteReply StartVoteReply `json:"startvotereply"` // Start vote snapshot
 }
@@ -355,6 +649,94 @@ func DecodeVoteResultsReply(payload []byte) (*VoteResultsReply, error) {
 	return &v, nil
 }
 
+// VoteBundle requests the signed vote bundle of a proposal.
+type VoteBundle struct {
+	Token string `json:"token"` // Censorship token
+}
+
+// EncodeVoteBundle encodes VoteBundle into a JSON byte slice.
+func EncodeVoteBundle(v VoteBundle) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DecodeVoteBundle decodes a JSON byte slice into a VoteBundle.
+func DecodeVoteBundle(payload []byte) (*VoteBundle, error) {
+	var v VoteBundle
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// VoteBundleAuthorizeVote is a vote authorization along with the record
+// version that it was signed for.  The author signs Token+RecordVersion+Action.
+type VoteBundleAuthorizeVote struct {
+	AuthorizeVote AuthorizeVote `json:"authorizevote"` // Vote authorization
+	RecordVersion string        `json:"recordversion"` // Record version
+}
+
+// VoteBundleCastVote is a cast vote along with its server receipt and the
+// largest commitment address of the ticket at the time of the export.
+type VoteBundleCastVote struct {
+	CastVote CastVote `json:"castvote"` // Client side vote
+	Receipt  string   `json:"receipt"`  // Signature of CastVote.Signature
+	Address  string   `json:"address"`  // Largest commitment address
+}
+
+// VoteBundleReply is a self contained export of a proposal vote that allows
+// the vote to be tallied independently.  It contains the vote authorizations
+// of all record versions, the signed start vote, the ticket snapshot and all
+// cast votes in the order in which they were received.  The bundle is signed
+// by the politeiad identity and contains the politeiad identity history so
+// that the receipts of rotated keys can be verified from a single trusted key.
+const VersionVoteBundleReply = 2
+
+type VoteBundleReply struct {
+	Version        uint                      `json:"version"`        // Version of this structure
+	Timestamp      int64                     `json:"timestamp"`      // Export UNIX timestamp
+	Token          string                    `json:"token"`          // Censorship token
+	AuthorizeVotes []VoteBundleAuthorizeVote `json:"authorizevotes"` // Vote authorizations
+	StartVote      StartVote                 `json:"startvote"`      // Signed start vote
+	StartVoteReply StartVoteReply            `json:"startvotereply"` // Ticket snapshot
+	CastVotes      []VoteBundleCastVote      `json:"castvotes"`      // All cast votes
+	Identity       []pd.IdentityKey          `json:"identity"`       // Server identity history
+	PublicKey      string                    `json:"publickey"`      // Server public key
+	Signature      string                    `json:"signature"`      // Signature of Digest
+}
+
+// Digest returns the SHA256 digest of the JSON encoded vote bundle without its
+// signature.  This is the digest that is signed by the server.
+func (v *VoteBundleReply) Digest() ([]byte, error) {
+	c := *v
+	c.Signature = ""
+	j, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	d := sha256.Sum256(j)
+	return d[:], nil
+}
+
+// EncodeVoteBundleReply encodes VoteBundleReply into a JSON byte slice.
+func EncodeVoteBundleReply(v VoteBundleReply) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DecodeVoteBundleReply decodes a JSON byte slice into a VoteBundleReply.
+func DecodeVoteBundleReply(payload []byte) (*VoteBundleReply, error) {
+	var v VoteBundleReply
+
+	err := json.Unmarshal(payload, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
 // VoteSummary requests a summary of a proposal vote. This includes certain
 // voting period parameters and a summary of the vote results.
 type VoteSummary struct {
@@ -387,15 +769,34 @@ type VoteOptionResult struct {
 	Votes       uint64 `json:"votes"`       // Number of votes cast for this option
 }
 
+// VoteRound describes a single counting round of a ranked choice vote.  Each
+// ballot counts towards its most preferred option that has not been
+// eliminated yet.
+type VoteRound struct {
+	Results    []VoteOptionResult `json:"results"`    // Votes per remaining option
+	Eliminated []string           `json:"eliminated"` // Options eliminated after the round
+}
+
 // VoteSummaryReply is the reply to the VoteSummary command and returns certain
-// voting period parameters as well as a summary of the vote results.
+// voting period parameters as well as a summary of the vote results.  The
+// results are tallied according to the vote type.  For ranked choice votes
+// the results contain the first preferences and the rounds contain the
+// instant runoff count.  ScheduledHeight is set when the vote has been
+// scheduled but has not been started yet.
 type VoteSummaryReply struct {
 	Authorized          bool               `json:"authorized"`          // Vote is authorized
+	ScheduledHeight     uint32             `json:"scheduledheight"`     // Scheduled start height
+	Type                VoteT              `json:"type"`                // Vote type
 	EndHeight           string             `json:"endheight"`           // End block height
 	EligibleTicketCount int                `json:"eligibleticketcount"` // Number of eligible tickets
 	QuorumPercentage    uint32             `json:"quorumpercentage"`    // Percent of eligible votes required for quorum
 	PassPercentage      uint32             `json:"passpercentage"`      // Percent of total votes required to pass
 	Results             []VoteOptionResult `json:"results"`             // Vote results
+	TotalVotes          uint64             `json:"totalvotes"`          // Number of valid cast votes
+	QuorumMet           bool               `json:"quorummet"`           // Quorum has been met
+	Winner              string             `json:"winner,omitempty"`    // ID of the winning option
+	Approved            bool               `json:"approved"`            // Quorum met and a winner was found
+	Rounds              []VoteRound        `json:"rounds,omitempty"`    // Ranked choice rounds
 }
 
 // EncodeVoteSummaryReply encodes VoteSummary into a JSON byte slice.
@@ -878,6 +1279,8 @@ type InventoryReply struct {
 	AuthorizeVoteReplies []AuthorizeVoteReply `json:"authorizevotereplies"` // Authorize vote replies
 	StartVoteTuples      []StartVoteTuple     `json:"startvotetuples"`      // Start vote tuples
 	CastVotes            []CastVote           `json:"castvotes"`            // Cast votes
+	VoteRunoffs          []VoteRunoff         `json:"voterunoffs"`          // Runoff votes
+	ScheduleVotes        []ScheduleVote       `json:"schedulevotes"`        // Vote schedules
 }
 
 // EncodeInventoryReply encodes a InventoryReply into a JSON byte slice.
@@ -963,6 +1366,73 @@ func DecodeTokenInventoryReply(payload []byte) (*TokenInventoryReply, error) {
 	return &itr, nil
 }
 
+// Search requests the vetted records whose name, index file or comments match
+// the search query.  Only the most recent version of public and archived
+// records is searched and censored comments are never matched.
+//
+// If After is specified, only the results that are ordered after the provided
+// record are returned.  The record does not have to match the query, which
+// allows paging through results that changed in between requests.
+//
+// Statuses limits the search to the records with one of the provided
+// politeiad record statuses.  Limit is the maximum number of results that are
+// returned.  Neither is applied when empty.
+type Search struct {
+	Query    string `json:"query"`    // Search query
+	After    string `json:"after"`    // Censorship token of the last result
+	Statuses []int  `json:"statuses"` // Filter by record status
+	Limit    int    `json:"limit"`    // Maximum number of results
+}
+
+// EncodeSearch encodes a Search into a JSON byte slice.
+func EncodeSearch(s Search) ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// DecodeSearch decodes a JSON byte slice into a Search.
+func DecodeSearch(payload []byte) (*Search, error) {
+	var s Search
+
+	err := json.Unmarshal(payload, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// SearchResult describes which parts of a record matched the search query.
+type SearchResult struct {
+	Token      string   `json:"token"`      // Censorship token
+	Timestamp  int64    `json:"timestamp"`  // Record timestamp
+	Name       bool     `json:"name"`       // Record name matched
+	Index      bool     `json:"index"`      // Index file matched
+	CommentIDs []string `json:"commentids"` // IDs of the matching comments
+}
+
+// SearchReply is the reply to the Search command.  The results are sorted by
+// record timestamp in descending order and then by token.
+type SearchReply struct {
+	Results []SearchResult `json:"results"`
+}
+
+// EncodeSearchReply encodes a SearchReply into a JSON byte slice.
+func EncodeSearchReply(sr SearchReply) ([]byte, error) {
+	return json.Marshal(sr)
+}
+
+// DecodeSearchReply decodes a JSON byte slice into a SearchReply.
+func DecodeSearchReply(payload []byte) (*SearchReply, error) {
+	var sr SearchReply
+
+	err := json.Unmarshal(payload, &sr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sr, nil
+}
+
 // LoadVoteResults creates a vote results entry in the cache for any proposals
 // that have finsished voting but have not yet been added to the lazy loaded
 // vote results table.
@@ -0,0 +1,361 @@
+package decredplugin
+
+import "fmt"
+
+const (
+	// RankedChoiceBits is the number of vote bits that are used for a
+	// single preference of a ranked choice vote.  A ranked choice vote
+	// bit contains the bits of the ranked options ordered by preference,
+	// starting with the most preferred option in the least significant
+	// bits.  The preferences end at the first zero preference.
+	RankedChoiceBits = 4
+
+	// RankedChoiceMask is the mask of a single ranked choice preference.
+	RankedChoiceMask = 1<<RankedChoiceBits - 1
+
+	// RankedChoiceMaxOptions is the maximum number of options of a
+	// ranked choice vote.
+	RankedChoiceMaxOptions = RankedChoiceMask
+)
+
+// VoteType returns the type of the vote.  Votes that were started before
+// vote types were introduced do not set a type and are approval votes.
+func VoteType(v Vote) VoteT {
+	if v.Type == VoteTypeInvalid {
+		return VoteTypeApproval
+	}
+	return v.Type
+}
+
+// RankedChoicePreferences returns the option bits of a ranked choice vote
+// bit ordered by preference.  An error is returned when a zero preference is
+// followed by more preferences.
+func RankedChoicePreferences(bit uint64) ([]uint64, error) {
+	prefs := make([]uint64, 0, 64/RankedChoiceBits)
+	for bit != 0 {
+		p := bit & RankedChoiceMask
+		if p == 0 {
+			return nil, fmt.Errorf("empty preference %v", len(prefs))
+		}
+		prefs = append(prefs, p)
+		bit >>= RankedChoiceBits
+	}
+	return prefs, nil
+}
+
+// RankedChoiceVoteBit returns the ranked choice vote bit of the passed in
+// option bits, ordered by preference.
+func RankedChoiceVoteBit(prefs []uint64) (uint64, error) {
+	if len(prefs) == 0 || len(prefs) > 64/RankedChoiceBits {
+		return 0, fmt.Errorf("invalid number of preferences %v",
+			len(prefs))
+	}
+	var bit uint64
+	for i := len(prefs) - 1; i >= 0; i-- {
+		if prefs[i] == 0 || prefs[i] > RankedChoiceMask {
+			return 0, fmt.Errorf("invalid preference bits 0x%x",
+				prefs[i])
+		}
+		bit = bit<<RankedChoiceBits | prefs[i]
+	}
+	return bit, nil
+}
+
+// ValidateVote ensures that the vote options and parameters are valid for
+// the vote type.
+func ValidateVote(v Vote) error {
+	if v.QuorumPercentage > 100 || v.PassPercentage > 100 {
+		return fmt.Errorf("invalid percentages quorum %v pass %v",
+			v.QuorumPercentage, v.PassPercentage)
+	}
+	if v.QuorumPercentage == 0 {
+		return fmt.Errorf("quorum percentage must not be zero")
+	}
+	if len(v.Options) < 2 {
+		return fmt.Errorf("not enough vote options %v", len(v.Options))
+	}
+
+	ids := make(map[string]struct{}, len(v.Options))
+	bits := make(map[uint64]struct{}, len(v.Options))
+	for _, o := range v.Options {
+		if o.Bits == 0 || v.Mask&o.Bits != o.Bits {
+			return fmt.Errorf("invalid mask 0x%x bit 0x%x",
+				v.Mask, o.Bits)
+		}
+		if _, ok := ids[o.Id]; ok {
+			return fmt.Errorf("duplicate option id %v", o.Id)
+		}
+		if _, ok := bits[o.Bits]; ok {
+			return fmt.Errorf("duplicate option bits 0x%x", o.Bits)
+		}
+		ids[o.Id] = struct{}{}
+		bits[o.Bits] = struct{}{}
+	}
+
+	switch VoteType(v) {
+	case VoteTypeApproval:
+		_, approve := ids[VoteOptionIDApprove]
+		_, reject := ids[VoteOptionIDReject]
+		if len(v.Options) != 2 || !approve || !reject {
+			return fmt.Errorf("approval vote options must be %v and %v",
+				VoteOptionIDApprove, VoteOptionIDReject)
+		}
+	case VoteTypeMultipleChoice:
+	case VoteTypeRankedChoice:
+		if len(v.Options) > RankedChoiceMaxOptions {
+			return fmt.Errorf("too many ranked choice options %v",
+				len(v.Options))
+		}
+		for _, o := range v.Options {
+			if o.Bits > RankedChoiceMask {
+				return fmt.Errorf("invalid ranked choice bits 0x%x",
+					o.Bits)
+			}
+		}
+		// The winner must receive a majority of a round
+		if v.PassPercentage <= 50 {
+			return fmt.Errorf("ranked choice pass percentage must "+
+				"be greater than 50: %v", v.PassPercentage)
+		}
+	default:
+		return fmt.Errorf("invalid vote type %v", v.Type)
+	}
+
+	return nil
+}
+
+// ValidateVoteBit ensures that bit is a valid vote bit for the vote.  An
+// approval or multiple choice vote bit selects a single option.  A ranked
+// choice vote bit ranks one or more distinct options.
+func ValidateVoteBit(v Vote, bit uint64) error {
+	if len(v.Options) == 0 {
+		return fmt.Errorf("vote corrupt")
+	}
+	if bit == 0 {
+		return fmt.Errorf("invalid bit 0x%x", bit)
+	}
+
+	options := make(map[uint64]struct{}, len(v.Options))
+	for _, o := range v.Options {
+		options[o.Bits] = struct{}{}
+	}
+
+	if VoteType(v) != VoteTypeRankedChoice {
+		if v.Mask&bit != bit {
+			return fmt.Errorf("invalid mask 0x%x bit 0x%x",
+				v.Mask, bit)
+		}
+		if _, ok := options[bit]; !ok {
+			return fmt.Errorf("bit not found 0x%x", bit)
+		}
+		return nil
+	}
+
+	prefs, err := RankedChoicePreferences(bit)
+	if err != nil {
+		return fmt.Errorf("invalid bit 0x%x: %v", bit, err)
+	}
+	ranked := make(map[uint64]struct{}, len(prefs))
+	for _, p := range prefs {
+		if _, ok := options[p]; !ok {
+			return fmt.Errorf("bit not found 0x%x", p)
+		}
+		if _, ok := ranked[p]; ok {
+			return fmt.Errorf("duplicate preference 0x%x", p)
+		}
+		ranked[p] = struct{}{}
+	}
+
+	return nil
+}
+
+// VoteTally contains the results of a vote tallied according to its type.
+type VoteTally struct {
+	Type       VoteT              // Vote type
+	Results    []VoteOptionResult // Votes per option
+	TotalVotes uint64             // Number of valid cast votes
+	QuorumMet  bool               // Quorum has been met
+	Winner     string             // ID of the winning option
+	Approved   bool               // Quorum met and a winner was found
+	Rounds     []VoteRound        // Ranked choice rounds
+}
+
+// optionResults returns the number of votes of each option of the vote in
+// the order of the vote options.  Options that are not in remaining are
+// skipped when remaining is not nil.
+func optionResults(v Vote, votes map[uint64]uint64, remaining map[uint64]bool) []VoteOptionResult {
+	results := make([]VoteOptionResult, 0, len(v.Options))
+	for _, o := range v.Options {
+		if remaining != nil && !remaining[o.Bits] {
+			continue
+		}
+		results = append(results, VoteOptionResult{
+			ID:          o.Id,
+			Description: o.Description,
+			Bits:        o.Bits,
+			Votes:       votes[o.Bits],
+		})
+	}
+	return results
+}
+
+// plurality returns the option with the most votes.  False is returned when
+// there are no results or when the most votes are tied.
+func plurality(results []VoteOptionResult) (VoteOptionResult, bool) {
+	var (
+		best VoteOptionResult
+		tied bool
+	)
+	for i, r := range results {
+		switch {
+		case i == 0 || r.Votes > best.Votes:
+			best = r
+			tied = false
+		case r.Votes == best.Votes:
+			tied = true
+		}
+	}
+	return best, len(results) > 0 && !tied
+}
+
+// tallyRankedChoice counts the ballots of a ranked choice vote using instant
+// runoff rounds.  Each round a ballot counts towards its most preferred
+// remaining option.  An option wins once it is the only option with the most
+// votes and it received the pass percentage of the counted ballots of the
+// round.  Otherwise the options with the fewest votes are eliminated.  There
+// is no winner when all remaining options are tied.
+func tallyRankedChoice(v Vote, ballots map[uint64]uint64) ([]VoteRound, string) {
+	// Decode the ballot preferences once
+	type ballot struct {
+		prefs []uint64
+		count uint64
+	}
+	bs := make([]ballot, 0, len(ballots))
+	for bit, count := range ballots {
+		prefs, err := RankedChoicePreferences(bit)
+		if err != nil {
+			continue
+		}
+		bs = append(bs, ballot{prefs, count})
+	}
+
+	remaining := make(map[uint64]bool, len(v.Options))
+	for _, o := range v.Options {
+		remaining[o.Bits] = true
+	}
+
+	rounds := make([]VoteRound, 0, len(v.Options))
+	for len(remaining) > 0 {
+		var counted uint64
+		votes := make(map[uint64]uint64, len(remaining))
+		for _, b := range bs {
+			for _, p := range b.prefs {
+				if remaining[p] {
+					votes[p] += b.count
+					counted += b.count
+					break
+				}
+			}
+		}
+
+		round := VoteRound{
+			Results:    optionResults(v, votes, remaining),
+			Eliminated: []string{},
+		}
+		if counted == 0 {
+			rounds = append(rounds, round)
+			return rounds, ""
+		}
+
+		// Compare exactly so that a truncated pass threshold can not
+		// hand the win to an option without a majority.
+		best, ok := plurality(round.Results)
+		if ok && best.Votes*100 >= uint64(v.PassPercentage)*counted {
+			rounds = append(rounds, round)
+			return rounds, best.ID
+		}
+
+		// Eliminate the options with the fewest votes
+		fewest := round.Results[0].Votes
+		for _, r := range round.Results {
+			if r.Votes < fewest {
+				fewest = r.Votes
+			}
+		}
+		for _, r := range round.Results {
+			if r.Votes == fewest {
+				round.Eliminated = append(round.Eliminated, r.ID)
+			}
+		}
+		rounds = append(rounds, round)
+		if len(round.Eliminated) == len(remaining) {
+			// All remaining options are tied
+			return rounds, ""
+		}
+		for _, r := range round.Results {
+			if r.Votes == fewest {
+				delete(remaining, r.Bits)
+			}
+		}
+	}
+
+	return rounds, ""
+}
+
+// TallyVote tallies the cast votes of a vote according to its type.  The
+// ballots map the vote bits to the number of cast votes for that vote bit and
+// eligible is the number of eligible tickets of the vote.
+//
+// Every vote type requires the quorum percentage of the eligible tickets to
+// vote.  An approval vote passes when the approve option receives the pass
+// percentage of the votes.  A multiple choice vote is won by the only option
+// with the most votes when it receives the pass percentage of the votes.  A
+// ranked choice vote is counted in instant runoff rounds, see
+// tallyRankedChoice.  A vote without any valid cast votes never meets the
+// quorum and has no winner, regardless of the number of eligible tickets.
+func TallyVote(v Vote, eligible int, ballots map[uint64]uint64) VoteTally {
+	t := VoteTally{
+		Type: VoteType(v),
+	}
+
+	switch t.Type {
+	case VoteTypeRankedChoice:
+		t.Rounds, t.Winner = tallyRankedChoice(v, ballots)
+		if len(t.Rounds) > 0 {
+			t.Results = t.Rounds[0].Results
+		}
+	default:
+		t.Results = optionResults(v, ballots, nil)
+	}
+	for _, r := range t.Results {
+		t.TotalVotes += r.Votes
+	}
+
+	if t.TotalVotes == 0 {
+		return t
+	}
+
+	// The quorum and pass percentages are compared exactly, see
+	// tallyRankedChoice.
+	t.QuorumMet = t.TotalVotes*100 >=
+		uint64(v.QuorumPercentage)*uint64(eligible)
+
+	switch t.Type {
+	case VoteTypeApproval:
+		for _, r := range t.Results {
+			if r.ID == VoteOptionIDApprove &&
+				r.Votes*100 >= uint64(v.PassPercentage)*t.TotalVotes {
+				t.Winner = r.ID
+			}
+		}
+	case VoteTypeMultipleChoice:
+		best, ok := plurality(t.Results)
+		if ok && best.Votes*100 >=
+			uint64(v.PassPercentage)*t.TotalVotes {
+			t.Winner = best.ID
+		}
+	}
+	t.Approved = t.QuorumMet && t.Winner != ""
+
+	return t
+}
@@ -0,0 +1,310 @@
+package decredplugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newVote(t VoteT, pass uint32, ids ...string) Vote {
+	v := Vote{
+		Type:             t,
+		QuorumPercentage: 20,
+		PassPercentage:   pass,
+		Options:          make([]VoteOption, 0, len(ids)),
+	}
+	for i, id := range ids {
+		bits := uint64(1) << uint(i)
+		if t == VoteTypeRankedChoice {
+			bits = uint64(i + 1)
+		}
+		v.Mask |= bits
+		v.Options = append(v.Options, VoteOption{
+			Id:   id,
+			Bits: bits,
+		})
+	}
+	return v
+}
+
+func rankedVoteBit(t *testing.T, prefs ...uint64) uint64 {
+	t.Helper()
+
+	bit, err := RankedChoiceVoteBit(prefs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bit
+}
+
+func TestRankedChoiceVoteBit(t *testing.T) {
+	prefs := []uint64{3, 1, 2}
+	bit := rankedVoteBit(t, prefs...)
+	if bit != 0x213 {
+		t.Fatalf("got vote bit 0x%x, want 0x213", bit)
+	}
+	got, err := RankedChoicePreferences(bit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, prefs) {
+		t.Fatalf("got preferences %v, want %v", got, prefs)
+	}
+
+	// A zero preference ends the preferences
+	_, err = RankedChoicePreferences(0x203)
+	if err == nil {
+		t.Fatalf("expected error for empty preference")
+	}
+}
+
+func TestValidateVote(t *testing.T) {
+	approval := newVote(VoteTypeApproval, 60, VoteOptionIDReject,
+		VoteOptionIDApprove)
+	legacy := approval
+	legacy.Type = VoteTypeInvalid
+	approvalIDs := newVote(VoteTypeApproval, 60, "a", "b")
+	multiple := newVote(VoteTypeMultipleChoice, 40, "a", "b", "c")
+	single := newVote(VoteTypeMultipleChoice, 40, "a")
+	duplicate := newVote(VoteTypeMultipleChoice, 40, "a", "a")
+	mask := newVote(VoteTypeMultipleChoice, 40, "a", "b")
+	mask.Mask = 0x01
+	ranked := newVote(VoteTypeRankedChoice, 60, "a", "b", "c")
+	rankedPass := newVote(VoteTypeRankedChoice, 50, "a", "b", "c")
+	rankedBits := newVote(VoteTypeMultipleChoice, 60, "a", "b", "c", "d",
+		"e")
+	rankedBits.Type = VoteTypeRankedChoice
+	invalidType := newVote(VoteTypeMultipleChoice, 60, "a", "b")
+	invalidType.Type = 9
+	zeroQuorum := approval
+	zeroQuorum.QuorumPercentage = 0
+
+	// Setup tests
+	var tests = []struct {
+		name    string
+		vote    Vote
+		wantErr bool
+	}{
+		{"approval", approval, false},
+		{"legacy approval", legacy, false},
+		{"approval option ids", approvalIDs, true},
+		{"multiple choice", multiple, false},
+		{"not enough options", single, true},
+		{"duplicate option id", duplicate, true},
+		{"option bits outside mask", mask, true},
+		{"ranked choice", ranked, false},
+		{"ranked choice pass percentage", rankedPass, true},
+		{"ranked choice option bits", rankedBits, true},
+		{"invalid type", invalidType, true},
+		{"zero quorum", zeroQuorum, true},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			err := ValidateVote(v.vote)
+			if (err != nil) != v.wantErr {
+				t.Errorf("got error %v, want error %v",
+					err, v.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVoteBit(t *testing.T) {
+	multiple := newVote(VoteTypeMultipleChoice, 40, "a", "b", "c")
+	ranked := newVote(VoteTypeRankedChoice, 60, "a", "b", "c")
+
+	// Setup tests
+	var tests = []struct {
+		name    string
+		vote    Vote
+		bit     uint64
+		wantErr bool
+	}{
+		{"multiple choice", multiple, 0x04, false},
+		{"multiple choice zero bit", multiple, 0x00, true},
+		{"multiple choice several options", multiple, 0x03, true},
+		{"multiple choice outside mask", multiple, 0x08, true},
+		{"ranked choice", ranked, rankedVoteBit(t, 3, 1), false},
+		{"ranked choice all options", ranked,
+			rankedVoteBit(t, 2, 3, 1), false},
+		{"ranked choice unknown option", ranked,
+			rankedVoteBit(t, 1, 4), true},
+		{"ranked choice duplicate option", ranked,
+			rankedVoteBit(t, 1, 1), true},
+		{"ranked choice empty preference", ranked, 0x201, true},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			err := ValidateVoteBit(v.vote, v.bit)
+			if (err != nil) != v.wantErr {
+				t.Errorf("got error %v, want error %v",
+					err, v.wantErr)
+			}
+		})
+	}
+}
+
+func TestTallyVote(t *testing.T) {
+	approval := newVote(VoteTypeApproval, 60, VoteOptionIDReject,
+		VoteOptionIDApprove)
+	multiple := newVote(VoteTypeMultipleChoice, 40, "a", "b", "c")
+	ranked := newVote(VoteTypeRankedChoice, 60, "a", "b", "c")
+
+	// Setup tests
+	var tests = []struct {
+		name         string
+		vote         Vote
+		ballots      map[uint64]uint64
+		wantTotal    uint64
+		wantQuorum   bool
+		wantWinner   string
+		wantApproved bool
+		wantRounds   int
+	}{
+		{"approval approved", approval,
+			map[uint64]uint64{0x01: 10, 0x02: 20},
+			30, true, VoteOptionIDApprove, true, 0},
+
+		{"approval pass not met", approval,
+			map[uint64]uint64{0x01: 15, 0x02: 15},
+			30, true, "", false, 0},
+
+		// A truncated pass threshold of 12 votes would approve
+		// the vote with 57% of the votes.
+		{"approval pass not met exactly", approval,
+			map[uint64]uint64{0x01: 9, 0x02: 12},
+			21, true, "", false, 0},
+
+		{"approval quorum not met", approval,
+			map[uint64]uint64{0x02: 10},
+			10, false, VoteOptionIDApprove, false, 0},
+
+		{"multiple choice winner", multiple,
+			map[uint64]uint64{0x01: 5, 0x02: 15, 0x04: 10},
+			30, true, "b", true, 0},
+
+		{"multiple choice tie", multiple,
+			map[uint64]uint64{0x01: 5, 0x02: 15, 0x04: 15},
+			35, true, "", false, 0},
+
+		{"multiple choice pass not met", multiple,
+			map[uint64]uint64{0x01: 10, 0x02: 11, 0x04: 10},
+			31, true, "", false, 0},
+
+		// A truncated pass threshold of 8 votes would hand a the
+		// win with 38% of the votes.
+		{"multiple choice pass not met exactly", multiple,
+			map[uint64]uint64{0x01: 8, 0x02: 7, 0x04: 6},
+			21, true, "", false, 0},
+
+		// c is eliminated after the first round and its votes
+		// are transferred to the second preference a.
+		{"ranked choice transfer", ranked,
+			map[uint64]uint64{
+				rankedVoteBit(t, 1, 2): 10,
+				rankedVoteBit(t, 2, 1): 12,
+				rankedVoteBit(t, 3, 1): 8,
+			},
+			30, true, "a", true, 2},
+
+		{"ranked choice first round majority", ranked,
+			map[uint64]uint64{
+				rankedVoteBit(t, 2): 20,
+				rankedVoteBit(t, 1): 5,
+				rankedVoteBit(t, 3): 5,
+			},
+			30, true, "b", true, 1},
+
+		// The ballots ranking only c are exhausted once c is
+		// eliminated and a and b end up tied.
+		{"ranked choice tie", ranked,
+			map[uint64]uint64{
+				rankedVoteBit(t, 1): 10,
+				rankedVoteBit(t, 2): 10,
+				rankedVoteBit(t, 3): 5,
+			},
+			25, true, "", false, 2},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			got := TallyVote(v.vote, 100, v.ballots)
+			if got.TotalVotes != v.wantTotal {
+				t.Errorf("got total %v, want %v",
+					got.TotalVotes, v.wantTotal)
+			}
+			if got.QuorumMet != v.wantQuorum {
+				t.Errorf("got quorum met %v, want %v",
+					got.QuorumMet, v.wantQuorum)
+			}
+			if got.Winner != v.wantWinner {
+				t.Errorf("got winner %v, want %v",
+					got.Winner, v.wantWinner)
+			}
+			if got.Approved != v.wantApproved {
+				t.Errorf("got approved %v, want %v",
+					got.Approved, v.wantApproved)
+			}
+			if len(got.Rounds) != v.wantRounds {
+				t.Errorf("got %v rounds, want %v",
+					len(got.Rounds), v.wantRounds)
+			}
+		})
+	}
+}
+
+func TestTallyVoteNoVotes(t *testing.T) {
+	approval := newVote(VoteTypeApproval, 60, VoteOptionIDReject,
+		VoteOptionIDApprove)
+	zeroQuorum := approval
+	zeroQuorum.QuorumPercentage = 0
+	zeroPass := approval
+	zeroPass.PassPercentage = 0
+	multiple := newVote(VoteTypeMultipleChoice, 0, "a", "b", "c")
+	ranked := newVote(VoteTypeRankedChoice, 60, "a", "b", "c")
+
+	// Setup tests
+	var tests = []struct {
+		name         string
+		vote         Vote
+		eligible     int
+		ballots      map[uint64]uint64
+		wantQuorum   bool
+		wantApproved bool
+	}{
+		{"zero votes", approval, 100, map[uint64]uint64{}, false, false},
+		{"zero votes and pass", zeroPass, 100, nil, false, false},
+		{"zero eligible tickets", approval, 0, nil, false, false},
+		{"zero quorum", zeroQuorum, 100, nil, false, false},
+		{"zero quorum and eligible tickets", zeroQuorum, 0,
+			map[uint64]uint64{0x01: 0, 0x02: 0}, false, false},
+		{"multiple choice zero votes", multiple, 0, nil, false, false},
+		{"ranked choice zero votes", ranked, 0, nil, false, false},
+
+		// A single vote meets a zero quorum
+		{"zero quorum one vote", zeroQuorum, 100,
+			map[uint64]uint64{0x02: 1}, true, true},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			got := TallyVote(v.vote, v.eligible, v.ballots)
+			if got.QuorumMet != v.wantQuorum {
+				t.Errorf("got quorum met %v, want %v",
+					got.QuorumMet, v.wantQuorum)
+			}
+			if got.Approved != v.wantApproved {
+				t.Errorf("got approved %v, want %v",
+					got.Approved, v.wantApproved)
+			}
+			if got.TotalVotes == 0 && got.Winner != "" {
+				t.Errorf("got winner %v, want none", got.Winner)
+			}
+		})
+	}
+}
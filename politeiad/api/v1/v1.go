@@ -5,12 +5,19 @@
 package v1
 
 import (
+	"bytes"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
+	dcrtime "github.com/decred/dcrtime/api/v1"
 	"github.com/decred/dcrtime/merkle"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/api/v1/mime"
@@ -18,16 +25,22 @@ import (
 
 type ErrorStatusT int
 type RecordStatusT int
+type DiffActionT int
+type InventoryStateT int
 
 const (
 	// Routes
-	IdentityRoute             = "/v1/identity/"       // Retrieve identity
-	NewRecordRoute            = "/v1/newrecord/"      // New record
-	UpdateUnvettedRoute       = "/v1/updateunvetted/" // Update unvetted record
-	UpdateVettedRoute         = "/v1/updatevetted/"   // Update vetted record
-	UpdateVettedMetadataRoute = "/v1/updatevettedmd/" // Update vetted metadata
-	GetUnvettedRoute          = "/v1/getunvetted/"    // Retrieve unvetted record
-	GetVettedRoute            = "/v1/getvetted/"      // Retrieve vetted record
+	IdentityRoute             = "/v1/identity/"          // Retrieve identity
+	IdentityHistoryRoute      = "/v1/identityhistory/"   // Retrieve identity history
+	NewRecordRoute            = "/v1/newrecord/"         // New record
+	UpdateUnvettedRoute       = "/v1/updateunvetted/"    // Update unvetted record
+	UpdateVettedRoute         = "/v1/updatevetted/"      // Update vetted record
+	UpdateVettedMetadataRoute = "/v1/updatevettedmd/"    // Update vetted metadata
+	GetUnvettedRoute          = "/v1/getunvetted/"       // Retrieve unvetted record
+	GetVettedRoute            = "/v1/getvetted/"         // Retrieve vetted record
+	GetVettedVersionsRoute    = "/v1/getvettedversions/" // Retrieve vetted record versions
+	GetVettedDiffRoute        = "/v1/getvetteddiff/"     // Diff two vetted record versions
+	GetVettedAnchorRoute      = "/v1/getvettedanchor/"   // Anchor of vetted record
 
 	// Auth required
 	InventoryRoute         = "/v1/inventory/"                  // Inventory records
@@ -36,6 +49,7 @@ const (
 	PluginCommandRoute     = "/v1/plugin/"                     // Send a command to a plugin
 	PluginInventoryRoute   = PluginCommandRoute + "inventory/" // Inventory all plugins
 	UpdateReadmeRoute      = "/v1/updatereadme/"               // Update README
+	RotateIdentityRoute    = "/v1/rotateidentity/"             // Rotate identity
 
 	ChallengeSize      = 32         // Size of challenge token in bytes
 	TokenSize          = 32         // Size of token
@@ -59,6 +73,12 @@ const (
 	ErrorStatusNoChanges                     ErrorStatusT = 14
 	ErrorStatusRecordFound                   ErrorStatusT = 15
 	ErrorStatusInvalidRPCCredentials         ErrorStatusT = 16
+	ErrorStatusRecordNotFound                ErrorStatusT = 17
+	ErrorStatusRecordNotAnchored             ErrorStatusT = 18
+	ErrorStatusReadOnly                      ErrorStatusT = 19
+	ErrorStatusInvalidCursor                 ErrorStatusT = 20
+	ErrorStatusInvalidPluginCommand          ErrorStatusT = 21
+	ErrorStatusNotSupported                  ErrorStatusT = 22
 
 	// Record status codes (set and get)
 	RecordStatusInvalid           RecordStatusT = 0 // Invalid status
@@ -69,6 +89,19 @@ const (
 	RecordStatusUnreviewedChanges RecordStatusT = 5 // Unvetted record that has been changed
 	RecordStatusArchived          RecordStatusT = 6 // Vetted record that has been archived
 
+	// Diff actions
+	DiffActionInvalid  DiffActionT = 0 // Invalid action
+	DiffActionAdded    DiffActionT = 1 // Added in the newer version
+	DiffActionDeleted  DiffActionT = 2 // Deleted in the newer version
+	DiffActionModified DiffActionT = 3 // Modified in the newer version
+
+	// Inventory states
+	InventoryStateAll      InventoryStateT = 0 // Vetted and unvetted records
+	InventoryStateVetted   InventoryStateT = 1 // Public and archived records
+	InventoryStateUnvetted InventoryStateT = 2 // Unreviewed and censored records
+
+	InventoryPageSize = 100 // Maximum number of records in an inventory page
+
 	// Default network bits
 	DefaultMainnetHost = "politeia.decred.org"
 	DefaultMainnetPort = "49374"
@@ -98,6 +131,12 @@ var (
 		ErrorStatusNoChanges:                     "no changes in record",
 		ErrorStatusRecordFound:                   "record found",
 		ErrorStatusInvalidRPCCredentials:         "invalid RPC client credentials",
+		ErrorStatusRecordNotFound:                "record not found",
+		ErrorStatusRecordNotAnchored:             "record not anchored",
+		ErrorStatusReadOnly:                      "read-only replica",
+		ErrorStatusInvalidCursor:                 "invalid inventory cursor",
+		ErrorStatusInvalidPluginCommand:          "invalid plugin command",
+		ErrorStatusNotSupported:                  "not supported by backend",
 	}
 
 	// RecordStatus converts record status codes to human readable text.
@@ -111,6 +150,14 @@ var (
 		RecordStatusArchived:          "archived",
 	}
 
+	// DiffAction converts diff action codes to human readable text.
+	DiffAction = map[DiffActionT]string{
+		DiffActionInvalid:  "invalid action",
+		DiffActionAdded:    "added",
+		DiffActionDeleted:  "deleted",
+		DiffActionModified: "modified",
+	}
+
 	// Input validation
 	RegexpSHA256 = regexp.MustCompile("[A-Fa-f0-9]{64}")
 
@@ -119,6 +166,12 @@ var (
 	ErrInvalidBase64 = errors.New("corrupt base64")
 	ErrInvalidMerkle = errors.New("merkle roots do not match")
 	ErrCorrupt       = errors.New("signature verification failed")
+	ErrInvalidProof  = errors.New("invalid merkle inclusion proof")
+	ErrFileNotFound  = errors.New("file digest not found")
+	ErrInvalidAnchor = errors.New("invalid anchor")
+	ErrInvalidKey    = errors.New("invalid public key")
+	ErrInvalidChain  = errors.New("invalid identity history")
+	ErrNoKey         = errors.New("no key valid at timestamp")
 )
 
 // Verify ensures that a CensorshipRecord properly describes the array of
@@ -173,11 +226,353 @@ func Verify(pid identity.PublicIdentity, csr CensorshipRecord, files []File) err
 // The Merkle field contains the ordered merkle root of all files in the record.
 // The Token field contains a random censorship token that is signed by the
 // server private key.  The token can be used on the client to verify the
-// authenticity of the CensorshipRecord.
+// authenticity of the CensorshipRecord.  The FileProofs field is only set
+// when the record files are returned and contains the merkle inclusion proof
+// of every file.
 type CensorshipRecord struct {
-	Token     string `json:"token"`     // Censorship token
-	Merkle    string `json:"merkle"`    // Merkle root of record
-	Signature string `json:"signature"` // Signature of merkle+token
+	Token      string      `json:"token"`                // Censorship token
+	Merkle     string      `json:"merkle"`               // Merkle root of record
+	Signature  string      `json:"signature"`            // Signature of merkle+token
+	FileProofs []FileProof `json:"fileproofs,omitempty"` // Inclusion proofs of the files
+}
+
+// FileProof is the merkle inclusion proof of a single file in the
+// CensorshipRecord merkle root.  The merkle root is calculated over the
+// sorted file digests so Index refers to the position of the digest in the
+// sorted digests and not to the position of the file in the record.
+type FileProof struct {
+	Name      string   `json:"name"`      // Filename
+	Digest    string   `json:"digest"`    // SHA256 digest of the file payload
+	Index     uint32   `json:"index"`     // Index of the sorted digest
+	NumLeaves uint32   `json:"numleaves"` // Number of files in the record
+	Hashes    []string `json:"hashes"`    // Merkle branch, leaf to root
+}
+
+// decodeDigest decodes a hex encoded SHA256 digest.
+func decodeDigest(digest string) (*[sha256.Size]byte, error) {
+	b, err := hex.DecodeString(digest)
+	if err != nil || len(b) != sha256.Size {
+		return nil, ErrInvalidHex
+	}
+	var d [sha256.Size]byte
+	copy(d[:], b)
+	return &d, nil
+}
+
+// hashMerkleBranches returns the parent node of the provided merkle tree
+// nodes.
+func hashMerkleBranches(left, right *[sha256.Size]byte) *[sha256.Size]byte {
+	var b [sha256.Size * 2]byte
+	copy(b[:sha256.Size], left[:])
+	copy(b[sha256.Size:], right[:])
+	h := sha256.Sum256(b[:])
+	return &h
+}
+
+// merkleBranch returns the index of digest in the sorted leaves along with
+// the hashes that are required to recalculate merkle.Root(leaves) from the
+// digest.  The hashes are ordered from the leaf to the root.  Nodes without a
+// right sibling are hashed with themselves and do not appear in the branch.
+func merkleBranch(leaves []*[sha256.Size]byte, digest *[sha256.Size]byte) (uint32, []*[sha256.Size]byte, error) {
+	// merkle.Root sorts the leaves so the proof has to be created
+	// against the sorted leaves as well.
+	level := make([]*[sha256.Size]byte, len(leaves))
+	copy(level, leaves)
+	sort.Slice(level, func(i, j int) bool {
+		return bytes.Compare(level[i][:], level[j][:]) < 0
+	})
+
+	index := -1
+	for k, v := range level {
+		if *v == *digest {
+			index = k
+			break
+		}
+	}
+	if index == -1 {
+		return 0, nil, ErrFileNotFound
+	}
+
+	leafIndex := uint32(index)
+	branch := make([]*[sha256.Size]byte, 0, 32)
+	for len(level) > 1 {
+		if sibling := index ^ 1; sibling < len(level) {
+			branch = append(branch, level[sibling])
+		}
+		next := make([]*[sha256.Size]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, hashMerkleBranches(level[i],
+					level[i+1]))
+			} else {
+				next = append(next, hashMerkleBranches(level[i],
+					level[i]))
+			}
+		}
+		level = next
+		index /= 2
+	}
+
+	return leafIndex, branch, nil
+}
+
+// merkleBranchLen returns the number of hashes in the merkle branch of the
+// leaf at the provided index of a tree with numLeaves leaves.
+func merkleBranchLen(index, numLeaves uint32) int {
+	var n int
+	for numLeaves > 1 {
+		if index%2 == 1 || index+1 < numLeaves {
+			n++
+		}
+		index /= 2
+		numLeaves = (numLeaves + 1) / 2
+	}
+	return n
+}
+
+// merkleBranchRoot returns the merkle root that is obtained by walking the
+// branch of the digest at the provided leaf index.  The branch must contain
+// exactly the number of hashes that a leaf at index requires in a tree of
+// numLeaves leaves.
+func merkleBranchRoot(digest *[sha256.Size]byte, index, numLeaves uint32, branch []*[sha256.Size]byte) (*[sha256.Size]byte, error) {
+	if index >= numLeaves || len(branch) != merkleBranchLen(index, numLeaves) {
+		return nil, ErrInvalidProof
+	}
+
+	node := digest
+	for numLeaves > 1 {
+		switch {
+		case index%2 == 1:
+			node = hashMerkleBranches(branch[0], node)
+			branch = branch[1:]
+		case index+1 < numLeaves:
+			node = hashMerkleBranches(node, branch[0])
+			branch = branch[1:]
+		default:
+			node = hashMerkleBranches(node, node)
+		}
+		index /= 2
+		numLeaves = (numLeaves + 1) / 2
+	}
+
+	return node, nil
+}
+
+// FileProofs returns the merkle inclusion proof of every file.  The digests
+// of the files are not recalculated so the caller must ensure the files have
+// been verified.
+func FileProofs(files []File) ([]FileProof, error) {
+	digests := make([]*[sha256.Size]byte, 0, len(files))
+	for _, file := range files {
+		d, err := decodeDigest(file.Digest)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, d)
+	}
+
+	proofs := make([]FileProof, 0, len(files))
+	for k, file := range files {
+		index, branch, err := merkleBranch(digests, digests[k])
+		if err != nil {
+			return nil, err
+		}
+		hashes := make([]string, 0, len(branch))
+		for _, v := range branch {
+			hashes = append(hashes, hex.EncodeToString(v[:]))
+		}
+		proofs = append(proofs, FileProof{
+			Name:      file.Name,
+			Digest:    file.Digest,
+			Index:     index,
+			NumLeaves: uint32(len(files)),
+			Hashes:    hashes,
+		})
+	}
+
+	return proofs, nil
+}
+
+// VerifyFileProof ensures that the file digest is part of the record that is
+// described by the CensorshipRecord and that the CensorshipRecord was signed
+// by the provided identity.  This allows a client to verify a single file
+// without having access to the remaining files of the record.
+//
+// The merkle tree does not distinguish leaves from internal nodes, so a valid
+// proof only shows that the digest is a node of the tree.  The number of
+// hashes in the proof must match the leaf index and NumLeaves, which rejects
+// an internal node that is presented as a leaf of the real tree.  NumLeaves
+// is not covered by the signature however, so a client that needs to know
+// the digest is a file of the record must check NumLeaves against a file
+// count it trusts.
+func VerifyFileProof(pid identity.PublicIdentity, csr CensorshipRecord, fp FileProof) error {
+	digest, err := decodeDigest(fp.Digest)
+	if err != nil {
+		return err
+	}
+	branch := make([]*[sha256.Size]byte, 0, len(fp.Hashes))
+	for _, v := range fp.Hashes {
+		h, err := decodeDigest(v)
+		if err != nil {
+			return err
+		}
+		branch = append(branch, h)
+	}
+
+	// Verify merkle root
+	root, err := merkleBranchRoot(digest, fp.Index, fp.NumLeaves, branch)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(root[:]) != csr.Merkle {
+		return ErrInvalidMerkle
+	}
+
+	// Verify signature
+	s, err := hex.DecodeString(csr.Signature)
+	if err != nil {
+		return ErrInvalidHex
+	}
+	var signature [identity.SignatureSize]byte
+	copy(signature[:], s)
+	if !pid.VerifyMessage([]byte(csr.Merkle+csr.Token), signature) {
+		return ErrCorrupt
+	}
+
+	return nil
+}
+
+// gitObjectHash returns the git object hash of the provided raw object.
+func gitObjectHash(objectType string, object []byte) []byte {
+	h := sha1.New()
+	fmt.Fprintf(h, "%v %v\x00", objectType, len(object))
+	h.Write(object)
+	return h.Sum(nil)
+}
+
+// gitTreeEntries parses a raw git tree object and returns the object hash of
+// every entry indexed by name.
+func gitTreeEntries(tree []byte) (map[string][]byte, error) {
+	entries := make(map[string][]byte)
+	for len(tree) > 0 {
+		// Entries are "<mode> <name>\x00<20 byte hash>"
+		i := bytes.IndexByte(tree, ' ')
+		j := bytes.IndexByte(tree, 0)
+		if i == -1 || j == -1 || j < i || len(tree) < j+1+sha1.Size {
+			return nil, ErrInvalidAnchor
+		}
+		name := string(tree[i+1 : j])
+		entries[name] = tree[j+1 : j+1+sha1.Size]
+		tree = tree[j+1+sha1.Size:]
+	}
+	return entries, nil
+}
+
+// VerifyRecordAnchor verifies that the provided files, indexed by filename,
+// are part of the anchored record version.  The files are linked to the
+// anchored commit through the git objects of the anchor.  The commit must be
+// part of the anchor digests and the merkle root of the digests must match
+// the anchor merkle root.  When the anchor has been confirmed the dcrtime
+// merkle path of the anchor merkle root is verified as well.  It is up to the
+// caller to look up ChainInformation.Transaction in the Decred blockchain.
+func VerifyRecordAnchor(ra RecordAnchor, files map[string][]byte) error {
+	if len(ra.Path) < 2 || ra.Path[0] != ra.Token ||
+		ra.Path[1] != ra.Version || len(ra.Trees) != len(ra.Path)+1 {
+		return fmt.Errorf("%v: invalid path", ErrInvalidAnchor)
+	}
+
+	// Walk the git objects from the commit to the record files
+	commit, err := base64.StdEncoding.DecodeString(ra.Commit)
+	if err != nil {
+		return ErrInvalidBase64
+	}
+	firstLine := strings.SplitN(string(commit), "\n", 2)[0]
+	if !strings.HasPrefix(firstLine, "tree ") {
+		return fmt.Errorf("%v: invalid commit", ErrInvalidAnchor)
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(firstLine, "tree "))
+	if err != nil {
+		return ErrInvalidHex
+	}
+	var entries map[string][]byte
+	for k, v := range ra.Trees {
+		tree, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return ErrInvalidBase64
+		}
+		if !bytes.Equal(gitObjectHash("tree", tree), expected) {
+			return fmt.Errorf("%v: invalid tree %v", ErrInvalidAnchor,
+				k)
+		}
+		entries, err = gitTreeEntries(tree)
+		if err != nil {
+			return err
+		}
+		if k < len(ra.Path) {
+			var ok bool
+			expected, ok = entries[ra.Path[k]]
+			if !ok {
+				return fmt.Errorf("%v: %v not found",
+					ErrInvalidAnchor, ra.Path[k])
+			}
+		}
+	}
+	for name, payload := range files {
+		if !bytes.Equal(gitObjectHash("blob", payload), entries[name]) {
+			return fmt.Errorf("%v: file %v not found",
+				ErrInvalidAnchor, name)
+		}
+	}
+
+	// Verify the commit is part of the anchor.  Commit hashes are
+	// extended to the size of a SHA256 digest.
+	var digest [sha256.Size]byte
+	copy(digest[:], gitObjectHash("commit", commit))
+	digests := make([]*[sha256.Size]byte, 0, len(ra.Digests))
+	var found bool
+	for _, v := range ra.Digests {
+		d, err := decodeDigest(v)
+		if err != nil {
+			return err
+		}
+		if *d == digest {
+			found = true
+		}
+		digests = append(digests, d)
+	}
+	if !found {
+		return fmt.Errorf("%v: commit not found", ErrInvalidAnchor)
+	}
+	root := merkle.Root(digests)
+	if hex.EncodeToString(root[:]) != ra.Merkle {
+		return ErrInvalidMerkle
+	}
+
+	// Verify the dcrtime merkle path
+	if ra.ChainInformation == nil {
+		return nil
+	}
+	ci := ra.ChainInformation
+	chainRoot, err := merkle.VerifyAuthPath(&ci.MerklePath)
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrInvalidAnchor, err)
+	}
+	if hex.EncodeToString(chainRoot[:]) != ci.MerkleRoot {
+		return ErrInvalidMerkle
+	}
+	found = false
+	for _, v := range ci.MerklePath.Hashes {
+		if bytes.Equal(v[:], root[:]) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%v: merkle root not in merkle path",
+			ErrInvalidAnchor)
+	}
+
+	return nil
 }
 
 // Identity requests the record server identity.
@@ -189,6 +584,48 @@ type Identity struct {
 type IdentityReply struct {
 	Response  string `json:"response"`  // Signature of Challenge
 	PublicKey string `json:"publickey"` // Public key
+
+	// PrimaryPublicKey is set by read-only replicas.  It is the public
+	// key of the primary that signed the censorship records that are
+	// served by the replica.
+	PrimaryPublicKey string `json:"primarypublickey,omitempty"`
+}
+
+// IdentityKey is a single key of the politeiad identity history.  A key
+// transition is signed by both the previous and the new key so that a client
+// that trusts any key of the history can verify all other keys.  The first
+// key of the history does not have transition signatures.
+type IdentityKey struct {
+	PublicKey         string `json:"publickey"`         // Public key
+	ValidFrom         int64  `json:"validfrom"`         // Unix time key became active
+	ValidUntil        int64  `json:"validuntil"`        // Unix time key was replaced, 0 if active
+	PreviousSignature string `json:"previoussignature"` // Transition signature of previous key
+	Signature         string `json:"signature"`         // Transition signature of this key
+}
+
+// IdentityHistory requests all keys that were used by the record server.
+type IdentityHistory struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// IdentityHistoryReply contains the identity history, sorted from oldest to
+// newest.  The challenge is signed by the active key.
+type IdentityHistoryReply struct {
+	Response string        `json:"response"` // Signature of Challenge
+	Keys     []IdentityKey `json:"keys"`     // Identity history
+}
+
+// RotateIdentity replaces the record server identity with a newly generated
+// one.  Censorship records that were signed by the previous key remain valid.
+type RotateIdentity struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// RotateIdentityReply contains the new key.  The challenge is signed by the
+// previous key so that clients can verify the reply with the key they trust.
+type RotateIdentityReply struct {
+	Response string      `json:"response"` // Signature of Challenge
+	Key      IdentityKey `json:"key"`      // New key
 }
 
 // File describes an individual file that is part of the record.  The
@@ -263,6 +700,99 @@ type GetVettedReply struct {
 	Record   Record `json:"record"`
 }
 
+// RecordVersion describes a single version of a record.
+type RecordVersion struct {
+	Version   string        `json:"version"`   // Version of the record
+	Status    RecordStatusT `json:"status"`    // Status of the version
+	Timestamp int64         `json:"timestamp"` // Last update of the version
+	Merkle    string        `json:"merkle"`    // Merkle root of the version files
+}
+
+// GetVettedVersions requests all versions of a vetted record.
+type GetVettedVersions struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+}
+
+// GetVettedVersionsReply returns all versions of a vetted record, oldest
+// first.
+type GetVettedVersionsReply struct {
+	Response string          `json:"response"` // Challenge response
+	Versions []RecordVersion `json:"versions"` // Record versions
+}
+
+// FileDiff describes how a file changed between two record versions.  Diff
+// contains a unified diff and is only set for text files.
+type FileDiff struct {
+	Name       string      `json:"name"`                 // Filename
+	Action     DiffActionT `json:"action"`               // Diff action
+	MIME       string      `json:"mime"`                 // Mime type
+	FromDigest string      `json:"fromdigest,omitempty"` // Digest in older version
+	ToDigest   string      `json:"todigest,omitempty"`   // Digest in newer version
+	Diff       string      `json:"diff,omitempty"`       // Unified diff
+}
+
+// MetadataStreamDiff describes how a metadata stream changed between two
+// record versions.  Diff contains a unified diff of the stream payloads.
+type MetadataStreamDiff struct {
+	ID     uint64      `json:"id"`     // Stream identity
+	Action DiffActionT `json:"action"` // Diff action
+	Diff   string      `json:"diff"`   // Unified diff
+}
+
+// GetVettedDiff requests the differences between two versions of a vetted
+// record.  The latest version is used when a version is empty.
+type GetVettedDiff struct {
+	Challenge   string `json:"challenge"`   // Random challenge
+	Token       string `json:"token"`       // Censorship token
+	FromVersion string `json:"fromversion"` // Older record version
+	ToVersion   string `json:"toversion"`   // Newer record version
+}
+
+// GetVettedDiffReply returns the files and metadata streams that differ
+// between two versions of a vetted record.  Unchanged files and metadata
+// streams are omitted.
+type GetVettedDiffReply struct {
+	Response string               `json:"response"` // Challenge response
+	Files    []FileDiff           `json:"files"`    // File differences
+	Metadata []MetadataStreamDiff `json:"metadata"` // Metadata stream differences
+}
+
+// RecordAnchor contains the proof that a record version was timestamped in
+// dcrtime.  Commit is the raw git commit that contains the record version and
+// Trees are the raw git tree objects from the repository root down to the
+// directory that contains the record files.  Path contains the name of each
+// directory that is walked, starting with the token and the version.
+//
+// The commit hash, extended to the size of a SHA256 digest, is one of
+// Digests.  Merkle is the merkle root of Digests and is the digest that was
+// sent to dcrtime.  ChainInformation is only set once dcrtime has confirmed
+// the anchor.
+type RecordAnchor struct {
+	Token            string                    `json:"token"`                      // Censorship token
+	Version          string                    `json:"version"`                    // Record version
+	Commit           string                    `json:"commit"`                     // Base64 encoded git commit
+	Trees            []string                  `json:"trees"`                      // Base64 encoded git trees
+	Path             []string                  `json:"path"`                       // Tree entry names
+	Digests          []string                  `json:"digests"`                    // Digests of all anchored commits
+	Merkle           string                    `json:"merkle"`                     // Merkle root of digests
+	ChainInformation *dcrtime.ChainInformation `json:"chaininformation,omitempty"` // dcrtime proof
+}
+
+// GetVettedAnchor requests the anchor of a vetted record version.  The latest
+// version is used when Version is empty.
+type GetVettedAnchor struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+	Version   string `json:"version"`   // Record version
+}
+
+// GetVettedAnchorReply returns the anchor of a vetted record version.
+type GetVettedAnchorReply struct {
+	Response string       `json:"response"` // Challenge response
+	Anchor   RecordAnchor `json:"anchor"`   // Record anchor
+}
+
 // SetUnvettedStatus updates the status of an unvetted record.  This is used
 // to either promote a record to the public viewable repository or to censor
 // it. Additionally, metadata updates may travel along.
@@ -354,6 +884,27 @@ type Inventory struct {
 	VettedCount   uint `json:"vettedcount"`   // Last N vetted records
 	BranchesCount uint `json:"branchescount"` // Last N branches (censored, new etc)
 	AllVersions   bool `json:"allversions"`   // Return all versions of the proposals
+
+	// Filter requests a single page of the latest version of the records
+	// that match the filter.  The counts and AllVersions are ignored
+	// when it is set.
+	Filter *InventoryFilter `json:"filter,omitempty"`
+}
+
+// InventoryFilter selects a page of records.  Records are ordered by the most
+// recent timestamp first; records with the same timestamp are ordered by
+// token.  Cursor is the opaque cursor that was returned with the previous
+// page.  Reverse pages from the cursor towards more recent records, which are
+// then returned oldest first.
+type InventoryFilter struct {
+	State          InventoryStateT `json:"state"`                    // Vetted and/or unvetted records
+	Statuses       []RecordStatusT `json:"statuses,omitempty"`       // Only these statuses, all if empty
+	FromTimestamp  int64           `json:"fromtimestamp,omitempty"`  // Only records updated at or after
+	UntilTimestamp int64           `json:"untiltimestamp,omitempty"` // Only records updated before
+	MDStreams      []uint64        `json:"mdstreams,omitempty"`      // Metadata streams that must be present
+	Cursor         string          `json:"cursor,omitempty"`         // Cursor of the previous page
+	PageSize       uint            `json:"pagesize,omitempty"`       // Capped at InventoryPageSize
+	Reverse        bool            `json:"reverse,omitempty"`        // Page towards more recent records
 }
 
 // InventoryReply returns vetted and unvetted records.  If the Inventory
@@ -364,6 +915,10 @@ type InventoryReply struct {
 	Response string   `json:"response"` // Challenge response
 	Vetted   []Record `json:"vetted"`   // Last N vetted records
 	Branches []Record `json:"branches"` // Last N branches (censored, new etc)
+
+	// The following fields are only set when a filter was provided.
+	Records []Record `json:"records,omitempty"` // Page of records
+	Cursor  string   `json:"cursor,omitempty"`  // Next page cursor, empty on the last page
 }
 
 // UserErrorReply returns details about an error that occurred while trying to
@@ -420,3 +975,167 @@ type PluginCommandReply struct {
 	CommandID string `json:"commandid"` // User setable command identifier
 	Payload   string `json:"payload"`   // Actual command reply
 }
+
+// KeyTransition returns the message that is signed by the previous and the
+// new key when the identity is rotated.
+func KeyTransition(previous, next string, validFrom int64) []byte {
+	return []byte(previous + next + strconv.FormatInt(validFrom, 10))
+}
+
+// IdentityKeyPublic decodes the public key of an identity history key.
+func IdentityKeyPublic(key IdentityKey) (*identity.PublicIdentity, error) {
+	b, err := hex.DecodeString(key.PublicKey)
+	if err != nil {
+		return nil, ErrInvalidHex
+	}
+	pid, err := identity.PublicIdentityFromBytes(b)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return pid, nil
+}
+
+// verifyTransition verifies the transition signature of a key.
+func verifyTransition(pid *identity.PublicIdentity, signature string, msg []byte) error {
+	s, err := identity.SignatureFromString(signature)
+	if err != nil {
+		return ErrInvalidHex
+	}
+	if !pid.VerifyMessage(msg, *s) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// VerifyIdentityHistory verifies that the keys form an unbroken chain of key
+// transitions and that the trusted key is part of it.  Because every
+// transition is signed by both keys, all keys of a valid history are
+// authenticated by the trusted key.
+func VerifyIdentityHistory(keys []IdentityKey, trusted identity.PublicIdentity) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("%v: no keys", ErrInvalidChain)
+	}
+
+	var (
+		found    bool
+		previous *identity.PublicIdentity
+	)
+	for k, v := range keys {
+		pid, err := IdentityKeyPublic(v)
+		if err != nil {
+			return fmt.Errorf("%v: key %v: %v", ErrInvalidChain, k, err)
+		}
+		if pid.Key == trusted.Key {
+			found = true
+		}
+
+		// Validity window
+		last := k == len(keys)-1
+		if (last && v.ValidUntil != 0) ||
+			(!last && v.ValidUntil <= v.ValidFrom) {
+			return fmt.Errorf("%v: key %v: invalid validity window",
+				ErrInvalidChain, k)
+		}
+
+		if k == 0 {
+			previous = pid
+			continue
+		}
+
+		// Transition
+		if v.ValidFrom != keys[k-1].ValidUntil {
+			return fmt.Errorf("%v: key %v: not contiguous",
+				ErrInvalidChain, k)
+		}
+		msg := KeyTransition(keys[k-1].PublicKey, v.PublicKey, v.ValidFrom)
+		err = verifyTransition(previous, v.PreviousSignature, msg)
+		if err != nil {
+			return fmt.Errorf("%v: key %v previous signature: %v",
+				ErrInvalidChain, k, err)
+		}
+		err = verifyTransition(pid, v.Signature, msg)
+		if err != nil {
+			return fmt.Errorf("%v: key %v signature: %v",
+				ErrInvalidChain, k, err)
+		}
+		previous = pid
+	}
+	if !found {
+		return fmt.Errorf("%v: trusted key not found", ErrInvalidChain)
+	}
+
+	return nil
+}
+
+// IdentityAt returns the key of the identity history that was active at the
+// provided unix time.  Censorship records are signed by the key that was
+// active at the record timestamp.
+func IdentityAt(keys []IdentityKey, timestamp int64) (*identity.PublicIdentity, error) {
+	for _, v := range keys {
+		if timestamp >= v.ValidFrom &&
+			(v.ValidUntil == 0 || timestamp < v.ValidUntil) {
+			return IdentityKeyPublic(v)
+		}
+	}
+	return nil, ErrNoKey
+}
+
+// verifyCensorshipRecord verifies a CensorshipRecord with the provided key.
+// Only the signature is verified when no files are provided.
+func verifyCensorshipRecord(pid identity.PublicIdentity, csr CensorshipRecord, files []File) error {
+	if len(files) > 0 {
+		return Verify(pid, csr, files)
+	}
+
+	s, err := identity.SignatureFromString(csr.Signature)
+	if err != nil {
+		return ErrInvalidHex
+	}
+	if !pid.VerifyMessage([]byte(csr.Merkle+csr.Token), *s) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// SigningKey returns the key of the identity history that signed a
+// CensorshipRecord.  The record must be signed by the key that was active at
+// the record timestamp.  Backends that do not store signatures sign records
+// with the active key, therefore the active key is accepted as well.
+func SigningKey(keys []IdentityKey, timestamp int64, csr CensorshipRecord) (*identity.PublicIdentity, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+
+	pid, err := IdentityAt(keys, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyCensorshipRecord(*pid, csr, nil)
+	if err != ErrCorrupt {
+		return pid, err
+	}
+
+	active, err := IdentityKeyPublic(keys[len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	if active.Key == pid.Key {
+		return nil, ErrCorrupt
+	}
+	err = verifyCensorshipRecord(*active, csr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// VerifyWithHistory verifies a CensorshipRecord using the identity history of
+// the record server.  The record must be signed by the key that SigningKey
+// returns.  Only the signature is verified when no files are provided.
+func VerifyWithHistory(keys []IdentityKey, timestamp int64, csr CensorshipRecord, files []File) error {
+	pid, err := SigningKey(keys, timestamp, csr)
+	if err != nil {
+		return err
+	}
+	return verifyCensorshipRecord(*pid, csr, files)
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+)
+
+func TestMerkleBranch(t *testing.T) {
+	for n := 1; n <= 33; n++ {
+		leaves := make([]*[sha256.Size]byte, 0, n)
+		for i := 0; i < n; i++ {
+			d := sha256.Sum256([]byte(strconv.Itoa(i)))
+			leaves = append(leaves, &d)
+		}
+		// merkle.Root sorts in place so hand it a copy.
+		sorted := make([]*[sha256.Size]byte, n)
+		copy(sorted, leaves)
+		root := merkle.Root(sorted)
+
+		for _, v := range leaves {
+			index, branch, err := merkleBranch(leaves, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := merkleBranchRoot(v, index, uint32(n), branch)
+			if err != nil {
+				t.Fatalf("%v leaves: %v", n, err)
+			}
+			if *r != *root {
+				t.Fatalf("%v leaves: invalid root", n)
+			}
+
+			// Wrong index must fail
+			if n == 1 {
+				continue
+			}
+			r, err = merkleBranchRoot(v, (index+1)%uint32(n),
+				uint32(n), branch)
+			if err == nil && *r == *root {
+				t.Fatalf("%v leaves: proof verified with "+
+					"wrong index", n)
+			}
+		}
+	}
+
+	d := sha256.Sum256([]byte("not a leaf"))
+	_, _, err := merkleBranch(nil, &d)
+	if err != ErrFileNotFound {
+		t.Fatalf("expected ErrFileNotFound got %v", err)
+	}
+}
+
+func TestVerifyFileProof(t *testing.T) {
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make([]File, 0, 3)
+	digests := make([]*[sha256.Size]byte, 0, 3)
+	for i := 0; i < 3; i++ {
+		payload := []byte("file" + strconv.Itoa(i))
+		d := sha256.Sum256(payload)
+		digests = append(digests, &d)
+		files = append(files, File{
+			Name:    "file" + strconv.Itoa(i),
+			MIME:    "text/plain; charset=utf-8",
+			Digest:  hex.EncodeToString(d[:]),
+			Payload: base64.StdEncoding.EncodeToString(payload),
+		})
+	}
+	root := hex.EncodeToString(merkle.Root(digests)[:])
+	token := "token"
+	signature := id.SignMessage([]byte(root + token))
+	csr := CensorshipRecord{
+		Token:     token,
+		Merkle:    root,
+		Signature: hex.EncodeToString(signature[:]),
+	}
+
+	proofs, err := FileProofs(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proofs) != len(files) {
+		t.Fatalf("invalid number of proofs got %v want %v",
+			len(proofs), len(files))
+	}
+	for k, v := range proofs {
+		if v.Name != files[k].Name {
+			t.Fatalf("invalid proof name got %v want %v", v.Name,
+				files[k].Name)
+		}
+		err = VerifyFileProof(id.Public, csr, v)
+		if err != nil {
+			t.Fatalf("%v: %v", v.Name, err)
+		}
+	}
+
+	// Tampered digest
+	fp := proofs[0]
+	fp.Digest = proofs[1].Digest
+	if err := VerifyFileProof(id.Public, csr, fp); err != ErrInvalidMerkle {
+		t.Fatalf("expected ErrInvalidMerkle got %v", err)
+	}
+
+	// Forged proof of an internal node presented as a leaf
+	for _, v := range proofs {
+		if v.Index != 0 {
+			continue
+		}
+		d, err := decodeDigest(v.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sibling, err := decodeDigest(v.Hashes[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		node := hashMerkleBranches(d, sibling)
+		fp = FileProof{
+			Name:      "forged",
+			Digest:    hex.EncodeToString(node[:]),
+			Index:     0,
+			NumLeaves: v.NumLeaves,
+			Hashes:    v.Hashes[1:],
+		}
+	}
+	if err := VerifyFileProof(id.Public, csr, fp); err != ErrInvalidProof {
+		t.Fatalf("expected ErrInvalidProof got %v", err)
+	}
+
+	// Invalid signature
+	csr.Token = "other token"
+	if err := VerifyFileProof(id.Public, csr, proofs[0]); err != ErrCorrupt {
+		t.Fatalf("expected ErrCorrupt got %v", err)
+	}
+}
+
+func TestVerifyIdentityHistory(t *testing.T) {
+	// Create an identity history with two rotations
+	ids := make([]*identity.FullIdentity, 0, 3)
+	keys := make([]IdentityKey, 0, 3)
+	for i := 0; i < 3; i++ {
+		id, err := identity.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := IdentityKey{
+			PublicKey: hex.EncodeToString(id.Public.Key[:]),
+			ValidFrom: int64(i * 100),
+		}
+		if i > 0 {
+			keys[i-1].ValidUntil = key.ValidFrom
+			msg := KeyTransition(keys[i-1].PublicKey, key.PublicKey,
+				key.ValidFrom)
+			ps := ids[i-1].SignMessage(msg)
+			s := id.SignMessage(msg)
+			key.PreviousSignature = hex.EncodeToString(ps[:])
+			key.Signature = hex.EncodeToString(s[:])
+		}
+		ids = append(ids, id)
+		keys = append(keys, key)
+	}
+
+	// Every key authenticates the history
+	for _, v := range ids {
+		err := VerifyIdentityHistory(keys, v.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unknown key
+	other, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyIdentityHistory(keys, other.Public) == nil {
+		t.Fatalf("expected unknown key to fail")
+	}
+
+	// Forged transition
+	forged := make([]IdentityKey, len(keys))
+	copy(forged, keys)
+	forged[2].PublicKey = hex.EncodeToString(other.Public.Key[:])
+	if VerifyIdentityHistory(forged, ids[0].Public) == nil {
+		t.Fatalf("expected forged transition to fail")
+	}
+
+	// Gap in validity windows
+	copy(forged, keys)
+	forged[1].ValidUntil = 150
+	if VerifyIdentityHistory(forged, ids[0].Public) == nil {
+		t.Fatalf("expected gap to fail")
+	}
+
+	// Key selection by timestamp
+	tests := []struct {
+		timestamp int64
+		want      int
+	}{
+		{0, 0},
+		{99, 0},
+		{100, 1},
+		{199, 1},
+		{200, 2},
+		{1000, 2},
+	}
+	for _, test := range tests {
+		pid, err := IdentityAt(keys, test.timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pid.Key != ids[test.want].Public.Key {
+			t.Fatalf("unexpected key at %v", test.timestamp)
+		}
+	}
+	if _, err := IdentityAt(keys, -1); err != ErrNoKey {
+		t.Fatalf("expected ErrNoKey got %v", err)
+	}
+
+	// Signing key of a censorship record.  The key that was active at
+	// the record timestamp and the active key are accepted.
+	sign := func(id *identity.FullIdentity) CensorshipRecord {
+		csr := CensorshipRecord{
+			Token:  "token",
+			Merkle: "merkle",
+		}
+		s := id.SignMessage([]byte(csr.Merkle + csr.Token))
+		csr.Signature = hex.EncodeToString(s[:])
+		return csr
+	}
+	signers := []struct {
+		name   string
+		signer int
+		want   int
+		err    error
+	}{
+		{"key at timestamp", 1, 1, nil},
+		{"active key", 2, 2, nil},
+		{"previous key", 0, 0, ErrCorrupt},
+	}
+	for _, test := range signers {
+		t.Run(test.name, func(t *testing.T) {
+			csr := sign(ids[test.signer])
+			pid, err := SigningKey(keys, 150, csr)
+			if err != test.err {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			if pid.Key != ids[test.want].Public.Key {
+				t.Fatalf("got key %x, want %x", pid.Key,
+					ids[test.want].Public.Key)
+			}
+			err = VerifyWithHistory(keys, 150, csr, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
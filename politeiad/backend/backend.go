@@ -5,11 +5,20 @@
 package backend
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strconv"
 
+	dcrtime "github.com/decred/dcrtime/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/util"
+	"github.com/subosito/gozaru"
 )
 
 var (
@@ -38,10 +47,22 @@ var (
 	// archived record.
 	ErrRecordArchived = errors.New("record is archived")
 
+	// ErrRecordNotAnchored is emitted when a record version has not been
+	// anchored in dcrtime yet.
+	ErrRecordNotAnchored = errors.New("record not anchored")
+
 	// ErrJournalsNotReplayed is returned when the journals have not been replayed
 	// and the subsequent code expect it to be replayed
 	ErrJournalsNotReplayed = errors.New("journals have not been replayed")
 
+	// ErrReadOnly is returned when a write operation is attempted on a
+	// read-only replica.
+	ErrReadOnly = errors.New("backend is read-only")
+
+	// ErrNotSupported is returned when the backend does not implement the
+	// requested operation.
+	ErrNotSupported = errors.New("operation not supported by backend")
+
 	// Plugin names must be all lowercase letters and have a length of <20
 	PluginRE = regexp.MustCompile(`^[a-z]{1,20}$`)
 )
@@ -57,6 +78,162 @@ func (c ContentVerificationError) Error() string {
 	return fmt.Sprintf("%v: %v", v1.ErrorStatus[c.ErrorCode], c.ErrorContext)
 }
 
+// VerifyContent verifies that all provided MetadataStream and File are sane.
+// It is shared by all backends so that they reject the same content.  A
+// ContentVerificationError with ErrorStatusEmpty is returned when no files
+// are provided; callers that allow metadata only changes must check for it.
+func VerifyContent(metadata []MetadataStream, files []File, filesDel []string) error {
+	// Make sure all metadata is within maxima.
+	for _, v := range metadata {
+		if v.ID > v1.MetadataStreamsMax-1 {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidMDID,
+				ErrorContext: []string{
+					strconv.FormatUint(v.ID, 10),
+				},
+			}
+		}
+	}
+	for i := range metadata {
+		for j := range metadata {
+			// Skip self and non duplicates.
+			if i == j || metadata[i].ID != metadata[j].ID {
+				continue
+			}
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusDuplicateMDID,
+				ErrorContext: []string{
+					strconv.FormatUint(metadata[i].ID, 10),
+				},
+			}
+		}
+	}
+
+	// Prevent paths
+	for i := range files {
+		if filepath.Base(files[i].Name) != files[i].Name {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidFilename,
+				ErrorContext: []string{
+					files[i].Name,
+				},
+			}
+		}
+	}
+	for _, v := range filesDel {
+		if filepath.Base(v) != v {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidFilename,
+				ErrorContext: []string{
+					v,
+				},
+			}
+		}
+	}
+
+	// Now check files
+	if len(files) == 0 {
+		return ContentVerificationError{
+			ErrorCode: v1.ErrorStatusEmpty,
+		}
+	}
+
+	// Prevent bad filenames and duplicate filenames
+	for i := range files {
+		for j := range files {
+			if i == j {
+				continue
+			}
+			if files[i].Name == files[j].Name {
+				return ContentVerificationError{
+					ErrorCode: v1.ErrorStatusDuplicateFilename,
+					ErrorContext: []string{
+						files[i].Name,
+					},
+				}
+			}
+		}
+		// Check against filesDel
+		for _, v := range filesDel {
+			if files[i].Name == v {
+				return ContentVerificationError{
+					ErrorCode: v1.ErrorStatusDuplicateFilename,
+					ErrorContext: []string{
+						files[i].Name,
+					},
+				}
+			}
+		}
+	}
+
+	for i := range files {
+		if gozaru.Sanitize(files[i].Name) != files[i].Name {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidFilename,
+				ErrorContext: []string{
+					files[i].Name,
+				},
+			}
+		}
+
+		// Validate digest
+		d, ok := util.ConvertDigest(files[i].Digest)
+		if !ok {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidFileDigest,
+				ErrorContext: []string{
+					files[i].Name,
+				},
+			}
+		}
+
+		// Decode base64 payload
+		payload, err := base64.StdEncoding.DecodeString(files[i].Payload)
+		if err != nil {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidBase64,
+				ErrorContext: []string{
+					files[i].Name,
+				},
+			}
+		}
+
+		// Calculate payload digest
+		if !bytes.Equal(d[:], util.Digest(payload)) {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidFileDigest,
+				ErrorContext: []string{
+					files[i].Name,
+				},
+			}
+		}
+
+		// Verify MIME
+		detectedMIMEType := mime.DetectMimeType(payload)
+		if detectedMIMEType != files[i].MIME {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidMIMEType,
+				ErrorContext: []string{
+					files[i].Name,
+					detectedMIMEType,
+				},
+			}
+		}
+
+		if !mime.MimeValid(files[i].MIME) {
+			return ContentVerificationError{
+				ErrorCode: v1.ErrorStatusUnsupportedMIMEType,
+				ErrorContext: []string{
+					files[i].Name,
+					files[i].MIME,
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
 type File struct {
 	Name    string // Basename of the file
 	MIME    string // MIME type
@@ -125,6 +302,19 @@ type Record struct {
 	Version        string           // Version of Files
 	Metadata       []MetadataStream // User provided metadata
 	Files          []File           // User provided files
+	Signature      string           // Stored censorship signature, if any
+}
+
+// RecordAnchor is the proof that a record version was anchored in dcrtime.
+// The git objects link the record files to the anchored commit.
+type RecordAnchor struct {
+	Version          string                    // Version of the record
+	Commit           []byte                    // Raw git commit object
+	Trees            [][]byte                  // Raw git trees, repository root first
+	Path             []string                  // Tree entry names, starting with token
+	Digests          [][]byte                  // Digests of all anchored commits
+	Merkle           []byte                    // Merkle root of Digests
+	ChainInformation *dcrtime.ChainInformation // Set once dcrtime confirmed
 }
 
 // PluginSettings
@@ -165,6 +355,12 @@ type Backend interface {
 	// Get vetted record
 	GetVetted([]byte, string) (*Record, error)
 
+	// Get all versions of a vetted record without files
+	GetVettedVersions([]byte) ([]Record, error)
+
+	// Get the dcrtime anchor of a vetted record version
+	GetVettedAnchor([]byte, string) (*RecordAnchor, error)
+
 	// Set unvetted record status
 	SetUnvettedStatus([]byte, MDStatusT, []MetadataStream,
 		[]MetadataStream) (*Record, error)
@@ -176,6 +372,10 @@ type Backend interface {
 	// Inventory retrieves various record records.
 	Inventory(uint, uint, bool, bool) ([]Record, []Record, error)
 
+	// InventoryPage retrieves a filtered page of the latest version of
+	// all records and the cursor of the next page.
+	InventoryPage(InventoryFilter) ([]Record, string, error)
+
 	// Obtain plugin settings
 	GetPlugins() ([]Plugin, error)
 
@@ -185,3 +385,10 @@ type Backend interface {
 	// Close performs cleanup of the backend.
 	Close()
 }
+
+// IdentitySetter is implemented by backends and plugins that sign data with
+// the politeiad identity.  It is called when the identity is rotated.  Backends
+// pass the new identity on to their enabled plugins.
+type IdentitySetter interface {
+	SetIdentity(*identity.FullIdentity) error
+}
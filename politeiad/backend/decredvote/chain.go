@@ -0,0 +1,134 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"fmt"
+
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// Decred plugin settings that select and configure the chain source.
+	SettingChainSource = "chainsource"
+	SettingDcrdata     = "dcrdata"
+	SettingDcrdHost    = "dcrdhost"
+	SettingDcrdUser    = "dcrduser"
+	SettingDcrdPass    = "dcrdpass"
+	SettingDcrdCert    = "dcrdcert"
+	SettingSimChain    = "simchain"
+
+	// Supported chain sources.
+	ChainSourceDcrdata = "dcrdata" // dcrdata block explorer API
+	ChainSourceDcrd    = "dcrd"    // dcrd JSON-RPC
+	ChainSourceSim     = "sim"     // File driven simulated chain
+)
+
+// ChainBlock identifies a block of the decred chain.
+type ChainBlock struct {
+	Height uint32 // Block height
+	Hash   string // Block hash
+}
+
+// CommitmentAddress is the largest commitment address of a ticket or the
+// error that prevented it from being found.
+type CommitmentAddress struct {
+	Address string // Largest commitment address
+	Err     error  // Error looking up the address
+}
+
+// ChainSource provides the decred chain data that the decred plugin requires
+// to start votes and to validate ballots.
+type ChainSource interface {
+	// BestBlock returns the tip of the main chain.
+	BestBlock() (*ChainBlock, error)
+
+	// Block returns the main chain block at the passed in height.
+	Block(height uint32) (*ChainBlock, error)
+
+	// Snapshot returns the sorted live ticket pool at the passed in
+	// block hash.
+	Snapshot(hash string) ([]string, error)
+
+	// LargestCommitmentAddresses returns the largest commitment address
+	// of each of the passed in tickets, in the same order.
+	LargestCommitmentAddresses(tickets []string) ([]CommitmentAddress, error)
+}
+
+// commitmentOutput is a transaction output along with its ticket commitment
+// amount, if any.
+type commitmentOutput struct {
+	addresses []string
+	commitAmt *float64
+}
+
+// largestCommitment returns the address of the ticket commitment output with
+// the largest amount.
+func largestCommitment(txID string, outputs []commitmentOutput) CommitmentAddress {
+	// Best is address with largest commit amount.
+	var bestAddr string
+	var bestAmount float64
+	for _, v := range outputs {
+		if v.commitAmt == nil {
+			continue
+		}
+		if *v.commitAmt > bestAmount {
+			if len(v.addresses) == 0 {
+				log.Errorf("unexpected addresses length: %v", txID)
+				continue
+			}
+			bestAddr = v.addresses[0]
+			bestAmount = *v.commitAmt
+		}
+	}
+
+	if bestAddr == "" || bestAmount == 0.0 {
+		return CommitmentAddress{
+			Err: fmt.Errorf("no best commitment address found: %v",
+				txID),
+		}
+	}
+	return CommitmentAddress{
+		Address: bestAddr,
+	}
+}
+
+// NewChainSource returns the chain source that is selected by the decred
+// plugin settings.  dcrdata is used when no chain source is set.
+func NewChainSource(settings map[string]string) (ChainSource, error) {
+	var dcrdata ChainSource
+	if url := settings[SettingDcrdata]; url != "" {
+		dcrdata = NewDcrdataSource(url)
+	}
+
+	switch settings[SettingChainSource] {
+	case "", ChainSourceDcrdata:
+		if dcrdata == nil {
+			return nil, fmt.Errorf("dcrdata url not set")
+		}
+		return dcrdata, nil
+	case ChainSourceDcrd:
+		// dcrd does not keep historical ticket pools so the vote
+		// snapshots are always obtained from dcrdata.
+		dcrd, err := newDcrdSource(settings[SettingDcrdHost],
+			settings[SettingDcrdUser],
+			settings[SettingDcrdPass],
+			util.CleanAndExpandPath(settings[SettingDcrdCert]),
+			dcrdata)
+		if err != nil {
+			return nil, err
+		}
+		return dcrd, nil
+	case ChainSourceSim:
+		filename := settings[SettingSimChain]
+		if filename == "" {
+			return nil, fmt.Errorf("simulated chain file not set")
+		}
+		return NewSimSource(util.CleanAndExpandPath(filename)), nil
+	}
+
+	return nil, fmt.Errorf("invalid chain source: %v",
+		settings[SettingChainSource])
+}
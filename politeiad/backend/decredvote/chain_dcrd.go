@@ -0,0 +1,233 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// dcrdTimeout is the timeout of a single dcrd JSON-RPC request.
+const dcrdTimeout = time.Minute
+
+// dcrdRequest is a dcrd JSON-RPC request.
+type dcrdRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// dcrdError is a dcrd JSON-RPC error.
+type dcrdError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// dcrdReply is a dcrd JSON-RPC reply.
+type dcrdReply struct {
+	Result json.RawMessage `json:"result"`
+	Error  *dcrdError      `json:"error"`
+	ID     uint64          `json:"id"`
+}
+
+// dcrdBestBlock is the result of the dcrd getbestblock command.
+type dcrdBestBlock struct {
+	Hash   string `json:"hash"`
+	Height int64  `json:"height"`
+}
+
+// dcrdRawTransaction is the subset of the verbose result of the dcrd
+// getrawtransaction command that is required to find the ticket commitments.
+type dcrdRawTransaction struct {
+	TxID string `json:"txid"`
+	Vout []struct {
+		ScriptPubKey struct {
+			Addresses []string `json:"addresses"`
+			CommitAmt *float64 `json:"commitamt"`
+		} `json:"scriptPubKey"`
+	} `json:"vout"`
+}
+
+// dcrdSource is a chain source that is backed by the dcrd JSON-RPC server.
+// The ticket transactions are looked up using getrawtransaction, which
+// requires dcrd to run with the transaction index enabled.  dcrd does not
+// keep historical ticket pools so snapshots are obtained from the optional
+// snapshots chain source.
+//
+// dcrdSource satisfies the ChainSource interface.
+type dcrdSource struct {
+	id        uint64       // Last request id, atomic, must be 64 bit aligned
+	url       string       // dcrd JSON-RPC URL
+	user      string       // RPC user
+	pass      string       // RPC password
+	client    *http.Client // HTTP client
+	snapshots ChainSource  // Ticket pool snapshots, may be nil
+}
+
+// newDcrdSource returns a dcrd chain source that connects to the passed in
+// host.  The RPC certificate is used to verify the dcrd TLS certificate when
+// it is set.
+func newDcrdSource(host, user, pass, cert string, snapshots ChainSource) (*dcrdSource, error) {
+	if host == "" {
+		return nil, fmt.Errorf("dcrd host not set")
+	}
+
+	tlsConfig := &tls.Config{}
+	if cert != "" {
+		pem, err := ioutil.ReadFile(cert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid dcrd certificate: %v",
+				cert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &dcrdSource{
+		url:  "https://" + host,
+		user: user,
+		pass: pass,
+		client: &http.Client{
+			Timeout: dcrdTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		snapshots: snapshots,
+	}, nil
+}
+
+// call executes a dcrd JSON-RPC command and decodes its result into v.
+func (d *dcrdSource) call(method string, v interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	b, err := json.Marshal(dcrdRequest{
+		JSONRPC: "1.0",
+		ID:      atomic.AddUint64(&d.id, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(d.user, d.pass)
+
+	log.Debugf("dcrd %v %v", method, params)
+	r, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("dcrd error: %v %v %v", r.StatusCode,
+			method, err)
+	}
+
+	// dcrd replies to failed commands with an error object, other
+	// failures such as authentication errors only set the status code.
+	var reply dcrdReply
+	err = json.Unmarshal(body, &reply)
+	if err != nil {
+		return fmt.Errorf("dcrd error: %v %v %s", r.StatusCode,
+			method, body)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("dcrd error: %v %v %v", method,
+			reply.Error.Code, reply.Error.Message)
+	}
+
+	return json.Unmarshal(reply.Result, v)
+}
+
+// BestBlock returns the tip of the main chain.
+//
+// BestBlock satisfies the ChainSource interface.
+func (d *dcrdSource) BestBlock() (*ChainBlock, error) {
+	var bb dcrdBestBlock
+	err := d.call("getbestblock", &bb)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainBlock{
+		Height: uint32(bb.Height),
+		Hash:   bb.Hash,
+	}, nil
+}
+
+// Block returns the main chain block at the passed in height.
+//
+// Block satisfies the ChainSource interface.
+func (d *dcrdSource) Block(height uint32) (*ChainBlock, error) {
+	var hash string
+	err := d.call("getblockhash", &hash, int64(height))
+	if err != nil {
+		return nil, err
+	}
+	return &ChainBlock{
+		Height: height,
+		Hash:   hash,
+	}, nil
+}
+
+// Snapshot returns the sorted live ticket pool at the passed in block hash.
+//
+// Snapshot satisfies the ChainSource interface.
+func (d *dcrdSource) Snapshot(hash string) ([]string, error) {
+	if d.snapshots == nil {
+		return nil, fmt.Errorf("dcrd does not provide ticket pool " +
+			"snapshots and no dcrdata url is set")
+	}
+	return d.snapshots.Snapshot(hash)
+}
+
+// LargestCommitmentAddresses returns the largest commitment address of each
+// of the passed in tickets.  A ticket that can not be looked up only fails
+// its own result.
+//
+// LargestCommitmentAddresses satisfies the ChainSource interface.
+func (d *dcrdSource) LargestCommitmentAddresses(tickets []string) ([]CommitmentAddress, error) {
+	r := make([]CommitmentAddress, 0, len(tickets))
+	for _, t := range tickets {
+		var tx dcrdRawTransaction
+		err := d.call("getrawtransaction", &tx, t, 1)
+		if err != nil {
+			r = append(r, CommitmentAddress{
+				Err: err,
+			})
+			continue
+		}
+
+		outputs := make([]commitmentOutput, 0, len(tx.Vout))
+		for _, v := range tx.Vout {
+			outputs = append(outputs, commitmentOutput{
+				addresses: v.ScriptPubKey.Addresses,
+				commitAmt: v.ScriptPubKey.CommitAmt,
+			})
+		}
+		r = append(r, largestCommitment(t, outputs))
+	}
+
+	return r, nil
+}
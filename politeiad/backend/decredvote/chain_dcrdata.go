@@ -0,0 +1,159 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	dcrdataapi "github.com/decred/dcrdata/api/types/v3"
+)
+
+// dcrdataTimeout is the timeout of a single dcrdata request.
+const dcrdataTimeout = time.Minute
+
+// dcrdataSource is a chain source that is backed by the dcrdata block
+// explorer API.
+//
+// dcrdataSource satisfies the ChainSource interface.
+type dcrdataSource struct {
+	url    string       // dcrdata URL, ends with a slash
+	client *http.Client // HTTP client
+}
+
+// NewDcrdataSource returns a dcrdata chain source for the passed in dcrdata
+// URL.  The URL must end with a slash.
+func NewDcrdataSource(url string) ChainSource {
+	return &dcrdataSource{
+		url: url,
+		client: &http.Client{
+			Timeout: dcrdataTimeout,
+		},
+	}
+}
+
+// request sends a request to the passed in dcrdata API route and decodes the
+// JSON reply into v.  The request body is sent as JSON when it is not nil.
+func (d *dcrdataSource) request(method, route string, body, v interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	url := d.url + route
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	log.Debugf("connecting to %v", url)
+	r, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("dcrdata error: %v %v %v",
+				r.StatusCode, url, err)
+		}
+		return fmt.Errorf("dcrdata error: %v %v %s",
+			r.StatusCode, url, body)
+	}
+
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// BestBlock returns the tip of the main chain.
+//
+// BestBlock satisfies the ChainSource interface.
+func (d *dcrdataSource) BestBlock() (*ChainBlock, error) {
+	var bdb dcrdataapi.BlockDataBasic
+	err := d.request(http.MethodGet, "api/block/best", nil, &bdb)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainBlock{
+		Height: bdb.Height,
+		Hash:   bdb.Hash,
+	}, nil
+}
+
+// Block returns the main chain block at the passed in height.
+//
+// Block satisfies the ChainSource interface.
+func (d *dcrdataSource) Block(height uint32) (*ChainBlock, error) {
+	var bdb dcrdataapi.BlockDataBasic
+	err := d.request(http.MethodGet, "api/block/"+
+		strconv.FormatUint(uint64(height), 10), nil, &bdb)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainBlock{
+		Height: bdb.Height,
+		Hash:   bdb.Hash,
+	}, nil
+}
+
+// Snapshot returns the sorted live ticket pool at the passed in block hash.
+//
+// Snapshot satisfies the ChainSource interface.
+func (d *dcrdataSource) Snapshot(hash string) ([]string, error) {
+	var tickets []string
+	err := d.request(http.MethodGet, "api/stake/pool/b/"+hash+
+		"/full?sort=true", nil, &tickets)
+	if err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// LargestCommitmentAddresses returns the largest commitment address of each
+// of the passed in tickets.  The ticket transactions are requested in a single
+// batch.
+//
+// LargestCommitmentAddresses satisfies the ChainSource interface.
+func (d *dcrdataSource) LargestCommitmentAddresses(tickets []string) ([]CommitmentAddress, error) {
+	var ttxs []dcrdataapi.TrimmedTx
+	err := d.request(http.MethodPost, "api/txs/trimmed", dcrdataapi.Txns{
+		Transactions: tickets,
+	}, &ttxs)
+	if err != nil {
+		return nil, err
+	}
+	if len(ttxs) != len(tickets) {
+		return nil, fmt.Errorf("unexpected number of transactions: "+
+			"got %v, want %v", len(ttxs), len(tickets))
+	}
+
+	r := make([]CommitmentAddress, 0, len(ttxs))
+	for _, tx := range ttxs {
+		outputs := make([]commitmentOutput, 0, len(tx.Vout))
+		for _, v := range tx.Vout {
+			outputs = append(outputs, commitmentOutput{
+				addresses: v.ScriptPubKeyDecoded.Addresses,
+				commitAmt: v.ScriptPubKeyDecoded.CommitAmt,
+			})
+		}
+		r = append(r, largestCommitment(tx.TxID, outputs))
+	}
+
+	return r, nil
+}
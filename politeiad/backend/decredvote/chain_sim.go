@@ -0,0 +1,147 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// SimBlock is a block of a simulated chain along with its live ticket pool.
+type SimBlock struct {
+	Height     uint32   `json:"height"`     // Block height
+	Hash       string   `json:"hash"`       // Block hash
+	TicketPool []string `json:"ticketpool"` // Live tickets
+}
+
+// SimChain is the JSON file format of a simulated chain.  The block with the
+// largest height is the best block.
+type SimChain struct {
+	Blocks      []SimBlock        `json:"blocks"`      // Main chain blocks
+	Commitments map[string]string `json:"commitments"` // [ticket]address
+}
+
+// simSource is a chain source that reads a simulated chain from a file.  The
+// file is read on every call so that the chain can be advanced by rewriting
+// the file, which allows votes to be run end to end without a decred node.
+//
+// simSource satisfies the ChainSource interface.
+type simSource struct {
+	filename string // Simulated chain file
+}
+
+// NewSimSource returns a chain source for the passed in simulated chain
+// file.
+func NewSimSource(filename string) ChainSource {
+	return &simSource{
+		filename: filename,
+	}
+}
+
+// load reads the simulated chain file.
+func (s *simSource) load() (*SimChain, error) {
+	b, err := ioutil.ReadFile(s.filename)
+	if err != nil {
+		return nil, err
+	}
+	var sc SimChain
+	err = json.Unmarshal(b, &sc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulated chain %v: %v",
+			s.filename, err)
+	}
+	return &sc, nil
+}
+
+// BestBlock returns the block with the largest height.
+//
+// BestBlock satisfies the ChainSource interface.
+func (s *simSource) BestBlock() (*ChainBlock, error) {
+	sc, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(sc.Blocks) == 0 {
+		return nil, fmt.Errorf("simulated chain has no blocks")
+	}
+	best := sc.Blocks[0]
+	for _, v := range sc.Blocks {
+		if v.Height > best.Height {
+			best = v
+		}
+	}
+	return &ChainBlock{
+		Height: best.Height,
+		Hash:   best.Hash,
+	}, nil
+}
+
+// Block returns the block at the passed in height.
+//
+// Block satisfies the ChainSource interface.
+func (s *simSource) Block(height uint32) (*ChainBlock, error) {
+	sc, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range sc.Blocks {
+		if v.Height == height {
+			return &ChainBlock{
+				Height: v.Height,
+				Hash:   v.Hash,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("block not found: %v", height)
+}
+
+// Snapshot returns the sorted ticket pool of the block with the passed in
+// hash.
+//
+// Snapshot satisfies the ChainSource interface.
+func (s *simSource) Snapshot(hash string) ([]string, error) {
+	sc, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range sc.Blocks {
+		if v.Hash != hash {
+			continue
+		}
+		tickets := make([]string, len(v.TicketPool))
+		copy(tickets, v.TicketPool)
+		sort.Strings(tickets)
+		return tickets, nil
+	}
+	return nil, fmt.Errorf("block not found: %v", hash)
+}
+
+// LargestCommitmentAddresses returns the commitment address of each of the
+// passed in tickets.
+//
+// LargestCommitmentAddresses satisfies the ChainSource interface.
+func (s *simSource) LargestCommitmentAddresses(tickets []string) ([]CommitmentAddress, error) {
+	sc, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	r := make([]CommitmentAddress, 0, len(tickets))
+	for _, t := range tickets {
+		addr, ok := sc.Commitments[t]
+		if !ok {
+			r = append(r, CommitmentAddress{
+				Err: fmt.Errorf("no best commitment address "+
+					"found: %v", t),
+			})
+			continue
+		}
+		r = append(r, CommitmentAddress{
+			Address: addr,
+		})
+	}
+	return r, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+)
+
+// VerifyMessage verifies a message is properly signed.
+// Copied from https://github.com/decred/dcrd/blob/0fc55252f912756c23e641839b1001c21442c38a/rpcserver.go#L5605
+func VerifyMessage(params *chaincfg.Params, address, message, signature string) (bool, error) {
+	// Decode the provided address.
+	addr, err := dcrutil.DecodeAddress(address)
+	if err != nil {
+		return false, fmt.Errorf("Could not decode address: %v",
+			err)
+	}
+
+	// Only P2PKH addresses are valid for signing.
+	if _, ok := addr.(*dcrutil.AddressPubKeyHash); !ok {
+		return false, fmt.Errorf("Address is not a pay-to-pubkey-hash "+
+			"address: %v", address)
+	}
+
+	// Decode base64 signature.
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("Malformed base64 encoding: %v", err)
+	}
+
+	// Validate the signature - this just shows that it was valid at all.
+	// we will compare it with the key next.
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, message)
+	expectedMessageHash := chainhash.HashB(buf.Bytes())
+	pk, wasCompressed, err := secp256k1.RecoverCompact(sig,
+		expectedMessageHash)
+	if err != nil {
+		// Mirror Bitcoin Core behavior, which treats error in
+		// RecoverCompact as invalid signature.
+		return false, nil
+	}
+
+	// Reconstruct the pubkey hash.
+	dcrPK := pk
+	var serializedPK []byte
+	if wasCompressed {
+		serializedPK = dcrPK.SerializeCompressed()
+	} else {
+		serializedPK = dcrPK.SerializeUncompressed()
+	}
+	a, err := dcrutil.NewAddressSecpPubKey(serializedPK, params)
+	if err != nil {
+		// Again mirror Bitcoin Core behavior, which treats error in
+		// public key reconstruction as invalid signature.
+		return false, nil
+	}
+
+	// Return boolean if addresses match.
+	return a.EncodeAddress() == address, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/backend"
+)
+
+const (
+	// SettingInventory is the decred plugin setting that tells politeiad
+	// which plugin command returns the decred plugin data that is
+	// required to build the external politeiad cache.
+	SettingInventory = "inventory"
+)
+
+// DecredPlugin returns the decred plugin identifier, version and settings.
+// The provided settings replace the default settings with the same key.  It
+// is shared by the decred plugin implementations of all backends.
+func DecredPlugin(testnet bool, settings []backend.PluginSetting) backend.Plugin {
+	decredPlugin := backend.Plugin{
+		ID:       decredplugin.ID,
+		Version:  decredplugin.Version,
+		Settings: []backend.PluginSetting{},
+	}
+
+	if testnet {
+		decredPlugin.Settings = append(decredPlugin.Settings,
+			backend.PluginSetting{
+				Key:   SettingDcrdata,
+				Value: "https://testnet.dcrdata.org:443/",
+			},
+		)
+	} else {
+		decredPlugin.Settings = append(decredPlugin.Settings,
+			backend.PluginSetting{
+				Key:   SettingDcrdata,
+				Value: "https://explorer.dcrdata.org:443/",
+			})
+	}
+
+	// This setting is used to tell politeiad how to retrieve the
+	// decred plugin data that is required to build the external
+	// politeiad cache.
+	decredPlugin.Settings = append(decredPlugin.Settings,
+		backend.PluginSetting{
+			Key:   SettingInventory,
+			Value: decredplugin.CmdInventory,
+		})
+
+	for _, v := range settings {
+		var found bool
+		for k := range decredPlugin.Settings {
+			if decredPlugin.Settings[k].Key == v.Key {
+				decredPlugin.Settings[k].Value = v.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			decredPlugin.Settings = append(decredPlugin.Settings, v)
+		}
+	}
+
+	return decredPlugin
+}
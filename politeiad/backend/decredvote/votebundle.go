@@ -0,0 +1,360 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package decredvote
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// voteBundleBatchSize is the number of tickets whose commitment
+	// addresses are looked up in a single chain source request.
+	voteBundleBatchSize = 500
+)
+
+var (
+	// ErrInvalidVoteBundle is emitted when a vote bundle fails
+	// verification.
+	ErrInvalidVoteBundle = errors.New("invalid vote bundle")
+)
+
+// RejectedVote is a cast vote of a vote bundle that is not counted along with
+// the reason it was rejected.
+type RejectedVote struct {
+	Ticket string `json:"ticket"` // Ticket hash
+	Reason string `json:"reason"` // Reason the vote was rejected
+}
+
+// VoteBundleTally is the outcome of an independent tally of a vote bundle.
+type VoteBundleTally struct {
+	Tally    decredplugin.VoteTally // Vote tally of the counted votes
+	Counted  int                    // Number of counted votes
+	Rejected []RejectedVote         // Votes that were not counted
+}
+
+// CommitmentAddresses looks up the largest commitment address of the ticket
+// of every cast vote in batches.
+func CommitmentAddresses(chain ChainSource, cvs []decredplugin.VoteBundleCastVote) ([]string, error) {
+	addrs := make([]string, 0, len(cvs))
+	for i := 0; i < len(cvs); i += voteBundleBatchSize {
+		end := i + voteBundleBatchSize
+		if end > len(cvs) {
+			end = len(cvs)
+		}
+		tickets := make([]string, 0, end-i)
+		for _, v := range cvs[i:end] {
+			tickets = append(tickets, v.CastVote.Ticket)
+		}
+		r, err := chain.LargestCommitmentAddresses(tickets)
+		if err != nil {
+			return nil, err
+		}
+		if len(r) != len(tickets) {
+			return nil, fmt.Errorf("unexpected number of commitment "+
+				"addresses: got %v, want %v", len(r), len(tickets))
+		}
+		for k, v := range r {
+			if v.Err != nil {
+				return nil, fmt.Errorf("ticket %v: %v", tickets[k],
+					v.Err)
+			}
+			addrs = append(addrs, v.Address)
+		}
+	}
+	return addrs, nil
+}
+
+// VoteBundleIdentity returns the identity history that is embedded in the
+// vote bundles that are signed by the provided identity.  An identity without
+// a history has never been rotated and is its own history.
+func VoteBundleIdentity(id *identity.FullIdentity, history func() []pd.IdentityKey) []pd.IdentityKey {
+	if history != nil {
+		return history()
+	}
+	if id == nil {
+		return nil
+	}
+	return []pd.IdentityKey{{
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+	}}
+}
+
+// VerifyReceipt verifies that the receipt is a signature of the client
+// signature by one of the keys.
+func VerifyReceipt(keys []*identity.PublicIdentity, signature, receipt string) error {
+	r, err := identity.SignatureFromString(receipt)
+	if err != nil {
+		return fmt.Errorf("invalid receipt: %v", err)
+	}
+	for _, v := range keys {
+		if v.VerifyMessage([]byte(signature), *r) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid receipt")
+}
+
+// verifyUserSignature verifies an ed25519 signature of a user.
+func verifyUserSignature(publicKey, signature, msg string) error {
+	pk, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return err
+	}
+	pid, err := identity.PublicIdentityFromBytes(pk)
+	if err != nil {
+		return err
+	}
+	sig, err := util.ConvertSignature(signature)
+	if err != nil {
+		return err
+	}
+	if !pid.VerifyMessage([]byte(msg), sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// verifyVoteBundleCastVote verifies the server receipt and the ticket
+// signature of a cast vote and returns its vote bit.
+func verifyVoteBundleCastVote(params *chaincfg.Params, keys []*identity.PublicIdentity, v decredplugin.Vote, cv decredplugin.VoteBundleCastVote) (uint64, error) {
+	err := VerifyReceipt(keys, cv.CastVote.Signature, cv.Receipt)
+	if err != nil {
+		return 0, err
+	}
+	bit, err := strconv.ParseUint(cv.CastVote.VoteBit, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid vote bit: %v", err)
+	}
+	err = decredplugin.ValidateVoteBit(v, bit)
+	if err != nil {
+		return 0, err
+	}
+	sig, err := hex.DecodeString(cv.CastVote.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature: %v", err)
+	}
+	ok, err := VerifyMessage(params, cv.Address, cv.CastVote.Token+
+		cv.CastVote.Ticket+cv.CastVote.VoteBit,
+		base64.StdEncoding.EncodeToString(sig))
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("invalid signature")
+	}
+	return bit, nil
+}
+
+// VerifyVoteBundle verifies a vote bundle offline and tallies the votes.  It
+// verifies the identity history, the bundle signature, the signatures and
+// receipts of the vote authorizations, the start vote signature and the
+// receipt and ticket signature of every cast vote.  A cast vote is only
+// counted when it verifies, its ticket is part of the snapshot and the ticket
+// has not voted before.  The votes that are not counted are returned along
+// with the tally.
+//
+// The identity history of the bundle must contain the trusted key, which must
+// be obtained out of band.  The bundle and all receipts must be signed by a
+// key of the history.  The ticket snapshot and the commitment addresses are
+// taken from the bundle and must be verified with VerifyVoteBundleChain
+// before the tally can be relied upon.
+func VerifyVoteBundle(vbr *decredplugin.VoteBundleReply, params *chaincfg.Params, trusted *identity.PublicIdentity) (*VoteBundleTally, error) {
+	if trusted == nil {
+		return nil, fmt.Errorf("%v: no trusted identity",
+			ErrInvalidVoteBundle)
+	}
+	if vbr.Version != decredplugin.VersionVoteBundleReply {
+		return nil, fmt.Errorf("%v: unsupported version %v",
+			ErrInvalidVoteBundle, vbr.Version)
+	}
+
+	// Identity history
+	err := pd.VerifyIdentityHistory(vbr.Identity, *trusted)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidVoteBundle, err)
+	}
+	keys := make([]*identity.PublicIdentity, 0, len(vbr.Identity))
+	for _, v := range vbr.Identity {
+		pid, err := pd.IdentityKeyPublic(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", ErrInvalidVoteBundle, err)
+		}
+		keys = append(keys, pid)
+	}
+
+	// Bundle signature
+	var key *identity.PublicIdentity
+	for k, v := range vbr.Identity {
+		if v.PublicKey == vbr.PublicKey {
+			key = keys[k]
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%v: public key %v is not part of the "+
+			"identity history", ErrInvalidVoteBundle, vbr.PublicKey)
+	}
+	d, err := vbr.Digest()
+	if err != nil {
+		return nil, err
+	}
+	s, err := identity.SignatureFromString(vbr.Signature)
+	if err != nil || !key.VerifyMessage(d, *s) {
+		return nil, fmt.Errorf("%v: invalid signature",
+			ErrInvalidVoteBundle)
+	}
+
+	// Vote authorizations, the most recent one must authorize the vote
+	if len(vbr.AuthorizeVotes) == 0 {
+		return nil, fmt.Errorf("%v: vote not authorized",
+			ErrInvalidVoteBundle)
+	}
+	for _, v := range vbr.AuthorizeVotes {
+		av := v.AuthorizeVote
+		if av.Token != vbr.Token {
+			return nil, fmt.Errorf("%v: authorize vote token "+
+				"mismatch", ErrInvalidVoteBundle)
+		}
+		err := verifyUserSignature(av.PublicKey, av.Signature,
+			av.Token+v.RecordVersion+av.Action)
+		if err != nil {
+			return nil, fmt.Errorf("%v: authorize vote version %v: "+
+				"%v", ErrInvalidVoteBundle, v.RecordVersion, err)
+		}
+		err = VerifyReceipt(keys, av.Signature, av.Receipt)
+		if err != nil {
+			return nil, fmt.Errorf("%v: authorize vote version %v: "+
+				"%v", ErrInvalidVoteBundle, v.RecordVersion, err)
+		}
+	}
+	last := vbr.AuthorizeVotes[len(vbr.AuthorizeVotes)-1].AuthorizeVote
+	if last.Action != decredplugin.AuthVoteActionAuthorize {
+		return nil, fmt.Errorf("%v: vote authorization revoked",
+			ErrInvalidVoteBundle)
+	}
+
+	// Start vote
+	sv := vbr.StartVote
+	if sv.Vote.Token != vbr.Token {
+		return nil, fmt.Errorf("%v: start vote token mismatch",
+			ErrInvalidVoteBundle)
+	}
+	err = verifyUserSignature(sv.PublicKey, sv.Signature, sv.Vote.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%v: start vote: %v", ErrInvalidVoteBundle,
+			err)
+	}
+	err = decredplugin.ValidateVote(sv.Vote)
+	if err != nil {
+		return nil, fmt.Errorf("%v: start vote: %v", ErrInvalidVoteBundle,
+			err)
+	}
+
+	// Cast votes
+	eligible := make(map[string]bool,
+		len(vbr.StartVoteReply.EligibleTickets))
+	for _, v := range vbr.StartVoteReply.EligibleTickets {
+		eligible[v] = true
+	}
+	voted := make(map[string]bool, len(vbr.CastVotes))
+	ballots := make(map[uint64]uint64)
+	t := VoteBundleTally{
+		Rejected: []RejectedVote{},
+	}
+	for _, v := range vbr.CastVotes {
+		var reason string
+		ticket := v.CastVote.Ticket
+		switch {
+		case v.CastVote.Token != vbr.Token:
+			reason = "token mismatch"
+		case !eligible[ticket]:
+			reason = "ineligible ticket"
+		case voted[ticket]:
+			reason = "duplicate vote"
+		}
+		if reason == "" {
+			bit, err := verifyVoteBundleCastVote(params, keys,
+				sv.Vote, v)
+			if err != nil {
+				reason = err.Error()
+			} else {
+				voted[ticket] = true
+				ballots[bit]++
+				t.Counted++
+				continue
+			}
+		}
+		t.Rejected = append(t.Rejected, RejectedVote{
+			Ticket: ticket,
+			Reason: reason,
+		})
+	}
+	t.Tally = decredplugin.TallyVote(sv.Vote, len(eligible), ballots)
+
+	return &t, nil
+}
+
+// VerifyVoteBundleChain verifies the ticket snapshot and the commitment
+// addresses of a vote bundle against a chain source.
+func VerifyVoteBundleChain(vbr *decredplugin.VoteBundleReply, chain ChainSource) error {
+	height, err := strconv.ParseUint(vbr.StartVoteReply.StartBlockHeight,
+		10, 32)
+	if err != nil {
+		return fmt.Errorf("%v: invalid start block height",
+			ErrInvalidVoteBundle)
+	}
+	b, err := chain.Block(uint32(height))
+	if err != nil {
+		return err
+	}
+	if b.Hash != vbr.StartVoteReply.StartBlockHash {
+		return fmt.Errorf("%v: start block hash mismatch: got %v, "+
+			"want %v", ErrInvalidVoteBundle,
+			vbr.StartVoteReply.StartBlockHash, b.Hash)
+	}
+
+	snapshot, err := chain.Snapshot(b.Hash)
+	if err != nil {
+		return err
+	}
+	eligible := make([]string, len(vbr.StartVoteReply.EligibleTickets))
+	copy(eligible, vbr.StartVoteReply.EligibleTickets)
+	sort.Strings(eligible)
+	sort.Strings(snapshot)
+	if len(eligible) != len(snapshot) {
+		return fmt.Errorf("%v: ticket snapshot mismatch",
+			ErrInvalidVoteBundle)
+	}
+	for k := range snapshot {
+		if eligible[k] != snapshot[k] {
+			return fmt.Errorf("%v: ticket snapshot mismatch",
+				ErrInvalidVoteBundle)
+		}
+	}
+
+	addrs, err := CommitmentAddresses(chain, vbr.CastVotes)
+	if err != nil {
+		return err
+	}
+	for k, v := range vbr.CastVotes {
+		if v.Address != addrs[k] {
+			return fmt.Errorf("%v: ticket %v commitment address "+
+				"mismatch", ErrInvalidVoteBundle, v.CastVote.Ticket)
+		}
+	}
+
+	return nil
+}
@@ -5,14 +5,20 @@
 package gitbe
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"regexp"
 	"strings"
 	"time"
 
+	v1 "github.com/decred/dcrtime/api/v1"
 	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/politeiad/backend"
 )
 
 // An anchor corresponds to a set of git commit hashes, along with their
@@ -318,3 +324,71 @@ func (g *gitBackEnd) readUnconfirmedAnchorRecord() (*UnconfirmedAnchor, error) {
 
 	return &ua, nil
 }
+
+// findCommitAnchor returns the merkle root and the digests of the anchor that
+// contains the provided extended commit digest.  backend.ErrRecordNotAnchored
+// is returned if the commit has not been anchored yet.
+//
+// This function must be called with the lock held.
+func (g *gitBackEnd) findCommitAnchor(digest []byte) ([]byte, [][]byte, error) {
+	// Get the git log
+	gitLog, err := g.gitLog(g.vetted)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Iterate over the anchor commits and look for the digest
+	currLine := 0
+	for currLine < len(gitLog) {
+		commit, linesUsed, err := extractCommit(gitLog[currLine:])
+		if err != nil {
+			return nil, nil, err
+		}
+		currLine += linesUsed
+
+		firstLine := commit.Message[0]
+		if regexAnchorConfirmation.MatchString(firstLine) ||
+			!regexAnchor.MatchString(firstLine) {
+			continue
+		}
+		digests, _, err := parseAnchorCommit(commit)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, d := range digests {
+			if !bytes.Equal(d, digest) {
+				continue
+			}
+			merkleBytes, err := hex.DecodeString(anchorCommitMerkle(commit))
+			if err != nil {
+				return nil, nil, err
+			}
+			return merkleBytes, digests, nil
+		}
+	}
+
+	return nil, nil, backend.ErrRecordNotAnchored
+}
+
+// readChainInformation returns the dcrtime chain information of a confirmed
+// anchor.  Nil is returned if the anchor has not been confirmed yet.
+//
+// This function must be called with the lock held.
+func (g *gitBackEnd) readChainInformation(anchorKey []byte) (*v1.ChainInformation, error) {
+	b, err := ioutil.ReadFile(pijoin(g.vetted, defaultAnchorsDirectory,
+		hex.EncodeToString(anchorKey)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ci v1.ChainInformation
+	err = json.Unmarshal(b, &ci)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ci, nil
+}
@@ -0,0 +1,656 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// BundleVersion is the version of the record bundle format.
+	BundleVersion = 1
+)
+
+var (
+	// ErrInvalidBundle is emitted when a record bundle fails verification.
+	ErrInvalidBundle = errors.New("invalid bundle")
+)
+
+// BundleJournal is a plugin journal of a record.  The journal is stored
+// verbatim so that every entry, including the server receipts, can be
+// verified.
+type BundleJournal struct {
+	Plugin  string `json:"plugin"`  // Plugin identifier
+	Name    string `json:"name"`    // Journal filename
+	Payload string `json:"payload"` // Journal content
+}
+
+// BundleRecordVersion is a single version of a vetted record.  Anchor is only
+// set when the record version was anchored.
+type BundleRecordVersion struct {
+	RecordMetadata backend.RecordMetadata `json:"recordmetadata"`   // Record metadata
+	Signature      string                 `json:"signature"`        // Censorship record signature
+	Metadata       []pd.MetadataStream    `json:"metadata"`         // Metadata streams
+	Files          []pd.File              `json:"files"`            // Record files
+	Anchor         *pd.RecordAnchor       `json:"anchor,omitempty"` // Record anchor
+}
+
+// BundleRecord is a vetted record with all of its versions, oldest first, and
+// its plugin journals.
+type BundleRecord struct {
+	Token    string                `json:"token"`    // Censorship token
+	Versions []BundleRecordVersion `json:"versions"` // All record versions
+	Journals []BundleJournal       `json:"journals"` // Plugin journals
+}
+
+// Bundle is a self contained archive of vetted records.  The bundle is signed
+// by the active key of the identity history, which in turn authenticates the
+// keys that signed the censorship records and the plugin receipts.
+type Bundle struct {
+	Version   uint             `json:"version"`   // Bundle format version
+	Timestamp int64            `json:"timestamp"` // Export UNIX timestamp
+	Identity  []pd.IdentityKey `json:"identity"`  // Server identity history
+	Records   []BundleRecord   `json:"records"`   // Exported records
+	Signature string           `json:"signature"` // Signature of Digest
+}
+
+// Digest returns the SHA256 digest of the JSON encoded bundle without its
+// signature.  This is the digest that is signed by the server.
+func (b *Bundle) Digest() ([]byte, error) {
+	c := *b
+	c.Signature = ""
+	j, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	d := sha256.Sum256(j)
+	return d[:], nil
+}
+
+// newOffline returns a backend context that operates on the repos in root
+// without starting any of the backend services.  politeiad must not be
+// running while the context is in use.
+func newOffline(root, gitPath string) *gitBackEnd {
+	if gitPath == "" {
+		gitPath = "git"
+	}
+	return &gitBackEnd{
+		root:     root,
+		unvetted: filepath.Join(root, DefaultUnvettedPath),
+		vetted:   filepath.Join(root, DefaultVettedPath),
+		journals: filepath.Join(root, DefaultJournalsPath),
+		gitPath:  gitPath,
+		journal:  NewJournal(),
+	}
+}
+
+// convertBackendAnchor converts a backend anchor into its wire
+// representation.
+func convertBackendAnchor(token string, ra backend.RecordAnchor) pd.RecordAnchor {
+	a := pd.RecordAnchor{
+		Token:            token,
+		Version:          ra.Version,
+		Commit:           base64.StdEncoding.EncodeToString(ra.Commit),
+		Trees:            make([]string, 0, len(ra.Trees)),
+		Path:             ra.Path,
+		Digests:          make([]string, 0, len(ra.Digests)),
+		Merkle:           hex.EncodeToString(ra.Merkle),
+		ChainInformation: ra.ChainInformation,
+	}
+	for _, v := range ra.Trees {
+		a.Trees = append(a.Trees, base64.StdEncoding.EncodeToString(v))
+	}
+	for _, v := range ra.Digests {
+		a.Digests = append(a.Digests, hex.EncodeToString(v))
+	}
+	return a
+}
+
+// exportVersion loads a single vetted record version.
+func (g *gitBackEnd) exportVersion(token, version string) (*BundleRecordVersion, error) {
+	brm, err := loadMD(g.vetted, token, version)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := loadSignature(g.vetted, token, version)
+	if err != nil {
+		return nil, err
+	}
+	mds, err := loadMDStreams(g.vetted, token, version)
+	if err != nil {
+		return nil, err
+	}
+	files, err := loadRecord(g.vetted, token, version)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := BundleRecordVersion{
+		RecordMetadata: *brm,
+		Signature:      signature,
+		Metadata:       make([]pd.MetadataStream, 0, len(mds)),
+		Files:          make([]pd.File, 0, len(files)),
+	}
+	for _, v := range mds {
+		rv.Metadata = append(rv.Metadata, pd.MetadataStream{
+			ID:      v.ID,
+			Payload: v.Payload,
+		})
+	}
+	sort.Slice(rv.Metadata, func(i, j int) bool {
+		return rv.Metadata[i].ID < rv.Metadata[j].ID
+	})
+	for _, v := range files {
+		rv.Files = append(rv.Files, pd.File{
+			Name:    v.Name,
+			MIME:    v.MIME,
+			Digest:  v.Digest,
+			Payload: v.Payload,
+		})
+	}
+
+	// Anchor
+	t, err := hex.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	ra, err := g.GetVettedAnchor(t, version)
+	switch err {
+	case nil:
+		a := convertBackendAnchor(token, *ra)
+		rv.Anchor = &a
+	case backend.ErrRecordNotAnchored:
+		// Not anchored yet
+	default:
+		return nil, err
+	}
+
+	return &rv, nil
+}
+
+// exportJournals loads the plugin journals of a record.  The journals
+// directory holds the most recent journals, the copies that were flushed into
+// the vetted repo are used when it does not.
+func (g *gitBackEnd) exportJournals(token, latest string) ([]BundleJournal, error) {
+	journals := make([]BundleJournal, 0, 2)
+	for _, name := range []string{defaultCommentFilename,
+		defaultBallotFilename} {
+		filename := pijoin(g.journals, token, name)
+		if !util.FileExists(filename) {
+			filename = pijoin(g.vetted, token, latest, pluginDataDir,
+				name)
+			if !util.FileExists(filename) {
+				continue
+			}
+		}
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		journals = append(journals, BundleJournal{
+			Plugin:  decredplugin.ID,
+			Name:    name,
+			Payload: string(b),
+		})
+	}
+	return journals, nil
+}
+
+// exportRecord loads all versions and the plugin journals of a vetted record.
+func (g *gitBackEnd) exportRecord(token string) (*BundleRecord, error) {
+	latest, err := getLatest(pijoin(g.vetted, token))
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseUint(latest, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	br := BundleRecord{
+		Token:    token,
+		Versions: make([]BundleRecordVersion, 0, n),
+	}
+	for i := uint64(1); i <= n; i++ {
+		rv, err := g.exportVersion(token, strconv.FormatUint(i, 10))
+		if err != nil {
+			return nil, fmt.Errorf("version %v: %v", i, err)
+		}
+		br.Versions = append(br.Versions, *rv)
+	}
+	br.Journals, err = g.exportJournals(token, latest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &br, nil
+}
+
+// Export creates a bundle of the provided vetted records that is signed by
+// the provided identity.  All vetted records are exported when no tokens are
+// provided.  The identity history must end with the provided identity.
+// politeiad must not be running while Export is called.
+func Export(root, gitPath string, tokens []string, id *identity.FullIdentity, history []pd.IdentityKey) (*Bundle, error) {
+	if len(history) == 0 ||
+		history[len(history)-1].PublicKey != hex.EncodeToString(id.Public.Key[:]) {
+		return nil, fmt.Errorf("identity is not the active key of the " +
+			"identity history")
+	}
+
+	g := newOffline(root, gitPath)
+	if len(tokens) == 0 {
+		dirs, err := ioutil.ReadDir(g.vetted)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range dirs {
+			if v.IsDir() && isToken(v.Name()) {
+				tokens = append(tokens, v.Name())
+			}
+		}
+	}
+
+	b := Bundle{
+		Version:   BundleVersion,
+		Timestamp: time.Now().Unix(),
+		Identity:  history,
+		Records:   make([]BundleRecord, 0, len(tokens)),
+	}
+	for _, token := range tokens {
+		if !isToken(token) || !util.FileExists(pijoin(g.vetted, token)) {
+			return nil, fmt.Errorf("record %v: %v", token,
+				backend.ErrRecordNotFound)
+		}
+		br, err := g.exportRecord(token)
+		if err != nil {
+			return nil, fmt.Errorf("record %v: %v", token, err)
+		}
+		b.Records = append(b.Records, *br)
+	}
+
+	// Sign bundle
+	d, err := b.Digest()
+	if err != nil {
+		return nil, err
+	}
+	signature := id.SignMessage(d)
+	b.Signature = hex.EncodeToString(signature[:])
+
+	return &b, nil
+}
+
+// replayBundleJournal calls replay with the action and a decoder positioned
+// at the entry of every journal line.
+func replayBundleJournal(payload string, replay func(action string, d *json.Decoder) error) error {
+	for k, line := range strings.Split(payload, "\n") {
+		if line == "" {
+			continue
+		}
+		d := json.NewDecoder(strings.NewReader(line))
+		var action JournalAction
+		err := d.Decode(&action)
+		if err != nil {
+			return fmt.Errorf("line %v: journal action: %v", k+1, err)
+		}
+		err = replay(action.Action, d)
+		if err != nil {
+			return fmt.Errorf("line %v: %v", k+1, err)
+		}
+	}
+	return nil
+}
+
+// verifyJournal verifies the receipts of all entries of a plugin journal.
+func verifyJournal(keys []*identity.PublicIdentity, j BundleJournal) error {
+	if j.Plugin != decredplugin.ID {
+		return fmt.Errorf("unknown plugin: %v", j.Plugin)
+	}
+	if j.Name != defaultCommentFilename && j.Name != defaultBallotFilename {
+		return fmt.Errorf("unknown journal: %v", j.Name)
+	}
+
+	return replayBundleJournal(j.Payload, func(action string, d *json.Decoder) error {
+		var signature, receipt string
+		switch {
+		case j.Name == defaultCommentFilename && action == journalActionAdd:
+			var c decredplugin.Comment
+			err := d.Decode(&c)
+			if err != nil {
+				return err
+			}
+			signature, receipt = c.Signature, c.Receipt
+		case j.Name == defaultCommentFilename && action == journalActionDel:
+			var cc decredplugin.CensorComment
+			err := d.Decode(&cc)
+			if err != nil {
+				return err
+			}
+			signature, receipt = cc.Signature, cc.Receipt
+		case j.Name == defaultCommentFilename && action == journalActionAddLike:
+			var lc decredplugin.LikeComment
+			err := d.Decode(&lc)
+			if err != nil {
+				return err
+			}
+			signature, receipt = lc.Signature, lc.Receipt
+		case j.Name == defaultBallotFilename && action == journalActionAdd:
+			var cvj CastVoteJournal
+			err := d.Decode(&cvj)
+			if err != nil {
+				return err
+			}
+			signature, receipt = cvj.CastVote.Signature, cvj.Receipt
+		default:
+			return fmt.Errorf("invalid journal action: %v", action)
+		}
+		return decredvote.VerifyReceipt(keys, signature, receipt)
+	})
+}
+
+// verifyBundleVersion verifies the censorship record and the anchor of a
+// record version.
+func verifyBundleVersion(keys []pd.IdentityKey, token, version string, rv BundleRecordVersion) error {
+	if rv.RecordMetadata.Token != token {
+		return fmt.Errorf("record metadata token mismatch")
+	}
+	if len(rv.Files) == 0 {
+		return fmt.Errorf("no files")
+	}
+	csr := pd.CensorshipRecord{
+		Token:     token,
+		Merkle:    rv.RecordMetadata.Merkle,
+		Signature: rv.Signature,
+	}
+	err := pd.VerifyWithHistory(keys, rv.RecordMetadata.Timestamp, csr,
+		rv.Files)
+	if err != nil {
+		return fmt.Errorf("censorship record: %v", err)
+	}
+
+	if rv.Anchor == nil {
+		return nil
+	}
+	if rv.Anchor.Token != token || rv.Anchor.Version != version {
+		return fmt.Errorf("anchor record mismatch")
+	}
+	files := make(map[string][]byte, len(rv.Files))
+	for _, v := range rv.Files {
+		b, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return err
+		}
+		files[v.Name] = b
+	}
+	err = pd.VerifyRecordAnchor(*rv.Anchor, files)
+	if err != nil {
+		return fmt.Errorf("anchor: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyBundle verifies a bundle offline.  It verifies the identity history,
+// the bundle signature, the censorship record and anchor of every record
+// version and the server receipts of all plugin journal entries.  The
+// identity history must contain the trusted key, which must be obtained out of
+// band.  Anchors that were confirmed by dcrtime refer to a Decred transaction
+// that must be looked up separately.
+func VerifyBundle(b *Bundle, trusted *identity.PublicIdentity) error {
+	if trusted == nil {
+		return fmt.Errorf("%v: no trusted identity", ErrInvalidBundle)
+	}
+	if b.Version != BundleVersion {
+		return fmt.Errorf("%v: unsupported version %v", ErrInvalidBundle,
+			b.Version)
+	}
+	if len(b.Identity) == 0 {
+		return fmt.Errorf("%v: no identity", ErrInvalidBundle)
+	}
+
+	// Identity history
+	keys := make([]*identity.PublicIdentity, 0, len(b.Identity))
+	for _, v := range b.Identity {
+		pid, err := pd.IdentityKeyPublic(v)
+		if err != nil {
+			return fmt.Errorf("%v: %v", ErrInvalidBundle, err)
+		}
+		keys = append(keys, pid)
+	}
+	active := keys[len(keys)-1]
+	err := pd.VerifyIdentityHistory(b.Identity, *trusted)
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrInvalidBundle, err)
+	}
+
+	// Bundle signature
+	d, err := b.Digest()
+	if err != nil {
+		return err
+	}
+	s, err := identity.SignatureFromString(b.Signature)
+	if err != nil {
+		return fmt.Errorf("%v: invalid signature", ErrInvalidBundle)
+	}
+	if !active.VerifyMessage(d, *s) {
+		return fmt.Errorf("%v: invalid signature", ErrInvalidBundle)
+	}
+
+	// Records
+	for _, r := range b.Records {
+		if !isToken(r.Token) {
+			return fmt.Errorf("%v: invalid token %v", ErrInvalidBundle,
+				r.Token)
+		}
+		if len(r.Versions) == 0 {
+			return fmt.Errorf("%v: record %v: no versions",
+				ErrInvalidBundle, r.Token)
+		}
+		for k, v := range r.Versions {
+			version := strconv.Itoa(k + 1)
+			err := verifyBundleVersion(b.Identity, r.Token, version, v)
+			if err != nil {
+				return fmt.Errorf("%v: record %v version %v: %v",
+					ErrInvalidBundle, r.Token, version, err)
+			}
+		}
+		for _, v := range r.Journals {
+			err := verifyJournal(keys, v)
+			if err != nil {
+				return fmt.Errorf("%v: record %v journal %v: %v",
+					ErrInvalidBundle, r.Token, v.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// lastCommentID returns the highest comment ID of a comments journal.
+func lastCommentID(payload string) (uint64, error) {
+	var last uint64
+	err := replayBundleJournal(payload, func(action string, d *json.Decoder) error {
+		if action != journalActionAdd {
+			return nil
+		}
+		var c decredplugin.Comment
+		err := d.Decode(&c)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseUint(c.CommentID, 10, 64)
+		if err != nil {
+			return err
+		}
+		if id > last {
+			last = id
+		}
+		return nil
+	})
+	return last, err
+}
+
+// importJournals writes the plugin journals of a record into the journals
+// directory and into the latest version of the record.  The journals are
+// marked as flushed since both copies are identical.
+func (g *gitBackEnd) importJournals(r BundleRecord) error {
+	latest := strconv.Itoa(len(r.Versions))
+	dir := pijoin(g.vetted, r.Token, latest, pluginDataDir)
+	jdir := pijoin(g.journals, r.Token)
+	for _, v := range r.Journals {
+		err := os.MkdirAll(dir, 0764)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(jdir, 0764)
+		if err != nil {
+			return err
+		}
+		for _, filename := range []string{pijoin(dir, v.Name),
+			pijoin(jdir, v.Name)} {
+			err = ioutil.WriteFile(filename, []byte(v.Payload), 0664)
+			if err != nil {
+				return err
+			}
+		}
+
+		var flushed string
+		switch v.Name {
+		case defaultCommentFilename:
+			flushed = defaultCommentsFlushed
+
+			// Continue numbering comments after the last one
+			cid, err := lastCommentID(v.Payload)
+			if err != nil {
+				return err
+			}
+			err = ioutil.WriteFile(pijoin(jdir,
+				defaultCommentIDFilename),
+				[]byte(strconv.FormatUint(cid, 10)+"\n"), 0664)
+			if err != nil {
+				return err
+			}
+		case defaultBallotFilename:
+			flushed = defaultBallotFlushed
+		}
+		err = createFlushFile(pijoin(jdir, flushed))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importRecord writes all versions of a record into the vetted repo and
+// commits them.
+func (g *gitBackEnd) importRecord(r BundleRecord) error {
+	for k, rv := range r.Versions {
+		path := pijoin(g.vetted, r.Token, strconv.Itoa(k+1))
+		payload := pijoin(path, defaultPayloadDir)
+		err := os.MkdirAll(payload, 0764)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range rv.Files {
+			b, err := base64.StdEncoding.DecodeString(v.Payload)
+			if err != nil {
+				return err
+			}
+			err = ioutil.WriteFile(pijoin(payload, v.Name), b, 0664)
+			if err != nil {
+				return err
+			}
+		}
+		for _, v := range rv.Metadata {
+			err = ioutil.WriteFile(pijoin(path,
+				strconv.FormatUint(v.ID, 10)+defaultMDFilenameSuffix),
+				[]byte(v.Payload), 0664)
+			if err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(rv.RecordMetadata)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(pijoin(path, defaultRecordMetadataFilename),
+			append(b, '\n'), 0664)
+		if err != nil {
+			return err
+		}
+		if rv.Signature != "" {
+			err = ioutil.WriteFile(pijoin(path,
+				defaultRecordSignatureFilename),
+				[]byte(rv.Signature+"\n"), 0664)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := g.importJournals(r)
+	if err != nil {
+		return err
+	}
+
+	err = g.gitAdd(g.vetted, pijoin(g.vetted, r.Token))
+	if err != nil {
+		return err
+	}
+	return g.gitCommit(g.vetted, "Import record "+r.Token)
+}
+
+// Import recreates the vetted and unvetted repos and the plugin journals in
+// root from a bundle.  Every record is committed to a new git history, the
+// anchors in the bundle remain the proof of the original history.  The
+// repos must not exist and the bundle should be verified with VerifyBundle
+// before it is imported.
+func Import(root, gitPath string, b *Bundle) error {
+	g := newOffline(root, gitPath)
+	for _, path := range []string{g.vetted, g.unvetted} {
+		if util.FileExists(path) {
+			return fmt.Errorf("repo already exists: %v", path)
+		}
+	}
+
+	err := os.MkdirAll(g.journals, 0760)
+	if err != nil {
+		return err
+	}
+	err = g.gitInitRepo(g.vetted, defaultRepoConfig)
+	if err != nil {
+		return err
+	}
+	for _, r := range b.Records {
+		if !isToken(r.Token) {
+			return fmt.Errorf("invalid token: %v", r.Token)
+		}
+		err = g.importRecord(r)
+		if err != nil {
+			return fmt.Errorf("record %v: %v", r.Token, err)
+		}
+	}
+
+	return g.gitClone(g.vetted, g.unvetted, defaultRepoConfig)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/slog"
+)
+
+func TestBundle(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := pijoin(dir, "primary")
+	g, err := New(&chaincfg.TestNet3Params, root, "", "", id,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	token := hex.EncodeToString(newVettedRecord(t, g))
+	err = g.anchorAllRepos()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Journal a comment with a server receipt
+	c := decredplugin.Comment{
+		Token:     token,
+		CommentID: "1",
+		Comment:   "comment",
+		Signature: "signature",
+	}
+	r := id.SignMessage([]byte(c.Signature))
+	c.Receipt = hex.EncodeToString(r[:])
+	cb, err := decredplugin.EncodeComment(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(pijoin(g.journals, token), 0760)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.journal.Journal(pijoin(g.journals, token, defaultCommentFilename),
+		string(journalAdd)+string(cb))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Export and verify
+	history := []pd.IdentityKey{{
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+	}}
+	b, err := Export(root, "", nil, id, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Records) != 1 || len(b.Records[0].Versions) != 1 ||
+		len(b.Records[0].Journals) != 1 {
+		t.Fatalf("unexpected bundle %v", spewBundle(b))
+	}
+	if b.Records[0].Versions[0].Anchor == nil {
+		t.Fatalf("anchor not exported")
+	}
+	err = VerifyBundle(b, nil)
+	if err == nil {
+		t.Fatalf("expected missing trusted identity to fail")
+	}
+	err = VerifyBundle(b, &id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyBundle(b, &other.Public)
+	if err == nil {
+		t.Fatalf("expected untrusted identity to fail")
+	}
+
+	// Tampering is detected
+	encoded, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		tamper func(*Bundle)
+		resign bool
+	}{
+		{"timestamp", func(b *Bundle) { b.Timestamp++ }, false},
+		{"file", func(b *Bundle) {
+			b.Records[0].Versions[0].Files[0].Payload =
+				base64.StdEncoding.EncodeToString([]byte("tampered"))
+		}, true},
+		{"receipt", func(b *Bundle) {
+			tc := c
+			r := other.SignMessage([]byte(tc.Signature))
+			tc.Receipt = hex.EncodeToString(r[:])
+			cb, err := decredplugin.EncodeComment(tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Records[0].Journals[0].Payload = string(journalAdd) +
+				string(cb) + "\n"
+		}, true},
+	}
+	for _, test := range tests {
+		var tb Bundle
+		err = json.Unmarshal(encoded, &tb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = VerifyBundle(&tb, &id.Public)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		test.tamper(&tb)
+		if test.resign {
+			d, err := tb.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := id.SignMessage(d)
+			tb.Signature = hex.EncodeToString(s[:])
+		}
+		err = VerifyBundle(&tb, &id.Public)
+		if err == nil {
+			t.Fatalf("%v: expected verification to fail", test.name)
+		}
+	}
+
+	// Import and export again
+	imported := pijoin(dir, "imported")
+	err = Import(imported, "", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Import(imported, "", b)
+	if err == nil {
+		t.Fatalf("expected import into existing repos to fail")
+	}
+	ib, err := Export(imported, "", nil, id, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyBundle(ib, &id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Records[0].Versions[0].Anchor = nil
+	if !reflect.DeepEqual(b.Records, ib.Records) {
+		t.Fatalf("unexpected import got %v want %v", spewBundle(ib),
+			spewBundle(b))
+	}
+	cid, err := ioutil.ReadFile(pijoin(imported, DefaultJournalsPath, token,
+		defaultCommentIDFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cid) != "1\n" {
+		t.Fatalf("unexpected comment id %q", cid)
+	}
+
+	// The imported repos are consistent
+	checkInconsistencies(t, imported, false, nil)
+}
+
+// spewBundle returns the JSON encoding of a bundle for test failures.
+func spewBundle(b *Bundle) string {
+	j, _ := json.Marshal(b)
+	return string(j)
+}
@@ -0,0 +1,680 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+)
+
+// InconsistencyT identifies the type of an inconsistency that was found by
+// Check.
+type InconsistencyT int
+
+const (
+	// Inconsistency codes
+	InconsistencyInvalid               InconsistencyT = 0  // Invalid code
+	InconsistencyGitCorrupt            InconsistencyT = 1  // git fsck failed
+	InconsistencyDirtyWorkTree         InconsistencyT = 2  // Uncommitted changes
+	InconsistencyWrongBranch           InconsistencyT = 3  // Repo not on master
+	InconsistencyMasterOutOfSync       InconsistencyT = 4  // Unvetted master differs from vetted
+	InconsistencyLeftoverBranch        InconsistencyT = 5  // Temporary branch was not deleted
+	InconsistencyUnknownBranch         InconsistencyT = 6  // Branch is not a record
+	InconsistencyInvalidToken          InconsistencyT = 7  // Record directory is not a token
+	InconsistencyInvalidVersion        InconsistencyT = 8  // Invalid or missing version directory
+	InconsistencyRecordMetadataMissing InconsistencyT = 9  // recordmetadata.json missing
+	InconsistencyRecordMetadataCorrupt InconsistencyT = 10 // recordmetadata.json can't be decoded
+	InconsistencyTokenMismatch         InconsistencyT = 11 // Record metadata token differs
+	InconsistencyInvalidStatus         InconsistencyT = 12 // Record metadata status invalid
+	InconsistencyPayloadMissing        InconsistencyT = 13 // No record files
+	InconsistencyMerkleMismatch        InconsistencyT = 14 // Files don't match record metadata
+	InconsistencyMDStreamInvalid       InconsistencyT = 15 // Invalid metadata stream filename
+	InconsistencyMDStreamMissing       InconsistencyT = 16 // Metadata stream dropped by version
+	InconsistencyDuplicateRecord       InconsistencyT = 17 // Record is vetted and unvetted
+	InconsistencyOrphanedJournal       InconsistencyT = 18 // Journals of unknown record
+	InconsistencyStaleFlushMarker      InconsistencyT = 19 // Journal flagged flushed but differs
+	InconsistencyStaleSnapshot         InconsistencyT = 20 // Snapshot does not match journal
+)
+
+var (
+	// Inconsistencies converts inconsistency codes to human readable text.
+	Inconsistencies = map[InconsistencyT]string{
+		InconsistencyInvalid:               "invalid inconsistency",
+		InconsistencyGitCorrupt:            "git repository corrupt",
+		InconsistencyDirtyWorkTree:         "uncommitted changes in work tree",
+		InconsistencyWrongBranch:           "repository not on master",
+		InconsistencyMasterOutOfSync:       "master out of sync with vetted",
+		InconsistencyLeftoverBranch:        "leftover temporary branch",
+		InconsistencyUnknownBranch:         "unknown branch",
+		InconsistencyInvalidToken:          "invalid record token",
+		InconsistencyInvalidVersion:        "invalid record version",
+		InconsistencyRecordMetadataMissing: "record metadata missing",
+		InconsistencyRecordMetadataCorrupt: "record metadata corrupt",
+		InconsistencyTokenMismatch:         "record metadata token mismatch",
+		InconsistencyInvalidStatus:         "invalid record status",
+		InconsistencyPayloadMissing:        "record files missing",
+		InconsistencyMerkleMismatch:        "record merkle mismatch",
+		InconsistencyMDStreamInvalid:       "invalid metadata stream",
+		InconsistencyMDStreamMissing:       "metadata stream missing",
+		InconsistencyDuplicateRecord:       "record is both vetted and unvetted",
+		InconsistencyOrphanedJournal:       "orphaned journal",
+		InconsistencyStaleFlushMarker:      "stale journal flush marker",
+		InconsistencyStaleSnapshot:         "stale journal snapshot",
+	}
+
+	// temporaryBranchSuffixes are the suffixes of the branches that are
+	// created during an operation and deleted once it completes.
+	temporaryBranchSuffixes = []string{
+		"_tmp",
+		"_rm",
+		"_flushcomments",
+		"_flushvotes",
+		"_signrecords",
+	}
+)
+
+// Inconsistency describes a single inconsistency that was found by Check.
+type Inconsistency struct {
+	Code     InconsistencyT `json:"code"`              // Inconsistency code
+	Repo     string         `json:"repo"`              // vetted, unvetted or journals
+	Token    string         `json:"token,omitempty"`   // Record token
+	Version  string         `json:"version,omitempty"` // Record version
+	Context  string         `json:"context,omitempty"` // Additional information
+	Repaired bool           `json:"repaired"`          // Inconsistency was repaired
+}
+
+// String returns a human readable representation of the inconsistency.
+func (i Inconsistency) String() string {
+	s := fmt.Sprintf("%v %v", i.Repo, Inconsistencies[i.Code])
+	if i.Token != "" {
+		s += " " + i.Token
+	}
+	if i.Version != "" {
+		s += " version " + i.Version
+	}
+	if i.Context != "" {
+		s += ": " + i.Context
+	}
+	if i.Repaired {
+		s += " (repaired)"
+	}
+	return s
+}
+
+// treeEntry is a file or a directory in a record tree.
+type treeEntry struct {
+	name string // Base name
+	dir  bool   // Entry is a directory
+}
+
+// recordTree provides read-only access to the records of a repo.  The path
+// elements are relative to the top level directory of the repo.
+type recordTree interface {
+	// Read the entries of a directory
+	readDir(...string) ([]treeEntry, error)
+
+	// Read the content of a file
+	readFile(...string) ([]byte, error)
+}
+
+// workTree is a recordTree that reads the work tree of a repo.
+type workTree struct {
+	path string // Path to repo
+}
+
+// readDir returns the entries of a work tree directory.
+func (t workTree) readDir(elements ...string) ([]treeEntry, error) {
+	fi, err := ioutil.ReadDir(pijoin(append([]string{t.path},
+		elements...)...))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]treeEntry, 0, len(fi))
+	for _, v := range fi {
+		entries = append(entries, treeEntry{
+			name: v.Name(),
+			dir:  v.IsDir(),
+		})
+	}
+	return entries, nil
+}
+
+// readFile returns the content of a work tree file.
+func (t workTree) readFile(elements ...string) ([]byte, error) {
+	return ioutil.ReadFile(pijoin(append([]string{t.path},
+		elements...)...))
+}
+
+// branchTree is a recordTree that reads the objects of a branch.  The branch
+// is not checked out so the work tree is left untouched.
+type branchTree struct {
+	g      *gitBackEnd
+	path   string // Path to repo
+	branch string // Branch name
+}
+
+// object returns the git object name of a path in the branch.  Git paths
+// always use forward slashes.
+func (t branchTree) object(elements ...string) string {
+	return t.branch + ":" + path.Join(elements...)
+}
+
+// readDir returns the entries of a directory in the branch.
+func (t branchTree) readDir(elements ...string) ([]treeEntry, error) {
+	out, err := t.g.git(t.path, "ls-tree", t.object(elements...))
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree %v: %v",
+			t.object(elements...), err)
+	}
+	entries := make([]treeEntry, 0, len(out))
+	for _, v := range out {
+		// <mode> SP <type> SP <object> TAB <name>
+		s := strings.SplitN(v, "\t", 2)
+		if len(s) != 2 {
+			return nil, fmt.Errorf("unexpected git ls-tree output: %v",
+				v)
+		}
+		fields := strings.Fields(s[0])
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git ls-tree output: %v",
+				v)
+		}
+		entries = append(entries, treeEntry{
+			name: s[1],
+			dir:  fields[1] == "tree",
+		})
+	}
+	return entries, nil
+}
+
+// readFile returns the content of a file in the branch.
+func (t branchTree) readFile(elements ...string) ([]byte, error) {
+	return t.g.gitCatFile(t.path, "blob", t.object(elements...))
+}
+
+// checker contains the state of a single consistency check.
+type checker struct {
+	g               *gitBackEnd
+	repair          bool
+	inconsistencies []Inconsistency
+}
+
+// report records an inconsistency.
+func (c *checker) report(code InconsistencyT, repo, token, version, context string) {
+	c.inconsistencies = append(c.inconsistencies, Inconsistency{
+		Code:    code,
+		Repo:    repo,
+		Token:   token,
+		Version: version,
+		Context: context,
+	})
+}
+
+// reportRepair records an inconsistency that can be repaired safely. The
+// repair function is only called when repairs were requested.
+func (c *checker) reportRepair(code InconsistencyT, repo, token, context string, repair func() error) {
+	i := Inconsistency{
+		Code:    code,
+		Repo:    repo,
+		Token:   token,
+		Context: context,
+	}
+	if c.repair {
+		err := repair()
+		if err != nil {
+			i.Context += fmt.Sprintf(" (repair failed: %v)", err)
+		} else {
+			i.Repaired = true
+		}
+	}
+	c.inconsistencies = append(c.inconsistencies, i)
+}
+
+// repoName returns the name that is used to report inconsistencies in the
+// provided repo.
+func (c *checker) repoName(path string) string {
+	if path == c.g.vetted {
+		return DefaultVettedPath
+	}
+	return DefaultUnvettedPath
+}
+
+// isToken returns true if s is a lowercase hex encoded censorship token.
+func isToken(s string) bool {
+	t, err := util.ConvertStringToken(s)
+	return err == nil && hex.EncodeToString(t) == s
+}
+
+// checkWorkTree verifies that the repo is on master and that there are no
+// uncommitted changes.
+func (c *checker) checkWorkTree(path string) error {
+	repo := c.repoName(path)
+
+	out, err := c.g.git(path, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if len(out) != 0 {
+		c.reportRepair(InconsistencyDirtyWorkTree, repo, "",
+			strings.Join(out, ", "), func() error {
+				return c.g.gitUnwind(path)
+			})
+	}
+
+	branch, err := c.g.gitBranchNow(path)
+	if err != nil {
+		return err
+	}
+	if branch != "master" {
+		c.reportRepair(InconsistencyWrongBranch, repo, "", branch,
+			func() error {
+				return c.g.gitCheckout(path, "master")
+			})
+	}
+
+	return nil
+}
+
+// checkVersion verifies a single version of a record and returns the
+// metadata stream IDs that it contains.
+func (c *checker) checkVersion(t recordTree, repo, token, version string) map[uint64]struct{} {
+	entries, err := t.readDir(token, version)
+	if err != nil {
+		c.report(InconsistencyInvalidVersion, repo, token, version,
+			err.Error())
+		return map[uint64]struct{}{}
+	}
+
+	// Record metadata
+	var brm *backend.RecordMetadata
+	var found bool
+	for _, v := range entries {
+		if v.name == defaultRecordMetadataFilename && !v.dir {
+			found = true
+			break
+		}
+	}
+	if found {
+		b, err := t.readFile(token, version,
+			defaultRecordMetadataFilename)
+		if err == nil {
+			var rm backend.RecordMetadata
+			err = json.Unmarshal(b, &rm)
+			brm = &rm
+		}
+		if err != nil {
+			c.report(InconsistencyRecordMetadataCorrupt, repo,
+				token, version, err.Error())
+			brm = nil
+		}
+	} else {
+		c.report(InconsistencyRecordMetadataMissing, repo, token,
+			version, "")
+	}
+	if brm != nil {
+		if brm.Token != token {
+			c.report(InconsistencyTokenMismatch, repo, token,
+				version, brm.Token)
+		}
+		if _, ok := backend.MDStatus[brm.Status]; !ok ||
+			brm.Status == backend.MDStatusInvalid {
+			c.report(InconsistencyInvalidStatus, repo, token,
+				version, strconv.Itoa(int(brm.Status)))
+		}
+	}
+
+	// Record files
+	files, err := t.readDir(token, version, defaultPayloadDir)
+	if err != nil || len(files) == 0 {
+		c.report(InconsistencyPayloadMissing, repo, token, version, "")
+	} else {
+		hashes := make([]*[sha256.Size]byte, 0, len(files))
+		for _, v := range files {
+			if v.dir {
+				c.report(InconsistencyPayloadMissing, repo,
+					token, version, "unexpected directory "+
+						v.name)
+				continue
+			}
+			b, err := t.readFile(token, version,
+				defaultPayloadDir, v.name)
+			if err != nil {
+				c.report(InconsistencyPayloadMissing, repo,
+					token, version, err.Error())
+				continue
+			}
+			d := sha256.Sum256(b)
+			hashes = append(hashes, &d)
+		}
+		m := merkle.Root(hashes)
+		if brm != nil && brm.Merkle != hex.EncodeToString(m[:]) {
+			c.report(InconsistencyMerkleMismatch, repo, token,
+				version, fmt.Sprintf("got %x want %v", m[:],
+					brm.Merkle))
+		}
+	}
+
+	// Metadata streams
+	streams := make(map[uint64]struct{})
+	for _, v := range entries {
+		if !strings.HasSuffix(v.name, defaultMDFilenameSuffix) {
+			continue
+		}
+		ids := strings.TrimSuffix(v.name, defaultMDFilenameSuffix)
+		id, err := strconv.ParseUint(ids, 10, 64)
+		if err != nil {
+			c.report(InconsistencyMDStreamInvalid, repo, token,
+				version, v.name)
+			continue
+		}
+		streams[id] = struct{}{}
+	}
+
+	return streams
+}
+
+// checkRecord verifies all versions of a record in the provided record tree
+// of a repo.
+func (c *checker) checkRecord(t recordTree, repo, token string) {
+	if !isToken(token) {
+		c.report(InconsistencyInvalidToken, repo, token, "", "")
+		return
+	}
+
+	entries, err := t.readDir(token)
+	if err != nil {
+		c.report(InconsistencyInvalidVersion, repo, token, "",
+			err.Error())
+		return
+	}
+	versions := make([]int, 0, len(entries))
+	for _, v := range entries {
+		version, err := strconv.Atoi(v.name)
+		if err != nil || !v.dir {
+			c.report(InconsistencyInvalidVersion, repo, token,
+				v.name, "")
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		c.report(InconsistencyInvalidVersion, repo, token, "",
+			"no versions")
+		return
+	}
+	sort.Ints(versions)
+
+	// Versions must start at 1 and may not skip. Metadata streams are
+	// carried over to the next version with the exception of the
+	// authorize vote stream which is removed when a record is edited.
+	var prev map[uint64]struct{}
+	for k, v := range versions {
+		version := strconv.Itoa(v)
+		if v != k+1 {
+			c.report(InconsistencyInvalidVersion, repo, token,
+				version, fmt.Sprintf("expected version %v",
+					k+1))
+		}
+		streams := c.checkVersion(t, repo, token, version)
+		for id := range prev {
+			if id == decredplugin.MDStreamAuthorizeVote {
+				continue
+			}
+			if _, ok := streams[id]; !ok {
+				c.report(InconsistencyMDStreamMissing, repo,
+					token, version, strconv.FormatUint(id,
+						10))
+			}
+		}
+		prev = streams
+	}
+}
+
+// checkVetted verifies all records in the vetted repo. It returns the tokens
+// of all vetted records.
+func (c *checker) checkVetted() (map[string]struct{}, error) {
+	entries, err := ioutil.ReadDir(c.g.vetted)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]struct{}, len(entries))
+	for _, v := range entries {
+		if !v.IsDir() || v.Name() == ".git" ||
+			v.Name() == defaultAnchorsDirectory {
+			continue
+		}
+		c.checkRecord(workTree{path: c.g.vetted}, DefaultVettedPath,
+			v.Name())
+		tokens[v.Name()] = struct{}{}
+	}
+
+	return tokens, nil
+}
+
+// checkUnvetted verifies that the unvetted master matches the vetted master
+// and verifies all records that reside in unvetted branches.  The branches are
+// read from the git objects and are never checked out.
+func (c *checker) checkUnvetted(vetted map[string]struct{}) error {
+	// Unvetted master must be identical to vetted master
+	out, err := c.g.git(c.g.vetted, "rev-parse", "master")
+	if err != nil {
+		return err
+	}
+	if len(out) != 1 {
+		return fmt.Errorf("unexpected git output")
+	}
+	vettedMaster := out[0]
+	out, err = c.g.git(c.g.unvetted, "rev-parse", "master")
+	if err != nil {
+		return err
+	}
+	if len(out) != 1 {
+		return fmt.Errorf("unexpected git output")
+	}
+	if out[0] != vettedMaster {
+		c.reportRepair(InconsistencyMasterOutOfSync,
+			DefaultUnvettedPath, "", fmt.Sprintf("%v != %v",
+				out[0], vettedMaster), func() error {
+				err := c.g.gitCheckout(c.g.unvetted, "master")
+				if err != nil {
+					return err
+				}
+				return c.g.gitPull(c.g.unvetted, true)
+			})
+	}
+
+	branches, err := c.g.gitBranches(c.g.unvetted)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		if branch == "master" {
+			continue
+		}
+
+		var temporary bool
+		for _, suffix := range temporaryBranchSuffixes {
+			if strings.HasSuffix(branch, suffix) {
+				temporary = true
+				break
+			}
+		}
+		if temporary {
+			b := branch
+			c.reportRepair(InconsistencyLeftoverBranch,
+				DefaultUnvettedPath, "", b, func() error {
+					return c.g.gitBranchDelete(c.g.unvetted,
+						b)
+				})
+			continue
+		}
+
+		if !isToken(branch) {
+			c.report(InconsistencyUnknownBranch,
+				DefaultUnvettedPath, "", "", branch)
+			continue
+		}
+		if _, ok := vetted[branch]; ok {
+			c.report(InconsistencyDuplicateRecord,
+				DefaultUnvettedPath, branch, "", "")
+		}
+
+		c.checkRecord(branchTree{
+			g:      c.g,
+			path:   c.g.unvetted,
+			branch: branch,
+		}, DefaultUnvettedPath, branch)
+	}
+
+	return nil
+}
+
+// checkFlushMarker verifies that a journal that is marked as flushed is
+// identical to the copy in the vetted repo. The marker of a stale journal is
+// removed during repair which causes the next flush to copy the journal.
+func (c *checker) checkFlushMarker(token, journal, marker string) {
+	if !util.FileExists(pijoin(c.g.journals, token, marker)) {
+		return
+	}
+	version, err := getLatest(pijoin(c.g.vetted, token))
+	if err != nil {
+		return
+	}
+	j, err := ioutil.ReadFile(pijoin(c.g.journals, token, journal))
+	if err != nil {
+		return
+	}
+	flushed, err := ioutil.ReadFile(pijoin(c.g.vetted, token, version,
+		pluginDataDir, journal))
+	if err == nil && bytes.Equal(j, flushed) {
+		return
+	}
+
+	c.reportRepair(InconsistencyStaleFlushMarker, DefaultJournalsPath,
+		token, journal, func() error {
+			return os.Remove(pijoin(c.g.journals, token, marker))
+		})
+}
+
+// checkSnapshot verifies that a journal snapshot matches its journal. Stale
+// snapshots are ignored during replay and are removed during repair.
+func (c *checker) checkSnapshot(token, journal, snapshot string) {
+	filename := pijoin(c.g.journals, token, snapshot)
+	var js JournalSnapshot
+	ok, err := readSnapshot(filename, &js)
+	if !ok && err == nil {
+		return
+	}
+	if err == nil {
+		err = verifySnapshot(pijoin(c.g.journals, token, journal), js)
+		if err == nil {
+			return
+		}
+	}
+
+	c.reportRepair(InconsistencyStaleSnapshot, DefaultJournalsPath,
+		token, fmt.Sprintf("%v: %v", snapshot, err), func() error {
+			return os.Remove(filename)
+		})
+}
+
+// checkJournals verifies the journals of all records.
+func (c *checker) checkJournals(vetted map[string]struct{}) error {
+	entries, err := ioutil.ReadDir(c.g.journals)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, v := range entries {
+		token := v.Name()
+		if _, ok := vetted[token]; !ok {
+			c.report(InconsistencyOrphanedJournal,
+				DefaultJournalsPath, token, "", "")
+			continue
+		}
+
+		c.checkFlushMarker(token, defaultCommentFilename,
+			defaultCommentsFlushed)
+		c.checkFlushMarker(token, defaultBallotFilename,
+			defaultBallotFlushed)
+		c.checkSnapshot(token, defaultCommentFilename,
+			defaultCommentsSnapshot)
+		c.checkSnapshot(token, defaultBallotFilename,
+			defaultBallotSnapshot)
+	}
+
+	return nil
+}
+
+// Check walks the vetted and unvetted repos and the journals that reside in
+// the provided root directory and returns all inconsistencies that were found.
+// When repair is set the inconsistencies that can be fixed safely are
+// repaired.  Inconsistencies that can't be repaired are only reported and the
+// repos are not modified.
+//
+// Check locks the root directory and returns ErrLocked if politeiad is using
+// it.
+func Check(root, gitPath string, repair bool) ([]Inconsistency, error) {
+	lock, err := lockRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	g := newOffline(root, gitPath)
+	c := checker{
+		g:               g,
+		repair:          repair,
+		inconsistencies: make([]Inconsistency, 0, 16),
+	}
+
+	// Git level checks
+	for _, path := range []string{g.vetted, g.unvetted} {
+		_, err := g.gitFsck(path)
+		if err != nil {
+			c.report(InconsistencyGitCorrupt, c.repoName(path), "",
+				"", err.Error())
+			return c.inconsistencies, nil
+		}
+		err = c.checkWorkTree(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Record level checks
+	vetted, err := c.checkVetted()
+	if err != nil {
+		return nil, err
+	}
+	err = c.checkUnvetted(vetted)
+	if err != nil {
+		return nil, err
+	}
+	err = c.checkJournals(vetted)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.inconsistencies, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+	"github.com/decred/slog"
+)
+
+// checkInconsistencies runs Check and verifies that exactly the expected
+// inconsistencies were found. The expected map contains whether the
+// inconsistency is expected to be repaired.
+func checkInconsistencies(t *testing.T, root string, repair bool, expected map[InconsistencyT]bool) {
+	t.Helper()
+
+	inconsistencies, err := Check(root, "", repair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range inconsistencies {
+		t.Logf("%v", v)
+	}
+	if len(inconsistencies) != len(expected) {
+		t.Fatalf("unexpected inconsistencies got %v want %v",
+			len(inconsistencies), len(expected))
+	}
+	for _, v := range inconsistencies {
+		repaired, ok := expected[v.Code]
+		if !ok {
+			t.Fatalf("unexpected inconsistency: %v", v)
+		}
+		if v.Repaired != repaired {
+			t.Fatalf("unexpected repair got %v want %v: %v",
+				v.Repaired, repaired, v)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet3Params, dir, "", "", nil,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+
+	// Create an unvetted and a vetted record
+	tokens := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		r, err := util.Random(64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload := hex.EncodeToString(r)
+		rm, err := g.New([]backend.MetadataStream{{
+			ID:      0,
+			Payload: "this is metadata",
+		}}, []backend.File{{
+			Name:    "index.md",
+			MIME:    mime.DetectMimeType([]byte(payload)),
+			Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+			Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, rm.Token)
+	}
+	token, err := hex.DecodeString(tokens[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyMD := []backend.MetadataStream{}
+	_, err = g.SetUnvettedStatus(token, backend.MDStatusVetted, emptyMD,
+		emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The root directory is locked while the backend is running
+	_, err = Check(dir, "", false)
+	if err != ErrLocked {
+		t.Fatalf("unexpected error got %v want %v", err, ErrLocked)
+	}
+	g.Close()
+
+	// Consistent backend
+	checkInconsistencies(t, dir, true, map[InconsistencyT]bool{})
+
+	// Corrupt the vetted record metadata
+	filename := pijoin(g.vetted, tokens[1], "1",
+		defaultRecordMetadataFilename)
+	err = ioutil.WriteFile(filename, []byte("{"), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.gitAdd(g.vetted, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.gitCommit(g.vetted, "Corrupt record metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave a temporary branch behind
+	err = g.gitNewBranch(g.unvetted, "1_flushcomments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.gitCheckout(g.unvetted, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Journals of a record that does not exist
+	err = os.MkdirAll(pijoin(g.journals, tokens[0]), 0760)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Report only
+	expected := map[InconsistencyT]bool{
+		InconsistencyRecordMetadataCorrupt: false,
+		InconsistencyMasterOutOfSync:       false,
+		InconsistencyLeftoverBranch:        false,
+		InconsistencyOrphanedJournal:       false,
+	}
+	checkInconsistencies(t, dir, false, expected)
+
+	// Repair
+	expected[InconsistencyMasterOutOfSync] = true
+	expected[InconsistencyLeftoverBranch] = true
+	checkInconsistencies(t, dir, true, expected)
+
+	// Only the inconsistencies that can't be repaired remain
+	checkInconsistencies(t, dir, false, map[InconsistencyT]bool{
+		InconsistencyRecordMetadataCorrupt: false,
+		InconsistencyOrphanedJournal:       false,
+	})
+}
@@ -7,6 +7,7 @@ package gitbe
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -14,37 +15,33 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/decred/dcrd/chaincfg/chainhash"
-	"github.com/decred/dcrd/dcrec/secp256k1"
-	"github.com/decred/dcrd/dcrutil"
-	"github.com/decred/dcrd/wire"
-	dcrdataapi "github.com/decred/dcrdata/api/types/v3"
 	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
 	"github.com/decred/politeia/util"
 )
 
-// XXX plugins really need to become an interface. Run with this for now.
-
 const (
-	decredPluginIdentity  = "fullidentity"
-	decredPluginJournals  = "journals"
-	decredPluginInventory = "inventory"
+	decredPluginIdentity = "fullidentity"
+	decredPluginJournals = "journals"
 
 	defaultCommentIDFilename = "commentid.txt"
 	defaultCommentFilename   = "comments.journal"
 	defaultCommentsFlushed   = "comments.flushed"
+	defaultCommentsSnapshot  = "comments.snapshot"
 
 	defaultBallotFilename = "ballot.journal"
 	defaultBallotFlushed  = "ballot.flushed"
+	defaultBallotSnapshot = "ballot.snapshot"
 
 	journalVersion       = "1"       // Version 1 of the comment journal
 	journalActionAdd     = "add"     // Add entry
@@ -53,8 +50,9 @@ const (
 
 	flushRecordVersion = "1" // Version 1 of the flush journal
 
-	// Following are what should be well-known interface hooks
-	PluginPostHookEdit = "postedit" // Hook Post Edit
+	// Version 1 snapshots only kept the aggregated comment likes and
+	// are discarded when they are loaded.
+	snapshotVersion = "2" // Version 2 of the journal snapshot
 )
 
 var (
@@ -73,6 +71,35 @@ type FlushRecord struct {
 	Timestamp string `json:"timestamp"` // Timestamp
 }
 
+// JournalSnapshot is stored next to a journal and records the offset up to
+// which the journal was replayed in order to create the snapshot. The digest
+// of the journal up to that offset is used to detect journals that were
+// modified after the snapshot was created, in which case the snapshot is
+// discarded and the journal is replayed in full.
+//
+// Snapshots never leave the journals directory. The journals are flushed to
+// git unmodified so that the git history remains verifiable.
+type JournalSnapshot struct {
+	Version string `json:"version"` // Version
+	Offset  int64  `json:"offset"`  // Journal offset in bytes
+	Digest  string `json:"digest"`  // SHA256 digest of journal up to offset
+}
+
+// CommentsSnapshot is the compacted state of a comments journal.  The comment
+// likes are the full like history in journal order so that the likes that are
+// returned are the same as those of a full journal replay.
+type CommentsSnapshot struct {
+	JournalSnapshot
+	Comments      map[string]decredplugin.Comment `json:"comments"`      // [commentid]comment
+	CommentsLikes []decredplugin.LikeComment      `json:"commentslikes"` // Comment likes
+}
+
+// BallotSnapshot is the compacted state of a ballot journal.
+type BallotSnapshot struct {
+	JournalSnapshot
+	Tickets []string `json:"tickets"` // Tickets that voted
+}
+
 // JournalAction prefixes and determines what the next structure is in
 // the JSON journal.
 // Version is used to determine what version of the comment journal structure
@@ -111,8 +138,8 @@ func decodeCastVoteJournal(payload []byte) (*CastVoteJournal, error) {
 }
 
 var (
-	decredPluginSettings map[string]string             // [key]setting
-	decredPluginHooks    map[string]func(string) error // [key]func(token) error
+	decredPluginSettingsMtx sync.RWMutex              // Protects decredPluginSettings
+	decredPluginSettings    = make(map[string]string) // [key]setting
 
 	// Cached values, requires lock. These caches are lazy loaded.
 	// XXX why is this a pointer? Convert if possible after investigating
@@ -135,8 +162,11 @@ var (
 	journalsReplayed bool = false
 )
 
-// init is used to pregenerate the JSON journal actions.
+// init is used to pregenerate the JSON journal actions and to register the
+// decred plugin.
 func init() {
+	backend.RegisterPlugin(decredplugin.ID, newDecredPlugin)
+
 	var err error
 
 	journalAdd, err = json.Marshal(JournalAction{
@@ -162,48 +192,185 @@ func init() {
 	}
 }
 
-func getDecredPlugin(testnet bool) backend.Plugin {
-	decredPlugin := backend.Plugin{
-		ID:       decredplugin.ID,
-		Version:  decredplugin.Version,
-		Settings: []backend.PluginSetting{},
+// decredPlugin implements the backend PluginDriver interface.  The plugin
+// state is kept in the gitBackEnd context and in the decred plugin caches.
+type decredPlugin struct {
+	g        *gitBackEnd
+	plugin   backend.Plugin
+	identity *identity.FullIdentity
+	history  func() []pd.IdentityKey
+}
+
+// newDecredPlugin returns a decred plugin context for the git backend.  Other
+// backends register their own decred plugin constructor.
+//
+// newDecredPlugin satisfies the backend PluginConstructor type.
+func newDecredPlugin(cfg backend.PluginConfig) (backend.PluginDriver, error) {
+	g, ok := cfg.Backend.(*gitBackEnd)
+	if !ok {
+		return nil, backend.ErrInvalidPlugin
 	}
 
-	if testnet {
-		decredPlugin.Settings = append(decredPlugin.Settings,
-			backend.PluginSetting{
-				Key:   "dcrdata",
-				Value: "https://testnet.dcrdata.org:443/",
-			},
-		)
-	} else {
-		decredPlugin.Settings = append(decredPlugin.Settings,
-			backend.PluginSetting{
-				Key:   "dcrdata",
-				Value: "https://explorer.dcrdata.org:443/",
-			})
-	}
-
-	// This setting is used to tell politeiad how to retrieve the
-	// decred plugin data that is required to build the external
-	// politeiad cache.
-	decredPlugin.Settings = append(decredPlugin.Settings,
-		backend.PluginSetting{
-			Key:   decredPluginInventory,
-			Value: decredplugin.CmdInventory,
-		})
+	return &decredPlugin{
+		g:        g,
+		plugin:   decredvote.DecredPlugin(cfg.TestNet, cfg.Settings),
+		identity: cfg.Identity,
+		history:  cfg.IdentityHistory,
+	}, nil
+}
+
+// Plugin returns the decred plugin identifier, version and settings.
+//
+// Plugin satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Plugin() backend.Plugin {
+	return d.plugin
+}
+
+// Setup initializes the decred plugin settings and chain source and replays
+// the comments and ballot journals.
+//
+// Setup satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Setup() error {
+	log.Tracef("decredPlugin Setup")
+
+	for _, v := range d.plugin.Settings {
+		setDecredPluginSetting(v.Key, v.Value)
+	}
+	if d.identity != nil {
+		idJSON, err := d.identity.Marshal()
+		if err != nil {
+			return err
+		}
+		setDecredPluginSetting(decredPluginIdentity, string(idJSON))
+	}
+	setDecredPluginSetting(decredPluginJournals, d.g.journals)
+
+	decredPluginSettingsMtx.RLock()
+	chain, err := decredvote.NewChainSource(decredPluginSettings)
+	decredPluginSettingsMtx.RUnlock()
+	if err != nil {
+		return fmt.Errorf("chain source: %v", err)
+	}
+	d.g.chain = chain
 
-	// Initialize hooks
-	decredPluginHooks = make(map[string]func(string) error)
+	return d.g.initDecredPluginJournals()
+}
+
+// SetIdentity replaces the identity that signs the decred plugin receipts and
+// vote bundles.  It is called when the politeiad identity is rotated.
+//
+// SetIdentity satisfies the backend IdentitySetter interface.
+func (d *decredPlugin) SetIdentity(id *identity.FullIdentity) error {
+	log.Tracef("decredPlugin SetIdentity")
+
+	idJSON, err := id.Marshal()
+	if err != nil {
+		return err
+	}
+	setDecredPluginSetting(decredPluginIdentity, string(idJSON))
+	return nil
+}
+
+// Commands returns the decred plugin commands.  Read-only commands are also
+// served by replicas.
+//
+// Commands satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Commands() []backend.PluginCmd {
+	g := d.g
+	return []backend.PluginCmd{
+		{
+			Command: decredplugin.CmdAuthorizeVote,
+			Exec:    g.pluginAuthorizeVote,
+		},
+		{
+			Command: decredplugin.CmdScheduleVote,
+			Exec:    g.pluginScheduleVote,
+		},
+		{
+			Command: decredplugin.CmdStartVote,
+			Exec:    g.pluginStartVote,
+		},
+		{
+			Command: decredplugin.CmdStartVoteRunoff,
+			Exec:    g.pluginStartVoteRunoff,
+		},
+		{
+			Command: decredplugin.CmdBallot,
+			Exec:    g.pluginBallot,
+		},
+		{
+			Command:  decredplugin.CmdProposalVotes,
+			ReadOnly: true,
+			Exec:     g.pluginProposalVotes,
+		},
+		{
+			Command:  decredplugin.CmdVoteBundle,
+			ReadOnly: true,
+			Exec: func(payload string) (string, error) {
+				return g.pluginVoteBundle(payload, d.history)
+			},
+		},
+		{
+			Command:  decredplugin.CmdBestBlock,
+			ReadOnly: true,
+			Exec: func(string) (string, error) {
+				return g.pluginBestBlock()
+			},
+		},
+		{
+			Command: decredplugin.CmdNewComment,
+			Exec:    g.pluginNewComment,
+		},
+		{
+			Command: decredplugin.CmdLikeComment,
+			Exec:    g.pluginLikeComment,
+		},
+		{
+			Command: decredplugin.CmdCensorComment,
+			Exec:    g.pluginCensorComment,
+		},
+		{
+			Command:  decredplugin.CmdGetComments,
+			ReadOnly: true,
+			Exec:     g.pluginGetComments,
+		},
+		{
+			Command:  decredplugin.CmdProposalCommentsLikes,
+			ReadOnly: true,
+			Exec:     g.pluginGetProposalCommentsLikes,
+		},
+		{
+			Command:  decredplugin.CmdInventory,
+			ReadOnly: true,
+			Exec: func(string) (string, error) {
+				return g.pluginInventory()
+			},
+		},
+		{
+			Command:  decredplugin.CmdLoadVoteResults,
+			ReadOnly: true,
+			Exec: func(string) (string, error) {
+				return g.pluginLoadVoteResults()
+			},
+		},
+	}
+}
 
-	// Initialize settings map
-	decredPluginSettings = make(map[string]string)
-	for _, v := range decredPlugin.Settings {
-		decredPluginSettings[v.Key] = v.Value
+// Hooks returns the decred plugin record hooks.
+//
+// Hooks satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Hooks() map[backend.HookT]backend.HookFunc {
+	return map[backend.HookT]backend.HookFunc{
+		backend.HookPostEdit: func(rc backend.RecordChange) error {
+			return d.g.decredPluginPostEdit(rc.Token)
+		},
 	}
-	return decredPlugin
 }
 
+// Close satisfies the backend PluginDriver interface.  The journals are
+// closed by the backend.
+func (d *decredPlugin) Close() {}
+
 // initDecredPlugin is called externally to run initial procedures
 // such as replaying journals
 func (g *gitBackEnd) initDecredPluginJournals() error {
@@ -248,6 +415,9 @@ func (g *gitBackEnd) replayAllJournals() error {
 
 //SetDecredPluginSetting removes a setting if the value is "" and adds a setting otherwise.
 func setDecredPluginSetting(key, value string) {
+	decredPluginSettingsMtx.Lock()
+	defer decredPluginSettingsMtx.Unlock()
+
 	if value == "" {
 		delete(decredPluginSettings, key)
 		return
@@ -256,8 +426,17 @@ func setDecredPluginSetting(key, value string) {
 	decredPluginSettings[key] = value
 }
 
-func setDecredPluginHook(name string, f func(string) error) {
-	decredPluginHooks[name] = f
+// decredPluginFullIdentity returns the identity that signs the decred plugin
+// receipts and vote bundles.  It is replaced when the politeiad identity is
+// rotated.
+func decredPluginFullIdentity() (*identity.FullIdentity, error) {
+	decredPluginSettingsMtx.RLock()
+	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
+	decredPluginSettingsMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("full identity not set")
+	}
+	return identity.UnmarshalFullIdentity([]byte(fiJSON))
 }
 
 func (g *gitBackEnd) propExists(repo, token string) bool {
@@ -331,236 +510,9 @@ func (g *gitBackEnd) getNewCid(token string) (string, error) {
 	return cid, nil
 }
 
-// verifyMessage verifies a message is properly signed.
-// Copied from https://github.com/decred/dcrd/blob/0fc55252f912756c23e641839b1001c21442c38a/rpcserver.go#L5605
-func (g *gitBackEnd) verifyMessage(address, message, signature string) (bool, error) {
-	// Decode the provided address.
-	addr, err := dcrutil.DecodeAddress(address)
-	if err != nil {
-		return false, fmt.Errorf("Could not decode address: %v",
-			err)
-	}
-
-	// Only P2PKH addresses are valid for signing.
-	if _, ok := addr.(*dcrutil.AddressPubKeyHash); !ok {
-		return false, fmt.Errorf("Address is not a pay-to-pubkey-hash "+
-			"address: %v", address)
-	}
-
-	// Decode base64 signature.
-	sig, err := base64.StdEncoding.DecodeString(signature)
-	if err != nil {
-		return false, fmt.Errorf("Malformed base64 encoding: %v", err)
-	}
-
-	// Validate the signature - this just shows that it was valid at all.
-	// we will compare it with the key next.
-	var buf bytes.Buffer
-	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
-	wire.WriteVarString(&buf, 0, message)
-	expectedMessageHash := chainhash.HashB(buf.Bytes())
-	pk, wasCompressed, err := secp256k1.RecoverCompact(sig,
-		expectedMessageHash)
-	if err != nil {
-		// Mirror Bitcoin Core behavior, which treats error in
-		// RecoverCompact as invalid signature.
-		return false, nil
-	}
-
-	// Reconstruct the pubkey hash.
-	dcrPK := pk
-	var serializedPK []byte
-	if wasCompressed {
-		serializedPK = dcrPK.SerializeCompressed()
-	} else {
-		serializedPK = dcrPK.SerializeUncompressed()
-	}
-	a, err := dcrutil.NewAddressSecpPubKey(serializedPK, g.activeNetParams)
-	if err != nil {
-		// Again mirror Bitcoin Core behavior, which treats error in
-		// public key reconstruction as invalid signature.
-		return false, nil
-	}
-
-	// Return boolean if addresses match.
-	return a.EncodeAddress() == address, nil
-}
-
-func bestBlock() (*dcrdataapi.BlockDataBasic, error) {
-	url := decredPluginSettings["dcrdata"] + "api/block/best"
-	log.Debugf("connecting to %v", url)
-	// XXX this http command needs a reasonable timeout.
-	r, err := http.Get(url)
-	log.Debugf("http connecting to %v", url)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	var bdb dcrdataapi.BlockDataBasic
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&bdb); err != nil {
-		return nil, err
-	}
-
-	return &bdb, nil
-}
-
-func block(block uint32) (*dcrdataapi.BlockDataBasic, error) {
-	h := strconv.FormatUint(uint64(block), 10)
-	url := decredPluginSettings["dcrdata"] + "api/block/" + h
-	log.Debugf("connecting to %v", url)
-	r, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	var bdb dcrdataapi.BlockDataBasic
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&bdb); err != nil {
-		return nil, err
-	}
-
-	return &bdb, nil
-}
-
-func snapshot(hash string) ([]string, error) {
-	url := decredPluginSettings["dcrdata"] + "api/stake/pool/b/" + hash +
-		"/full?sort=true"
-	log.Debugf("connecting to %v", url)
-	r, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	var tickets []string
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&tickets); err != nil {
-		return nil, err
-	}
-
-	return tickets, nil
-}
-
-func batchTransactions(hashes []string) ([]dcrdataapi.TrimmedTx, error) {
-	// Request body is dcrdataapi.Txns marshalled to JSON
-	reqBody, err := json.Marshal(dcrdataapi.Txns{
-		Transactions: hashes,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	// Make the POST request
-	url := decredPluginSettings["dcrdata"] + "api/txs/trimmed"
-	log.Debugf("connecting to %v", url)
-	r, err := http.Post(url, "application/json; charset=utf-8",
-		bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	// Unmarshal the response
-	var ttx []dcrdataapi.TrimmedTx
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&ttx); err != nil {
-		return nil, err
-	}
-	return ttx, nil
-}
-
-// largestCommitmentResult returns the largest commitment address or an error.
-type largestCommitmentResult struct {
-	bestAddr string
-	err      error
-}
-
-func largestCommitmentAddresses(hashes []string) ([]largestCommitmentResult, error) {
-	// Batch request all of the transaction info from dcrdata.
-	ttxs, err := batchTransactions(hashes)
-	if err != nil {
-		return nil, err
-	}
-
-	// Find largest commitment address for each transaction.
-	r := make([]largestCommitmentResult, len(hashes))
-	for i := range ttxs {
-		// Best is address with largest commit amount.
-		var bestAddr string
-		var bestAmount float64
-		for _, v := range ttxs[i].Vout {
-			if v.ScriptPubKeyDecoded.CommitAmt == nil {
-				continue
-			}
-			if *v.ScriptPubKeyDecoded.CommitAmt > bestAmount {
-				if len(v.ScriptPubKeyDecoded.Addresses) == 0 {
-					// jrick, does this need to be printed?
-					log.Errorf("unexpected addresses "+
-						"length: %v", ttxs[i].TxID)
-					continue
-				}
-				bestAddr = v.ScriptPubKeyDecoded.Addresses[0]
-				bestAmount = *v.ScriptPubKeyDecoded.CommitAmt
-			}
-		}
-
-		if bestAddr == "" || bestAmount == 0.0 {
-			r[i].err = fmt.Errorf("no best commitment address found: %v",
-				ttxs[i].TxID)
-			continue
-		}
-		r[i].bestAddr = bestAddr
-	}
-
-	return r, nil
-}
-
-// pluginBestBlock returns current best block height from wallet.
+// pluginBestBlock returns current best block height of the chain source.
 func (g *gitBackEnd) pluginBestBlock() (string, error) {
-	bb, err := bestBlock()
+	bb, err := g.chain.BestBlock()
 	if err != nil {
 		return "", err
 	}
@@ -1005,15 +957,47 @@ func (g *gitBackEnd) decredPluginJournalFlusher() {
 	if err != nil {
 		log.Errorf("decredPluginVoteFlusher: %v", err)
 	}
+	err = g.snapshotJournals()
+	if err != nil {
+		log.Errorf("decredPluginJournalSnapshot: %v", err)
+	}
 }
 
-func (g *gitBackEnd) pluginNewComment(payload string) (string, error) {
-	// XXX this should become part of some sort of context
-	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
-	if !ok {
-		return "", fmt.Errorf("full identity not set")
+// snapshotJournals updates the snapshots of all comment and ballot journals.
+// Snapshots allow replayAllJournals to only replay the journal entries that
+// were added after the snapshots were created.  The journals are read without
+// registering them as open so concurrent journal writes never fail with
+// ErrBusy.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) snapshotJournals() error {
+	log.Tracef("snapshotJournals")
+
+	dirs, err := ioutil.ReadDir(g.journals)
+	if err != nil {
+		return err
+	}
+	for _, v := range dirs {
+		token := v.Name()
+		if !g.propExists(g.vetted, token) {
+			continue
+		}
+
+		err = g.snapshotComments(token)
+		if err != nil {
+			log.Errorf("snapshotComments %v: %v", token, err)
+		}
+		err = g.snapshotBallot(token)
+		if err != nil {
+			log.Errorf("snapshotBallot %v: %v", token, err)
+		}
 	}
-	fi, err := identity.UnmarshalFullIdentity([]byte(fiJSON))
+
+	return nil
+}
+
+func (g *gitBackEnd) pluginNewComment(payload string) (string, error) {
+	fi, err := decredPluginFullIdentity()
 	if err != nil {
 		return "", err
 	}
@@ -1118,12 +1102,7 @@ func (g *gitBackEnd) pluginLikeComment(payload string) (string, error) {
 		return "", backend.ErrJournalsNotReplayed
 	}
 
-	// XXX this should become part of some sort of context
-	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
-	if !ok {
-		return "", fmt.Errorf("full identity not set")
-	}
-	fi, err := identity.UnmarshalFullIdentity([]byte(fiJSON))
+	fi, err := decredPluginFullIdentity()
 	if err != nil {
 		return "", err
 	}
@@ -1232,14 +1211,9 @@ func (g *gitBackEnd) pluginCensorComment(payload string) (string, error) {
 		return "", backend.ErrJournalsNotReplayed
 	}
 
-	// XXX this should become part of some sort of context
-	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
-	if !ok {
-		return "", fmt.Errorf("full identity not set")
-	}
-	fi, err := identity.UnmarshalFullIdentity([]byte(fiJSON))
+	fi, err := decredPluginFullIdentity()
 	if err != nil {
-		return "", fmt.Errorf("UnmarshalFullIdentity: %v", err)
+		return "", err
 	}
 
 	// Decode censor comment
@@ -1366,46 +1340,150 @@ func encodeGetCommentsReply(cm map[string]decredplugin.Comment) (string, error)
 	return string(gcrb), nil
 }
 
-// replayComments replay the comments for a given proposal
-// the proposal is matched by the provided token
-// this function can be called WITHOUT the lock held
-func (g *gitBackEnd) replayComments(token string) (map[string]decredplugin.Comment, error) {
-	log.Debugf("replayComments %s", token)
-	// Verify proposal exists, we can run this lockless
-	if !g.propExists(g.vetted, token) {
-		return nil, nil
+// journalDigest returns the hex encoded SHA256 digest of the first offset
+// bytes of a journal.
+func journalDigest(filename string, offset int64) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
 	}
+	defer f.Close()
 
-	// Do some cheap things before expensive calls
-	cfilename := pijoin(g.journals, token,
-		defaultCommentFilename)
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(f, offset))
+	if err != nil {
+		return "", err
+	}
+	if n != offset {
+		return "", fmt.Errorf("journal shorter than offset: %v %v", n,
+			offset)
+	}
 
-	// Replay journal
-	err := g.journal.Open(cfilename)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifySnapshot returns an error if the snapshot was not created from the
+// provided journal.
+func verifySnapshot(filename string, js JournalSnapshot) error {
+	if js.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %v",
+			js.Version)
+	}
+
+	digest, err := journalDigest(filename, js.Offset)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("journal.Open: %v", err)
-		}
-		return nil, nil
+		return err
 	}
-	defer func() {
-		err = g.journal.Close(cfilename)
-		if err != nil {
-			log.Errorf("journal.Close: %v", err)
+	if digest != js.Digest {
+		return fmt.Errorf("journal digest mismatch")
+	}
+
+	return nil
+}
+
+// readSnapshot decodes a snapshot file into snapshot. It returns false if the
+// snapshot file does not exist.
+func readSnapshot(filename string, snapshot interface{}) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
 		}
-	}()
+		return false, err
+	}
+	defer f.Close()
 
-	comments := make(map[string]decredplugin.Comment)
-	commentsLikes := make([]decredplugin.LikeComment, 0, 1024)
+	d := json.NewDecoder(f)
+	err = d.Decode(snapshot)
+	if err != nil {
+		return false, err
+	}
 
-	for {
-		err = g.journal.Replay(cfilename, func(s string) error {
-			ss := bytes.NewReader([]byte(s))
-			d := json.NewDecoder(ss)
+	return true, nil
+}
 
-			// Decode action
+// writeSnapshot atomically replaces a snapshot file with the JSON encoded
+// snapshot.
+func writeSnapshot(filename string, snapshot interface{}) error {
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	e := json.NewEncoder(f)
+	err = e.Encode(snapshot)
+	if err == nil {
+		err = f.Sync()
+	}
+	cerr := f.Close()
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// newCommentsSnapshot returns an empty comments snapshot.
+func newCommentsSnapshot() CommentsSnapshot {
+	return CommentsSnapshot{
+		JournalSnapshot: JournalSnapshot{
+			Version: snapshotVersion,
+		},
+		Comments:      make(map[string]decredplugin.Comment),
+		CommentsLikes: make([]decredplugin.LikeComment, 0, 1024),
+	}
+}
+
+// loadCommentsSnapshot returns the comments snapshot of the provided proposal.
+// An empty snapshot is returned if there is no snapshot or if the snapshot
+// does not match the comments journal.
+func (g *gitBackEnd) loadCommentsSnapshot(token string) CommentsSnapshot {
+	cs := newCommentsSnapshot()
+	ok, err := readSnapshot(pijoin(g.journals, token,
+		defaultCommentsSnapshot), &cs)
+	if err != nil {
+		log.Errorf("loadCommentsSnapshot %v: %v", token, err)
+		return newCommentsSnapshot()
+	}
+	if !ok {
+		return cs
+	}
+
+	err = verifySnapshot(pijoin(g.journals, token, defaultCommentFilename),
+		cs.JournalSnapshot)
+	if err != nil {
+		log.Infof("Discarding comments snapshot %v: %v", token, err)
+		return newCommentsSnapshot()
+	}
+	if cs.Comments == nil {
+		cs.Comments = make(map[string]decredplugin.Comment)
+	}
+
+	return cs
+}
+
+// replayCommentsJournal replays the comments journal on top of the provided
+// snapshot, starting at the snapshot offset, and returns the resulting
+// snapshot. The digest of the returned snapshot is not set. It returns nil if
+// the journal does not exist.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) replayCommentsJournal(filename string, cs CommentsSnapshot) (*CommentsSnapshot, error) {
+	comments := cs.Comments
+	commentsLikes := cs.CommentsLikes
+
+	offset, err := g.journal.ReplayFrom(filename, cs.Offset,
+		func(s string) error {
+			ss := bytes.NewReader([]byte(s))
+			d := json.NewDecoder(ss)
+
+			// Decode action
 			var action JournalAction
-			err = d.Decode(&action)
+			err := d.Decode(&action)
 			if err != nil {
 				return fmt.Errorf("journal action: %v", err)
 			}
@@ -1466,19 +1544,76 @@ func (g *gitBackEnd) replayComments(token string) (map[string]decredplugin.Comme
 			}
 			return nil
 		})
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("journal.ReplayFrom: %v", err)
+	}
+
+	return &CommentsSnapshot{
+		JournalSnapshot: JournalSnapshot{
+			Version: snapshotVersion,
+			Offset:  offset,
+		},
+		Comments:      comments,
+		CommentsLikes: commentsLikes,
+	}, nil
+}
+
+// replayComments replay the comments for a given proposal
+// the proposal is matched by the provided token
+// this function can be called WITHOUT the lock held
+func (g *gitBackEnd) replayComments(token string) (map[string]decredplugin.Comment, error) {
+	log.Debugf("replayComments %s", token)
+	// Verify proposal exists, we can run this lockless
+	if !g.propExists(g.vetted, token) {
+		return nil, nil
+	}
+
+	// Replay journal entries that are not part of the snapshot
+	cfilename := pijoin(g.journals, token,
+		defaultCommentFilename)
+	cs, err := g.replayCommentsJournal(cfilename,
+		g.loadCommentsSnapshot(token))
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, nil
 	}
 
 	g.Lock()
-	decredPluginCommentsCache[token] = comments
-	decredPluginCommentsLikesCache[token] = commentsLikes
+	decredPluginCommentsCache[token] = cs.Comments
+	decredPluginCommentsLikesCache[token] = cs.CommentsLikes
 	g.Unlock()
 
-	return comments, nil
+	return cs.Comments, nil
+}
+
+// snapshotComments updates the comments snapshot of the provided proposal if
+// the comments journal grew since the snapshot was created.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) snapshotComments(token string) error {
+	cfilename := pijoin(g.journals, token, defaultCommentFilename)
+	prev := g.loadCommentsSnapshot(token)
+	offset := prev.Offset
+	cs, err := g.replayCommentsJournal(cfilename, prev)
+	if err != nil {
+		return err
+	}
+	if cs == nil || cs.Offset == offset {
+		return nil
+	}
+
+	cs.Digest, err = journalDigest(cfilename, cs.Offset)
+	if err != nil {
+		return err
+	}
+
+	return writeSnapshot(pijoin(g.journals, token,
+		defaultCommentsSnapshot), cs)
 }
 
 // pluginGetProposalCommentLikes return all UserCommentVotes for a given proposal
@@ -1539,14 +1674,9 @@ func (g *gitBackEnd) pluginAuthorizeVote(payload string) (string, error) {
 	}
 
 	// Get identity
-	// XXX this should become part of some sort of context
-	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
-	if !ok {
-		return "", fmt.Errorf("full identity not set")
-	}
-	fi, err := identity.UnmarshalFullIdentity([]byte(fiJSON))
+	fi, err := decredPluginFullIdentity()
 	if err != nil {
-		return "", fmt.Errorf("UnmarshalFullIdentity: %v", err)
+		return "", err
 	}
 
 	// Sign signature
@@ -1621,96 +1751,245 @@ func (g *gitBackEnd) pluginAuthorizeVote(payload string) (string, error) {
 	return string(avrb), nil
 }
 
-func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
-	vote, err := decredplugin.DecodeStartVote([]byte(payload))
-	if err != nil {
-		return "", fmt.Errorf("DecodeStartVote %v", err)
-	}
-
-	// Verify vote bits are somewhat sane
-	for _, v := range vote.Vote.Options {
-		err = _validateVoteBit(vote.Vote, v.Bits)
-		if err != nil {
-			return "", fmt.Errorf("invalid vote bits: %v", err)
-		}
-	}
-
-	// Verify proposal exists
-	tokenB, err := util.ConvertStringToken(vote.Vote.Token)
-	if err != nil {
-		return "", fmt.Errorf("ConvertStringToken %v", err)
-	}
-	token := vote.Vote.Token
-
-	if !g.propExists(g.vetted, token) {
-		return "", fmt.Errorf("unknown proposal: %v", token)
-	}
-
+// voteSnapshot returns the start vote reply of a vote with the passed in
+// duration.  The vote starts at the ticket pool snapshot of the start height
+// minus the ticket maturity.  The best block is used as the start height when
+// startHeight is 0.  A scheduled start height must have been reached.
+func (g *gitBackEnd) voteSnapshot(token string, startHeight, duration uint32) (*decredplugin.StartVoteReply, error) {
 	// 1. Get best block
-	bb, err := bestBlock()
+	bb, err := g.chain.BestBlock()
 	if err != nil {
-		return "", fmt.Errorf("bestBlock %v", err)
+		return nil, fmt.Errorf("bestBlock %v", err)
 	}
-	if bb.Height < uint32(g.activeNetParams.TicketMaturity) {
-		return "", fmt.Errorf("invalid height")
+	if startHeight == 0 {
+		startHeight = bb.Height
+	} else if bb.Height < startHeight {
+		return nil, fmt.Errorf("vote start height not reached: %v "+
+			"(best block %v)", startHeight, bb.Height)
+	} else if bb.Height > startHeight+decredplugin.ScheduleVoteMaxDelay {
+		// The voting period would be shortened
+		return nil, fmt.Errorf("vote start height missed: %v "+
+			"(best block %v)", startHeight, bb.Height)
+	}
+	if startHeight < uint32(g.activeNetParams.TicketMaturity) {
+		return nil, fmt.Errorf("invalid height")
 	}
 	// 2. Subtract TicketMaturity from block height to get into
 	// unforkable teritory
-	snapshotBlock, err := block(bb.Height -
+	snapshotBlock, err := g.chain.Block(startHeight -
 		uint32(g.activeNetParams.TicketMaturity))
 	if err != nil {
-		return "", fmt.Errorf("bestBlock %v", err)
+		return nil, fmt.Errorf("block %v", err)
 	}
 	// 3. Get ticket pool snapshot
-	snapshot, err := snapshot(snapshotBlock.Hash)
+	snapshot, err := g.chain.Snapshot(snapshotBlock.Hash)
 	if err != nil {
-		return "", fmt.Errorf("snapshot %v", err)
+		return nil, fmt.Errorf("snapshot %v", err)
 	}
 	if len(snapshot) == 0 {
-		return "", fmt.Errorf("no eligible voters for %v", token)
+		return nil, fmt.Errorf("no eligible voters for %v", token)
 	}
 
 	// Make sure vote duration is within min/max range
 	// XXX calculate this value for testnet instead of using hard coded values.
-	if vote.Vote.Duration < decredplugin.VoteDurationMin ||
-		vote.Vote.Duration > decredplugin.VoteDurationMax {
+	if duration < decredplugin.VoteDurationMin ||
+		duration > decredplugin.VoteDurationMax {
 		// XXX return a user error instead of an internal error
-		return "", fmt.Errorf("invalid duration: %v (%v - %v)",
-			vote.Vote.Duration, decredplugin.VoteDurationMin,
+		return nil, fmt.Errorf("invalid duration: %v (%v - %v)",
+			duration, decredplugin.VoteDurationMin,
 			decredplugin.VoteDurationMax)
 	}
 
-	svr := decredplugin.StartVoteReply{
+	return &decredplugin.StartVoteReply{
 		Version: decredplugin.VersionStartVoteReply,
 		StartBlockHeight: strconv.FormatUint(uint64(snapshotBlock.Height),
 			10),
 		StartBlockHash: snapshotBlock.Hash,
 		// On EndHeight: we start in the past, add maturity to correct
 		EndHeight: strconv.FormatUint(uint64(snapshotBlock.Height+
-			vote.Vote.Duration+
-			uint32(g.activeNetParams.TicketMaturity)), 10),
+			duration+uint32(g.activeNetParams.TicketMaturity)), 10),
 		EligibleTickets: snapshot,
+	}, nil
+}
+
+// _scheduleVote returns the vote schedule of the latest version of a
+// proposal.  nil is returned when the vote has never been scheduled.
+//
+// This function must be called with the lock held.
+func (g *gitBackEnd) _scheduleVote(token string) (*decredplugin.ScheduleVote, error) {
+	b, err := ioutil.ReadFile(pijoin(joinLatest(g.vetted, token),
+		fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteSchedule,
+			defaultMDFilenameSuffix)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("readfile schedulevote: %v", err)
 	}
-	svrb, err := decredplugin.EncodeStartVoteReply(svr)
+	sv, err := decredplugin.DecodeScheduleVote(b)
 	if err != nil {
-		return "", fmt.Errorf("EncodeStartVoteReply: %v", err)
+		return nil, fmt.Errorf("DecodeScheduleVote: %v", err)
 	}
+	return sv, nil
+}
 
-	// Add version to on disk structure
-	vote.Version = decredplugin.VersionStartVote
-	voteb, err := decredplugin.EncodeStartVote(*vote)
+// pluginScheduleVote updates the vetted repo with the vote schedule of a
+// proposal.  A vote is scheduled by an admin and can be rescheduled until it
+// has been started.  A scheduled vote can be cancelled before its start
+// height and fails once it can no longer be started in time.  A cancelled or
+// failed schedule keeps the start height and start vote of the schedule.
+func (g *gitBackEnd) pluginScheduleVote(payload string) (string, error) {
+	log.Tracef("pluginScheduleVote")
+
+	// Decode schedule vote
+	schedule, err := decredplugin.DecodeScheduleVote([]byte(payload))
 	if err != nil {
-		return "", fmt.Errorf("EncodeStartVote: %v", err)
+		return "", fmt.Errorf("DecodeScheduleVote %v", err)
+	}
+	token := schedule.Token
+
+	// Verify proposal exists
+	if !g.propExists(g.vetted, token) {
+		return "", fmt.Errorf("unknown proposal: %v", token)
+	}
+
+	// Verify schedule
+	maturity := uint32(g.activeNetParams.TicketMaturity)
+	switch schedule.Action {
+	case decredplugin.ScheduleVoteActionSchedule:
+		if schedule.StartVote.Vote.Token != token {
+			return "", fmt.Errorf("start vote token mismatch: %v",
+				schedule.StartVote.Vote.Token)
+		}
+		err = decredplugin.ValidateVote(schedule.StartVote.Vote)
+		if err != nil {
+			return "", fmt.Errorf("invalid vote: %v", err)
+		}
+		if schedule.StartHeight < maturity {
+			return "", fmt.Errorf("invalid start height: %v",
+				schedule.StartHeight)
+		}
+	case decredplugin.ScheduleVoteActionCancel:
+	case decredplugin.ScheduleVoteActionFail:
+	default:
+		return "", fmt.Errorf("invalid schedule vote action: %v",
+			schedule.Action)
+	}
+
+	// The start height must not have been reached yet, unless the
+	// schedule fails
+	bb, err := g.chain.BestBlock()
+	if err != nil {
+		return "", fmt.Errorf("bestBlock %v", err)
+	}
+
+	// Get identity
+	fi, err := decredPluginFullIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	// Sign signature
+	r := fi.SignMessage([]byte(schedule.Signature))
+	receipt := hex.EncodeToString(r[:])
+
+	tokenb, err := util.ConvertStringToken(token)
+	if err != nil {
+		return "", fmt.Errorf("ConvertStringToken %v", err)
 	}
 
 	// Verify proposal state
 	g.Lock()
 	defer g.Unlock()
 	if g.shutdown {
-		// Make sure we are not shutting down
 		return "", backend.ErrShutdown
 	}
 
+	err = g._validateStartVoteState(token)
+	if err != nil {
+		return "", err
+	}
+	current, err := g._scheduleVote(token)
+	if err != nil {
+		return "", err
+	}
+
+	// Create on disk structure
+	sv := decredplugin.ScheduleVote{
+		Version:     decredplugin.VersionScheduleVote,
+		Receipt:     receipt,
+		Timestamp:   time.Now().Unix(),
+		Action:      schedule.Action,
+		Token:       token,
+		StartHeight: schedule.StartHeight,
+		StartVote:   schedule.StartVote,
+		Signature:   schedule.Signature,
+		PublicKey:   schedule.PublicKey,
+	}
+	if sv.Action != decredplugin.ScheduleVoteActionSchedule {
+		if current == nil ||
+			current.Action != decredplugin.ScheduleVoteActionSchedule {
+			return "", fmt.Errorf("proposal vote not scheduled: %v",
+				token)
+		}
+		if schedule.StartHeight != current.StartHeight {
+			return "", fmt.Errorf("start height mismatch: got %v, "+
+				"want %v", schedule.StartHeight, current.StartHeight)
+		}
+		sv.StartVote = current.StartVote
+	}
+	switch {
+	case sv.Action == decredplugin.ScheduleVoteActionFail &&
+		bb.Height <= sv.StartHeight+decredplugin.ScheduleVoteMaxDelay:
+		return "", fmt.Errorf("scheduled vote can still be started: "+
+			"%v (best block %v)", sv.StartHeight, bb.Height)
+	case sv.Action != decredplugin.ScheduleVoteActionFail &&
+		bb.Height >= sv.StartHeight:
+		return "", fmt.Errorf("vote start height reached: %v "+
+			"(best block %v)", sv.StartHeight, bb.Height)
+	}
+	sv.StartVote.Version = decredplugin.VersionStartVote
+	svb, err := decredplugin.EncodeScheduleVote(sv)
+	if err != nil {
+		return "", fmt.Errorf("EncodeScheduleVote: %v", err)
+	}
+
+	// Update metadata
+	err = g._updateVettedMetadata(tokenb, nil, []backend.MetadataStream{
+		{
+			ID:      decredplugin.MDStreamVoteSchedule,
+			Payload: string(svb),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("_updateVettedMetadata: %v", err)
+	}
+
+	// Prepare reply
+	svr := decredplugin.ScheduleVoteReply{
+		Action:         sv.Action,
+		StartHeight:    sv.StartHeight,
+		SnapshotHeight: sv.StartHeight - maturity,
+		Receipt:        sv.Receipt,
+		Timestamp:      sv.Timestamp,
+	}
+	svrb, err := decredplugin.EncodeScheduleVoteReply(svr)
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("Vote %v for %v at height %v", sv.Action, token,
+		sv.StartHeight)
+
+	return string(svrb), nil
+}
+
+// _validateStartVoteState ensures that the vote of a proposal has been
+// authorized, that the authorization has not been revoked and that the vote
+// has not been started yet.
+//
+// This function must be called with the lock held.
+func (g *gitBackEnd) _validateStartVoteState(token string) error {
 	_, err1 := os.Stat(pijoin(joinLatest(g.vetted, token),
 		fmt.Sprintf("%02v%v", decredplugin.MDStreamAuthorizeVote,
 			defaultMDFilenameSuffix)))
@@ -1723,17 +2002,17 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 
 	if err1 != nil {
 		// Authorize vote md is not present
-		return "", fmt.Errorf("no authorize vote metadata: %v",
+		return fmt.Errorf("no authorize vote metadata: %v",
 			token)
 	} else if err2 != nil && err3 != nil {
 		// Vote has not started, continue
 	} else if err2 == nil && err3 == nil {
 		// Vote has started
-		return "", fmt.Errorf("proposal vote already started: %v",
+		return fmt.Errorf("proposal vote already started: %v",
 			token)
 	} else {
 		// This is bad, both files should exist or not exist
-		return "", fmt.Errorf("proposal is unknown vote state: %v",
+		return fmt.Errorf("proposal is unknown vote state: %v",
 			token)
 	}
 
@@ -1742,14 +2021,109 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 		fmt.Sprintf("%02v%v", decredplugin.MDStreamAuthorizeVote,
 			defaultMDFilenameSuffix)))
 	if err != nil {
-		return "", fmt.Errorf("readfile authorizevote: %v", err)
+		return fmt.Errorf("readfile authorizevote: %v", err)
 	}
 	av, err := decredplugin.DecodeAuthorizeVote(b)
 	if err != nil {
-		return "", fmt.Errorf("DecodeAuthorizeVote: %v", err)
+		return fmt.Errorf("DecodeAuthorizeVote: %v", err)
 	}
 	if av.Action == decredplugin.AuthVoteActionRevoke {
-		return "", fmt.Errorf("vote authorization revoked")
+		return fmt.Errorf("vote authorization revoked")
+	}
+
+	return nil
+}
+
+func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
+	vote, err := decredplugin.DecodeStartVote([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeStartVote %v", err)
+	}
+
+	// Verify vote options are valid for the vote type
+	err = decredplugin.ValidateVote(vote.Vote)
+	if err != nil {
+		return "", fmt.Errorf("invalid vote: %v", err)
+	}
+
+	// Verify proposal exists
+	tokenB, err := util.ConvertStringToken(vote.Vote.Token)
+	if err != nil {
+		return "", fmt.Errorf("ConvertStringToken %v", err)
+	}
+	token := vote.Vote.Token
+
+	if !g.propExists(g.vetted, token) {
+		return "", fmt.Errorf("unknown proposal: %v", token)
+	}
+
+	// A scheduled vote starts at its scheduled start height
+	g.Lock()
+	schedule, err := g._scheduleVote(token)
+	g.Unlock()
+	if err != nil {
+		return "", err
+	}
+	var startHeight uint32
+	if schedule != nil &&
+		schedule.Action == decredplugin.ScheduleVoteActionSchedule {
+		startHeight = schedule.StartHeight
+	}
+
+	// Get ticket pool snapshot
+	svr, err := g.voteSnapshot(token, startHeight, vote.Vote.Duration)
+	if err != nil {
+		return "", err
+	}
+	svrb, err := decredplugin.EncodeStartVoteReply(*svr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVoteReply: %v", err)
+	}
+
+	// Add version to on disk structure
+	vote.Version = decredplugin.VersionStartVote
+	voteb, err := decredplugin.EncodeStartVote(*vote)
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVote: %v", err)
+	}
+
+	// Verify proposal state
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		// Make sure we are not shutting down
+		return "", backend.ErrShutdown
+	}
+
+	err = g._validateStartVoteState(token)
+	if err != nil {
+		return "", err
+	}
+
+	// Ensure the vote schedule did not change while the snapshot was
+	// taken and that the vote matches its schedule.  The start vote of
+	// a cancelled schedule can not be used to start the vote.
+	current, err := g._scheduleVote(token)
+	if err != nil {
+		return "", err
+	}
+	if current != nil {
+		if schedule == nil || current.Receipt != schedule.Receipt {
+			return "", fmt.Errorf("vote schedule changed: %v", token)
+		}
+		switch {
+		case current.Action == decredplugin.ScheduleVoteActionSchedule &&
+			current.StartVote.Signature != vote.Signature:
+			return "", fmt.Errorf("start vote does not match vote "+
+				"schedule: %v", token)
+		case current.Action == decredplugin.ScheduleVoteActionCancel &&
+			current.StartVote.Signature == vote.Signature:
+			return "", fmt.Errorf("scheduled vote was cancelled: %v",
+				token)
+		case current.Action == decredplugin.ScheduleVoteActionFail &&
+			current.StartVote.Signature == vote.Signature:
+			return "", fmt.Errorf("scheduled vote failed: %v", token)
+		}
 	}
 
 	// Store snapshot in metadata
@@ -1767,7 +2141,7 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 	}
 
 	// Add vote snapshot to in-memory cache
-	decredPluginVoteSnapshotCache[token] = svr
+	decredPluginVoteSnapshotCache[token] = *svr
 
 	log.Infof("Vote started for: %v snapshot %v start %v end %v",
 		token, svr.StartBlockHash, svr.StartBlockHeight,
@@ -1777,20 +2151,180 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 	return string(svrb), nil
 }
 
+// pluginStartVoteRunoff starts the votes of the submissions of an RFP as a
+// single runoff.  All submission votes share the same ticket snapshot and
+// start and end heights.  The submission tokens are recorded in the runoff
+// metadata stream of the RFP.
+func (g *gitBackEnd) pluginStartVoteRunoff(payload string) (string, error) {
+	runoff, err := decredplugin.DecodeStartVoteRunoff([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeStartVoteRunoff %v", err)
+	}
+	rfpTokenB, err := util.ConvertStringToken(runoff.Token)
+	if err != nil {
+		return "", fmt.Errorf("ConvertStringToken %v", err)
+	}
+	if !g.propExists(g.vetted, runoff.Token) {
+		return "", fmt.Errorf("unknown proposal: %v", runoff.Token)
+	}
+	if len(runoff.StartVotes) < 2 {
+		return "", fmt.Errorf("runoff requires at least 2 votes")
+	}
+
+	// Verify the submission votes
+	duration := runoff.StartVotes[0].Vote.Duration
+	tokens := make([]string, 0, len(runoff.StartVotes))
+	unique := make(map[string]struct{}, len(runoff.StartVotes))
+	for _, sv := range runoff.StartVotes {
+		token := sv.Vote.Token
+		if token == runoff.Token {
+			return "", fmt.Errorf("rfp can not be part of its " +
+				"own runoff")
+		}
+		if _, ok := unique[token]; ok {
+			return "", fmt.Errorf("duplicate runoff token: %v", token)
+		}
+		unique[token] = struct{}{}
+		if !g.propExists(g.vetted, token) {
+			return "", fmt.Errorf("unknown proposal: %v", token)
+		}
+		if sv.Vote.Duration != duration {
+			return "", fmt.Errorf("runoff vote durations differ")
+		}
+		err = decredplugin.ValidateVote(sv.Vote)
+		if err != nil {
+			return "", fmt.Errorf("invalid vote %v: %v", token, err)
+		}
+		if decredplugin.VoteType(sv.Vote) != decredplugin.VoteTypeApproval {
+			return "", fmt.Errorf("runoff vote is not an approval "+
+				"vote: %v", token)
+		}
+		tokens = append(tokens, token)
+	}
+
+	// Get the shared ticket pool snapshot
+	svr, err := g.voteSnapshot(runoff.Token, 0, duration)
+	if err != nil {
+		return "", err
+	}
+	svrb, err := decredplugin.EncodeStartVoteReply(*svr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVoteReply: %v", err)
+	}
+	vr := decredplugin.VoteRunoff{
+		Version:          decredplugin.VersionVoteRunoff,
+		Token:            runoff.Token,
+		Tokens:           tokens,
+		StartBlockHeight: svr.StartBlockHeight,
+		StartBlockHash:   svr.StartBlockHash,
+		EndHeight:        svr.EndHeight,
+	}
+	vrb, err := decredplugin.EncodeVoteRunoff(vr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeVoteRunoff: %v", err)
+	}
+
+	// Verify proposal states
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		// Make sure we are not shutting down
+		return "", backend.ErrShutdown
+	}
+
+	_, err = os.Stat(pijoin(joinLatest(g.vetted, runoff.Token),
+		fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteRunoff,
+			defaultMDFilenameSuffix)))
+	if err == nil {
+		return "", fmt.Errorf("runoff vote already started: %v",
+			runoff.Token)
+	}
+	for _, token := range tokens {
+		err = g._validateStartVoteState(token)
+		if err != nil {
+			return "", err
+		}
+		schedule, err := g._scheduleVote(token)
+		if err != nil {
+			return "", err
+		}
+		if schedule != nil &&
+			schedule.Action == decredplugin.ScheduleVoteActionSchedule {
+			return "", fmt.Errorf("proposal vote is scheduled: %v",
+				token)
+		}
+	}
+
+	// Store the submission votes in metadata
+	for _, sv := range runoff.StartVotes {
+		token := sv.Vote.Token
+		tokenB, err := util.ConvertStringToken(token)
+		if err != nil {
+			return "", fmt.Errorf("ConvertStringToken %v", err)
+		}
+		sv.Version = decredplugin.VersionStartVote
+		voteb, err := decredplugin.EncodeStartVote(sv)
+		if err != nil {
+			return "", fmt.Errorf("EncodeStartVote: %v", err)
+		}
+		err = g._updateVettedMetadata(tokenB, nil,
+			[]backend.MetadataStream{
+				{
+					ID:      decredplugin.MDStreamVoteBits,
+					Payload: string(voteb),
+				},
+				{
+					ID:      decredplugin.MDStreamVoteSnapshot,
+					Payload: string(svrb),
+				}})
+		if err != nil {
+			return "", fmt.Errorf("_updateVettedMetadata %v: %v",
+				token, err)
+		}
+
+		// Add vote snapshot to in-memory cache
+		decredPluginVoteSnapshotCache[token] = *svr
+	}
+
+	// Store the runoff on the RFP
+	err = g._updateVettedMetadata(rfpTokenB, nil, []backend.MetadataStream{
+		{
+			ID:      decredplugin.MDStreamVoteRunoff,
+			Payload: string(vrb),
+		}})
+	if err != nil {
+		return "", fmt.Errorf("_updateVettedMetadata: %v", err)
+	}
+
+	log.Infof("Runoff vote started for: %v submissions %v snapshot %v "+
+		"start %v end %v", runoff.Token, len(tokens), svr.StartBlockHash,
+		svr.StartBlockHeight, svr.EndHeight)
+
+	reply, err := decredplugin.EncodeStartVoteRunoffReply(
+		decredplugin.StartVoteRunoffReply{
+			StartVoteReply: *svr,
+		})
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVoteRunoffReply: %v", err)
+	}
+
+	return string(reply), nil
+}
+
 // validateVoteByAddress validates that vote, as specified by the commitment
 // address with largest amount, is signed correctly.
 func (g *gitBackEnd) validateVoteByAddress(token, ticket, addr, votebit, signature string) error {
 	// Recreate message
 	msg := token + ticket + votebit
 
-	// verifyMessage expects base64 encoded sig
+	// VerifyMessage expects base64 encoded sig
 	sig, err := hex.DecodeString(signature)
 	if err != nil {
 		return err
 	}
 
 	// Verify message
-	validated, err := g.verifyMessage(addr, msg,
+	validated, err := decredvote.VerifyMessage(g.activeNetParams, addr, msg,
 		base64.StdEncoding.EncodeToString(sig))
 	if err != nil {
 		return err
@@ -1811,31 +2345,19 @@ func (i invalidVoteBitError) Error() string {
 	return i.err.Error()
 }
 
-// _validateVoteBit iterates over all vote bits and ensure the sent in vote bit
-// exists.
+// _validateVoteBit ensures that the sent in vote bit is valid for the vote
+// type.
 func _validateVoteBit(vote decredplugin.Vote, bit uint64) error {
 	if len(vote.Options) == 0 {
 		return fmt.Errorf("_validateVoteBit vote corrupt")
 	}
-	if bit == 0 {
-		return invalidVoteBitError{
-			err: fmt.Errorf("invalid bit 0x%x", bit),
-		}
-	}
-	if vote.Mask&bit != bit {
+	err := decredplugin.ValidateVoteBit(vote, bit)
+	if err != nil {
 		return invalidVoteBitError{
-			err: fmt.Errorf("invalid mask 0x%x bit 0x%x",
-				vote.Mask, bit),
+			err: err,
 		}
 	}
-	for _, v := range vote.Options {
-		if v.Bits == bit {
-			return nil
-		}
-	}
-	return invalidVoteBitError{
-		err: fmt.Errorf("bit not found 0x%x", bit),
-	}
+	return nil
 }
 
 // validateVoteBits ensures that the passed in bit is a valid vote option.
@@ -1889,42 +2411,62 @@ func (g *gitBackEnd) validateVoteBit(token, bit string) error {
 	return _validateVoteBit(sv.Vote, b)
 }
 
-// replayBallot replays voting journalfor given proposal.
-//
-// Functions must be called WITH the lock held.
-func (g *gitBackEnd) replayBallot(token string) error {
-	// Verify proposal exists, we can run this lockless
-	if !g.propExists(g.vetted, token) {
-		return nil
+// newBallotSnapshot returns an empty ballot snapshot.
+func newBallotSnapshot() BallotSnapshot {
+	return BallotSnapshot{
+		JournalSnapshot: JournalSnapshot{
+			Version: snapshotVersion,
+		},
+		Tickets: make([]string, 0, 1024),
 	}
+}
 
-	// Do some cheap things before expensive calls
-	bfilename := pijoin(g.journals, token,
-		defaultBallotFilename)
+// loadBallotSnapshot returns the ballot snapshot of the provided proposal. An
+// empty snapshot is returned if there is no snapshot or if the snapshot does
+// not match the ballot journal.
+func (g *gitBackEnd) loadBallotSnapshot(token string) BallotSnapshot {
+	bs := newBallotSnapshot()
+	ok, err := readSnapshot(pijoin(g.journals, token,
+		defaultBallotSnapshot), &bs)
+	if err != nil {
+		log.Errorf("loadBallotSnapshot %v: %v", token, err)
+		return newBallotSnapshot()
+	}
+	if !ok {
+		return bs
+	}
 
-	// Replay journal
-	err := g.journal.Open(bfilename)
+	err = verifySnapshot(pijoin(g.journals, token, defaultBallotFilename),
+		bs.JournalSnapshot)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("journal.Open: %v", err)
-		}
-		return nil
+		log.Infof("Discarding ballot snapshot %v: %v", token, err)
+		return newBallotSnapshot()
+	}
+
+	return bs
+}
+
+// replayBallotJournal replays the ballot journal on top of the provided
+// snapshot, starting at the snapshot offset, and returns the resulting
+// snapshot. The digest of the returned snapshot is not set. It returns nil if
+// the journal does not exist.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) replayBallotJournal(filename string, bs BallotSnapshot) (*BallotSnapshot, error) {
+	tickets := bs.Tickets
+	votes := make(map[string]struct{}, len(tickets))
+	for _, v := range tickets {
+		votes[v] = struct{}{}
 	}
-	defer func() {
-		err = g.journal.Close(bfilename)
-		if err != nil {
-			log.Errorf("journal.Close: %v", err)
-		}
-	}()
 
-	for {
-		err = g.journal.Replay(bfilename, func(s string) error {
+	offset, err := g.journal.ReplayFrom(filename, bs.Offset,
+		func(s string) error {
 			ss := bytes.NewReader([]byte(s))
 			d := json.NewDecoder(ss)
 
 			// Decode action
 			var action JournalAction
-			err = d.Decode(&action)
+			err := d.Decode(&action)
 			if err != nil {
 				return fmt.Errorf("journal action: %v", err)
 			}
@@ -1938,20 +2480,15 @@ func (g *gitBackEnd) replayBallot(token string) error {
 						err)
 				}
 
-				token := cvj.CastVote.Token
-				ticket := cvj.CastVote.Ticket
-				// See if the prop already exists
-				if _, ok := decredPluginVotesCache[token]; !ok {
-					// Create map to track tickets
-					decredPluginVotesCache[token] = make(map[string]struct{})
-				}
 				// See if we have a duplicate vote
-				if _, ok := decredPluginVotesCache[token][ticket]; ok {
+				ticket := cvj.CastVote.Ticket
+				if _, ok := votes[ticket]; ok {
 					log.Errorf("duplicate cast vote %v %v",
-						token, ticket)
+						cvj.CastVote.Token, ticket)
+					return nil
 				}
-				// All good, record vote in cache
-				decredPluginVotesCache[token][ticket] = struct{}{}
+				votes[ticket] = struct{}{}
+				tickets = append(tickets, ticket)
 
 			default:
 				return fmt.Errorf("invalid action: %v",
@@ -1959,16 +2496,80 @@ func (g *gitBackEnd) replayBallot(token string) error {
 			}
 			return nil
 		})
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("journal.ReplayFrom: %v", err)
+	}
+
+	return &BallotSnapshot{
+		JournalSnapshot: JournalSnapshot{
+			Version: snapshotVersion,
+			Offset:  offset,
+		},
+		Tickets: tickets,
+	}, nil
+}
+
+// replayBallot replays voting journalfor given proposal.
+//
+// Functions must be called WITHOUT the lock held.
+func (g *gitBackEnd) replayBallot(token string) error {
+	// Verify proposal exists, we can run this lockless
+	if !g.propExists(g.vetted, token) {
+		return nil
 	}
 
+	// Replay journal entries that are not part of the snapshot
+	bfilename := pijoin(g.journals, token,
+		defaultBallotFilename)
+	bs, err := g.replayBallotJournal(bfilename,
+		g.loadBallotSnapshot(token))
+	if err != nil {
+		return err
+	}
+	if bs == nil {
+		return nil
+	}
+
+	// Record votes in cache
+	votes := make(map[string]struct{}, len(bs.Tickets))
+	for _, v := range bs.Tickets {
+		votes[v] = struct{}{}
+	}
+	g.Lock()
+	decredPluginVotesCache[token] = votes
+	g.Unlock()
+
 	return nil
 }
 
+// snapshotBallot updates the ballot snapshot of the provided proposal if the
+// ballot journal grew since the snapshot was created.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) snapshotBallot(token string) error {
+	bfilename := pijoin(g.journals, token, defaultBallotFilename)
+	prev := g.loadBallotSnapshot(token)
+	offset := prev.Offset
+	bs, err := g.replayBallotJournal(bfilename, prev)
+	if err != nil {
+		return err
+	}
+	if bs == nil || bs.Offset == offset {
+		return nil
+	}
+
+	bs.Digest, err = journalDigest(bfilename, bs.Offset)
+	if err != nil {
+		return err
+	}
+
+	return writeSnapshot(pijoin(g.journals, token,
+		defaultBallotSnapshot), bs)
+}
+
 // loadVoteSnapshotCache loads the StartVoteReply from disk for the provided
 // token and adds it to the decredPluginVoteSnapshotCache.
 //
@@ -2113,18 +2714,13 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 		return "", fmt.Errorf("DecodeBallot: %v", err)
 	}
 
-	// XXX this should become part of some sort of context
-	fiJSON, ok := decredPluginSettings[decredPluginIdentity]
-	if !ok {
-		return "", fmt.Errorf("full identity not set")
-	}
-	fi, err := identity.UnmarshalFullIdentity([]byte(fiJSON))
+	fi, err := decredPluginFullIdentity()
 	if err != nil {
 		return "", err
 	}
 
 	// Get best block
-	bb, err := bestBlock()
+	bb, err := g.chain.BestBlock()
 	if err != nil {
 		return "", fmt.Errorf("bestBlock %v", err)
 	}
@@ -2135,7 +2731,7 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 	for _, v := range ballot.Votes {
 		tickets = append(tickets, v.Ticket)
 	}
-	ticketAddresses, err := largestCommitmentAddresses(tickets)
+	ticketAddresses, err := g.chain.LargestCommitmentAddresses(tickets)
 	if err != nil {
 		return "", err
 	}
@@ -2183,10 +2779,10 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 		}
 
 		// See if there was an error for this address
-		if ticketAddresses[k].err != nil {
+		if ticketAddresses[k].Err != nil {
 			t := time.Now().Unix()
 			log.Errorf("pluginBallot: ticketAddresses %v %v %v %v",
-				v.Ticket, v.Token, t, err)
+				v.Ticket, v.Token, t, ticketAddresses[k].Err)
 			br.Receipts[k].Error = fmt.Sprintf("internal error %v",
 				t)
 			continue
@@ -2195,7 +2791,7 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 
 		// Verify that vote is signed correctly
 		err = g.validateVoteByAddress(v.Token, v.Ticket,
-			ticketAddresses[k].bestAddr, v.VoteBit, v.Signature)
+			ticketAddresses[k].Address, v.VoteBit, v.Signature)
 		if err != nil {
 			t := time.Now().Unix()
 			log.Errorf("pluginBallot: validateVote %v %v %v %v",
@@ -2254,64 +2850,63 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 	return string(brb), nil
 }
 
-// tallyVotes replays the ballot journal for a proposal and tallies the votes.
+// ballotJournal replays the ballot journal for a proposal and returns the
+// cast votes along with their receipts in journal order.
 //
 // Function must be called WITH the lock held.
-func (g *gitBackEnd) tallyVotes(token string) ([]decredplugin.CastVote, error) {
+func (g *gitBackEnd) ballotJournal(token string) ([]CastVoteJournal, error) {
 	// Do some cheap things before expensive calls
 	bfilename := pijoin(g.journals, token, defaultBallotFilename)
 
 	// Replay journal
-	err := g.journal.Open(bfilename)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("journal.Open: %v", err)
-		}
-		return []decredplugin.CastVote{}, nil
-	}
-	defer func() {
-		err = g.journal.Close(bfilename)
+	cvj := make([]CastVoteJournal, 0, 41000)
+	_, err := g.journal.ReplayFrom(bfilename, 0, func(s string) error {
+		ss := bytes.NewReader([]byte(s))
+		d := json.NewDecoder(ss)
+
+		// Decode action
+		var action JournalAction
+		err := d.Decode(&action)
 		if err != nil {
-			log.Errorf("journal.Close: %v", err)
+			return fmt.Errorf("journal action: %v", err)
 		}
-	}()
 
-	cv := make([]decredplugin.CastVote, 0, 41000)
-	for {
-		err = g.journal.Replay(bfilename, func(s string) error {
-			ss := bytes.NewReader([]byte(s))
-			d := json.NewDecoder(ss)
-
-			// Decode action
-			var action JournalAction
-			err = d.Decode(&action)
+		switch action.Action {
+		case journalActionAdd:
+			var v CastVoteJournal
+			err = d.Decode(&v)
 			if err != nil {
-				return fmt.Errorf("journal action: %v", err)
+				return fmt.Errorf("journal add: %v", err)
 			}
+			cvj = append(cvj, v)
 
-			switch action.Action {
-			case journalActionAdd:
-				var cvj CastVoteJournal
-				err = d.Decode(&cvj)
-				if err != nil {
-					return fmt.Errorf("journal add: %v",
-						err)
-				}
-				cv = append(cv, cvj.CastVote)
-
-			default:
-				return fmt.Errorf("invalid action: %v",
-					action.Action)
-			}
-			return nil
-		})
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
+		default:
+			return fmt.Errorf("invalid action: %v", action.Action)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []CastVoteJournal{}, nil
 		}
+		return nil, fmt.Errorf("journal.ReplayFrom: %v", err)
 	}
 
+	return cvj, nil
+}
+
+// tallyVotes replays the ballot journal for a proposal and tallies the votes.
+//
+// Function must be called WITH the lock held.
+func (g *gitBackEnd) tallyVotes(token string) ([]decredplugin.CastVote, error) {
+	cvj, err := g.ballotJournal(token)
+	if err != nil {
+		return nil, err
+	}
+	cv := make([]decredplugin.CastVote, 0, len(cvj))
+	for _, v := range cvj {
+		cv = append(cv, v.CastVote)
+	}
 	return cv, nil
 }
 
@@ -2393,7 +2988,7 @@ nodata:
 
 // pluginInventory returns the decred plugin inventory for all proposals.  The
 // inventory consists of comments, like comments, vote authorizations, vote
-// details, and cast votes.
+// details, cast votes, runoff votes and vote schedules.
 func (g *gitBackEnd) pluginInventory() (string, error) {
 	log.Tracef("pluginInventory")
 
@@ -2446,16 +3041,26 @@ func (g *gitBackEnd) pluginInventory() (string, error) {
 	// start vote metadata
 	avPaths := make([]string, 0, len(paths))
 	svPaths := make([]string, 0, len(paths))
+	vrPaths := make([]string, 0, len(paths))
+	vsPaths := make([]string, 0, len(paths))
 	avFile := fmt.Sprintf("%02v%v", decredplugin.MDStreamAuthorizeVote,
 		defaultMDFilenameSuffix)
 	svFile := fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteBits,
 		defaultMDFilenameSuffix)
+	vrFile := fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteRunoff,
+		defaultMDFilenameSuffix)
+	vsFile := fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteSchedule,
+		defaultMDFilenameSuffix)
 	for _, v := range paths {
 		switch filepath.Base(v) {
 		case avFile:
 			avPaths = append(avPaths, v)
 		case svFile:
 			svPaths = append(svPaths, v)
+		case vrFile:
+			vrPaths = append(vrPaths, v)
+		case vsFile:
+			vsPaths = append(vsPaths, v)
 		}
 	}
 
@@ -2531,6 +3136,57 @@ func (g *gitBackEnd) pluginInventory() (string, error) {
 		})
 	}
 
+	// Compile the runoff votes. A runoff is only returned once
+	// per RFP.
+	vr := make([]decredplugin.VoteRunoff, 0, len(vrPaths))
+	runoffs := make(map[string]struct{}, len(vrPaths))
+	for _, v := range vrPaths {
+		b, err := ioutil.ReadFile(v)
+		if err != nil {
+			return "", fmt.Errorf("ReadFile %v: %v", v, err)
+		}
+		r, err := decredplugin.DecodeVoteRunoff(b)
+		if err != nil {
+			return "", fmt.Errorf("DecodeVoteRunoff: %v", err)
+		}
+		if _, ok := runoffs[r.Token]; ok {
+			continue
+		}
+		runoffs[r.Token] = struct{}{}
+		vr = append(vr, *r)
+	}
+
+	// Compile the vote schedules. The vote schedule is copied into
+	// every new record version so only the schedule of the latest
+	// version is returned.
+	latest := make(map[string]int64, len(vsPaths))
+	schedules := make(map[string]decredplugin.ScheduleVote, len(vsPaths))
+	for _, v := range vsPaths {
+		versionDir := filepath.Dir(v)
+		version, err := strconv.ParseInt(filepath.Base(versionDir), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("ParseInt %v: %v", v, err)
+		}
+		token := filepath.Base(filepath.Dir(versionDir))
+		if l, ok := latest[token]; ok && l > version {
+			continue
+		}
+		b, err := ioutil.ReadFile(v)
+		if err != nil {
+			return "", fmt.Errorf("ReadFile %v: %v", v, err)
+		}
+		sv, err := decredplugin.DecodeScheduleVote(b)
+		if err != nil {
+			return "", fmt.Errorf("DecodeScheduleVote: %v", err)
+		}
+		latest[token] = version
+		schedules[token] = *sv
+	}
+	vs := make([]decredplugin.ScheduleVote, 0, len(schedules))
+	for _, v := range schedules {
+		vs = append(vs, v)
+	}
+
 	// Compile cast votes. The in-memory votes cache does not
 	// store the full cast vote struct so we need to replay the
 	// vote journals.
@@ -2578,6 +3234,8 @@ func (g *gitBackEnd) pluginInventory() (string, error) {
 		AuthorizeVoteReplies: avr,
 		StartVoteTuples:      svt,
 		CastVotes:            votes,
+		VoteRunoffs:          vr,
+		ScheduleVotes:        vs,
 	}
 
 	payload, err := decredplugin.EncodeInventoryReply(ir)
@@ -0,0 +1,1295 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
+	"github.com/decred/slog"
+)
+
+// enableDecredPlugin enables the decred plugin on a backend.
+func enableDecredPlugin(t *testing.T, g *gitBackEnd) {
+	t.Helper()
+
+	d, err := backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend:  g,
+		DataDir:  g.root,
+		Identity: g.identity,
+		TestNet:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnablePlugin(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet3Params, dir, "", "", nil,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	// Plugins are disabled by default
+	_, _, err = g.Plugin(decredplugin.CmdInventory, "")
+	if err != backend.ErrInvalidPluginCmd {
+		t.Fatalf("expected %v got %v", backend.ErrInvalidPluginCmd, err)
+	}
+
+	enableDecredPlugin(t, g)
+	plugins, err := g.GetPlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || plugins[0].ID != decredplugin.ID {
+		t.Fatalf("unexpected plugins %v", plugins)
+	}
+	cmd, _, err := g.Plugin(decredplugin.CmdInventory, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd != decredplugin.CmdInventory {
+		t.Fatalf("unexpected command %v", cmd)
+	}
+
+	// Plugins can only be enabled once
+	d, err := backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend: g,
+		TestNet: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err == nil {
+		t.Fatalf("expected duplicate plugin error")
+	}
+
+	// Configured settings replace the defaults
+	d, err = backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend: g,
+		Settings: []backend.PluginSetting{{
+			Key:   "dcrdata",
+			Value: "https://localhost/",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dcrdata []string
+	for _, v := range d.Plugin().Settings {
+		if v.Key == "dcrdata" {
+			dcrdata = append(dcrdata, v.Value)
+		}
+	}
+	if !reflect.DeepEqual(dcrdata, []string{"https://localhost/"}) {
+		t.Fatalf("unexpected dcrdata settings %v", dcrdata)
+	}
+
+	// The decred plugin requires the git backend
+	_, err = backend.NewPlugin(decredplugin.ID, backend.PluginConfig{})
+	if err != backend.ErrInvalidPlugin {
+		t.Fatalf("expected %v got %v", backend.ErrInvalidPlugin, err)
+	}
+	_, err = backend.NewPlugin("invalid", backend.PluginConfig{})
+	if err != backend.ErrInvalidPlugin {
+		t.Fatalf("expected %v got %v", backend.ErrInvalidPlugin, err)
+	}
+}
+
+func TestJournalSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	token := "token"
+	err = os.MkdirAll(pijoin(dir, token), 0760)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &gitBackEnd{
+		journal:  NewJournal(),
+		journals: dir,
+	}
+	cfilename := pijoin(dir, token, defaultCommentFilename)
+	bfilename := pijoin(dir, token, defaultBallotFilename)
+
+	// journalEntries adds comments, likes and votes to the journals.
+	// Every comment is liked, unliked and disliked by the same key so
+	// that the snapshots must keep the full like history.
+	var likes []decredplugin.LikeComment
+	journalEntries := func(start, count int) {
+		t.Helper()
+
+		for i := start; i < start+count; i++ {
+			id := strconv.Itoa(i)
+			c, err := decredplugin.EncodeComment(decredplugin.Comment{
+				Token:     token,
+				CommentID: id,
+				Comment:   "comment " + id,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = g.journal.Journal(cfilename, string(journalAdd)+
+				string(c))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, action := range []string{"1", "1", "-1"} {
+				like := decredplugin.LikeComment{
+					Token:     token,
+					CommentID: id,
+					Action:    action,
+					PublicKey: "publickey",
+				}
+				lc, err := decredplugin.EncodeLikeComment(like)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = g.journal.Journal(cfilename,
+					string(journalAddLike)+string(lc))
+				if err != nil {
+					t.Fatal(err)
+				}
+				likes = append(likes, like)
+			}
+
+			cvj, err := encodeCastVoteJournal(CastVoteJournal{
+				CastVote: decredplugin.CastVote{
+					Token:  token,
+					Ticket: "ticket" + id,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = g.journal.Journal(bfilename, string(journalAdd)+
+				string(cvj))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// verifyReplay ensures that replaying from the snapshot yields the
+	// same state as a full replay.
+	verifyReplay := func(offset bool) {
+		t.Helper()
+
+		cs := g.loadCommentsSnapshot(token)
+		if offset != (cs.Offset != 0) {
+			t.Fatalf("unexpected comments snapshot offset %v",
+				cs.Offset)
+		}
+		csReplay, err := g.replayCommentsJournal(cfilename, cs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csFull, err := g.replayCommentsJournal(cfilename,
+			newCommentsSnapshot())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(csReplay, csFull) {
+			t.Fatalf("comments snapshot replay mismatch")
+		}
+		if !reflect.DeepEqual(csReplay.CommentsLikes, likes) {
+			t.Fatalf("comment likes replay mismatch got %v want %v",
+				len(csReplay.CommentsLikes), len(likes))
+		}
+
+		bs := g.loadBallotSnapshot(token)
+		if offset != (bs.Offset != 0) {
+			t.Fatalf("unexpected ballot snapshot offset %v",
+				bs.Offset)
+		}
+		bsReplay, err := g.replayBallotJournal(bfilename, bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bsFull, err := g.replayBallotJournal(bfilename,
+			newBallotSnapshot())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(bsReplay, bsFull) {
+			t.Fatalf("ballot snapshot replay mismatch")
+		}
+	}
+
+	// No snapshots yet
+	journalEntries(0, 10)
+	verifyReplay(false)
+
+	// Snapshot and grow the journals
+	err = g.snapshotComments(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.snapshotBallot(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalEntries(10, 10)
+	verifyReplay(true)
+
+	cs := g.loadCommentsSnapshot(token)
+	if len(cs.Comments) != 10 || len(cs.CommentsLikes) != 30 {
+		t.Fatalf("invalid comments snapshot %v %v", len(cs.Comments),
+			len(cs.CommentsLikes))
+	}
+	bs := g.loadBallotSnapshot(token)
+	if len(bs.Tickets) != 10 {
+		t.Fatalf("invalid ballot snapshot %v", len(bs.Tickets))
+	}
+
+	// Update snapshots
+	err = g.snapshotComments(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.snapshotBallot(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs = g.loadCommentsSnapshot(token)
+	if len(cs.Comments) != 20 {
+		t.Fatalf("comments snapshot not updated %v", len(cs.Comments))
+	}
+
+	// Version 1 snapshots aggregated the likes and must be discarded
+	v1 := cs
+	v1.Version = "1"
+	err = writeSnapshot(pijoin(dir, token, defaultCommentsSnapshot), v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := g.loadCommentsSnapshot(token); cs.Offset != 0 {
+		t.Fatalf("version 1 snapshot not discarded")
+	}
+
+	// Snapshots of modified journals must be discarded
+	err = os.Remove(cfilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(bfilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	likes = nil
+	journalEntries(100, 20)
+	verifyReplay(false)
+}
+
+// writeSimChain writes a simulated chain file.
+func writeSimChain(t *testing.T, filename string, sc decredvote.SimChain) {
+	t.Helper()
+
+	b, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filename, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// signVote signs the cast vote message the way dcrwallet signs messages.
+func signVote(t *testing.T, key *secp256k1.PrivateKey, cv decredplugin.CastVote) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, cv.Token+cv.Ticket+cv.VoteBit)
+	sig, err := secp256k1.SignCompact(key, chainhash.HashB(buf.Bytes()),
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(sig)
+}
+
+func TestSimChainVote(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &chaincfg.TestNet3Params
+	g, err := New(params, filepath.Join(dir, "data"), "", "", id,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	// Setup the simulated chain.  The vote snapshot is taken at the best
+	// block minus the ticket maturity.
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := dcrutil.NewAddressSecpPubKey(
+		key.PubKey().SerializeCompressed(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := hex.EncodeToString(chainhash.HashB([]byte("ticket")))
+	ticketNoCommitment := hex.EncodeToString(chainhash.HashB([]byte("nc")))
+	ticketIneligible := hex.EncodeToString(chainhash.HashB([]byte("ie")))
+	best := uint32(1000)
+	snapshotHeight := best - uint32(params.TicketMaturity)
+	sc := decredvote.SimChain{
+		Blocks: []decredvote.SimBlock{{
+			Height:     snapshotHeight,
+			Hash:       "snapshot",
+			TicketPool: []string{ticketNoCommitment, ticket},
+		}, {
+			Height: best,
+			Hash:   "best",
+		}},
+		Commitments: map[string]string{
+			ticket:           addr.EncodeAddress(),
+			ticketIneligible: addr.EncodeAddress(),
+		},
+	}
+	simChain := filepath.Join(dir, "simchain.json")
+	writeSimChain(t, simChain, sc)
+
+	// An invalid chain source fails the plugin setup
+	d, err := backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend: g,
+		TestNet: true,
+		Settings: []backend.PluginSetting{{
+			Key:   decredvote.SettingChainSource,
+			Value: "invalid",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err == nil {
+		t.Fatalf("expected invalid chain source error")
+	}
+
+	d, err = backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend:  g,
+		DataDir:  g.root,
+		Identity: g.identity,
+		TestNet:  true,
+		Settings: []backend.PluginSetting{{
+			Key:   decredvote.SettingChainSource,
+			Value: decredvote.ChainSourceSim,
+		}, {
+			Key:   decredvote.SettingSimChain,
+			Value: simChain,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Authorize and start the vote
+	user, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userSign := func(msg string) string {
+		s := user.SignMessage([]byte(msg))
+		return hex.EncodeToString(s[:])
+	}
+	token := hex.EncodeToString(newVettedRecord(t, g))
+	avb, err := decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+		Action: decredplugin.AuthVoteActionAuthorize,
+		Token:  token,
+		Signature: userSign(token + "1" +
+			decredplugin.AuthVoteActionAuthorize),
+		PublicKey: user.Public.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = g.Plugin(decredplugin.CmdAuthorizeVote, string(avb))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svb, err := decredplugin.EncodeStartVote(decredplugin.StartVote{
+		PublicKey: user.Public.String(),
+		Signature: userSign(token),
+		Vote: decredplugin.Vote{
+			Token:            token,
+			Type:             decredplugin.VoteTypeApproval,
+			Mask:             0x03,
+			Duration:         decredplugin.VoteDurationMin,
+			QuorumPercentage: 20,
+			PassPercentage:   60,
+			Options: []decredplugin.VoteOption{{
+				Id:   decredplugin.VoteOptionIDReject,
+				Bits: 0x01,
+			}, {
+				Id:   decredplugin.VoteOptionIDApprove,
+				Bits: 0x02,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reply, err := g.Plugin(decredplugin.CmdStartVote, string(svb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr, err := decredplugin.DecodeStartVoteReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svr.StartBlockHash != "snapshot" ||
+		svr.StartBlockHeight != strconv.Itoa(int(snapshotHeight)) {
+		t.Fatalf("unexpected start block %v %v", svr.StartBlockHeight,
+			svr.StartBlockHash)
+	}
+	eligible := []string{ticketNoCommitment, ticket}
+	sort.Strings(eligible)
+	if !reflect.DeepEqual(svr.EligibleTickets, eligible) {
+		t.Fatalf("unexpected eligible tickets %v", svr.EligibleTickets)
+	}
+
+	// ballot casts the votes and returns the receipt errors.
+	ballot := func(tickets ...string) []string {
+		t.Helper()
+
+		b := decredplugin.Ballot{
+			Votes: make([]decredplugin.CastVote, 0, len(tickets)),
+		}
+		for _, v := range tickets {
+			cv := decredplugin.CastVote{
+				Token:   token,
+				Ticket:  v,
+				VoteBit: "2",
+			}
+			cv.Signature = signVote(t, key, cv)
+			b.Votes = append(b.Votes, cv)
+		}
+		bb, err := decredplugin.EncodeBallot(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, reply, err := g.Plugin(decredplugin.CmdBallot, string(bb))
+		if err != nil {
+			t.Fatal(err)
+		}
+		br, err := decredplugin.DecodeBallotReply([]byte(reply))
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := make([]string, 0, len(br.Receipts))
+		for _, v := range br.Receipts {
+			errs = append(errs, v.Error)
+		}
+		return errs
+	}
+
+	errs := ballot(ticket, ticketIneligible, ticketNoCommitment)
+	if errs[0] != "" {
+		t.Fatalf("unexpected vote error: %v", errs[0])
+	}
+	if errs[1] != "ineligible ticket: "+token {
+		t.Fatalf("unexpected ineligible ticket error: %v", errs[1])
+	}
+	if errs[2] == "" {
+		t.Fatalf("expected commitment address error")
+	}
+
+	// The vote bundle tallies offline and matches the simulated chain
+	vbb, err := decredplugin.EncodeVoteBundle(decredplugin.VoteBundle{
+		Token: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reply, err = g.Plugin(decredplugin.CmdVoteBundle, string(vbb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vbr, err := decredplugin.DecodeVoteBundleReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vbr.CastVotes) != 1 ||
+		vbr.CastVotes[0].Address != addr.EncodeAddress() {
+		t.Fatalf("unexpected bundle cast votes %v", vbr.CastVotes)
+	}
+	vbt, err := decredvote.VerifyVoteBundle(vbr, params, &id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vbt.Counted != 1 || len(vbt.Rejected) != 0 ||
+		vbt.Tally.TotalVotes != 1 {
+		t.Fatalf("unexpected bundle tally %+v", vbt)
+	}
+	err = decredvote.VerifyVoteBundleChain(vbr,
+		decredvote.NewSimSource(simChain))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A bundle is never trusted on its own and a bundle whose identity
+	// history does not contain the trusted key is rejected
+	_, err = decredvote.VerifyVoteBundle(vbr, params, nil)
+	if err == nil {
+		t.Fatalf("expected missing trusted key error")
+	}
+	_, err = decredvote.VerifyVoteBundle(vbr, params, &user.Public)
+	if err == nil {
+		t.Fatalf("expected untrusted key error")
+	}
+
+	// A tampered vote fails the bundle signature and, once the bundle is
+	// signed again, the vote itself
+	vbr.CastVotes[0].CastVote.VoteBit = "1"
+	_, err = decredvote.VerifyVoteBundle(vbr, params, &id.Public)
+	if err == nil {
+		t.Fatalf("expected bundle signature error")
+	}
+	vd, err := vbr.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs := id.SignMessage(vd)
+	vbr.Signature = hex.EncodeToString(vs[:])
+	vbt, err = decredvote.VerifyVoteBundle(vbr, params, &id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vbt.Counted != 0 || len(vbt.Rejected) != 1 ||
+		vbt.Tally.TotalVotes != 0 {
+		t.Fatalf("unexpected tampered bundle tally %+v", vbt)
+	}
+
+	// Votes are rejected once the simulated chain reaches the end height
+	endHeight, err := strconv.ParseUint(svr.EndHeight, 10, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Blocks = append(sc.Blocks, decredvote.SimBlock{
+		Height: uint32(endHeight),
+		Hash:   "end",
+	})
+	writeSimChain(t, simChain, sc)
+	errs = ballot(ticket)
+	if errs[0] != "vote has ended: "+token {
+		t.Fatalf("unexpected vote error: %v", errs[0])
+	}
+}
+
+func TestRotateIdentityCastVote(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &chaincfg.TestNet3Params
+	g, err := New(params, filepath.Join(dir, "data"), "", "", id,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	// Setup the simulated chain
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := dcrutil.NewAddressSecpPubKey(
+		key.PubKey().SerializeCompressed(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := hex.EncodeToString(chainhash.HashB([]byte("ticket")))
+	best := uint32(1000)
+	sc := decredvote.SimChain{
+		Blocks: []decredvote.SimBlock{{
+			Height:     best - uint32(params.TicketMaturity),
+			Hash:       "snapshot",
+			TicketPool: []string{ticket},
+		}, {
+			Height: best,
+			Hash:   "best",
+		}},
+		Commitments: map[string]string{
+			ticket: addr.EncodeAddress(),
+		},
+	}
+	simChain := filepath.Join(dir, "simchain.json")
+	writeSimChain(t, simChain, sc)
+
+	d, err := backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend:  g,
+		DataDir:  g.root,
+		Identity: g.identity,
+		TestNet:  true,
+		Settings: []backend.PluginSetting{{
+			Key:   decredvote.SettingChainSource,
+			Value: decredvote.ChainSourceSim,
+		}, {
+			Key:   decredvote.SettingSimChain,
+			Value: simChain,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Authorize and start the vote
+	user, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userSign := func(msg string) string {
+		s := user.SignMessage([]byte(msg))
+		return hex.EncodeToString(s[:])
+	}
+	token := hex.EncodeToString(newVettedRecord(t, g))
+	avb, err := decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+		Action: decredplugin.AuthVoteActionAuthorize,
+		Token:  token,
+		Signature: userSign(token + "1" +
+			decredplugin.AuthVoteActionAuthorize),
+		PublicKey: user.Public.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = g.Plugin(decredplugin.CmdAuthorizeVote, string(avb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svb, err := decredplugin.EncodeStartVote(decredplugin.StartVote{
+		PublicKey: user.Public.String(),
+		Signature: userSign(token),
+		Vote: decredplugin.Vote{
+			Token:            token,
+			Type:             decredplugin.VoteTypeApproval,
+			Mask:             0x03,
+			Duration:         decredplugin.VoteDurationMin,
+			QuorumPercentage: 20,
+			PassPercentage:   60,
+			Options: []decredplugin.VoteOption{{
+				Id:   decredplugin.VoteOptionIDReject,
+				Bits: 0x01,
+			}, {
+				Id:   decredplugin.VoteOptionIDApprove,
+				Bits: 0x02,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = g.Plugin(decredplugin.CmdStartVote, string(svb))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the identity
+	next, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.SetIdentity(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receipt of a vote that is cast after the rotation is signed by
+	// the new identity
+	cv := decredplugin.CastVote{
+		Token:   token,
+		Ticket:  ticket,
+		VoteBit: "2",
+	}
+	cv.Signature = signVote(t, key, cv)
+	bb, err := decredplugin.EncodeBallot(decredplugin.Ballot{
+		Votes: []decredplugin.CastVote{cv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reply, err := g.Plugin(decredplugin.CmdBallot, string(bb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := decredplugin.DecodeBallotReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.Receipts[0].Error != "" {
+		t.Fatalf("unexpected vote error: %v", br.Receipts[0].Error)
+	}
+	err = decredvote.VerifyReceipt([]*identity.PublicIdentity{&next.Public},
+		cv.Signature, br.Receipts[0].Signature)
+	if err != nil {
+		t.Fatalf("receipt not signed by the new identity: %v", err)
+	}
+	err = decredvote.VerifyReceipt([]*identity.PublicIdentity{&id.Public},
+		cv.Signature, br.Receipts[0].Signature)
+	if err == nil {
+		t.Fatalf("receipt signed by the previous identity")
+	}
+
+	// The vote bundle is signed by the new identity as well
+	vbb, err := decredplugin.EncodeVoteBundle(decredplugin.VoteBundle{
+		Token: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reply, err = g.Plugin(decredplugin.CmdVoteBundle, string(vbb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vbr, err := decredplugin.DecodeVoteBundleReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vbr.PublicKey != hex.EncodeToString(next.Public.Key[:]) {
+		t.Fatalf("unexpected bundle public key %v", vbr.PublicKey)
+	}
+	vbt, err := decredvote.VerifyVoteBundle(vbr, params, &next.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vbt.Counted != 1 {
+		t.Fatalf("unexpected bundle tally %+v", vbt)
+	}
+}
+
+func TestScheduledVote(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &chaincfg.TestNet3Params
+	g, err := New(params, filepath.Join(dir, "data"), "", "", id,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	// Setup the simulated chain.  The vote is scheduled to start in the
+	// future so the snapshot block is ahead of the best block.
+	best := uint32(1000)
+	startHeight := best + 100
+	snapshotHeight := startHeight - uint32(params.TicketMaturity)
+	sc := decredvote.SimChain{
+		Blocks: []decredvote.SimBlock{{
+			Height: best,
+			Hash:   "best",
+		}},
+	}
+	simChain := filepath.Join(dir, "simchain.json")
+	writeSimChain(t, simChain, sc)
+
+	d, err := backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend:  g,
+		DataDir:  g.root,
+		Identity: g.identity,
+		TestNet:  true,
+		Settings: []backend.PluginSetting{{
+			Key:   decredvote.SettingChainSource,
+			Value: decredvote.ChainSourceSim,
+		}, {
+			Key:   decredvote.SettingSimChain,
+			Value: simChain,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userSign := func(msg string) string {
+		s := user.SignMessage([]byte(msg))
+		return hex.EncodeToString(s[:])
+	}
+	token := hex.EncodeToString(newVettedRecord(t, g))
+	sv := decredplugin.StartVote{
+		PublicKey: user.Public.String(),
+		Signature: userSign(token),
+		Vote: decredplugin.Vote{
+			Token:            token,
+			Type:             decredplugin.VoteTypeApproval,
+			Mask:             0x03,
+			Duration:         decredplugin.VoteDurationMin,
+			QuorumPercentage: 20,
+			PassPercentage:   60,
+			Options: []decredplugin.VoteOption{{
+				Id:   decredplugin.VoteOptionIDReject,
+				Bits: 0x01,
+			}, {
+				Id:   decredplugin.VoteOptionIDApprove,
+				Bits: 0x02,
+			}},
+		},
+	}
+
+	// schedule sends a schedule vote command and returns the reply.
+	schedule := func(action string, height uint32, sv decredplugin.StartVote) (*decredplugin.ScheduleVoteReply, error) {
+		t.Helper()
+
+		svb, err := decredplugin.EncodeScheduleVote(decredplugin.ScheduleVote{
+			Action:      action,
+			Token:       token,
+			StartHeight: height,
+			StartVote:   sv,
+			Signature: userSign(token +
+				strconv.FormatUint(uint64(height), 10) + action),
+			PublicKey: user.Public.String(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, reply, err := g.Plugin(decredplugin.CmdScheduleVote, string(svb))
+		if err != nil {
+			return nil, err
+		}
+		svr, err := decredplugin.DecodeScheduleVoteReply([]byte(reply))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svr, nil
+	}
+
+	// startVote sends a start vote command and returns the reply.
+	startVote := func(sv decredplugin.StartVote) (*decredplugin.StartVoteReply, error) {
+		t.Helper()
+
+		svb, err := decredplugin.EncodeStartVote(sv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, reply, err := g.Plugin(decredplugin.CmdStartVote, string(svb))
+		if err != nil {
+			return nil, err
+		}
+		svr, err := decredplugin.DecodeStartVoteReply([]byte(reply))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svr, nil
+	}
+
+	// A vote must be authorized before it can be scheduled
+	_, err = schedule(decredplugin.ScheduleVoteActionSchedule, startHeight,
+		sv)
+	if err == nil {
+		t.Fatalf("expected unauthorized vote error")
+	}
+	avb, err := decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+		Action: decredplugin.AuthVoteActionAuthorize,
+		Token:  token,
+		Signature: userSign(token + "1" +
+			decredplugin.AuthVoteActionAuthorize),
+		PublicKey: user.Public.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = g.Plugin(decredplugin.CmdAuthorizeVote, string(avb))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The start height must be in the future
+	_, err = schedule(decredplugin.ScheduleVoteActionSchedule, best, sv)
+	if err == nil {
+		t.Fatalf("expected start height reached error")
+	}
+
+	// Schedule, cancel and reschedule the vote
+	svr, err := schedule(decredplugin.ScheduleVoteActionSchedule,
+		startHeight, sv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svr.StartHeight != startHeight ||
+		svr.SnapshotHeight != snapshotHeight || svr.Receipt == "" {
+		t.Fatalf("unexpected schedule vote reply %+v", svr)
+	}
+	_, err = schedule(decredplugin.ScheduleVoteActionCancel, startHeight+1,
+		decredplugin.StartVote{})
+	if err == nil {
+		t.Fatalf("expected start height mismatch error")
+	}
+	_, err = schedule(decredplugin.ScheduleVoteActionCancel, startHeight,
+		decredplugin.StartVote{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = startVote(sv)
+	if err == nil {
+		t.Fatalf("expected cancelled schedule error")
+	}
+	_, err = schedule(decredplugin.ScheduleVoteActionSchedule,
+		startHeight, sv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The vote can not start before the start height and only with
+	// the scheduled start vote
+	_, err = startVote(sv)
+	if err == nil {
+		t.Fatalf("expected start height not reached error")
+	}
+	sc.Blocks = append(sc.Blocks, decredvote.SimBlock{
+		Height:     snapshotHeight,
+		Hash:       "snapshot",
+		TicketPool: []string{"ticket"},
+	}, decredvote.SimBlock{
+		Height: startHeight,
+		Hash:   "start",
+	})
+	writeSimChain(t, simChain, sc)
+	other := sv
+	other.Signature = userSign(token + "other")
+	_, err = startVote(other)
+	if err == nil {
+		t.Fatalf("expected start vote mismatch error")
+	}
+
+	// The vote starts at the scheduled snapshot
+	vr, err := startVote(sv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endHeight := snapshotHeight + decredplugin.VoteDurationMin +
+		uint32(params.TicketMaturity)
+	if vr.StartBlockHash != "snapshot" ||
+		vr.StartBlockHeight != strconv.Itoa(int(snapshotHeight)) ||
+		vr.EndHeight != strconv.Itoa(int(endHeight)) {
+		t.Fatalf("unexpected start vote reply %+v", vr)
+	}
+
+	// A started vote can not be cancelled
+	_, err = schedule(decredplugin.ScheduleVoteActionCancel, startHeight,
+		decredplugin.StartVote{})
+	if err == nil {
+		t.Fatalf("expected vote started error")
+	}
+
+	// The inventory contains the vote schedule
+	_, reply, err := g.Plugin(decredplugin.CmdInventory, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir, err := decredplugin.DecodeInventoryReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ir.ScheduleVotes) != 1 ||
+		ir.ScheduleVotes[0].Token != token ||
+		ir.ScheduleVotes[0].StartHeight != startHeight {
+		t.Fatalf("unexpected inventory vote schedules %+v",
+			ir.ScheduleVotes)
+	}
+}
+
+func TestScheduledVoteLate(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &chaincfg.TestNet3Params
+	g, err := New(params, filepath.Join(dir, "data"), "", "", id,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	// Setup the simulated chain
+	best := uint32(1000)
+	startHeight := best + 100
+	snapshotHeight := startHeight - uint32(params.TicketMaturity)
+	sc := decredvote.SimChain{
+		Blocks: []decredvote.SimBlock{{
+			Height: best,
+			Hash:   "best",
+		}},
+	}
+	simChain := filepath.Join(dir, "simchain.json")
+	writeSimChain(t, simChain, sc)
+
+	d, err := backend.NewPlugin(decredplugin.ID, backend.PluginConfig{
+		Backend:  g,
+		DataDir:  g.root,
+		Identity: g.identity,
+		TestNet:  true,
+		Settings: []backend.PluginSetting{{
+			Key:   decredvote.SettingChainSource,
+			Value: decredvote.ChainSourceSim,
+		}, {
+			Key:   decredvote.SettingSimChain,
+			Value: simChain,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.EnablePlugin(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userSign := func(msg string) string {
+		s := user.SignMessage([]byte(msg))
+		return hex.EncodeToString(s[:])
+	}
+	token := hex.EncodeToString(newVettedRecord(t, g))
+	sv := decredplugin.StartVote{
+		PublicKey: user.Public.String(),
+		Signature: userSign(token),
+		Vote: decredplugin.Vote{
+			Token:            token,
+			Type:             decredplugin.VoteTypeApproval,
+			Mask:             0x03,
+			Duration:         decredplugin.VoteDurationMin,
+			QuorumPercentage: 20,
+			PassPercentage:   60,
+			Options: []decredplugin.VoteOption{{
+				Id:   decredplugin.VoteOptionIDReject,
+				Bits: 0x01,
+			}, {
+				Id:   decredplugin.VoteOptionIDApprove,
+				Bits: 0x02,
+			}},
+		},
+	}
+
+	// schedule sends a schedule vote command and returns the reply.
+	schedule := func(action string, height uint32, sv decredplugin.StartVote) (*decredplugin.ScheduleVoteReply, error) {
+		t.Helper()
+
+		svb, err := decredplugin.EncodeScheduleVote(decredplugin.ScheduleVote{
+			Action:      action,
+			Token:       token,
+			StartHeight: height,
+			StartVote:   sv,
+			Signature: userSign(token +
+				strconv.FormatUint(uint64(height), 10) + action),
+			PublicKey: user.Public.String(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, reply, err := g.Plugin(decredplugin.CmdScheduleVote, string(svb))
+		if err != nil {
+			return nil, err
+		}
+		svr, err := decredplugin.DecodeScheduleVoteReply([]byte(reply))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svr, nil
+	}
+
+	// startVote sends a start vote command.
+	startVote := func(sv decredplugin.StartVote) error {
+		t.Helper()
+
+		svb, err := decredplugin.EncodeStartVote(sv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = g.Plugin(decredplugin.CmdStartVote, string(svb))
+		return err
+	}
+
+	// setBest appends a best block to the simulated chain.
+	setBest := func(height uint32) {
+		t.Helper()
+
+		sc.Blocks = append(sc.Blocks, decredvote.SimBlock{
+			Height: height,
+			Hash:   "best" + strconv.Itoa(int(height)),
+		})
+		writeSimChain(t, simChain, sc)
+	}
+
+	// Authorize and schedule the vote
+	avb, err := decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+		Action: decredplugin.AuthVoteActionAuthorize,
+		Token:  token,
+		Signature: userSign(token + "1" +
+			decredplugin.AuthVoteActionAuthorize),
+		PublicKey: user.Public.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = g.Plugin(decredplugin.CmdAuthorizeVote, string(avb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = schedule(decredplugin.ScheduleVoteActionSchedule, startHeight,
+		sv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A schedule can not fail while the vote can still be started in
+	// time
+	sc.Blocks = append(sc.Blocks, decredvote.SimBlock{
+		Height:     snapshotHeight,
+		Hash:       "snapshot",
+		TicketPool: []string{"ticket"},
+	})
+	setBest(startHeight + decredplugin.ScheduleVoteMaxDelay)
+	_, err = schedule(decredplugin.ScheduleVoteActionFail, startHeight,
+		decredplugin.StartVote{})
+	if err == nil {
+		t.Fatalf("expected vote can still be started error")
+	}
+
+	// A late vote is not started with a shortened voting period
+	setBest(startHeight + decredplugin.ScheduleVoteMaxDelay + 1)
+	err = startVote(sv)
+	if err == nil {
+		t.Fatalf("expected start height missed error")
+	}
+
+	// The schedule fails and its start vote can no longer be used
+	_, err = schedule(decredplugin.ScheduleVoteActionFail, startHeight+1,
+		decredplugin.StartVote{})
+	if err == nil {
+		t.Fatalf("expected start height mismatch error")
+	}
+	svr, err := schedule(decredplugin.ScheduleVoteActionFail, startHeight,
+		decredplugin.StartVote{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svr.Action != decredplugin.ScheduleVoteActionFail ||
+		svr.StartHeight != startHeight {
+		t.Fatalf("unexpected schedule vote reply %+v", svr)
+	}
+	_, err = schedule(decredplugin.ScheduleVoteActionFail, startHeight,
+		decredplugin.StartVote{})
+	if err == nil {
+		t.Fatalf("expected vote not scheduled error")
+	}
+	err = startVote(sv)
+	if err == nil {
+		t.Fatalf("expected failed schedule error")
+	}
+
+	// A failed vote can be rescheduled
+	_, err = schedule(decredplugin.ScheduleVoteActionSchedule,
+		startHeight+100, sv)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
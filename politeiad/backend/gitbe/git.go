@@ -297,6 +297,39 @@ func (g *gitBackEnd) gitLog(path string) ([]string, error) {
 	return out, nil
 }
 
+// gitLogPath returns the hash of the last commit on master that touched the
+// provided path.
+func (g *gitBackEnd) gitLogPath(path, filename string) (string, error) {
+	out, err := g.git(path, "log", "-n 1", "--pretty=format:%H", "master",
+		"--", filename)
+	if err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("no commit found for %v", filename)
+	}
+
+	return out[0], nil
+}
+
+// gitCatFile returns the raw content of a git object.  The object may be
+// anything that git rev-parse understands, for example <commit>:<path>.
+// The output of git is not split into lines since tree objects are binary.
+func (g *gitBackEnd) gitCatFile(path, objectType, object string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(g.gitPath, "cat-file", objectType, object)
+	cmd.Dir = path
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file %v %v: %v %v", objectType,
+			object, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
 func (g *gitBackEnd) gitFsck(path string) ([]string, error) {
 	out, err := g.git(path, "fsck", "--full", "--strict")
 	if err != nil {
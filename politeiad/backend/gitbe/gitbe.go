@@ -30,12 +30,11 @@ import (
 	"github.com/decred/politeia/decredplugin"
 	pd "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
-	"github.com/decred/politeia/politeiad/api/v1/mime"
 	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
 	"github.com/decred/politeia/util"
 	filesystem "github.com/otiai10/copy"
 	"github.com/robfig/cron"
-	"github.com/subosito/gozaru"
 )
 
 const (
@@ -56,6 +55,11 @@ const (
 	// defaultRecordMetadataFilename is the filename of record record.
 	defaultRecordMetadataFilename = "recordmetadata.json"
 
+	// defaultRecordSignatureFilename is the filename of the hex encoded
+	// censorship record signature.  It allows read-only replicas to serve
+	// censorship records that were signed by the primary.
+	defaultRecordSignatureFilename = "recordsignature.txt"
+
 	// defaultMDFilenameSuffix is the filename suffic for the user provided
 	// metadata record.  The metadata record shall be string encoded.
 	defaultMDFilenameSuffix = ".metadata.txt"
@@ -113,22 +117,32 @@ type file struct {
 // gitBackEnd is a git based backend context that satisfies the backend
 // interface.
 type gitBackEnd struct {
-	sync.Mutex                       // Global lock
-	cron            *cron.Cron       // Scheduler for periodic tasks
-	activeNetParams *chaincfg.Params // indicator if we are running on testnet
-	journal         *Journal         // Journal context
-	shutdown        bool             // Backend is shutdown
-	root            string           // Root directory
-	unvetted        string           // Unvettend content
-	vetted          string           // Vetted, public, visible content
-	journals        string           // Journals/cache
-	dcrtimeHost     string           // Dcrtimed host
-	gitPath         string           // Path to git
-	gitTrace        bool             // Enable git tracing
-	test            bool             // Set during UT
-	exit            chan struct{}    // Close channel
-	checkAnchor     chan struct{}    // Work notification
-	plugins         []backend.Plugin // Plugins
+	sync.Mutex                             // Global lock
+	cron            *cron.Cron             // Scheduler for periodic tasks
+	activeNetParams *chaincfg.Params       // indicator if we are running on testnet
+	journal         *Journal               // Journal context
+	shutdown        bool                   // Backend is shutdown
+	root            string                 // Root directory
+	lock            *rootLock              // Root directory lock
+	unvetted        string                 // Unvettend content
+	vetted          string                 // Vetted, public, visible content
+	journals        string                 // Journals/cache
+	dcrtimeHost     string                 // Dcrtimed host
+	gitPath         string                 // Path to git
+	gitTrace        bool                   // Enable git tracing
+	test            bool                   // Set during UT
+	exit            chan struct{}          // Close channel
+	checkAnchor     chan struct{}          // Work notification
+	plugins         *backend.Plugins       // Enabled plugins
+	chain           decredvote.ChainSource // Decred plugin chain data
+
+	// identity signs the censorship records that are stored alongside
+	// the record metadata.
+	identity *identity.FullIdentity
+
+	// The following items are only set on read-only replicas
+	replica bool   // Read-only replica of primary
+	primary string // Primary vetted repo URL
 
 	// The following items are used for testing only
 	testAnchors map[string]bool // [digest]anchored
@@ -259,163 +273,23 @@ func extendSHA1FromString(s string) (string, error) {
 // verifyContent verifies that all provided backend.MetadataStream and
 // backend.File are sane and returns a cooked array of the files.
 func verifyContent(metadata []backend.MetadataStream, files []backend.File, filesDel []string) ([]file, error) {
-	// Make sure all metadata is within maxima.
-	for _, v := range metadata {
-		if v.ID > pd.MetadataStreamsMax-1 {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidMDID,
-				ErrorContext: []string{
-					strconv.FormatUint(v.ID, 10),
-				},
-			}
-		}
-	}
-	for i := range metadata {
-		for j := range metadata {
-			// Skip self and non duplicates.
-			if i == j || metadata[i].ID != metadata[j].ID {
-				continue
-			}
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusDuplicateMDID,
-				ErrorContext: []string{
-					strconv.FormatUint(metadata[i].ID, 10),
-				},
-			}
-		}
-	}
-
-	// Prevent paths
-	for i := range files {
-		if filepath.Base(files[i].Name) != files[i].Name {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFilename,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-	}
-	for _, v := range filesDel {
-		if filepath.Base(v) != v {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFilename,
-				ErrorContext: []string{
-					v,
-				},
-			}
-		}
-	}
-
-	// Now check files
-	if len(files) == 0 {
-		return nil, backend.ContentVerificationError{
-			ErrorCode: pd.ErrorStatusEmpty,
-		}
-	}
-
-	// Prevent bad filenames and duplicate filenames
-	for i := range files {
-		for j := range files {
-			if i == j {
-				continue
-			}
-			if files[i].Name == files[j].Name {
-				return nil, backend.ContentVerificationError{
-					ErrorCode: pd.ErrorStatusDuplicateFilename,
-					ErrorContext: []string{
-						files[i].Name,
-					},
-				}
-			}
-		}
-		// Check against filesDel
-		for _, v := range filesDel {
-			if files[i].Name == v {
-				return nil, backend.ContentVerificationError{
-					ErrorCode: pd.ErrorStatusDuplicateFilename,
-					ErrorContext: []string{
-						files[i].Name,
-					},
-				}
-			}
-		}
+	err := backend.VerifyContent(metadata, files, filesDel)
+	if err != nil {
+		return nil, err
 	}
 
+	// Content has been verified so decoding can not fail.
 	fa := make([]file, 0, len(files))
 	for i := range files {
-		if gozaru.Sanitize(files[i].Name) != files[i].Name {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFilename,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-
-		// Validate digest
-		d, ok := util.ConvertDigest(files[i].Digest)
-		if !ok {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFileDigest,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-
-		// Setup cooked file.
-		f := file{
-			name: files[i].Name,
-		}
-
-		// Decode base64 payload
-		var err error
-		f.payload, err = base64.StdEncoding.DecodeString(files[i].Payload)
+		payload, err := base64.StdEncoding.DecodeString(files[i].Payload)
 		if err != nil {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidBase64,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-
-		// Calculate payload digest
-		dp := util.Digest(f.payload)
-		if !bytes.Equal(d[:], dp) {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFileDigest,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-		f.digest = dp
-
-		// Verify MIME
-		detectedMIMEType := mime.DetectMimeType(f.payload)
-		if detectedMIMEType != files[i].MIME {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidMIMEType,
-				ErrorContext: []string{
-					files[i].Name,
-					detectedMIMEType,
-				},
-			}
-		}
-
-		if !mime.MimeValid(files[i].MIME) {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusUnsupportedMIMEType,
-				ErrorContext: []string{
-					files[i].Name,
-					files[i].MIME,
-				},
-			}
+			return nil, err
 		}
-
-		fa = append(fa, f)
+		fa = append(fa, file{
+			name:    files[i].Name,
+			digest:  util.Digest(payload),
+			payload: payload,
+		})
 	}
 
 	return fa, nil
@@ -580,6 +454,73 @@ func (g *gitBackEnd) commitMD(path, id, msg string) error {
 	return g.gitCommit(path, "Update record status "+id+" "+msg)
 }
 
+// loadSignature loads the censorship record signature from the provided
+// path/id.  An empty signature is returned for records that predate stored
+// signatures.
+//
+// This function should be called with the lock held.
+func loadSignature(path, id, version string) (string, error) {
+	filename := pijoin(getPathToVersion(path, id, version),
+		defaultRecordSignatureFilename)
+	signature, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(signature)), nil
+}
+
+// signMD stores the censorship record signature of the provided
+// RecordMetadata in path/id/version and adds it to the git repo.  The latest
+// version is used when version is empty.  The signature is only stored when
+// the backend was created with an identity.
+//
+// This function should be called with the lock held.
+func (g *gitBackEnd) signMD(path, id, version string, brm *backend.RecordMetadata) error {
+	if g.identity == nil {
+		return nil
+	}
+
+	signature := g.identity.SignMessage([]byte(brm.Merkle + brm.Token))
+	filename := pijoin(getPathToVersion(path, id, version),
+		defaultRecordSignatureFilename)
+	err := ioutil.WriteFile(filename,
+		[]byte(hex.EncodeToString(signature[:])+"\n"), 0664)
+	if err != nil {
+		return err
+	}
+
+	// git add id/version/recordsignature.txt
+	return g.gitAdd(path, filename)
+}
+
+// SetIdentity replaces the identity that signs the censorship records and
+// the plugin data that are stored from now on.  Stored signatures are not
+// modified and remain valid for the identity that was active at the record
+// timestamp.
+//
+// SetIdentity satisfies the backend IdentitySetter interface.
+func (g *gitBackEnd) SetIdentity(id *identity.FullIdentity) error {
+	log.Tracef("SetIdentity")
+
+	g.Lock()
+	if g.shutdown {
+		g.Unlock()
+		return backend.ErrShutdown
+	}
+	if g.replica {
+		g.Unlock()
+		return backend.ErrReadOnly
+	}
+	g.identity = id
+	g.Unlock()
+
+	// The plugins take the backend lock themselves.
+	return g.plugins.SetIdentity(id)
+}
+
 // deltaCommits returns sha1 extended digests and one line commit messages to
 // the caller.  If lastAnchor is empty then the range is from the dawn of time
 // until now.  If lastAnchor is a valid hash the range is from lastAnchor up
@@ -1102,6 +1043,10 @@ func (g *gitBackEnd) _newRecord(id string, metadata []backend.MetadataStream, fa
 	if err != nil {
 		return nil, err
 	}
+	err = g.signMD(g.unvetted, id, "", brm)
+	if err != nil {
+		return nil, err
+	}
 
 	// git add id/version/recordmetadata.json
 	filename := pijoin(joinLatest(g.unvetted, id),
@@ -1157,12 +1102,63 @@ func (g *gitBackEnd) newRecord(token []byte, metadata []backend.MetadataStream,
 	return rm, nil
 }
 
+// preHook calls the pre-hooks of the enabled plugins with the latest version
+// of the record in the provided repo.  It is called with the lock held so that
+// the record the hooks validate can not change before the change is applied.
+//
+// This function must be called with the lock held.
+func (g *gitBackEnd) preHook(h backend.HookT, rc backend.RecordChange, token []byte, repo string) error {
+	return g.plugins.Hook(h, rc, func() (*backend.Record, error) {
+		return g.getRecord(token, "", repo, true)
+	})
+}
+
+// postHook calls the post-hooks of the enabled plugins.  Failures are only
+// logged because the record was already changed.
+//
+// This function must be called without the lock held.
+func (g *gitBackEnd) postHook(h backend.HookT, rc backend.RecordChange, record func() (*backend.Record, error)) {
+	err := g.plugins.Hook(h, rc, record)
+	if err != nil {
+		log.Errorf("%v %v: %v", h, rc.Token, err)
+	}
+}
+
 // New takes a record verifies it and drops it on disk in the unvetted
 // directory.  Records and metadata are stored in unvetted/token/.  the
 // function returns a RecordMetadata.
 //
 // New satisfies the backend interface.
 func (g *gitBackEnd) New(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
+	rc := backend.RecordChange{
+		MDAppend: metadata,
+		FilesAdd: files,
+	}
+	err := g.plugins.Hook(backend.HookPreNewRecord, rc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rm, err := g.newRecordLocked(metadata, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Token = rm.Token
+	g.postHook(backend.HookPostNewRecord, rc,
+		func() (*backend.Record, error) {
+			token, err := hex.DecodeString(rm.Token)
+			if err != nil {
+				return nil, err
+			}
+			return g.GetUnvetted(token)
+		})
+
+	return rm, nil
+}
+
+// newRecordLocked runs the portion of New that has to be locked.
+func (g *gitBackEnd) newRecordLocked(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
 	log.Tracef("New")
 	fa, err := verifyContent(metadata, files, []string{})
 	if err != nil {
@@ -1183,6 +1179,9 @@ func (g *gitBackEnd) New(metadata []backend.MetadataStream, files []backend.File
 	if g.shutdown {
 		return nil, backend.ErrShutdown
 	}
+	if g.replica {
+		return nil, backend.ErrReadOnly
+	}
 
 	// git checkout master
 	err = g.gitCheckout(g.unvetted, "master")
@@ -1413,7 +1412,11 @@ func (g *gitBackEnd) _updateRecord(commit bool, id string, mdAppend, mdOverwrite
 	if brm.Status == backend.MDStatusVetted {
 		ns = backend.MDStatusVetted
 	}
-	_, err = createMD(g.unvetted, id, ns, brm.Iteration+1, hashes)
+	nbrm, err := createMD(g.unvetted, id, ns, brm.Iteration+1, hashes)
+	if err != nil {
+		return err
+	}
+	err = g.signMD(g.unvetted, id, "", nbrm)
 	if err != nil {
 		return err
 	}
@@ -1430,13 +1433,10 @@ func (g *gitBackEnd) _updateRecord(commit bool, id string, mdAppend, mdOverwrite
 	}
 
 	// Call plugin hooks
-	f, ok := decredPluginHooks[PluginPostHookEdit]
-	if ok {
-		log.Tracef("Calling hook: %v(%v)", PluginPostHookEdit, id)
-		err = f(id)
-		if err != nil {
-			return err
-		}
+	err = g.plugins.Hook(backend.HookPostEdit,
+		backend.RecordChange{Token: id}, nil)
+	if err != nil {
+		return err
 	}
 
 	// git add id/recordmetadata.json
@@ -1485,7 +1485,7 @@ func (g *gitBackEnd) wouldChange(id string, mdAppend []backend.MetadataStream, m
 // update occurred on master.
 //
 // Must be called WITHOUT the lock held.
-func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string, master bool) (*backend.Record, error) {
+func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string, master bool, rc backend.RecordChange) (*backend.Record, error) {
 	log.Tracef("updateRecord: %x", token)
 
 	// Send in a single metadata array to verify there are no dups.
@@ -1508,6 +1508,19 @@ func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStrea
 	if g.shutdown {
 		return nil, backend.ErrShutdown
 	}
+	if g.replica {
+		return nil, backend.ErrReadOnly
+	}
+
+	// Call pre-hooks
+	repo := g.unvetted
+	if master {
+		repo = g.vetted
+	}
+	err = g.preHook(backend.HookPreEditRecord, rc, token, repo)
+	if err != nil {
+		return nil, err
+	}
 
 	// git checkout master
 	err = g.gitCheckout(g.unvetted, "master")
@@ -1649,8 +1662,25 @@ func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStrea
 // This function is part of the interface.
 func (g *gitBackEnd) UpdateVettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
 	log.Debugf("UpdateVettedRecord %x", token)
-	return g.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
-		true)
+
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      true,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	record, err := g.updateRecord(token, mdAppend, mdOverwrite, filesAdd,
+		filesDel, true, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	g.postHook(backend.HookPostEditRecord, rc, nil)
+
+	return record, nil
 }
 
 // UpdateUnvettedRecord updates the unvetted record.
@@ -1658,8 +1688,25 @@ func (g *gitBackEnd) UpdateVettedRecord(token []byte, mdAppend []backend.Metadat
 // This function is part of the interface.
 func (g *gitBackEnd) UpdateUnvettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
 	log.Debugf("UpdateUnvettedRecord %x", token)
-	return g.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
-		false)
+
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      false,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	record, err := g.updateRecord(token, mdAppend, mdOverwrite, filesAdd,
+		filesDel, false, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	g.postHook(backend.HookPostEditRecord, rc, nil)
+
+	return record, nil
 }
 
 // updateVettedMetadata updates metadata in the unvetted repo and pushes it
@@ -1758,6 +1805,28 @@ func (g *gitBackEnd) _updateVettedMetadata(token []byte, mdAppend []backend.Meta
 //
 // This function must be called without the lock held.
 func (g *gitBackEnd) UpdateVettedMetadata(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream) error {
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      true,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	err := g.updateVettedMetadataLocked(token, mdAppend, mdOverwrite, rc)
+	if err != nil {
+		return err
+	}
+
+	g.postHook(backend.HookPostEditMetadata, rc,
+		func() (*backend.Record, error) {
+			return g.GetVetted(token, "")
+		})
+
+	return nil
+}
+
+// updateVettedMetadataLocked runs the portion of UpdateVettedMetadata that has
+// to be locked, including the pre-hooks.
+func (g *gitBackEnd) updateVettedMetadataLocked(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, rc backend.RecordChange) error {
 	log.Debugf("UpdateVettedMetadata: %x", token)
 
 	// Send in a single metadata array to verify there are no dups.
@@ -1780,6 +1849,14 @@ func (g *gitBackEnd) UpdateVettedMetadata(token []byte, mdAppend []backend.Metad
 	if g.shutdown {
 		return backend.ErrShutdown
 	}
+	if g.replica {
+		return backend.ErrReadOnly
+	}
+
+	err = g.preHook(backend.HookPreEditMetadata, rc, token, g.vetted)
+	if err != nil {
+		return err
+	}
 
 	return g._updateVettedMetadata(token, mdAppend, mdOverwrite)
 }
@@ -1858,6 +1935,9 @@ func (g *gitBackEnd) UpdateReadme(content string) error {
 	if g.shutdown {
 		return backend.ErrShutdown
 	}
+	if g.replica {
+		return backend.ErrReadOnly
+	}
 	// git checkout master
 	err := g.gitCheckout(g.unvetted, "master")
 	if err != nil {
@@ -1923,11 +2003,18 @@ func (g *gitBackEnd) _getRecord(id, version, repo string, includeFiles bool) (*b
 		}
 	}
 
+	// load censorship record signature
+	signature, err := loadSignature(repo, id, version)
+	if err != nil {
+		return nil, err
+	}
+
 	return &backend.Record{
 		RecordMetadata: *brm,
 		Version:        version,
 		Metadata:       mds,
 		Files:          files,
+		Signature:      signature,
 	}, nil
 }
 
@@ -2090,6 +2177,114 @@ func (g *gitBackEnd) GetVetted(token []byte, version string) (*backend.Record, e
 	return g.getRecordLock(token, version, g.vetted, true)
 }
 
+// GetVettedVersions returns all versions of a vetted record, oldest first.
+// The records do not include the record files.
+//
+// GetVettedVersions satisfies the backend interface.
+func (g *gitBackEnd) GetVettedVersions(token []byte) ([]backend.Record, error) {
+	log.Debugf("GetVettedVersions %x", token)
+
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	latest, err := getLatest(pijoin(g.vetted, hex.EncodeToString(token)))
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseUint(latest, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]backend.Record, 0, n)
+	for i := uint64(1); i <= n; i++ {
+		r, err := g.getRecord(token, strconv.FormatUint(i, 10), g.vetted,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+
+	return records, nil
+}
+
+// GetVettedAnchor returns the dcrtime anchor of a vetted record version.  The
+// anchor contains the raw git objects that link the record files to the last
+// commit that touched the record version.
+//
+// GetVettedAnchor satisfies the backend interface.
+func (g *gitBackEnd) GetVettedAnchor(token []byte, version string) (*backend.RecordAnchor, error) {
+	log.Debugf("GetVettedAnchor %x %v", token, version)
+
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	if version == "" {
+		latest, err := getLatest(pijoin(g.vetted, id))
+		if err != nil {
+			return nil, err
+		}
+		version = latest
+	}
+	if !util.FileExists(pijoin(g.vetted, id, version)) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Find the commit and the git objects that lead to the payload
+	commit, err := g.gitLogPath(g.vetted, pijoin(id, version))
+	if err != nil {
+		return nil, err
+	}
+	rawCommit, err := g.gitCatFile(g.vetted, "commit", commit)
+	if err != nil {
+		return nil, err
+	}
+	path := []string{id, version, defaultPayloadDir}
+	trees := make([][]byte, 0, len(path)+1)
+	for k := 0; k <= len(path); k++ {
+		tree, err := g.gitCatFile(g.vetted, "tree",
+			commit+":"+strings.Join(path[:k], "/"))
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+
+	// Find the anchor that contains the commit
+	commitDigest, err := hex.DecodeString(commit)
+	if err != nil {
+		return nil, err
+	}
+	anchorKey, digests, err := g.findCommitAnchor(extendSHA1(commitDigest))
+	if err != nil {
+		return nil, err
+	}
+	ci, err := g.readChainInformation(anchorKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend.RecordAnchor{
+		Version:          version,
+		Commit:           rawCommit,
+		Trees:            trees,
+		Path:             path,
+		Digests:          digests,
+		Merkle:           anchorKey,
+		ChainInformation: ci,
+	}, nil
+}
+
 // setUnvettedStatus takes various parameters to update a record metadata and
 // status.  Note that this function must be wrapped by a function that delivers
 // the call with the unvetted repo sitting in master.  The idea is that if this
@@ -2132,6 +2327,13 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 			return nil, err
 		}
 
+		// Sign records that were created before signatures were
+		// stored.
+		err = g.signMD(g.unvetted, id, "", &record.RecordMetadata)
+		if err != nil {
+			return nil, err
+		}
+
 		// Commit brm
 		err = g.commitMD(g.unvetted, id, "published")
 		if err != nil {
@@ -2162,6 +2364,13 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 			return nil, err
 		}
 
+		// The signature is stored again because the timestamp
+		// determines which identity signed the record.
+		err = g.signMD(g.unvetted, id, "", &record.RecordMetadata)
+		if err != nil {
+			return nil, err
+		}
+
 		// Commit brm
 		err = g.commitMD(g.unvetted, id, "censored")
 		if err != nil {
@@ -2182,12 +2391,42 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 //
 // SetUnvettedStatus satisfies the backend interface.
 func (g *gitBackEnd) SetUnvettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      false,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	record, err := g.setUnvettedStatusLocked(token, status, mdAppend,
+		mdOverwrite, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	g.postHook(backend.HookPostSetRecordStatus, rc, nil)
+
+	return record, nil
+}
+
+// setUnvettedStatusLocked runs the portion of SetUnvettedStatus that has to be
+// locked, including the pre-hooks.
+func (g *gitBackEnd) setUnvettedStatusLocked(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream, rc backend.RecordChange) (*backend.Record, error) {
 	// Lock filesystem
 	g.Lock()
 	defer g.Unlock()
 	if g.shutdown {
 		return nil, backend.ErrShutdown
 	}
+	if g.replica {
+		return nil, backend.ErrReadOnly
+	}
+
+	err := g.preHook(backend.HookPreSetRecordStatus, rc, token, g.unvetted)
+	if err != nil {
+		return nil, err
+	}
 
 	log.Debugf("setting status %v (%v) -> %x", status,
 		backend.MDStatus[status], token)
@@ -2293,6 +2532,13 @@ func (g *gitBackEnd) _setVettedStatus(token []byte, status backend.MDStatusT, md
 		return nil, err
 	}
 
+	// The signature is stored again because the timestamp determines
+	// which identity signed the record.
+	err = g.signMD(g.unvetted, id, "", &record.RecordMetadata)
+	if err != nil {
+		return nil, err
+	}
+
 	// Commit changes
 	err = g.commitMD(g.unvetted, id, "archived")
 	if err != nil {
@@ -2313,12 +2559,42 @@ func (g *gitBackEnd) _setVettedStatus(token []byte, status backend.MDStatusT, md
 //
 // SetVettedStatus satisfies the backend interface.
 func (g *gitBackEnd) SetVettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      true,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	record, err := g.setVettedStatusLocked(token, status, mdAppend,
+		mdOverwrite, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	g.postHook(backend.HookPostSetRecordStatus, rc, nil)
+
+	return record, nil
+}
+
+// setVettedStatusLocked runs the portion of SetVettedStatus that has to be
+// locked, including the pre-hooks.
+func (g *gitBackEnd) setVettedStatusLocked(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream, rc backend.RecordChange) (*backend.Record, error) {
 	// Lock filesystem
 	g.Lock()
 	defer g.Unlock()
 	if g.shutdown {
 		return nil, backend.ErrShutdown
 	}
+	if g.replica {
+		return nil, backend.ErrReadOnly
+	}
+
+	err := g.preHook(backend.HookPreSetRecordStatus, rc, token, g.vetted)
+	if err != nil {
+		return nil, err
+	}
 
 	log.Debugf("setting status %v (%v) -> %x", status,
 		backend.MDStatus[status], token)
@@ -2397,6 +2673,11 @@ func (g *gitBackEnd) Inventory(vettedCount, branchCount uint, includeFiles, allV
 		}
 	}
 
+	// Replicas only carry the vetted repo
+	if g.replica {
+		return pr, []backend.Record{}, nil
+	}
+
 	// Walk Branches on unvetted
 	branches, err := g.gitBranches(g.unvetted)
 	if err != nil {
@@ -2423,60 +2704,197 @@ func (g *gitBackEnd) Inventory(vettedCount, branchCount uint, includeFiles, allV
 	return pr, br, nil
 }
 
-// GetPlugins returns a list of currently supported plugins and their settings.
+// inventoryRecord returns the latest version of a record in a record tree
+// with only its record metadata and the IDs of its metadata streams.  This is
+// enough to apply an inventory filter before the record is loaded.
+func inventoryRecord(t recordTree, token string) (*backend.Record, error) {
+	entries, err := t.readDir(token)
+	if err != nil {
+		return nil, err
+	}
+	latest := -1
+	for _, v := range entries {
+		version, err := strconv.Atoi(v.name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version: %v", v.name)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	if latest < 0 {
+		return nil, backend.ErrRecordNotFound
+	}
+	version := strconv.Itoa(latest)
+
+	b, err := t.readFile(token, version, defaultRecordMetadataFilename)
+	if err != nil {
+		return nil, err
+	}
+	var brm backend.RecordMetadata
+	err = json.Unmarshal(b, &brm)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err = t.readDir(token, version)
+	if err != nil {
+		return nil, err
+	}
+	mds := make([]backend.MetadataStream, 0, len(entries))
+	for _, v := range entries {
+		if !strings.HasSuffix(v.name, defaultMDFilenameSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(v.name,
+			defaultMDFilenameSuffix), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		mds = append(mds, backend.MetadataStream{
+			ID: id,
+		})
+	}
+
+	return &backend.Record{
+		RecordMetadata: brm,
+		Version:        version,
+		Metadata:       mds,
+	}, nil
+}
+
+// InventoryPage returns a filtered page of the latest version of all records.
+// The git backend does not index records.  The record metadata of all records
+// is read to select and order the records of the page and only the records
+// of the page are loaded.  The unvetted branches are read without checking
+// them out.
+//
+// InventoryPage satisfies the backend interface.
+func (g *gitBackEnd) InventoryPage(f backend.InventoryFilter) ([]backend.Record, string, error) {
+	log.Debugf("InventoryPage: %v %v", f.State, f.Cursor)
+
+	match, err := backend.InventoryMatcher(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, "", backend.ErrShutdown
+	}
+
+	matched := make([]backend.Record, 0, 1024) // PNOOMA
+	repos := make(map[string]string)           // [token]repo
+	add := func(t recordTree, repo, token string) error {
+		r, err := inventoryRecord(t, token)
+		if err != nil {
+			return fmt.Errorf("%v: %v", token, err)
+		}
+		if match(*r) {
+			matched = append(matched, *r)
+			repos[r.RecordMetadata.Token] = repo
+		}
+		return nil
+	}
+
+	// The vetted repo only contains vetted and archived records.  The
+	// unvetted branches only contain unvetted and censored records.
+	if f.State != backend.InventoryStateUnvetted {
+		files, err := ioutil.ReadDir(g.vetted)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, v := range files {
+			if !util.IsDigest(v.Name()) {
+				continue
+			}
+			err := add(workTree{path: g.vetted}, g.vetted, v.Name())
+			if err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	// Replicas only carry the vetted repo
+	if f.State != backend.InventoryStateVetted && !g.replica {
+		branches, err := g.gitBranches(g.unvetted)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, id := range branches {
+			if !util.IsDigest(id) {
+				continue
+			}
+			err := add(branchTree{
+				g:      g,
+				path:   g.unvetted,
+				branch: id,
+			}, g.unvetted, id)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	backend.SortInventory(matched, f)
+	page, cursor := backend.PageInventory(matched, f)
+
+	// Load the records of the page
+	records := make([]backend.Record, 0, len(page))
+	for _, v := range page {
+		token, err := hex.DecodeString(v.RecordMetadata.Token)
+		if err != nil {
+			return nil, "", err
+		}
+		r, err := g.getRecord(token, v.Version,
+			repos[v.RecordMetadata.Token], f.IncludeFiles)
+		if err != nil {
+			return nil, "", err
+		}
+		records = append(records, *r)
+	}
+
+	return records, cursor, nil
+}
+
+// GetPlugins returns a list of the enabled plugins and their settings.
 //
 // GetPlugins satisfies the backend interface.
 func (g *gitBackEnd) GetPlugins() ([]backend.Plugin, error) {
 	log.Debugf("GetPlugins")
-	return g.plugins, nil
+	return g.plugins.Plugins(), nil
 }
 
 // Plugin send a passthrough command. The return values are: incomming command
 // identifier, encoded command result and an error if the command failed to
-// execute.
+// execute.  Replicas only execute read-only commands.
 //
 // Plugin satisfies the backend interface.
 func (g *gitBackEnd) Plugin(command, payload string) (string, string, error) {
 	log.Debugf("Plugin: %v", command)
-	switch command {
-	case decredplugin.CmdAuthorizeVote:
-		payload, err := g.pluginAuthorizeVote(payload)
-		return decredplugin.CmdAuthorizeVote, payload, err
-	case decredplugin.CmdStartVote:
-		payload, err := g.pluginStartVote(payload)
-		return decredplugin.CmdStartVote, payload, err
-	case decredplugin.CmdBallot:
-		payload, err := g.pluginBallot(payload)
-		return decredplugin.CmdBallot, payload, err
-	case decredplugin.CmdProposalVotes:
-		payload, err := g.pluginProposalVotes(payload)
-		return decredplugin.CmdProposalVotes, payload, err
-	case decredplugin.CmdBestBlock:
-		payload, err := g.pluginBestBlock()
-		return decredplugin.CmdBestBlock, payload, err
-	case decredplugin.CmdNewComment:
-		payload, err := g.pluginNewComment(payload)
-		return decredplugin.CmdNewComment, payload, err
-	case decredplugin.CmdLikeComment:
-		payload, err := g.pluginLikeComment(payload)
-		return decredplugin.CmdLikeComment, payload, err
-	case decredplugin.CmdCensorComment:
-		payload, err := g.pluginCensorComment(payload)
-		return decredplugin.CmdCensorComment, payload, err
-	case decredplugin.CmdGetComments:
-		payload, err := g.pluginGetComments(payload)
-		return decredplugin.CmdGetComments, payload, err
-	case decredplugin.CmdProposalCommentsLikes:
-		payload, err := g.pluginGetProposalCommentsLikes(payload)
-		return decredplugin.CmdProposalCommentsLikes, payload, err
-	case decredplugin.CmdInventory:
-		payload, err := g.pluginInventory()
-		return decredplugin.CmdInventory, payload, err
-	case decredplugin.CmdLoadVoteResults:
-		payload, err := g.pluginLoadVoteResults()
-		return decredplugin.CmdLoadVoteResults, payload, err
-	}
-	return "", "", fmt.Errorf("invalid payload command") // XXX this needs to become a type error
+	reply, err := g.plugins.Exec(command, payload, g.replica)
+	if err == backend.ErrInvalidPluginCmd || err == backend.ErrReadOnly {
+		return "", "", err
+	}
+	return command, reply, err
+}
+
+// EnablePlugin sets up a plugin and serves its commands and hooks.
+//
+// EnablePlugin satisfies the backend PluginHost interface.
+func (g *gitBackEnd) EnablePlugin(d backend.PluginDriver) error {
+	log.Debugf("EnablePlugin: %v", d.Plugin().ID)
+
+	g.Lock()
+	shutdown := g.shutdown
+	g.Unlock()
+	if shutdown {
+		return backend.ErrShutdown
+	}
+
+	return g.plugins.Add(d)
 }
 
 // Close shuts down the backend.  It obtains the lock and sets the shutdown
@@ -2492,6 +2910,12 @@ func (g *gitBackEnd) Close() {
 
 	g.shutdown = true
 	close(g.exit)
+	g.plugins.Close()
+
+	err := g.lock.unlock()
+	if err != nil {
+		log.Errorf("Close: unlock %v: %v", g.root, err)
+	}
 }
 
 // newLocked runs the portion of new that has to be locked.
@@ -2637,33 +3061,34 @@ func New(anp *chaincfg.Params, root string, dcrtimeHost string, gitPath string,
 		exit:            make(chan struct{}),
 		checkAnchor:     make(chan struct{}),
 		testAnchors:     make(map[string]bool),
-		plugins:         []backend.Plugin{getDecredPlugin(anp.Name != "mainnet")},
+		plugins:         &backend.Plugins{},
+		identity:        id,
 	}
-	idJSON, err := id.Marshal()
+
+	// Create jounals path
+	log.Infof("Journals directory: %v", g.journals)
+	err := os.MkdirAll(g.journals, 0760)
 	if err != nil {
 		return nil, err
 	}
-	setDecredPluginSetting(decredPluginIdentity, string(idJSON))
-	setDecredPluginSetting(decredPluginJournals, g.journals)
-	setDecredPluginHook(PluginPostHookEdit, g.decredPluginPostEdit)
 
-	// Create jounals path
-	// XXX this needs to move into plugin init
-	log.Infof("Journals directory: %v", g.journals)
-	err = os.MkdirAll(g.journals, 0760)
+	// Prevent other processes, such as politeiafsck, from using the root
+	// directory while the backend is running.
+	g.lock, err = lockRoot(root)
 	if err != nil {
 		return nil, err
 	}
 
 	g.journal = NewJournal()
 
-	// this function must be called after g.journal is created
-	err = g.initDecredPluginJournals()
+	err = g.newLocked()
 	if err != nil {
 		return nil, err
 	}
 
-	err = g.newLocked()
+	// Store the censorship record signatures of vetted records that
+	// predate stored signatures.
+	err = g.signVettedRecords()
 	if err != nil {
 		return nil, err
 	}
@@ -2678,7 +3103,9 @@ func New(anp *chaincfg.Params, root string, dcrtimeHost string, gitPath string,
 	// Launch cron.
 	err = g.cron.AddFunc(anchorSchedule, func() {
 		// Flush journals
-		g.decredPluginJournalFlusher()
+		if g.plugins.Enabled(decredplugin.ID) {
+			g.decredPluginJournalFlusher()
+		}
 
 		// Anchor commit
 		g.anchorAllReposCronJob()
@@ -18,9 +18,11 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrd/chaincfg"
+	pd "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/mime"
 	"github.com/decred/politeia/politeiad/backend"
 	"github.com/decred/politeia/util"
@@ -40,6 +42,37 @@ func validateMD(got, want *backend.RecordMetadata) error {
 	return nil
 }
 
+// verifyRecordAnchor verifies the record files against the anchor the same
+// way a client does.
+func verifyRecordAnchor(ra *backend.RecordAnchor, token string, files []backend.File) error {
+	a := pd.RecordAnchor{
+		Token:   token,
+		Version: ra.Version,
+		Commit:  base64.StdEncoding.EncodeToString(ra.Commit),
+		Trees:   make([]string, 0, len(ra.Trees)),
+		Path:    ra.Path,
+		Digests: make([]string, 0, len(ra.Digests)),
+		Merkle:  hex.EncodeToString(ra.Merkle),
+	}
+	for _, v := range ra.Trees {
+		a.Trees = append(a.Trees, base64.StdEncoding.EncodeToString(v))
+	}
+	for _, v := range ra.Digests {
+		a.Digests = append(a.Digests, hex.EncodeToString(v))
+	}
+
+	payloads := make(map[string][]byte, len(files))
+	for _, v := range files {
+		b, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return err
+		}
+		payloads[v.Name] = b
+	}
+
+	return pd.VerifyRecordAnchor(a, payloads)
+}
+
 func TestExtendUnextend(t *testing.T) {
 	sha1Digest := make([]byte, sha1.Size)
 	for i := 0; i < sha1.Size; i++ {
@@ -204,6 +237,12 @@ func TestAnchorWithCommits(t *testing.T) {
 			spew.Sdump(pru.Files), spew.Sdump(allFiles[1]))
 	}
 
+	// The record has not been anchored yet
+	_, err = g.GetVettedAnchor(token, "")
+	if err != backend.ErrRecordNotAnchored {
+		t.Fatalf("expected ErrRecordNotAnchored got %v", err)
+	}
+
 	// Anchor all repos
 	t.Logf("===== ANCHOR =====")
 	err = g.anchorAllRepos()
@@ -243,6 +282,26 @@ func TestAnchorWithCommits(t *testing.T) {
 		t.Fatalf("invalid anchor type %v expected %v", anchor.Type,
 			AnchorVerified)
 	}
+	// Verify the record against the unconfirmed anchor
+	ra, err := g.GetVettedAnchor(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.ChainInformation != nil {
+		t.Fatalf("unexpected chain information")
+	}
+	if !bytes.Equal(ra.Merkle, mr[:]) {
+		t.Fatalf("invalid anchor merkle got %x wanted %x", ra.Merkle,
+			mr)
+	}
+	if ra.Version != pru.Version {
+		t.Fatalf("invalid anchor version got %v wanted %v", ra.Version,
+			pru.Version)
+	}
+	err = verifyRecordAnchor(ra, rm[1].Token, allFiles[1])
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Anchor again and make sure nothing changed
 	t.Logf("===== REANCHOR NOTHING TO DO =====")
@@ -319,6 +378,16 @@ func TestAnchorWithCommits(t *testing.T) {
 		t.Fatalf("invalid anchor type %v expected %v", anchor3.Type,
 			AnchorVerified)
 	}
+	// Verify that the record anchor was confirmed
+	ra, err = g.GetVettedAnchor(token, pru.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.ChainInformation == nil ||
+		ra.ChainInformation.Transaction != expectedTestTX {
+		t.Fatalf("invalid chain information %v",
+			spew.Sdump(ra.ChainInformation))
+	}
 	// Verify that Merkle was cleared in last anchor record
 	la, err = g.readLastAnchorRecord()
 	if err != nil {
@@ -558,3 +627,264 @@ func TestUpdateReadme(t *testing.T) {
 	}
 
 }
+
+func TestInventoryPage(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet3Params, dir, "", "", nil,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	// Create three vetted records and one unvetted record
+	for i := 0; i < 3; i++ {
+		newVettedRecord(t, g)
+	}
+	payload := "unvetted record"
+	_, err = g.New([]backend.MetadataStream{}, []backend.File{{
+		Name:    "index.md",
+		MIME:    mime.DetectMimeType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Page through the vetted records
+	f := backend.InventoryFilter{
+		State: backend.InventoryStateVetted,
+		Limit: 2,
+	}
+	seen := make(map[string]struct{})
+	var pages int
+	for {
+		records, cursor, err := g.InventoryPage(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, v := range records {
+			if v.RecordMetadata.Status != backend.MDStatusVetted {
+				t.Fatalf("unexpected status %v",
+					v.RecordMetadata.Status)
+			}
+			if len(v.Files) != 0 {
+				t.Fatalf("unexpected files")
+			}
+			seen[v.RecordMetadata.Token] = struct{}{}
+		}
+		if cursor == "" {
+			break
+		}
+		f.Cursor = cursor
+	}
+	if pages != 2 || len(seen) != 3 {
+		t.Fatalf("unexpected pages %v records %v", pages, len(seen))
+	}
+
+	// Page back from the end of the first page
+	f.Reverse = true
+	records, cursor, err := g.InventoryPage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || cursor != "" {
+		t.Fatalf("unexpected reverse page %v", len(records))
+	}
+
+	tests := []struct {
+		name   string
+		filter backend.InventoryFilter
+		want   int
+	}{
+		{"unvetted", backend.InventoryFilter{
+			State: backend.InventoryStateUnvetted,
+		}, 1},
+		{"status", backend.InventoryFilter{
+			Statuses: []backend.MDStatusT{backend.MDStatusVetted,
+				backend.MDStatusUnvetted},
+		}, 4},
+		{"metadata stream", backend.InventoryFilter{
+			MDStreams: []uint64{0},
+		}, 3},
+		{"missing metadata stream", backend.InventoryFilter{
+			MDStreams: []uint64{1},
+		}, 0},
+		{"future", backend.InventoryFilter{
+			FromTimestamp: time.Now().Unix() + 60,
+		}, 0},
+		{"past", backend.InventoryFilter{
+			UntilTimestamp: time.Now().Unix() + 60,
+		}, 4},
+	}
+	for _, test := range tests {
+		records, cursor, err := g.InventoryPage(test.filter)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if len(records) != test.want || cursor != "" {
+			t.Fatalf("%v: got %v records want %v", test.name,
+				len(records), test.want)
+		}
+	}
+
+	// Only the records of the page are loaded.  Unvetted records are
+	// selected without checking out their branch.
+	records, _, err = g.InventoryPage(backend.InventoryFilter{
+		State:        backend.InventoryStateUnvetted,
+		IncludeFiles: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || len(records[0].Files) != 1 ||
+		records[0].Files[0].Payload != base64.StdEncoding.EncodeToString(
+			[]byte(payload)) {
+		t.Fatalf("unexpected unvetted page %v", spew.Sdump(records))
+	}
+	branch, err := g.gitBranchNow(g.unvetted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != "master" {
+		t.Fatalf("unexpected unvetted branch %v", branch)
+	}
+
+	_, _, err = g.InventoryPage(backend.InventoryFilter{
+		Cursor: "invalid",
+	})
+	if err != backend.ErrInvalidCursor {
+		t.Fatalf("expected %v got %v", backend.ErrInvalidCursor, err)
+	}
+}
+
+// testHookPlugin is a plugin that records the hooks that were called and
+// rejects status changes while reject is set.
+type testHookPlugin struct {
+	reject bool
+	calls  map[backend.HookT][]backend.RecordChange
+}
+
+func (p *testHookPlugin) Plugin() backend.Plugin {
+	return backend.Plugin{
+		ID:      "testhook",
+		Version: "1",
+	}
+}
+
+func (p *testHookPlugin) Setup() error { return nil }
+
+func (p *testHookPlugin) Commands() []backend.PluginCmd { return nil }
+
+func (p *testHookPlugin) Close() {}
+
+func (p *testHookPlugin) Hooks() map[backend.HookT]backend.HookFunc {
+	record := func(h backend.HookT) backend.HookFunc {
+		return func(rc backend.RecordChange) error {
+			p.calls[h] = append(p.calls[h], rc)
+			return nil
+		}
+	}
+	return map[backend.HookT]backend.HookFunc{
+		backend.HookPreNewRecord:  record(backend.HookPreNewRecord),
+		backend.HookPostNewRecord: record(backend.HookPostNewRecord),
+		backend.HookPreSetRecordStatus: func(rc backend.RecordChange) error {
+			if p.reject {
+				return backend.ContentVerificationError{
+					ErrorCode: pd.ErrorStatusInvalidRecordStatusTransition,
+				}
+			}
+			p.calls[backend.HookPreSetRecordStatus] = append(
+				p.calls[backend.HookPreSetRecordStatus], rc)
+			return nil
+		},
+		backend.HookPostSetRecordStatus: record(backend.HookPostSetRecordStatus),
+	}
+}
+
+func TestHooks(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet3Params, dir, "", "", nil,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	p := &testHookPlugin{
+		calls: make(map[backend.HookT][]backend.RecordChange),
+	}
+	err = g.EnablePlugin(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := newVettedRecord(t, g)
+	pre := p.calls[backend.HookPreNewRecord]
+	post := p.calls[backend.HookPostNewRecord]
+	if len(pre) != 1 || pre[0].Token != "" || pre[0].Record != nil {
+		t.Fatalf("unexpected pre new record hooks %v", spew.Sdump(pre))
+	}
+	if len(post) != 1 || post[0].Token != hex.EncodeToString(token) ||
+		post[0].Record == nil {
+		t.Fatalf("unexpected post new record hooks %v",
+			spew.Sdump(post))
+	}
+
+	// A rejected status change must not change the record
+	p.reject = true
+	p.calls = make(map[backend.HookT][]backend.RecordChange)
+	_, err = g.SetVettedStatus(token, backend.MDStatusArchived, nil, nil)
+	if _, ok := err.(backend.ContentVerificationError); !ok {
+		t.Fatalf("expected content verification error got %v", err)
+	}
+	r, err := g.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Status != backend.MDStatusVetted {
+		t.Fatalf("unexpected status %v", r.RecordMetadata.Status)
+	}
+	if len(p.calls[backend.HookPostSetRecordStatus]) != 0 {
+		t.Fatalf("post hook called on rejected status change")
+	}
+
+	// Pre-hooks see the record before the change and post-hooks see the
+	// record after the change.
+	p.reject = false
+	_, err = g.SetVettedStatus(token, backend.MDStatusArchived, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre = p.calls[backend.HookPreSetRecordStatus]
+	post = p.calls[backend.HookPostSetRecordStatus]
+	if len(pre) != 1 || !pre[0].Vetted ||
+		pre[0].Status != backend.MDStatusArchived ||
+		pre[0].Record.RecordMetadata.Status != backend.MDStatusVetted {
+		t.Fatalf("unexpected pre status hooks %v", spew.Sdump(pre))
+	}
+	if len(post) != 1 ||
+		post[0].Record.RecordMetadata.Status != backend.MDStatusArchived {
+		t.Fatalf("unexpected post status hooks %v", spew.Sdump(post))
+	}
+}
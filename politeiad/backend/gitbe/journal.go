@@ -71,6 +71,13 @@ func (j *Journal) Journal(filename, content string) error {
 // the journal file needs to be closed. Note that if the journal is open writes
 // return ErrBusy.
 func (j *Journal) Open(filename string) error {
+	return j.OpenAt(filename, 0)
+}
+
+// OpenAt opens a journal file ready for replay starting at the provided byte
+// offset. The offset must point to the beginning of a line. This call has the
+// same semantics as the Open call.
+func (j *Journal) OpenAt(filename string, offset int64) error {
 	j.Lock()
 	defer j.Unlock()
 
@@ -82,6 +89,11 @@ func (j *Journal) Open(filename string) error {
 	if err != nil {
 		return err
 	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return err
+	}
 
 	j.journals[filename] = &journalFile{
 		file:    f,
@@ -129,6 +141,48 @@ func (j *Journal) Replay(filename string, replay func(string) error) error {
 	return replay(f.scanner.Text())
 }
 
+// ReplayFrom calls replay for every line of the journal file that follows the
+// provided byte offset and returns the offset of the end of the last line.
+// Only the lines that were written before the call are replayed.  Unlike
+// Open, ReplayFrom does not register the journal file as open so concurrent
+// writes do not return ErrBusy.  This works because journal files are append
+// only and every write happens under the journal mutex.
+func (j *Journal) ReplayFrom(filename string, offset int64, replay func(string) error) (int64, error) {
+	j.Lock()
+	f, err := os.Open(filename)
+	if err != nil {
+		j.Unlock()
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	j.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	// We can run unlocked from here
+	size := fi.Size()
+	if offset > size {
+		return 0, fmt.Errorf("offset beyond end of journal: %v %v",
+			offset, size)
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(io.LimitReader(f, size-offset))
+	for scanner.Scan() {
+		offset += int64(len(scanner.Bytes())) + 1
+		err = replay(scanner.Text())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return offset, scanner.Err()
+}
+
 // Copy copies a journal file from source to destination. During the copy
 // process the source file remains locked. This call has the same error
 // semantics as the Open call.
@@ -307,3 +307,135 @@ func TestJournalCopy(t *testing.T) {
 
 	os.RemoveAll(dir)
 }
+
+func TestJournalOpenAt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	t.Logf("TestJournalOpenAt: %v", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewJournal()
+
+	// Test journal
+	count := 1000
+	start := 500
+	var offset int64
+	filename := filepath.Join(dir, "file1")
+	for i := 0; i < count; i++ {
+		line := fmt.Sprintf("%v", i)
+		err = j.Journal(filename, line)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if i < start {
+			offset += int64(len(line)) + 1
+		}
+	}
+
+	// Replay from the middle of the journal
+	err = j.OpenAt(filename, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := start
+	for ; ; i++ {
+		err = j.Replay(filename, func(s string) error {
+			ss := fmt.Sprintf("%v", i)
+			if ss != s {
+				return fmt.Errorf("not equal: %v %v", ss, s)
+			}
+			return nil
+		})
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if i != count {
+		t.Fatalf("invalid count: %v %v", i, count)
+	}
+
+	// Open must fail while the journal is open
+	err = j.OpenAt(filename, offset)
+	if err != ErrBusy {
+		t.Fatal(err)
+	}
+
+	err = j.Close(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.RemoveAll(dir)
+}
+
+func TestJournalReplayFrom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	t.Logf("TestJournalReplayFrom: %v", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := NewJournal()
+
+	// Test journal
+	count := 1000
+	start := 500
+	var offset, size int64
+	filename := filepath.Join(dir, "file1")
+	for i := 0; i < count; i++ {
+		line := fmt.Sprintf("%v", i)
+		err = j.Journal(filename, line)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if i < start {
+			offset += int64(len(line)) + 1
+		}
+		size += int64(len(line)) + 1
+	}
+
+	// Replay from the middle of the journal while writing to it.  The
+	// writes must not fail and must not be replayed.
+	i := start
+	end, err := j.ReplayFrom(filename, offset, func(s string) error {
+		ss := fmt.Sprintf("%v", i)
+		if ss != s {
+			return fmt.Errorf("not equal: %v %v", ss, s)
+		}
+		i++
+		return j.Journal(filename, "new")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != count || end != size {
+		t.Fatalf("invalid count: %v %v end %v %v", i, count, end, size)
+	}
+
+	// Replay the lines that were written during the replay
+	var lines int
+	end, err = j.ReplayFrom(filename, end, func(s string) error {
+		if s != "new" {
+			return fmt.Errorf("unexpected line: %v", s)
+		}
+		lines++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != count-start || end != size+int64(lines*len("new\n")) {
+		t.Fatalf("invalid new lines: %v end %v", lines, end)
+	}
+
+	_, err = j.ReplayFrom(filename, end+1, func(string) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expected offset error")
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+const (
+	// DefaultLockFilename is the name of the file in the root directory
+	// that is locked while the root directory is in use.
+	DefaultLockFilename = "gitbe.lock"
+)
+
+var (
+	// ErrLocked is emitted when the root directory is in use by another
+	// process, typically a running politeiad.
+	ErrLocked = errors.New("root directory is in use by another process")
+)
+
+// lockRoot takes an exclusive lock on the root directory.  The lock is held
+// by the process until it is released or the process exits.  ErrLocked is
+// returned when another process holds the lock.
+func lockRoot(root string) (*rootLock, error) {
+	return lockFile(filepath.Join(root, DefaultLockFilename))
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package gitbe
+
+import (
+	"os"
+	"syscall"
+)
+
+// rootLock is an exclusive advisory lock on a file.
+type rootLock struct {
+	f *os.File
+}
+
+// lockFile creates the provided file if it does not exist and takes an
+// exclusive lock on it.
+func lockFile(filename string) (*rootLock, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return &rootLock{f: f}, nil
+}
+
+// unlock releases the lock.
+func (l *rootLock) unlock() error {
+	return l.f.Close()
+}
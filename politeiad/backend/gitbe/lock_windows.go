@@ -0,0 +1,46 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package gitbe
+
+import (
+	"syscall"
+)
+
+// errSharingViolation is returned by CreateFile when another process has the
+// file open.
+const errSharingViolation syscall.Errno = 32
+
+// rootLock is an exclusive lock on a file.  The file is opened without any
+// sharing so that no other process can open it while the lock is held.
+type rootLock struct {
+	h syscall.Handle
+}
+
+// lockFile creates the provided file if it does not exist and opens it for
+// exclusive access.
+func lockFile(filename string) (*rootLock, error) {
+	name, err := syscall.UTF16PtrFromString(filename)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		if err == errSharingViolation {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return &rootLock{h: h}, nil
+}
+
+// unlock releases the lock.
+func (l *rootLock) unlock() error {
+	return syscall.CloseHandle(l.h)
+}
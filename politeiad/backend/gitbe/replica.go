@@ -0,0 +1,395 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+	"github.com/robfig/cron"
+)
+
+const (
+	// replicaSchedule determines how often a replica pulls the vetted repo
+	// of the primary.
+	// Seconds Minutes Hours Days Months DayOfWeek
+	replicaSchedule = "0 */5 * * * *" // Every 5 minutes
+)
+
+// _signRecords stores the censorship record signature of every record
+// version on the current unvetted branch that predates stored signatures and
+// commits the result.  It returns backend.ErrNoChanges when all versions were
+// already signed.
+//
+// This function must be called WITH the lock held.
+func (g *gitBackEnd) _signRecords() error {
+	dirs, err := ioutil.ReadDir(g.unvetted)
+	if err != nil {
+		return err
+	}
+	for _, v := range dirs {
+		id := v.Name()
+		if !util.IsDigest(id) {
+			continue
+		}
+		versions, err := ioutil.ReadDir(pijoin(g.unvetted, id))
+		if err != nil {
+			return err
+		}
+		for _, vv := range versions {
+			version := vv.Name()
+			if _, err := strconv.ParseUint(version, 10, 64); err != nil {
+				continue
+			}
+			if util.FileExists(pijoin(g.unvetted, id, version,
+				defaultRecordSignatureFilename)) {
+				continue
+			}
+			brm, err := loadMD(g.unvetted, id, version)
+			if err != nil {
+				return fmt.Errorf("%v %v: %v", id, version, err)
+			}
+			err = g.signMD(g.unvetted, id, version, brm)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !g.gitHasChanges(g.unvetted) {
+		return backend.ErrNoChanges
+	}
+
+	return g.gitCommit(g.unvetted, "Add censorship record signatures")
+}
+
+// signVettedRecords stores the censorship record signatures of all vetted
+// records that were created before signatures were stored.  This allows
+// replicas to serve these records.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) signVettedRecords() error {
+	if g.identity == nil {
+		return nil
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	// git checkout master
+	err := g.gitCheckout(g.unvetted, "master")
+	if err != nil {
+		return err
+	}
+
+	// git pull --ff-only --rebase
+	err = g.gitPull(g.unvetted, true)
+	if err != nil {
+		return err
+	}
+
+	// git checkout -b timestamp_signrecords
+	branch := strconv.FormatInt(time.Now().Unix(), 10) + "_signrecords"
+	_ = g.gitBranchDelete(g.unvetted, branch) // Just in case
+	err = g.gitNewBranch(g.unvetted, branch)
+	if err != nil {
+		return err
+	}
+
+	err = g._signRecords()
+	if err != nil {
+		err2 := g.gitUnwindBranch(g.unvetted, branch)
+		if err2 != nil {
+			// We are in trouble and should consider a panic
+			log.Criticalf("signVettedRecords: %v", err2)
+		}
+		if err == backend.ErrNoChanges {
+			return nil
+		}
+		return err
+	}
+
+	log.Infof("Signed censorship records of vetted records")
+
+	// create and rebase PR
+	return g.rebasePR(branch)
+}
+
+// flushedJournal returns the filename of the most recently flushed journal
+// of a vetted record.  An empty filename is returned if the journal was
+// never flushed.
+//
+// This function must be called WITH the lock held.
+func (g *gitBackEnd) flushedJournal(token, journal string) (string, error) {
+	latest, err := getLatest(pijoin(g.vetted, token))
+	if err != nil {
+		return "", err
+	}
+	version, err := strconv.Atoi(latest)
+	if err != nil {
+		return "", err
+	}
+
+	// Journals are flushed into the latest version of a record so
+	// older versions may carry the most recent one.
+	for ; version > 0; version-- {
+		filename := pijoin(g.vetted, token, strconv.Itoa(version),
+			pluginDataDir, journal)
+		if util.FileExists(filename) {
+			return filename, nil
+		}
+	}
+
+	return "", nil
+}
+
+// sameContent returns true if both files exist and have identical content.
+func sameContent(a, b string) (bool, error) {
+	ca, err := ioutil.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := ioutil.ReadFile(b)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return bytes.Equal(ca, cb), nil
+}
+
+// copyFlushedJournals copies the journals that were flushed into the vetted
+// repo by the primary into the journals directory.  It returns the tokens of
+// the records with modified journals.
+//
+// This function must be called WITH the lock held.
+func (g *gitBackEnd) copyFlushedJournals() ([]string, error) {
+	dirs, err := ioutil.ReadDir(g.vetted)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]string, 0, len(dirs))
+	for _, v := range dirs {
+		token := v.Name()
+		if !util.IsDigest(token) {
+			continue
+		}
+
+		var modified bool
+		for _, journal := range []string{defaultCommentFilename,
+			defaultBallotFilename} {
+			source, err := g.flushedJournal(token, journal)
+			if err != nil {
+				return nil, err
+			}
+			if source == "" {
+				continue
+			}
+			destination := pijoin(g.journals, token, journal)
+			same, err := sameContent(source, destination)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+
+			err = os.MkdirAll(pijoin(g.journals, token), 0774)
+			if err != nil {
+				return nil, err
+			}
+			err = g.journal.Copy(source, destination)
+			if err != nil {
+				return nil, err
+			}
+			modified = true
+		}
+		if modified {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+// pullPrimary pulls the vetted repo of the primary and copies the flushed
+// journals.  It returns the tokens of the records with modified journals.
+//
+// This function must be called WITH the lock held.
+func (g *gitBackEnd) pullPrimary() ([]string, error) {
+	// git checkout master
+	err := g.gitCheckout(g.vetted, "master")
+	if err != nil {
+		return nil, err
+	}
+
+	// git pull --ff-only --rebase
+	err = g.gitPull(g.vetted, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.copyFlushedJournals()
+}
+
+// syncReplica pulls the vetted repo of the primary and replays the journals
+// that were modified.
+//
+// This function must be called WITHOUT the lock held.
+func (g *gitBackEnd) syncReplica() error {
+	log.Tracef("syncReplica")
+
+	g.Lock()
+	if g.shutdown {
+		g.Unlock()
+		return backend.ErrShutdown
+	}
+	tokens, err := g.pullPrimary()
+	g.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !g.plugins.Enabled(decredplugin.ID) {
+		return nil
+	}
+	for _, token := range tokens {
+		log.Debugf("Replaying journals: %v", token)
+		err := g.replayBallot(token)
+		if err != nil {
+			return fmt.Errorf("replayBallot %v: %v", token, err)
+		}
+		_, err = g.replayComments(token)
+		if err != nil {
+			return fmt.Errorf("replayComments %v: %v", token, err)
+		}
+	}
+
+	return nil
+}
+
+// newReplicaLocked runs the portion of NewReplica that has to be locked.
+func (g *gitBackEnd) newReplicaLocked() error {
+	g.Lock()
+	defer g.Unlock()
+
+	// Ensure git works
+	version, err := g.gitVersion()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Git version: %v", version)
+
+	_, err = os.Stat(pijoin(g.vetted, ".git"))
+	switch {
+	case os.IsNotExist(err):
+		// Clone the vetted repo of the primary.  gitClone is not used
+		// because the primary is usually not a local repo.
+		log.Infof("Cloning git repo %v to %v", g.primary, g.vetted)
+		_, err = g.git("", "clone", g.primary, g.vetted)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		// Refuse to follow a different primary
+		out, err := g.git(g.vetted, "config", "--get",
+			"remote.origin.url")
+		if err != nil {
+			return err
+		}
+		if len(out) == 0 || out[0] != g.primary {
+			return fmt.Errorf("vetted repo does not follow %v",
+				g.primary)
+		}
+	}
+
+	log.Infof("Running git fsck on vetted repository")
+	_, err = g.gitFsck(g.vetted)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.pullPrimary()
+	return err
+}
+
+// NewReplica returns a read-only gitBackEnd context that follows the vetted
+// repo of a primary.  Replicas serve vetted records, the record inventory and
+// the plugin commands that do not modify any state.  The comments and ballot
+// journals are rebuilt from the journals that the primary flushed into the
+// vetted repo once the decred plugin is enabled.
+func NewReplica(anp *chaincfg.Params, root, primary, gitPath string, gitTrace bool) (*gitBackEnd, error) {
+	// Default to system git
+	if gitPath == "" {
+		gitPath = "git"
+	}
+
+	g := &gitBackEnd{
+		activeNetParams: anp,
+		root:            root,
+		cron:            cron.New(),
+		unvetted:        filepath.Join(root, DefaultUnvettedPath),
+		vetted:          filepath.Join(root, DefaultVettedPath),
+		journals:        filepath.Join(root, DefaultJournalsPath),
+		gitPath:         gitPath,
+		gitTrace:        gitTrace,
+		exit:            make(chan struct{}),
+		checkAnchor:     make(chan struct{}),
+		testAnchors:     make(map[string]bool),
+		plugins:         &backend.Plugins{},
+		replica:         true,
+		primary:         primary,
+	}
+
+	log.Infof("Journals directory: %v", g.journals)
+	err := os.MkdirAll(g.journals, 0760)
+	if err != nil {
+		return nil, err
+	}
+
+	g.lock, err = lockRoot(root)
+	if err != nil {
+		return nil, err
+	}
+
+	g.journal = NewJournal()
+
+	// Journals are copied from the vetted repo so they are replayed by
+	// the decred plugin once it is enabled.
+	err = g.newReplicaLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Launch cron.
+	err = g.cron.AddFunc(replicaSchedule, func() {
+		err := g.syncReplica()
+		if err != nil {
+			log.Errorf("syncReplica: %v", err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	g.cron.Start()
+
+	log.Infof("Replica of: %v", g.primary)
+
+	return g, nil
+}
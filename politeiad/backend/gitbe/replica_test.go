@@ -0,0 +1,286 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+	"github.com/decred/slog"
+)
+
+// newVettedRecord creates a record and makes it public.
+func newVettedRecord(t *testing.T, g *gitBackEnd) []byte {
+	t.Helper()
+
+	r, err := util.Random(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := hex.EncodeToString(r)
+	rm, err := g.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{{
+		Name:    "index.md",
+		MIME:    mime.DetectMimeType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyMD := []backend.MetadataStream{}
+	_, err = g.SetUnvettedStatus(token, backend.MDStatusVetted, emptyMD,
+		emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// verifySignature verifies the stored censorship record signature.
+func verifySignature(t *testing.T, id *identity.FullIdentity, r *backend.Record) {
+	t.Helper()
+
+	s, err := identity.SignatureFromString(r.Signature)
+	if err != nil {
+		t.Fatalf("invalid signature %v: %v", r.RecordMetadata.Token, err)
+	}
+	if !id.Public.VerifyMessage([]byte(r.RecordMetadata.Merkle+
+		r.RecordMetadata.Token), *s) {
+		t.Fatalf("signature verification failed %v",
+			r.RecordMetadata.Token)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := New(&chaincfg.TestNet3Params, pijoin(dir, "primary"), "", "",
+		id, testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+	enableDecredPlugin(t, g)
+
+	// Records of a primary without an identity are not signed
+	g.identity = nil
+	legacy := newVettedRecord(t, g)
+	r, err := g.GetVetted(legacy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Signature != "" {
+		t.Fatalf("unexpected signature %v", r.Signature)
+	}
+
+	// Sign legacy records
+	g.identity = id
+	err = g.signVettedRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = g.GetVetted(legacy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySignature(t, id, r)
+
+	// New records are signed
+	token := newVettedRecord(t, g)
+	r, err = g.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySignature(t, id, r)
+
+	// Follow the primary
+	rg, err := NewReplica(&chaincfg.TestNet3Params, pijoin(dir, "replica"),
+		g.vetted, "", testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rg.Close()
+	enableDecredPlugin(t, rg)
+
+	rr, err := rg.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Signature != r.Signature {
+		t.Fatalf("unexpected signature got %v want %v", rr.Signature,
+			r.Signature)
+	}
+	vetted, unvetted, err := rg.Inventory(0, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 2 || len(unvetted) != 0 {
+		t.Fatalf("unexpected inventory %v %v", len(vetted),
+			len(unvetted))
+	}
+
+	// Writes are rejected
+	err = rg.UpdateReadme("replica")
+	if err != backend.ErrReadOnly {
+		t.Fatalf("unexpected error got %v want %v", err,
+			backend.ErrReadOnly)
+	}
+	emptyMD := []backend.MetadataStream{}
+	_, err = rg.SetVettedStatus(token, backend.MDStatusArchived, emptyMD,
+		emptyMD)
+	if err != backend.ErrReadOnly {
+		t.Fatalf("unexpected error got %v want %v", err,
+			backend.ErrReadOnly)
+	}
+	_, _, err = rg.Plugin(decredplugin.CmdNewComment, "")
+	if err != backend.ErrReadOnly {
+		t.Fatalf("unexpected error got %v want %v", err,
+			backend.ErrReadOnly)
+	}
+	_, _, err = rg.Plugin(decredplugin.CmdInventory, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flush a comments journal on the primary
+	c, err := decredplugin.EncodeComment(decredplugin.Comment{
+		Token:     hex.EncodeToString(token),
+		CommentID: "1",
+		Comment:   "comment",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(pijoin(g.journals, hex.EncodeToString(token)), 0760)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfilename := pijoin(g.journals, hex.EncodeToString(token),
+		defaultCommentFilename)
+	err = g.journal.Journal(cfilename, string(journalAdd)+string(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.flushCommentJournals()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sync the replica with the new record and the flushed journal
+	newVettedRecord(t, g)
+	err = rg.syncReplica()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vetted, _, err = rg.Inventory(0, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 3 {
+		t.Fatalf("unexpected inventory %v", len(vetted))
+	}
+	same, err := sameContent(cfilename, pijoin(rg.journals,
+		hex.EncodeToString(token), defaultCommentFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same {
+		t.Fatalf("comments journal not replicated")
+	}
+	if len(decredPluginCommentsCache[hex.EncodeToString(token)]) != 1 {
+		t.Fatalf("comments journal not replayed")
+	}
+}
+
+func TestSetIdentity(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := New(&chaincfg.TestNet3Params, dir, "", "", id,
+		testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.test = true
+	defer g.Close()
+
+	old := newVettedRecord(t, g)
+
+	// Rotate identity
+	next, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.SetIdentity(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored signatures are not modified
+	r, err := g.GetVetted(old, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySignature(t, id, r)
+
+	// New records and status changes are signed with the new identity
+	token := newVettedRecord(t, g)
+	r, err = g.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySignature(t, next, r)
+
+	emptyMD := []backend.MetadataStream{}
+	_, err = g.SetVettedStatus(old, backend.MDStatusArchived, emptyMD,
+		emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = g.GetVetted(old, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySignature(t, next, r)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
+)
+
+// _voteBundle loads the vote authorizations of all record versions, the start
+// vote and the cast votes of a proposal.
+//
+// This function must be called with the lock held.
+func (g *gitBackEnd) _voteBundle(token string) (*decredplugin.VoteBundleReply, error) {
+	err := g.gitCheckout(g.vetted, "master")
+	if err != nil {
+		return nil, err
+	}
+
+	vbr := decredplugin.VoteBundleReply{
+		Version:        decredplugin.VersionVoteBundleReply,
+		Token:          token,
+		AuthorizeVotes: []decredplugin.VoteBundleAuthorizeVote{},
+		CastVotes:      []decredplugin.VoteBundleCastVote{},
+	}
+
+	// Start vote and ticket snapshot
+	b, err := ioutil.ReadFile(mdFilename(g.vetted, token,
+		decredplugin.MDStreamVoteBits))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("vote not started: %v", token)
+		}
+		return nil, err
+	}
+	sv, err := decredplugin.DecodeStartVote(b)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeStartVote: %v", err)
+	}
+	vbr.StartVote = *sv
+
+	b, err = ioutil.ReadFile(mdFilename(g.vetted, token,
+		decredplugin.MDStreamVoteSnapshot))
+	if err != nil {
+		return nil, err
+	}
+	svr, err := decredplugin.DecodeStartVoteReply(b)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeStartVoteReply: %v", err)
+	}
+	vbr.StartVoteReply = *svr
+
+	// Vote authorizations of all record versions
+	latest, err := getLatest(pijoin(g.vetted, token))
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseUint(latest, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(1); i <= n; i++ {
+		version := strconv.FormatUint(i, 10)
+		b, err := ioutil.ReadFile(pijoin(g.vetted, token, version,
+			fmt.Sprintf("%02v%v", decredplugin.MDStreamAuthorizeVote,
+				defaultMDFilenameSuffix)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		av, err := decredplugin.DecodeAuthorizeVote(b)
+		if err != nil {
+			return nil, fmt.Errorf("DecodeAuthorizeVote: %v", err)
+		}
+
+		// Record updates copy the metadata of the previous version,
+		// skip authorizations that were carried over.
+		l := len(vbr.AuthorizeVotes)
+		if l > 0 &&
+			vbr.AuthorizeVotes[l-1].AuthorizeVote.Receipt == av.Receipt {
+			continue
+		}
+		vbr.AuthorizeVotes = append(vbr.AuthorizeVotes,
+			decredplugin.VoteBundleAuthorizeVote{
+				AuthorizeVote: *av,
+				RecordVersion: version,
+			})
+	}
+
+	// Cast votes
+	cvj, err := g.ballotJournal(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range cvj {
+		vbr.CastVotes = append(vbr.CastVotes,
+			decredplugin.VoteBundleCastVote{
+				CastVote: v.CastVote,
+				Receipt:  v.Receipt,
+			})
+	}
+
+	return &vbr, nil
+}
+
+// pluginVoteBundle returns the signed vote bundle of a proposal.  The bundle
+// contains the largest commitment address of every ticket that voted so that
+// the vote signatures can be verified offline and the identity history so
+// that the receipts can be verified from a single trusted key.
+func (g *gitBackEnd) pluginVoteBundle(payload string, history func() []pd.IdentityKey) (string, error) {
+	log.Tracef("pluginVoteBundle: %v", payload)
+
+	vb, err := decredplugin.DecodeVoteBundle([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeVoteBundle %v", err)
+	}
+
+	// Verify proposal exists, we can run this lockless
+	if !g.propExists(g.vetted, vb.Token) {
+		return "", fmt.Errorf("proposal not found: %v", vb.Token)
+	}
+
+	fi, err := decredPluginFullIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	g.Lock()
+	if g.shutdown {
+		g.Unlock()
+		return "", backend.ErrShutdown
+	}
+	vbr, err := g._voteBundle(vb.Token)
+	g.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	// Lookup the commitment addresses of the tickets that voted
+	addrs, err := decredvote.CommitmentAddresses(g.chain, vbr.CastVotes)
+	if err != nil {
+		return "", err
+	}
+	for k := range vbr.CastVotes {
+		vbr.CastVotes[k].Address = addrs[k]
+	}
+
+	// Sign bundle
+	vbr.Timestamp = time.Now().Unix()
+	vbr.Identity = decredvote.VoteBundleIdentity(fi, history)
+	vbr.PublicKey = hex.EncodeToString(fi.Public.Key[:])
+	d, err := vbr.Digest()
+	if err != nil {
+		return "", err
+	}
+	signature := fi.SignMessage(d)
+	vbr.Signature = hex.EncodeToString(signature[:])
+
+	reply, err := decredplugin.EncodeVoteBundleReply(*vbr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeVoteBundleReply: %v", err)
+	}
+
+	return string(reply), nil
+}
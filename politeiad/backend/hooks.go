@@ -0,0 +1,97 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+// HookT identifies a point in the record life cycle at which the backend
+// calls the plugin hooks.
+type HookT string
+
+const (
+	// Pre-hooks are called before a record is changed.  A pre-hook that
+	// fails rejects the change.  Pre-hooks should return a
+	// ContentVerificationError so that the reason is returned to the
+	// client.  Pre-hooks, except HookPreNewRecord, are called with the
+	// backend lock held so the record they validate can not change
+	// before the change is applied.  Hooks must therefore not call back
+	// into the backend.
+	HookPreNewRecord       HookT = "prenewrecord"
+	HookPreEditRecord      HookT = "preeditrecord"
+	HookPreSetRecordStatus HookT = "presetrecordstatus"
+	HookPreEditMetadata    HookT = "preeditmetadata"
+
+	// Post-hooks are called after a record was changed.  A post-hook that
+	// fails is logged but does not undo the change.
+	HookPostNewRecord       HookT = "postnewrecord"
+	HookPostEditRecord      HookT = "posteditrecord"
+	HookPostSetRecordStatus HookT = "postsetrecordstatus"
+	HookPostEditMetadata    HookT = "posteditmetadata"
+
+	// HookPostEdit is called after the files of a record were updated but
+	// before the change is stored.  A hook that fails aborts the update.
+	HookPostEdit HookT = "postedit"
+)
+
+// RecordChange describes the record change that is passed to the hooks.
+type RecordChange struct {
+	Token       string           // Record token, empty before a new record
+	Vetted      bool             // Change applies to a vetted record
+	Status      MDStatusT        // New status, status changes only
+	MDAppend    []MetadataStream // Metadata streams that are appended
+	MDOverwrite []MetadataStream // Metadata streams that are overwritten
+	FilesAdd    []File           // Files that are added
+	FilesDel    []string         // Files that are deleted
+
+	// Record is the record before the change for pre-hooks and the
+	// record after the change for post-hooks.  It is nil before a new
+	// record is created.
+	Record *Record
+}
+
+// HookFunc is called with the record change.  Returning an error aborts the
+// remaining hooks and, for pre-hooks, the change.
+type HookFunc func(RecordChange) error
+
+// hooks returns the hooks of all enabled plugins for the provided hook point
+// in the order in which the plugins were enabled.
+func (p *Plugins) hooks(h HookT) []HookFunc {
+	p.RLock()
+	defer p.RUnlock()
+
+	var hooks []HookFunc
+	for _, d := range p.drivers {
+		f, ok := d.Hooks()[h]
+		if ok {
+			hooks = append(hooks, f)
+		}
+	}
+	return hooks
+}
+
+// Hook calls the hooks of all enabled plugins for the provided hook point.
+// The record is looked up only when there are hooks to call, record may be
+// nil if the change does not have a record.  The first hook that fails aborts
+// the remaining hooks.
+func (p *Plugins) Hook(h HookT, rc RecordChange, record func() (*Record, error)) error {
+	hooks := p.hooks(h)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	if record != nil {
+		r, err := record()
+		if err != nil {
+			return err
+		}
+		rc.Record = r
+	}
+	for _, f := range hooks {
+		err := f(rc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
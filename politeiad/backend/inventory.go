@@ -0,0 +1,222 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InventoryStateT selects vetted and/or unvetted records in an inventory
+// request.
+type InventoryStateT int
+
+const (
+	// Inventory states
+	InventoryStateAll      InventoryStateT = 0 // Vetted and unvetted records
+	InventoryStateVetted   InventoryStateT = 1 // Vetted and archived records
+	InventoryStateUnvetted InventoryStateT = 2 // Unvetted and censored records
+
+	// InventoryPageSize is the maximum number of records that are returned
+	// by a single InventoryPage call.
+	InventoryPageSize = 100
+)
+
+var (
+	// ErrInvalidCursor is emitted when an inventory cursor can not be
+	// decoded.
+	ErrInvalidCursor = errors.New("invalid inventory cursor")
+)
+
+// InventoryFilter selects a page of records.  Records are ordered by the most
+// recent timestamp first; records with the same timestamp are ordered by
+// token.  The cursor is returned by the previous page and is opaque to the
+// caller.  Reverse pages from the cursor towards more recent records, which
+// are then returned oldest first.
+type InventoryFilter struct {
+	State          InventoryStateT // Vetted and/or unvetted records
+	Statuses       []MDStatusT     // Only these statuses, all if empty
+	FromTimestamp  int64           // Only records updated at or after, 0 if unbounded
+	UntilTimestamp int64           // Only records updated before, 0 if unbounded
+	MDStreams      []uint64        // Metadata streams that must be present
+	IncludeFiles   bool            // Include record files
+	Cursor         string          // Cursor of the previous page
+	Limit          uint            // Page size, capped at InventoryPageSize
+	Reverse        bool            // Page towards more recent records
+}
+
+// InventoryCursor returns the cursor that points at the provided record.
+// The next page starts with the record that follows it.
+func InventoryCursor(r Record) string {
+	return strconv.FormatInt(r.RecordMetadata.Timestamp, 10) + ":" +
+		r.RecordMetadata.Token
+}
+
+// parseInventoryCursor decodes an inventory cursor into the timestamp and
+// token of the record it points at.
+func parseInventoryCursor(cursor string) (int64, string, error) {
+	s := strings.SplitN(cursor, ":", 2)
+	if len(s) != 2 || s[1] == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	timestamp, err := strconv.ParseInt(s[0], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return timestamp, s[1], nil
+}
+
+// inventoryLess returns true if the record with timestamp ta and token a is
+// ordered before the record with timestamp tb and token b.
+func inventoryLess(ta int64, a string, tb int64, b string) bool {
+	if ta != tb {
+		return ta > tb
+	}
+	return a < b
+}
+
+// isVettedStatus returns true if the status belongs to a vetted record.
+func isVettedStatus(s MDStatusT) bool {
+	return s == MDStatusVetted || s == MDStatusArchived
+}
+
+// matchInventoryFilter returns true if the record is selected by the filter.
+// The cursor is not taken into account.
+func matchInventoryFilter(r Record, f InventoryFilter) bool {
+	rm := r.RecordMetadata
+	switch f.State {
+	case InventoryStateVetted:
+		if !isVettedStatus(rm.Status) {
+			return false
+		}
+	case InventoryStateUnvetted:
+		if isVettedStatus(rm.Status) {
+			return false
+		}
+	}
+
+	if len(f.Statuses) > 0 {
+		var found bool
+		for _, v := range f.Statuses {
+			if v == rm.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.FromTimestamp != 0 && rm.Timestamp < f.FromTimestamp {
+		return false
+	}
+	if f.UntilTimestamp != 0 && rm.Timestamp >= f.UntilTimestamp {
+		return false
+	}
+
+	for _, id := range f.MDStreams {
+		var found bool
+		for _, v := range r.Metadata {
+			if v.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InventoryMatcher returns a function that returns true if a record is
+// selected by the filter and is ordered after the cursor of the filter.  The
+// function only uses the record metadata and the IDs of the metadata streams
+// of a record, which allows backends to select their records before the rest
+// of a record is loaded.
+func InventoryMatcher(f InventoryFilter) (func(Record) bool, error) {
+	var (
+		ct     int64
+		ctoken string
+		err    error
+	)
+	if f.Cursor != "" {
+		ct, ctoken, err = parseInventoryCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(r Record) bool {
+		if !matchInventoryFilter(r, f) {
+			return false
+		}
+		if f.Cursor == "" {
+			return true
+		}
+
+		// Skip the records up to and including the cursor
+		rm := r.RecordMetadata
+		if f.Reverse {
+			return inventoryLess(rm.Timestamp, rm.Token, ct, ctoken)
+		}
+		return inventoryLess(ct, ctoken, rm.Timestamp, rm.Token)
+	}, nil
+}
+
+// SortInventory sorts the records in the page order of the filter.
+func SortInventory(records []Record, f InventoryFilter) {
+	sort.Slice(records, func(i, j int) bool {
+		a := records[i].RecordMetadata
+		b := records[j].RecordMetadata
+		if f.Reverse {
+			return inventoryLess(b.Timestamp, b.Token, a.Timestamp,
+				a.Token)
+		}
+		return inventoryLess(a.Timestamp, a.Token, b.Timestamp,
+			b.Token)
+	})
+}
+
+// PageInventory returns the first page of the sorted records that were
+// selected by the filter and the cursor of the next page.  The cursor is empty
+// when there are no more records.
+func PageInventory(records []Record, f InventoryFilter) ([]Record, string) {
+	limit := int(f.Limit)
+	if limit == 0 || limit > InventoryPageSize {
+		limit = InventoryPageSize
+	}
+	if len(records) <= limit {
+		return records, ""
+	}
+	page := records[:limit]
+
+	return page, InventoryCursor(page[len(page)-1])
+}
+
+// FilterInventory returns the page of records that is selected by the filter
+// and the cursor of the next page.  The cursor is empty when there are no
+// more records.  It is used by backends that load their entire inventory.
+func FilterInventory(records []Record, f InventoryFilter) ([]Record, string, error) {
+	match, err := InventoryMatcher(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	matched := make([]Record, 0, len(records))
+	for _, v := range records {
+		if match(v) {
+			matched = append(matched, v)
+		}
+	}
+	SortInventory(matched, f)
+	page, cursor := PageInventory(matched, f)
+
+	return page, cursor, nil
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+)
+
+var (
+	// ErrInvalidPlugin is emitted when a plugin is not registered or not
+	// supported by the backend.
+	ErrInvalidPlugin = errors.New("invalid plugin")
+
+	// ErrInvalidPluginCmd is emitted when a plugin command is not served by
+	// any of the enabled plugins.
+	ErrInvalidPluginCmd = errors.New("invalid plugin command")
+
+	// pluginsMtx protects the plugin constructors.
+	pluginsMtx sync.RWMutex
+
+	// pluginConstructors contains the constructors of all registered
+	// plugins.  A plugin that is served by several backends has a
+	// constructor per backend.
+	pluginConstructors = make(map[string][]PluginConstructor) // [id]constructors
+)
+
+// PluginCmd describes a command that is served by a plugin.
+type PluginCmd struct {
+	Command  string                       // Command identifier
+	ReadOnly bool                         // Command does not modify any state
+	Exec     func(string) (string, error) // Execute command with payload
+}
+
+// PluginDriver is the interface that all backend plugins must implement.
+type PluginDriver interface {
+	// Plugin returns the identifier, version and settings of the plugin.
+	// The settings are published to the plugin clients.
+	Plugin() Plugin
+
+	// Setup performs the plugin initialization, such as creating its data
+	// directory and loading its state.
+	Setup() error
+
+	// Commands returns the commands that are served by the plugin.
+	Commands() []PluginCmd
+
+	// Hooks returns the record hooks of the plugin.
+	Hooks() map[HookT]HookFunc
+
+	// Close performs cleanup of the plugin.
+	Close()
+}
+
+// PluginConfig is passed to a plugin constructor.
+type PluginConfig struct {
+	Backend  Backend                // Backend the plugin is enabled on
+	DataDir  string                 // Data directory of the backend
+	Identity *identity.FullIdentity // Identity of politeiad, nil on replicas
+	TestNet  bool                   // Running on a test network
+	Settings []PluginSetting        // Settings from the politeiad config
+
+	// IdentityHistory returns the identity history of politeiad, oldest
+	// key first.  It is nil on replicas.
+	IdentityHistory func() []v1.IdentityKey
+}
+
+// PluginConstructor returns a new plugin context.  It must return
+// ErrInvalidPlugin if the plugin does not support the provided backend.
+type PluginConstructor func(PluginConfig) (PluginDriver, error)
+
+// PluginHost is implemented by backends that serve plugins.  Plugins must be
+// enabled before the backend serves requests.
+type PluginHost interface {
+	EnablePlugin(PluginDriver) error
+}
+
+// RegisterPlugin makes a plugin available to politeiad under the provided
+// identifier.  It is meant to be called from the init function of the package
+// that implements the plugin.  Backends that implement the same plugin each
+// register a constructor under the same identifier.  It panics if the
+// identifier is invalid.
+func RegisterPlugin(id string, c PluginConstructor) {
+	pluginsMtx.Lock()
+	defer pluginsMtx.Unlock()
+
+	if PluginRE.FindString(id) != id {
+		panic(fmt.Sprintf("invalid plugin id: %v", id))
+	}
+	pluginConstructors[id] = append(pluginConstructors[id], c)
+}
+
+// RegisteredPlugins returns the sorted identifiers of all registered plugins.
+func RegisteredPlugins() []string {
+	pluginsMtx.RLock()
+	defer pluginsMtx.RUnlock()
+
+	ids := make([]string, 0, len(pluginConstructors))
+	for k := range pluginConstructors {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// NewPlugin returns a new context of the registered plugin with the provided
+// identifier.  The constructors of the plugin are tried in registration order
+// and the first one that supports the configured backend is used.
+// ErrInvalidPlugin is returned when none of them does.
+func NewPlugin(id string, cfg PluginConfig) (PluginDriver, error) {
+	pluginsMtx.RLock()
+	cs := pluginConstructors[id]
+	pluginsMtx.RUnlock()
+
+	for _, c := range cs {
+		d, err := c(cfg)
+		if err == ErrInvalidPlugin {
+			continue
+		}
+		return d, err
+	}
+
+	return nil, ErrInvalidPlugin
+}
+
+// pluginCmd is a command and the identifier of the plugin that serves it.
+type pluginCmd struct {
+	id  string
+	cmd PluginCmd
+}
+
+// Plugins dispatches commands and hooks to the plugins that are enabled on a
+// backend.  Hooks are called in the order in which the plugins were enabled.
+type Plugins struct {
+	sync.RWMutex
+
+	drivers  []PluginDriver       // Enabled plugins
+	commands map[string]pluginCmd // [command]pluginCmd
+}
+
+// Add sets up a plugin and enables it.  The plugin identifier and all of its
+// commands must be unique.
+func (p *Plugins) Add(d PluginDriver) error {
+	p.Lock()
+	defer p.Unlock()
+
+	id := d.Plugin().ID
+	if PluginRE.FindString(id) != id {
+		return fmt.Errorf("invalid plugin id: %v", id)
+	}
+	for _, v := range p.drivers {
+		if v.Plugin().ID == id {
+			return fmt.Errorf("duplicate plugin: %v", id)
+		}
+	}
+	if p.commands == nil {
+		p.commands = make(map[string]pluginCmd)
+	}
+	cmds := d.Commands()
+	for _, v := range cmds {
+		if c, ok := p.commands[v.Command]; ok {
+			return fmt.Errorf("plugin %v command %v is already "+
+				"served by plugin %v", id, v.Command, c.id)
+		}
+	}
+
+	err := d.Setup()
+	if err != nil {
+		return fmt.Errorf("plugin %v setup: %v", id, err)
+	}
+
+	p.drivers = append(p.drivers, d)
+	for _, v := range cmds {
+		p.commands[v.Command] = pluginCmd{
+			id:  id,
+			cmd: v,
+		}
+	}
+
+	return nil
+}
+
+// Enabled returns true if the plugin with the provided identifier is enabled.
+func (p *Plugins) Enabled(id string) bool {
+	p.RLock()
+	defer p.RUnlock()
+
+	for _, v := range p.drivers {
+		if v.Plugin().ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Plugins returns the identifier, version and settings of all enabled
+// plugins.
+func (p *Plugins) Plugins() []Plugin {
+	p.RLock()
+	defer p.RUnlock()
+
+	plugins := make([]Plugin, 0, len(p.drivers))
+	for _, v := range p.drivers {
+		plugins = append(plugins, v.Plugin())
+	}
+	return plugins
+}
+
+// Command returns the identifier of the plugin that serves the command and
+// whether the command is read-only.
+func (p *Plugins) Command(command string) (string, bool, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	c, ok := p.commands[command]
+	if !ok {
+		return "", false, ErrInvalidPluginCmd
+	}
+	return c.id, c.cmd.ReadOnly, nil
+}
+
+// Exec executes a plugin command.  Commands that are not read-only are
+// rejected with ErrReadOnly when readOnly is set.
+func (p *Plugins) Exec(command, payload string, readOnly bool) (string, error) {
+	p.RLock()
+	c, ok := p.commands[command]
+	p.RUnlock()
+	if !ok {
+		return "", ErrInvalidPluginCmd
+	}
+	if readOnly && !c.cmd.ReadOnly {
+		return "", ErrReadOnly
+	}
+
+	return c.cmd.Exec(payload)
+}
+
+// SetIdentity replaces the identity of the enabled plugins that sign data
+// with the politeiad identity.  Plugins that do not implement IdentitySetter
+// are skipped.
+func (p *Plugins) SetIdentity(id *identity.FullIdentity) error {
+	p.RLock()
+	defer p.RUnlock()
+
+	for _, v := range p.drivers {
+		setter, ok := v.(IdentitySetter)
+		if !ok {
+			continue
+		}
+		err := setter.SetIdentity(id)
+		if err != nil {
+			return fmt.Errorf("plugin %v: %v", v.Plugin().ID, err)
+		}
+	}
+	return nil
+}
+
+// Close closes all enabled plugins.
+func (p *Plugins) Close() {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, v := range p.drivers {
+		v.Close()
+	}
+}
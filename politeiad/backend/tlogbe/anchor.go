@@ -0,0 +1,296 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	dcrtime "github.com/decred/dcrtime/api/v1"
+	"github.com/decred/dcrtime/merkle"
+	v1 "github.com/decred/politeia/tlog/api/v1"
+	"github.com/decred/politeia/util"
+	"github.com/syndtr/goleveldb/leveldb"
+	lutil "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// anchorSchedule determines how often we anchor the record trees.
+	// Seconds Minutes Hours Days Months DayOfWeek
+	anchorSchedule = "0 56 * * * *" // At 56 minutes every hour
+
+	// keyUnconfirmed is the database key of the anchors that have been
+	// sent to dcrtime but have not been confirmed yet.
+	keyUnconfirmed = "unconfirmed"
+
+	// expectedTestTX is the transaction used for fake anchors in test
+	// mode.
+	expectedTestTX = "TESTTX"
+)
+
+// anchorRoot is the root of a single tree at the time it was anchored.
+type anchorRoot struct {
+	Token    string `json:"token"`    // Record token
+	TreeSize int64  `json:"treesize"` // Tree size at time of anchor
+	RootHash []byte `json:"roothash"` // Tree root at time of anchor
+}
+
+// unconfirmedAnchor is a dcrtime digest, the merkle root of all tree roots,
+// that is waiting for confirmation.
+type unconfirmedAnchor struct {
+	Digest string       `json:"digest"` // Digest sent to dcrtime
+	Roots  []anchorRoot `json:"roots"`  // Tree roots in the digest
+}
+
+// anchor is appended to a record tree once its root has been timestamped.
+// The dcrtime merkle path proves that the root is part of the anchored
+// digest.
+type anchor struct {
+	TreeSize     int64                `json:"treesize"`     // Anchored tree size
+	RootHash     []byte               `json:"roothash"`     // Anchored tree root
+	VerifyDigest dcrtime.VerifyDigest `json:"verifydigest"` // dcrtime proof
+}
+
+// readUnconfirmed returns all anchors that are waiting for confirmation.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) readUnconfirmed() ([]unconfirmedAnchor, error) {
+	b, err := t.db.Get([]byte(keyUnconfirmed), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return []unconfirmedAnchor{}, nil
+		}
+		return nil, err
+	}
+	var ua []unconfirmedAnchor
+	err = json.Unmarshal(b, &ua)
+	if err != nil {
+		return nil, err
+	}
+	return ua, nil
+}
+
+// anchorTrees timestamps the roots of all trees that were changed since they
+// were last anchored.
+func (t *tlogBackend) anchorTrees() error {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil
+	}
+
+	// Collect dirty trees
+	dirty := make([]*tree, 0, 64)
+	iter := t.db.NewIterator(lutil.BytesPrefix([]byte(keyPrefixTree)), nil)
+	for iter.Next() {
+		var tr tree
+		err := json.Unmarshal(iter.Value(), &tr)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+		if tr.Dirty {
+			dirty = append(dirty, &tr)
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if len(dirty) == 0 {
+		log.Infof("anchorTrees: nothing dirty")
+		return nil
+	}
+
+	// Calculate the digest of all roots
+	ua := unconfirmedAnchor{
+		Roots: make([]anchorRoot, 0, len(dirty)),
+	}
+	hashes := make([]*[sha256.Size]byte, 0, len(dirty))
+	for _, tr := range dirty {
+		ua.Roots = append(ua.Roots, anchorRoot{
+			Token:    tr.Token,
+			TreeSize: tr.Size,
+			RootHash: tr.Root,
+		})
+		var d [sha256.Size]byte
+		copy(d[:], tr.Root)
+		hashes = append(hashes, &d)
+	}
+	m := *merkle.Root(hashes)
+	ua.Digest = hex.EncodeToString(m[:])
+
+	// Timestamp
+	if t.test {
+		t.testAnchors[ua.Digest] = false
+	} else {
+		err := util.Timestamp("politeia", t.dcrtimeHost,
+			[]*[sha256.Size]byte{&m})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the unconfirmed anchor and mark the trees clean
+	unconfirmed, err := t.readUnconfirmed()
+	if err != nil {
+		return err
+	}
+	unconfirmed = append(unconfirmed, ua)
+	b, err := json.Marshal(unconfirmed)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(keyUnconfirmed), b)
+	for _, tr := range dirty {
+		tr.Dirty = false
+		err := putTree(batch, tr)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Anchored %v trees: %v", len(dirty), ua.Digest)
+
+	return t.db.Write(batch, nil)
+}
+
+// verifyAnchor asks dcrtime if an anchor has been verified.
+func (t *tlogBackend) verifyAnchor(digest string) (*dcrtime.VerifyDigest, error) {
+	var (
+		vr  *dcrtime.VerifyReply
+		err error
+	)
+
+	// In test mode we fake success.
+	if t.test {
+		anchored, ok := t.testAnchors[digest]
+		if !ok {
+			return nil, fmt.Errorf("test not found")
+		}
+		if anchored {
+			return nil, fmt.Errorf("already anchored")
+		}
+		vr = &dcrtime.VerifyReply{
+			Digests: []dcrtime.VerifyDigest{{
+				Digest: digest,
+				Result: dcrtime.ResultOK,
+				ChainInformation: dcrtime.ChainInformation{
+					ChainTimestamp: time.Now().Unix(),
+					Transaction:    expectedTestTX,
+				},
+			}},
+		}
+	} else {
+		vr, err = util.Verify("politeia", t.dcrtimeHost,
+			[]string{digest})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Do some sanity checks
+	if len(vr.Digests) != 1 {
+		return nil, fmt.Errorf("unexpected number of digests")
+	}
+	if vr.Digests[0].Result != dcrtime.ResultOK {
+		return nil, fmt.Errorf("unexpected result: %v",
+			vr.Digests[0].Result)
+	}
+
+	return &vr.Digests[0], nil
+}
+
+// anchorChecker appends an anchor to every tree of the confirmed digests.
+func (t *tlogBackend) anchorChecker() error {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil
+	}
+
+	unconfirmed, err := t.readUnconfirmed()
+	if err != nil {
+		return err
+	}
+
+	pending := make([]unconfirmedAnchor, 0, len(unconfirmed))
+	batch := new(leveldb.Batch)
+	for _, ua := range unconfirmed {
+		vd, err := t.verifyAnchor(ua.Digest)
+		if err != nil {
+			log.Errorf("anchorChecker verify: %v", err)
+			pending = append(pending, ua)
+			continue
+		}
+		if vd.ChainInformation.ChainTimestamp == 0 {
+			// dcrtime returns 0 when there are not enough
+			// confirmations yet.
+			pending = append(pending, ua)
+			continue
+		}
+
+		for _, root := range ua.Roots {
+			tr, err := t.getTree(root.Token)
+			if err != nil {
+				return err
+			}
+			re, err := t.newRecordEntry(v1.DataDescriptorAnchor,
+				anchor{
+					TreeSize:     root.TreeSize,
+					RootHash:     root.RootHash,
+					VerifyDigest: *vd,
+				})
+			if err != nil {
+				return err
+			}
+			_, err = t.appendEntries(tr, batch, []*v1.RecordEntry{re})
+			if err != nil {
+				return err
+			}
+			err = putTree(batch, tr)
+			if err != nil {
+				return err
+			}
+		}
+
+		// A tree may be part of several unconfirmed anchors so the
+		// batch must be written before the next anchor is processed.
+		err = t.db.Write(batch, nil)
+		if err != nil {
+			return err
+		}
+		batch.Reset()
+
+		if t.test {
+			t.testAnchors[ua.Digest] = true
+		}
+		log.Infof("Anchor confirmed %v in TX %v", ua.Digest,
+			vd.ChainInformation.Transaction)
+	}
+
+	b, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return t.db.Put([]byte(keyUnconfirmed), b, nil)
+}
+
+// anchorTreesCronJob is the cron job that confirms outstanding anchors and
+// anchors all dirty trees.
+func (t *tlogBackend) anchorTreesCronJob() {
+	err := t.anchorChecker()
+	if err != nil {
+		log.Errorf("anchorChecker: %v", err)
+	}
+	err = t.anchorTrees()
+	if err != nil {
+		log.Errorf("anchorTrees: %v", err)
+	}
+}
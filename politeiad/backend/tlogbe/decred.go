@@ -0,0 +1,1649 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
+	lutil "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// Decred plugin data descriptors.  The decred plugin data is
+	// appended to the record trees so that it is anchored along with
+	// the records.
+	dataDescriptorComment       = "decredcomment"
+	dataDescriptorLikeComment   = "decredlikecomment"
+	dataDescriptorCensorComment = "decredcensorcomment"
+	dataDescriptorCastVote      = "decredcastvote"
+)
+
+var (
+	// errDuplicateVote is emitted when a cast vote is a duplicate.
+	errDuplicateVote = errors.New("duplicate vote")
+
+	// errIneligibleTicket is emitted when a vote is cast using an
+	// ineligible ticket.
+	errIneligibleTicket = errors.New("ineligible ticket")
+)
+
+// castVote is a cast vote along with the server receipt.
+type castVote struct {
+	CastVote decredplugin.CastVote `json:"castvote"` // Client side vote
+	Receipt  string                `json:"receipt"`  // Signature of CastVote.Signature
+}
+
+// init registers the decred plugin.
+func init() {
+	backend.RegisterPlugin(decredplugin.ID, newDecredPlugin)
+}
+
+// decredPlugin implements the backend PluginDriver interface on top of the
+// tlog backend.  Vote data is stored in the record metadata streams, the same
+// way the git backend stores it.  Comments, comment likes, comment censors and
+// cast votes are appended to the record trees.
+type decredPlugin struct {
+	sync.Mutex // Serializes plugin commands and protects the caches
+
+	t       *tlogBackend
+	plugin  backend.Plugin
+	history func() []pd.IdentityKey
+	params  *chaincfg.Params
+	chain   decredvote.ChainSource
+
+	identityMtx sync.RWMutex           // Protects identity
+	identity    *identity.FullIdentity // Receipt and vote bundle signer
+
+	// Caches that are built from the record trees on setup.
+	comments map[string]map[string]decredplugin.Comment // [token][commentid]comment
+	likes    map[string][]decredplugin.LikeComment      // [token]likes
+	votes    map[string]map[string]struct{}             // [token][ticket]struct{}
+}
+
+// newDecredPlugin returns a decred plugin context for the tlog backend.
+//
+// newDecredPlugin satisfies the backend PluginConstructor type.
+func newDecredPlugin(cfg backend.PluginConfig) (backend.PluginDriver, error) {
+	t, ok := cfg.Backend.(*tlogBackend)
+	if !ok {
+		return nil, backend.ErrInvalidPlugin
+	}
+
+	params := &chaincfg.MainNetParams
+	if cfg.TestNet {
+		params = &chaincfg.TestNet3Params
+	}
+
+	return &decredPlugin{
+		t:        t,
+		plugin:   decredvote.DecredPlugin(cfg.TestNet, cfg.Settings),
+		history:  cfg.IdentityHistory,
+		params:   params,
+		identity: cfg.Identity,
+		comments: make(map[string]map[string]decredplugin.Comment),
+		likes:    make(map[string][]decredplugin.LikeComment),
+		votes:    make(map[string]map[string]struct{}),
+	}, nil
+}
+
+// Plugin returns the decred plugin identifier, version and settings.
+//
+// Plugin satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Plugin() backend.Plugin {
+	return d.plugin
+}
+
+// Setup creates the chain source and builds the caches from the record
+// trees.
+//
+// Setup satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Setup() error {
+	log.Tracef("decredPlugin Setup")
+
+	settings := make(map[string]string, len(d.plugin.Settings))
+	for _, v := range d.plugin.Settings {
+		settings[v.Key] = v.Value
+	}
+	chain, err := decredvote.NewChainSource(settings)
+	if err != nil {
+		return fmt.Errorf("chain source: %v", err)
+	}
+	d.chain = chain
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	iter := d.t.db.NewIterator(lutil.BytesPrefix([]byte(keyPrefixTree)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var tr tree
+		err := json.Unmarshal(iter.Value(), &tr)
+		if err != nil {
+			return err
+		}
+		err = d.loadTree(&tr)
+		if err != nil {
+			return fmt.Errorf("load %v: %v", tr.Token, err)
+		}
+	}
+
+	return iter.Error()
+}
+
+// Commands returns the decred plugin commands.
+//
+// Commands satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Commands() []backend.PluginCmd {
+	return []backend.PluginCmd{
+		{
+			Command: decredplugin.CmdAuthorizeVote,
+			Exec:    d.cmdAuthorizeVote,
+		},
+		{
+			Command: decredplugin.CmdScheduleVote,
+			Exec:    d.cmdScheduleVote,
+		},
+		{
+			Command: decredplugin.CmdStartVote,
+			Exec:    d.cmdStartVote,
+		},
+		{
+			Command: decredplugin.CmdStartVoteRunoff,
+			Exec:    d.cmdStartVoteRunoff,
+		},
+		{
+			Command: decredplugin.CmdBallot,
+			Exec:    d.cmdBallot,
+		},
+		{
+			Command:  decredplugin.CmdProposalVotes,
+			ReadOnly: true,
+			Exec:     d.cmdProposalVotes,
+		},
+		{
+			Command:  decredplugin.CmdVoteBundle,
+			ReadOnly: true,
+			Exec:     d.cmdVoteBundle,
+		},
+		{
+			Command:  decredplugin.CmdBestBlock,
+			ReadOnly: true,
+			Exec:     d.cmdBestBlock,
+		},
+		{
+			Command: decredplugin.CmdNewComment,
+			Exec:    d.cmdNewComment,
+		},
+		{
+			Command: decredplugin.CmdLikeComment,
+			Exec:    d.cmdLikeComment,
+		},
+		{
+			Command: decredplugin.CmdCensorComment,
+			Exec:    d.cmdCensorComment,
+		},
+		{
+			Command:  decredplugin.CmdGetComments,
+			ReadOnly: true,
+			Exec:     d.cmdGetComments,
+		},
+		{
+			Command:  decredplugin.CmdProposalCommentsLikes,
+			ReadOnly: true,
+			Exec:     d.cmdProposalCommentsLikes,
+		},
+		{
+			Command:  decredplugin.CmdInventory,
+			ReadOnly: true,
+			Exec:     d.cmdInventory,
+		},
+		{
+			Command:  decredplugin.CmdLoadVoteResults,
+			ReadOnly: true,
+			Exec:     d.cmdLoadVoteResults,
+		},
+	}
+}
+
+// Hooks returns the decred plugin record hooks.  The plugin data does not
+// have to be flushed on record edits since it is already part of the record
+// trees.
+//
+// Hooks satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Hooks() map[backend.HookT]backend.HookFunc {
+	return nil
+}
+
+// Close satisfies the backend PluginDriver interface.
+func (d *decredPlugin) Close() {}
+
+// loadTree adds the plugin data of a record tree to the caches.
+//
+// This function must be called with the plugin and backend locks held.
+func (d *decredPlugin) loadTree(tr *tree) error {
+	comments, err := d.t.pluginIndexes(tr, dataDescriptorComment)
+	if err != nil {
+		return err
+	}
+	for _, v := range comments {
+		var c decredplugin.Comment
+		err := d.t.getEntry(tr, v, dataDescriptorComment, &c)
+		if err != nil {
+			return err
+		}
+		if _, ok := d.comments[c.Token]; !ok {
+			d.comments[c.Token] = make(map[string]decredplugin.Comment)
+		}
+		d.comments[c.Token][c.CommentID] = c
+	}
+	censors, err := d.t.pluginIndexes(tr, dataDescriptorCensorComment)
+	if err != nil {
+		return err
+	}
+	for _, v := range censors {
+		var cc decredplugin.CensorComment
+		err := d.t.getEntry(tr, v, dataDescriptorCensorComment, &cc)
+		if err != nil {
+			return err
+		}
+		c, ok := d.comments[cc.Token][cc.CommentID]
+		if !ok {
+			return fmt.Errorf("censored comment not found %v:%v",
+				cc.Token, cc.CommentID)
+		}
+		c.Comment = ""
+		c.Censored = true
+		d.comments[cc.Token][cc.CommentID] = c
+	}
+	likes, err := d.t.pluginIndexes(tr, dataDescriptorLikeComment)
+	if err != nil {
+		return err
+	}
+	for _, v := range likes {
+		var lc decredplugin.LikeComment
+		err := d.t.getEntry(tr, v, dataDescriptorLikeComment, &lc)
+		if err != nil {
+			return err
+		}
+		d.likes[lc.Token] = append(d.likes[lc.Token], lc)
+	}
+	votes, err := d.t.pluginIndexes(tr, dataDescriptorCastVote)
+	if err != nil {
+		return err
+	}
+	for _, v := range votes {
+		var cv castVote
+		err := d.t.getEntry(tr, v, dataDescriptorCastVote, &cv)
+		if err != nil {
+			return err
+		}
+		if _, ok := d.votes[cv.CastVote.Token]; !ok {
+			d.votes[cv.CastVote.Token] = make(map[string]struct{})
+		}
+		d.votes[cv.CastVote.Token][cv.CastVote.Ticket] = struct{}{}
+	}
+	return nil
+}
+
+// SetIdentity replaces the identity that signs receipts and vote bundles.
+// It is called when the politeiad identity is rotated.
+//
+// SetIdentity satisfies the backend IdentitySetter interface.
+func (d *decredPlugin) SetIdentity(id *identity.FullIdentity) error {
+	log.Tracef("decredPlugin SetIdentity")
+
+	d.identityMtx.Lock()
+	defer d.identityMtx.Unlock()
+
+	d.identity = id
+	return nil
+}
+
+// fullIdentity returns the identity that signs receipts and vote bundles.
+func (d *decredPlugin) fullIdentity() (*identity.FullIdentity, error) {
+	d.identityMtx.RLock()
+	defer d.identityMtx.RUnlock()
+
+	if d.identity == nil {
+		return nil, fmt.Errorf("full identity not set")
+	}
+	return d.identity, nil
+}
+
+// receipt signs the provided client signature with the politeiad identity.
+func (d *decredPlugin) receipt(signature string) (string, error) {
+	fi, err := d.fullIdentity()
+	if err != nil {
+		return "", err
+	}
+	r := fi.SignMessage([]byte(signature))
+	return hex.EncodeToString(r[:]), nil
+}
+
+// vettedTree returns the tree and the latest record index of a vetted
+// record.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) vettedTree(token string) (*tree, *recordIndex, error) {
+	if d.t.shutdown {
+		return nil, nil, backend.ErrShutdown
+	}
+	tr, err := d.t.getTree(token)
+	if err != nil {
+		if err == backend.ErrRecordNotFound {
+			return nil, nil, fmt.Errorf("unknown proposal: %v", token)
+		}
+		return nil, nil, err
+	}
+	ri, err := d.t.recordIndexLatest(tr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isVetted(ri.RecordMetadata.Status) {
+		return nil, nil, fmt.Errorf("unknown proposal: %v", token)
+	}
+	return tr, ri, nil
+}
+
+// metadataStream decodes the JSON metadata stream with the provided ID of
+// the record index into v.  It returns false if the stream does not exist.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) metadataStream(tr *tree, ri *recordIndex, id uint64, v interface{}) (bool, error) {
+	index, ok := ri.Metadata[id]
+	if !ok {
+		return false, nil
+	}
+	var md backend.MetadataStream
+	err := d.t.getEntry(tr, index, dataDescriptorMetadataStream, &md)
+	if err != nil {
+		return false, err
+	}
+	err = json.Unmarshal([]byte(md.Payload), v)
+	if err != nil {
+		return false, fmt.Errorf("metadata stream %v: %v", id, err)
+	}
+	return true, nil
+}
+
+// castVotes returns the cast votes of a record in the order in which they
+// were received.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) castVotes(tr *tree) ([]castVote, error) {
+	indexes, err := d.t.pluginIndexes(tr, dataDescriptorCastVote)
+	if err != nil {
+		return nil, err
+	}
+	cvs := make([]castVote, 0, len(indexes))
+	for _, v := range indexes {
+		var cv castVote
+		err := d.t.getEntry(tr, v, dataDescriptorCastVote, &cv)
+		if err != nil {
+			return nil, err
+		}
+		cvs = append(cvs, cv)
+	}
+	return cvs, nil
+}
+
+// validateStartVoteState ensures that the vote of a proposal has been
+// authorized, that the authorization has not been revoked and that the vote
+// has not been started yet.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) validateStartVoteState(tr *tree, ri *recordIndex) error {
+	var av decredplugin.AuthorizeVote
+	ok, err := d.metadataStream(tr, ri, decredplugin.MDStreamAuthorizeVote,
+		&av)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no authorize vote metadata: %v", tr.Token)
+	}
+	_, bits := ri.Metadata[decredplugin.MDStreamVoteBits]
+	_, snapshot := ri.Metadata[decredplugin.MDStreamVoteSnapshot]
+	switch {
+	case !bits && !snapshot:
+		// Vote has not started, continue
+	case bits && snapshot:
+		return fmt.Errorf("proposal vote already started: %v",
+			tr.Token)
+	default:
+		return fmt.Errorf("proposal is unknown vote state: %v",
+			tr.Token)
+	}
+	if av.Action == decredplugin.AuthVoteActionRevoke {
+		return fmt.Errorf("vote authorization revoked")
+	}
+	return nil
+}
+
+// scheduleVote returns the vote schedule of the latest version of a
+// proposal.  nil is returned when the vote has never been scheduled.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) scheduleVote(tr *tree, ri *recordIndex) (*decredplugin.ScheduleVote, error) {
+	var sv decredplugin.ScheduleVote
+	ok, err := d.metadataStream(tr, ri, decredplugin.MDStreamVoteSchedule,
+		&sv)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &sv, nil
+}
+
+// voteSnapshot returns the start vote reply of a vote with the passed in
+// duration.  See the git backend voteSnapshot for details.
+func (d *decredPlugin) voteSnapshot(token string, startHeight, duration uint32) (*decredplugin.StartVoteReply, error) {
+	bb, err := d.chain.BestBlock()
+	if err != nil {
+		return nil, fmt.Errorf("bestBlock %v", err)
+	}
+	if startHeight == 0 {
+		startHeight = bb.Height
+	} else if bb.Height < startHeight {
+		return nil, fmt.Errorf("vote start height not reached: %v "+
+			"(best block %v)", startHeight, bb.Height)
+	} else if bb.Height > startHeight+decredplugin.ScheduleVoteMaxDelay {
+		// The voting period would be shortened
+		return nil, fmt.Errorf("vote start height missed: %v "+
+			"(best block %v)", startHeight, bb.Height)
+	}
+	maturity := uint32(d.params.TicketMaturity)
+	if startHeight < maturity {
+		return nil, fmt.Errorf("invalid height")
+	}
+	snapshotBlock, err := d.chain.Block(startHeight - maturity)
+	if err != nil {
+		return nil, fmt.Errorf("block %v", err)
+	}
+	snapshot, err := d.chain.Snapshot(snapshotBlock.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %v", err)
+	}
+	if len(snapshot) == 0 {
+		return nil, fmt.Errorf("no eligible voters for %v", token)
+	}
+
+	if duration < decredplugin.VoteDurationMin ||
+		duration > decredplugin.VoteDurationMax {
+		return nil, fmt.Errorf("invalid duration: %v (%v - %v)",
+			duration, decredplugin.VoteDurationMin,
+			decredplugin.VoteDurationMax)
+	}
+
+	return &decredplugin.StartVoteReply{
+		Version: decredplugin.VersionStartVoteReply,
+		StartBlockHeight: strconv.FormatUint(uint64(snapshotBlock.Height),
+			10),
+		StartBlockHash: snapshotBlock.Hash,
+		EndHeight: strconv.FormatUint(uint64(snapshotBlock.Height+
+			duration+maturity), 10),
+		EligibleTickets: snapshot,
+	}, nil
+}
+
+// cmdAuthorizeVote stores the vote authorization of the proposal author in
+// the record metadata.
+func (d *decredPlugin) cmdAuthorizeVote(payload string) (string, error) {
+	log.Tracef("cmdAuthorizeVote")
+
+	authorize, err := decredplugin.DecodeAuthorizeVote([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeAuthorizeVote %v", err)
+	}
+	receipt, err := d.receipt(authorize.Signature)
+	if err != nil {
+		return "", err
+	}
+	av := decredplugin.AuthorizeVote{
+		Version:   decredplugin.VersionAuthorizeVote,
+		Receipt:   receipt,
+		Timestamp: time.Now().Unix(),
+		Action:    authorize.Action,
+		Token:     authorize.Token,
+		Signature: authorize.Signature,
+		PublicKey: authorize.PublicKey,
+	}
+	avb, err := decredplugin.EncodeAuthorizeVote(av)
+	if err != nil {
+		return "", fmt.Errorf("EncodeAuthorizeVote: %v", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, ri, err := d.vettedTree(av.Token)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := ri.Metadata[decredplugin.MDStreamVoteBits]; ok {
+		return "", fmt.Errorf("proposal vote already started: %v",
+			av.Token)
+	}
+	err = d.t._updateVettedMetadata(tr, ri, nil, []backend.MetadataStream{{
+		ID:      decredplugin.MDStreamAuthorizeVote,
+		Payload: string(avb),
+	}})
+	if err != nil {
+		return "", fmt.Errorf("_updateVettedMetadata: %v", err)
+	}
+
+	avrb, err := decredplugin.EncodeAuthorizeVoteReply(
+		decredplugin.AuthorizeVoteReply{
+			Action:        av.Action,
+			RecordVersion: strconv.FormatUint(ri.Version, 10),
+			Receipt:       av.Receipt,
+			Timestamp:     av.Timestamp,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("Vote authorized for %v", av.Token)
+
+	return string(avrb), nil
+}
+
+// cmdScheduleVote stores the vote schedule of a proposal in the record
+// metadata.  See the git backend pluginScheduleVote for the rules.
+func (d *decredPlugin) cmdScheduleVote(payload string) (string, error) {
+	log.Tracef("cmdScheduleVote")
+
+	schedule, err := decredplugin.DecodeScheduleVote([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeScheduleVote %v", err)
+	}
+	token := schedule.Token
+
+	maturity := uint32(d.params.TicketMaturity)
+	switch schedule.Action {
+	case decredplugin.ScheduleVoteActionSchedule:
+		if schedule.StartVote.Vote.Token != token {
+			return "", fmt.Errorf("start vote token mismatch: %v",
+				schedule.StartVote.Vote.Token)
+		}
+		err = decredplugin.ValidateVote(schedule.StartVote.Vote)
+		if err != nil {
+			return "", fmt.Errorf("invalid vote: %v", err)
+		}
+		if schedule.StartHeight < maturity {
+			return "", fmt.Errorf("invalid start height: %v",
+				schedule.StartHeight)
+		}
+	case decredplugin.ScheduleVoteActionCancel:
+	case decredplugin.ScheduleVoteActionFail:
+	default:
+		return "", fmt.Errorf("invalid schedule vote action: %v",
+			schedule.Action)
+	}
+
+	bb, err := d.chain.BestBlock()
+	if err != nil {
+		return "", fmt.Errorf("bestBlock %v", err)
+	}
+	receipt, err := d.receipt(schedule.Signature)
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, ri, err := d.vettedTree(token)
+	if err != nil {
+		return "", err
+	}
+	err = d.validateStartVoteState(tr, ri)
+	if err != nil {
+		return "", err
+	}
+	current, err := d.scheduleVote(tr, ri)
+	if err != nil {
+		return "", err
+	}
+
+	sv := decredplugin.ScheduleVote{
+		Version:     decredplugin.VersionScheduleVote,
+		Receipt:     receipt,
+		Timestamp:   time.Now().Unix(),
+		Action:      schedule.Action,
+		Token:       token,
+		StartHeight: schedule.StartHeight,
+		StartVote:   schedule.StartVote,
+		Signature:   schedule.Signature,
+		PublicKey:   schedule.PublicKey,
+	}
+	if sv.Action != decredplugin.ScheduleVoteActionSchedule {
+		if current == nil ||
+			current.Action != decredplugin.ScheduleVoteActionSchedule {
+			return "", fmt.Errorf("proposal vote not scheduled: %v",
+				token)
+		}
+		if schedule.StartHeight != current.StartHeight {
+			return "", fmt.Errorf("start height mismatch: got %v, "+
+				"want %v", schedule.StartHeight, current.StartHeight)
+		}
+		sv.StartVote = current.StartVote
+	}
+	switch {
+	case sv.Action == decredplugin.ScheduleVoteActionFail &&
+		bb.Height <= sv.StartHeight+decredplugin.ScheduleVoteMaxDelay:
+		return "", fmt.Errorf("scheduled vote can still be started: "+
+			"%v (best block %v)", sv.StartHeight, bb.Height)
+	case sv.Action != decredplugin.ScheduleVoteActionFail &&
+		bb.Height >= sv.StartHeight:
+		return "", fmt.Errorf("vote start height reached: %v "+
+			"(best block %v)", sv.StartHeight, bb.Height)
+	}
+	sv.StartVote.Version = decredplugin.VersionStartVote
+	svb, err := decredplugin.EncodeScheduleVote(sv)
+	if err != nil {
+		return "", fmt.Errorf("EncodeScheduleVote: %v", err)
+	}
+	err = d.t._updateVettedMetadata(tr, ri, nil, []backend.MetadataStream{{
+		ID:      decredplugin.MDStreamVoteSchedule,
+		Payload: string(svb),
+	}})
+	if err != nil {
+		return "", fmt.Errorf("_updateVettedMetadata: %v", err)
+	}
+
+	svrb, err := decredplugin.EncodeScheduleVoteReply(
+		decredplugin.ScheduleVoteReply{
+			Action:         sv.Action,
+			StartHeight:    sv.StartHeight,
+			SnapshotHeight: sv.StartHeight - maturity,
+			Receipt:        sv.Receipt,
+			Timestamp:      sv.Timestamp,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("Vote %v for %v at height %v", sv.Action, token,
+		sv.StartHeight)
+
+	return string(svrb), nil
+}
+
+// cmdStartVote takes the ticket snapshot of a proposal vote and stores the
+// vote and the snapshot in the record metadata.  A scheduled vote starts at
+// its scheduled start height.
+func (d *decredPlugin) cmdStartVote(payload string) (string, error) {
+	log.Tracef("cmdStartVote")
+
+	vote, err := decredplugin.DecodeStartVote([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeStartVote %v", err)
+	}
+	err = decredplugin.ValidateVote(vote.Vote)
+	if err != nil {
+		return "", fmt.Errorf("invalid vote: %v", err)
+	}
+	token := vote.Vote.Token
+
+	// Lookup the schedule without holding the locks while the
+	// snapshot is taken.
+	d.t.Lock()
+	tr, ri, err := d.vettedTree(token)
+	var schedule *decredplugin.ScheduleVote
+	if err == nil {
+		schedule, err = d.scheduleVote(tr, ri)
+	}
+	d.t.Unlock()
+	if err != nil {
+		return "", err
+	}
+	var startHeight uint32
+	if schedule != nil &&
+		schedule.Action == decredplugin.ScheduleVoteActionSchedule {
+		startHeight = schedule.StartHeight
+	}
+
+	svr, err := d.voteSnapshot(token, startHeight, vote.Vote.Duration)
+	if err != nil {
+		return "", err
+	}
+	svrb, err := decredplugin.EncodeStartVoteReply(*svr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVoteReply: %v", err)
+	}
+	vote.Version = decredplugin.VersionStartVote
+	voteb, err := decredplugin.EncodeStartVote(*vote)
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVote: %v", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, ri, err = d.vettedTree(token)
+	if err != nil {
+		return "", err
+	}
+	err = d.validateStartVoteState(tr, ri)
+	if err != nil {
+		return "", err
+	}
+
+	// Ensure the vote schedule did not change while the snapshot was
+	// taken and that the vote matches its schedule.
+	current, err := d.scheduleVote(tr, ri)
+	if err != nil {
+		return "", err
+	}
+	if current != nil {
+		if schedule == nil || current.Receipt != schedule.Receipt {
+			return "", fmt.Errorf("vote schedule changed: %v", token)
+		}
+		switch {
+		case current.Action == decredplugin.ScheduleVoteActionSchedule &&
+			current.StartVote.Signature != vote.Signature:
+			return "", fmt.Errorf("start vote does not match vote "+
+				"schedule: %v", token)
+		case current.Action == decredplugin.ScheduleVoteActionCancel &&
+			current.StartVote.Signature == vote.Signature:
+			return "", fmt.Errorf("scheduled vote was cancelled: %v",
+				token)
+		case current.Action == decredplugin.ScheduleVoteActionFail &&
+			current.StartVote.Signature == vote.Signature:
+			return "", fmt.Errorf("scheduled vote failed: %v", token)
+		}
+	}
+
+	err = d.t._updateVettedMetadata(tr, ri, nil, []backend.MetadataStream{
+		{
+			ID:      decredplugin.MDStreamVoteBits,
+			Payload: string(voteb),
+		},
+		{
+			ID:      decredplugin.MDStreamVoteSnapshot,
+			Payload: string(svrb),
+		}})
+	if err != nil {
+		return "", fmt.Errorf("_updateVettedMetadata: %v", err)
+	}
+
+	log.Infof("Vote started for: %v snapshot %v start %v end %v",
+		token, svr.StartBlockHash, svr.StartBlockHeight,
+		svr.EndHeight)
+
+	return string(svrb), nil
+}
+
+// cmdStartVoteRunoff starts the votes of the submissions of an RFP as a
+// single runoff.  See the git backend pluginStartVoteRunoff for the rules.
+func (d *decredPlugin) cmdStartVoteRunoff(payload string) (string, error) {
+	log.Tracef("cmdStartVoteRunoff")
+
+	runoff, err := decredplugin.DecodeStartVoteRunoff([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeStartVoteRunoff %v", err)
+	}
+	if len(runoff.StartVotes) < 2 {
+		return "", fmt.Errorf("runoff requires at least 2 votes")
+	}
+
+	duration := runoff.StartVotes[0].Vote.Duration
+	tokens := make([]string, 0, len(runoff.StartVotes))
+	unique := make(map[string]struct{}, len(runoff.StartVotes))
+	for _, sv := range runoff.StartVotes {
+		token := sv.Vote.Token
+		if token == runoff.Token {
+			return "", fmt.Errorf("rfp can not be part of its " +
+				"own runoff")
+		}
+		if _, ok := unique[token]; ok {
+			return "", fmt.Errorf("duplicate runoff token: %v", token)
+		}
+		unique[token] = struct{}{}
+		if sv.Vote.Duration != duration {
+			return "", fmt.Errorf("runoff vote durations differ")
+		}
+		err = decredplugin.ValidateVote(sv.Vote)
+		if err != nil {
+			return "", fmt.Errorf("invalid vote %v: %v", token, err)
+		}
+		if decredplugin.VoteType(sv.Vote) != decredplugin.VoteTypeApproval {
+			return "", fmt.Errorf("runoff vote is not an approval "+
+				"vote: %v", token)
+		}
+		tokens = append(tokens, token)
+	}
+
+	svr, err := d.voteSnapshot(runoff.Token, 0, duration)
+	if err != nil {
+		return "", err
+	}
+	svrb, err := decredplugin.EncodeStartVoteReply(*svr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVoteReply: %v", err)
+	}
+	vrb, err := decredplugin.EncodeVoteRunoff(decredplugin.VoteRunoff{
+		Version:          decredplugin.VersionVoteRunoff,
+		Token:            runoff.Token,
+		Tokens:           tokens,
+		StartBlockHeight: svr.StartBlockHeight,
+		StartBlockHash:   svr.StartBlockHash,
+		EndHeight:        svr.EndHeight,
+	})
+	if err != nil {
+		return "", fmt.Errorf("EncodeVoteRunoff: %v", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	// Verify all states before anything is written
+	rfpTree, rfpIndex, err := d.vettedTree(runoff.Token)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := rfpIndex.Metadata[decredplugin.MDStreamVoteRunoff]; ok {
+		return "", fmt.Errorf("runoff vote already started: %v",
+			runoff.Token)
+	}
+	trees := make([]*tree, 0, len(tokens))
+	indexes := make([]*recordIndex, 0, len(tokens))
+	for _, token := range tokens {
+		tr, ri, err := d.vettedTree(token)
+		if err != nil {
+			return "", err
+		}
+		err = d.validateStartVoteState(tr, ri)
+		if err != nil {
+			return "", err
+		}
+		schedule, err := d.scheduleVote(tr, ri)
+		if err != nil {
+			return "", err
+		}
+		if schedule != nil &&
+			schedule.Action == decredplugin.ScheduleVoteActionSchedule {
+			return "", fmt.Errorf("proposal vote is scheduled: %v",
+				token)
+		}
+		trees = append(trees, tr)
+		indexes = append(indexes, ri)
+	}
+
+	for k, sv := range runoff.StartVotes {
+		sv.Version = decredplugin.VersionStartVote
+		voteb, err := decredplugin.EncodeStartVote(sv)
+		if err != nil {
+			return "", fmt.Errorf("EncodeStartVote: %v", err)
+		}
+		err = d.t._updateVettedMetadata(trees[k], indexes[k], nil,
+			[]backend.MetadataStream{
+				{
+					ID:      decredplugin.MDStreamVoteBits,
+					Payload: string(voteb),
+				},
+				{
+					ID:      decredplugin.MDStreamVoteSnapshot,
+					Payload: string(svrb),
+				}})
+		if err != nil {
+			return "", fmt.Errorf("_updateVettedMetadata %v: %v",
+				sv.Vote.Token, err)
+		}
+	}
+	err = d.t._updateVettedMetadata(rfpTree, rfpIndex, nil,
+		[]backend.MetadataStream{{
+			ID:      decredplugin.MDStreamVoteRunoff,
+			Payload: string(vrb),
+		}})
+	if err != nil {
+		return "", fmt.Errorf("_updateVettedMetadata: %v", err)
+	}
+
+	log.Infof("Runoff vote started for: %v submissions %v snapshot %v "+
+		"start %v end %v", runoff.Token, len(tokens), svr.StartBlockHash,
+		svr.StartBlockHeight, svr.EndHeight)
+
+	reply, err := decredplugin.EncodeStartVoteRunoffReply(
+		decredplugin.StartVoteRunoffReply{
+			StartVoteReply: *svr,
+		})
+	if err != nil {
+		return "", fmt.Errorf("EncodeStartVoteRunoffReply: %v", err)
+	}
+
+	return string(reply), nil
+}
+
+// activeVote is the vote of a proposal that has been started.
+type activeVote struct {
+	tree      *tree
+	startVote decredplugin.StartVote
+	endHeight uint64
+	eligible  map[string]struct{}
+}
+
+// activeVote returns the vote of a proposal.  An error is returned if the
+// vote has not been started.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) activeVote(token string) (*activeVote, error) {
+	tr, ri, err := d.vettedTree(token)
+	if err != nil {
+		return nil, err
+	}
+	bv := activeVote{
+		tree: tr,
+	}
+	ok, err := d.metadataStream(tr, ri, decredplugin.MDStreamVoteBits,
+		&bv.startVote)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("vote not started: %v", token)
+	}
+	var svr decredplugin.StartVoteReply
+	_, err = d.metadataStream(tr, ri, decredplugin.MDStreamVoteSnapshot,
+		&svr)
+	if err != nil {
+		return nil, err
+	}
+	bv.endHeight, err = strconv.ParseUint(svr.EndHeight, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	bv.eligible = make(map[string]struct{}, len(svr.EligibleTickets))
+	for _, v := range svr.EligibleTickets {
+		bv.eligible[v] = struct{}{}
+	}
+	return &bv, nil
+}
+
+// writeVote appends a cast vote to the record tree if the ticket is eligible
+// and has not voted yet.
+//
+// This function must be called with the plugin and backend locks held.
+func (d *decredPlugin) writeVote(bv *activeVote, v decredplugin.CastVote, receipt string) error {
+	if _, ok := bv.eligible[v.Ticket]; !ok {
+		return errIneligibleTicket
+	}
+	if _, ok := d.votes[v.Token][v.Ticket]; ok {
+		return errDuplicateVote
+	}
+
+	err := d.t.putPluginData(bv.tree, dataDescriptorCastVote, castVote{
+		CastVote: v,
+		Receipt:  receipt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, ok := d.votes[v.Token]; !ok {
+		d.votes[v.Token] = make(map[string]struct{})
+	}
+	d.votes[v.Token][v.Ticket] = struct{}{}
+
+	return nil
+}
+
+// cmdBallot verifies the votes of a ballot and appends the valid votes to the
+// record trees.  Every vote gets its own receipt or error.
+func (d *decredPlugin) cmdBallot(payload string) (string, error) {
+	log.Tracef("cmdBallot")
+
+	ballot, err := decredplugin.DecodeBallot([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeBallot: %v", err)
+	}
+	_, err = d.fullIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	bb, err := d.chain.BestBlock()
+	if err != nil {
+		return "", fmt.Errorf("bestBlock %v", err)
+	}
+	tickets := make([]string, 0, len(ballot.Votes))
+	for _, v := range ballot.Votes {
+		tickets = append(tickets, v.Ticket)
+	}
+	ticketAddresses, err := d.chain.LargestCommitmentAddresses(tickets)
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	votes := make(map[string]*activeVote)
+	br := decredplugin.BallotReply{
+		Receipts: make([]decredplugin.CastVoteReply, len(ballot.Votes)),
+	}
+	for k, v := range ballot.Votes {
+		bv, ok := votes[v.Token]
+		if !ok {
+			bv, err = d.activeVote(v.Token)
+			if err != nil {
+				log.Errorf("cmdBallot: activeVote %v: %v", v.Token,
+					err)
+				br.Receipts[k].Error = "proposal not found: " +
+					v.Token
+				continue
+			}
+			votes[v.Token] = bv
+		}
+
+		// Ensure that the vote bit is valid
+		bit, err := strconv.ParseUint(v.VoteBit, 16, 64)
+		if err == nil {
+			err = decredplugin.ValidateVoteBit(bv.startVote.Vote, bit)
+		}
+		if err != nil {
+			br.Receipts[k].Error = err.Error()
+			continue
+		}
+
+		// Verify voting period has not ended
+		if uint64(bb.Height) >= bv.endHeight {
+			br.Receipts[k].Error = "vote has ended: " + v.Token
+			continue
+		}
+
+		// Verify that the vote is signed by the largest commitment
+		// address of the ticket
+		if ticketAddresses[k].Err != nil {
+			t := time.Now().Unix()
+			log.Errorf("cmdBallot: ticketAddresses %v %v %v %v",
+				v.Ticket, v.Token, t, ticketAddresses[k].Err)
+			br.Receipts[k].Error = fmt.Sprintf("internal error %v", t)
+			continue
+		}
+		sig, err := hex.DecodeString(v.Signature)
+		var valid bool
+		if err == nil {
+			valid, err = decredvote.VerifyMessage(d.params,
+				ticketAddresses[k].Address, v.Token+v.Ticket+v.VoteBit,
+				base64.StdEncoding.EncodeToString(sig))
+		}
+		if err != nil || !valid {
+			t := time.Now().Unix()
+			log.Errorf("cmdBallot: VerifyMessage %v %v %v %v %v",
+				v.Ticket, v.Token, t, valid, err)
+			br.Receipts[k].Error = fmt.Sprintf("internal error %v", t)
+			continue
+		}
+
+		receipt, err := d.receipt(v.Signature)
+		if err != nil {
+			return "", err
+		}
+		err = d.writeVote(bv, v, receipt)
+		if err != nil {
+			switch err {
+			case errDuplicateVote:
+				br.Receipts[k].Error = "duplicate vote: " + v.Token
+				continue
+			case errIneligibleTicket:
+				br.Receipts[k].Error = "ineligible ticket: " + v.Token
+				continue
+			default:
+				// Should not fail, so return failure to alert people
+				return "", fmt.Errorf("write vote: %v", err)
+			}
+		}
+
+		br.Receipts[k].ClientSignature = v.Signature
+		br.Receipts[k].Signature = receipt
+	}
+
+	brb, err := decredplugin.EncodeBallotReply(br)
+	if err != nil {
+		return "", fmt.Errorf("EncodeBallotReply: %v", err)
+	}
+
+	return string(brb), nil
+}
+
+// cmdProposalVotes returns the start vote and all cast votes of a proposal.
+func (d *decredPlugin) cmdProposalVotes(payload string) (string, error) {
+	log.Tracef("cmdProposalVotes: %v", payload)
+
+	vote, err := decredplugin.DecodeVoteResults([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeVoteResults %v", err)
+	}
+
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, ri, err := d.vettedTree(vote.Token)
+	if err != nil {
+		return "", err
+	}
+	var vrr decredplugin.VoteResultsReply
+	_, err = d.metadataStream(tr, ri, decredplugin.MDStreamVoteBits,
+		&vrr.StartVote)
+	if err != nil {
+		return "", err
+	}
+	cvs, err := d.castVotes(tr)
+	if err != nil {
+		return "", fmt.Errorf("castVotes: %v", err)
+	}
+	vrr.CastVotes = make([]decredplugin.CastVote, 0, len(cvs))
+	for _, v := range cvs {
+		vrr.CastVotes = append(vrr.CastVotes, v.CastVote)
+	}
+
+	reply, err := decredplugin.EncodeVoteResultsReply(vrr)
+	if err != nil {
+		return "", fmt.Errorf("Could not encode VoteResultsReply: %v",
+			err)
+	}
+
+	return string(reply), nil
+}
+
+// authorizeVotes returns the vote authorizations of all versions of a record
+// along with the version they were signed for.
+//
+// This function must be called with the backend lock held.
+func (d *decredPlugin) authorizeVotes(tr *tree, ri *recordIndex) ([]decredplugin.VoteBundleAuthorizeVote, error) {
+	avs := make([]decredplugin.VoteBundleAuthorizeVote, 0, ri.Version)
+	for i := uint64(1); i <= ri.Version; i++ {
+		riv, err := d.t.recordIndexVersion(tr, i)
+		if err != nil {
+			return nil, err
+		}
+		var av decredplugin.AuthorizeVote
+		ok, err := d.metadataStream(tr, riv,
+			decredplugin.MDStreamAuthorizeVote, &av)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		avs = append(avs, decredplugin.VoteBundleAuthorizeVote{
+			AuthorizeVote: av,
+			RecordVersion: strconv.FormatUint(i, 10),
+		})
+	}
+	return avs, nil
+}
+
+// cmdVoteBundle returns the signed vote bundle of a proposal.  See the git
+// backend pluginVoteBundle.
+func (d *decredPlugin) cmdVoteBundle(payload string) (string, error) {
+	log.Tracef("cmdVoteBundle: %v", payload)
+
+	vb, err := decredplugin.DecodeVoteBundle([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeVoteBundle %v", err)
+	}
+	fi, err := d.fullIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	vbr := decredplugin.VoteBundleReply{
+		Version:   decredplugin.VersionVoteBundleReply,
+		Token:     vb.Token,
+		CastVotes: []decredplugin.VoteBundleCastVote{},
+	}
+	d.t.Lock()
+	tr, ri, err := d.vettedTree(vb.Token)
+	if err != nil {
+		d.t.Unlock()
+		return "", err
+	}
+	ok, err := d.metadataStream(tr, ri, decredplugin.MDStreamVoteBits,
+		&vbr.StartVote)
+	if err == nil && !ok {
+		err = fmt.Errorf("vote not started: %v", vb.Token)
+	}
+	if err == nil {
+		_, err = d.metadataStream(tr, ri,
+			decredplugin.MDStreamVoteSnapshot, &vbr.StartVoteReply)
+	}
+	if err == nil {
+		vbr.AuthorizeVotes, err = d.authorizeVotes(tr, ri)
+	}
+	var cvs []castVote
+	if err == nil {
+		cvs, err = d.castVotes(tr)
+	}
+	d.t.Unlock()
+	if err != nil {
+		return "", err
+	}
+	for _, v := range cvs {
+		vbr.CastVotes = append(vbr.CastVotes,
+			decredplugin.VoteBundleCastVote{
+				CastVote: v.CastVote,
+				Receipt:  v.Receipt,
+			})
+	}
+
+	// Lookup the commitment addresses of the tickets that voted
+	addrs, err := decredvote.CommitmentAddresses(d.chain, vbr.CastVotes)
+	if err != nil {
+		return "", err
+	}
+	for k := range vbr.CastVotes {
+		vbr.CastVotes[k].Address = addrs[k]
+	}
+
+	// Sign bundle
+	vbr.Timestamp = time.Now().Unix()
+	vbr.Identity = decredvote.VoteBundleIdentity(fi, d.history)
+	vbr.PublicKey = hex.EncodeToString(fi.Public.Key[:])
+	digest, err := vbr.Digest()
+	if err != nil {
+		return "", err
+	}
+	signature := fi.SignMessage(digest)
+	vbr.Signature = hex.EncodeToString(signature[:])
+
+	reply, err := decredplugin.EncodeVoteBundleReply(vbr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeVoteBundleReply: %v", err)
+	}
+
+	return string(reply), nil
+}
+
+// cmdBestBlock returns the best block height of the chain source.
+func (d *decredPlugin) cmdBestBlock(payload string) (string, error) {
+	bb, err := d.chain.BestBlock()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(bb.Height), 10), nil
+}
+
+// cmdNewComment appends a new comment to the record tree.
+func (d *decredPlugin) cmdNewComment(payload string) (string, error) {
+	log.Tracef("cmdNewComment")
+
+	comment, err := decredplugin.DecodeNewComment([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeNewComment: %v", err)
+	}
+	if comment.ParentID == "" {
+		// Empty ParentID means comment 0
+		comment.ParentID = "0"
+	}
+	receipt, err := d.receipt(comment.Signature)
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, _, err := d.vettedTree(comment.Token)
+	if err != nil {
+		return "", err
+	}
+
+	// Comments are never removed so the comment id is the number of
+	// comments plus one.
+	c := decredplugin.Comment{
+		Token:     comment.Token,
+		ParentID:  comment.ParentID,
+		Comment:   comment.Comment,
+		Signature: comment.Signature,
+		PublicKey: comment.PublicKey,
+		CommentID: strconv.Itoa(len(d.comments[comment.Token]) + 1),
+		Receipt:   receipt,
+		Timestamp: time.Now().Unix(),
+	}
+	err = d.t.putPluginData(tr, dataDescriptorComment, c)
+	if err != nil {
+		return "", fmt.Errorf("putPluginData: %v", err)
+	}
+	if _, ok := d.comments[c.Token]; !ok {
+		d.comments[c.Token] = make(map[string]decredplugin.Comment)
+	}
+	d.comments[c.Token][c.CommentID] = c
+
+	ncrb, err := decredplugin.EncodeNewCommentReply(
+		decredplugin.NewCommentReply{
+			CommentID: c.CommentID,
+			Receipt:   c.Receipt,
+			Timestamp: c.Timestamp,
+		})
+	if err != nil {
+		return "", fmt.Errorf("EncodeNewCommentReply: %v", err)
+	}
+
+	return string(ncrb), nil
+}
+
+// cmdLikeComment appends an up or down vote of a comment to the record tree.
+func (d *decredPlugin) cmdLikeComment(payload string) (string, error) {
+	log.Tracef("cmdLikeComment")
+
+	like, err := decredplugin.DecodeLikeComment([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeLikeComment: %v", err)
+	}
+	if like.Action != "-1" && like.Action != "1" {
+		return "", fmt.Errorf("invalid action")
+	}
+	receipt, err := d.receipt(like.Signature)
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, _, err := d.vettedTree(like.Token)
+	if err != nil {
+		return "", err
+	}
+	c, ok := d.comments[like.Token][like.CommentID]
+	if !ok {
+		return "", fmt.Errorf("comment not found %v:%v",
+			like.Token, like.CommentID)
+	}
+
+	lc := decredplugin.LikeComment{
+		Token:     like.Token,
+		CommentID: like.CommentID,
+		Action:    like.Action,
+		Signature: like.Signature,
+		PublicKey: like.PublicKey,
+		Receipt:   receipt,
+		Timestamp: time.Now().Unix(),
+	}
+	err = d.t.putPluginData(tr, dataDescriptorLikeComment, lc)
+	if err != nil {
+		return "", fmt.Errorf("putPluginData: %v", err)
+	}
+	d.likes[lc.Token] = append(d.likes[lc.Token], lc)
+
+	lcrb, err := decredplugin.EncodeLikeCommentReply(
+		decredplugin.LikeCommentReply{
+			Total:   c.TotalVotes,
+			Result:  c.ResultVotes,
+			Receipt: receipt,
+		})
+	if err != nil {
+		return "", fmt.Errorf("EncodeLikeCommentReply: %v", err)
+	}
+
+	return string(lcrb), nil
+}
+
+// cmdCensorComment appends a comment censor to the record tree.  The content
+// of the censored comment is no longer returned.
+func (d *decredPlugin) cmdCensorComment(payload string) (string, error) {
+	log.Tracef("cmdCensorComment")
+
+	censor, err := decredplugin.DecodeCensorComment([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeCensorComment: %v", err)
+	}
+	receipt, err := d.receipt(censor.Signature)
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	tr, _, err := d.vettedTree(censor.Token)
+	if err != nil {
+		return "", err
+	}
+	c, ok := d.comments[censor.Token][censor.CommentID]
+	if !ok {
+		return "", fmt.Errorf("comment not found %v:%v",
+			censor.Token, censor.CommentID)
+	}
+	if c.Censored {
+		return "", fmt.Errorf("comment already censored %v: %v",
+			censor.Token, censor.CommentID)
+	}
+
+	cc := decredplugin.CensorComment{
+		Token:     censor.Token,
+		CommentID: censor.CommentID,
+		Reason:    censor.Reason,
+		Signature: censor.Signature,
+		PublicKey: censor.PublicKey,
+		Receipt:   receipt,
+		Timestamp: time.Now().Unix(),
+	}
+	err = d.t.putPluginData(tr, dataDescriptorCensorComment, cc)
+	if err != nil {
+		return "", fmt.Errorf("putPluginData: %v", err)
+	}
+	c.Comment = ""
+	c.Censored = true
+	d.comments[censor.Token][censor.CommentID] = c
+
+	ccrb, err := decredplugin.EncodeCensorCommentReply(
+		decredplugin.CensorCommentReply{
+			Receipt: cc.Receipt,
+		})
+	if err != nil {
+		return "", fmt.Errorf("EncodeCensorCommentReply: %v", err)
+	}
+
+	return string(ccrb), nil
+}
+
+// cmdGetComments returns all comments of a record.
+func (d *decredPlugin) cmdGetComments(payload string) (string, error) {
+	log.Tracef("cmdGetComments")
+
+	gc, err := decredplugin.DecodeGetComments([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeGetComments: %v", err)
+	}
+
+	d.Lock()
+	gcr := decredplugin.GetCommentsReply{
+		Comments: make([]decredplugin.Comment, 0,
+			len(d.comments[gc.Token])),
+	}
+	for _, v := range d.comments[gc.Token] {
+		gcr.Comments = append(gcr.Comments, v)
+	}
+	d.Unlock()
+
+	gcrb, err := decredplugin.EncodeGetCommentsReply(gcr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeGetCommentsReply: %v", err)
+	}
+
+	return string(gcrb), nil
+}
+
+// cmdProposalCommentsLikes returns all comment likes of a record.
+func (d *decredPlugin) cmdProposalCommentsLikes(payload string) (string, error) {
+	gpcl, err := decredplugin.DecodeGetProposalCommentsLikes([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeGetProposalCommentsLikes: %v", err)
+	}
+
+	var gpclr decredplugin.GetProposalCommentsLikesReply
+	d.Lock()
+	gpclr.CommentsLikes = append([]decredplugin.LikeComment{},
+		d.likes[gpcl.Token]...)
+	d.Unlock()
+
+	egpclr, err := decredplugin.EncodeGetProposalCommentsLikesReply(gpclr)
+	if err != nil {
+		return "", fmt.Errorf("EncodeGetProposalCommentsLikesReply: %v", err)
+	}
+	return string(egpclr), nil
+}
+
+// cmdInventory returns the decred plugin inventory of all records.  The vote
+// authorizations of all record versions are returned, the other vote data is
+// only returned for the latest record version.
+func (d *decredPlugin) cmdInventory(payload string) (string, error) {
+	log.Tracef("cmdInventory")
+
+	d.Lock()
+	defer d.Unlock()
+	d.t.Lock()
+	defer d.t.Unlock()
+
+	ir := decredplugin.InventoryReply{
+		Comments:             []decredplugin.Comment{},
+		LikeComments:         []decredplugin.LikeComment{},
+		AuthorizeVotes:       []decredplugin.AuthorizeVote{},
+		AuthorizeVoteReplies: []decredplugin.AuthorizeVoteReply{},
+		StartVoteTuples:      []decredplugin.StartVoteTuple{},
+		CastVotes:            []decredplugin.CastVote{},
+		VoteRunoffs:          []decredplugin.VoteRunoff{},
+		ScheduleVotes:        []decredplugin.ScheduleVote{},
+	}
+	for _, v := range d.comments {
+		for _, c := range v {
+			ir.Comments = append(ir.Comments, c)
+		}
+	}
+	for _, v := range d.likes {
+		ir.LikeComments = append(ir.LikeComments, v...)
+	}
+
+	iter := d.t.db.NewIterator(lutil.BytesPrefix([]byte(keyPrefixTree)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var tr tree
+		err := json.Unmarshal(iter.Value(), &tr)
+		if err != nil {
+			return "", err
+		}
+		ri, err := d.t.recordIndexLatest(&tr)
+		if err != nil {
+			return "", err
+		}
+		if !isVetted(ri.RecordMetadata.Status) {
+			continue
+		}
+
+		avs, err := d.authorizeVotes(&tr, ri)
+		if err != nil {
+			return "", fmt.Errorf("authorizeVotes %v: %v", tr.Token,
+				err)
+		}
+		for _, v := range avs {
+			ir.AuthorizeVotes = append(ir.AuthorizeVotes,
+				v.AuthorizeVote)
+			ir.AuthorizeVoteReplies = append(ir.AuthorizeVoteReplies,
+				decredplugin.AuthorizeVoteReply{
+					Action:        v.AuthorizeVote.Action,
+					RecordVersion: v.RecordVersion,
+					Receipt:       v.AuthorizeVote.Receipt,
+					Timestamp:     v.AuthorizeVote.Timestamp,
+				})
+		}
+
+		var svt decredplugin.StartVoteTuple
+		ok, err := d.metadataStream(&tr, ri,
+			decredplugin.MDStreamVoteBits, &svt.StartVote)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			_, err = d.metadataStream(&tr, ri,
+				decredplugin.MDStreamVoteSnapshot,
+				&svt.StartVoteReply)
+			if err != nil {
+				return "", err
+			}
+			ir.StartVoteTuples = append(ir.StartVoteTuples, svt)
+		}
+
+		var vr decredplugin.VoteRunoff
+		ok, err = d.metadataStream(&tr, ri,
+			decredplugin.MDStreamVoteRunoff, &vr)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			ir.VoteRunoffs = append(ir.VoteRunoffs, vr)
+		}
+
+		sv, err := d.scheduleVote(&tr, ri)
+		if err != nil {
+			return "", err
+		}
+		if sv != nil {
+			ir.ScheduleVotes = append(ir.ScheduleVotes, *sv)
+		}
+
+		cvs, err := d.castVotes(&tr)
+		if err != nil {
+			return "", fmt.Errorf("castVotes %v: %v", tr.Token, err)
+		}
+		for _, v := range cvs {
+			ir.CastVotes = append(ir.CastVotes, v.CastVote)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return "", err
+	}
+
+	reply, err := decredplugin.EncodeInventoryReply(ir)
+	if err != nil {
+		return "", fmt.Errorf("EncodeInventoryReply: %v", err)
+	}
+
+	return string(reply), nil
+}
+
+// cmdLoadVoteResults is a pass through function.  CmdLoadVoteResults does not
+// require any work to be performed in the backend.
+func (d *decredPlugin) cmdLoadVoteResults(payload string) (string, error) {
+	reply, err := decredplugin.EncodeLoadVoteResultsReply(
+		decredplugin.LoadVoteResultsReply{})
+	if err != nil {
+		return "", err
+	}
+	return string(reply), nil
+}
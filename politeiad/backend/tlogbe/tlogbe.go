@@ -0,0 +1,1470 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
+	v1 "github.com/decred/politeia/tlog/api/v1"
+	"github.com/decred/politeia/util"
+	"github.com/robfig/cron"
+	"github.com/syndtr/goleveldb/leveldb"
+	lutil "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// DefaultDbPath is the directory, relative to the backend root, that
+	// contains the record trees and blobs.
+	DefaultDbPath = "tlog"
+
+	// Record entry data descriptors.
+	dataDescriptorFile           = "file"
+	dataDescriptorMetadataStream = "metadatastream"
+	dataDescriptorRecordIndex    = "recordindex"
+
+	// Database key prefixes.
+	keyPrefixTree   = "tree/"
+	keyPrefixLeaf   = "leaf/"
+	keyPrefixPlugin = "plugin/"
+	keyPrefixBlob   = "blob/"
+
+	// keyReadme is the database key of the README content.
+	keyReadme = "readme"
+)
+
+var (
+	_ backend.Backend = (*tlogBackend)(nil)
+)
+
+// leaf is a single entry in a record tree.  It mirrors the fields of a
+// trillian LogLeaf that are relevant to the backend.
+type leaf struct {
+	LeafValue      []byte `json:"leafvalue"`      // Hash of the record entry data
+	MerkleLeafHash []byte `json:"merkleleafhash"` // RFC 6962 leaf hash
+	LeafIndex      int64  `json:"leafindex"`      // Index in the tree
+	ExtraData      string `json:"extradata"`      // Blob key of the record entry
+}
+
+// tree is the append-only Merkle log of a single record.  Every record change
+// appends the changed files and metadata streams followed by a record index
+// that describes the complete record at that point in time.  Plugin data is
+// appended to the tree as well but it is not part of any record index.
+//
+// The tree only contains the metadata of the log.  The leaves are stored
+// under their own keys and the leaf indexes of plugin data are stored under
+// per descriptor keys so that appending a leaf does not require the existing
+// leaves.  The frontier contains the roots of the perfect subtrees of the log
+// which is all that is needed to calculate the new root of an append.
+type tree struct {
+	Token    string   `json:"token"`    // Record token
+	Size     int64    `json:"size"`     // Number of leaves
+	Root     []byte   `json:"root"`     // Root hash
+	Frontier [][]byte `json:"frontier"` // Perfect subtree roots, largest first
+	Indexes  []int64  `json:"indexes"`  // Leaf indexes of all record indexes
+	Dirty    bool     `json:"dirty"`    // Tree was changed since last anchor
+}
+
+// recordIndex describes a record at a point in time.  File and metadata
+// stream contents are referenced by the index of the leaf that contains them.
+type recordIndex struct {
+	Version        uint64                 `json:"version"`        // Record version
+	RecordMetadata backend.RecordMetadata `json:"recordmetadata"` // Record metadata
+	Metadata       map[uint64]int64       `json:"metadata"`       // Stream ID to leaf index
+	Files          map[string]int64       `json:"files"`          // Filename to leaf index
+}
+
+// tlogBackend implements the backend.Backend interface on top of per record
+// append-only Merkle logs.
+type tlogBackend struct {
+	sync.Mutex
+	shutdown bool
+
+	root        string                 // Root directory
+	db          *leveldb.DB            // Trees and blobs
+	identity    *identity.FullIdentity // Record entry signing identity
+	dcrtimeHost string                 // Timestamp host
+	cron        *cron.Cron             // Scheduler for periodic tasks
+	test        bool                   // Set during UT
+	plugins     *backend.Plugins       // Enabled plugins
+	testAnchors map[string]bool        // [digest]anchored, test only
+}
+
+// isVetted returns whether the provided status belongs to a vetted record.
+func isVetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusVetted ||
+		status == backend.MDStatusArchived
+}
+
+// treeKey returns the database key of the tree for the provided token.
+func treeKey(token string) []byte {
+	return []byte(keyPrefixTree + token)
+}
+
+// leafKey returns the database key of the leaf with the provided index.  The
+// index is zero padded so that the leaves of a tree sort in append order.
+func leafKey(token string, index int64) []byte {
+	return []byte(fmt.Sprintf("%v%v/%016x", keyPrefixLeaf, token, index))
+}
+
+// pluginPrefix returns the database key prefix of the leaf indexes of the
+// plugin data with the provided descriptor.
+func pluginPrefix(token, descriptor string) []byte {
+	return []byte(keyPrefixPlugin + token + "/" + descriptor + "/")
+}
+
+// pluginKey returns the database key that records that the leaf with the
+// provided index contains plugin data with the provided descriptor.
+func pluginKey(token, descriptor string, index int64) []byte {
+	return []byte(fmt.Sprintf("%s%016x", pluginPrefix(token, descriptor),
+		index))
+}
+
+// putTree adds the tree to the provided batch.
+func putTree(batch *leveldb.Batch, tr *tree) error {
+	b, err := json.Marshal(tr)
+	if err != nil {
+		return err
+	}
+	batch.Put(treeKey(tr.Token), b)
+	return nil
+}
+
+// getTree returns the tree of the provided record token.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) getTree(token string) (*tree, error) {
+	b, err := t.db.Get(treeKey(token), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, backend.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	var tr tree
+	err = json.Unmarshal(b, &tr)
+	if err != nil {
+		return nil, err
+	}
+	return &tr, nil
+}
+
+// getLeaf returns the leaf of the tree with the provided index.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) getLeaf(tr *tree, index int64) (*leaf, error) {
+	if index < 0 || index >= tr.Size {
+		return nil, fmt.Errorf("invalid leaf index %v", index)
+	}
+	b, err := t.db.Get(leafKey(tr.Token, index), nil)
+	if err != nil {
+		return nil, fmt.Errorf("leaf %v: %v", index, err)
+	}
+	var l leaf
+	err = json.Unmarshal(b, &l)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// pluginIndexes returns the leaf indexes of the plugin data of the tree with
+// the provided descriptor in append order.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) pluginIndexes(tr *tree, descriptor string) ([]int64, error) {
+	prefix := pluginPrefix(tr.Token, descriptor)
+	indexes := make([]int64, 0, 64)
+	iter := t.db.NewIterator(lutil.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		index, err := strconv.ParseInt(string(iter.Key()[len(prefix):]),
+			16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugin key %s", iter.Key())
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, iter.Error()
+}
+
+// newRecordEntry encodes the provided structure as a tlog record entry.
+func (t *tlogBackend) newRecordEntry(descriptor string, v interface{}) (*v1.RecordEntry, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	hint, err := json.Marshal(v1.DataDescriptor{
+		Type:       v1.DataTypeStructure,
+		Descriptor: descriptor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	re := util.RecordEntryNew(t.identity, hint, data)
+	return &re, nil
+}
+
+// appendEntries appends the provided record entries to the tree.  The leaves
+// and blobs are added to the provided batch.  The tree itself is not written.
+// The inclusion proof of every new leaf is verified against the root of the
+// tree it was appended to.  Only the hashes on the path of the new leaf are
+// calculated so the cost of an append does not depend on the tree size.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) appendEntries(tr *tree, batch *leveldb.Batch, entries []*v1.RecordEntry) ([]int64, error) {
+	indexes := make([]int64, 0, len(entries))
+	for _, re := range entries {
+		lv, err := hex.DecodeString(re.Hash)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(re)
+		if err != nil {
+			return nil, err
+		}
+		l := leaf{
+			LeafValue:      lv,
+			MerkleLeafHash: util.MerkleLeafHash(lv),
+			LeafIndex:      tr.Size,
+		}
+		l.ExtraData = keyPrefixBlob + hex.EncodeToString(l.MerkleLeafHash)
+		lb, err := json.Marshal(l)
+		if err != nil {
+			return nil, err
+		}
+
+		// Prove that the new leaf is included in the new tree.
+		frontier, proof, err := util.MerkleAppend(tr.Frontier, tr.Size,
+			l.MerkleLeafHash)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %v: %v", l.LeafIndex, err)
+		}
+		root := util.MerkleFrontierRoot(frontier)
+		err = util.MerkleInclusionProofVerify(l.LeafIndex, tr.Size+1,
+			proof, root, l.MerkleLeafHash)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %v: %v", l.LeafIndex, err)
+		}
+
+		batch.Put([]byte(l.ExtraData), b)
+		batch.Put(leafKey(tr.Token, l.LeafIndex), lb)
+		tr.Frontier = frontier
+		tr.Root = root
+		tr.Size++
+		indexes = append(indexes, l.LeafIndex)
+	}
+
+	return indexes, nil
+}
+
+// getEntry loads the record entry of the provided leaf, verifies it and
+// decodes its data into v.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) getEntry(tr *tree, index int64, descriptor string, v interface{}) error {
+	l, err := t.getLeaf(tr, index)
+	if err != nil {
+		return err
+	}
+	b, err := t.db.Get([]byte(l.ExtraData), nil)
+	if err != nil {
+		return fmt.Errorf("blob %v: %v", l.ExtraData, err)
+	}
+	var re v1.RecordEntry
+	err = json.Unmarshal(b, &re)
+	if err != nil {
+		return err
+	}
+
+	// Verify data hint
+	dhb, err := base64.StdEncoding.DecodeString(re.DataHint)
+	if err != nil {
+		return err
+	}
+	var dd v1.DataDescriptor
+	err = json.Unmarshal(dhb, &dd)
+	if err != nil {
+		return err
+	}
+	if dd.Type != v1.DataTypeStructure || dd.Descriptor != descriptor {
+		return fmt.Errorf("unexpected data descriptor %v %v, "+
+			"wanted %v", dd.Type, dd.Descriptor, descriptor)
+	}
+
+	// Verify data against the leaf
+	data, err := base64.StdEncoding.DecodeString(re.Data)
+	if err != nil {
+		return err
+	}
+	h := sha256.Sum256(data)
+	if !bytes.Equal(h[:], l.LeafValue) {
+		return fmt.Errorf("corrupt record entry %v", l.ExtraData)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// recordIndexLatest returns the most recent record index of the tree.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) recordIndexLatest(tr *tree) (*recordIndex, error) {
+	if len(tr.Indexes) == 0 {
+		return nil, backend.ErrRecordNotFound
+	}
+	var ri recordIndex
+	err := t.getEntry(tr, tr.Indexes[len(tr.Indexes)-1],
+		dataDescriptorRecordIndex, &ri)
+	if err != nil {
+		return nil, err
+	}
+	return &ri, nil
+}
+
+// recordIndexVersion returns the most recent record index of the provided
+// record version.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) recordIndexVersion(tr *tree, version uint64) (*recordIndex, error) {
+	for i := len(tr.Indexes) - 1; i >= 0; i-- {
+		var ri recordIndex
+		err := t.getEntry(tr, tr.Indexes[i], dataDescriptorRecordIndex,
+			&ri)
+		if err != nil {
+			return nil, err
+		}
+		if ri.Version == version {
+			return &ri, nil
+		}
+		if ri.Version < version {
+			break
+		}
+	}
+	return nil, backend.ErrRecordNotFound
+}
+
+// loadMetadata returns all metadata streams of the record index sorted by
+// stream ID.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) loadMetadata(tr *tree, ri *recordIndex) ([]backend.MetadataStream, error) {
+	mds := make([]backend.MetadataStream, 0, len(ri.Metadata))
+	for _, v := range ri.Metadata {
+		var md backend.MetadataStream
+		err := t.getEntry(tr, v, dataDescriptorMetadataStream, &md)
+		if err != nil {
+			return nil, err
+		}
+		mds = append(mds, md)
+	}
+	sort.Slice(mds, func(i, j int) bool {
+		return mds[i].ID < mds[j].ID
+	})
+	return mds, nil
+}
+
+// loadFiles returns all files of the record index sorted by name.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) loadFiles(tr *tree, ri *recordIndex) ([]backend.File, error) {
+	files := make([]backend.File, 0, len(ri.Files))
+	for _, v := range ri.Files {
+		var f backend.File
+		err := t.getEntry(tr, v, dataDescriptorFile, &f)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// getRecord assembles the record described by the provided record index.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) getRecord(tr *tree, ri *recordIndex, includeFiles bool) (*backend.Record, error) {
+	mds, err := t.loadMetadata(tr, ri)
+	if err != nil {
+		return nil, err
+	}
+	var files []backend.File
+	if includeFiles {
+		files, err = t.loadFiles(tr, ri)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &backend.Record{
+		RecordMetadata: ri.RecordMetadata,
+		Version:        strconv.FormatUint(ri.Version, 10),
+		Metadata:       mds,
+		Files:          files,
+	}, nil
+}
+
+// putRecord appends the provided metadata streams and files to the tree,
+// points the record index at the new leaves and appends the record index.
+// Metadata streams and files that did not change must not be provided; they
+// remain referenced by the record index.  The tree and all blobs are written
+// atomically.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) putRecord(tr *tree, ri *recordIndex, mds []backend.MetadataStream, files []backend.File) error {
+	entries := make([]*v1.RecordEntry, 0, len(mds)+len(files)+1)
+	for _, v := range mds {
+		re, err := t.newRecordEntry(dataDescriptorMetadataStream, v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, re)
+	}
+	for _, v := range files {
+		re, err := t.newRecordEntry(dataDescriptorFile, v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, re)
+	}
+
+	batch := new(leveldb.Batch)
+	indexes, err := t.appendEntries(tr, batch, entries)
+	if err != nil {
+		return err
+	}
+	for k, v := range mds {
+		ri.Metadata[v.ID] = indexes[k]
+	}
+	for k, v := range files {
+		ri.Files[v.Name] = indexes[len(mds)+k]
+	}
+
+	// Append record index
+	re, err := t.newRecordEntry(dataDescriptorRecordIndex, ri)
+	if err != nil {
+		return err
+	}
+	indexes, err = t.appendEntries(tr, batch, []*v1.RecordEntry{re})
+	if err != nil {
+		return err
+	}
+	tr.Indexes = append(tr.Indexes, indexes[0])
+	tr.Dirty = true
+	err = putTree(batch, tr)
+	if err != nil {
+		return err
+	}
+
+	return t.db.Write(batch, nil)
+}
+
+// putPluginData appends plugin data to the tree and writes the tree.  The
+// leaf index of the data is recorded under the provided data descriptor.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) putPluginData(tr *tree, descriptor string, v interface{}) error {
+	re, err := t.newRecordEntry(descriptor, v)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	indexes, err := t.appendEntries(tr, batch, []*v1.RecordEntry{re})
+	if err != nil {
+		return err
+	}
+	batch.Put(pluginKey(tr.Token, descriptor, indexes[0]), nil)
+	tr.Dirty = true
+	err = putTree(batch, tr)
+	if err != nil {
+		return err
+	}
+
+	return t.db.Write(batch, nil)
+}
+
+// merkleRoot returns the hex encoded merkle root of the provided files.
+func merkleRoot(files []backend.File) (string, error) {
+	hashes := make([]*[sha256.Size]byte, 0, len(files))
+	for _, v := range files {
+		d, ok := util.ConvertDigest(v.Digest)
+		if !ok {
+			return "", fmt.Errorf("invalid digest %v", v.Digest)
+		}
+		hashes = append(hashes, &d)
+	}
+	m := *merkle.Root(hashes)
+	return hex.EncodeToString(m[:]), nil
+}
+
+// applyMetadata applies the append and overwrite metadata streams to the
+// streams in the provided map.  It returns the streams that changed.
+func applyMetadata(current map[uint64]string, mdAppend, mdOverwrite []backend.MetadataStream) []backend.MetadataStream {
+	changed := make(map[uint64]struct{})
+	for _, v := range mdOverwrite {
+		if p, ok := current[v.ID]; ok && p == v.Payload {
+			continue
+		}
+		current[v.ID] = v.Payload
+		changed[v.ID] = struct{}{}
+	}
+	for _, v := range mdAppend {
+		if v.Payload == "" {
+			continue
+		}
+		current[v.ID] += v.Payload
+		changed[v.ID] = struct{}{}
+	}
+
+	mds := make([]backend.MetadataStream, 0, len(changed))
+	for id := range changed {
+		mds = append(mds, backend.MetadataStream{
+			ID:      id,
+			Payload: current[id],
+		})
+	}
+	sort.Slice(mds, func(i, j int) bool {
+		return mds[i].ID < mds[j].ID
+	})
+	return mds
+}
+
+// updateMetadata applies the metadata changes to the record index.  It
+// returns the metadata streams that must be appended to the tree.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) updateMetadata(tr *tree, ri *recordIndex, mdAppend, mdOverwrite []backend.MetadataStream) ([]backend.MetadataStream, error) {
+	mds, err := t.loadMetadata(tr, ri)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[uint64]string, len(mds))
+	for _, v := range mds {
+		current[v.ID] = v.Payload
+	}
+	return applyMetadata(current, mdAppend, mdOverwrite), nil
+}
+
+// preHook calls the pre-hooks of the enabled plugins with the record as it is
+// before the change.  It is called after the record was loaded and verified so
+// that the record the hooks validate can not change before the change is
+// applied.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) preHook(h backend.HookT, rc backend.RecordChange, tr *tree, ri *recordIndex) error {
+	return t.plugins.Hook(h, rc, func() (*backend.Record, error) {
+		return t.getRecord(tr, ri, true)
+	})
+}
+
+// postHook calls the post-hooks of the enabled plugins.  Failures are only
+// logged because the record was already changed.
+//
+// This function must be called without the lock held.
+func (t *tlogBackend) postHook(h backend.HookT, rc backend.RecordChange, record func() (*backend.Record, error)) {
+	err := t.plugins.Hook(h, rc, record)
+	if err != nil {
+		log.Errorf("%v %v: %v", h, rc.Token, err)
+	}
+}
+
+// New verifies a record and stores it in a new tree.  The record starts out
+// unvetted at version 1.
+//
+// New satisfies the backend interface.
+func (t *tlogBackend) New(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
+	rc := backend.RecordChange{
+		MDAppend: metadata,
+		FilesAdd: files,
+	}
+	err := t.plugins.Hook(backend.HookPreNewRecord, rc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rm, err := t.newRecordLocked(metadata, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Token = rm.Token
+	t.postHook(backend.HookPostNewRecord, rc,
+		func() (*backend.Record, error) {
+			token, err := hex.DecodeString(rm.Token)
+			if err != nil {
+				return nil, err
+			}
+			return t.GetUnvetted(token)
+		})
+
+	return rm, nil
+}
+
+// newRecordLocked runs the portion of New that has to be locked.
+func (t *tlogBackend) newRecordLocked(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
+	log.Tracef("New")
+
+	err := backend.VerifyContent(metadata, files, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a censorship token.
+	token, err := util.Random(pd.TokenSize)
+	if err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(token)
+
+	log.Debugf("New %v", id)
+
+	m, err := merkleRoot(files)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	// Tokens are random but make sure we never clobber a tree.
+	_, err = t.getTree(id)
+	if err != backend.ErrRecordNotFound {
+		if err == nil {
+			err = backend.ErrRecordFound
+		}
+		return nil, err
+	}
+
+	tr := &tree{
+		Token: id,
+	}
+	ri := &recordIndex{
+		Version: 1,
+		RecordMetadata: backend.RecordMetadata{
+			Version:   backend.VersionRecordMD,
+			Iteration: 1,
+			Status:    backend.MDStatusUnvetted,
+			Merkle:    m,
+			Timestamp: time.Now().Unix(),
+			Token:     id,
+		},
+		Metadata: make(map[uint64]int64),
+		Files:    make(map[string]int64),
+	}
+	mds := make([]backend.MetadataStream, len(metadata))
+	copy(mds, metadata)
+	sort.Slice(mds, func(i, j int) bool {
+		return mds[i].ID < mds[j].ID
+	})
+	fs := make([]backend.File, len(files))
+	copy(fs, files)
+	sort.Slice(fs, func(i, j int) bool {
+		return fs[i].Name < fs[j].Name
+	})
+	err = t.putRecord(tr, ri, mds, fs)
+	if err != nil {
+		return nil, err
+	}
+
+	rm := ri.RecordMetadata
+	return &rm, nil
+}
+
+// updateRecord updates the files and metadata of a record.  A vetted update
+// creates a new record version, an unvetted update creates a new iteration of
+// the current version.
+//
+// This function must be called WITHOUT the lock held.
+func (t *tlogBackend) updateRecord(token []byte, mdAppend, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string, vetted bool, rc backend.RecordChange) (*backend.Record, error) {
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	err := backend.VerifyContent(allMD, filesAdd, filesDel)
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return nil, err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return nil, err
+		}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	tr, err := t.getTree(id)
+	if err != nil {
+		return nil, err
+	}
+	ri, err := t.recordIndexLatest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ri.RecordMetadata.Status
+	switch {
+	case vetted && status == backend.MDStatusArchived:
+		return nil, backend.ErrRecordArchived
+	case vetted && status != backend.MDStatusVetted:
+		return nil, backend.ErrRecordNotFound
+	case !vetted && isVetted(status):
+		return nil, backend.ErrRecordFound
+	case !vetted && !(status == backend.MDStatusUnvetted ||
+		status == backend.MDStatusIterationUnvetted):
+		return nil, fmt.Errorf("can not update record that "+
+			"has status: %v %v", status, backend.MDStatus[status])
+	}
+
+	// Call pre-hooks
+	err = t.preHook(backend.HookPreEditRecord, rc, tr, ri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify all deletes before executing
+	for _, v := range filesDel {
+		if _, ok := ri.Files[v]; !ok {
+			return nil, backend.ContentVerificationError{
+				ErrorCode:    pd.ErrorStatusFileNotFound,
+				ErrorContext: []string{v},
+			}
+		}
+	}
+
+	// Apply file changes
+	files, err := t.loadFiles(tr, ri)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]backend.File, len(files))
+	for _, v := range files {
+		current[v.Name] = v
+	}
+	for _, v := range filesDel {
+		delete(current, v)
+		delete(ri.Files, v)
+	}
+	changedFiles := make([]backend.File, 0, len(filesAdd))
+	for _, v := range filesAdd {
+		if f, ok := current[v.Name]; ok && f == v {
+			continue
+		}
+		current[v.Name] = v
+		changedFiles = append(changedFiles, v)
+	}
+	if len(current) == 0 {
+		return nil, backend.ContentVerificationError{
+			ErrorCode: pd.ErrorStatusEmpty,
+		}
+	}
+
+	// Apply metadata changes
+	mds, err := t.updateMetadata(tr, ri, mdAppend, mdOverwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	// If there are no changes DO NOT update the record and reply with no
+	// changes.
+	if len(filesDel) == 0 && len(changedFiles) == 0 && len(mds) == 0 {
+		return nil, backend.ErrNoChanges
+	}
+
+	// Update record metadata
+	files = make([]backend.File, 0, len(current))
+	for _, v := range current {
+		files = append(files, v)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	m, err := merkleRoot(files)
+	if err != nil {
+		return nil, err
+	}
+	ns := backend.MDStatusIterationUnvetted
+	if vetted {
+		ns = backend.MDStatusVetted
+		ri.Version++
+	}
+	ri.RecordMetadata.Status = ns
+	ri.RecordMetadata.Iteration++
+	ri.RecordMetadata.Merkle = m
+	ri.RecordMetadata.Timestamp = time.Now().Unix()
+
+	// Check for authorizevote metadata and delete it if found
+	delete(ri.Metadata, decredplugin.MDStreamAuthorizeVote)
+	for k, v := range mds {
+		if v.ID == decredplugin.MDStreamAuthorizeVote {
+			mds = append(mds[:k], mds[k+1:]...)
+			break
+		}
+	}
+
+	// Call plugin hooks
+	err = t.plugins.Hook(backend.HookPostEdit,
+		backend.RecordChange{Token: id}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changedFiles, func(i, j int) bool {
+		return changedFiles[i].Name < changedFiles[j].Name
+	})
+	err = t.putRecord(tr, ri, mds, changedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.getRecord(tr, ri, true)
+}
+
+// UpdateVettedRecord updates the vetted record.  This creates a new version
+// of the record.
+//
+// This function is part of the interface.
+func (t *tlogBackend) UpdateVettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateVettedRecord %x", token)
+
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      true,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	record, err := t.updateRecord(token, mdAppend, mdOverwrite, filesAdd,
+		filesDel, true, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	t.postHook(backend.HookPostEditRecord, rc, nil)
+
+	return record, nil
+}
+
+// UpdateUnvettedRecord updates the unvetted record.
+//
+// This function is part of the interface.
+func (t *tlogBackend) UpdateUnvettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateUnvettedRecord %x", token)
+
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      false,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	record, err := t.updateRecord(token, mdAppend, mdOverwrite, filesAdd,
+		filesDel, false, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	t.postHook(backend.HookPostEditRecord, rc, nil)
+
+	return record, nil
+}
+
+// UpdateVettedMetadata updates metadata in vetted record.  Record itself is
+// not changed.
+//
+// UpdateVettedMetadata satisfies the backend interface.
+func (t *tlogBackend) UpdateVettedMetadata(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream) error {
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      true,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	err := t.updateVettedMetadataLocked(token, mdAppend, mdOverwrite, rc)
+	if err != nil {
+		return err
+	}
+
+	t.postHook(backend.HookPostEditMetadata, rc,
+		func() (*backend.Record, error) {
+			return t.GetVetted(token, "")
+		})
+
+	return nil
+}
+
+// updateVettedMetadataLocked runs the portion of UpdateVettedMetadata that has
+// to be locked, including the pre-hooks.
+func (t *tlogBackend) updateVettedMetadataLocked(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, rc backend.RecordChange) error {
+	log.Debugf("UpdateVettedMetadata: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	err := backend.VerifyContent(allMD, []backend.File{}, []string{})
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return err
+		}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return backend.ErrShutdown
+	}
+
+	tr, err := t.getTree(hex.EncodeToString(token))
+	if err != nil {
+		return err
+	}
+	ri, err := t.recordIndexLatest(tr)
+	if err != nil {
+		return err
+	}
+	if !isVetted(ri.RecordMetadata.Status) {
+		return backend.ErrRecordNotFound
+	}
+
+	err = t.preHook(backend.HookPreEditMetadata, rc, tr, ri)
+	if err != nil {
+		return err
+	}
+
+	return t._updateVettedMetadata(tr, ri, mdAppend, mdOverwrite)
+}
+
+// _updateVettedMetadata updates the metadata of the latest version of a
+// vetted record.  It does not call any hooks.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) _updateVettedMetadata(tr *tree, ri *recordIndex, mdAppend, mdOverwrite []backend.MetadataStream) error {
+	switch ri.RecordMetadata.Status {
+	case backend.MDStatusVetted:
+	case backend.MDStatusArchived:
+		return backend.ErrRecordArchived
+	default:
+		return backend.ErrRecordNotFound
+	}
+
+	mds, err := t.updateMetadata(tr, ri, mdAppend, mdOverwrite)
+	if err != nil {
+		return err
+	}
+	if len(mds) == 0 {
+		return backend.ErrNoChanges
+	}
+
+	return t.putRecord(tr, ri, mds, []backend.File{})
+}
+
+// UpdateReadme updates the README content.
+//
+// UpdateReadme satisfies the backend interface.
+func (t *tlogBackend) UpdateReadme(content string) error {
+	log.Debugf("UpdateReadme")
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return backend.ErrShutdown
+	}
+
+	current, err := t.db.Get([]byte(keyReadme), nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+	if err == nil && string(current) == content {
+		return backend.ErrNoChanges
+	}
+
+	return t.db.Put([]byte(keyReadme), []byte(content), nil)
+}
+
+// getRecordLock returns the latest record, or the requested version, if its
+// vetted status matches the provided one.
+//
+// This function must be called WITHOUT the lock held.
+func (t *tlogBackend) getRecordLock(token []byte, version string, vetted, includeFiles bool) (*backend.Record, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	tr, err := t.getTree(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+	ri, err := t.recordIndexLatest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if isVetted(ri.RecordMetadata.Status) != vetted {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	if version != "" {
+		v, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return nil, backend.ErrRecordNotFound
+		}
+		ri, err = t.recordIndexVersion(tr, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.getRecord(tr, ri, includeFiles)
+}
+
+// GetUnvetted returns the latest iteration of an unvetted record.
+//
+// GetUnvetted satisfies the backend interface.
+func (t *tlogBackend) GetUnvetted(token []byte) (*backend.Record, error) {
+	log.Debugf("GetUnvetted %x", token)
+	return t.getRecordLock(token, "", false, true)
+}
+
+// GetVetted returns the requested version of a vetted record.  The latest
+// version is returned when version is empty.
+//
+// GetVetted satisfies the backend interface.
+func (t *tlogBackend) GetVetted(token []byte, version string) (*backend.Record, error) {
+	log.Debugf("GetVetted %x", token)
+	return t.getRecordLock(token, version, true, true)
+}
+
+// GetVettedVersions returns all versions of a vetted record, oldest first.
+// The records do not include the record files.
+//
+// GetVettedVersions satisfies the backend interface.
+func (t *tlogBackend) GetVettedVersions(token []byte) ([]backend.Record, error) {
+	log.Debugf("GetVettedVersions %x", token)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	tr, err := t.getTree(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+	ri, err := t.recordIndexLatest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if !isVetted(ri.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	records := make([]backend.Record, 0, ri.Version)
+	for i := uint64(1); i <= ri.Version; i++ {
+		riv, err := t.recordIndexVersion(tr, i)
+		if err != nil {
+			return nil, err
+		}
+		r, err := t.getRecord(tr, riv, false)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+
+	return records, nil
+}
+
+// GetVettedAnchor is not supported by the tlog backend.  A tlog anchor covers
+// the root of the whole record tree and is appended to the tree itself so it
+// cannot be expressed as a git commit anchor.
+//
+// GetVettedAnchor satisfies the backend interface.
+func (t *tlogBackend) GetVettedAnchor(token []byte, version string) (*backend.RecordAnchor, error) {
+	log.Debugf("GetVettedAnchor %x %v", token, version)
+
+	return nil, backend.ErrNotSupported
+}
+
+// setStatus updates the record status and metadata.  The caller is
+// responsible for validating the state transition.
+//
+// This function must be called with the lock held.
+func (t *tlogBackend) setStatus(tr *tree, ri *recordIndex, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	mds, err := t.updateMetadata(tr, ri, mdAppend, mdOverwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	ri.RecordMetadata.Status = status
+	ri.RecordMetadata.Iteration++
+	ri.RecordMetadata.Timestamp = time.Now().Unix()
+	err = t.putRecord(tr, ri, mds, []backend.File{})
+	if err != nil {
+		return nil, err
+	}
+
+	return t.getRecord(tr, ri, false)
+}
+
+// SetUnvettedStatus tries to update the status for an unvetted record. It
+// returns the updated record if successful but without the Files component.
+//
+// SetUnvettedStatus satisfies the backend interface.
+func (t *tlogBackend) SetUnvettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      false,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	record, err := t.setUnvettedStatusLocked(token, status, mdAppend, mdOverwrite,
+		rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	t.postHook(backend.HookPostSetRecordStatus, rc, nil)
+
+	return record, nil
+}
+
+// setUnvettedStatusLocked runs the portion of SetUnvettedStatus that has to be
+// locked, including the pre-hooks.
+func (t *tlogBackend) setUnvettedStatusLocked(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream, rc backend.RecordChange) (*backend.Record, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	tr, err := t.getTree(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+	ri, err := t.recordIndexLatest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if isVetted(ri.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	err = t.preHook(backend.HookPreSetRecordStatus, rc, tr, ri)
+	if err != nil {
+		return nil, err
+	}
+
+	// We only allow a transition from unvetted to vetted or censored
+	from := ri.RecordMetadata.Status
+	if !((from == backend.MDStatusUnvetted ||
+		from == backend.MDStatusIterationUnvetted) &&
+		(status == backend.MDStatusVetted ||
+			status == backend.MDStatusCensored)) {
+		return nil, backend.StateTransitionError{
+			From: from,
+			To:   status,
+		}
+	}
+
+	return t.setStatus(tr, ri, status, mdAppend, mdOverwrite)
+}
+
+// SetVettedStatus tries to update the status for a vetted record.  It returns
+// the updated record if successful but without the Files component.
+//
+// SetVettedStatus satisfies the backend interface.
+func (t *tlogBackend) SetVettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	rc := backend.RecordChange{
+		Token:       hex.EncodeToString(token),
+		Vetted:      true,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	record, err := t.setVettedStatusLocked(token, status, mdAppend, mdOverwrite,
+		rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Record = record
+	t.postHook(backend.HookPostSetRecordStatus, rc, nil)
+
+	return record, nil
+}
+
+// setVettedStatusLocked runs the portion of SetVettedStatus that has to be
+// locked, including the pre-hooks.
+func (t *tlogBackend) setVettedStatusLocked(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream, rc backend.RecordChange) (*backend.Record, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	tr, err := t.getTree(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+	ri, err := t.recordIndexLatest(tr)
+	if err != nil {
+		return nil, err
+	}
+	switch ri.RecordMetadata.Status {
+	case backend.MDStatusVetted:
+	case backend.MDStatusArchived:
+		return nil, backend.ErrRecordArchived
+	default:
+		return nil, backend.ErrRecordNotFound
+	}
+
+	err = t.preHook(backend.HookPreSetRecordStatus, rc, tr, ri)
+	if err != nil {
+		return nil, err
+	}
+
+	// We only allow a transition from vetted to archived
+	if status != backend.MDStatusArchived {
+		return nil, backend.StateTransitionError{
+			From: ri.RecordMetadata.Status,
+			To:   status,
+		}
+	}
+
+	return t.setStatus(tr, ri, status, mdAppend, mdOverwrite)
+}
+
+// Inventory returns an inventory of vetted and unvetted records.  If
+// includeFiles is set the content is also returned.  If allVersions is set
+// all versions of the vetted records are returned.
+//
+// Inventory satisfies the backend interface.
+func (t *tlogBackend) Inventory(vettedCount, branchCount uint, includeFiles, allVersions bool) ([]backend.Record, []backend.Record, error) {
+	log.Debugf("Inventory: %v %v %v", vettedCount, branchCount, includeFiles)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, nil, backend.ErrShutdown
+	}
+
+	pr := make([]backend.Record, 0, 1024)
+	br := make([]backend.Record, 0, 1024)
+	iter := t.db.NewIterator(lutil.BytesPrefix([]byte(keyPrefixTree)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var tr tree
+		err := json.Unmarshal(iter.Value(), &tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		ri, err := t.recordIndexLatest(&tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, err := t.getRecord(&tr, ri, includeFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !isVetted(ri.RecordMetadata.Status) {
+			br = append(br, *r)
+			continue
+		}
+		pr = append(pr, *r)
+
+		if allVersions {
+			// Include all versions of the record
+			for i := uint64(1); i < ri.Version; i++ {
+				riv, err := t.recordIndexVersion(&tr, i)
+				if err != nil {
+					return nil, nil, err
+				}
+				r, err := t.getRecord(&tr, riv, includeFiles)
+				if err != nil {
+					return nil, nil, err
+				}
+				pr = append(pr, *r)
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+
+	return pr, br, nil
+}
+
+// InventoryPage returns a filtered page of the latest version of all records.
+//
+// InventoryPage satisfies the backend interface.
+func (t *tlogBackend) InventoryPage(f backend.InventoryFilter) ([]backend.Record, string, error) {
+	log.Debugf("InventoryPage: %v %v", f.State, f.Cursor)
+
+	pr, br, err := t.Inventory(0, 0, f.IncludeFiles, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return backend.FilterInventory(append(pr, br...), f)
+}
+
+// GetPlugins returns a list of the enabled plugins and their settings.
+//
+// GetPlugins satisfies the backend interface.
+func (t *tlogBackend) GetPlugins() ([]backend.Plugin, error) {
+	log.Debugf("GetPlugins")
+	return t.plugins.Plugins(), nil
+}
+
+// Plugin send a passthrough command. The return values are: incomming command
+// identifier, encoded command result and an error if the command failed to
+// execute.
+//
+// Plugin satisfies the backend interface.
+func (t *tlogBackend) Plugin(command, payload string) (string, string, error) {
+	log.Debugf("Plugin: %v", command)
+	reply, err := t.plugins.Exec(command, payload, false)
+	if err == backend.ErrInvalidPluginCmd {
+		return "", "", err
+	}
+	return command, reply, err
+}
+
+// EnablePlugin sets up a plugin and serves its commands and hooks.
+//
+// EnablePlugin satisfies the backend PluginHost interface.
+func (t *tlogBackend) EnablePlugin(d backend.PluginDriver) error {
+	log.Debugf("EnablePlugin: %v", d.Plugin().ID)
+
+	t.Lock()
+	shutdown := t.shutdown
+	t.Unlock()
+	if shutdown {
+		return backend.ErrShutdown
+	}
+
+	return t.plugins.Add(d)
+}
+
+// SetIdentity replaces the identity that signs new record entries and plugin
+// data.  Existing record entries keep the signature of the identity that
+// created them.
+//
+// SetIdentity satisfies the backend IdentitySetter interface.
+func (t *tlogBackend) SetIdentity(id *identity.FullIdentity) error {
+	log.Tracef("SetIdentity")
+
+	t.Lock()
+	if t.shutdown {
+		t.Unlock()
+		return backend.ErrShutdown
+	}
+	t.identity = id
+	t.Unlock()
+
+	// The plugins take the backend lock themselves.
+	return t.plugins.SetIdentity(id)
+}
+
+// Close shuts down the backend.  It obtains the lock and sets the shutdown
+// boolean to true.  All interface functions MUST return with errShutdown if
+// the backend is shutting down.
+//
+// Close satisfies the backend interface.
+func (t *tlogBackend) Close() {
+	log.Debugf("Close")
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.shutdown = true
+	t.cron.Stop()
+	t.plugins.Close()
+	t.db.Close()
+}
+
+// New returns a tlogBackend context.  Record trees are stored in a leveldb
+// database inside root.  The identity is used to sign all record entries; it
+// may be nil in which case the entries are not signed.
+func New(root string, dcrtimeHost string, id *identity.FullIdentity) (*tlogBackend, error) {
+	dbPath := filepath.Join(root, DefaultDbPath)
+	err := os.MkdirAll(dbPath, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Tlog database: %v", dbPath)
+	db, err := leveldb.OpenFile(dbPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tlogBackend{
+		root:        root,
+		db:          db,
+		identity:    id,
+		dcrtimeHost: dcrtimeHost,
+		cron:        cron.New(),
+		plugins:     &backend.Plugins{},
+		testAnchors: make(map[string]bool),
+	}
+
+	// Launch cron.
+	err = t.cron.AddFunc(anchorSchedule, func() {
+		t.anchorTreesCronJob()
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	t.cron.Start()
+
+	// Message user
+	log.Infof("Timestamp host: %v", t.dcrtimeHost)
+
+	return t, nil
+}
@@ -0,0 +1,1178 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
+	v1 "github.com/decred/politeia/tlog/api/v1"
+	"github.com/decred/politeia/util"
+	"github.com/decred/slog"
+)
+
+type testWriter struct {
+	t *testing.T
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.t.Logf("%s", p)
+	return len(p), nil
+}
+
+func newTestTlogBackend(t *testing.T) (*tlogBackend, func()) {
+	t.Helper()
+
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb, err := New(dir, "", nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	tb.test = true
+
+	return tb, func() {
+		tb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestFile(t *testing.T, name string) backend.File {
+	t.Helper()
+
+	r, err := util.Random(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Create text file
+	payload := hex.EncodeToString(r)
+	return backend.File{
+		Name:    name,
+		MIME:    mime.DetectMimeType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}
+}
+
+func TestRecordLifecycle(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	// Create 5 unvetted records
+	propCount := 5
+	fileCount := 3
+	t.Logf("===== CREATE %v RECORDS WITH %v FILES =====", propCount,
+		fileCount)
+	rm := make([]*backend.RecordMetadata, propCount)
+	allFiles := make([][]backend.File, propCount)
+	for i := 0; i < propCount; i++ {
+		name := fmt.Sprintf("record%v", i)
+		files := make([]backend.File, 0, fileCount)
+		for j := 0; j < fileCount; j++ {
+			files = append(files, newTestFile(t,
+				name+"_"+strconv.Itoa(j)))
+		}
+		allFiles[i] = files
+
+		var err error
+		rm[i], err = tb.New([]backend.MetadataStream{{
+			ID:      0, // XXX
+			Payload: "this is metadata",
+		}}, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Call getunvetted to verify integrity
+	for k, v := range rm {
+		token, err := hex.DecodeString(v.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pru, err := tb.GetUnvetted(token)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !reflect.DeepEqual(&pru.RecordMetadata, rm[k]) {
+			t.Fatalf("unexpected rm got %v, wanted %v",
+				spew.Sdump(pru.RecordMetadata),
+				spew.Sdump(rm[k]))
+		}
+		if !reflect.DeepEqual(pru.Files, allFiles[k]) {
+			t.Fatalf("unexpected payload got %v, wanted %v",
+				spew.Sdump(pru.Files), spew.Sdump(allFiles[k]))
+		}
+
+		// Unvetted records are not vetted
+		_, err = tb.GetVetted(token, "")
+		if err != backend.ErrRecordNotFound {
+			t.Fatalf("expected ErrRecordNotFound, got %v", err)
+		}
+	}
+
+	// Vet 1 of the records
+	t.Logf("===== VET RECORD 1 =====")
+	emptyMD := []backend.MetadataStream{}
+	token, err := hex.DecodeString(rm[1].Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := tb.SetUnvettedStatus(token,
+		backend.MDStatusVetted, emptyMD, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RecordMetadata.Status != backend.MDStatusVetted {
+		t.Fatalf("unexpected status: got %v wanted %v",
+			record.RecordMetadata.Status, backend.MDStatusVetted)
+	}
+	pru, err := tb.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	psrG := &pru.RecordMetadata
+	if psrG.Iteration != rm[1].Iteration+1 ||
+		psrG.Status != backend.MDStatusVetted ||
+		psrG.Merkle != rm[1].Merkle ||
+		psrG.Token != rm[1].Token {
+		t.Fatalf("unexpected rm got %v, wanted %v",
+			spew.Sdump(*psrG), spew.Sdump(*rm[1]))
+	}
+	if !reflect.DeepEqual(pru.Files, allFiles[1]) {
+		t.Fatalf("unexpected payload got %v, wanted %v",
+			spew.Sdump(pru.Files), spew.Sdump(allFiles[1]))
+	}
+	_, err = tb.GetUnvetted(token)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	_, err = tb.SetUnvettedStatus(token, backend.MDStatusCensored,
+		emptyMD, emptyMD)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Censor record 2
+	t.Logf("===== CENSOR RECORD 2 =====")
+	token2, err := hex.DecodeString(rm[2].Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.SetUnvettedStatus(token2, backend.MDStatusCensored,
+		emptyMD, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.SetUnvettedStatus(token2, backend.MDStatusVetted,
+		emptyMD, emptyMD)
+	if _, ok := err.(backend.StateTransitionError); !ok {
+		t.Fatalf("expected StateTransitionError, got %v", err)
+	}
+
+	// Update unvetted record 0
+	t.Logf("===== UPDATE UNVETTED RECORD 0 =====")
+	token0, err := hex.DecodeString(rm[0].Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.UpdateUnvettedRecord(token0, emptyMD, emptyMD,
+		[]backend.File{}, []string{})
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+	_, err = tb.UpdateUnvettedRecord(token0, emptyMD, emptyMD,
+		[]backend.File{}, []string{"nope"})
+	if e, ok := err.(backend.ContentVerificationError); !ok ||
+		e.ErrorCode != pd.ErrorStatusFileNotFound {
+		t.Fatalf("expected file not found, got %v", err)
+	}
+	f := newTestFile(t, "record0_3")
+	pru, err = tb.UpdateUnvettedRecord(token0, emptyMD, emptyMD,
+		[]backend.File{f}, []string{"record0_0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := append([]backend.File{}, allFiles[0][1:]...)
+	wantFiles = append(wantFiles, f)
+	if !reflect.DeepEqual(pru.Files, wantFiles) {
+		t.Fatalf("unexpected payload got %v, wanted %v",
+			spew.Sdump(pru.Files), spew.Sdump(wantFiles))
+	}
+	if pru.RecordMetadata.Status != backend.MDStatusIterationUnvetted ||
+		pru.RecordMetadata.Iteration != 2 || pru.Version != "1" {
+		t.Fatalf("unexpected rm %v", spew.Sdump(pru.RecordMetadata))
+	}
+	_, err = tb.UpdateVettedRecord(token0, emptyMD, emptyMD,
+		[]backend.File{f}, []string{})
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Update vetted record 1
+	t.Logf("===== UPDATE VETTED RECORD 1 =====")
+	_, err = tb.UpdateUnvettedRecord(token, emptyMD, emptyMD,
+		[]backend.File{f}, []string{})
+	if err != backend.ErrRecordFound {
+		t.Fatalf("expected ErrRecordFound, got %v", err)
+	}
+	md := []backend.MetadataStream{{
+		ID:      1,
+		Payload: "more metadata",
+	}}
+	pru, err = tb.UpdateVettedRecord(token, md, emptyMD,
+		[]backend.File{f}, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pru.Version != "2" || len(pru.Files) != fileCount+1 ||
+		len(pru.Metadata) != 2 {
+		t.Fatalf("unexpected record %v", spew.Sdump(pru))
+	}
+	rv1, err := tb.GetVetted(token, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rv1.Files, allFiles[1]) {
+		t.Fatalf("unexpected payload got %v, wanted %v",
+			spew.Sdump(rv1.Files), spew.Sdump(allFiles[1]))
+	}
+	_, err = tb.GetVetted(token, "3")
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	versions, err := tb.GetVettedVersions(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Version != "1" ||
+		versions[1].Version != "2" || versions[1].Files != nil ||
+		versions[0].RecordMetadata.Merkle != rv1.RecordMetadata.Merkle {
+		t.Fatalf("unexpected versions %v", spew.Sdump(versions))
+	}
+	_, err = tb.GetVettedVersions(token0)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Update vetted metadata
+	t.Logf("===== UPDATE VETTED METADATA =====")
+	err = tb.UpdateVettedMetadata(token, emptyMD, md)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+	err = tb.UpdateVettedMetadata(token, md, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pru, err = tb.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pru.Version != "2" || pru.Metadata[1].Payload !=
+		md[0].Payload+md[0].Payload {
+		t.Fatalf("unexpected record %v", spew.Sdump(pru))
+	}
+
+	// Inventory
+	t.Logf("===== INVENTORY =====")
+	vetted, unvetted, err := tb.Inventory(0, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 1 || len(unvetted) != propCount-1 {
+		t.Fatalf("unexpected inventory %v %v", len(vetted),
+			len(unvetted))
+	}
+	vetted, _, err = tb.Inventory(0, 0, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 2 {
+		t.Fatalf("unexpected vetted inventory %v", len(vetted))
+	}
+
+	// Archive record 1
+	t.Logf("===== ARCHIVE RECORD 1 =====")
+	_, err = tb.SetVettedStatus(token, backend.MDStatusCensored,
+		emptyMD, emptyMD)
+	if _, ok := err.(backend.StateTransitionError); !ok {
+		t.Fatalf("expected StateTransitionError, got %v", err)
+	}
+	record, err = tb.SetVettedStatus(token, backend.MDStatusArchived,
+		emptyMD, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RecordMetadata.Status != backend.MDStatusArchived {
+		t.Fatalf("unexpected status: got %v wanted %v",
+			record.RecordMetadata.Status, backend.MDStatusArchived)
+	}
+	err = tb.UpdateVettedMetadata(token, md, emptyMD)
+	if err != backend.ErrRecordArchived {
+		t.Fatalf("expected ErrRecordArchived, got %v", err)
+	}
+
+	// Anchor all dirty trees and confirm
+	t.Logf("===== ANCHOR =====")
+	err = tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err := tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 1 || len(unconfirmed[0].Roots) != propCount {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+	err = tb.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err = tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 0 {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+
+	// Nothing to anchor, records must be unaffected by the anchors
+	err = tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err = tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 0 {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+	pru, err = tb.GetUnvetted(token0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pru.Files, wantFiles) {
+		t.Fatalf("unexpected payload got %v, wanted %v",
+			spew.Sdump(pru.Files), spew.Sdump(wantFiles))
+	}
+}
+
+func TestUpdateReadme(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	updatedReadmeContent := "Updated Readme Content!! \n"
+	err := tb.UpdateReadme(updatedReadmeContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trying to update readme to the same content returns an error.
+	err = tb.UpdateReadme(updatedReadmeContent)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+}
+
+func TestGetVettedAnchor(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	token := newTestVettedRecord(t, tb)
+	_, err := tb.GetVettedAnchor(token, "1")
+	if err != backend.ErrNotSupported {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+// newTestVettedRecord creates a record with a single file and vets it.
+func newTestVettedRecord(t *testing.T, tb *tlogBackend) []byte {
+	t.Helper()
+
+	rm, err := tb.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{newTestFile(t, "index.md")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.SetUnvettedStatus(token, backend.MDStatusVetted,
+		[]backend.MetadataStream{}, []backend.MetadataStream{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// anchorCount returns the number of anchors that were appended to the tree
+// of a record.
+func anchorCount(t *testing.T, tb *tlogBackend, token []byte) int {
+	t.Helper()
+
+	tr, err := tb.getTree(hex.EncodeToString(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var anchors int
+	for k := int64(0); k < tr.Size; k++ {
+		var a anchor
+		err := tb.getEntry(tr, k, v1.DataDescriptorAnchor, &a)
+		if err != nil {
+			continue
+		}
+		if a.VerifyDigest.ChainInformation.Transaction != expectedTestTX {
+			t.Fatalf("unexpected anchor tx %v",
+				a.VerifyDigest.ChainInformation.Transaction)
+		}
+		anchors++
+	}
+	return anchors
+}
+
+func TestTreeAppend(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	token := newTestVettedRecord(t, tb)
+	for i := 0; i < 5; i++ {
+		err := tb.UpdateVettedMetadata(token, []backend.MetadataStream{{
+			ID:      1,
+			Payload: strconv.Itoa(i),
+		}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tb.Lock()
+	defer tb.Unlock()
+	tr, err := tb.getTree(hex.EncodeToString(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		err = tb.putPluginData(tr, "test", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The incrementally calculated root must match the root of all
+	// stored leaves.
+	tr, err = tb.getTree(hex.EncodeToString(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lh := make([][]byte, 0, tr.Size)
+	for k := int64(0); k < tr.Size; k++ {
+		l, err := tb.getLeaf(tr, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.LeafIndex != k {
+			t.Fatalf("got leaf index %v, want %v", l.LeafIndex, k)
+		}
+		lh = append(lh, l.MerkleLeafHash)
+	}
+	if !bytes.Equal(util.MerkleRootHash(lh), tr.Root) {
+		t.Fatalf("got root %x, want %x", tr.Root,
+			util.MerkleRootHash(lh))
+	}
+
+	// The plugin data is found in append order
+	indexes, err := tb.pluginIndexes(tr, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{tr.Size - 3, tr.Size - 2, tr.Size - 1}
+	if !reflect.DeepEqual(indexes, want) {
+		t.Fatalf("got plugin indexes %v, want %v", indexes, want)
+	}
+	for k, v := range indexes {
+		var got int
+		err := tb.getEntry(tr, v, "test", &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != k {
+			t.Fatalf("got plugin data %v, want %v", got, k)
+		}
+	}
+	_, err = tb.getLeaf(tr, tr.Size)
+	if err == nil {
+		t.Fatalf("expected invalid leaf index error")
+	}
+}
+
+func TestAnchorWithChanges(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	// Anchor the first record and change it before the anchor is
+	// confirmed
+	token1 := newTestVettedRecord(t, tb)
+	err := tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := []backend.MetadataStream{{
+		ID:      1,
+		Payload: "more metadata",
+	}}
+	err = tb.UpdateVettedMetadata(token1, md, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Anchor both records while the first anchor is unconfirmed
+	token2 := newTestVettedRecord(t, tb)
+	err = tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err := tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 2 || len(unconfirmed[0].Roots) != 1 ||
+		len(unconfirmed[1].Roots) != 2 {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+	for _, v := range unconfirmed[1].Roots {
+		if v.Token == unconfirmed[0].Roots[0].Token &&
+			v.TreeSize <= unconfirmed[0].Roots[0].TreeSize {
+			t.Fatalf("tree did not grow between anchors")
+		}
+	}
+
+	// Confirm both anchors.  The anchors are appended to the trees but
+	// do not make them dirty.
+	err = tb.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err = tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 0 {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+	if got := anchorCount(t, tb, token1); got != 2 {
+		t.Fatalf("unexpected anchors record 1: got %v want 2", got)
+	}
+	if got := anchorCount(t, tb, token2); got != 1 {
+		t.Fatalf("unexpected anchors record 2: got %v want 1", got)
+	}
+	for _, token := range [][]byte{token1, token2} {
+		tr, err := tb.getTree(hex.EncodeToString(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.Dirty {
+			t.Fatalf("anchor marked tree dirty %x", token)
+		}
+	}
+
+	// Nothing left to anchor
+	err = tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err = tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 0 {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+
+	// The records are unaffected by the anchors
+	r, err := tb.GetVetted(token1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "1" || len(r.Metadata) != 2 {
+		t.Fatalf("unexpected record %v", spew.Sdump(r))
+	}
+}
+
+func TestRecordVersions(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	// Create enough versions to catch lexical version ordering
+	token := newTestVettedRecord(t, tb)
+	files := make([]backend.File, 0, 11)
+	for i := 2; i <= 11; i++ {
+		f := newTestFile(t, "file"+strconv.Itoa(i))
+		r, err := tb.UpdateVettedRecord(token, nil, nil,
+			[]backend.File{f}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Version != strconv.Itoa(i) {
+			t.Fatalf("unexpected version: got %v want %v",
+				r.Version, i)
+		}
+		files = append(files, f)
+	}
+
+	r, err := tb.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "11" || len(r.Files) != 11 {
+		t.Fatalf("unexpected latest version %v files %v", r.Version,
+			len(r.Files))
+	}
+	r, err = tb.GetVetted(token, "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "10" || len(r.Files) != 10 ||
+		!reflect.DeepEqual(r.Files[9], files[8]) {
+		t.Fatalf("unexpected version 10 %v", spew.Sdump(r))
+	}
+
+	for _, v := range []string{"0", "12", "-1", "1a", "01x"} {
+		_, err = tb.GetVetted(token, v)
+		if err != backend.ErrRecordNotFound {
+			t.Fatalf("version %v: expected ErrRecordNotFound, got %v",
+				v, err)
+		}
+	}
+
+	versions, err := tb.GetVettedVersions(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 11 {
+		t.Fatalf("unexpected versions %v", len(versions))
+	}
+	for k, v := range versions {
+		if v.Version != strconv.Itoa(k+1) {
+			t.Fatalf("unexpected version order %v at %v", v.Version,
+				k)
+		}
+	}
+}
+
+func TestInventoryPage(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	// Create three vetted records and one unvetted record
+	for i := 0; i < 3; i++ {
+		newTestVettedRecord(t, tb)
+	}
+	_, err := tb.New([]backend.MetadataStream{},
+		[]backend.File{newTestFile(t, "index.md")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Page through the vetted records
+	f := backend.InventoryFilter{
+		State: backend.InventoryStateVetted,
+		Limit: 2,
+	}
+	seen := make(map[string]struct{})
+	var pages int
+	for {
+		records, cursor, err := tb.InventoryPage(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, v := range records {
+			if v.RecordMetadata.Status != backend.MDStatusVetted {
+				t.Fatalf("unexpected status %v",
+					v.RecordMetadata.Status)
+			}
+			if len(v.Files) != 0 {
+				t.Fatalf("unexpected files")
+			}
+			seen[v.RecordMetadata.Token] = struct{}{}
+		}
+		if cursor == "" {
+			break
+		}
+		f.Cursor = cursor
+	}
+	if pages != 2 || len(seen) != 3 {
+		t.Fatalf("unexpected pages %v records %v", pages, len(seen))
+	}
+
+	tests := []struct {
+		name   string
+		filter backend.InventoryFilter
+		want   int
+	}{
+		{"unvetted", backend.InventoryFilter{
+			State: backend.InventoryStateUnvetted,
+		}, 1},
+		{"status", backend.InventoryFilter{
+			Statuses: []backend.MDStatusT{backend.MDStatusVetted,
+				backend.MDStatusUnvetted},
+		}, 4},
+		{"metadata stream", backend.InventoryFilter{
+			MDStreams: []uint64{0},
+		}, 3},
+		{"missing metadata stream", backend.InventoryFilter{
+			MDStreams: []uint64{1},
+		}, 0},
+		{"future", backend.InventoryFilter{
+			FromTimestamp: time.Now().Unix() + 60,
+		}, 0},
+	}
+	for _, test := range tests {
+		records, cursor, err := tb.InventoryPage(test.filter)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if len(records) != test.want || cursor != "" {
+			t.Fatalf("%v: got %v records want %v", test.name,
+				len(records), test.want)
+		}
+	}
+
+	_, _, err = tb.InventoryPage(backend.InventoryFilter{
+		Cursor: "invalid",
+	})
+	if err != backend.ErrInvalidCursor {
+		t.Fatalf("expected %v got %v", backend.ErrInvalidCursor, err)
+	}
+}
+
+// testHookPlugin is a plugin that records the hooks that were called and
+// rejects edits while reject is set.
+type testHookPlugin struct {
+	reject bool
+	calls  map[backend.HookT][]backend.RecordChange
+}
+
+func (p *testHookPlugin) Plugin() backend.Plugin {
+	return backend.Plugin{
+		ID:      "testhook",
+		Version: "1",
+	}
+}
+
+func (p *testHookPlugin) Setup() error { return nil }
+
+func (p *testHookPlugin) Commands() []backend.PluginCmd { return nil }
+
+func (p *testHookPlugin) Close() {}
+
+func (p *testHookPlugin) Hooks() map[backend.HookT]backend.HookFunc {
+	record := func(h backend.HookT) backend.HookFunc {
+		return func(rc backend.RecordChange) error {
+			p.calls[h] = append(p.calls[h], rc)
+			return nil
+		}
+	}
+	return map[backend.HookT]backend.HookFunc{
+		backend.HookPreNewRecord:  record(backend.HookPreNewRecord),
+		backend.HookPostNewRecord: record(backend.HookPostNewRecord),
+		backend.HookPreEditRecord: func(rc backend.RecordChange) error {
+			if p.reject {
+				return backend.ContentVerificationError{
+					ErrorCode: pd.ErrorStatusInvalidRecordStatusTransition,
+				}
+			}
+			p.calls[backend.HookPreEditRecord] = append(
+				p.calls[backend.HookPreEditRecord], rc)
+			return nil
+		},
+		backend.HookPostEditRecord: record(backend.HookPostEditRecord),
+	}
+}
+
+func TestHooks(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	p := &testHookPlugin{
+		calls: make(map[backend.HookT][]backend.RecordChange),
+	}
+	err := tb.EnablePlugin(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := newTestVettedRecord(t, tb)
+	pre := p.calls[backend.HookPreNewRecord]
+	post := p.calls[backend.HookPostNewRecord]
+	if len(pre) != 1 || pre[0].Token != "" || pre[0].Record != nil {
+		t.Fatalf("unexpected pre new record hooks %v", spew.Sdump(pre))
+	}
+	if len(post) != 1 || post[0].Token != hex.EncodeToString(token) ||
+		post[0].Record == nil {
+		t.Fatalf("unexpected post new record hooks %v",
+			spew.Sdump(post))
+	}
+
+	// A rejected edit must not change the record
+	f := newTestFile(t, "edit.md")
+	p.reject = true
+	_, err = tb.UpdateVettedRecord(token, nil, nil, []backend.File{f}, nil)
+	if _, ok := err.(backend.ContentVerificationError); !ok {
+		t.Fatalf("expected content verification error got %v", err)
+	}
+	r, err := tb.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "1" || len(r.Files) != 1 {
+		t.Fatalf("unexpected record %v", spew.Sdump(r))
+	}
+	if len(p.calls[backend.HookPostEditRecord]) != 0 {
+		t.Fatalf("post hook called on rejected edit")
+	}
+
+	// Pre-hooks see the record before the edit and post-hooks see the
+	// record after the edit.
+	p.reject = false
+	_, err = tb.UpdateVettedRecord(token, nil, nil, []backend.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre = p.calls[backend.HookPreEditRecord]
+	post = p.calls[backend.HookPostEditRecord]
+	if len(pre) != 1 || !pre[0].Vetted || pre[0].Record.Version != "1" {
+		t.Fatalf("unexpected pre edit hooks %v", spew.Sdump(pre))
+	}
+	if len(post) != 1 || post[0].Record.Version != "2" {
+		t.Fatalf("unexpected post edit hooks %v", spew.Sdump(post))
+	}
+}
+
+// signVote signs the cast vote message the way dcrwallet signs messages.
+func signVote(t *testing.T, key *secp256k1.PrivateKey, cv decredplugin.CastVote) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, cv.Token+cv.Ticket+cv.VoteBit)
+	sig, err := secp256k1.SignCompact(key, chainhash.HashB(buf.Bytes()),
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(sig)
+}
+
+func TestDecredPlugin(t *testing.T) {
+	tb, cleanup := newTestTlogBackend(t)
+	defer cleanup()
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup the simulated chain.  The vote snapshot is taken at the best
+	// block minus the ticket maturity.
+	params := &chaincfg.TestNet3Params
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := dcrutil.NewAddressSecpPubKey(
+		key.PubKey().SerializeCompressed(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := hex.EncodeToString(chainhash.HashB([]byte("ticket")))
+	ticketIneligible := hex.EncodeToString(chainhash.HashB([]byte("ie")))
+	best := uint32(1000)
+	sc := decredvote.SimChain{
+		Blocks: []decredvote.SimBlock{{
+			Height:     best - uint32(params.TicketMaturity),
+			Hash:       "snapshot",
+			TicketPool: []string{ticket},
+		}, {
+			Height: best,
+			Hash:   "best",
+		}},
+		Commitments: map[string]string{
+			ticket:           addr.EncodeAddress(),
+			ticketIneligible: addr.EncodeAddress(),
+		},
+	}
+	b, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simChain := filepath.Join(tb.root, "simchain.json")
+	err = ioutil.WriteFile(simChain, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := backend.PluginConfig{
+		Backend:  tb,
+		DataDir:  tb.root,
+		Identity: id,
+		TestNet:  true,
+		Settings: []backend.PluginSetting{{
+			Key:   "chainsource",
+			Value: decredvote.ChainSourceSim,
+		}, {
+			Key:   "simchain",
+			Value: simChain,
+		}},
+	}
+	d, err := backend.NewPlugin(decredplugin.ID, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.(*decredPlugin); !ok {
+		t.Fatalf("unexpected plugin driver %T", d)
+	}
+	err = tb.EnablePlugin(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// encode returns the encoded plugin payload and fails the test on
+	// error.
+	encode := func(payload []byte, err error) string {
+		t.Helper()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(payload)
+	}
+
+	// plugin executes a plugin command and fails the test on error.
+	plugin := func(command, payload string) string {
+		t.Helper()
+
+		_, reply, err := tb.Plugin(command, payload)
+		if err != nil {
+			t.Fatalf("%v: %v", command, err)
+		}
+		return reply
+	}
+
+	user, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userSign := func(msg string) string {
+		s := user.SignMessage([]byte(msg))
+		return hex.EncodeToString(s[:])
+	}
+	token := hex.EncodeToString(newTestVettedRecord(t, tb))
+
+	// Comments, likes and censors
+	reply := plugin(decredplugin.CmdNewComment,
+		encode(decredplugin.EncodeNewComment(decredplugin.NewComment{
+			Token:     token,
+			Comment:   "comment",
+			Signature: userSign(token + "comment"),
+			PublicKey: user.Public.String(),
+		})))
+	ncr, err := decredplugin.DecodeNewCommentReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ncr.CommentID != "1" {
+		t.Fatalf("unexpected comment id %v", ncr.CommentID)
+	}
+	plugin(decredplugin.CmdNewComment,
+		encode(decredplugin.EncodeNewComment(decredplugin.NewComment{
+			Token:     token,
+			ParentID:  ncr.CommentID,
+			Comment:   "reply",
+			Signature: userSign(token + "1reply"),
+			PublicKey: user.Public.String(),
+		})))
+	plugin(decredplugin.CmdLikeComment,
+		encode(decredplugin.EncodeLikeComment(decredplugin.LikeComment{
+			Token:     token,
+			CommentID: "1",
+			Action:    "1",
+			Signature: userSign(token + "11"),
+			PublicKey: user.Public.String(),
+		})))
+	plugin(decredplugin.CmdCensorComment,
+		encode(decredplugin.EncodeCensorComment(decredplugin.CensorComment{
+			Token:     token,
+			CommentID: "2",
+			Reason:    "spam",
+			Signature: userSign(token + "2spam"),
+			PublicKey: user.Public.String(),
+		})))
+	ccb, err := decredplugin.EncodeCensorComment(decredplugin.CensorComment{
+		Token:     token,
+		CommentID: "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = tb.Plugin(decredplugin.CmdCensorComment, string(ccb))
+	if err == nil {
+		t.Fatalf("expected comment already censored error")
+	}
+
+	// Authorize and start the vote
+	plugin(decredplugin.CmdAuthorizeVote,
+		encode(decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+			Action: decredplugin.AuthVoteActionAuthorize,
+			Token:  token,
+			Signature: userSign(token + "1" +
+				decredplugin.AuthVoteActionAuthorize),
+			PublicKey: user.Public.String(),
+		})))
+	reply = plugin(decredplugin.CmdStartVote,
+		encode(decredplugin.EncodeStartVote(decredplugin.StartVote{
+			PublicKey: user.Public.String(),
+			Signature: userSign(token),
+			Vote: decredplugin.Vote{
+				Token:            token,
+				Type:             decredplugin.VoteTypeApproval,
+				Mask:             0x03,
+				Duration:         decredplugin.VoteDurationMin,
+				QuorumPercentage: 20,
+				PassPercentage:   60,
+				Options: []decredplugin.VoteOption{{
+					Id:   decredplugin.VoteOptionIDReject,
+					Bits: 0x01,
+				}, {
+					Id:   decredplugin.VoteOptionIDApprove,
+					Bits: 0x02,
+				}},
+			},
+		})))
+	svr, err := decredplugin.DecodeStartVoteReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svr.StartBlockHash != "snapshot" ||
+		!reflect.DeepEqual(svr.EligibleTickets, []string{ticket}) {
+		t.Fatalf("unexpected start vote reply %v", spew.Sdump(svr))
+	}
+
+	// Cast votes
+	ballot := decredplugin.Ballot{}
+	for _, v := range []string{ticket, ticketIneligible, ticket} {
+		cv := decredplugin.CastVote{
+			Token:   token,
+			Ticket:  v,
+			VoteBit: "2",
+		}
+		cv.Signature = signVote(t, key, cv)
+		ballot.Votes = append(ballot.Votes, cv)
+	}
+	reply = plugin(decredplugin.CmdBallot,
+		encode(decredplugin.EncodeBallot(ballot)))
+	br, err := decredplugin.DecodeBallotReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.Receipts[0].Error != "" ||
+		br.Receipts[1].Error != "ineligible ticket: "+token ||
+		br.Receipts[2].Error != "duplicate vote: "+token {
+		t.Fatalf("unexpected ballot reply %v", spew.Sdump(br))
+	}
+
+	// The vote bundle verifies against the plugin identity
+	reply = plugin(decredplugin.CmdVoteBundle,
+		encode(decredplugin.EncodeVoteBundle(decredplugin.VoteBundle{
+			Token: token,
+		})))
+	vbr, err := decredplugin.DecodeVoteBundleReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vbt, err := decredvote.VerifyVoteBundle(vbr, params, &id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vbt.Counted != 1 || len(vbr.AuthorizeVotes) != 1 {
+		t.Fatalf("unexpected bundle tally %+v", vbt)
+	}
+
+	// The inventory contains all plugin data
+	reply = plugin(decredplugin.CmdInventory,
+		encode(decredplugin.EncodeInventory(decredplugin.Inventory{})))
+	ir, err := decredplugin.DecodeInventoryReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ir.Comments) != 2 || len(ir.LikeComments) != 1 ||
+		len(ir.AuthorizeVotes) != 1 || len(ir.StartVoteTuples) != 1 ||
+		len(ir.CastVotes) != 1 {
+		t.Fatalf("unexpected inventory %v", spew.Sdump(ir))
+	}
+
+	// The plugin data is appended to the record tree and is anchored
+	// along with the record
+	err = tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err := tb.readUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed) != 1 || len(unconfirmed[0].Roots) != 1 {
+		t.Fatalf("unexpected unconfirmed %v", spew.Sdump(unconfirmed))
+	}
+
+	// A new plugin context rebuilds the caches from the record trees
+	d, err = backend.NewPlugin(decredplugin.ID, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp := d.(*decredPlugin)
+	if len(dp.comments[token]) != 2 || !dp.comments[token]["2"].Censored ||
+		dp.comments[token]["2"].Comment != "" ||
+		len(dp.likes[token]) != 1 || len(dp.votes[token]) != 1 {
+		t.Fatalf("unexpected caches %v %v %v", spew.Sdump(dp.comments),
+			spew.Sdump(dp.likes), spew.Sdump(dp.votes))
+	}
+}
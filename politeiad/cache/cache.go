@@ -5,7 +5,11 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"sort"
 )
 
 type RecordStatusT int
@@ -148,6 +152,110 @@ type PluginDriver interface {
 	Exec(string, string, string) (string, error)
 }
 
+// Migration describes a single schema migration of a cache component.
+type Migration struct {
+	From        string // Version before the migration
+	To          string // Version after the migration
+	Description string // Description of the schema change
+}
+
+// MigrationStatus describes the schema version of a cache component, which
+// is either the records cache or the cache of a plugin, and the migrations
+// that are required to bring it up to date.
+type MigrationStatus struct {
+	ID      string      // Component identifier, records or a plugin ID
+	Version string      // Version in the cache, empty if not built
+	Latest  string      // Version of the cache implementation
+	Pending []Migration // Ordered migrations from Version to Latest
+	Rebuild bool        // No migration path, the component must be rebuilt
+}
+
+// Migrator is implemented by caches that can migrate their schema in place.
+// Migrations of a component are applied in order within a single
+// transaction so that a failed migration leaves the component untouched.
+type Migrator interface {
+	// Get the schema status of the records cache and of all registered
+	// plugins
+	MigrationStatus() ([]MigrationStatus, error)
+
+	// Apply all pending migrations.  The transactions are rolled back
+	// on a dry run.  The status prior to the migration is returned.
+	Migrate(bool) ([]MigrationStatus, error)
+}
+
+// RecordSummary describes a single version of a record.  It is used to
+// detect drift between the backend and the cache without loading the record
+// files.
+type RecordSummary struct {
+	Token    string        // Censorship token
+	Version  string        // Version of the record
+	Status   RecordStatusT // Status of the version
+	Merkle   string        // Merkle root of the version
+	Metadata string        // Digest of the metadata streams
+}
+
+// MetadataDigest returns the hex encoded SHA256 digest of the metadata streams
+// of a record version.  The streams are ordered by ID and every payload is
+// prefixed with its ID and length so that the digest does not depend on the
+// order in which the streams are stored.
+func MetadataDigest(md []MetadataStream) string {
+	streams := make([]MetadataStream, len(md))
+	copy(streams, md)
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].ID < streams[j].ID
+	})
+
+	h := sha256.New()
+	for _, v := range streams {
+		fmt.Fprintf(h, "%v:%v:", v.ID, len(v.Payload))
+		h.Write([]byte(v.Payload))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Drift describes a difference between the backend and the cache.
+type Drift struct {
+	Token  string // Censorship token
+	Reason string // Description of the difference
+}
+
+// PluginReconciler is implemented by cache plugins that can rebuild the
+// plugin data of individual records.  The payload is the plugin inventory of
+// the backend, the same payload that is used to build the plugin cache.
+type PluginReconciler interface {
+	// Compare the plugin inventory with the plugin cache
+	Drift(string) ([]Drift, error)
+
+	// Rebuild the plugin data of a set of records
+	Reconcile([]string, string) error
+}
+
+// PluginIndexer is implemented by cache plugins that index the content of
+// records.  The cache passes the most recent version of a record to the
+// indexer every time a record is created, edited or replaced.  The plugin
+// rebuilds its index of all records when the plugin cache is built.
+type PluginIndexer interface {
+	// Replace the indexed content of a record
+	IndexRecord(Record) error
+}
+
+// Reconciler is implemented by caches that can rebuild individual records
+// instead of the entire cache.
+type Reconciler interface {
+	// Get the summaries of all versions of all records
+	RecordSummaries() ([]RecordSummary, error)
+
+	// Replace all versions of a record.  The record is removed when no
+	// versions are provided.
+	ReplaceRecord(string, []Record) error
+
+	// Compare the plugin inventory of the backend with the plugin cache
+	PluginDrift(string, string) ([]Drift, error)
+
+	// Rebuild the plugin data of a set of records
+	PluginReconcile(string, []string, string) error
+}
+
 // Cache describes the interface used for interacting with an external
 // politeiad cache.  The politeiad backend implementation serves as the source
 // of truth for politeiad data and an external cache can be used if more
@@ -178,6 +286,10 @@ type Cache interface {
 	// Get the latest version of all records
 	Inventory() ([]Record, error)
 
+	// Get a filtered page of the latest version of all records and the
+	// cursor of the next page
+	InventoryPage(InventoryFilter) ([]Record, string, error)
+
 	// Get a summary of the number of records by status
 	InventoryStats() (*InventoryStats, error)
 
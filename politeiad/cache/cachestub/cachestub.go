@@ -49,6 +49,11 @@ func (c *cachestub) Inventory() ([]cache.Record, error) {
 	return make([]cache.Record, 0), nil
 }
 
+// InventoryPage is a stub to satisfy the cache interface.
+func (c *cachestub) InventoryPage(f cache.InventoryFilter) ([]cache.Record, string, error) {
+	return make([]cache.Record, 0), "", nil
+}
+
 // InventoryStats is a stub to satisfy the cache interface.
 func (c *cachestub) InventoryStats() (*cache.InventoryStats, error) {
 	return &cache.InventoryStats{}, nil
@@ -9,11 +9,11 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/decred/politeia/decredplugin"
 	"github.com/decred/politeia/politeiad/cache"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
@@ -34,6 +34,47 @@ const (
 	UserPoliteiawww = "politeiawww" // politeiawww user (read access)
 )
 
+var (
+	// pluginDriversMtx protects the plugin driver constructors.
+	pluginDriversMtx sync.RWMutex
+
+	// pluginDrivers contains the constructors of the cache implementations
+	// of all registered plugins.
+	pluginDrivers = make(map[string]PluginDriverConstructor) // [pluginID]constructor
+)
+
+// PluginDriverConstructor returns the cache implementation of a plugin.  The
+// plugin tables are created in the provided database.
+type PluginDriverConstructor func(*gorm.DB, cache.Plugin) cache.PluginDriver
+
+// RegisterPluginDriver makes the cache implementation of a plugin available
+// under the provided plugin identifier.  It is meant to be called from the
+// init function of the package that implements the plugin.  It panics if the
+// identifier was already registered.
+func RegisterPluginDriver(id string, c PluginDriverConstructor) {
+	pluginDriversMtx.Lock()
+	defer pluginDriversMtx.Unlock()
+
+	if _, ok := pluginDrivers[id]; ok {
+		panic(fmt.Sprintf("duplicate plugin driver: %v", id))
+	}
+	pluginDrivers[id] = c
+}
+
+// RegisteredPluginDrivers returns the sorted identifiers of the plugins that
+// have a cache implementation.
+func RegisteredPluginDrivers() []string {
+	pluginDriversMtx.RLock()
+	defer pluginDriversMtx.RUnlock()
+
+	ids := make([]string, 0, len(pluginDrivers))
+	for k := range pluginDrivers {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // cockroachdb implements the cache interface.
 type cockroachdb struct {
 	sync.RWMutex
@@ -42,6 +83,25 @@ type cockroachdb struct {
 	plugins   map[string]cache.PluginDriver // [pluginID]PluginDriver
 }
 
+// indexRecord passes the most recent version of a record to the plugins that
+// index record content.
+//
+// This function must be called with the lock held.
+func (c *cockroachdb) indexRecord(r cache.Record) error {
+	for id, p := range c.plugins {
+		pi, ok := p.(cache.PluginIndexer)
+		if !ok {
+			continue
+		}
+		err := pi.IndexRecord(r)
+		if err != nil {
+			return fmt.Errorf("index %v %v: %v", id,
+				r.CensorshipRecord.Token, err)
+		}
+	}
+	return nil
+}
+
 // NewRecord creates a new entry in the database for the passed in record.
 func (c *cockroachdb) NewRecord(cr cache.Record) error {
 	log.Tracef("NewRecord: %v", cr.CensorshipRecord.Token)
@@ -61,7 +121,14 @@ func (c *cockroachdb) NewRecord(cr cache.Record) error {
 	}
 
 	r := convertRecordFromCache(cr, v)
-	return c.recordsdb.Create(&r).Error
+	err = c.recordsdb.Create(&r).Error
+	if err != nil {
+		return err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+	return c.indexRecord(cr)
 }
 
 // recordVersion gets the specified version of a record from the database.
@@ -268,7 +335,14 @@ func (c *cockroachdb) UpdateRecord(r cache.Record) error {
 		tx.Rollback()
 		return err
 	}
-	return tx.Commit().Error
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+	return c.indexRecord(r)
 }
 
 // updateRecordStatus updates the status of a record in the database.  This
@@ -474,6 +548,147 @@ func (c *cockroachdb) Inventory() ([]cache.Record, error) {
 	return c.getRecords(true, nil, true)
 }
 
+// InventoryPage returns a filtered page of the latest version of all records
+// and the cursor of the next page.  The filter is applied by the database so
+// only a single page of records is loaded.
+func (c *cockroachdb) InventoryPage(f cache.InventoryFilter) ([]cache.Record, string, error) {
+	log.Tracef("InventoryPage: %v %v", f.State, f.Cursor)
+
+	c.RLock()
+	shutdown := c.shutdown
+	c.RUnlock()
+
+	if shutdown {
+		return nil, "", cache.ErrShutdown
+	}
+
+	// This query gets the latest version of each record
+	query := `SELECT a.* FROM records a
+		LEFT OUTER JOIN records b
+			ON a.token = b.token AND a.version < b.version
+		WHERE b.token IS NULL`
+	args := make([]interface{}, 0, 16)
+
+	vetted := []int{int(cache.RecordStatusPublic),
+		int(cache.RecordStatusArchived)}
+	switch f.State {
+	case cache.InventoryStateVetted:
+		query += ` AND a.status IN (?)`
+		args = append(args, vetted)
+	case cache.InventoryStateUnvetted:
+		query += ` AND a.status NOT IN (?)`
+		args = append(args, vetted)
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]int, 0, len(f.Statuses))
+		for _, v := range f.Statuses {
+			statuses = append(statuses, int(v))
+		}
+		query += ` AND a.status IN (?)`
+		args = append(args, statuses)
+	}
+	if f.FromTimestamp != 0 {
+		query += ` AND a.timestamp >= ?`
+		args = append(args, f.FromTimestamp)
+	}
+	if f.UntilTimestamp != 0 {
+		query += ` AND a.timestamp < ?`
+		args = append(args, f.UntilTimestamp)
+	}
+	for _, v := range f.MDStreams {
+		query += ` AND EXISTS (SELECT 1 FROM metadata_streams m
+			WHERE m.record_key = a.key AND m.id = ?)`
+		args = append(args, v)
+	}
+
+	// Records are ordered by the most recent timestamp first
+	if f.Cursor != "" {
+		timestamp, token, err := cache.ParseInventoryCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if f.Reverse {
+			query += ` AND (a.timestamp > ? OR
+				(a.timestamp = ? AND a.token < ?))`
+		} else {
+			query += ` AND (a.timestamp < ? OR
+				(a.timestamp = ? AND a.token > ?))`
+		}
+		args = append(args, timestamp, timestamp, token)
+	}
+	if f.Reverse {
+		query += ` ORDER BY a.timestamp ASC, a.token DESC`
+	} else {
+		query += ` ORDER BY a.timestamp DESC, a.token ASC`
+	}
+
+	// Request one extra record to find out if there is a next page
+	limit := int(f.Limit)
+	if limit == 0 || limit > cache.InventoryPageSize {
+		limit = cache.InventoryPageSize
+	}
+	query += ` LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := c.recordsdb.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	records := make([]Record, 0, limit+1)
+	for rows.Next() {
+		var r Record
+		err := c.recordsdb.ScanRows(rows, &r)
+		if err != nil {
+			return nil, "", err
+		}
+		records = append(records, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(records) == 0 {
+		return []cache.Record{}, "", nil
+	}
+
+	var cursor string
+	if len(records) > limit {
+		records = records[:limit]
+		cursor = cache.InventoryCursor(convertRecordToCache(
+			records[len(records)-1]))
+	}
+
+	// Load the metadata and files of the page.  The order of the page is
+	// restored afterwards.
+	keys := make([]string, 0, len(records))
+	order := make(map[string]int, len(records))
+	for k, r := range records {
+		keys = append(keys, r.Key)
+		order[r.Key] = k
+	}
+	db := c.recordsdb
+	if f.IncludeFiles {
+		db = db.Preload("Files")
+	}
+	var page []Record
+	err = db.
+		Preload("Metadata").
+		Where(keys).
+		Find(&page).
+		Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	cr := make([]cache.Record, len(page))
+	for _, r := range page {
+		cr[order[r.Key]] = convertRecordToCache(r)
+	}
+
+	return cr, cursor, nil
+}
+
 // InventoryStats compiles summary statistics on the number of records in the
 // database grouped by record status.  Only the latest version of each record
 // is included in the statistics.
@@ -609,14 +824,14 @@ func (c *cockroachdb) RegisterPlugin(p cache.Plugin) error {
 	}
 
 	// Register the plugin
-	var pd cache.PluginDriver
-	switch p.ID {
-	case decredplugin.ID:
-		pd = newDecredPlugin(c.recordsdb, p)
-		c.plugins[decredplugin.ID] = pd
-	default:
+	pluginDriversMtx.RLock()
+	newPluginDriver, ok := pluginDrivers[p.ID]
+	pluginDriversMtx.RUnlock()
+	if !ok {
 		return cache.ErrInvalidPlugin
 	}
+	pd := newPluginDriver(c.recordsdb, p)
+	c.plugins[p.ID] = pd
 
 	// Ensure we're using the correct plugin version
 	return pd.CheckVersion()
@@ -192,6 +192,7 @@ func convertStartVoteFromDecred(sv decredplugin.StartVote, svr decredplugin.Star
 	}
 	return StartVote{
 		Token:               sv.Vote.Token,
+		Type:                int(sv.Vote.Type),
 		Mask:                sv.Vote.Mask,
 		Duration:            sv.Vote.Duration,
 		QuorumPercentage:    sv.Vote.QuorumPercentage,
@@ -222,6 +223,7 @@ func convertStartVoteToDecred(sv StartVote) (decredplugin.StartVote, decredplugi
 		Signature: sv.Signature,
 		Vote: decredplugin.Vote{
 			Token:            sv.Token,
+			Type:             decredplugin.VoteT(sv.Type),
 			Mask:             sv.Mask,
 			Duration:         sv.Duration,
 			QuorumPercentage: sv.QuorumPercentage,
@@ -244,6 +246,66 @@ func convertStartVoteToDecred(sv StartVote) (decredplugin.StartVote, decredplugi
 	return dsv, dsvr
 }
 
+func convertVoteRunoffFromDecred(vr decredplugin.VoteRunoff, endHeight uint64) VoteRunoff {
+	return VoteRunoff{
+		Token:            vr.Token,
+		Tokens:           strings.Join(vr.Tokens, ","),
+		StartBlockHeight: vr.StartBlockHeight,
+		StartBlockHash:   vr.StartBlockHash,
+		EndHeight:        endHeight,
+	}
+}
+
+func convertVoteRunoffToDecred(vr VoteRunoff) decredplugin.VoteRunoff {
+	var tokens []string
+	if vr.Tokens != "" {
+		tokens = strings.Split(vr.Tokens, ",")
+	}
+	return decredplugin.VoteRunoff{
+		Version:          decredplugin.VersionVoteRunoff,
+		Token:            vr.Token,
+		Tokens:           tokens,
+		StartBlockHeight: vr.StartBlockHeight,
+		StartBlockHash:   vr.StartBlockHash,
+		EndHeight:        fmt.Sprint(vr.EndHeight),
+	}
+}
+
+func convertVoteScheduleFromDecred(sv decredplugin.ScheduleVote) (*VoteSchedule, error) {
+	svb, err := decredplugin.EncodeStartVote(sv.StartVote)
+	if err != nil {
+		return nil, err
+	}
+	return &VoteSchedule{
+		Token:       sv.Token,
+		Action:      sv.Action,
+		StartHeight: sv.StartHeight,
+		StartVote:   string(svb),
+		Signature:   sv.Signature,
+		PublicKey:   sv.PublicKey,
+		Receipt:     sv.Receipt,
+		Timestamp:   sv.Timestamp,
+	}, nil
+}
+
+func convertVoteScheduleToDecred(vs VoteSchedule) (*decredplugin.ScheduleVote, error) {
+	sv, err := decredplugin.DecodeStartVote([]byte(vs.StartVote))
+	if err != nil {
+		return nil, err
+	}
+	return &decredplugin.ScheduleVote{
+		Version:     decredplugin.VersionScheduleVote,
+		Receipt:     vs.Receipt,
+		Timestamp:   vs.Timestamp,
+		Action:      vs.Action,
+		Token:       vs.Token,
+		StartHeight: vs.StartHeight,
+		StartVote:   *sv,
+		Signature:   vs.Signature,
+		PublicKey:   vs.PublicKey,
+	}, nil
+}
+
 func convertCastVoteFromDecred(cv decredplugin.CastVote) CastVote {
 	return CastVote{
 		Token:        cv.Token,
@@ -6,6 +6,8 @@ package cockroachdb
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,7 +22,7 @@ const (
 	// decredVersion is the version of the cache implementation of
 	// decred plugin. This may differ from the decredplugin package
 	// version.
-	decredVersion = "1.1"
+	decredVersion = "1.5"
 
 	// Decred plugin table names
 	tableComments          = "comments"
@@ -31,11 +33,79 @@ const (
 	tableStartVotes        = "start_votes"
 	tableVoteOptionResults = "vote_option_results"
 	tableVoteResults       = "vote_results"
-
-	// Vote option IDs
-	voteOptionIDApproved = "yes"
+	tableVoteRunoffs       = "vote_runoffs"
+	tableVoteSchedules     = "vote_schedules"
+	tableSearchTerms       = "search_terms"
+
+	// searchIndexBatch is the number of records or comments that are
+	// added to the search index in a single transaction when the index
+	// is built.
+	searchIndexBatch = 100
+
+	// searchTermsInsert is the maximum number of search index entries
+	// that are inserted with a single statement.
+	searchTermsInsert = 1000
 )
 
+// decredMigrations contains the migrations of the decred plugin tables.  The
+// last migration must migrate to decredVersion.
+var decredMigrations = []Migration{
+	{
+		From:        "1.1",
+		To:          "1.2",
+		Description: "Add vote runoffs table",
+		Migrate: func(tx *gorm.DB) error {
+			// The vote runoffs table is created by Setup and
+			// no runoffs exist prior to this version.
+			return nil
+		},
+	},
+	{
+		From:        "1.2",
+		To:          "1.3",
+		Description: "Add vote type to start votes",
+		Migrate: func(tx *gorm.DB) error {
+			// Existing start votes are approval votes, which
+			// is what the zero vote type defaults to.
+			return tx.Exec("ALTER TABLE " + tableStartVotes +
+				" ADD COLUMN IF NOT EXISTS type INT NOT NULL " +
+				"DEFAULT 0").Error
+		},
+	},
+	{
+		From:        "1.3",
+		To:          "1.4",
+		Description: "Add vote schedules table",
+		Migrate: func(tx *gorm.DB) error {
+			// The vote schedules table is created by Setup and
+			// no vote schedules exist prior to this version.
+			return nil
+		},
+	},
+	{
+		From:        "1.4",
+		To:          "1.5",
+		Description: "Add search index",
+		Batched: func(db *gorm.DB) error {
+			// The search terms table is created by Setup. The
+			// index contains every record and comment and is
+			// therefore built in batches.
+			err := indexRecords(db)
+			if err != nil {
+				return err
+			}
+			return indexComments(db)
+		},
+	},
+}
+
+func init() {
+	RegisterPluginDriver(decredplugin.ID,
+		func(db *gorm.DB, p cache.Plugin) cache.PluginDriver {
+			return newDecredPlugin(db, p)
+		})
+}
+
 // decred implements the PluginDriver interface.
 type decred struct {
 	recordsdb *gorm.DB              // Database context
@@ -58,9 +128,19 @@ func (d *decred) cmdNewComment(cmdPayload, replyPayload string) (string, error)
 	}
 
 	c := convertNewCommentFromDecred(*nc, *ncr)
-	err = d.recordsdb.Create(&c).Error
+	tx := d.recordsdb.Begin()
+	err = tx.Create(&c).Error
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	err = indexComment(tx, c)
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("index comment: %v", err)
+	}
 
-	return replyPayload, err
+	return replyPayload, tx.Commit().Error
 }
 
 // cmdLikeComment creates a LikeComment record using the passed in payloads
@@ -92,13 +172,23 @@ func (d *decred) cmdCensorComment(cmdPayload, replyPayload string) (string, erro
 	c := Comment{
 		Key: cc.Token + cc.CommentID,
 	}
-	err = d.recordsdb.Model(&c).
+	tx := d.recordsdb.Begin()
+	err = tx.Model(&c).
 		Updates(map[string]interface{}{
 			"comment":  "",
 			"censored": true,
 		}).Error
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	err = unindexComment(tx, cc.Token, cc.CommentID)
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("unindex comment: %v", err)
+	}
 
-	return replyPayload, err
+	return replyPayload, tx.Commit().Error
 }
 
 func (d *decred) commentGetByID(token string, commentID string) (*Comment, error) {
@@ -411,8 +501,229 @@ func (d *decred) cmdStartVote(cmdPayload, replyPayload string) (string, error) {
 	return replyPayload, nil
 }
 
-// cmdVoteDetails returns the AuthorizeVote and StartVote records for the
-// passed in record token.
+// cmdScheduleVote creates a VoteSchedule record using the passed in payloads
+// and inserts it into the database.  The VoteSchedule record replaces any
+// previous schedule of the proposal.  A cancelled or failed schedule keeps
+// the start vote of the schedule.
+func (d *decred) cmdScheduleVote(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdScheduleVote")
+
+	sv, err := decredplugin.DecodeScheduleVote([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	svr, err := decredplugin.DecodeScheduleVoteReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+	sv.Receipt = svr.Receipt
+	sv.Timestamp = svr.Timestamp
+
+	// Run update in a transaction
+	tx := d.recordsdb.Begin()
+	if sv.Action != decredplugin.ScheduleVoteActionSchedule {
+		var vs VoteSchedule
+		err = tx.Where("token = ?", sv.Token).
+			Find(&vs).
+			Error
+		if err != nil {
+			tx.Rollback()
+			return "", fmt.Errorf("vote schedule lookup failed: %v",
+				err)
+		}
+		current, err := convertVoteScheduleToDecred(vs)
+		if err != nil {
+			tx.Rollback()
+			return "", err
+		}
+		sv.StartVote = current.StartVote
+	}
+	sv.StartVote.Version = decredplugin.VersionStartVote
+	vs, err := convertVoteScheduleFromDecred(*sv)
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	err = tx.Where("token = ?", vs.Token).
+		Delete(VoteSchedule{}).
+		Error
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("delete vote schedule: %v", err)
+	}
+	err = tx.Create(vs).Error
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("create vote schedule: %v", err)
+	}
+
+	// Commit transaction
+	err = tx.Commit().Error
+	if err != nil {
+		return "", fmt.Errorf("commit transaction: %v", err)
+	}
+
+	return replyPayload, nil
+}
+
+// cmdScheduledVotes returns the vote schedules of the proposal votes that are
+// scheduled and have not been started yet.
+func (d *decred) cmdScheduledVotes(payload string) (string, error) {
+	log.Tracef("decred cmdScheduledVotes")
+
+	_, err := decredplugin.DecodeScheduledVotes([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	var vss []VoteSchedule
+	err = d.recordsdb.
+		Where("action = ?", decredplugin.ScheduleVoteActionSchedule).
+		Order("start_height").
+		Find(&vss).
+		Error
+	if err != nil {
+		return "", fmt.Errorf("vote schedules: %v", err)
+	}
+
+	// Filter out the votes that have already been started
+	var svs []StartVote
+	err = d.recordsdb.
+		Select("token").
+		Find(&svs).
+		Error
+	if err != nil {
+		return "", fmt.Errorf("start votes: %v", err)
+	}
+	started := make(map[string]struct{}, len(svs))
+	for _, v := range svs {
+		started[v.Token] = struct{}{}
+	}
+
+	svr := decredplugin.ScheduledVotesReply{
+		ScheduleVotes: make([]decredplugin.ScheduleVote, 0, len(vss)),
+	}
+	for _, v := range vss {
+		if _, ok := started[v.Token]; ok {
+			continue
+		}
+		sv, err := convertVoteScheduleToDecred(v)
+		if err != nil {
+			return "", err
+		}
+		svr.ScheduleVotes = append(svr.ScheduleVotes, *sv)
+	}
+
+	reply, err := decredplugin.EncodeScheduledVotesReply(svr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// voteSchedule returns the VoteSchedule record of the passed in token.  nil
+// is returned when the vote has never been scheduled.
+func (d *decred) voteSchedule(token string) (*VoteSchedule, error) {
+	var vs VoteSchedule
+	err := d.recordsdb.
+		Where("token = ?", token).
+		Find(&vs).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("vote schedule lookup failed: %v", err)
+	}
+	return &vs, nil
+}
+
+// cmdStartVoteRunoff creates a StartVote record for each of the runoff
+// submissions and a VoteRunoff record for the RFP using the passed in
+// payloads.  All records are inserted inside of a single transaction.
+func (d *decred) cmdStartVoteRunoff(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdStartVoteRunoff")
+
+	svr, err := decredplugin.DecodeStartVoteRunoff([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	svrr, err := decredplugin.DecodeStartVoteRunoffReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	endHeight, err := strconv.ParseUint(svrr.StartVoteReply.EndHeight, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("parse end height '%v': %v",
+			svrr.StartVoteReply.EndHeight, err)
+	}
+
+	tokens := make([]string, 0, len(svr.StartVotes))
+	tx := d.recordsdb.Begin()
+	for _, v := range svr.StartVotes {
+		s := convertStartVoteFromDecred(v, svrr.StartVoteReply, endHeight)
+		err = d.newStartVote(tx, s)
+		if err != nil {
+			tx.Rollback()
+			return "", err
+		}
+		tokens = append(tokens, v.Vote.Token)
+	}
+	vr := convertVoteRunoffFromDecred(decredplugin.VoteRunoff{
+		Token:            svr.Token,
+		Tokens:           tokens,
+		StartBlockHeight: svrr.StartVoteReply.StartBlockHeight,
+		StartBlockHash:   svrr.StartVoteReply.StartBlockHash,
+	}, endHeight)
+	err = tx.Create(&vr).Error
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, nil
+}
+
+// cmdVoteRunoffDetails returns the VoteRunoff record of the passed in RFP
+// token.  An empty VoteRunoff is returned when no runoff vote has been
+// started for the RFP.
+func (d *decred) cmdVoteRunoffDetails(payload string) (string, error) {
+	log.Tracef("decred cmdVoteRunoffDetails")
+
+	vrd, err := decredplugin.DecodeVoteRunoffDetails([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	var vrdr decredplugin.VoteRunoffDetailsReply
+	var vr VoteRunoff
+	err = d.recordsdb.
+		Where("token = ?", vrd.Token).
+		Find(&vr).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		// A runoff vote may not exist. This is ok.
+	} else if err != nil {
+		return "", fmt.Errorf("vote runoff lookup failed: %v", err)
+	} else {
+		vrdr.VoteRunoff = convertVoteRunoffToDecred(vr)
+	}
+
+	reply, err := decredplugin.EncodeVoteRunoffDetailsReply(vrdr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// cmdVoteDetails returns the AuthorizeVote, VoteSchedule and StartVote
+// records for the passed in record token.
 func (d *decred) cmdVoteDetails(payload string) (string, error) {
 	log.Tracef("decred cmdVoteDetails")
 
@@ -462,6 +773,12 @@ func (d *decred) cmdVoteDetails(payload string) (string, error) {
 		return "", fmt.Errorf("start vote lookup failed: %v", err)
 	}
 
+	// Lookup vote schedule
+	vs, err := d.voteSchedule(vd.Token)
+	if err != nil {
+		return "", err
+	}
+
 	// Prepare reply
 	dav := convertAuthorizeVoteToDecred(av)
 	dsv, dsvr := convertStartVoteToDecred(sv)
@@ -470,6 +787,13 @@ func (d *decred) cmdVoteDetails(payload string) (string, error) {
 		StartVote:      dsv,
 		StartVoteReply: dsvr,
 	}
+	if vs != nil {
+		dvs, err := convertVoteScheduleToDecred(*vs)
+		if err != nil {
+			return "", err
+		}
+		vdr.ScheduleVote = *dvs
+	}
 	vdrb, err := decredplugin.EncodeVoteDetailsReply(vdr)
 	if err != nil {
 		return "", err
@@ -607,6 +931,34 @@ func (d *decred) cmdInventory() (string, error) {
 	return string(irb), err
 }
 
+// ballots returns the number of cast votes per vote bit of a proposal vote.
+func (d *decred) ballots(token string) (map[uint64]uint64, error) {
+	var cv []CastVote
+	err := d.recordsdb.
+		Where("token = ?", token).
+		Find(&cv).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		// No cast votes exists. In theory, this could
+		// happen if no one were to vote on a proposal.
+		// In practice, this shouldn't happen.
+	} else if err != nil {
+		return nil, fmt.Errorf("lookup cast votes: %v", err)
+	}
+
+	ballots := make(map[uint64]uint64) // [voteBit]voteCount
+	for _, v := range cv {
+		bit, err := strconv.ParseUint(v.VoteBit, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vote bit %v %v: %v",
+				v.Ticket, v.VoteBit, err)
+		}
+		ballots[bit]++
+	}
+
+	return ballots, nil
+}
+
 // newVoteResults creates a VoteResults record for a proposal and inserts it
 // into the cache. A VoteResults record should only be created for proposals
 // once the voting period has ended.
@@ -624,74 +976,34 @@ func (d *decred) newVoteResults(token string) error {
 		return fmt.Errorf("lookup start vote: %v", err)
 	}
 
-	// Lookup cast votes
-	var cv []CastVote
-	err = d.recordsdb.
-		Where("token = ?", token).
-		Find(&cv).
-		Error
-	if err == gorm.ErrRecordNotFound {
-		// No cast votes exists. In theory, this could
-		// happen if no one were to vote on a proposal.
-		// In practice, this shouldn't happen.
-	} else if err != nil {
-		return fmt.Errorf("lookup cast votes: %v", err)
+	ballots, err := d.ballots(token)
+	if err != nil {
+		return err
 	}
 
-	// Tally cast votes
-	tally := make(map[string]uint64) // [voteBit]voteCount
-	for _, v := range cv {
-		tally[v.VoteBit]++
-	}
+	// Tally the cast votes according to the vote type
+	dsv, _ := convertStartVoteToDecred(sv)
+	eligible := len(strings.Split(sv.EligibleTickets, ","))
+	t := decredplugin.TallyVote(dsv.Vote, eligible, ballots)
 
 	// Create vote option results
-	results := make([]VoteOptionResult, 0, len(sv.Options))
+	options := make(map[uint64]VoteOption, len(sv.Options))
 	for _, v := range sv.Options {
-		voteBit := strconv.FormatUint(v.Bits, 16)
-		voteCount := tally[voteBit]
-
+		options[v.Bits] = v
+	}
+	results := make([]VoteOptionResult, 0, len(t.Results))
+	for _, v := range t.Results {
 		results = append(results, VoteOptionResult{
-			Key:    token + voteBit,
-			Votes:  voteCount,
-			Option: v,
+			Key:    token + strconv.FormatUint(v.Bits, 16),
+			Votes:  v.Votes,
+			Option: options[v.Bits],
 		})
 	}
 
-	// Check whether vote was approved
-	var total uint64
-	for _, v := range results {
-		total += v.Votes
-	}
-
-	eligible := len(strings.Split(sv.EligibleTickets, ","))
-	quorum := uint64(float64(sv.QuorumPercentage) / 100 * float64(eligible))
-	pass := uint64(float64(sv.PassPercentage) / 100 * float64(total))
-
-	// XXX: this only supports proposals with yes/no
-	// voting options. Multiple voting option support
-	// will need to be added in the future.
-	var approvedVotes uint64
-	for _, v := range results {
-		if v.Option.ID == voteOptionIDApproved {
-			approvedVotes = v.Votes
-		}
-	}
-
-	var approved bool
-	switch {
-	case total < quorum:
-		// Quorum not met
-	case approvedVotes < pass:
-		// Pass percentage not met
-	default:
-		// Vote was approved
-		approved = true
-	}
-
 	// Create a vote results entry
 	err = d.recordsdb.Create(&VoteResults{
 		Token:    token,
-		Approved: approved,
+		Approved: t.Approved,
 		Results:  results,
 	}).Error
 	if err != nil {
@@ -977,11 +1289,15 @@ func (d *decred) cmdVoteSummary(payload string) (string, error) {
 	}
 
 	// Declare here to prevent goto errors
-	results := make([]decredplugin.VoteOptionResult, 0, 16)
 	var (
-		av AuthorizeVote
-		sv StartVote
-		vr VoteResults
+		av              AuthorizeVote
+		schedule        *VoteSchedule
+		scheduledHeight uint32
+		sv              StartVote
+		vr              VoteResults
+		dsv             decredplugin.StartVote
+		ballots         map[uint64]uint64
+		t               decredplugin.VoteTally
 	)
 
 	// Lookup authorize vote
@@ -1005,14 +1321,34 @@ func (d *decred) cmdVoteSummary(payload string) (string, error) {
 		Find(&sv).
 		Error
 	if err == gorm.ErrRecordNotFound {
-		// If an start vote doesn't exist then
-		// there is no need to continue.
+		// If an start vote doesn't exist then the
+		// vote may have been scheduled.
+		schedule, err = d.voteSchedule(vs.Token)
+		if err != nil {
+			return "", err
+		}
+		if schedule != nil &&
+			schedule.Action == decredplugin.ScheduleVoteActionSchedule {
+			scheduledHeight = schedule.StartHeight
+		}
 		goto sendReply
 	} else if err != nil {
 		return "", fmt.Errorf("lookup start vote: %v", err)
 	}
+	dsv, _ = convertStartVoteToDecred(sv)
+
+	// The rounds of a ranked choice vote can only be counted using
+	// the individual ballots.
+	if decredplugin.VoteType(dsv.Vote) == decredplugin.VoteTypeRankedChoice {
+		ballots, err = d.ballots(vs.Token)
+		if err != nil {
+			return "", err
+		}
+		goto tally
+	}
 
 	// Lookup vote results
+	ballots = make(map[uint64]uint64, len(sv.Options))
 	err = d.recordsdb.
 		Where("token = ?", vs.Token).
 		Preload("Results").
@@ -1028,10 +1364,11 @@ func (d *decred) cmdVoteSummary(payload string) (string, error) {
 		return "", fmt.Errorf("lookup vote results: %v", err)
 	} else {
 		// Vote results record exists. We have all of the data
-		// that we need to send the reply.
-		vor := convertVoteOptionResultsToDecred(vr.Results)
-		results = append(results, vor...)
-		goto sendReply
+		// that we need to tally the vote.
+		for _, v := range vr.Results {
+			ballots[v.Option.Bits] = v.Votes
+		}
+		goto tally
 	}
 
 	// Lookup vote results manually
@@ -1046,16 +1383,12 @@ func (d *decred) cmdVoteSummary(payload string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("count cast votes: %v", err)
 		}
-
-		results = append(results,
-			decredplugin.VoteOptionResult{
-				ID:          v.ID,
-				Description: v.Description,
-				Bits:        v.Bits,
-				Votes:       votes,
-			})
+		ballots[v.Bits] = votes
 	}
 
+tally:
+	t = decredplugin.TallyVote(dsv.Vote, sv.EligibleTicketCount, ballots)
+
 sendReply:
 	// Return "" not "0" if end height doesn't exist
 	var endHeight string
@@ -1065,11 +1398,21 @@ sendReply:
 
 	vsr := decredplugin.VoteSummaryReply{
 		Authorized:          (av.Action == decredplugin.AuthVoteActionAuthorize),
+		ScheduledHeight:     scheduledHeight,
+		Type:                t.Type,
 		EndHeight:           endHeight,
 		EligibleTicketCount: sv.EligibleTicketCount,
 		QuorumPercentage:    sv.QuorumPercentage,
 		PassPercentage:      sv.PassPercentage,
-		Results:             results,
+		Results:             t.Results,
+		TotalVotes:          t.TotalVotes,
+		QuorumMet:           t.QuorumMet,
+		Winner:              t.Winner,
+		Approved:            t.Approved,
+		Rounds:              t.Rounds,
+	}
+	if vsr.Results == nil {
+		vsr.Results = []decredplugin.VoteOptionResult{}
 	}
 	reply, err := decredplugin.EncodeVoteSummaryReply(vsr)
 	if err != nil {
@@ -1079,36 +1422,522 @@ sendReply:
 	return string(reply), nil
 }
 
-// Exec executes a decred plugin command.  Plugin commands that write data to
-// the cache require both the command payload and the reply payload.  Plugin
-// commands that fetch data from the cache require only the command payload.
-// All commands return the appropriate reply payload.
-func (d *decred) Exec(cmd, cmdPayload, replyPayload string) (string, error) {
-	log.Tracef("decred Exec: %v", cmd)
+// searchTerms returns the search index entries of a text.
+func searchTerms(token, source, commentID, text string) []SearchTerm {
+	terms := cache.SearchTerms(text)
+	st := make([]SearchTerm, 0, len(terms))
+	for _, v := range terms {
+		st = append(st, SearchTerm{
+			Term:      v,
+			Token:     token,
+			Source:    source,
+			CommentID: commentID,
+		})
+	}
+	return st
+}
 
-	switch cmd {
-	case decredplugin.CmdAuthorizeVote:
-		return d.cmdAuthorizeVote(cmdPayload, replyPayload)
-	case decredplugin.CmdStartVote:
-		return d.cmdStartVote(cmdPayload, replyPayload)
-	case decredplugin.CmdVoteDetails:
-		return d.cmdVoteDetails(cmdPayload)
-	case decredplugin.CmdBallot:
-		return d.cmdNewBallot(cmdPayload, replyPayload)
-	case decredplugin.CmdBestBlock:
-		return "", nil
-	case decredplugin.CmdNewComment:
-		return d.cmdNewComment(cmdPayload, replyPayload)
-	case decredplugin.CmdLikeComment:
-		return d.cmdLikeComment(cmdPayload, replyPayload)
-	case decredplugin.CmdCensorComment:
-		return d.cmdCensorComment(cmdPayload, replyPayload)
-	case decredplugin.CmdGetComment:
-		return d.cmdGetComment(cmdPayload)
-	case decredplugin.CmdGetComments:
-		return d.cmdGetComments(cmdPayload)
-	case decredplugin.CmdGetNumComments:
-		return d.cmdGetNumComments(cmdPayload)
+// insertSearchTerms adds entries to the search index using multi-row inserts
+// of at most searchTermsInsert entries.  This function has a database
+// parameter so that it can be called inside of a transaction when required.
+func insertSearchTerms(db *gorm.DB, st []SearchTerm) error {
+	for len(st) > 0 {
+		n := len(st)
+		if n > searchTermsInsert {
+			n = searchTermsInsert
+		}
+		values := make([]string, 0, n)
+		args := make([]interface{}, 0, 4*n)
+		for _, v := range st[:n] {
+			values = append(values, "(?, ?, ?, ?)")
+			args = append(args, v.Term, v.Token, v.Source, v.CommentID)
+		}
+		err := db.Exec("INSERT INTO "+tableSearchTerms+
+			" (term, token, source, comment_id) VALUES "+
+			strings.Join(values, ", "), args...).Error
+		if err != nil {
+			return err
+		}
+		st = st[n:]
+	}
+	return nil
+}
+
+// indexRecord replaces the search index entries of the name and of the index
+// file of a record.  A record without an index file is removed from the
+// index.  This function has a database parameter so that it can be called
+// inside of a transaction when required.
+func indexRecord(db *gorm.DB, r cache.Record) error {
+	token := r.CensorshipRecord.Token
+	err := db.Where("token = ? AND source IN (?)", token,
+		[]string{cache.SearchSourceName, cache.SearchSourceIndex}).
+		Delete(SearchTerm{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	name, text, ok, err := cache.RecordText(r, decredplugin.IndexFile)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	st := append(searchTerms(token, cache.SearchSourceName, "", name),
+		searchTerms(token, cache.SearchSourceIndex, "", text)...)
+
+	return insertSearchTerms(db, st)
+}
+
+// indexRecords adds the most recent version of all records to the search
+// index.  The records are indexed in batches of searchIndexBatch records that
+// are each committed in their own transaction so that the cockroachdb
+// transaction size limit is not exceeded.  Indexing a record replaces its
+// entries, which allows an interrupted build to be run again.
+//
+// This function cannot be called using a transaction.
+func indexRecords(db *gorm.DB) error {
+	query := `SELECT a.key
+        FROM records a
+        LEFT OUTER JOIN records b
+          ON a.token = b.token
+          AND a.version < b.version
+        WHERE b.token IS NULL`
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, 1024) // PNOOMA
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > searchIndexBatch {
+			n = searchIndexBatch
+		}
+		records := make([]Record, 0, n)
+		err := db.
+			Where(keys[:n]).
+			Preload("Files", "name = ?", decredplugin.IndexFile).
+			Find(&records).
+			Error
+		if err != nil {
+			return err
+		}
+
+		tx := db.Begin()
+		for _, r := range records {
+			err := indexRecord(tx, convertRecordToCache(r))
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("index record %v: %v", r.Token, err)
+			}
+		}
+		err = tx.Commit().Error
+		if err != nil {
+			return err
+		}
+
+		keys = keys[n:]
+	}
+
+	return nil
+}
+
+// indexComment adds a comment to the search index.  Censored comments are not
+// indexed.  This function has a database parameter so that it can be called
+// inside of a transaction when required.
+func indexComment(db *gorm.DB, c Comment) error {
+	if c.Censored {
+		return nil
+	}
+	return insertSearchTerms(db, searchTerms(c.Token,
+		cache.SearchSourceComment, c.CommentID, c.Comment))
+}
+
+// indexComments adds all non-censored comments to the search index.  The
+// comments are indexed in batches of searchIndexBatch comments that are each
+// committed in their own transaction so that the cockroachdb transaction size
+// limit is not exceeded.  The existing entries of a comment are replaced,
+// which allows an interrupted build to be run again.
+//
+// This function cannot be called using a transaction.
+func indexComments(db *gorm.DB) error {
+	var after string
+	for {
+		cs := make([]Comment, 0, searchIndexBatch)
+		err := db.
+			Where("key > ?", after).
+			Order("key").
+			Limit(searchIndexBatch).
+			Find(&cs).
+			Error
+		if err != nil {
+			return err
+		}
+		if len(cs) == 0 {
+			return nil
+		}
+
+		tx := db.Begin()
+		st := make([]SearchTerm, 0, 1024) // PNOOMA
+		for _, c := range cs {
+			err := unindexComment(tx, c.Token, c.CommentID)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("unindex comment %v %v: %v",
+					c.Token, c.CommentID, err)
+			}
+			if c.Censored {
+				continue
+			}
+			st = append(st, searchTerms(c.Token,
+				cache.SearchSourceComment, c.CommentID, c.Comment)...)
+		}
+		err = insertSearchTerms(tx, st)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Commit().Error
+		if err != nil {
+			return err
+		}
+
+		after = cs[len(cs)-1].Key
+	}
+}
+
+// unindexComment removes a comment from the search index.
+//
+// This function must be called using a transaction.
+func unindexComment(tx *gorm.DB, token, commentID string) error {
+	return tx.Where("token = ? AND source = ? AND comment_id = ?", token,
+		cache.SearchSourceComment, commentID).
+		Delete(SearchTerm{}).
+		Error
+}
+
+// IndexRecord replaces the search index entries of the passed in record.  The
+// record must be the most recent version of the record.
+//
+// This function satisfies the cache PluginIndexer interface.
+func (d *decred) IndexRecord(r cache.Record) error {
+	log.Tracef("decred IndexRecord: %v", r.CensorshipRecord.Token)
+
+	tx := d.recordsdb.Begin()
+	err := indexRecord(tx, r)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// searchAfter returns the most recent version of the vetted record that marks
+// the end of the previous search results page.
+func (d *decred) searchAfter(token string) (*Record, error) {
+	var r Record
+	err := d.recordsdb.
+		Where("token = ?", token).
+		Order("version desc").
+		Limit(1).
+		Find(&r).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, cache.ErrRecordNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !cache.IsVettedStatus(cache.RecordStatusT(r.Status)) {
+		return nil, cache.ErrRecordNotFound
+	}
+	return &r, nil
+}
+
+// searchResults returns the search results ordered by record timestamp in
+// descending order and then by token.  If after is not nil, only the results
+// that are ordered after it are returned.  At most limit results are returned
+// unless limit is zero.
+func searchResults(results map[string]*decredplugin.SearchResult, after *Record, limit int) []decredplugin.SearchResult {
+	sr := make([]decredplugin.SearchResult, 0, len(results))
+	for _, v := range results {
+		sort.Slice(v.CommentIDs, func(i, j int) bool {
+			a, _ := strconv.Atoi(v.CommentIDs[i])
+			b, _ := strconv.Atoi(v.CommentIDs[j])
+			return a < b
+		})
+		sr = append(sr, *v)
+	}
+	sort.Slice(sr, func(i, j int) bool {
+		if sr[i].Timestamp != sr[j].Timestamp {
+			return sr[i].Timestamp > sr[j].Timestamp
+		}
+		return sr[i].Token < sr[j].Token
+	})
+	if after != nil {
+		i := sort.Search(len(sr), func(i int) bool {
+			if sr[i].Timestamp != after.Timestamp {
+				return sr[i].Timestamp < after.Timestamp
+			}
+			return sr[i].Token > after.Token
+		})
+		sr = sr[i:]
+	}
+	if limit > 0 && len(sr) > limit {
+		sr = sr[:limit]
+	}
+	return sr
+}
+
+// cmdSearch returns the vetted records whose name, index file or non-censored
+// comments match the search query.  The candidates are looked up in the
+// search index and filtered by record status in the records query.  Only
+// phrases are matched against the text itself.
+func (d *decred) cmdSearch(payload string) (string, error) {
+	log.Tracef("decred cmdSearch")
+
+	s, err := decredplugin.DecodeSearch([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	q, err := cache.ParseSearchQuery(s.Query)
+	if err != nil {
+		return "", err
+	}
+	var after *Record
+	if s.After != "" {
+		after, err = d.searchAfter(s.After)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Lookup the names, index files and comments that contain all
+	// of the words of the query
+	words := q.Words()
+	query := `SELECT token, source, comment_id
+        FROM search_terms
+        WHERE term IN (?)
+        GROUP BY token, source, comment_id
+        HAVING COUNT(DISTINCT term) = ?`
+	rows, err := d.recordsdb.Raw(query, words, len(words)).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	matches := make([]SearchTerm, 0, 1024) // PNOOMA
+	tokens := make([]string, 0, 1024)      // PNOOMA
+	for rows.Next() {
+		var st SearchTerm
+		err := rows.Scan(&st.Token, &st.Source, &st.CommentID)
+		if err != nil {
+			return "", err
+		}
+		matches = append(matches, st)
+		tokens = append(tokens, st.Token)
+	}
+	err = rows.Err()
+	if err != nil {
+		return "", err
+	}
+
+	// Lookup the most recent version of the vetted records that
+	// matched and that have one of the requested statuses.  The
+	// index file is only needed to match phrases.
+	statuses := make([]int, 0, 2)
+	for _, v := range []pd.RecordStatusT{pd.RecordStatusPublic,
+		pd.RecordStatusArchived} {
+		if cache.SearchStatus(cache.RecordStatusT(v), s.Statuses) {
+			statuses = append(statuses, int(v))
+		}
+	}
+	records := make([]Record, 0, len(tokens))
+	if len(tokens) > 0 && len(statuses) > 0 {
+		query = `SELECT a.key
+            FROM records a
+            LEFT OUTER JOIN records b
+              ON a.token = b.token
+              AND a.version < b.version
+            WHERE b.token IS NULL
+              AND a.token IN (?)
+              AND a.status IN (?)`
+		rows, err := d.recordsdb.Raw(query, tokens, statuses).Rows()
+		if err != nil {
+			return "", err
+		}
+		defer rows.Close()
+
+		keys := make([]string, 0, len(tokens))
+		for rows.Next() {
+			var key string
+			err := rows.Scan(&key)
+			if err != nil {
+				return "", err
+			}
+			keys = append(keys, key)
+		}
+		err = rows.Err()
+		if err != nil {
+			return "", err
+		}
+
+		if len(keys) > 0 {
+			db := d.recordsdb
+			if q.HasPhrases() {
+				db = db.Preload("Files", "name = ?",
+					decredplugin.IndexFile)
+			}
+			err = db.Where(keys).Find(&records).Error
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	vetted := make(map[string]cache.Record, len(records))
+	for _, r := range records {
+		vetted[r.Token] = convertRecordToCache(r)
+	}
+
+	// Match the phrases of the comments
+	comments := make(map[string]Comment)
+	if q.HasPhrases() {
+		keys := make([]string, 0, len(matches))
+		for _, v := range matches {
+			if v.Source == cache.SearchSourceComment {
+				keys = append(keys, v.Token+v.CommentID)
+			}
+		}
+		if len(keys) > 0 {
+			cs := make([]Comment, 0, len(keys))
+			err = d.recordsdb.
+				Where("key IN (?)", keys).
+				Find(&cs).
+				Error
+			if err != nil {
+				return "", err
+			}
+			for _, c := range cs {
+				comments[c.Key] = c
+			}
+		}
+	}
+
+	results := make(map[string]*decredplugin.SearchResult, len(vetted))
+	for _, v := range matches {
+		r, ok := vetted[v.Token]
+		if !ok {
+			continue
+		}
+
+		var name, index bool
+		switch v.Source {
+		case cache.SearchSourceName, cache.SearchSourceIndex:
+			name = v.Source == cache.SearchSourceName
+			index = v.Source == cache.SearchSourceIndex
+			if q.HasPhrases() {
+				n, i, err := q.MatchFile(r, decredplugin.IndexFile)
+				if err != nil {
+					return "", fmt.Errorf("match %v: %v",
+						v.Token, err)
+				}
+				name = name && n
+				index = index && i
+			}
+			if !name && !index {
+				continue
+			}
+		case cache.SearchSourceComment:
+			if q.HasPhrases() {
+				c, ok := comments[v.Token+v.CommentID]
+				if !ok || c.Censored || !q.Match(c.Comment) {
+					continue
+				}
+			}
+		default:
+			continue
+		}
+
+		sr, ok := results[v.Token]
+		if !ok {
+			sr = &decredplugin.SearchResult{
+				Token:      v.Token,
+				Timestamp:  r.Timestamp,
+				CommentIDs: []string{},
+			}
+			results[v.Token] = sr
+		}
+		sr.Name = sr.Name || name
+		sr.Index = sr.Index || index
+		if v.Source == cache.SearchSourceComment {
+			sr.CommentIDs = append(sr.CommentIDs, v.CommentID)
+		}
+	}
+
+	srb, err := decredplugin.EncodeSearchReply(decredplugin.SearchReply{
+		Results: searchResults(results, after, s.Limit),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(srb), nil
+}
+
+// Exec executes a decred plugin command.  Plugin commands that write data to
+// the cache require both the command payload and the reply payload.  Plugin
+// commands that fetch data from the cache require only the command payload.
+// All commands return the appropriate reply payload.
+func (d *decred) Exec(cmd, cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred Exec: %v", cmd)
+
+	switch cmd {
+	case decredplugin.CmdAuthorizeVote:
+		return d.cmdAuthorizeVote(cmdPayload, replyPayload)
+	case decredplugin.CmdScheduleVote:
+		return d.cmdScheduleVote(cmdPayload, replyPayload)
+	case decredplugin.CmdScheduledVotes:
+		return d.cmdScheduledVotes(cmdPayload)
+	case decredplugin.CmdStartVote:
+		return d.cmdStartVote(cmdPayload, replyPayload)
+	case decredplugin.CmdStartVoteRunoff:
+		return d.cmdStartVoteRunoff(cmdPayload, replyPayload)
+	case decredplugin.CmdVoteDetails:
+		return d.cmdVoteDetails(cmdPayload)
+	case decredplugin.CmdVoteRunoffDetails:
+		return d.cmdVoteRunoffDetails(cmdPayload)
+	case decredplugin.CmdBallot:
+		return d.cmdNewBallot(cmdPayload, replyPayload)
+	case decredplugin.CmdBestBlock:
+		return "", nil
+	case decredplugin.CmdNewComment:
+		return d.cmdNewComment(cmdPayload, replyPayload)
+	case decredplugin.CmdLikeComment:
+		return d.cmdLikeComment(cmdPayload, replyPayload)
+	case decredplugin.CmdCensorComment:
+		return d.cmdCensorComment(cmdPayload, replyPayload)
+	case decredplugin.CmdGetComment:
+		return d.cmdGetComment(cmdPayload)
+	case decredplugin.CmdGetComments:
+		return d.cmdGetComments(cmdPayload)
+	case decredplugin.CmdGetNumComments:
+		return d.cmdGetNumComments(cmdPayload)
 	case decredplugin.CmdProposalVotes:
 		return d.cmdProposalVotes(cmdPayload)
 	case decredplugin.CmdCommentLikes:
@@ -1123,6 +1952,8 @@ func (d *decred) Exec(cmd, cmdPayload, replyPayload string) (string, error) {
 		return d.cmdTokenInventory(cmdPayload)
 	case decredplugin.CmdVoteSummary:
 		return d.cmdVoteSummary(cmdPayload)
+	case decredplugin.CmdSearch:
+		return d.cmdSearch(cmdPayload)
 	}
 
 	return "", cache.ErrInvalidPluginCmd
@@ -1185,6 +2016,24 @@ func (d *decred) createTables(tx *gorm.DB) error {
 			return err
 		}
 	}
+	if !tx.HasTable(tableVoteRunoffs) {
+		err := tx.CreateTable(&VoteRunoff{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if !tx.HasTable(tableVoteSchedules) {
+		err := tx.CreateTable(&VoteSchedule{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if !tx.HasTable(tableSearchTerms) {
+		err := tx.CreateTable(&SearchTerm{}).Error
+		if err != nil {
+			return err
+		}
+	}
 
 	// Check if a decred version record exists. Insert one
 	// if no version record is found.
@@ -1215,7 +2064,8 @@ func (d *decred) dropTables(tx *gorm.DB) error {
 	// Drop decred plugin tables
 	err := tx.DropTableIfExists(tableComments, tableCommentLikes,
 		tableCastVotes, tableAuthorizeVotes, tableVoteOptions,
-		tableStartVotes, tableVoteOptionResults, tableVoteResults).
+		tableStartVotes, tableVoteOptionResults, tableVoteResults,
+		tableVoteRunoffs, tableVoteSchedules, tableSearchTerms).
 		Error
 	if err != nil {
 		return err
@@ -1258,22 +2108,43 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 		return err
 	}
 
+	// Populate decred plugin tables
+	err = d.insertInventory(d.recordsdb, ir)
+	if err != nil {
+		return err
+	}
+
+	// Add the records to the search index. The comments were
+	// indexed when they were inserted.
+	log.Tracef("decred: building search index")
+	return indexRecords(d.recordsdb)
+}
+
+// insertInventory inserts the passed in decred plugin inventory into the
+// decred plugin tables.  This function has a database parameter so that it
+// can be called inside of a transaction when required.
+func (d *decred) insertInventory(db *gorm.DB, ir *decredplugin.InventoryReply) error {
 	// Build comments cache
 	log.Tracef("decred: building comments cache")
 	for _, v := range ir.Comments {
 		c := convertCommentFromDecred(v)
-		err := d.recordsdb.Create(&c).Error
+		err := db.Create(&c).Error
 		if err != nil {
 			log.Debugf("create comment failed on '%v'", c)
 			return fmt.Errorf("newComment: %v", err)
 		}
+		err = indexComment(db, c)
+		if err != nil {
+			return fmt.Errorf("index comment %v %v: %v", c.Token,
+				c.CommentID, err)
+		}
 	}
 
 	// Build like comments cache
 	log.Tracef("decred: building like comments cache")
 	for _, v := range ir.LikeComments {
 		lc := convertLikeCommentFromDecred(v)
-		err := d.recordsdb.Create(&lc).Error
+		err := db.Create(&lc).Error
 		if err != nil {
 			log.Debugf("newLikeComment failed on '%v'", lc)
 			return fmt.Errorf("newLikeComment: %v", err)
@@ -1304,7 +2175,7 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 		}
 
 		av := convertAuthorizeVoteFromDecred(v, r, rv)
-		err = d.newAuthorizeVote(d.recordsdb, av)
+		err = d.newAuthorizeVote(db, av)
 		if err != nil {
 			log.Debugf("newAuthorizeVote failed on '%v'", av)
 			return fmt.Errorf("newAuthorizeVote: %v", err)
@@ -1323,18 +2194,50 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 
 		sv := convertStartVoteFromDecred(v.StartVote,
 			v.StartVoteReply, endHeight)
-		err = d.newStartVote(d.recordsdb, sv)
+		err = d.newStartVote(db, sv)
 		if err != nil {
 			log.Debugf("newStartVote failed on '%v'", sv)
 			return fmt.Errorf("newStartVote: %v", err)
 		}
 	}
 
+	// Build vote runoff cache
+	log.Tracef("decred: building vote runoff cache")
+	for _, v := range ir.VoteRunoffs {
+		endHeight, err := strconv.ParseUint(v.EndHeight, 10, 64)
+		if err != nil {
+			log.Debugf("insert vote runoff failed on '%v'", v)
+			return fmt.Errorf("parse end height '%v': %v",
+				v.EndHeight, err)
+		}
+
+		vr := convertVoteRunoffFromDecred(v, endHeight)
+		err = db.Create(&vr).Error
+		if err != nil {
+			log.Debugf("insert vote runoff failed on '%v'", vr)
+			return fmt.Errorf("insert vote runoff: %v", err)
+		}
+	}
+
+	// Build vote schedule cache
+	log.Tracef("decred: building vote schedule cache")
+	for _, v := range ir.ScheduleVotes {
+		vs, err := convertVoteScheduleFromDecred(v)
+		if err != nil {
+			return err
+		}
+		err = db.Create(vs).Error
+		if err != nil {
+			log.Debugf("insert vote schedule failed on '%v'", vs)
+			return fmt.Errorf("insert vote schedule: %v", err)
+		}
+	}
+
 	// Build cast vote cache
 	log.Tracef("decred: building cast vote cache")
 	for _, v := range ir.CastVotes {
 		cv := convertCastVoteFromDecred(v)
-		err := d.recordsdb.Create(&cv).Error
+		err := db.Create(&cv).Error
 		if err != nil {
 			log.Debugf("insert cast vote failed on '%v'", cv)
 			return fmt.Errorf("insert cast vote: %v", err)
@@ -1375,6 +2278,368 @@ func (d *decred) Build(payload string) error {
 	return err
 }
 
+// decredSummary contains the decred plugin data of a record that is compared
+// to detect drift between the backend and the cache.
+type decredSummary struct {
+	comments         int               // Number of comments
+	censoredComments int               // Number of censored comments
+	likes            int               // Number of comment likes
+	authorizeVotes   map[string]string // [token+version]action
+	startVote        bool              // Vote has been started
+	voteRunoff       bool              // Runoff vote has been started
+	voteSchedule     string            // Vote schedule action and start height
+	castVotes        int               // Number of cast votes
+}
+
+// summarizeInventory returns the decred plugin data of every record in the
+// passed in inventory as it would be inserted into the cache.
+func summarizeInventory(ir *decredplugin.InventoryReply) map[string]*decredSummary {
+	summaries := make(map[string]*decredSummary)
+	summary := func(token string) *decredSummary {
+		s, ok := summaries[token]
+		if !ok {
+			s = &decredSummary{
+				authorizeVotes: make(map[string]string),
+			}
+			summaries[token] = s
+		}
+		return s
+	}
+
+	for _, v := range ir.Comments {
+		s := summary(v.Token)
+		s.comments++
+		if v.Censored {
+			s.censoredComments++
+		}
+	}
+	for _, v := range ir.LikeComments {
+		summary(v.Token).likes++
+	}
+	avr := make(map[string]decredplugin.AuthorizeVoteReply,
+		len(ir.AuthorizeVoteReplies)) // [receipt]AuthorizeVoteReply
+	for _, v := range ir.AuthorizeVoteReplies {
+		avr[v.Receipt] = v
+	}
+	for _, v := range ir.AuthorizeVotes {
+		// Authorize votes of the same version replace each other
+		r := avr[v.Receipt]
+		summary(v.Token).authorizeVotes[v.Token+r.RecordVersion] = v.Action
+	}
+	for _, v := range ir.StartVoteTuples {
+		summary(v.StartVote.Vote.Token).startVote = true
+	}
+	for _, v := range ir.VoteRunoffs {
+		summary(v.Token).voteRunoff = true
+	}
+	for _, v := range ir.ScheduleVotes {
+		summary(v.Token).voteSchedule = fmt.Sprintf("%v %v", v.Action,
+			v.StartHeight)
+	}
+	for _, v := range ir.CastVotes {
+		summary(v.Token).castVotes++
+	}
+
+	return summaries
+}
+
+// summarizeCache returns the decred plugin data of every record in the
+// cache.
+func (d *decred) summarizeCache() (map[string]*decredSummary, error) {
+	summaries := make(map[string]*decredSummary)
+	summary := func(token string) *decredSummary {
+		s, ok := summaries[token]
+		if !ok {
+			s = &decredSummary{
+				authorizeVotes: make(map[string]string),
+			}
+			summaries[token] = s
+		}
+		return s
+	}
+
+	comments, err := countByToken(d.recordsdb, tableComments)
+	if err != nil {
+		return nil, fmt.Errorf("count comments: %v", err)
+	}
+	for k, v := range comments {
+		summary(k).comments = v
+	}
+	censored, err := countByToken(d.recordsdb, tableComments,
+		"censored = ?", true)
+	if err != nil {
+		return nil, fmt.Errorf("count censored comments: %v", err)
+	}
+	for k, v := range censored {
+		summary(k).censoredComments = v
+	}
+	likes, err := countByToken(d.recordsdb, tableCommentLikes)
+	if err != nil {
+		return nil, fmt.Errorf("count comment likes: %v", err)
+	}
+	for k, v := range likes {
+		summary(k).likes = v
+	}
+	var avs []AuthorizeVote
+	err = d.recordsdb.
+		Select("key, token, action").
+		Find(&avs).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("authorize votes: %v", err)
+	}
+	for _, v := range avs {
+		summary(v.Token).authorizeVotes[v.Key] = v.Action
+	}
+	var svs []StartVote
+	err = d.recordsdb.
+		Select("token").
+		Find(&svs).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("start votes: %v", err)
+	}
+	for _, v := range svs {
+		summary(v.Token).startVote = true
+	}
+	var vrs []VoteRunoff
+	err = d.recordsdb.
+		Select("token").
+		Find(&vrs).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("vote runoffs: %v", err)
+	}
+	for _, v := range vrs {
+		summary(v.Token).voteRunoff = true
+	}
+	var vss []VoteSchedule
+	err = d.recordsdb.
+		Select("token, action, start_height").
+		Find(&vss).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("vote schedules: %v", err)
+	}
+	for _, v := range vss {
+		summary(v.Token).voteSchedule = fmt.Sprintf("%v %v", v.Action,
+			v.StartHeight)
+	}
+	castVotes, err := countByToken(d.recordsdb, tableCastVotes)
+	if err != nil {
+		return nil, fmt.Errorf("count cast votes: %v", err)
+	}
+	for k, v := range castVotes {
+		summary(k).castVotes = v
+	}
+
+	return summaries, nil
+}
+
+// compareSummaries returns the differences between the decred plugin data
+// of a record in the backend, b, and in the cache, c.
+func compareSummaries(b, c *decredSummary) []string {
+	var reasons []string
+	if b.comments != c.comments {
+		reasons = append(reasons, fmt.Sprintf("comments: backend %v "+
+			"cache %v", b.comments, c.comments))
+	}
+	if b.censoredComments != c.censoredComments {
+		reasons = append(reasons, fmt.Sprintf("censored comments: "+
+			"backend %v cache %v", b.censoredComments,
+			c.censoredComments))
+	}
+	if b.likes != c.likes {
+		reasons = append(reasons, fmt.Sprintf("comment likes: backend "+
+			"%v cache %v", b.likes, c.likes))
+	}
+	if !reflect.DeepEqual(b.authorizeVotes, c.authorizeVotes) {
+		reasons = append(reasons, fmt.Sprintf("authorize votes: backend "+
+			"%v cache %v", b.authorizeVotes, c.authorizeVotes))
+	}
+	if b.startVote != c.startVote {
+		reasons = append(reasons, fmt.Sprintf("vote started: backend "+
+			"%v cache %v", b.startVote, c.startVote))
+	}
+	if b.voteRunoff != c.voteRunoff {
+		reasons = append(reasons, fmt.Sprintf("runoff vote started: "+
+			"backend %v cache %v", b.voteRunoff, c.voteRunoff))
+	}
+	if b.voteSchedule != c.voteSchedule {
+		reasons = append(reasons, fmt.Sprintf("vote schedule: backend "+
+			"%q cache %q", b.voteSchedule, c.voteSchedule))
+	}
+	if b.castVotes != c.castVotes {
+		reasons = append(reasons, fmt.Sprintf("cast votes: backend %v "+
+			"cache %v", b.castVotes, c.castVotes))
+	}
+	return reasons
+}
+
+// Drift compares the comments, comment likes, vote authorizations, started
+// votes, runoff votes, vote schedules and cast votes of every record in the
+// passed in inventory payload with the cache.  The drift is returned ordered
+// by token.
+//
+// This function satisfies the cache PluginReconciler interface.
+func (d *decred) Drift(payload string) ([]cache.Drift, error) {
+	log.Tracef("decred Drift")
+
+	ir, err := decredplugin.DecodeInventoryReply([]byte(payload))
+	if err != nil {
+		return nil, fmt.Errorf("DecodeInventoryReply: %v", err)
+	}
+
+	backend := summarizeInventory(ir)
+	cached, err := d.summarizeCache()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]string, 0, len(backend)+len(cached))
+	for k := range backend {
+		tokens = append(tokens, k)
+	}
+	for k := range cached {
+		if _, ok := backend[k]; !ok {
+			tokens = append(tokens, k)
+		}
+	}
+	sort.Strings(tokens)
+
+	empty := &decredSummary{
+		authorizeVotes: make(map[string]string),
+	}
+	var drift []cache.Drift
+	for _, token := range tokens {
+		b, ok := backend[token]
+		if !ok {
+			b = empty
+		}
+		c, ok := cached[token]
+		if !ok {
+			c = empty
+		}
+		for _, v := range compareSummaries(b, c) {
+			drift = append(drift, cache.Drift{
+				Token:  token,
+				Reason: v,
+			})
+		}
+	}
+
+	return drift, nil
+}
+
+// deleteRecordData deletes all decred plugin data of a record.
+//
+// This function must be called using a transaction.
+func deleteRecordData(tx *gorm.DB, token string) error {
+	models := []interface{}{
+		Comment{},
+		LikeComment{},
+		AuthorizeVote{},
+		CastVote{},
+		VoteOptionResult{},
+		VoteResults{},
+		VoteOption{},
+		StartVote{},
+		VoteRunoff{},
+		VoteSchedule{},
+	}
+	for _, v := range models {
+		err := tx.Where("token = ?", token).
+			Delete(v).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// The search index entries of the record content are maintained
+	// by IndexRecord and are kept.
+	return tx.Where("token = ? AND source = ?", token,
+		cache.SearchSourceComment).
+		Delete(SearchTerm{}).
+		Error
+}
+
+// Reconcile replaces the decred plugin data of the passed in records with
+// their data from the inventory payload.  Each record is replaced inside of
+// a transaction.  Vote results are recalculated when they are requested.
+//
+// This function satisfies the cache PluginReconciler interface.
+func (d *decred) Reconcile(tokens []string, payload string) error {
+	log.Tracef("decred Reconcile: %v", len(tokens))
+
+	ir, err := decredplugin.DecodeInventoryReply([]byte(payload))
+	if err != nil {
+		return fmt.Errorf("DecodeInventoryReply: %v", err)
+	}
+
+	for _, token := range tokens {
+		// Select the inventory of the record.  Authorize vote
+		// replies are looked up by receipt so all of them are
+		// kept.
+		rir := decredplugin.InventoryReply{
+			AuthorizeVoteReplies: ir.AuthorizeVoteReplies,
+		}
+		for _, v := range ir.Comments {
+			if v.Token == token {
+				rir.Comments = append(rir.Comments, v)
+			}
+		}
+		for _, v := range ir.LikeComments {
+			if v.Token == token {
+				rir.LikeComments = append(rir.LikeComments, v)
+			}
+		}
+		for _, v := range ir.AuthorizeVotes {
+			if v.Token == token {
+				rir.AuthorizeVotes = append(rir.AuthorizeVotes, v)
+			}
+		}
+		for _, v := range ir.StartVoteTuples {
+			if v.StartVote.Vote.Token == token {
+				rir.StartVoteTuples = append(rir.StartVoteTuples, v)
+			}
+		}
+		for _, v := range ir.CastVotes {
+			if v.Token == token {
+				rir.CastVotes = append(rir.CastVotes, v)
+			}
+		}
+		for _, v := range ir.VoteRunoffs {
+			if v.Token == token {
+				rir.VoteRunoffs = append(rir.VoteRunoffs, v)
+			}
+		}
+		for _, v := range ir.ScheduleVotes {
+			if v.Token == token {
+				rir.ScheduleVotes = append(rir.ScheduleVotes, v)
+			}
+		}
+
+		tx := d.recordsdb.Begin()
+		err := deleteRecordData(tx, token)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("delete %v: %v", token, err)
+		}
+		err = d.insertInventory(tx, &rir)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("insert %v: %v", token, err)
+		}
+		err = tx.Commit().Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Setup creates the decred plugin tables if they do not already exist.  A
 // decred plugin version record is inserted into the database during table
 // creation.
@@ -1423,6 +2688,20 @@ func (d *decred) CheckVersion() error {
 	return err
 }
 
+// Version returns the version of the decred plugin cache implementation.
+//
+// This function satisfies the MigrationDriver interface.
+func (d *decred) Version() string {
+	return d.version
+}
+
+// Migrations returns the migrations of the decred plugin tables.
+//
+// This function satisfies the MigrationDriver interface.
+func (d *decred) Migrations() []Migration {
+	return decredMigrations
+}
+
 // newDecredPlugin returns a cache decred plugin context.
 func newDecredPlugin(db *gorm.DB, p cache.Plugin) *decred {
 	log.Tracef("newDecredPlugin")
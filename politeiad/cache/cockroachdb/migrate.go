@@ -0,0 +1,301 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cockroachdb
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/decred/politeia/politeiad/cache"
+	"github.com/jinzhu/gorm"
+)
+
+// Migration is a schema migration of a cache component.  Migrations run after
+// Setup, which already creates the tables that are new in a version, so a
+// migration only has to change the existing tables and their data.  Migrate
+// runs inside of a transaction and must therefore stay within the cockroachdb
+// transaction size limit.
+//
+// Batched is used to populate tables that would exceed that limit.  It runs
+// outside of the migration transaction, commits its own batches and must be
+// safe to run again if it is interrupted.  The version is only updated once
+// it has completed.  Batched is skipped on a dry run.
+type Migration struct {
+	From        string               // Version before the migration
+	To          string               // Version after the migration
+	Description string               // Description of the schema change
+	Migrate     func(*gorm.DB) error // Apply the migration to a transaction
+	Batched     func(*gorm.DB) error // Apply the migration in batches
+}
+
+// MigrationDriver is implemented by the plugin drivers that support in place
+// schema migrations.  Plugins that do not implement it must be rebuilt when
+// their version changes.
+type MigrationDriver interface {
+	// Version returns the version of the plugin cache implementation
+	Version() string
+
+	// Migrations returns the migrations of the plugin tables
+	Migrations() []Migration
+}
+
+// recordsMigrations contains the migrations of the records tables.  The last
+// migration must migrate to cacheVersion.
+var recordsMigrations = []Migration{}
+
+// migrationPath returns the ordered migrations that migrate a component from
+// version from to version to.  It returns false if no such path exists.
+func migrationPath(migrations []Migration, from, to string) ([]Migration, bool) {
+	var path []Migration
+	version := from
+	for version != to {
+		var found bool
+		for _, m := range migrations {
+			if m.From == version {
+				path = append(path, m)
+				version = m.To
+				found = true
+				break
+			}
+		}
+		// A path that is longer than the number of migrations
+		// contains a cycle.
+		if !found || len(path) > len(migrations) {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// component is a part of the cache that is versioned independently.
+type component struct {
+	id         string       // Version record ID
+	latest     string       // Version of the implementation
+	migrations []Migration  // Migrations of the component tables
+	check      func() error // Version check of plugins without migrations
+}
+
+// components returns the records cache followed by the registered plugins
+// ordered by plugin ID.
+//
+// This function must be called with the lock held.
+func (c *cockroachdb) components() []component {
+	components := []component{{
+		id:         cacheID,
+		latest:     cacheVersion,
+		migrations: recordsMigrations,
+	}}
+
+	ids := make([]string, 0, len(c.plugins))
+	for id := range c.plugins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		pd := c.plugins[id]
+		md, ok := pd.(MigrationDriver)
+		if !ok {
+			components = append(components, component{
+				id:    id,
+				check: pd.CheckVersion,
+			})
+			continue
+		}
+		components = append(components, component{
+			id:         id,
+			latest:     md.Version(),
+			migrations: md.Migrations(),
+		})
+	}
+
+	return components
+}
+
+// componentStatus returns the schema status of a component whose tables are
+// at the provided version and the ordered migrations that bring it up to
+// date.  An empty version means that the component has not been built.
+func componentStatus(comp component, version string) (*cache.MigrationStatus, []Migration, error) {
+	ms := cache.MigrationStatus{
+		ID:      comp.id,
+		Version: version,
+		Latest:  comp.latest,
+	}
+	if version == "" {
+		ms.Rebuild = true
+		return &ms, nil, nil
+	}
+
+	// Plugins without migrations can only be checked
+	if comp.check != nil {
+		err := comp.check()
+		switch err {
+		case nil:
+			ms.Latest = version
+		case cache.ErrNoVersionRecord, cache.ErrWrongVersion:
+			ms.Rebuild = true
+		default:
+			return nil, nil, err
+		}
+		return &ms, nil, nil
+	}
+
+	path, ok := migrationPath(comp.migrations, version, comp.latest)
+	if !ok {
+		ms.Rebuild = true
+		return &ms, nil, nil
+	}
+	for _, m := range path {
+		ms.Pending = append(ms.Pending, cache.Migration{
+			From:        m.From,
+			To:          m.To,
+			Description: m.Description,
+		})
+	}
+
+	return &ms, path, nil
+}
+
+// migrationStatus looks up the version of a component and returns its schema
+// status and the ordered migrations that bring it up to date.
+func (c *cockroachdb) migrationStatus(comp component) (*cache.MigrationStatus, []Migration, error) {
+	if !c.recordsdb.HasTable(tableVersions) {
+		return componentStatus(comp, "")
+	}
+	var v Version
+	err := c.recordsdb.
+		Where("id = ?", comp.id).
+		Find(&v).
+		Error
+	if err == gorm.ErrRecordNotFound {
+		return componentStatus(comp, "")
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return componentStatus(comp, v.Version)
+}
+
+// migrate applies the migrations of a component inside of a single
+// transaction.  The version record is updated along with every migration.
+// The transaction is committed before a batched migration runs and a new one
+// is started after it.  The transaction is rolled back on a dry run.
+//
+// This function must be called with the lock held.
+func (c *cockroachdb) migrate(id string, path []Migration, dryRun bool) error {
+	tx := c.recordsdb.Begin()
+	for _, m := range path {
+		log.Infof("Migrating cache %v from version %v to %v: %v",
+			id, m.From, m.To, m.Description)
+
+		if m.Migrate != nil {
+			err := m.Migrate(tx)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migrate %v to %v: %v", m.From, m.To,
+					err)
+			}
+		}
+
+		if m.Batched != nil && dryRun {
+			log.Infof("Dry run, skipping batched migration of cache "+
+				"%v to %v", id, m.To)
+		} else if m.Batched != nil {
+			// The batches must see the prior migrations
+			err := tx.Commit().Error
+			if err != nil {
+				return fmt.Errorf("commit %v: %v", m.From, err)
+			}
+			err = m.Batched(c.recordsdb)
+			if err != nil {
+				return fmt.Errorf("migrate %v to %v: %v", m.From, m.To,
+					err)
+			}
+			tx = c.recordsdb.Begin()
+		}
+
+		err := tx.Model(&Version{ID: id}).
+			Updates(map[string]interface{}{
+				"version":   m.To,
+				"timestamp": time.Now().Unix(),
+			}).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("update version %v: %v", m.To, err)
+		}
+	}
+
+	if dryRun {
+		log.Infof("Dry run, rolling back cache %v migrations", id)
+		return tx.Rollback().Error
+	}
+
+	return tx.Commit().Error
+}
+
+// MigrationStatus returns the schema status of the records cache and of all
+// registered plugins.
+//
+// This function satisfies the cache Migrator interface.
+func (c *cockroachdb) MigrationStatus() ([]cache.MigrationStatus, error) {
+	log.Tracef("MigrationStatus")
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	components := c.components()
+	statuses := make([]cache.MigrationStatus, 0, len(components))
+	for _, v := range components {
+		ms, _, err := c.migrationStatus(v)
+		if err != nil {
+			return nil, fmt.Errorf("status %v: %v", v.id, err)
+		}
+		statuses = append(statuses, *ms)
+	}
+
+	return statuses, nil
+}
+
+// Migrate applies the pending migrations of the records cache and of all
+// registered plugins.  Components that do not have a migration path are
+// skipped and must be rebuilt.  The status prior to the migration is
+// returned.
+//
+// This function satisfies the cache Migrator interface.
+func (c *cockroachdb) Migrate(dryRun bool) ([]cache.MigrationStatus, error) {
+	log.Tracef("Migrate: %v", dryRun)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	components := c.components()
+	statuses := make([]cache.MigrationStatus, 0, len(components))
+	for _, v := range components {
+		ms, path, err := c.migrationStatus(v)
+		if err != nil {
+			return nil, fmt.Errorf("status %v: %v", v.id, err)
+		}
+		statuses = append(statuses, *ms)
+
+		if len(path) == 0 {
+			continue
+		}
+		err = c.migrate(v.id, path, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", v.id, err)
+		}
+	}
+
+	return statuses, nil
+}
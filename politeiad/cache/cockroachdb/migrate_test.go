@@ -0,0 +1,271 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cockroachdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/politeiad/cache"
+)
+
+// testMigrations returns migrations without a migrate function that only
+// describe the version change.
+func testMigrations(versions ...[2]string) []Migration {
+	migrations := make([]Migration, 0, len(versions))
+	for _, v := range versions {
+		migrations = append(migrations, Migration{
+			From:        v[0],
+			To:          v[1],
+			Description: v[0] + " to " + v[1],
+		})
+	}
+	return migrations
+}
+
+// migrationVersions returns the versions that a migration path passes
+// through, starting with the version of the first migration.
+func migrationVersions(path []Migration) []string {
+	versions := make([]string, 0, len(path)+1)
+	for i, m := range path {
+		if i == 0 {
+			versions = append(versions, m.From)
+		}
+		versions = append(versions, m.To)
+	}
+	return versions
+}
+
+func TestMigrationPath(t *testing.T) {
+	var tests = []struct {
+		name       string
+		migrations []Migration
+		from       string
+		to         string
+		want       []string // Versions of the path
+		wantOK     bool
+	}{
+		{"up to date",
+			testMigrations([2]string{"1", "2"}),
+			"2", "2",
+			[]string{},
+			true},
+
+		{"no migrations",
+			testMigrations(),
+			"1", "2",
+			nil,
+			false},
+
+		{"no path",
+			testMigrations([2]string{"2", "3"}),
+			"1", "3",
+			nil,
+			false},
+
+		{"partial path",
+			testMigrations([2]string{"1", "2"}, [2]string{"2", "3"}),
+			"1", "4",
+			nil,
+			false},
+
+		{"cycle",
+			testMigrations([2]string{"1", "2"}, [2]string{"2", "1"}),
+			"1", "3",
+			nil,
+			false},
+
+		{"single migration",
+			testMigrations([2]string{"1", "2"}, [2]string{"2", "3"}),
+			"2", "3",
+			[]string{"2", "3"},
+			true},
+
+		{"unordered migrations",
+			testMigrations([2]string{"3", "4"}, [2]string{"1", "2"},
+				[2]string{"2", "3"}),
+			"1", "4",
+			[]string{"1", "2", "3", "4"},
+			true},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			path, ok := migrationPath(v.migrations, v.from, v.to)
+			if ok != v.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, v.wantOK)
+			}
+			if !ok {
+				if path != nil {
+					t.Fatalf("got path %v, want nil", path)
+				}
+				return
+			}
+			got := migrationVersions(path)
+			if !reflect.DeepEqual(got, v.want) {
+				t.Fatalf("got path %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestComponentStatus(t *testing.T) {
+	errCheck := errors.New("check failed")
+	migrations := testMigrations([2]string{"1", "2"}, [2]string{"2", "3"})
+
+	var tests = []struct {
+		name        string
+		comp        component
+		version     string
+		want        cache.MigrationStatus
+		wantPath    []string // Versions of the migration path
+		wantErr     error
+		wantChecked bool
+	}{
+		{"not built",
+			component{id: "records", latest: "3",
+				migrations: migrations},
+			"",
+			cache.MigrationStatus{ID: "records", Latest: "3",
+				Rebuild: true},
+			[]string{},
+			nil,
+			false},
+
+		{"up to date",
+			component{id: "records", latest: "3",
+				migrations: migrations},
+			"3",
+			cache.MigrationStatus{ID: "records", Version: "3",
+				Latest: "3"},
+			[]string{},
+			nil,
+			false},
+
+		{"partial migration",
+			component{id: "records", latest: "3",
+				migrations: migrations},
+			"2",
+			cache.MigrationStatus{ID: "records", Version: "2",
+				Latest: "3", Pending: []cache.Migration{{
+					From:        "2",
+					To:          "3",
+					Description: "2 to 3",
+				}}},
+			[]string{"2", "3"},
+			nil,
+			false},
+
+		{"full migration",
+			component{id: "records", latest: "3",
+				migrations: migrations},
+			"1",
+			cache.MigrationStatus{ID: "records", Version: "1",
+				Latest: "3", Pending: []cache.Migration{{
+					From:        "1",
+					To:          "2",
+					Description: "1 to 2",
+				}, {
+					From:        "2",
+					To:          "3",
+					Description: "2 to 3",
+				}}},
+			[]string{"1", "2", "3"},
+			nil,
+			false},
+
+		{"no path",
+			component{id: "records", latest: "3",
+				migrations: migrations},
+			"0",
+			cache.MigrationStatus{ID: "records", Version: "0",
+				Latest: "3", Rebuild: true},
+			[]string{},
+			nil,
+			false},
+
+		{"plugin up to date",
+			component{id: "plugin",
+				check: func() error { return nil }},
+			"1",
+			cache.MigrationStatus{ID: "plugin", Version: "1",
+				Latest: "1"},
+			[]string{},
+			nil,
+			true},
+
+		{"plugin wrong version",
+			component{id: "plugin",
+				check: func() error { return cache.ErrWrongVersion }},
+			"1",
+			cache.MigrationStatus{ID: "plugin", Version: "1",
+				Rebuild: true},
+			[]string{},
+			nil,
+			true},
+
+		{"plugin check error",
+			component{id: "plugin",
+				check: func() error { return errCheck }},
+			"1",
+			cache.MigrationStatus{},
+			[]string{},
+			errCheck,
+			true},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			// Wrap the version check to verify that it is used
+			var checked bool
+			comp := v.comp
+			if comp.check != nil {
+				check := comp.check
+				comp.check = func() error {
+					checked = true
+					return check()
+				}
+			}
+
+			ms, path, err := componentStatus(comp, v.version)
+			if err != v.wantErr {
+				t.Fatalf("got error %v, want %v", err, v.wantErr)
+			}
+			if checked != v.wantChecked {
+				t.Fatalf("got checked %v, want %v", checked,
+					v.wantChecked)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(*ms, v.want) {
+				t.Fatalf("got status %+v, want %+v", *ms, v.want)
+			}
+			got := migrationVersions(path)
+			if !reflect.DeepEqual(got, v.wantPath) {
+				t.Fatalf("got path %v, want %v", got, v.wantPath)
+			}
+		})
+	}
+}
+
+func TestDecredMigrations(t *testing.T) {
+	// Every decred plugin version that has a migration must be able
+	// to migrate to the current version.
+	for _, m := range decredMigrations {
+		path, ok := migrationPath(decredMigrations, m.From, decredVersion)
+		if !ok {
+			t.Fatalf("no migration path from %v to %v", m.From,
+				decredVersion)
+		}
+		for _, v := range path {
+			if v.Migrate == nil && v.Batched == nil {
+				t.Fatalf("migration %v to %v does nothing", v.From,
+					v.To)
+			}
+		}
+	}
+}
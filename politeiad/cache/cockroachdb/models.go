@@ -146,6 +146,7 @@ func (VoteOption) TableName() string {
 type StartVote struct {
 	Token               string       `gorm:"primary_key;size:64"` // Censorship token
 	Version             uint64       `gorm:"not null"`            // Version of files
+	Type                int          `gorm:"not null"`            // Vote type
 	Mask                uint64       `gorm:"not null"`            // Valid votebits
 	Duration            uint32       `gorm:"not null"`            // Duration in blocks
 	QuorumPercentage    uint32       `gorm:"not null"`            // Percent of eligible votes required for quorum
@@ -165,6 +166,63 @@ func (StartVote) TableName() string {
 	return tableStartVotes
 }
 
+// VoteRunoff records a runoff vote between the submissions of an RFP.  The
+// submission votes are recorded as regular StartVote records that share the
+// snapshot of the runoff.
+//
+// This is a decred plugin model.
+type VoteRunoff struct {
+	Token            string `gorm:"primary_key;size:64"` // RFP censorship token
+	Tokens           string `gorm:"not null"`            // Submission censorship tokens
+	StartBlockHeight string `gorm:"not null"`            // Block height
+	StartBlockHash   string `gorm:"not null"`            // Block hash
+	EndHeight        uint64 `gorm:"not null"`            // Height of vote end
+}
+
+// TableName returns the name of the VoteRunoff database table.
+func (VoteRunoff) TableName() string {
+	return tableVoteRunoffs
+}
+
+// VoteSchedule records the scheduled start of a proposal vote.  Only the most
+// recent schedule of a proposal is recorded.  A cancelled schedule keeps its
+// start height and start vote.
+//
+// This is a decred plugin model.
+type VoteSchedule struct {
+	Token       string `gorm:"primary_key;size:64"` // Censorship token
+	Action      string `gorm:"not null"`            // Schedule or cancel
+	StartHeight uint32 `gorm:"not null"`            // Block height of vote start
+	StartVote   string `gorm:"not null"`            // JSON encoded start vote
+	Signature   string `gorm:"not null;size:128"`   // Signature of token+startheight+action
+	PublicKey   string `gorm:"not null;size:64"`    // Pubkey used for signature
+	Receipt     string `gorm:"not null;size:128"`   // Server signature of client signature
+	Timestamp   int64  `gorm:"not null"`            // Received UNIX timestamp
+}
+
+// TableName returns the name of the VoteSchedule database table.
+func (VoteSchedule) TableName() string {
+	return tableVoteSchedules
+}
+
+// SearchTerm is an entry of the search index.  It records that a word appears
+// in the name or the index file of the most recent version of a record or in
+// a non-censored comment.
+//
+// This is a decred plugin model.
+type SearchTerm struct {
+	Key       uint   `gorm:"primary_key"`            // Primary key
+	Term      string `gorm:"not null;index"`         // Lower case word
+	Token     string `gorm:"not null;size:64;index"` // Censorship token
+	Source    string `gorm:"not null"`               // Name, index or comment
+	CommentID string `gorm:"not null"`               // Comment ID, comments only
+}
+
+// TableName returns the name of the SearchTerm database table.
+func (SearchTerm) TableName() string {
+	return tableSearchTerms
+}
+
 // CastVote records a signed vote.
 //
 // This is a decred plugin model.
@@ -0,0 +1,251 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cockroachdb
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/decred/politeia/politeiad/cache"
+	"github.com/jinzhu/gorm"
+)
+
+// tokenCount is the number of rows of a table that belong to a record.
+type tokenCount struct {
+	Token string
+	Count int
+}
+
+// countByToken returns the number of rows per token of the provided table.
+// The query is narrowed down by the optional where clause.
+func countByToken(db *gorm.DB, table string, where ...interface{}) (map[string]int, error) {
+	q := db.Table(table).
+		Select("token, count(*) as count").
+		Group("token")
+	if len(where) > 0 {
+		q = q.Where(where[0], where[1:]...)
+	}
+	var counts []tokenCount
+	err := q.Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]int, len(counts))
+	for _, v := range counts {
+		m[v.Token] = v.Count
+	}
+	return m, nil
+}
+
+// RecordSummaries returns the summaries of all versions of all records in
+// the cache.
+//
+// This function satisfies the cache Reconciler interface.
+func (c *cockroachdb) RecordSummaries() ([]cache.RecordSummary, error) {
+	log.Tracef("RecordSummaries")
+
+	c.RLock()
+	shutdown := c.shutdown
+	c.RUnlock()
+
+	if shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	// The metadata streams are loaded so that metadata only updates,
+	// which do not change the merkle root, are detected as well.
+	var records []Record
+	err := c.recordsdb.
+		Select("key, token, version, status, merkle").
+		Preload("Metadata").
+		Find(&records).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]cache.RecordSummary, 0, len(records))
+	for _, v := range records {
+		md := make([]cache.MetadataStream, 0, len(v.Metadata))
+		for _, m := range v.Metadata {
+			md = append(md, cache.MetadataStream{
+				ID:      m.ID,
+				Payload: m.Payload,
+			})
+		}
+		summaries = append(summaries, cache.RecordSummary{
+			Token:    v.Token,
+			Version:  strconv.FormatUint(v.Version, 10),
+			Status:   cache.RecordStatusT(v.Status),
+			Merkle:   v.Merkle,
+			Metadata: cache.MetadataDigest(md),
+		})
+	}
+
+	return summaries, nil
+}
+
+// deleteRecord deletes all versions of a record along with their metadata
+// streams and files.
+//
+// This function must be called using a transaction.
+func deleteRecord(tx *gorm.DB, token string) error {
+	var records []Record
+	err := tx.Select("key").
+		Where("token = ?", token).
+		Find(&records).
+		Error
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(records))
+	for _, v := range records {
+		keys = append(keys, v.Key)
+	}
+	err = tx.Where("record_key IN (?)", keys).
+		Delete(MetadataStream{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("delete MD streams: %v", err)
+	}
+	err = tx.Where("record_key IN (?)", keys).
+		Delete(File{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("delete files: %v", err)
+	}
+
+	return tx.Where("token = ?", token).
+		Delete(Record{}).
+		Error
+}
+
+// ReplaceRecord replaces all versions of a record with the passed in
+// versions.  The record is removed from the cache when no versions are
+// provided.
+//
+// This function satisfies the cache Reconciler interface.
+func (c *cockroachdb) ReplaceRecord(token string, versions []cache.Record) error {
+	log.Tracef("ReplaceRecord: %v", token)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return cache.ErrShutdown
+	}
+
+	// The record without any versions removes the record from the
+	// plugin indexes.
+	latest := cache.Record{
+		CensorshipRecord: cache.CensorshipRecord{
+			Token: token,
+		},
+	}
+	var latestVersion uint64
+	records := make([]Record, 0, len(versions))
+	for _, cr := range versions {
+		if cr.CensorshipRecord.Token != token {
+			return fmt.Errorf("unexpected token %v",
+				cr.CensorshipRecord.Token)
+		}
+		v, err := strconv.ParseUint(cr.Version, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse version '%v' failed: %v",
+				cr.Version, err)
+		}
+		records = append(records, convertRecordFromCache(cr, v))
+		if v >= latestVersion {
+			latest = cr
+			latestVersion = v
+		}
+	}
+
+	tx := c.recordsdb.Begin()
+	err := deleteRecord(tx, token)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete record: %v", err)
+	}
+	for _, r := range records {
+		err := tx.Create(&r).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("create record %v: %v", r.Key, err)
+		}
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	return c.indexRecord(latest)
+}
+
+// pluginReconciler returns the reconciler of a registered plugin.
+func (c *cockroachdb) pluginReconciler(id string) (cache.PluginReconciler, error) {
+	plugin, err := c.getPlugin(id)
+	if err != nil {
+		return nil, err
+	}
+	pr, ok := plugin.(cache.PluginReconciler)
+	if !ok {
+		return nil, cache.ErrInvalidPlugin
+	}
+	return pr, nil
+}
+
+// PluginDrift compares the passed in plugin inventory of the backend with the
+// cache of the plugin.  It returns ErrInvalidPlugin if the plugin can not be
+// reconciled.
+//
+// This function satisfies the cache Reconciler interface.
+func (c *cockroachdb) PluginDrift(id, payload string) ([]cache.Drift, error) {
+	log.Tracef("PluginDrift: %v", id)
+
+	c.RLock()
+	shutdown := c.shutdown
+	c.RUnlock()
+
+	if shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	pr, err := c.pluginReconciler(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return pr.Drift(payload)
+}
+
+// PluginReconcile rebuilds the plugin data of the passed in records using the
+// plugin inventory of the backend.  It returns ErrInvalidPlugin if the plugin
+// can not be reconciled.
+//
+// This function satisfies the cache Reconciler interface.
+func (c *cockroachdb) PluginReconcile(id string, tokens []string, payload string) error {
+	log.Tracef("PluginReconcile: %v %v", id, len(tokens))
+
+	c.RLock()
+	shutdown := c.shutdown
+	c.RUnlock()
+
+	if shutdown {
+		return cache.ErrShutdown
+	}
+
+	pr, err := c.pluginReconciler(id)
+	if err != nil {
+		return err
+	}
+
+	return pr.Reconcile(tokens, payload)
+}
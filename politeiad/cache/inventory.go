@@ -0,0 +1,201 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InventoryStateT selects vetted and/or unvetted records in an inventory
+// request.
+type InventoryStateT int
+
+const (
+	// Inventory states
+	InventoryStateAll      InventoryStateT = 0 // Vetted and unvetted records
+	InventoryStateVetted   InventoryStateT = 1 // Public and archived records
+	InventoryStateUnvetted InventoryStateT = 2 // Unreviewed and censored records
+
+	// InventoryPageSize is the maximum number of records that are returned
+	// by a single InventoryPage call.
+	InventoryPageSize = 100
+)
+
+var (
+	// ErrInvalidCursor is emitted when an inventory cursor can not be
+	// decoded.
+	ErrInvalidCursor = errors.New("invalid inventory cursor")
+)
+
+// InventoryFilter selects a page of records.  Records are ordered by the most
+// recent timestamp first; records with the same timestamp are ordered by
+// token.  The cursor is returned by the previous page and is opaque to the
+// caller.  Reverse pages from the cursor towards more recent records, which
+// are then returned oldest first.
+type InventoryFilter struct {
+	State          InventoryStateT // Vetted and/or unvetted records
+	Statuses       []RecordStatusT // Only these statuses, all if empty
+	FromTimestamp  int64           // Only records updated at or after, 0 if unbounded
+	UntilTimestamp int64           // Only records updated before, 0 if unbounded
+	MDStreams      []uint64        // Metadata streams that must be present
+	IncludeFiles   bool            // Include record files
+	Cursor         string          // Cursor of the previous page
+	Limit          uint            // Page size, capped at InventoryPageSize
+	Reverse        bool            // Page towards more recent records
+}
+
+// InventoryCursor returns the cursor that points at the provided record.
+// The next page starts with the record that follows it.
+func InventoryCursor(r Record) string {
+	return strconv.FormatInt(r.Timestamp, 10) + ":" +
+		r.CensorshipRecord.Token
+}
+
+// ParseInventoryCursor decodes an inventory cursor into the timestamp and
+// token of the record it points at.
+func ParseInventoryCursor(cursor string) (int64, string, error) {
+	s := strings.SplitN(cursor, ":", 2)
+	if len(s) != 2 || s[1] == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	timestamp, err := strconv.ParseInt(s[0], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return timestamp, s[1], nil
+}
+
+// inventoryLess returns true if the record with timestamp ta and token a is
+// ordered before the record with timestamp tb and token b.
+func inventoryLess(ta int64, a string, tb int64, b string) bool {
+	if ta != tb {
+		return ta > tb
+	}
+	return a < b
+}
+
+// IsVettedStatus returns true if the status belongs to a vetted record.
+func IsVettedStatus(s RecordStatusT) bool {
+	return s == RecordStatusPublic || s == RecordStatusArchived
+}
+
+// matchInventoryFilter returns true if the record is selected by the filter.
+// The cursor is not taken into account.
+func matchInventoryFilter(r Record, f InventoryFilter) bool {
+	switch f.State {
+	case InventoryStateVetted:
+		if !IsVettedStatus(r.Status) {
+			return false
+		}
+	case InventoryStateUnvetted:
+		if IsVettedStatus(r.Status) {
+			return false
+		}
+	}
+
+	if len(f.Statuses) > 0 {
+		var found bool
+		for _, v := range f.Statuses {
+			if v == r.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.FromTimestamp != 0 && r.Timestamp < f.FromTimestamp {
+		return false
+	}
+	if f.UntilTimestamp != 0 && r.Timestamp >= f.UntilTimestamp {
+		return false
+	}
+
+	for _, id := range f.MDStreams {
+		var found bool
+		for _, v := range r.Metadata {
+			if v.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterInventory returns the page of records that is selected by the filter
+// and the cursor of the next page.  The cursor is empty when there are no
+// more records.  It is used by caches that keep their records in memory.
+func FilterInventory(records []Record, f InventoryFilter) ([]Record, string, error) {
+	var (
+		ct     int64
+		ctoken string
+		err    error
+	)
+	if f.Cursor != "" {
+		ct, ctoken, err = ParseInventoryCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	matched := make([]Record, 0, len(records))
+	for _, v := range records {
+		if !matchInventoryFilter(v, f) {
+			continue
+		}
+		token := v.CensorshipRecord.Token
+		if f.Cursor != "" {
+			// Skip the records up to and including the cursor
+			if f.Reverse && !inventoryLess(v.Timestamp, token,
+				ct, ctoken) {
+				continue
+			}
+			if !f.Reverse && !inventoryLess(ct, ctoken,
+				v.Timestamp, token) {
+				continue
+			}
+		}
+		matched = append(matched, v)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		a := matched[i]
+		b := matched[j]
+		if f.Reverse {
+			return inventoryLess(b.Timestamp,
+				b.CensorshipRecord.Token, a.Timestamp,
+				a.CensorshipRecord.Token)
+		}
+		return inventoryLess(a.Timestamp, a.CensorshipRecord.Token,
+			b.Timestamp, b.CensorshipRecord.Token)
+	})
+
+	limit := int(f.Limit)
+	if limit == 0 || limit > InventoryPageSize {
+		limit = InventoryPageSize
+	}
+	var cursor string
+	if len(matched) > limit {
+		matched = matched[:limit]
+		cursor = InventoryCursor(matched[len(matched)-1])
+	}
+	if !f.IncludeFiles {
+		for i := range matched {
+			matched[i].Files = []File{}
+		}
+	}
+
+	return matched, cursor, nil
+}
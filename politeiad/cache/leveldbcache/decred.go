@@ -0,0 +1,1725 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package leveldbcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/cache"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// decredVersion is the version of the leveldb cache implementation
+	// of the decred plugin. This may differ from the decredplugin package
+	// version.
+	decredVersion = "2"
+
+	// Decred plugin key prefixes
+	prefixDecred        = "decred:"
+	prefixComment       = prefixDecred + "comment:"       // token:commentID
+	prefixLikeComment   = prefixDecred + "likecomment:"   // token:commentID:sequence
+	prefixAuthorizeVote = prefixDecred + "authorizevote:" // token:recordVersion
+	prefixStartVote     = prefixDecred + "startvote:"     // token
+	prefixCastVote      = prefixDecred + "castvote:"      // token:ticket
+	prefixVoteResults   = prefixDecred + "voteresults:"   // token
+	prefixVoteRunoff    = prefixDecred + "voterunoff:"    // rfpToken
+	prefixVoteSchedule  = prefixDecred + "voteschedule:"  // token
+	prefixSearchTerm    = prefixDecred + "searchterm:"    // term:token:source:commentID
+	prefixSearchDoc     = prefixDecred + "searchdoc:"     // token:source:commentID
+)
+
+func init() {
+	RegisterPluginDriver(decredplugin.ID,
+		func(db *leveldb.DB, p cache.Plugin) cache.PluginDriver {
+			return newDecredPlugin(db, p)
+		})
+}
+
+// startVote is a start vote along with the parsed end height of the voting
+// period.
+type startVote struct {
+	StartVote      decredplugin.StartVote      `json:"startvote"`      // Start vote
+	StartVoteReply decredplugin.StartVoteReply `json:"startvotereply"` // Start vote reply
+	EndHeight      uint64                      `json:"endheight"`      // End block height
+}
+
+// voteResults contains the results of a finished voting period.
+type voteResults struct {
+	Approved bool                            `json:"approved"` // Vote was approved
+	Results  []decredplugin.VoteOptionResult `json:"results"`  // Vote option results
+}
+
+// decred implements the PluginDriver interface.
+type decred struct {
+	sync.Mutex                       // Serializes the commands that write
+	db         *leveldb.DB           // Database context
+	version    string                // Version of decred cache plugin
+	settings   []cache.PluginSetting // Plugin settings
+}
+
+func commentKey(token, commentID string) string {
+	return prefixComment + token + ":" + commentID
+}
+
+func likeCommentKey(token, commentID string, seq int) string {
+	return fmt.Sprintf("%v%v:%v:%020d", prefixLikeComment, token,
+		commentID, seq)
+}
+
+func authorizeVoteKey(token, recordVersion string) string {
+	return prefixAuthorizeVote + token + ":" + recordVersion
+}
+
+// iterate decodes the JSON encoded values of all keys that start with the
+// provided prefix, in key order, and calls f with every decode function.
+func (d *decred) iterate(prefix string, f func(decode func(interface{}) error) error) error {
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		err := f(func(v interface{}) error {
+			return json.Unmarshal(iter.Value(), v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// count returns the number of keys that start with the provided prefix.
+func (d *decred) count(prefix string) (int, error) {
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	var n int
+	for iter.Next() {
+		n++
+	}
+	return n, iter.Error()
+}
+
+// comments returns the comments of all keys that start with the provided
+// prefix.
+func (d *decred) comments(prefix string) ([]decredplugin.Comment, error) {
+	comments := make([]decredplugin.Comment, 0, 1024) // PNOOMA
+	err := d.iterate(prefix, func(decode func(interface{}) error) error {
+		var c decredplugin.Comment
+		err := decode(&c)
+		if err != nil {
+			return err
+		}
+		comments = append(comments, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// likeComments returns the comment likes of all keys that start with the
+// provided prefix.
+func (d *decred) likeComments(prefix string) ([]decredplugin.LikeComment, error) {
+	likes := make([]decredplugin.LikeComment, 0, 1024) // PNOOMA
+	err := d.iterate(prefix, func(decode func(interface{}) error) error {
+		var lc decredplugin.LikeComment
+		err := decode(&lc)
+		if err != nil {
+			return err
+		}
+		likes = append(likes, lc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+// castVotes returns all of the cast votes of the passed in record token.
+func (d *decred) castVotes(token string) ([]decredplugin.CastVote, error) {
+	votes := make([]decredplugin.CastVote, 0, 1024) // PNOOMA
+	err := d.iterate(prefixCastVote+token+":",
+		func(decode func(interface{}) error) error {
+			var cv decredplugin.CastVote
+			err := decode(&cv)
+			if err != nil {
+				return err
+			}
+			votes = append(votes, cv)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// startVotes returns all start votes indexed by record token.
+func (d *decred) startVotes() (map[string]startVote, error) {
+	svs := make(map[string]startVote)
+	err := d.iterate(prefixStartVote,
+		func(decode func(interface{}) error) error {
+			var sv startVote
+			err := decode(&sv)
+			if err != nil {
+				return err
+			}
+			svs[sv.StartVote.Vote.Token] = sv
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return svs, nil
+}
+
+// allVoteResults returns all vote results indexed by record token.
+func (d *decred) allVoteResults() (map[string]voteResults, error) {
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(prefixVoteResults)),
+		nil)
+	defer iter.Release()
+
+	vrs := make(map[string]voteResults)
+	for iter.Next() {
+		var vr voteResults
+		err := json.Unmarshal(iter.Value(), &vr)
+		if err != nil {
+			return nil, err
+		}
+		token := string(iter.Key()[len(prefixVoteResults):])
+		vrs[token] = vr
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return vrs, nil
+}
+
+// lookup decodes the value of the provided key into v.  It returns false if
+// the key does not exist.
+func (d *decred) lookup(key string, v interface{}) (bool, error) {
+	err := get(d.db, key, v)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cmdNewComment stores the comment that is created by the passed in
+// payloads.
+func (d *decred) cmdNewComment(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdNewComment")
+
+	nc, err := decredplugin.DecodeNewComment([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	ncr, err := decredplugin.DecodeNewCommentReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	key := commentKey(nc.Token, ncr.CommentID)
+	ok, err := d.db.Has([]byte(key), nil)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return "", fmt.Errorf("comment exists: %v %v", nc.Token,
+			ncr.CommentID)
+	}
+
+	batch := new(leveldb.Batch)
+	err = put(batch, key, decredplugin.Comment{
+		Token:     nc.Token,
+		ParentID:  nc.ParentID,
+		Comment:   nc.Comment,
+		Signature: nc.Signature,
+		PublicKey: nc.PublicKey,
+		CommentID: ncr.CommentID,
+		Receipt:   ncr.Receipt,
+		Timestamp: ncr.Timestamp,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = indexText(batch, nc.Token, cache.SearchSourceComment,
+		ncr.CommentID, nc.Comment)
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdLikeComment stores the passed in comment like.  Comment likes are kept
+// in the order that they were received.
+func (d *decred) cmdLikeComment(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdLikeComment")
+
+	lc, err := decredplugin.DecodeLikeComment([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	seq, err := d.count(prefixLikeComment + lc.Token + ":" +
+		lc.CommentID + ":")
+	if err != nil {
+		return "", err
+	}
+
+	batch := new(leveldb.Batch)
+	err = put(batch, likeCommentKey(lc.Token, lc.CommentID, seq), lc)
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdCensorComment censors an existing comment.  A censored comment has its
+// comment message removed and is marked as censored.
+func (d *decred) cmdCensorComment(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdCensorComment")
+
+	cc, err := decredplugin.DecodeCensorComment([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	var c decredplugin.Comment
+	key := commentKey(cc.Token, cc.CommentID)
+	ok, err := d.lookup(key, &c)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", cache.ErrRecordNotFound
+	}
+	c.Comment = ""
+	c.Censored = true
+
+	batch := new(leveldb.Batch)
+	err = put(batch, key, c)
+	if err != nil {
+		return "", err
+	}
+	err = d.unindexText(batch, cc.Token, cache.SearchSourceComment,
+		cc.CommentID)
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdGetComment retrieves the passed in comment from the database.
+func (d *decred) cmdGetComment(payload string) (string, error) {
+	log.Tracef("decred cmdGetComment")
+
+	gc, err := decredplugin.DecodeGetComment([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	if gc.Token == "" {
+		return "", cache.ErrInvalidPluginCmdArgs
+	}
+
+	var (
+		c     decredplugin.Comment
+		found bool
+	)
+	switch {
+	case gc.CommentID != "":
+		found, err = d.lookup(commentKey(gc.Token, gc.CommentID), &c)
+	case gc.Signature != "":
+		var comments []decredplugin.Comment
+		comments, err = d.comments(prefixComment + gc.Token + ":")
+		for _, v := range comments {
+			if v.Signature == gc.Signature {
+				c = v
+				found = true
+				break
+			}
+		}
+	default:
+		return "", cache.ErrInvalidPluginCmdArgs
+	}
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", cache.ErrRecordNotFound
+	}
+
+	gcrb, err := decredplugin.EncodeGetCommentReply(
+		decredplugin.GetCommentReply{
+			Comment: c,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(gcrb), nil
+}
+
+// cmdGetComments returns all of the comments for the passed in record token.
+func (d *decred) cmdGetComments(payload string) (string, error) {
+	log.Tracef("decred cmdGetComments")
+
+	gc, err := decredplugin.DecodeGetComments([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	comments, err := d.comments(prefixComment + gc.Token + ":")
+	if err != nil {
+		return "", err
+	}
+
+	gcrb, err := decredplugin.EncodeGetCommentsReply(
+		decredplugin.GetCommentsReply{
+			Comments: comments,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(gcrb), nil
+}
+
+// cmdGetNumComments returns the number of comments of the passed in record
+// tokens.  Records without comments are left out of the reply.
+func (d *decred) cmdGetNumComments(payload string) (string, error) {
+	log.Tracef("decred cmdGetNumComments")
+
+	gnc, err := decredplugin.DecodeGetNumComments([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	commentMap := make(map[string]int, len(gnc.Tokens))
+	for _, v := range gnc.Tokens {
+		n, err := d.count(prefixComment + v + ":")
+		if err != nil {
+			return "", err
+		}
+		if n > 0 {
+			commentMap[v] = n
+		}
+	}
+
+	gncre, err := decredplugin.EncodeGetNumCommentsReply(
+		decredplugin.GetNumCommentsReply{
+			CommentsMap: commentMap,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(gncre), nil
+}
+
+// cmdCommentLikes returns all of the comment likes for the passed in comment.
+func (d *decred) cmdCommentLikes(payload string) (string, error) {
+	log.Tracef("decred cmdCommentLikes")
+
+	cl, err := decredplugin.DecodeCommentLikes([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	likes, err := d.likeComments(prefixLikeComment + cl.Token + ":" +
+		cl.CommentID + ":")
+	if err != nil {
+		return "", err
+	}
+
+	clrb, err := decredplugin.EncodeCommentLikesReply(
+		decredplugin.CommentLikesReply{
+			CommentLikes: likes,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(clrb), nil
+}
+
+// cmdProposalCommentsLikes returns all of the comment likes for all comments
+// of the passed in record token.
+func (d *decred) cmdProposalCommentsLikes(payload string) (string, error) {
+	log.Tracef("decred cmdProposalCommentsLikes")
+
+	cl, err := decredplugin.DecodeGetProposalCommentsLikes([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	likes, err := d.likeComments(prefixLikeComment + cl.Token + ":")
+	if err != nil {
+		return "", err
+	}
+
+	clrb, err := decredplugin.EncodeGetProposalCommentsLikesReply(
+		decredplugin.GetProposalCommentsLikesReply{
+			CommentsLikes: likes,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(clrb), nil
+}
+
+// cmdAuthorizeVote stores the authorize vote that is created by the passed in
+// payloads.  An existing authorize vote for the same record version is
+// replaced.
+func (d *decred) cmdAuthorizeVote(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdAuthorizeVote")
+
+	av, err := decredplugin.DecodeAuthorizeVote([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	avr, err := decredplugin.DecodeAuthorizeVoteReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+	av.Receipt = avr.Receipt
+	av.Timestamp = avr.Timestamp
+
+	d.Lock()
+	defer d.Unlock()
+
+	batch := new(leveldb.Batch)
+	err = put(batch, authorizeVoteKey(av.Token, avr.RecordVersion), av)
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdStartVote stores the start vote that is created by the passed in
+// payloads.
+func (d *decred) cmdStartVote(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdStartVote")
+
+	sv, err := decredplugin.DecodeStartVote([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	svr, err := decredplugin.DecodeStartVoteReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	endHeight, err := strconv.ParseUint(svr.EndHeight, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("parse end height '%v': %v",
+			svr.EndHeight, err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	key := prefixStartVote + sv.Vote.Token
+	ok, err := d.db.Has([]byte(key), nil)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return "", fmt.Errorf("start vote exists: %v", sv.Vote.Token)
+	}
+
+	batch := new(leveldb.Batch)
+	err = put(batch, key, startVote{
+		StartVote:      *sv,
+		StartVoteReply: *svr,
+		EndHeight:      endHeight,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdScheduleVote stores the vote schedule that is created by the passed in
+// payloads.  An existing vote schedule is replaced.  A cancelled or failed
+// schedule keeps the start vote of the schedule.
+func (d *decred) cmdScheduleVote(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdScheduleVote")
+
+	sv, err := decredplugin.DecodeScheduleVote([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	svr, err := decredplugin.DecodeScheduleVoteReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+	sv.Version = decredplugin.VersionScheduleVote
+	sv.Receipt = svr.Receipt
+	sv.Timestamp = svr.Timestamp
+
+	d.Lock()
+	defer d.Unlock()
+
+	key := prefixVoteSchedule + sv.Token
+	if sv.Action != decredplugin.ScheduleVoteActionSchedule {
+		var current decredplugin.ScheduleVote
+		ok, err := d.lookup(key, &current)
+		if err != nil {
+			return "", fmt.Errorf("vote schedule lookup failed: %v", err)
+		}
+		if !ok {
+			return "", fmt.Errorf("vote schedule not found: %v",
+				sv.Token)
+		}
+		sv.StartVote = current.StartVote
+	}
+	sv.StartVote.Version = decredplugin.VersionStartVote
+
+	batch := new(leveldb.Batch)
+	err = put(batch, key, sv)
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdScheduledVotes returns the vote schedules of the proposal votes that are
+// scheduled and have not been started yet.
+func (d *decred) cmdScheduledVotes(payload string) (string, error) {
+	log.Tracef("decred cmdScheduledVotes")
+
+	_, err := decredplugin.DecodeScheduledVotes([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	svs, err := d.startVotes()
+	if err != nil {
+		return "", err
+	}
+	svr := decredplugin.ScheduledVotesReply{
+		ScheduleVotes: make([]decredplugin.ScheduleVote, 0, 16),
+	}
+	err = d.iterate(prefixVoteSchedule,
+		func(decode func(interface{}) error) error {
+			var sv decredplugin.ScheduleVote
+			err := decode(&sv)
+			if err != nil {
+				return err
+			}
+			if sv.Action != decredplugin.ScheduleVoteActionSchedule {
+				return nil
+			}
+			if _, ok := svs[sv.Token]; ok {
+				return nil
+			}
+			svr.ScheduleVotes = append(svr.ScheduleVotes, sv)
+			return nil
+		})
+	if err != nil {
+		return "", err
+	}
+	sort.SliceStable(svr.ScheduleVotes, func(i, j int) bool {
+		return svr.ScheduleVotes[i].StartHeight <
+			svr.ScheduleVotes[j].StartHeight
+	})
+
+	reply, err := decredplugin.EncodeScheduledVotesReply(svr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// cmdStartVoteRunoff stores the start votes of the runoff submissions and
+// the runoff of the RFP that are created by the passed in payloads.  All
+// entries are written in a single batch.
+func (d *decred) cmdStartVoteRunoff(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdStartVoteRunoff")
+
+	svr, err := decredplugin.DecodeStartVoteRunoff([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	svrr, err := decredplugin.DecodeStartVoteRunoffReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	endHeight, err := strconv.ParseUint(svrr.StartVoteReply.EndHeight, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("parse end height '%v': %v",
+			svrr.StartVoteReply.EndHeight, err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	key := prefixVoteRunoff + svr.Token
+	ok, err := d.db.Has([]byte(key), nil)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return "", fmt.Errorf("vote runoff exists: %v", svr.Token)
+	}
+
+	batch := new(leveldb.Batch)
+	tokens := make([]string, 0, len(svr.StartVotes))
+	for _, v := range svr.StartVotes {
+		svKey := prefixStartVote + v.Vote.Token
+		ok, err := d.db.Has([]byte(svKey), nil)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return "", fmt.Errorf("start vote exists: %v", v.Vote.Token)
+		}
+		err = put(batch, svKey, startVote{
+			StartVote:      v,
+			StartVoteReply: svrr.StartVoteReply,
+			EndHeight:      endHeight,
+		})
+		if err != nil {
+			return "", err
+		}
+		tokens = append(tokens, v.Vote.Token)
+	}
+	err = put(batch, key, decredplugin.VoteRunoff{
+		Version:          decredplugin.VersionVoteRunoff,
+		Token:            svr.Token,
+		Tokens:           tokens,
+		StartBlockHeight: svrr.StartVoteReply.StartBlockHeight,
+		StartBlockHash:   svrr.StartVoteReply.StartBlockHash,
+		EndHeight:        svrr.StartVoteReply.EndHeight,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdVoteRunoffDetails returns the runoff vote of the passed in RFP token.
+func (d *decred) cmdVoteRunoffDetails(payload string) (string, error) {
+	log.Tracef("decred cmdVoteRunoffDetails")
+
+	vrd, err := decredplugin.DecodeVoteRunoffDetails([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	// A runoff vote may not exist. This is ok.
+	var vrdr decredplugin.VoteRunoffDetailsReply
+	_, err = d.lookup(prefixVoteRunoff+vrd.Token, &vrdr.VoteRunoff)
+	if err != nil {
+		return "", fmt.Errorf("vote runoff lookup failed: %v", err)
+	}
+
+	reply, err := decredplugin.EncodeVoteRunoffDetailsReply(vrdr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// cmdVoteDetails returns the AuthorizeVote of the most recent record version,
+// the vote schedule and the StartVote for the passed in record token.
+func (d *decred) cmdVoteDetails(payload string) (string, error) {
+	log.Tracef("decred cmdVoteDetails")
+
+	vd, err := decredplugin.DecodeVoteDetails([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	// Lookup the most recent version of the record
+	r, err := record(d.db, vd.Token)
+	if err != nil {
+		return "", err
+	}
+
+	// An authorize vote and a start vote may not exist. This
+	// is ok.
+	var av decredplugin.AuthorizeVote
+	_, err = d.lookup(authorizeVoteKey(vd.Token, r.Version), &av)
+	if err != nil {
+		return "", fmt.Errorf("authorize vote lookup failed: %v", err)
+	}
+	var sv startVote
+	_, err = d.lookup(prefixStartVote+vd.Token, &sv)
+	if err != nil {
+		return "", fmt.Errorf("start vote lookup failed: %v", err)
+	}
+	var vs decredplugin.ScheduleVote
+	_, err = d.lookup(prefixVoteSchedule+vd.Token, &vs)
+	if err != nil {
+		return "", fmt.Errorf("vote schedule lookup failed: %v", err)
+	}
+
+	vdrb, err := decredplugin.EncodeVoteDetailsReply(
+		decredplugin.VoteDetailsReply{
+			AuthorizeVote:  av,
+			ScheduleVote:   vs,
+			StartVote:      sv.StartVote,
+			StartVoteReply: sv.StartVoteReply,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(vdrb), nil
+}
+
+// cmdNewBallot stores the cast votes of the passed in ballot.  Only votes
+// that have a receipt signature are stored.
+func (d *decred) cmdNewBallot(cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred cmdNewBallot")
+
+	b, err := decredplugin.DecodeBallot([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+	br, err := decredplugin.DecodeBallotReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	// Put votes receipts into a map for easy lookup
+	receipts := make(map[string]string, len(br.Receipts)) // [clientSig]receiptSig
+	for _, v := range br.Receipts {
+		receipts[v.ClientSignature] = v.Signature
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	batch := new(leveldb.Batch)
+	for _, v := range b.Votes {
+		// Don't add votes that don't have a receipt signature
+		if receipts[v.Signature] == "" {
+			log.Debugf("cmdNewBallot: vote receipt not found %v %v",
+				v.Token, v.Ticket)
+			continue
+		}
+
+		err := put(batch, prefixCastVote+v.Token+":"+v.Ticket, v)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return replyPayload, d.db.Write(batch, nil)
+}
+
+// cmdProposalVotes returns the StartVote and all CastVotes for the passed in
+// record token.
+func (d *decred) cmdProposalVotes(payload string) (string, error) {
+	log.Tracef("decred cmdProposalVotes")
+
+	vr, err := decredplugin.DecodeVoteResults([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	// A start vote may not exist if the voting period has not
+	// been started yet. This is ok.
+	var sv startVote
+	_, err = d.lookup(prefixStartVote+vr.Token, &sv)
+	if err != nil {
+		return "", fmt.Errorf("start vote lookup failed: %v", err)
+	}
+	cv, err := d.castVotes(vr.Token)
+	if err != nil {
+		return "", fmt.Errorf("cast votes lookup failed: %v", err)
+	}
+
+	vrrb, err := decredplugin.EncodeVoteResultsReply(
+		decredplugin.VoteResultsReply{
+			StartVote: sv.StartVote,
+			CastVotes: cv,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(vrrb), nil
+}
+
+// cmdInventory returns the decred plugin inventory.
+func (d *decred) cmdInventory() (string, error) {
+	log.Tracef("decred cmdInventory")
+
+	// XXX the only part of the decred plugin inventory that we return
+	// at the moment is comments. This is because comments are the only
+	// thing politeiawww currently needs on startup.
+	comments, err := d.comments(prefixComment)
+	if err != nil {
+		return "", err
+	}
+
+	irb, err := decredplugin.EncodeInventoryReply(
+		decredplugin.InventoryReply{
+			Comments: comments,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(irb), nil
+}
+
+// ballots returns the number of cast votes per vote bit of the passed in
+// start vote.
+func (d *decred) ballots(sv startVote) (map[uint64]uint64, error) {
+	cv, err := d.castVotes(sv.StartVote.Vote.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	ballots := make(map[uint64]uint64) // [voteBit]voteCount
+	for _, v := range cv {
+		bit, err := strconv.ParseUint(v.VoteBit, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vote bit %v %v: %v",
+				v.Ticket, v.VoteBit, err)
+		}
+		ballots[bit]++
+	}
+
+	return ballots, nil
+}
+
+// tally tallies the cast votes of the passed in start vote according to the
+// vote type.
+func (d *decred) tally(sv startVote) (*decredplugin.VoteTally, error) {
+	ballots, err := d.ballots(sv)
+	if err != nil {
+		return nil, err
+	}
+
+	t := decredplugin.TallyVote(sv.StartVote.Vote,
+		len(sv.StartVoteReply.EligibleTickets), ballots)
+	return &t, nil
+}
+
+// newVoteResults returns the results of the passed in start vote.  Vote
+// results should only be created once the voting period has ended.
+func (d *decred) newVoteResults(sv startVote) (*voteResults, error) {
+	t, err := d.tally(sv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &voteResults{
+		Approved: t.Approved,
+		Results:  t.Results,
+	}, nil
+}
+
+// finishedWithoutResults returns the tokens of the start votes that have a
+// finished voting period but do not have vote results yet.
+func finishedWithoutResults(svs map[string]startVote, vrs map[string]voteResults, bestBlock uint64) []string {
+	tokens := make([]string, 0, len(svs))
+	for token, sv := range svs {
+		if sv.EndHeight > bestBlock {
+			continue
+		}
+		if _, ok := vrs[token]; ok {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// cmdLoadVoteResults creates vote results for any proposals that have a
+// finished voting period but do not have vote results yet.  The vote results
+// are lazy loaded.
+func (d *decred) cmdLoadVoteResults(payload string) (string, error) {
+	log.Tracef("cmdLoadVoteResults")
+
+	lvs, err := decredplugin.DecodeLoadVoteResults([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	svs, err := d.startVotes()
+	if err != nil {
+		return "", err
+	}
+	vrs, err := d.allVoteResults()
+	if err != nil {
+		return "", err
+	}
+
+	// Create vote result entries
+	batch := new(leveldb.Batch)
+	for _, v := range finishedWithoutResults(svs, vrs, lvs.BestBlock) {
+		vr, err := d.newVoteResults(svs[v])
+		if err != nil {
+			return "", fmt.Errorf("newVoteResults %v: %v", v, err)
+		}
+		err = put(batch, prefixVoteResults+v, vr)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = d.db.Write(batch, nil)
+	if err != nil {
+		return "", err
+	}
+
+	reply, err := decredplugin.EncodeLoadVoteResultsReply(
+		decredplugin.LoadVoteResultsReply{})
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// cmdTokenInventory returns the tokens of all records in the cache,
+// categorized by stage of the voting process.
+func (d *decred) cmdTokenInventory(payload string) (string, error) {
+	log.Tracef("decred cmdTokenInventory")
+
+	ti, err := decredplugin.DecodeTokenInventory([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	svs, err := d.startVotes()
+	if err != nil {
+		return "", err
+	}
+	vrs, err := d.allVoteResults()
+	if err != nil {
+		return "", err
+	}
+
+	// The token inventory call cannot be completed if there
+	// are any proposals that have finished voting but that
+	// don't have vote results yet. Return a ErrRecordNotFound
+	// to indicate one or more vote results were not found.
+	if len(finishedWithoutResults(svs, vrs, ti.BestBlock)) > 0 {
+		return "", cache.ErrRecordNotFound
+	}
+
+	records, err := latestRecords(d.db)
+	if err != nil {
+		return "", err
+	}
+
+	// Records are ordered by timestamp in descending order
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp > records[j].Timestamp
+	})
+	byStatus := func(statuses ...cache.RecordStatusT) []string {
+		tokens := make([]string, 0, 1024)
+		for _, r := range records {
+			for _, s := range statuses {
+				if r.Status == s {
+					tokens = append(tokens,
+						r.CensorshipRecord.Token)
+					break
+				}
+			}
+		}
+		return tokens
+	}
+
+	// Pre voting period tokens are public records that do not
+	// have a start vote.
+	pre := make([]string, 0, 1024)
+	for _, v := range byStatus(cache.RecordStatusPublic) {
+		if _, ok := svs[v]; !ok {
+			pre = append(pre, v)
+		}
+	}
+
+	// Voting period tokens are ordered by end height in
+	// descending order.
+	tokens := make([]string, 0, len(svs))
+	for k := range svs {
+		tokens = append(tokens, k)
+	}
+	sort.Strings(tokens)
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return svs[tokens[i]].EndHeight > svs[tokens[j]].EndHeight
+	})
+	active := make([]string, 0, 1024)
+	approved := make([]string, 0, 1024)
+	rejected := make([]string, 0, 1024)
+	for _, v := range tokens {
+		if svs[v].EndHeight > ti.BestBlock {
+			active = append(active, v)
+			continue
+		}
+		if vrs[v].Approved {
+			approved = append(approved, v)
+		} else {
+			rejected = append(rejected, v)
+		}
+	}
+
+	tir := decredplugin.TokenInventoryReply{
+		Pre:       pre,
+		Active:    active,
+		Approved:  approved,
+		Rejected:  rejected,
+		Abandoned: byStatus(cache.RecordStatusArchived),
+	}
+
+	// Add unvetted records if specified
+	if ti.Unvetted {
+		tir.Unreviewed = byStatus(cache.RecordStatusNotReviewed,
+			cache.RecordStatusUnreviewedChanges)
+		tir.Censored = byStatus(cache.RecordStatusCensored)
+	}
+
+	reply, err := decredplugin.EncodeTokenInventoryReply(tir)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// cmdVoteSummary returns the voting period parameters and a summary of the
+// vote results of the passed in record token.
+func (d *decred) cmdVoteSummary(payload string) (string, error) {
+	log.Tracef("cmdVoteSummary")
+
+	vs, err := decredplugin.DecodeVoteSummary([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	// Lookup the most recent record version
+	r, err := record(d.db, vs.Token)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		vsr decredplugin.VoteSummaryReply
+		av  decredplugin.AuthorizeVote
+		vsc decredplugin.ScheduleVote
+		sv  startVote
+		vr  voteResults
+		t   *decredplugin.VoteTally
+	)
+
+	// If an authorize vote or a start vote don't exist then
+	// there is no need to continue.
+	ok, err := d.lookup(authorizeVoteKey(vs.Token, r.Version), &av)
+	if err != nil {
+		return "", fmt.Errorf("lookup authorize vote: %v", err)
+	}
+	if !ok {
+		goto sendReply
+	}
+	ok, err = d.lookup(prefixStartVote+vs.Token, &sv)
+	if err != nil {
+		return "", fmt.Errorf("lookup start vote: %v", err)
+	}
+	if !ok {
+		// The vote may have been scheduled
+		_, err = d.lookup(prefixVoteSchedule+vs.Token, &vsc)
+		if err != nil {
+			return "", fmt.Errorf("lookup vote schedule: %v", err)
+		}
+		if vsc.Action == decredplugin.ScheduleVoteActionSchedule {
+			vsr.ScheduledHeight = vsc.StartHeight
+		}
+		goto sendReply
+	}
+
+	// The vote results do not exist when the vote is still active
+	// or when they have not been lazy loaded yet. The votes are
+	// tallied manually in that case. The rounds of a ranked choice
+	// vote can only be counted using the individual ballots.
+	ok, err = d.lookup(prefixVoteResults+vs.Token, &vr)
+	if err != nil {
+		return "", fmt.Errorf("lookup vote results: %v", err)
+	}
+	if ok && decredplugin.VoteType(sv.StartVote.Vote) !=
+		decredplugin.VoteTypeRankedChoice {
+		ballots := make(map[uint64]uint64, len(vr.Results))
+		for _, v := range vr.Results {
+			ballots[v.Bits] = v.Votes
+		}
+		vt := decredplugin.TallyVote(sv.StartVote.Vote,
+			len(sv.StartVoteReply.EligibleTickets), ballots)
+		t = &vt
+	} else {
+		t, err = d.tally(sv)
+		if err != nil {
+			return "", fmt.Errorf("tally: %v", err)
+		}
+	}
+
+	vsr = decredplugin.VoteSummaryReply{
+		Type:       t.Type,
+		Results:    t.Results,
+		TotalVotes: t.TotalVotes,
+		QuorumMet:  t.QuorumMet,
+		Winner:     t.Winner,
+		Approved:   t.Approved,
+		Rounds:     t.Rounds,
+	}
+
+sendReply:
+	vsr.Authorized = av.Action == decredplugin.AuthVoteActionAuthorize
+	vsr.EligibleTicketCount = len(sv.StartVoteReply.EligibleTickets)
+	vsr.QuorumPercentage = sv.StartVote.Vote.QuorumPercentage
+	vsr.PassPercentage = sv.StartVote.Vote.PassPercentage
+	if vsr.Results == nil {
+		vsr.Results = []decredplugin.VoteOptionResult{}
+	}
+
+	// Return "" not "0" if end height doesn't exist
+	if sv.EndHeight != 0 {
+		vsr.EndHeight = strconv.FormatUint(sv.EndHeight, 10)
+	}
+
+	reply, err := decredplugin.EncodeVoteSummaryReply(vsr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
+// searchDocKey returns the key under which the search terms of an indexed
+// text are recorded.
+func searchDocKey(token, source, commentID string) string {
+	return prefixSearchDoc + token + ":" + source + ":" + commentID
+}
+
+// indexText adds the search index entries of a text to the batch.  The terms
+// are also recorded under the document key so that the entries can be
+// removed again.
+func indexText(batch *leveldb.Batch, token, source, commentID, text string) error {
+	terms := cache.SearchTerms(text)
+	for _, v := range terms {
+		batch.Put([]byte(prefixSearchTerm+v+":"+token+":"+source+":"+
+			commentID), nil)
+	}
+	return put(batch, searchDocKey(token, source, commentID), terms)
+}
+
+// unindexText adds the removal of the search index entries of a text to the
+// batch.
+func (d *decred) unindexText(batch *leveldb.Batch, token, source, commentID string) error {
+	var terms []string
+	key := searchDocKey(token, source, commentID)
+	ok, err := d.lookup(key, &terms)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	for _, v := range terms {
+		batch.Delete([]byte(prefixSearchTerm + v + ":" + token + ":" +
+			source + ":" + commentID))
+	}
+	batch.Delete([]byte(key))
+	return nil
+}
+
+// indexRecord adds the search index entries of the name and of the index
+// file of a record to the batch.
+func indexRecord(batch *leveldb.Batch, r cache.Record) error {
+	name, text, ok, err := cache.RecordText(r, decredplugin.IndexFile)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	token := r.CensorshipRecord.Token
+	err = indexText(batch, token, cache.SearchSourceName, "", name)
+	if err != nil {
+		return err
+	}
+	return indexText(batch, token, cache.SearchSourceIndex, "", text)
+}
+
+// IndexRecord replaces the search index entries of the passed in record.  The
+// record must be the most recent version of the record.  A record without an
+// index file is removed from the index.
+//
+// This function satisfies the cache PluginIndexer interface.
+func (d *decred) IndexRecord(r cache.Record) error {
+	log.Tracef("decred IndexRecord: %v", r.CensorshipRecord.Token)
+
+	d.Lock()
+	defer d.Unlock()
+
+	batch := new(leveldb.Batch)
+	for _, v := range []string{cache.SearchSourceName,
+		cache.SearchSourceIndex} {
+		err := d.unindexText(batch, r.CensorshipRecord.Token, v, "")
+		if err != nil {
+			return err
+		}
+	}
+	err := indexRecord(batch, r)
+	if err != nil {
+		return err
+	}
+
+	return d.db.Write(batch, nil)
+}
+
+// searchIndex returns the indexed texts that contain all of the passed in
+// words.  The texts are identified by token:source:commentID.
+func (d *decred) searchIndex(words []string) (map[string]struct{}, error) {
+	var docs map[string]struct{}
+	for _, w := range words {
+		found := make(map[string]struct{})
+		prefix := prefixSearchTerm + w + ":"
+		iter := d.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+		for iter.Next() {
+			doc := strings.TrimPrefix(string(iter.Key()), prefix)
+			if docs != nil {
+				if _, ok := docs[doc]; !ok {
+					continue
+				}
+			}
+			found[doc] = struct{}{}
+		}
+		iter.Release()
+		err := iter.Error()
+		if err != nil {
+			return nil, err
+		}
+
+		docs = found
+		if len(docs) == 0 {
+			break
+		}
+	}
+	return docs, nil
+}
+
+// vettedRecord returns the most recent version of a record.  It returns
+// cache.ErrRecordNotFound if the record is not vetted.
+func vettedRecord(db *leveldb.DB, token string) (*cache.Record, error) {
+	r, err := record(db, token)
+	if err != nil {
+		return nil, err
+	}
+	if !cache.IsVettedStatus(r.Status) {
+		return nil, cache.ErrRecordNotFound
+	}
+	return r, nil
+}
+
+// cmdSearch returns the vetted records whose name, index file or non-censored
+// comments match the search query.  The candidates are looked up in the
+// search index.  Only phrases are matched against the text itself.
+func (d *decred) cmdSearch(payload string) (string, error) {
+	log.Tracef("decred cmdSearch")
+
+	s, err := decredplugin.DecodeSearch([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	q, err := cache.ParseSearchQuery(s.Query)
+	if err != nil {
+		return "", err
+	}
+	var after *cache.Record
+	if s.After != "" {
+		after, err = vettedRecord(d.db, s.After)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	docs, err := d.searchIndex(q.Words())
+	if err != nil {
+		return "", err
+	}
+
+	records := make(map[string]*cache.Record) // [token]record, nil if not vetted
+	results := make(map[string]*decredplugin.SearchResult)
+	for doc := range docs {
+		parts := strings.SplitN(doc, ":", 3)
+		if len(parts) != 3 {
+			return "", fmt.Errorf("invalid search index key: %v", doc)
+		}
+		token, source, commentID := parts[0], parts[1], parts[2]
+
+		r, ok := records[token]
+		if !ok {
+			r, err = vettedRecord(d.db, token)
+			if err != nil && err != cache.ErrRecordNotFound {
+				return "", err
+			}
+			records[token] = r
+		}
+		if r == nil || !cache.SearchStatus(r.Status, s.Statuses) {
+			continue
+		}
+
+		var name, index bool
+		switch source {
+		case cache.SearchSourceName, cache.SearchSourceIndex:
+			name = source == cache.SearchSourceName
+			index = source == cache.SearchSourceIndex
+			if q.HasPhrases() {
+				n, i, err := q.MatchFile(*r, decredplugin.IndexFile)
+				if err != nil {
+					return "", fmt.Errorf("match %v: %v", token,
+						err)
+				}
+				name = name && n
+				index = index && i
+			}
+			if !name && !index {
+				continue
+			}
+		case cache.SearchSourceComment:
+			if q.HasPhrases() {
+				var c decredplugin.Comment
+				ok, err := d.lookup(commentKey(token, commentID), &c)
+				if err != nil {
+					return "", err
+				}
+				if !ok || c.Censored || !q.Match(c.Comment) {
+					continue
+				}
+			}
+		default:
+			continue
+		}
+
+		sr, ok := results[token]
+		if !ok {
+			sr = &decredplugin.SearchResult{
+				Token:      token,
+				Timestamp:  r.Timestamp,
+				CommentIDs: []string{},
+			}
+			results[token] = sr
+		}
+		sr.Name = sr.Name || name
+		sr.Index = sr.Index || index
+		if source == cache.SearchSourceComment {
+			sr.CommentIDs = append(sr.CommentIDs, commentID)
+		}
+	}
+
+	srb, err := decredplugin.EncodeSearchReply(decredplugin.SearchReply{
+		Results: searchResults(results, after, s.Limit),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(srb), nil
+}
+
+// searchResults returns the search results ordered by record timestamp in
+// descending order and then by token.  If after is not nil, only the results
+// that are ordered after it are returned.  At most limit results are returned
+// unless limit is zero.
+func searchResults(results map[string]*decredplugin.SearchResult, after *cache.Record, limit int) []decredplugin.SearchResult {
+	sr := make([]decredplugin.SearchResult, 0, len(results))
+	for _, v := range results {
+		sort.Slice(v.CommentIDs, func(i, j int) bool {
+			a, _ := strconv.Atoi(v.CommentIDs[i])
+			b, _ := strconv.Atoi(v.CommentIDs[j])
+			return a < b
+		})
+		sr = append(sr, *v)
+	}
+	sort.Slice(sr, func(i, j int) bool {
+		if sr[i].Timestamp != sr[j].Timestamp {
+			return sr[i].Timestamp > sr[j].Timestamp
+		}
+		return sr[i].Token < sr[j].Token
+	})
+	if after != nil {
+		i := sort.Search(len(sr), func(i int) bool {
+			if sr[i].Timestamp != after.Timestamp {
+				return sr[i].Timestamp < after.Timestamp
+			}
+			return sr[i].Token > after.CensorshipRecord.Token
+		})
+		sr = sr[i:]
+	}
+	if limit > 0 && len(sr) > limit {
+		sr = sr[:limit]
+	}
+	return sr
+}
+
+// Exec executes a decred plugin command.  Plugin commands that write data to
+// the cache require both the command payload and the reply payload.  Plugin
+// commands that fetch data from the cache require only the command payload.
+// All commands return the appropriate reply payload.
+func (d *decred) Exec(cmd, cmdPayload, replyPayload string) (string, error) {
+	log.Tracef("decred Exec: %v", cmd)
+
+	switch cmd {
+	case decredplugin.CmdAuthorizeVote:
+		return d.cmdAuthorizeVote(cmdPayload, replyPayload)
+	case decredplugin.CmdScheduleVote:
+		return d.cmdScheduleVote(cmdPayload, replyPayload)
+	case decredplugin.CmdScheduledVotes:
+		return d.cmdScheduledVotes(cmdPayload)
+	case decredplugin.CmdStartVote:
+		return d.cmdStartVote(cmdPayload, replyPayload)
+	case decredplugin.CmdStartVoteRunoff:
+		return d.cmdStartVoteRunoff(cmdPayload, replyPayload)
+	case decredplugin.CmdVoteDetails:
+		return d.cmdVoteDetails(cmdPayload)
+	case decredplugin.CmdVoteRunoffDetails:
+		return d.cmdVoteRunoffDetails(cmdPayload)
+	case decredplugin.CmdBallot:
+		return d.cmdNewBallot(cmdPayload, replyPayload)
+	case decredplugin.CmdBestBlock:
+		return "", nil
+	case decredplugin.CmdNewComment:
+		return d.cmdNewComment(cmdPayload, replyPayload)
+	case decredplugin.CmdLikeComment:
+		return d.cmdLikeComment(cmdPayload, replyPayload)
+	case decredplugin.CmdCensorComment:
+		return d.cmdCensorComment(cmdPayload, replyPayload)
+	case decredplugin.CmdGetComment:
+		return d.cmdGetComment(cmdPayload)
+	case decredplugin.CmdGetComments:
+		return d.cmdGetComments(cmdPayload)
+	case decredplugin.CmdGetNumComments:
+		return d.cmdGetNumComments(cmdPayload)
+	case decredplugin.CmdProposalVotes:
+		return d.cmdProposalVotes(cmdPayload)
+	case decredplugin.CmdCommentLikes:
+		return d.cmdCommentLikes(cmdPayload)
+	case decredplugin.CmdProposalCommentsLikes:
+		return d.cmdProposalCommentsLikes(cmdPayload)
+	case decredplugin.CmdInventory:
+		return d.cmdInventory()
+	case decredplugin.CmdLoadVoteResults:
+		return d.cmdLoadVoteResults(cmdPayload)
+	case decredplugin.CmdTokenInventory:
+		return d.cmdTokenInventory(cmdPayload)
+	case decredplugin.CmdVoteSummary:
+		return d.cmdVoteSummary(cmdPayload)
+	case decredplugin.CmdSearch:
+		return d.cmdSearch(cmdPayload)
+	}
+
+	return "", cache.ErrInvalidPluginCmd
+}
+
+// insertInventory adds the passed in decred plugin inventory to the batch.
+// The batch must also remove the existing decred plugin data.
+func insertInventory(batch *leveldb.Batch, ir *decredplugin.InventoryReply) error {
+	for _, v := range ir.Comments {
+		v.TotalVotes = 0
+		v.ResultVotes = 0
+		err := put(batch, commentKey(v.Token, v.CommentID), v)
+		if err != nil {
+			return fmt.Errorf("newComment: %v", err)
+		}
+		if v.Censored {
+			continue
+		}
+		err = indexText(batch, v.Token, cache.SearchSourceComment,
+			v.CommentID, v.Comment)
+		if err != nil {
+			return fmt.Errorf("index comment: %v", err)
+		}
+	}
+
+	seqs := make(map[string]int) // [token:commentID]sequence
+	for _, v := range ir.LikeComments {
+		k := v.Token + ":" + v.CommentID
+		err := put(batch, likeCommentKey(v.Token, v.CommentID, seqs[k]), v)
+		if err != nil {
+			return fmt.Errorf("newLikeComment: %v", err)
+		}
+		seqs[k]++
+	}
+
+	// Put authorize vote replies in a map for quick lookups
+	avr := make(map[string]decredplugin.AuthorizeVoteReply,
+		len(ir.AuthorizeVoteReplies)) // [receipt]AuthorizeVote
+	for _, v := range ir.AuthorizeVoteReplies {
+		avr[v.Receipt] = v
+	}
+	for _, v := range ir.AuthorizeVotes {
+		r, ok := avr[v.Receipt]
+		if !ok {
+			return fmt.Errorf("AuthorizeVoteReply not found %v",
+				v.Token)
+		}
+		err := put(batch, authorizeVoteKey(v.Token, r.RecordVersion), v)
+		if err != nil {
+			return fmt.Errorf("newAuthorizeVote: %v", err)
+		}
+	}
+
+	for _, v := range ir.StartVoteTuples {
+		endHeight, err := strconv.ParseUint(v.StartVoteReply.EndHeight,
+			10, 64)
+		if err != nil {
+			return fmt.Errorf("parse end height '%v': %v",
+				v.StartVoteReply.EndHeight, err)
+		}
+		err = put(batch, prefixStartVote+v.StartVote.Vote.Token,
+			startVote{
+				StartVote:      v.StartVote,
+				StartVoteReply: v.StartVoteReply,
+				EndHeight:      endHeight,
+			})
+		if err != nil {
+			return fmt.Errorf("newStartVote: %v", err)
+		}
+	}
+
+	for _, v := range ir.VoteRunoffs {
+		err := put(batch, prefixVoteRunoff+v.Token, v)
+		if err != nil {
+			return fmt.Errorf("insert vote runoff: %v", err)
+		}
+	}
+
+	for _, v := range ir.ScheduleVotes {
+		err := put(batch, prefixVoteSchedule+v.Token, v)
+		if err != nil {
+			return fmt.Errorf("insert vote schedule: %v", err)
+		}
+	}
+
+	for _, v := range ir.CastVotes {
+		err := put(batch, prefixCastVote+v.Token+":"+v.Ticket, v)
+		if err != nil {
+			return fmt.Errorf("insert cast vote: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Build removes all existing decred plugin data from the database then uses
+// the passed in inventory payload to build the decred plugin cache.  The data
+// is replaced in a single atomic batch.
+func (d *decred) Build(payload string) error {
+	log.Tracef("decred Build")
+
+	ir, err := decredplugin.DecodeInventoryReply([]byte(payload))
+	if err != nil {
+		return fmt.Errorf("DecodeInventoryReply: %v", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	batch := new(leveldb.Batch)
+	err = deletePrefix(d.db, batch, prefixDecred)
+	if err != nil {
+		return fmt.Errorf("delete decred data: %v", err)
+	}
+	err = insertInventory(batch, ir)
+	if err != nil {
+		return err
+	}
+
+	// Add the records to the search index. The comments were
+	// indexed by insertInventory.
+	records, err := latestRecords(d.db)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		err = indexRecord(batch, r)
+		if err != nil {
+			return fmt.Errorf("index record %v: %v",
+				r.CensorshipRecord.Token, err)
+		}
+	}
+
+	err = put(batch, prefixVersion+decredplugin.ID, version{
+		Version:   decredVersion,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.db.Write(batch, nil)
+}
+
+// Setup inserts a decred plugin version record into the database if one does
+// not already exist.
+func (d *decred) Setup() error {
+	log.Tracef("decred: Setup")
+
+	return setupVersion(d.db, decredplugin.ID, decredVersion)
+}
+
+// CheckVersion retrieves the decred plugin version record from the database,
+// if one exists, and checks that it matches the version of the current decred
+// plugin cache implementation.
+func (d *decred) CheckVersion() error {
+	log.Tracef("decred: CheckVersion")
+
+	return checkVersion(d.db, decredplugin.ID, decredVersion)
+}
+
+// newDecredPlugin returns a cache decred plugin context.
+func newDecredPlugin(db *leveldb.DB, p cache.Plugin) *decred {
+	log.Tracef("newDecredPlugin")
+	return &decred{
+		db:       db,
+		version:  decredVersion,
+		settings: p.Settings,
+	}
+}
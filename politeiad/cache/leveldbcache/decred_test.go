@@ -0,0 +1,549 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package leveldbcache
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/cache"
+)
+
+// execDecred executes a decred plugin command and returns the reply payload.
+func execDecred(t *testing.T, l *leveldbcache, cmd string, payload, reply []byte) string {
+	t.Helper()
+
+	pcr, err := l.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        cmd,
+		CommandPayload: string(payload),
+		ReplyPayload:   string(reply),
+	})
+	if err != nil {
+		t.Fatalf("%v: %v", cmd, err)
+	}
+	return pcr.Payload
+}
+
+// newTestComment adds a comment to the cache and returns the error of the
+// plugin command.
+func newTestComment(l *leveldbcache, token, parentID, commentID, comment string) error {
+	nc, err := decredplugin.EncodeNewComment(decredplugin.NewComment{
+		Token:     token,
+		ParentID:  parentID,
+		Comment:   comment,
+		Signature: "signature" + commentID,
+		PublicKey: "publickey",
+	})
+	if err != nil {
+		return err
+	}
+	ncr, err := decredplugin.EncodeNewCommentReply(
+		decredplugin.NewCommentReply{
+			CommentID: commentID,
+			Receipt:   "receipt" + commentID,
+			Timestamp: 1,
+		})
+	if err != nil {
+		return err
+	}
+	_, err = l.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdNewComment,
+		CommandPayload: string(nc),
+		ReplyPayload:   string(ncr),
+	})
+	return err
+}
+
+// searchCommentIDs returns the IDs of the comments of a record that match
+// the search query.
+func searchCommentIDs(t *testing.T, l *leveldbcache, token, query string) []string {
+	t.Helper()
+
+	s, err := decredplugin.EncodeSearch(decredplugin.Search{
+		Query: query,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, err := decredplugin.DecodeSearchReply([]byte(execDecred(t, l,
+		decredplugin.CmdSearch, s, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range sr.Results {
+		if v.Token == token {
+			return v.CommentIDs
+		}
+	}
+	return []string{}
+}
+
+func TestSearch(t *testing.T) {
+	l, cleanup := newTestCache(t)
+	defer cleanup()
+
+	records := []cache.Record{
+		newTestRecord(testToken(1), "1", cache.RecordStatusPublic, 400),
+		newTestRecord(testToken(2), "1", cache.RecordStatusArchived, 300),
+		newTestRecord(testToken(3), "1", cache.RecordStatusNotReviewed,
+			200),
+		newTestRecord(testToken(4), "1", cache.RecordStatusPublic, 100),
+	}
+	for _, v := range records {
+		err := l.NewRecord(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = []struct {
+		name   string
+		search decredplugin.Search
+		want   []string
+	}{
+		{"all vetted",
+			decredplugin.Search{Query: "proposal"},
+			[]string{testToken(1), testToken(2), testToken(4)}},
+
+		{"public",
+			decredplugin.Search{Query: "proposal",
+				Statuses: []int{int(cache.RecordStatusPublic)}},
+			[]string{testToken(1), testToken(4)}},
+
+		{"unvetted status",
+			decredplugin.Search{Query: "proposal",
+				Statuses: []int{int(cache.RecordStatusNotReviewed)}},
+			[]string{}},
+
+		{"limit",
+			decredplugin.Search{Query: "proposal", Limit: 2},
+			[]string{testToken(1), testToken(2)}},
+
+		{"limit after",
+			decredplugin.Search{Query: "proposal", Limit: 2,
+				After: testToken(2)},
+			[]string{testToken(4)}},
+
+		{"limit after with status",
+			decredplugin.Search{Query: "proposal", Limit: 1,
+				After:    testToken(1),
+				Statuses: []int{int(cache.RecordStatusPublic)}},
+			[]string{testToken(4)}},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			s, err := decredplugin.EncodeSearch(v.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sr, err := decredplugin.DecodeSearchReply([]byte(
+				execDecred(t, l, decredplugin.CmdSearch, s, nil)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(sr.Results))
+			for _, r := range sr.Results {
+				got = append(got, r.Token)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Fatalf("got %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestComments(t *testing.T) {
+	l, cleanup := newTestCache(t)
+	defer cleanup()
+
+	token := testToken(1)
+	err := l.NewRecord(newTestRecord(token, "1", cache.RecordStatusPublic,
+		100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Comments
+	comments := []struct {
+		parentID  string
+		commentID string
+		comment   string
+	}{
+		{"0", "1", "the first comment"},
+		{"1", "2", "a reply about the funding"},
+		{"0", "3", "the funding is too high"},
+	}
+	for _, v := range comments {
+		err := newTestComment(l, token, v.parentID, v.commentID,
+			v.comment)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = newTestComment(l, token, "0", "1", "duplicate")
+	if err == nil {
+		t.Fatalf("expected duplicate comment error")
+	}
+
+	gc, err := decredplugin.EncodeGetComments(decredplugin.GetComments{
+		Token: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcr, err := decredplugin.DecodeGetCommentsReply([]byte(execDecred(t, l,
+		decredplugin.CmdGetComments, gc, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gcr.Comments) != len(comments) {
+		t.Fatalf("unexpected number of comments got %v want %v",
+			len(gcr.Comments), len(comments))
+	}
+	for i, v := range gcr.Comments {
+		if v.CommentID != comments[i].commentID ||
+			v.ParentID != comments[i].parentID ||
+			v.Comment != comments[i].comment {
+			t.Fatalf("unexpected comment got %+v want %+v", v,
+				comments[i])
+		}
+	}
+
+	// Comment likes are returned in the order that they were received
+	likes := []decredplugin.LikeComment{
+		{Token: token, CommentID: "1", Action: "1", PublicKey: "a"},
+		{Token: token, CommentID: "1", Action: "-1", PublicKey: "b"},
+		{Token: token, CommentID: "3", Action: "1", PublicKey: "a"},
+		{Token: token, CommentID: "1", Action: "1", PublicKey: "a"},
+	}
+	for _, v := range likes {
+		lc, err := decredplugin.EncodeLikeComment(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		execDecred(t, l, decredplugin.CmdLikeComment, lc, nil)
+	}
+
+	cl, err := decredplugin.EncodeCommentLikes(decredplugin.CommentLikes{
+		Token:     token,
+		CommentID: "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clr, err := decredplugin.DecodeCommentLikesReply([]byte(execDecred(t, l,
+		decredplugin.CmdCommentLikes, cl, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []decredplugin.LikeComment{likes[0], likes[1], likes[3]}
+	if !reflect.DeepEqual(clr.CommentLikes, want) {
+		t.Fatalf("unexpected comment likes got %v want %v",
+			clr.CommentLikes, want)
+	}
+
+	pcl, err := decredplugin.EncodeGetProposalCommentsLikes(
+		decredplugin.GetProposalCommentsLikes{
+			Token: token,
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pclr, err := decredplugin.DecodeGetProposalCommentsLikesReply(
+		[]byte(execDecred(t, l, decredplugin.CmdProposalCommentsLikes,
+			pcl, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []decredplugin.LikeComment{likes[0], likes[1], likes[3],
+		likes[2]}
+	if !reflect.DeepEqual(pclr.CommentsLikes, want) {
+		t.Fatalf("unexpected proposal comment likes got %v want %v",
+			pclr.CommentsLikes, want)
+	}
+
+	// Censorship removes the comment message and the comment from the
+	// search index
+	ids := searchCommentIDs(t, l, token, "funding")
+	if !reflect.DeepEqual(ids, []string{"2", "3"}) {
+		t.Fatalf("unexpected search results %v", ids)
+	}
+
+	cc, err := decredplugin.EncodeCensorComment(decredplugin.CensorComment{
+		Token:     token,
+		CommentID: "2",
+		Reason:    "spam",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execDecred(t, l, decredplugin.CmdCensorComment, cc, nil)
+
+	gcb, err := decredplugin.EncodeGetComment(decredplugin.GetComment{
+		Token:     token,
+		CommentID: "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcbr, err := decredplugin.DecodeGetCommentReply([]byte(execDecred(t, l,
+		decredplugin.CmdGetComment, gcb, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gcbr.Comment.Censored || gcbr.Comment.Comment != "" {
+		t.Fatalf("comment not censored: %+v", gcbr.Comment)
+	}
+
+	ids = searchCommentIDs(t, l, token, "funding")
+	if !reflect.DeepEqual(ids, []string{"3"}) {
+		t.Fatalf("unexpected search results after censorship %v", ids)
+	}
+
+	// Censored comments are still counted
+	gnc, err := decredplugin.EncodeGetNumComments(
+		decredplugin.GetNumComments{
+			Tokens: []string{token, testToken(2)},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gncr, err := decredplugin.DecodeGetNumCommentsReply([]byte(execDecred(t,
+		l, decredplugin.CmdGetNumComments, gnc, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNum := map[string]int{token: len(comments)}
+	if !reflect.DeepEqual(gncr.CommentsMap, wantNum) {
+		t.Fatalf("unexpected number of comments got %v want %v",
+			gncr.CommentsMap, wantNum)
+	}
+
+	// A comment that does not exist can't be censored
+	cc, err = decredplugin.EncodeCensorComment(decredplugin.CensorComment{
+		Token:     token,
+		CommentID: "9",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdCensorComment,
+		CommandPayload: string(cc),
+	})
+	if err != cache.ErrRecordNotFound {
+		t.Fatalf("unexpected error got %v want %v", err,
+			cache.ErrRecordNotFound)
+	}
+}
+
+// voteSummary returns the vote summary of a record.
+func voteSummary(t *testing.T, l *leveldbcache, token string) *decredplugin.VoteSummaryReply {
+	t.Helper()
+
+	vs, err := decredplugin.EncodeVoteSummary(decredplugin.VoteSummary{
+		Token: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vsr, err := decredplugin.DecodeVoteSummaryReply([]byte(execDecred(t, l,
+		decredplugin.CmdVoteSummary, vs, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vsr
+}
+
+func TestVoteSummary(t *testing.T) {
+	l, cleanup := newTestCache(t)
+	defer cleanup()
+
+	// The vote of the first record has finished, the vote of the second
+	// record is still active and the third record has no vote.
+	finished := testToken(1)
+	active := testToken(2)
+	pre := testToken(3)
+	for _, v := range []string{finished, active, pre} {
+		err := l.NewRecord(newTestRecord(v, "1",
+			cache.RecordStatusPublic, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vote := func(token string) decredplugin.Vote {
+		return decredplugin.Vote{
+			Token:            token,
+			Type:             decredplugin.VoteTypeApproval,
+			Mask:             0x03,
+			Duration:         100,
+			QuorumPercentage: 20,
+			PassPercentage:   60,
+			Options: []decredplugin.VoteOption{{
+				Id:   decredplugin.VoteOptionIDReject,
+				Bits: 0x01,
+			}, {
+				Id:   decredplugin.VoteOptionIDApprove,
+				Bits: 0x02,
+			}},
+		}
+	}
+	tickets := []string{"t1", "t2", "t3", "t4"}
+	ir := decredplugin.InventoryReply{
+		AuthorizeVotes: []decredplugin.AuthorizeVote{{
+			Action:  decredplugin.AuthVoteActionAuthorize,
+			Token:   finished,
+			Receipt: "receipt1",
+		}, {
+			Action:  decredplugin.AuthVoteActionAuthorize,
+			Token:   active,
+			Receipt: "receipt2",
+		}},
+		AuthorizeVoteReplies: []decredplugin.AuthorizeVoteReply{{
+			Action:        decredplugin.AuthVoteActionAuthorize,
+			RecordVersion: "1",
+			Receipt:       "receipt1",
+		}, {
+			Action:        decredplugin.AuthVoteActionAuthorize,
+			RecordVersion: "1",
+			Receipt:       "receipt2",
+		}},
+		StartVoteTuples: []decredplugin.StartVoteTuple{{
+			StartVote: decredplugin.StartVote{
+				Vote: vote(finished),
+			},
+			StartVoteReply: decredplugin.StartVoteReply{
+				StartBlockHeight: "0",
+				EndHeight:        "100",
+				EligibleTickets:  tickets,
+			},
+		}, {
+			StartVote: decredplugin.StartVote{
+				Vote: vote(active),
+			},
+			StartVoteReply: decredplugin.StartVoteReply{
+				StartBlockHeight: "100",
+				EndHeight:        "200",
+				EligibleTickets:  tickets,
+			},
+		}},
+		CastVotes: []decredplugin.CastVote{
+			{Token: finished, Ticket: "t1", VoteBit: "2"},
+			{Token: finished, Ticket: "t2", VoteBit: "2"},
+			{Token: finished, Ticket: "t3", VoteBit: "2"},
+			{Token: finished, Ticket: "t4", VoteBit: "1"},
+			{Token: active, Ticket: "t1", VoteBit: "1"},
+		},
+	}
+	irb, err := decredplugin.EncodeInventoryReply(ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.PluginBuild(decredplugin.ID, string(irb))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The votes are tallied before the vote results are loaded
+	wantResults := []decredplugin.VoteOptionResult{{
+		ID:    decredplugin.VoteOptionIDReject,
+		Bits:  0x01,
+		Votes: 1,
+	}, {
+		ID:    decredplugin.VoteOptionIDApprove,
+		Bits:  0x02,
+		Votes: 3,
+	}}
+	checkFinished := func() {
+		t.Helper()
+
+		vsr := voteSummary(t, l, finished)
+		if !vsr.Authorized || vsr.EndHeight != "100" ||
+			vsr.EligibleTicketCount != len(tickets) ||
+			vsr.TotalVotes != 4 || !vsr.QuorumMet || !vsr.Approved {
+			t.Fatalf("unexpected vote summary %+v", vsr)
+		}
+		if !reflect.DeepEqual(vsr.Results, wantResults) {
+			t.Fatalf("unexpected vote results got %v want %v",
+				vsr.Results, wantResults)
+		}
+	}
+	checkFinished()
+
+	vsr := voteSummary(t, l, pre)
+	if vsr.Authorized || vsr.EndHeight != "" || len(vsr.Results) != 0 {
+		t.Fatalf("unexpected vote summary %+v", vsr)
+	}
+
+	// The token inventory requires the vote results of all finished
+	// votes
+	ti, err := decredplugin.EncodeTokenInventory(decredplugin.TokenInventory{
+		BestBlock: 150,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdTokenInventory,
+		CommandPayload: string(ti),
+	})
+	if err != cache.ErrRecordNotFound {
+		t.Fatalf("unexpected error got %v want %v", err,
+			cache.ErrRecordNotFound)
+	}
+
+	// Only the vote results of finished votes are loaded
+	lvr, err := decredplugin.EncodeLoadVoteResults(
+		decredplugin.LoadVoteResults{
+			BestBlock: 150,
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execDecred(t, l, decredplugin.CmdLoadVoteResults, lvr, nil)
+	for token, want := range map[string]bool{
+		finished: true,
+		active:   false,
+		pre:      false,
+	} {
+		ok, err := l.db.Has([]byte(prefixVoteResults+token), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Fatalf("unexpected vote results %v got %v want %v",
+				token, ok, want)
+		}
+	}
+
+	// Loading the vote results again does not change them
+	execDecred(t, l, decredplugin.CmdLoadVoteResults, lvr, nil)
+	checkFinished()
+
+	tir, err := decredplugin.DecodeTokenInventoryReply([]byte(execDecred(t,
+		l, decredplugin.CmdTokenInventory, ti, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tir.Pre, []string{pre}) ||
+		!reflect.DeepEqual(tir.Active, []string{active}) ||
+		!reflect.DeepEqual(tir.Approved, []string{finished}) ||
+		len(tir.Rejected) != 0 || len(tir.Abandoned) != 0 {
+		t.Fatalf("unexpected token inventory %+v", tir)
+	}
+
+	// The active vote is tallied from the cast votes
+	vsr = voteSummary(t, l, active)
+	if !vsr.Authorized || vsr.EndHeight != "200" || vsr.TotalVotes != 1 ||
+		vsr.Approved {
+		t.Fatalf("unexpected vote summary %+v", vsr)
+	}
+}
@@ -0,0 +1,762 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package leveldbcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/politeia/politeiad/cache"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	cacheID      = "records"
+	cacheVersion = "1"
+
+	// cacheDirname is the directory of the database inside of the
+	// politeiad data directory.
+	cacheDirname = "cache"
+
+	// Key prefixes
+	prefixVersion = "version:"
+	prefixRecord  = "record:"
+)
+
+var (
+	_ cache.Cache = (*leveldbcache)(nil)
+
+	// pluginDriversMtx protects the plugin driver constructors.
+	pluginDriversMtx sync.RWMutex
+
+	// pluginDrivers contains the constructors of the cache implementations
+	// of all registered plugins.
+	pluginDrivers = make(map[string]PluginDriverConstructor) // [pluginID]constructor
+)
+
+// PluginDriverConstructor returns the cache implementation of a plugin.  The
+// plugin data is stored in the provided database under its own key prefix.
+type PluginDriverConstructor func(*leveldb.DB, cache.Plugin) cache.PluginDriver
+
+// RegisterPluginDriver makes the cache implementation of a plugin available
+// under the provided plugin identifier.  It is meant to be called from the
+// init function of the package that implements the plugin.  It panics if the
+// identifier was already registered.
+func RegisterPluginDriver(id string, c PluginDriverConstructor) {
+	pluginDriversMtx.Lock()
+	defer pluginDriversMtx.Unlock()
+
+	if _, ok := pluginDrivers[id]; ok {
+		panic(fmt.Sprintf("duplicate plugin driver: %v", id))
+	}
+	pluginDrivers[id] = c
+}
+
+// RegisteredPluginDrivers returns the sorted identifiers of the plugins that
+// have a cache implementation.
+func RegisteredPluginDrivers() []string {
+	pluginDriversMtx.RLock()
+	defer pluginDriversMtx.RUnlock()
+
+	ids := make([]string, 0, len(pluginDrivers))
+	for k := range pluginDrivers {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// version describes the version of the records cache or of a plugin cache
+// that the database is currently using.
+type version struct {
+	Version   string `json:"version"`   // Version
+	Timestamp int64  `json:"timestamp"` // UNIX timestamp of record creation
+}
+
+// leveldbcache implements the cache interface on top of an embedded leveldb
+// database.  Values are stored JSON encoded.
+type leveldbcache struct {
+	sync.RWMutex
+	shutdown bool                          // Backend is shutdown
+	root     string                        // Database directory
+	db       *leveldb.DB                   // Database context
+	plugins  map[string]cache.PluginDriver // [pluginID]PluginDriver
+}
+
+// get decodes the JSON encoded value of the provided key into v.  It returns
+// leveldb.ErrNotFound if the key does not exist.
+func get(db *leveldb.DB, key string, v interface{}) error {
+	b, err := db.Get([]byte(key), nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// put JSON encodes v and adds it to the batch under the provided key.
+func put(batch *leveldb.Batch, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	batch.Put([]byte(key), b)
+	return nil
+}
+
+// deletePrefix adds the deletion of all keys that start with the provided
+// prefix to the batch.
+func deletePrefix(db *leveldb.DB, batch *leveldb.Batch, prefix string) error {
+	iter := db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		// The iterator reuses the key buffer
+		key := make([]byte, len(iter.Key()))
+		copy(key, iter.Key())
+		batch.Delete(key)
+	}
+	return iter.Error()
+}
+
+// checkVersion looks up the version record of the provided ID and checks
+// that it matches the expected version.
+func checkVersion(db *leveldb.DB, id, want string) error {
+	var v version
+	err := get(db, prefixVersion+id, &v)
+	if err == leveldb.ErrNotFound {
+		log.Debugf("version record not found for ID '%v'", id)
+		return cache.ErrNoVersionRecord
+	} else if err != nil {
+		return err
+	}
+	if v.Version != want {
+		log.Debugf("version mismatch for ID '%v': got %v, want %v",
+			id, v.Version, want)
+		return cache.ErrWrongVersion
+	}
+	return nil
+}
+
+// setupVersion inserts a version record for the provided ID if one does not
+// already exist.
+func setupVersion(db *leveldb.DB, id, v string) error {
+	ok, err := db.Has([]byte(prefixVersion+id), nil)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	batch := new(leveldb.Batch)
+	err = put(batch, prefixVersion+id, version{
+		Version:   v,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return db.Write(batch, nil)
+}
+
+// recordKey returns the key of a record version.  The version is zero padded
+// so that the versions of a record are ordered.
+func recordKey(token string, v uint64) string {
+	return fmt.Sprintf("%v%v:%020d", prefixRecord, token, v)
+}
+
+// recordKeyFromCache returns the key of the passed in record.
+func recordKeyFromCache(r cache.Record) (string, error) {
+	v, err := strconv.ParseUint(r.Version, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("parse version '%v' failed: %v",
+			r.Version, err)
+	}
+	return recordKey(r.CensorshipRecord.Token, v), nil
+}
+
+// recordVersion gets the specified version of a record from the database.
+func recordVersion(db *leveldb.DB, token, version string) (*cache.Record, error) {
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return nil, cache.ErrRecordNotFound
+	}
+
+	var r cache.Record
+	err = get(db, recordKey(token, v), &r)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			err = cache.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// record gets the most recent version of a record from the database.
+func record(db *leveldb.DB, token string) (*cache.Record, error) {
+	prefix := util.BytesPrefix([]byte(prefixRecord + token + ":"))
+	iter := db.NewIterator(prefix, nil)
+	defer iter.Release()
+
+	if !iter.Last() {
+		err := iter.Error()
+		if err == nil {
+			err = cache.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	var r cache.Record
+	err := json.Unmarshal(iter.Value(), &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// latestRecords returns the most recent version of all records in the
+// database.  The records are ordered by token.
+func latestRecords(db *leveldb.DB) ([]cache.Record, error) {
+	iter := db.NewIterator(util.BytesPrefix([]byte(prefixRecord)), nil)
+	defer iter.Release()
+
+	records := make([]cache.Record, 0, 1024) // PNOOMA
+	add := func(b []byte) error {
+		var r cache.Record
+		err := json.Unmarshal(b, &r)
+		if err != nil {
+			return err
+		}
+		records = append(records, r)
+		return nil
+	}
+
+	// The versions of a record are ordered so only the last value of
+	// every token is decoded.
+	var (
+		token string
+		value []byte
+	)
+	for iter.Next() {
+		key := strings.TrimPrefix(string(iter.Key()), prefixRecord)
+		t := key[:strings.LastIndex(key, ":")]
+		if value != nil && t != token {
+			err := add(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		token = t
+		value = append(value[:0], iter.Value()...)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	if value != nil {
+		err := add(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return records, nil
+}
+
+// indexRecord passes the most recent version of a record to the plugins that
+// index record content.
+//
+// This function must be called with the lock held.
+func (l *leveldbcache) indexRecord(r cache.Record) error {
+	for id, p := range l.plugins {
+		pi, ok := p.(cache.PluginIndexer)
+		if !ok {
+			continue
+		}
+		err := pi.IndexRecord(r)
+		if err != nil {
+			return fmt.Errorf("index %v %v: %v", id,
+				r.CensorshipRecord.Token, err)
+		}
+	}
+	return nil
+}
+
+// NewRecord creates a new entry in the database for the passed in record.
+func (l *leveldbcache) NewRecord(cr cache.Record) error {
+	log.Tracef("NewRecord: %v", cr.CensorshipRecord.Token)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	key, err := recordKeyFromCache(cr)
+	if err != nil {
+		return err
+	}
+	ok, err := l.db.Has([]byte(key), nil)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return fmt.Errorf("record exists: %v %v",
+			cr.CensorshipRecord.Token, cr.Version)
+	}
+
+	batch := new(leveldb.Batch)
+	err = put(batch, key, cr)
+	if err != nil {
+		return err
+	}
+	err = l.db.Write(batch, nil)
+	if err != nil {
+		return err
+	}
+
+	return l.indexRecord(cr)
+}
+
+// RecordVersion gets the specified version of a record from the database.
+func (l *leveldbcache) RecordVersion(token, version string) (*cache.Record, error) {
+	log.Tracef("RecordVersion: %v %v", token, version)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	return recordVersion(l.db, token, version)
+}
+
+// Record gets the most recent version of a record from the database.
+func (l *leveldbcache) Record(token string) (*cache.Record, error) {
+	log.Tracef("Record: %v", token)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	return record(l.db, token)
+}
+
+// UpdateRecord updates a record in the database.  This replaces the record
+// along with its metadata streams and files.
+func (l *leveldbcache) UpdateRecord(r cache.Record) error {
+	log.Tracef("UpdateRecord: %v %v", r.CensorshipRecord.Token, r.Version)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	// Ensure record exists
+	_, err := recordVersion(l.db, r.CensorshipRecord.Token, r.Version)
+	if err != nil {
+		return err
+	}
+
+	key, err := recordKeyFromCache(r)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	err = put(batch, key, r)
+	if err != nil {
+		return err
+	}
+	err = l.db.Write(batch, nil)
+	if err != nil {
+		return err
+	}
+
+	return l.indexRecord(r)
+}
+
+// UpdateRecordStatus updates the status of a record in the database.  This
+// includes an update to the record as well as replacing the existing record
+// metadata streams with the passed in metadata streams.
+func (l *leveldbcache) UpdateRecordStatus(token, version string, status cache.RecordStatusT, timestamp int64, metadata []cache.MetadataStream) error {
+	log.Tracef("UpdateRecordStatus: %v %v", token, status)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	r, err := recordVersion(l.db, token, version)
+	if err != nil {
+		return err
+	}
+	r.Status = status
+	r.Timestamp = timestamp
+	r.Metadata = metadata
+
+	key, err := recordKeyFromCache(*r)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	err = put(batch, key, r)
+	if err != nil {
+		return err
+	}
+	return l.db.Write(batch, nil)
+}
+
+// UpdateRecordMetadata replaces the metadata streams of the most recent
+// version of the given record with the passed in metadata streams.
+func (l *leveldbcache) UpdateRecordMetadata(token string, ms []cache.MetadataStream) error {
+	log.Tracef("UpdateRecordMetadata: %v", token)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	r, err := record(l.db, token)
+	if err != nil {
+		return err
+	}
+	r.Metadata = ms
+
+	key, err := recordKeyFromCache(*r)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	err = put(batch, key, r)
+	if err != nil {
+		return err
+	}
+	return l.db.Write(batch, nil)
+}
+
+// Records gets the most recent versions of a list of records.  Records that
+// are not found are left out of the reply.
+func (l *leveldbcache) Records(tokens []string, fetchFiles bool) ([]cache.Record, error) {
+	log.Tracef("Records: %v", tokens)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	records := make([]cache.Record, 0, len(tokens))
+	for _, v := range tokens {
+		r, err := record(l.db, v)
+		if err == cache.ErrRecordNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !fetchFiles {
+			r.Files = []cache.File{}
+		}
+		records = append(records, *r)
+	}
+
+	return records, nil
+}
+
+// Inventory returns the latest version of all records from the database.
+func (l *leveldbcache) Inventory() ([]cache.Record, error) {
+	log.Tracef("Inventory")
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	return latestRecords(l.db)
+}
+
+// InventoryPage returns a filtered page of the latest version of all records
+// and the cursor of the next page.
+func (l *leveldbcache) InventoryPage(f cache.InventoryFilter) ([]cache.Record, string, error) {
+	log.Tracef("InventoryPage: %v %v", f.State, f.Cursor)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, "", cache.ErrShutdown
+	}
+
+	records, err := latestRecords(l.db)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return cache.FilterInventory(records, f)
+}
+
+// InventoryStats compiles summary statistics on the number of records in the
+// database grouped by record status.  Only the latest version of each record
+// is included in the statistics.
+func (l *leveldbcache) InventoryStats() (*cache.InventoryStats, error) {
+	log.Tracef("InventoryStats")
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	records, err := latestRecords(l.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var is cache.InventoryStats
+	for _, r := range records {
+		switch r.Status {
+		case cache.RecordStatusNotReviewed:
+			is.NotReviewed++
+		case cache.RecordStatusCensored:
+			is.Censored++
+		case cache.RecordStatusPublic:
+			is.Public++
+		case cache.RecordStatusUnreviewedChanges:
+			is.UnreviewedChanges++
+		case cache.RecordStatusArchived:
+			is.Archived++
+		default:
+			is.Invalid++
+		}
+	}
+
+	return &is, nil
+}
+
+func (l *leveldbcache) getPlugin(id string) (cache.PluginDriver, error) {
+	l.RLock()
+	defer l.RUnlock()
+	plugin, ok := l.plugins[id]
+	if !ok {
+		return nil, cache.ErrInvalidPlugin
+	}
+	return plugin, nil
+}
+
+// PluginExec is a pass through function for plugin commands.
+func (l *leveldbcache) PluginExec(pc cache.PluginCommand) (*cache.PluginCommandReply, error) {
+	log.Tracef("PluginExec: %v", pc.ID)
+
+	l.RLock()
+	shutdown := l.shutdown
+	l.RUnlock()
+
+	if shutdown {
+		return nil, cache.ErrShutdown
+	}
+
+	plugin, err := l.getPlugin(pc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := plugin.Exec(pc.Command, pc.CommandPayload,
+		pc.ReplyPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cache.PluginCommandReply{
+		ID:      pc.ID,
+		Command: pc.Command,
+		Payload: payload,
+	}, nil
+}
+
+// PluginSetup sets up the database for the passed in plugin.
+func (l *leveldbcache) PluginSetup(id string) error {
+	log.Tracef("PluginSetup: %v", id)
+
+	l.RLock()
+	shutdown := l.shutdown
+	l.RUnlock()
+
+	if shutdown {
+		return cache.ErrShutdown
+	}
+
+	plugin, err := l.getPlugin(id)
+	if err != nil {
+		return err
+	}
+
+	return plugin.Setup()
+}
+
+// RegisterPlugin registers a plugin with the cache and checks to make sure
+// that the cache is using the correct plugin version.
+func (l *leveldbcache) RegisterPlugin(p cache.Plugin) error {
+	log.Tracef("RegisterPlugin: %v", p.ID)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	_, ok := l.plugins[p.ID]
+	if ok {
+		return cache.ErrDuplicatePlugin
+	}
+
+	// Register the plugin
+	pluginDriversMtx.RLock()
+	newPluginDriver, ok := pluginDrivers[p.ID]
+	pluginDriversMtx.RUnlock()
+	if !ok {
+		return cache.ErrInvalidPlugin
+	}
+	pd := newPluginDriver(l.db, p)
+	l.plugins[p.ID] = pd
+
+	// Ensure we're using the correct plugin version
+	return pd.CheckVersion()
+}
+
+// PluginBuild builds the cache for the passed in plugin.
+func (l *leveldbcache) PluginBuild(id, payload string) error {
+	log.Tracef("PluginBuild: %v", id)
+
+	l.RLock()
+	shutdown := l.shutdown
+	l.RUnlock()
+
+	if shutdown {
+		return cache.ErrShutdown
+	}
+
+	plugin, err := l.getPlugin(id)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Building plugin cache: %v", id)
+
+	return plugin.Build(payload)
+}
+
+// Setup inserts a version record for the records cache if one does not
+// already exist.
+func (l *leveldbcache) Setup() error {
+	log.Tracef("Setup")
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	return setupVersion(l.db, cacheID, cacheVersion)
+}
+
+// Build removes all existing records from the cache then builds the records
+// cache using the passed in records.  The cache is replaced in a single
+// atomic batch so a failed build leaves the existing cache untouched.
+func (l *leveldbcache) Build(records []cache.Record) error {
+	log.Tracef("Build")
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return cache.ErrShutdown
+	}
+
+	log.Infof("Building records cache")
+
+	batch := new(leveldb.Batch)
+	err := deletePrefix(l.db, batch, prefixRecord)
+	if err != nil {
+		return fmt.Errorf("delete records: %v", err)
+	}
+	for _, r := range records {
+		key, err := recordKeyFromCache(r)
+		if err != nil {
+			return fmt.Errorf("%v: %v", r.CensorshipRecord.Token, err)
+		}
+		err = put(batch, key, r)
+		if err != nil {
+			return fmt.Errorf("put record %v: %v", key, err)
+		}
+	}
+	err = put(batch, prefixVersion+cacheID, version{
+		Version:   cacheVersion,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return l.db.Write(batch, nil)
+}
+
+// Close shuts down the cache.  All interface functions MUST return with
+// errShutdown if the backend is shutting down.
+func (l *leveldbcache) Close() {
+	log.Tracef("Close")
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.shutdown = true
+	l.db.Close()
+}
+
+// New returns a new leveldbcache context.  The database is created inside of
+// the provided data directory if it does not exist yet.  An error is returned
+// along with the context when the version record is missing or does not match
+// so that the cache can be built or rebuilt.
+func New(dataDir string) (*leveldbcache, error) {
+	log.Tracef("New: %v", dataDir)
+
+	root := filepath.Join(dataDir, cacheDirname)
+	db, err := leveldb.OpenFile(root, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open database '%v': %v", root, err)
+	}
+
+	l := &leveldbcache{
+		root:    root,
+		db:      db,
+		plugins: make(map[string]cache.PluginDriver),
+	}
+
+	log.Infof("Cache database: %v", root)
+
+	return l, checkVersion(l.db, cacheID, cacheVersion)
+}
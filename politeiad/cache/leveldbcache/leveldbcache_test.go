@@ -0,0 +1,285 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package leveldbcache
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/cache"
+)
+
+// newTestCache returns a leveldbcache with the records cache and the decred
+// plugin cache set up in a temporary directory.
+func newTestCache(t *testing.T) (*leveldbcache, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "leveldbcache.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := New(dir)
+	if err != cache.ErrNoVersionRecord {
+		t.Fatalf("unexpected error got %v want %v", err,
+			cache.ErrNoVersionRecord)
+	}
+	err = l.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.RegisterPlugin(cache.Plugin{
+		ID:      decredplugin.ID,
+		Version: decredplugin.Version,
+	})
+	if err != cache.ErrNoVersionRecord {
+		t.Fatalf("unexpected error got %v want %v", err,
+			cache.ErrNoVersionRecord)
+	}
+	err = l.PluginSetup(decredplugin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// testToken returns a censorship token for the provided index.
+func testToken(i int) string {
+	return fmt.Sprintf("%064x", i)
+}
+
+// newTestRecord returns a record with an index file.
+func newTestRecord(token, version string, status cache.RecordStatusT, timestamp int64) cache.Record {
+	return cache.Record{
+		Version:   version,
+		Status:    status,
+		Timestamp: timestamp,
+		CensorshipRecord: cache.CensorshipRecord{
+			Token: token,
+		},
+		Metadata: []cache.MetadataStream{},
+		Files: []cache.File{{
+			Name: decredplugin.IndexFile,
+			MIME: "text/plain; charset=utf-8",
+			Payload: base64.StdEncoding.EncodeToString(
+				[]byte("Proposal " + token + "\nversion " +
+					version)),
+		}},
+	}
+}
+
+// recordTokens returns the tokens of the passed in records.
+func recordTokens(records []cache.Record) []string {
+	tokens := make([]string, 0, len(records))
+	for _, v := range records {
+		tokens = append(tokens, v.CensorshipRecord.Token)
+	}
+	return tokens
+}
+
+func TestInventory(t *testing.T) {
+	l, cleanup := newTestCache(t)
+	defer cleanup()
+
+	// Records are not added in token order. The record of token 1
+	// has two versions.
+	records := []cache.Record{
+		newTestRecord(testToken(4), "1", cache.RecordStatusCensored, 150),
+		newTestRecord(testToken(1), "1", cache.RecordStatusNotReviewed, 100),
+		newTestRecord(testToken(1), "2",
+			cache.RecordStatusUnreviewedChanges, 200),
+		newTestRecord(testToken(3), "1", cache.RecordStatusPublic, 300),
+		newTestRecord(testToken(5), "1", cache.RecordStatusArchived, 250),
+		newTestRecord(testToken(2), "1", cache.RecordStatusPublic, 300),
+	}
+	for _, v := range records {
+		err := l.NewRecord(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := l.NewRecord(records[0])
+	if err == nil {
+		t.Fatalf("expected duplicate record error")
+	}
+
+	// Inventory returns the latest version of all records ordered by
+	// token
+	inv, err := l.Inventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{testToken(1), testToken(2), testToken(3),
+		testToken(4), testToken(5)}
+	if !reflect.DeepEqual(recordTokens(inv), want) {
+		t.Fatalf("unexpected inventory got %v want %v",
+			recordTokens(inv), want)
+	}
+	if inv[0].Version != "2" ||
+		inv[0].Status != cache.RecordStatusUnreviewedChanges {
+		t.Fatalf("unexpected latest version %v status %v",
+			inv[0].Version, inv[0].Status)
+	}
+
+	is, err := l.InventoryStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStats := cache.InventoryStats{
+		Censored:          1,
+		Public:            2,
+		UnreviewedChanges: 1,
+		Archived:          1,
+	}
+	if *is != wantStats {
+		t.Fatalf("unexpected inventory stats got %+v want %+v", *is,
+			wantStats)
+	}
+
+	tests := []struct {
+		name       string
+		filter     cache.InventoryFilter
+		want       []string
+		wantCursor bool
+		wantErr    error
+	}{
+		{
+			name: "vetted first page",
+			filter: cache.InventoryFilter{
+				State: cache.InventoryStateVetted,
+				Limit: 2,
+			},
+			want:       []string{testToken(2), testToken(3)},
+			wantCursor: true,
+		},
+		{
+			name: "vetted last page",
+			filter: cache.InventoryFilter{
+				State:  cache.InventoryStateVetted,
+				Cursor: cache.InventoryCursor(inv[2]),
+				Limit:  2,
+			},
+			want: []string{testToken(5)},
+		},
+		{
+			name: "vetted reverse page",
+			filter: cache.InventoryFilter{
+				State:   cache.InventoryStateVetted,
+				Cursor:  cache.InventoryCursor(inv[4]),
+				Limit:   2,
+				Reverse: true,
+			},
+			want: []string{testToken(3), testToken(2)},
+		},
+		{
+			name: "unvetted",
+			filter: cache.InventoryFilter{
+				State: cache.InventoryStateUnvetted,
+			},
+			want: []string{testToken(1), testToken(4)},
+		},
+		{
+			name: "status",
+			filter: cache.InventoryFilter{
+				Statuses: []cache.RecordStatusT{
+					cache.RecordStatusCensored,
+					cache.RecordStatusArchived,
+				},
+			},
+			want: []string{testToken(5), testToken(4)},
+		},
+		{
+			name: "timestamp range",
+			filter: cache.InventoryFilter{
+				FromTimestamp:  150,
+				UntilTimestamp: 250,
+			},
+			want: []string{testToken(1), testToken(4)},
+		},
+		{
+			name: "invalid cursor",
+			filter: cache.InventoryFilter{
+				Cursor: "invalid",
+			},
+			wantErr: cache.ErrInvalidCursor,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, cursor, err := l.InventoryPage(test.filter)
+			if err != test.wantErr {
+				t.Fatalf("unexpected error got %v want %v", err,
+					test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(recordTokens(page), test.want) {
+				t.Fatalf("unexpected page got %v want %v",
+					recordTokens(page), test.want)
+			}
+			if (cursor != "") != test.wantCursor {
+				t.Fatalf("unexpected cursor %q", cursor)
+			}
+			for _, v := range page {
+				if len(v.Files) != 0 {
+					t.Fatalf("unexpected files %v",
+						v.CensorshipRecord.Token)
+				}
+			}
+		})
+	}
+
+	// The next page continues after the cursor of the first page
+	_, cursor, err := l.InventoryPage(cache.InventoryFilter{
+		State: cache.InventoryStateVetted,
+		Limit: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _, err := l.InventoryPage(cache.InventoryFilter{
+		State:        cache.InventoryStateVetted,
+		Cursor:       cursor,
+		Limit:        2,
+		IncludeFiles: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recordTokens(page), []string{testToken(5)}) {
+		t.Fatalf("unexpected next page %v", recordTokens(page))
+	}
+	if len(page[0].Files) != 1 {
+		t.Fatalf("expected record files")
+	}
+
+	// A status change is reflected in the inventory
+	err = l.UpdateRecordStatus(testToken(2), "1",
+		cache.RecordStatusArchived, 400, []cache.MetadataStream{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _, err = l.InventoryPage(cache.InventoryFilter{
+		Statuses: []cache.RecordStatusT{cache.RecordStatusArchived},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{testToken(2), testToken(5)}
+	if !reflect.DeepEqual(recordTokens(page), want) {
+		t.Fatalf("unexpected archived records got %v want %v",
+			recordTokens(page), want)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// SearchQueryMaxLength is the maximum length of a search query in
+	// bytes.
+	SearchQueryMaxLength = 256
+
+	// SearchQueryMaxWords is the maximum number of words, including the
+	// words of the phrases, in a search query.
+	SearchQueryMaxWords = 16
+)
+
+// Search index sources.  The name of a record is the first line of its index
+// file.
+const (
+	SearchSourceName    = "name"    // Record name
+	SearchSourceIndex   = "index"   // Record index file
+	SearchSourceComment = "comment" // Non-censored comment
+)
+
+var (
+	// ErrInvalidSearchQuery is emitted when a search query can not be
+	// parsed.
+	ErrInvalidSearchQuery = errors.New("invalid search query")
+)
+
+// SearchQuery is a parsed search query.  A text matches the query when it
+// contains all of the terms and all of the phrases.  Matching ignores case
+// and punctuation.
+type SearchQuery struct {
+	Terms   []string   // Words that must be present
+	Phrases [][]string // Word sequences that must be present in order
+}
+
+// SearchStatus returns true if a record with the provided status is searched
+// when a search is limited to the provided statuses.  Only vetted records are
+// searched.  An empty list of statuses selects all vetted records.
+func SearchStatus(s RecordStatusT, statuses []int) bool {
+	if !IsVettedStatus(s) {
+		return false
+	}
+	if len(statuses) == 0 {
+		return true
+	}
+	for _, v := range statuses {
+		if RecordStatusT(v) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchWords splits a text into lower case words.  Any character that is not
+// a letter or a digit separates words.
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchTerms returns the distinct words of a text in ascending order.  These
+// are the words under which the text is added to a search index.
+func SearchTerms(text string) []string {
+	words := SearchWords(text)
+	sort.Strings(words)
+	terms := make([]string, 0, len(words))
+	for i, v := range words {
+		if i > 0 && words[i-1] == v {
+			continue
+		}
+		terms = append(terms, v)
+	}
+	return terms
+}
+
+// ParseSearchQuery parses a search query.  Words that are surrounded by double
+// quotes are matched as a phrase, all other words are matched as terms.
+func ParseSearchQuery(query string) (*SearchQuery, error) {
+	if len(query) > SearchQueryMaxLength {
+		return nil, ErrInvalidSearchQuery
+	}
+	if strings.Count(query, `"`)%2 != 0 {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	var (
+		q     SearchQuery
+		words int
+	)
+	for i, s := range strings.Split(query, `"`) {
+		w := SearchWords(s)
+		words += len(w)
+		switch {
+		case len(w) == 0:
+			// Nothing to match
+		case i%2 == 0:
+			q.Terms = append(q.Terms, w...)
+		case len(w) == 1:
+			q.Terms = append(q.Terms, w[0])
+		default:
+			q.Phrases = append(q.Phrases, w)
+		}
+	}
+	if words == 0 || words > SearchQueryMaxWords {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	return &q, nil
+}
+
+// Words returns the distinct words of the terms and of the phrases of the
+// query in ascending order.  A text can only match the query when it contains
+// all of these words.
+func (q *SearchQuery) Words() []string {
+	words := strings.Join(q.Terms, " ")
+	for _, v := range q.Phrases {
+		words += " " + strings.Join(v, " ")
+	}
+	return SearchTerms(words)
+}
+
+// HasPhrases returns true if the query contains phrases.  Search indexes do
+// not record the order of the words so phrases must be matched against the
+// text itself.
+func (q *SearchQuery) HasPhrases() bool {
+	return len(q.Phrases) > 0
+}
+
+// Match returns true if the text contains all of the terms and all of the
+// phrases of the query.
+func (q *SearchQuery) Match(text string) bool {
+	words := SearchWords(text)
+
+	present := make(map[string]struct{}, len(words))
+	for _, v := range words {
+		present[v] = struct{}{}
+	}
+	for _, v := range q.Terms {
+		if _, ok := present[v]; !ok {
+			return false
+		}
+	}
+
+	for _, phrase := range q.Phrases {
+		if !matchPhrase(words, phrase) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchPhrase returns true if the phrase appears as consecutive words.
+func matchPhrase(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		found := true
+		for j, v := range phrase {
+			if words[i+j] != v {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordText returns the name and the text of the named base64 encoded file
+// of a record.  The name is the first line of the file.  It returns false when
+// the record does not have the file.
+func RecordText(r Record, filename string) (string, string, bool, error) {
+	for _, v := range r.Files {
+		if v.Name != filename {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			return "", "", false, err
+		}
+		line, _, err := bufio.NewReader(bytes.NewReader(b)).ReadLine()
+		if err != nil && err != io.EOF {
+			return "", "", false, err
+		}
+		return string(line), string(b), true, nil
+	}
+	return "", "", false, nil
+}
+
+// MatchFile matches the query against the named base64 encoded file of a
+// record.  It returns whether the first line of the file and whether the
+// entire file matched.  Both are false when the record does not have the file.
+func (q *SearchQuery) MatchFile(r Record, filename string) (bool, bool, error) {
+	name, text, ok, err := RecordText(r, filename)
+	if err != nil || !ok {
+		return false, false, err
+	}
+	return q.Match(name), q.Match(text), nil
+}
@@ -5,6 +5,8 @@
 package testcache
 
 import (
+	"sort"
+
 	decred "github.com/decred/politeia/decredplugin"
 	"github.com/decred/politeia/politeiad/cache"
 )
@@ -77,6 +79,122 @@ func (c *testcache) startVote(cmdPayload, replyPayload string) (string, error) {
 	return replyPayload, nil
 }
 
+func (c *testcache) scheduleVote(cmdPayload, replyPayload string) (string, error) {
+	sv, err := decred.DecodeScheduleVote([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+
+	svr, err := decred.DecodeScheduleVoteReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	sv.Receipt = svr.Receipt
+	sv.Timestamp = svr.Timestamp
+
+	c.Lock()
+	defer c.Unlock()
+
+	// A cancelled or failed schedule keeps the start vote of the
+	// schedule
+	if sv.Action != decred.ScheduleVoteActionSchedule {
+		sv.StartVote = c.scheduleVotes[sv.Token].StartVote
+	}
+	c.scheduleVotes[sv.Token] = *sv
+
+	return replyPayload, nil
+}
+
+func (c *testcache) scheduledVotes(payload string) (string, error) {
+	_, err := decred.DecodeScheduledVotes([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	svs := make([]decred.ScheduleVote, 0, len(c.scheduleVotes))
+	for token, v := range c.scheduleVotes {
+		if v.Action != decred.ScheduleVoteActionSchedule {
+			continue
+		}
+		if _, ok := c.startVotes[token]; ok {
+			continue
+		}
+		svs = append(svs, v)
+	}
+	sort.Slice(svs, func(i, j int) bool {
+		return svs[i].StartHeight < svs[j].StartHeight
+	})
+
+	svrb, err := decred.EncodeScheduledVotesReply(
+		decred.ScheduledVotesReply{
+			ScheduleVotes: svs,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(svrb), nil
+}
+
+func (c *testcache) startVoteRunoff(cmdPayload, replyPayload string) (string, error) {
+	svr, err := decred.DecodeStartVoteRunoff([]byte(cmdPayload))
+	if err != nil {
+		return "", err
+	}
+
+	svrr, err := decred.DecodeStartVoteRunoffReply([]byte(replyPayload))
+	if err != nil {
+		return "", err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// Store start vote data of the submissions
+	tokens := make([]string, 0, len(svr.StartVotes))
+	for _, v := range svr.StartVotes {
+		c.startVotes[v.Vote.Token] = v
+		c.startVoteReplies[v.Vote.Token] = svrr.StartVoteReply
+		tokens = append(tokens, v.Vote.Token)
+	}
+
+	// Store runoff
+	c.voteRunoffs[svr.Token] = decred.VoteRunoff{
+		Version:          decred.VersionVoteRunoff,
+		Token:            svr.Token,
+		Tokens:           tokens,
+		StartBlockHeight: svrr.StartVoteReply.StartBlockHeight,
+		StartBlockHash:   svrr.StartVoteReply.StartBlockHash,
+		EndHeight:        svrr.StartVoteReply.EndHeight,
+	}
+
+	return replyPayload, nil
+}
+
+func (c *testcache) voteRunoffDetails(payload string) (string, error) {
+	vrd, err := decred.DecodeVoteRunoffDetails([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	vrdb, err := decred.EncodeVoteRunoffDetailsReply(
+		decred.VoteRunoffDetailsReply{
+			VoteRunoff: c.voteRunoffs[vrd.Token],
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(vrdb), nil
+}
+
 func (c *testcache) voteDetails(payload string) (string, error) {
 	vd, err := decred.DecodeVoteDetails([]byte(payload))
 	if err != nil {
@@ -101,6 +219,7 @@ func (c *testcache) voteDetails(payload string) (string, error) {
 	vdb, err := decred.EncodeVoteDetailsReply(
 		decred.VoteDetailsReply{
 			AuthorizeVote:  c.authorizeVotes[vd.Token][r.Version],
+			ScheduleVote:   c.scheduleVotes[vd.Token],
 			StartVote:      c.startVotes[vd.Token],
 			StartVoteReply: c.startVoteReplies[vd.Token],
 		})
@@ -111,16 +230,110 @@ func (c *testcache) voteDetails(payload string) (string, error) {
 	return string(vdb), nil
 }
 
+func (c *testcache) search(payload string) (string, error) {
+	s, err := decred.DecodeSearch([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	q, err := cache.ParseSearchQuery(s.Query)
+	if err != nil {
+		return "", err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	var after *cache.Record
+	if s.After != "" {
+		after, err = c.record(s.After)
+		if err != nil {
+			return "", err
+		}
+		if !cache.IsVettedStatus(after.Status) {
+			return "", cache.ErrRecordNotFound
+		}
+	}
+
+	results := make([]decred.SearchResult, 0, len(c.records))
+	for token := range c.records {
+		r, err := c.record(token)
+		if err != nil {
+			return "", err
+		}
+		if !cache.SearchStatus(r.Status, s.Statuses) {
+			continue
+		}
+		name, index, err := q.MatchFile(*r, decred.IndexFile)
+		if err != nil {
+			return "", err
+		}
+		ids := make([]string, 0, len(c.comments[token]))
+		for _, v := range c.comments[token] {
+			if !v.Censored && q.Match(v.Comment) {
+				ids = append(ids, v.CommentID)
+			}
+		}
+		if !name && !index && len(ids) == 0 {
+			continue
+		}
+		results = append(results, decred.SearchResult{
+			Token:      token,
+			Timestamp:  r.Timestamp,
+			Name:       name,
+			Index:      index,
+			CommentIDs: ids,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Timestamp != results[j].Timestamp {
+			return results[i].Timestamp > results[j].Timestamp
+		}
+		return results[i].Token < results[j].Token
+	})
+	if after != nil {
+		i := sort.Search(len(results), func(i int) bool {
+			if results[i].Timestamp != after.Timestamp {
+				return results[i].Timestamp < after.Timestamp
+			}
+			return results[i].Token > after.CensorshipRecord.Token
+		})
+		results = results[i:]
+	}
+	if s.Limit > 0 && len(results) > s.Limit {
+		results = results[:s.Limit]
+	}
+
+	srb, err := decred.EncodeSearchReply(
+		decred.SearchReply{
+			Results: results,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(srb), nil
+}
+
 func (c *testcache) decredExec(cmd, cmdPayload, replyPayload string) (string, error) {
 	switch cmd {
 	case decred.CmdGetComments:
 		return c.getComments(cmdPayload)
 	case decred.CmdAuthorizeVote:
 		return c.authorizeVote(cmdPayload, replyPayload)
+	case decred.CmdScheduleVote:
+		return c.scheduleVote(cmdPayload, replyPayload)
+	case decred.CmdScheduledVotes:
+		return c.scheduledVotes(cmdPayload)
 	case decred.CmdStartVote:
 		return c.startVote(cmdPayload, replyPayload)
+	case decred.CmdStartVoteRunoff:
+		return c.startVoteRunoff(cmdPayload, replyPayload)
 	case decred.CmdVoteDetails:
 		return c.voteDetails(cmdPayload)
+	case decred.CmdVoteRunoffDetails:
+		return c.voteRunoffDetails(cmdPayload)
+	case decred.CmdSearch:
+		return c.search(cmdPayload)
 	}
 
 	return "", cache.ErrInvalidPluginCmd
@@ -24,6 +24,8 @@ type testcache struct {
 	authorizeVotes   map[string]map[string]decred.AuthorizeVote // [token][version]AuthorizeVote
 	startVotes       map[string]decred.StartVote                // [token]StartVote
 	startVoteReplies map[string]decred.StartVoteReply           // [token]StartVoteReply
+	voteRunoffs      map[string]decred.VoteRunoff               // [rfpToken]VoteRunoff
+	scheduleVotes    map[string]decred.ScheduleVote             // [token]ScheduleVote
 }
 
 // NewRecords adds a record to the cache.
@@ -149,8 +151,22 @@ func (c *testcache) UpdateRecordStatus(token, version string, status cache.Recor
 	return nil
 }
 
-// UpdateRecordMetadata is a stub to satisfy the cache interface.
+// UpdateRecordMetadata replaces the metadata streams of the most recent
+// version of a record.
 func (c *testcache) UpdateRecordMetadata(token string, md []cache.MetadataStream) error {
+	c.Lock()
+	defer c.Unlock()
+
+	// Lookup record
+	r, err := c.record(token)
+	if err != nil {
+		return err
+	}
+
+	// Update record
+	r.Metadata = md
+	c.records[token][r.Version] = *r
+
 	return nil
 }
 
@@ -159,6 +175,24 @@ func (c *testcache) Inventory() ([]cache.Record, error) {
 	return make([]cache.Record, 0), nil
 }
 
+// InventoryPage returns a filtered page of the most recent version of all
+// records.
+func (c *testcache) InventoryPage(f cache.InventoryFilter) ([]cache.Record, string, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	records := make([]cache.Record, 0, len(c.records))
+	for token := range c.records {
+		r, err := c.record(token)
+		if err != nil {
+			return nil, "", err
+		}
+		records = append(records, *r)
+	}
+
+	return cache.FilterInventory(records, f)
+}
+
 // InventoryStats is a stub to satisfy the cache interface.
 func (c *testcache) InventoryStats() (*cache.InventoryStats, error) {
 	return &cache.InventoryStats{}, nil
@@ -218,5 +252,7 @@ func New() *testcache {
 		authorizeVotes:   make(map[string]map[string]decred.AuthorizeVote),
 		startVotes:       make(map[string]decred.StartVote),
 		startVoteReplies: make(map[string]decred.StartVoteReply),
+		voteRunoffs:      make(map[string]decred.VoteRunoff),
+		scheduleVotes:    make(map[string]decred.ScheduleVote),
 	}
 }
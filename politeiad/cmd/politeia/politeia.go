@@ -66,6 +66,10 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "\n actions:\n")
 	fmt.Fprintf(os.Stderr, "  identity          - Retrieve server "+
 		"identity\n")
+	fmt.Fprintf(os.Stderr, "  identityhistory   - Retrieve server "+
+		"identity history\n")
+	fmt.Fprintf(os.Stderr, "  rotateidentity    - Replace server "+
+		"identity\n")
 	fmt.Fprintf(os.Stderr, "  plugins           - Retrieve plugin "+
 		"inventory\n")
 	fmt.Fprintf(os.Stderr, "  inventory         - Inventory records "+
@@ -427,6 +431,155 @@ func inventory() error {
 	return nil
 }
 
+// postAuth sends an authenticated request to politeiad and returns the reply
+// body.
+func postAuth(route string, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if *printJson {
+		fmt.Println(string(b))
+	}
+
+	c, err := util.NewClient(verify, *rpccert)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", *rpchost+route,
+		bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(*rpcuser, *rpcpass)
+	r, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		e, err := getErrorFromResponse(r)
+		if err != nil {
+			return nil, fmt.Errorf("%v", r.Status)
+		}
+		return nil, fmt.Errorf("%v: %v", r.Status, e)
+	}
+
+	return util.ConvertBodyToByteArray(r.Body, *printJson), nil
+}
+
+func printIdentityKey(k v1.IdentityKey) {
+	fmt.Printf("  Public key : %v\n", k.PublicKey)
+	fmt.Printf("  Valid from : %v\n", k.ValidFrom)
+	if k.ValidUntil != 0 {
+		fmt.Printf("  Valid until: %v\n", k.ValidUntil)
+	}
+}
+
+func identityHistory() error {
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	bodyBytes, err := postAuth(v1.IdentityHistoryRoute, v1.IdentityHistory{
+		Challenge: hex.EncodeToString(challenge),
+	})
+	if err != nil {
+		return err
+	}
+
+	var ihr v1.IdentityHistoryReply
+	err = json.Unmarshal(bodyBytes, &ihr)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal IdentityHistoryReply: %v",
+			err)
+	}
+
+	// The pinned identity must authenticate the history
+	id, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+	err = v1.VerifyIdentityHistory(ihr.Keys, *id)
+	if err != nil {
+		return err
+	}
+	active, err := v1.IdentityKeyPublic(ihr.Keys[len(ihr.Keys)-1])
+	if err != nil {
+		return err
+	}
+	err = util.VerifyChallenge(active, challenge, ihr.Response)
+	if err != nil {
+		return err
+	}
+
+	if !*printJson {
+		for k, v := range ihr.Keys {
+			fmt.Printf("Key %v:\n", k)
+			printIdentityKey(v)
+		}
+	}
+
+	return nil
+}
+
+func rotateIdentity() error {
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	bodyBytes, err := postAuth(v1.RotateIdentityRoute, v1.RotateIdentity{
+		Challenge: hex.EncodeToString(challenge),
+	})
+	if err != nil {
+		return err
+	}
+
+	var rir v1.RotateIdentityReply
+	err = json.Unmarshal(bodyBytes, &rir)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal RotateIdentityReply: %v",
+			err)
+	}
+
+	// The reply is signed by the previous identity, which must also
+	// have signed the key transition.
+	id, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+	err = util.VerifyChallenge(id, challenge, rir.Response)
+	if err != nil {
+		return err
+	}
+	err = v1.VerifyIdentityHistory([]v1.IdentityKey{{
+		PublicKey:  hex.EncodeToString(id.Key[:]),
+		ValidUntil: rir.Key.ValidFrom,
+	}, rir.Key}, *id)
+	if err != nil {
+		return err
+	}
+
+	// Trust the new identity
+	next, err := v1.IdentityKeyPublic(rir.Key)
+	if err != nil {
+		return err
+	}
+	err = next.SavePublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	if !*printJson {
+		fmt.Println("New key:")
+		printIdentityKey(rir.Key)
+		fmt.Printf("Identity saved to: %v\n", *identityFilename)
+	}
+
+	return nil
+}
+
 func getFile(filename string) (*v1.File, *[sha256.Size]byte, error) {
 	var err error
 
@@ -1266,6 +1419,10 @@ func _main() error {
 				return newRecord()
 			case "identity":
 				return getIdentity()
+			case "identityhistory":
+				return identityHistory()
+			case "rotateidentity":
+				return rotateIdentity()
 			case "plugin":
 				return plugin()
 			case "plugininventory":
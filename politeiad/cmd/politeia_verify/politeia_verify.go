@@ -12,9 +12,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/agl/ed25519"
 	"github.com/decred/dcrtime/merkle"
+	v1 "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 )
 
 var (
@@ -22,19 +26,24 @@ var (
 	tokenFlag     = flag.String("t", "", "record censorship token")
 	signatureFlag = flag.String("s", "", "record censorship signature")
 	jsonInFlag    = flag.String("jsonin", "", "JSON record file")
+	anchorFlag    = flag.String("anchor", "", "JSON record anchor file")
 	jsonOutFlag   = flag.Bool("jsonout", false, "return output as JSON")
+	historyFlag   = flag.String("history", "", "JSON identity history file")
+	timestampFlag = flag.Int64("timestamp", 0, "record timestamp")
 	verboseFlag   = flag.Bool("v", false, "verbose output")
 )
 
 type record struct {
 	CensorshipRecord censorshipRecord `json:"censorshiprecord"`
 	ServerPublicKey  string           `json:"serverPubkey"`
+	Timestamp        int64            `json:"timestamp"`
 }
 
 type censorshipRecord struct {
-	Token     string `json:"token"`
-	Merkle    string `json:"merkle"`
-	Signature string `json:"signature"`
+	Token      string         `json:"token"`
+	Merkle     string         `json:"merkle"`
+	Signature  string         `json:"signature"`
+	FileProofs []v1.FileProof `json:"fileproofs"`
 }
 
 type output struct {
@@ -53,7 +62,20 @@ func usage() {
 		"and image files that make up the record\n")
 	fmt.Fprintf(os.Stderr, "  -jsonin <filename> - A path to a JSON file which "+
 		"represents the record. If this option is set, the other input "+
-		"options (-k, -t, -s) should not be provided.\n")
+		"options (-k, -t, -s) should not be provided. Any filenames "+
+		"are verified individually using the file proofs of the "+
+		"record.\n")
+	fmt.Fprintf(os.Stderr, "  -anchor <filename> - A path to a JSON file which "+
+		"contains the anchor of the record. The filenames are "+
+		"verified all the way to the Decred blockchain timestamp. "+
+		"No other input options should be provided.\n")
+	fmt.Fprintf(os.Stderr, "  -history <filename> - A path to a JSON file which "+
+		"contains the identity history of the server. The server "+
+		"public key is only used to authenticate the history and "+
+		"the record is verified with the key that was active at the "+
+		"record timestamp or with the active key.\n")
+	fmt.Fprintf(os.Stderr, "  -timestamp <time>  - Record timestamp, required "+
+		"with -history unless -jsonin is provided\n")
 	fmt.Fprintf(os.Stderr, "  -jsonout           - JSON output\n")
 	fmt.Fprintf(os.Stderr, "\n")
 }
@@ -87,12 +109,166 @@ func findMerkle() (*[sha256.Size]byte, error) {
 	return merkle.Root(hashes), nil
 }
 
+// verifyFiles verifies each file against the inclusion proofs of the signed
+// censorship record.  This does not require the remaining files of the
+// record.
+func verifyFiles(key []byte, cr censorshipRecord, filenames []string) error {
+	pid, err := identity.PublicIdentityFromBytes(key)
+	if err != nil {
+		return err
+	}
+	csr := v1.CensorshipRecord{
+		Token:     cr.Token,
+		Merkle:    cr.Merkle,
+		Signature: cr.Signature,
+	}
+	proofs := make(map[string]v1.FileProof, len(cr.FileProofs))
+	for _, v := range cr.FileProofs {
+		proofs[v.Digest] = v
+	}
+
+	for _, filename := range filenames {
+		payload, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(payload)
+		fp, ok := proofs[hex.EncodeToString(digest[:])]
+		if !ok {
+			return fmt.Errorf("%v: no file proof found", filename)
+		}
+		err = v1.VerifyFileProof(*pid, csr, fp)
+		if err != nil {
+			return fmt.Errorf("%v: %v", filename, err)
+		}
+		if *verboseFlag {
+			fmt.Printf("File %v verified as %v\n", filename,
+				fp.Name)
+		}
+	}
+
+	return nil
+}
+
+// verifyAnchor verifies that the files are part of the anchored record
+// version and that the anchor was timestamped in the Decred blockchain.
+func verifyAnchor(anchorFile string, filenames []string) error {
+	if len(filenames) == 0 {
+		usage()
+		return fmt.Errorf("must provide at least one filename for the " +
+			"record")
+	}
+
+	payload, err := ioutil.ReadFile(anchorFile)
+	if err != nil {
+		return err
+	}
+	var ra v1.RecordAnchor
+	err = json.Unmarshal(payload, &ra)
+	if err != nil {
+		return err
+	}
+
+	// Files are matched by their base name
+	files := make(map[string][]byte, len(filenames))
+	for _, filename := range filenames {
+		payload, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		files[filepath.Base(filename)] = payload
+	}
+
+	verifyErr := v1.VerifyRecordAnchor(ra, files)
+	if verifyErr == nil && ra.ChainInformation == nil {
+		verifyErr = fmt.Errorf("anchor has not been confirmed by " +
+			"dcrtime yet")
+	}
+	if *jsonOutFlag {
+		bytes, err := json.Marshal(output{
+			Success: verifyErr == nil,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(bytes))
+		return nil
+	}
+	if verifyErr != nil {
+		if *verboseFlag {
+			return fmt.Errorf("Anchor failed verification: %v",
+				verifyErr)
+		}
+		return fmt.Errorf("Anchor failed verification")
+	}
+
+	ci := ra.ChainInformation
+	if *verboseFlag {
+		fmt.Printf("Token      : %v\n", ra.Token)
+		fmt.Printf("Version    : %v\n", ra.Version)
+		fmt.Printf("Anchor     : %v\n", ra.Merkle)
+		fmt.Printf("Merkle root: %v\n", ci.MerkleRoot)
+	}
+	fmt.Printf("Record anchored in transaction %v at %v\n", ci.Transaction,
+		time.Unix(ci.ChainTimestamp, 0).UTC())
+	fmt.Printf("Ensure the transaction contains merkle root %v\n",
+		ci.MerkleRoot)
+
+	return nil
+}
+
+// historyKey authenticates the identity history with the trusted server key
+// and returns the key that signed the censorship record.  The key is selected
+// the same way politeiad selects it when it verifies a record.
+func historyKey(historyFile, trusted string, timestamp int64, csr v1.CensorshipRecord) (string, error) {
+	payload, err := ioutil.ReadFile(historyFile)
+	if err != nil {
+		return "", err
+	}
+	var ihr v1.IdentityHistoryReply
+	err = json.Unmarshal(payload, &ihr)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := hex.DecodeString(trusted)
+	if err != nil {
+		return "", err
+	}
+	pid, err := identity.PublicIdentityFromBytes(key)
+	if err != nil {
+		return "", err
+	}
+	err = v1.VerifyIdentityHistory(ihr.Keys, *pid)
+	if err != nil {
+		return "", err
+	}
+	pid, err = v1.SigningKey(ihr.Keys, timestamp, csr)
+	if err != nil {
+		return "", err
+	}
+	if *verboseFlag {
+		fmt.Printf("Key at %v: %x\n", timestamp, pid.Key)
+	}
+
+	return hex.EncodeToString(pid.Key[:]), nil
+}
+
 func verifyRecord(key [ed25519.PublicKeySize]byte, merkle, token string, signature [ed25519.SignatureSize]byte) bool {
 	return ed25519.Verify(&key, []byte(merkle+token), &signature)
 }
 
 func _main() error {
 	flag.Parse()
+	if *anchorFlag != "" {
+		if *publicKeyFlag != "" || *jsonInFlag != "" {
+			usage()
+			return fmt.Errorf("must not provide other input " +
+				"parameters with -anchor")
+		}
+		return verifyAnchor(*anchorFlag, flag.Args())
+	}
 	if (*publicKeyFlag == "" || *tokenFlag == "" || *signatureFlag == "") &&
 		*jsonInFlag == "" {
 		usage()
@@ -104,11 +280,32 @@ func _main() error {
 			"input parameters")
 	}
 
+	if *historyFlag != "" && *jsonInFlag == "" && *timestampFlag == 0 {
+		usage()
+		return fmt.Errorf("must provide -timestamp with -history")
+	}
+
 	var keyStr, tokenStr, merkleStr, signatureStr string
 	if *publicKeyFlag != "" {
 		keyStr = *publicKeyFlag
 		tokenStr = *tokenFlag
 		signatureStr = *signatureFlag
+		root, err := findMerkle()
+		if err != nil {
+			return err
+		}
+		merkleStr = hex.EncodeToString(root[:])
+		if *historyFlag != "" {
+			keyStr, err = historyKey(*historyFlag, keyStr,
+				*timestampFlag, v1.CensorshipRecord{
+					Token:     tokenStr,
+					Merkle:    merkleStr,
+					Signature: signatureStr,
+				})
+			if err != nil {
+				return err
+			}
+		}
 	} else {
 		var payload []byte
 		payload, err := ioutil.ReadFile(*jsonInFlag)
@@ -126,6 +323,51 @@ func _main() error {
 		tokenStr = record.CensorshipRecord.Token
 		signatureStr = record.CensorshipRecord.Signature
 		merkleStr = record.CensorshipRecord.Merkle
+		if *historyFlag != "" {
+			timestamp := record.Timestamp
+			if *timestampFlag != 0 {
+				timestamp = *timestampFlag
+			}
+			keyStr, err = historyKey(*historyFlag, keyStr, timestamp,
+				v1.CensorshipRecord{
+					Token:     tokenStr,
+					Merkle:    merkleStr,
+					Signature: signatureStr,
+				})
+			if err != nil {
+				return err
+			}
+		}
+
+		// Verify individual files when provided
+		if len(flag.Args()) > 0 {
+			key, err := hex.DecodeString(keyStr)
+			if err != nil {
+				return err
+			}
+			verifyErr := verifyFiles(key, record.CensorshipRecord,
+				flag.Args())
+			if *jsonOutFlag {
+				bytes, err := json.Marshal(output{
+					Success: verifyErr == nil,
+				})
+				if err != nil {
+					return err
+				}
+
+				fmt.Println(string(bytes))
+				return nil
+			}
+			if verifyErr != nil {
+				if *verboseFlag {
+					return fmt.Errorf("Files failed "+
+						"verification: %v", verifyErr)
+				}
+				return fmt.Errorf("Files failed verification")
+			}
+			fmt.Println("Files successfully verified")
+			return nil
+		}
 	}
 
 	// Decode the public key, token and signature.
@@ -144,21 +386,11 @@ func _main() error {
 	copy(signature[:], sig)
 
 	var merkle [sha256.Size]byte
-	if *publicKeyFlag != "" {
-		merklePtr, err := findMerkle()
-		if err != nil {
-			return err
-		}
-
-		merkle = *merklePtr
-	} else {
-		bytes, err := hex.DecodeString(merkleStr)
-		if err != nil {
-			return err
-		}
-
-		copy(merkle[:], bytes)
+	bytes, err := hex.DecodeString(merkleStr)
+	if err != nil {
+		return err
 	}
+	copy(merkle[:], bytes)
 
 	recordVerified := verifyRecord(publicKey, hex.EncodeToString(merkle[:]),
 		tokenStr, signature)
@@ -0,0 +1,147 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/politeiad/cache"
+	"github.com/decred/politeia/politeiad/cache/cockroachdb"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	defaultHost     = "localhost:26257"
+	defaultRootCert = "~/.cockroachdb/certs/clients/records_politeiad/ca.crt"
+	defaultCert     = "~/.cockroachdb/certs/clients/records_politeiad/client.records_politeiad.crt"
+	defaultKey      = "~/.cockroachdb/certs/clients/records_politeiad/client.records_politeiad.key"
+)
+
+var (
+	// CLI flags
+	testnet  = flag.Bool("testnet", false, "use the testnet cache")
+	host     = flag.String("cachehost", defaultHost, "cache ip:port")
+	rootCert = flag.String("cacherootcert", defaultRootCert, "cache CA certificate")
+	cert     = flag.String("cachecert", defaultCert, "politeiad client certificate")
+	key      = flag.String("cachekey", defaultKey, "politeiad client certificate key")
+	dryRun   = flag.Bool("dryrun", false, "roll back the migrations after applying them")
+	jsonFlag = flag.Bool("json", false, "print the status as JSON")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: politeiacache [flags] <command>\n")
+	fmt.Fprintf(os.Stderr, " flags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n commands:\n")
+	fmt.Fprintf(os.Stderr, "  status  - Show the schema version of the cache "+
+		"and the pending migrations\n")
+	fmt.Fprintf(os.Stderr, "  migrate - Apply the pending migrations\n")
+}
+
+func printStatus(statuses []cache.MigrationStatus) error {
+	if *jsonFlag {
+		b, err := json.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+
+	for _, v := range statuses {
+		switch {
+		case v.Rebuild && v.Version == "":
+			fmt.Printf("%v: not built, rebuild required\n", v.ID)
+		case v.Rebuild:
+			fmt.Printf("%v: version %v, no migration path, "+
+				"rebuild required\n", v.ID, v.Version)
+		case len(v.Pending) == 0:
+			fmt.Printf("%v: version %v, up to date\n", v.ID, v.Version)
+		default:
+			fmt.Printf("%v: version %v, %v pending migrations to %v\n",
+				v.ID, v.Version, len(v.Pending), v.Latest)
+			for _, m := range v.Pending {
+				fmt.Printf("  %v -> %v: %v\n", m.From, m.To,
+					m.Description)
+			}
+		}
+	}
+	return nil
+}
+
+func _main() error {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	net := chaincfg.MainNetParams.Name
+	if *testnet {
+		net = chaincfg.TestNet3Params.Name
+	}
+
+	// Connect to the cache.  A version mismatch is expected, that is
+	// what the migrations are for.
+	db, err := cockroachdb.New(cockroachdb.UserPoliteiad, *host, net,
+		util.CleanAndExpandPath(*rootCert),
+		util.CleanAndExpandPath(*cert),
+		util.CleanAndExpandPath(*key))
+	if err != nil && err != cache.ErrNoVersionRecord &&
+		err != cache.ErrWrongVersion {
+		return err
+	}
+	defer db.Close()
+
+	// Register the plugins that have a cache implementation
+	for _, v := range cockroachdb.RegisteredPluginDrivers() {
+		err := db.RegisterPlugin(cache.Plugin{ID: v})
+		if err != nil && err != cache.ErrNoVersionRecord &&
+			err != cache.ErrWrongVersion {
+			return fmt.Errorf("register plugin %v: %v", v, err)
+		}
+	}
+
+	var statuses []cache.MigrationStatus
+	switch flag.Arg(0) {
+	case "status":
+		statuses, err = db.MigrationStatus()
+	case "migrate":
+		statuses, err = db.Migrate(*dryRun)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = printStatus(statuses)
+	if err != nil {
+		return err
+	}
+	if flag.Arg(0) == "migrate" && !*jsonFlag {
+		if *dryRun {
+			fmt.Printf("Dry run, the migrations were rolled back\n")
+		} else {
+			fmt.Printf("Migrations applied\n")
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	err := _main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
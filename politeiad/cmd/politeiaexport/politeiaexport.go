@@ -0,0 +1,199 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/decred/dcrd/chaincfg"
+	v1 "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend/gitbe"
+	"github.com/decred/politeia/politeiad/sharedconfig"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	defaultDataDirname      = sharedconfig.DefaultDataDirname
+	defaultIdentityFilename = "identity.json"
+	defaultHistoryFilename  = "identityhistory.json"
+)
+
+var (
+	defaultHomeDir = sharedconfig.DefaultHomeDir
+
+	// CLI flags
+	homeDir         = flag.String("homedir", defaultHomeDir, "politeiad home dir path")
+	testnet         = flag.Bool("testnet", false, "export testnet data")
+	gitPath         = flag.String("gitpath", "", "path to git")
+	identityFile    = flag.String("identity", "", "politeiad identity file, defaults to homedir/identity.json")
+	historyFile     = flag.String("identityhistory", "", "politeiad identity history file, defaults to homedir/identityhistory.json")
+	out             = flag.String("out", "", "bundle file, defaults to stdout")
+	verify          = flag.String("verify", "", "verify the provided bundle file instead of exporting")
+	trustedIdentity = flag.String("trusted", "", "public identity file of a trusted politeiad key, defaults to the public key of the politeiad identity file")
+)
+
+// loadIdentityHistory loads the identity history of the provided identity.  A
+// missing history file means that the identity has never been rotated.
+func loadIdentityHistory(filename string, id identity.PublicIdentity) ([]v1.IdentityKey, error) {
+	key := hex.EncodeToString(id.Key[:])
+	if !util.FileExists(filename) {
+		return []v1.IdentityKey{{PublicKey: key}}, nil
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var keys []v1.IdentityKey
+	err = json.Unmarshal(b, &keys)
+	if err != nil {
+		return nil, err
+	}
+	err = v1.VerifyIdentityHistory(keys, id)
+	if err != nil {
+		return nil, err
+	}
+	if keys[len(keys)-1].PublicKey != key {
+		return nil, fmt.Errorf("identity %v is not the active key of "+
+			"the history", key)
+	}
+
+	return keys, nil
+}
+
+// loadTrustedIdentity loads the trusted politeiad key that is used to verify a
+// bundle.  It defaults to the public key of the local politeiad identity.  A
+// bundle is never trusted on its own.
+func loadTrustedIdentity() (*identity.PublicIdentity, error) {
+	if *trustedIdentity != "" {
+		return identity.LoadPublicIdentity(
+			util.CleanAndExpandPath(*trustedIdentity))
+	}
+
+	filename := *identityFile
+	if filename == "" {
+		filename = filepath.Join(util.CleanAndExpandPath(*homeDir),
+			defaultIdentityFilename)
+	}
+	filename = util.CleanAndExpandPath(filename)
+	if !util.FileExists(filename) {
+		return nil, fmt.Errorf("no trusted identity: use --trusted or "+
+			"provide the politeiad identity file %v", filename)
+	}
+	id, err := identity.LoadFullIdentity(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id.Public, nil
+}
+
+// verifyBundle verifies a bundle file offline.
+func verifyBundle(filename string) error {
+	b, err := ioutil.ReadFile(util.CleanAndExpandPath(filename))
+	if err != nil {
+		return err
+	}
+	var bundle gitbe.Bundle
+	err = json.Unmarshal(b, &bundle)
+	if err != nil {
+		return err
+	}
+
+	trusted, err := loadTrustedIdentity()
+	if err != nil {
+		return err
+	}
+
+	err = gitbe.VerifyBundle(&bundle, trusted)
+	if err != nil {
+		return err
+	}
+
+	var versions int
+	for _, v := range bundle.Records {
+		versions += len(v.Versions)
+	}
+	fmt.Printf("Bundle verified: %v records, %v record versions\n",
+		len(bundle.Records), versions)
+
+	return nil
+}
+
+func _main() error {
+	flag.Parse()
+
+	if *verify != "" {
+		return verifyBundle(*verify)
+	}
+
+	// Set data directory
+	home := util.CleanAndExpandPath(*homeDir)
+	activeNet := chaincfg.MainNetParams.Name
+	if *testnet {
+		activeNet = chaincfg.TestNet3Params.Name
+	}
+
+	dataDir := filepath.Join(home, defaultDataDirname, activeNet)
+	_, err := os.Stat(dataDir)
+	if err != nil {
+		return err
+	}
+
+	// Load identity
+	if *identityFile == "" {
+		*identityFile = filepath.Join(home, defaultIdentityFilename)
+	}
+	if *historyFile == "" {
+		*historyFile = filepath.Join(home, defaultHistoryFilename)
+	}
+	id, err := identity.LoadFullIdentity(
+		util.CleanAndExpandPath(*identityFile))
+	if err != nil {
+		return err
+	}
+	history, err := loadIdentityHistory(
+		util.CleanAndExpandPath(*historyFile), id.Public)
+	if err != nil {
+		return fmt.Errorf("load identity history: %v", err)
+	}
+
+	bundle, err := gitbe.Export(dataDir, *gitPath, flag.Args(), id,
+		history)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	err = ioutil.WriteFile(util.CleanAndExpandPath(*out), b, 0600)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %v records to %v\n", len(bundle.Records), *out)
+
+	return nil
+}
+
+func main() {
+	err := _main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
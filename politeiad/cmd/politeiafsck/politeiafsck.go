@@ -0,0 +1,90 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/politeiad/backend/gitbe"
+	"github.com/decred/politeia/politeiad/sharedconfig"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	defaultDataDirname = sharedconfig.DefaultDataDirname
+)
+
+var (
+	defaultHomeDir = sharedconfig.DefaultHomeDir
+
+	// CLI flags
+	homeDir  = flag.String("homedir", defaultHomeDir, "politeiad home dir path")
+	testnet  = flag.Bool("testnet", false, "check testnet data")
+	gitPath  = flag.String("gitpath", "", "path to git")
+	repair   = flag.Bool("repair", false, "repair inconsistencies that can be fixed safely")
+	jsonFlag = flag.Bool("json", false, "print inconsistencies as JSON")
+)
+
+func _main() error {
+	flag.Parse()
+
+	// Set data directory
+	activeNet := chaincfg.MainNetParams.Name
+	if *testnet {
+		activeNet = chaincfg.TestNet3Params.Name
+	}
+
+	dataDir := filepath.Join(util.CleanAndExpandPath(*homeDir),
+		defaultDataDirname, activeNet)
+	_, err := os.Stat(dataDir)
+	if err != nil {
+		return err
+	}
+
+	inconsistencies, err := gitbe.Check(dataDir, *gitPath, *repair)
+	if err != nil {
+		return err
+	}
+
+	var unrepaired int
+	for _, v := range inconsistencies {
+		if !v.Repaired {
+			unrepaired++
+		}
+	}
+
+	if *jsonFlag {
+		b, err := json.Marshal(inconsistencies)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+	} else {
+		for _, v := range inconsistencies {
+			fmt.Printf("%3v %v\n", v.Code, v)
+		}
+		fmt.Printf("%v inconsistencies found, %v repaired\n",
+			len(inconsistencies), len(inconsistencies)-unrepaired)
+	}
+
+	if unrepaired != 0 {
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func main() {
+	err := _main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -14,16 +15,18 @@ import (
 	"strings"
 
 	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/backend/gitbe"
 	"github.com/decred/politeia/politeiad/sharedconfig"
 	"github.com/decred/politeia/util"
 )
 
 const (
-	defaultDataDirname     = sharedconfig.DefaultDataDirname
-	defaultUnvettedDirname = gitbe.DefaultUnvettedPath
-	defaultVettedDirname   = gitbe.DefaultVettedPath
-	defaultJournalsDirname = gitbe.DefaultJournalsPath
+	defaultDataDirname      = sharedconfig.DefaultDataDirname
+	defaultUnvettedDirname  = gitbe.DefaultUnvettedPath
+	defaultVettedDirname    = gitbe.DefaultVettedPath
+	defaultJournalsDirname  = gitbe.DefaultJournalsPath
+	defaultIdentityFilename = "identity.json"
 )
 
 var (
@@ -32,20 +35,76 @@ var (
 	// CLI flags
 	homeDir = flag.String("homedir", defaultHomeDir, "politeiad home dir path")
 	testnet = flag.Bool("testnet", false, "import data is testnet data")
+	gitPath = flag.String("gitpath", "", "path to git")
+	trusted = flag.String("trusted", "", "public identity file of a trusted politeiad key, defaults to the public key of homedir/identity.json")
 )
 
+// loadTrustedIdentity loads the trusted politeiad key that is used to verify a
+// bundle.  It defaults to the public key of the local politeiad identity.  A
+// bundle is never trusted on its own.
+func loadTrustedIdentity() (*identity.PublicIdentity, error) {
+	if *trusted != "" {
+		return identity.LoadPublicIdentity(util.CleanAndExpandPath(*trusted))
+	}
+
+	filename := filepath.Join(util.CleanAndExpandPath(*homeDir),
+		defaultIdentityFilename)
+	if !util.FileExists(filename) {
+		return nil, fmt.Errorf("no trusted identity: use --trusted or "+
+			"provide the politeiad identity file %v", filename)
+	}
+	id, err := identity.LoadFullIdentity(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id.Public, nil
+}
+
+// loadBundle loads and verifies a bundle that was created by politeiaexport.
+func loadBundle(filename string) (*gitbe.Bundle, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var bundle gitbe.Bundle
+	err = json.Unmarshal(b, &bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := loadTrustedIdentity()
+	if err != nil {
+		return nil, err
+	}
+	err = gitbe.VerifyBundle(&bundle, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
 func _main() error {
 	flag.Parse()
 	if len(flag.Args()) == 0 {
-		return fmt.Errorf("must provide import directory")
+		return fmt.Errorf("must provide import directory or bundle")
 	}
 
-	// Parse import directory
+	// Parse import directory.  A regular file is a bundle.
 	importDir := util.CleanAndExpandPath(flag.Arg(0))
-	_, err := os.Stat(importDir)
+	fi, err := os.Stat(importDir)
 	if err != nil {
 		return err
 	}
+	var bundle *gitbe.Bundle
+	if fi.Mode().IsRegular() {
+		fmt.Printf("Verifying bundle...\n")
+		bundle, err = loadBundle(importDir)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Set data directory
 	activeNet := chaincfg.MainNetParams.Name
@@ -97,6 +156,16 @@ func _main() error {
 		return err
 	}
 
+	if bundle != nil {
+		fmt.Printf("Importing %v records...\n", len(bundle.Records))
+		err = gitbe.Import(dataDir, *gitPath, bundle)
+		if err != nil {
+			return fmt.Errorf("import bundle: %v", err)
+		}
+		fmt.Printf("Done!\n")
+		return nil
+	}
+
 	fmt.Printf("Walking import directory...\n")
 
 	// Walk import directory and copy all relevant files over
@@ -0,0 +1,198 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	defaultDcrdataMainnet = "https://explorer.dcrdata.org:443/"
+	defaultDcrdataTestnet = "https://testnet.dcrdata.org:443/"
+)
+
+var (
+	// CLI flags
+	testnet         = flag.Bool("testnet", false, "verify a testnet vote")
+	trustedIdentity = flag.String("trusted", "", "public identity file of a trusted politeiad key (required)")
+	dcrdata         = flag.String("dcrdata", "", "dcrdata URL used to verify the ticket snapshot and commitment addresses, defaults to the public dcrdata instance of the network")
+	offline         = flag.Bool("offline", false, "skip the verification of the ticket snapshot and commitment addresses against the blockchain")
+	jsonOutput      = flag.Bool("json", false, "print the tally as JSON")
+)
+
+// tallyResult is the JSON output of the tally.
+type tallyResult struct {
+	Token         string                          `json:"token"`
+	Type          decredplugin.VoteT              `json:"type"`
+	Eligible      int                             `json:"eligible"`
+	Counted       int                             `json:"counted"`
+	Results       []decredplugin.VoteOptionResult `json:"results"`
+	Rounds        []decredplugin.VoteRound        `json:"rounds,omitempty"`
+	QuorumMet     bool                            `json:"quorummet"`
+	Winner        string                          `json:"winner,omitempty"`
+	Approved      bool                            `json:"approved"`
+	Rejected      []decredvote.RejectedVote       `json:"rejected"`
+	ChainVerified bool                            `json:"chainverified"`
+}
+
+// loadVoteBundle reads a vote bundle file.  Both the politeiad bundle and the
+// politeiawww reply that wraps it are accepted.
+func loadVoteBundle(filename string) (*decredplugin.VoteBundleReply, error) {
+	b, err := ioutil.ReadFile(util.CleanAndExpandPath(filename))
+	if err != nil {
+		return nil, err
+	}
+	var wrapped struct {
+		Bundle json.RawMessage `json:"bundle"`
+	}
+	err = json.Unmarshal(b, &wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped.Bundle != nil {
+		b = wrapped.Bundle
+	}
+	return decredplugin.DecodeVoteBundleReply(b)
+}
+
+func printTally(tr tallyResult) {
+	fmt.Printf("Token    : %v\n", tr.Token)
+	fmt.Printf("Eligible : %v tickets\n", tr.Eligible)
+	fmt.Printf("Counted  : %v votes\n", tr.Counted)
+	fmt.Printf("Rejected : %v votes\n", len(tr.Rejected))
+	fmt.Printf("Quorum   : %v\n", tr.QuorumMet)
+	fmt.Printf("Winner   : %v\n", tr.Winner)
+	fmt.Printf("Approved : %v\n", tr.Approved)
+	if tr.ChainVerified {
+		fmt.Printf("Chain    : snapshot and commitment addresses " +
+			"verified\n")
+	} else {
+		fmt.Printf("Chain    : NOT VERIFIED\n")
+	}
+
+	fmt.Printf("\nResults\n")
+	for _, v := range tr.Results {
+		fmt.Printf("  %-16v %v\n", v.ID, v.Votes)
+	}
+	for k, r := range tr.Rounds {
+		fmt.Printf("\nRound %v\n", k+1)
+		for _, v := range r.Results {
+			fmt.Printf("  %-16v %v\n", v.ID, v.Votes)
+		}
+		if len(r.Eliminated) > 0 {
+			fmt.Printf("  eliminated: %v\n",
+				strings.Join(r.Eliminated, ", "))
+		}
+	}
+
+	if len(tr.Rejected) > 0 {
+		fmt.Printf("\nRejected votes\n")
+		for _, v := range tr.Rejected {
+			fmt.Printf("  %v %v\n", v.Ticket, v.Reason)
+		}
+	}
+}
+
+func _main() error {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		return fmt.Errorf("usage: politeiatally [flags] bundle.json")
+	}
+
+	params := &chaincfg.MainNetParams
+	if *testnet {
+		params = &chaincfg.TestNet3Params
+	}
+
+	// A bundle is never trusted on its own
+	if *trustedIdentity == "" {
+		return fmt.Errorf("must provide the public identity of a " +
+			"trusted politeiad key using --trusted")
+	}
+	trusted, err := identity.LoadPublicIdentity(
+		util.CleanAndExpandPath(*trustedIdentity))
+	if err != nil {
+		return err
+	}
+
+	vbr, err := loadVoteBundle(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	t, err := decredvote.VerifyVoteBundle(vbr, params, trusted)
+	if err != nil {
+		return err
+	}
+
+	// Verify the ticket snapshot and the commitment addresses against the
+	// chain unless explicitly skipped.  Without this step the tally
+	// relies on data that was provided by politeiad.
+	if *offline {
+		fmt.Fprintf(os.Stderr, "WARNING: the ticket snapshot and "+
+			"commitment addresses were NOT verified against the "+
+			"blockchain; the tally relies on data provided by "+
+			"politeiad\n")
+	} else {
+		url := *dcrdata
+		if url == "" {
+			url = defaultDcrdataMainnet
+			if *testnet {
+				url = defaultDcrdataTestnet
+			}
+		}
+		if !strings.HasSuffix(url, "/") {
+			url += "/"
+		}
+		err = decredvote.VerifyVoteBundleChain(vbr, decredvote.NewDcrdataSource(url))
+		if err != nil {
+			return err
+		}
+	}
+
+	tr := tallyResult{
+		Token:         vbr.Token,
+		Type:          t.Tally.Type,
+		Eligible:      len(vbr.StartVoteReply.EligibleTickets),
+		Counted:       t.Counted,
+		Results:       t.Tally.Results,
+		Rounds:        t.Tally.Rounds,
+		QuorumMet:     t.Tally.QuorumMet,
+		Winner:        t.Tally.Winner,
+		Approved:      t.Tally.Approved,
+		Rejected:      t.Rejected,
+		ChainVerified: !*offline,
+	}
+	if *jsonOutput {
+		b, err := json.MarshalIndent(tr, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	printTally(tr)
+
+	return nil
+}
+
+func main() {
+	err := _main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
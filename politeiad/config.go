@@ -22,6 +22,8 @@ import (
 	"strings"
 
 	"github.com/decred/dcrtime/api/v1"
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiad/backend"
 	"github.com/decred/politeia/politeiad/sharedconfig"
 	"github.com/decred/politeia/util"
 	"github.com/decred/politeia/util/version"
@@ -35,6 +37,19 @@ const (
 	defaultLogDirname       = "logs"
 	defaultLogFilename      = "politeiad.log"
 	defaultIdentityFilename = "identity.json"
+	defaultHistoryFilename  = "identityhistory.json"
+
+	// Supported backends.
+	backendGit  = "git"
+	backendTlog = "tlog"
+
+	defaultBackend = backendGit
+
+	// Supported cache types.
+	cacheTypeCockroachdb = "cockroachdb"
+	cacheTypeLeveldb     = "leveldb"
+
+	defaultCacheType = cacheTypeCockroachdb
 
 	defaultMainnetPort = "49374"
 	defaultTestnetPort = "59374"
@@ -48,6 +63,7 @@ var (
 	defaultHTTPSCertFile = filepath.Join(defaultHomeDir, "https.cert")
 	defaultLogDir        = filepath.Join(defaultHomeDir, defaultLogDirname)
 	defaultIdentityFile  = filepath.Join(defaultHomeDir, defaultIdentityFilename)
+	defaultHistoryFile   = filepath.Join(defaultHomeDir, defaultHistoryFilename)
 )
 
 // runServiceCommand is only set to a real function on Windows.  It is used
@@ -85,6 +101,23 @@ type config struct {
 	BuildCache    bool   `long:"buildcache" description:"Build the cache from scratch"`
 	Identity      string `long:"identity" description:"File containing the politeiad identity file"`
 	GitTrace      bool   `long:"gittrace" description:"Enable git tracing in logs"`
+	Backend       string `long:"backend" description:"Backend type {git, tlog}"`
+
+	ReconcileCache bool `long:"reconcilecache" description:"Rebuild only the cache records that differ from the backend"`
+
+	CacheType string `long:"cachetype" description:"Cache type {cockroachdb, leveldb}"`
+
+	ReplicaOf       string `long:"replicaof" description:"Run as a read-only replica of the primary vetted git repository at the provided URL"`
+	PrimaryIdentity string `long:"primaryidentity" description:"File containing the public identity of the primary, required by replicaof"`
+
+	IdentityHistory        string `long:"identityhistory" description:"File containing the politeiad identity history"`
+	PrimaryIdentityHistory string `long:"primaryidentityhistory" description:"File containing the identity history of the primary, used by replicaof"`
+
+	Plugins        []string `long:"plugin" description:"Enable a plugin, may be specified multiple times (default: decred on the git backend)"`
+	PluginSettings []string `long:"pluginsetting" description:"Plugin setting in the form pluginid,key=value, may be specified multiple times"`
+
+	// pluginSettings contains the parsed plugin settings.
+	pluginSettings map[string][]backend.PluginSetting // [pluginID]settings
 }
 
 // serviceOptions defines the configuration options for the daemon as a service
@@ -255,6 +288,25 @@ func normalizeAddresses(addrs []string, defaultPort string) []string {
 	return removeDuplicateAddresses(addrs)
 }
 
+// parsePluginSetting parses a plugin setting in the form pluginid,key=value
+// and returns the plugin identifier and the setting.
+func parsePluginSetting(setting string) (string, *backend.PluginSetting, error) {
+	s := strings.SplitN(setting, ",", 2)
+	if len(s) != 2 {
+		return "", nil, fmt.Errorf("invalid pluginsetting %v: missing "+
+			"plugin id", setting)
+	}
+	kv := strings.SplitN(s[1], "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return "", nil, fmt.Errorf("invalid pluginsetting %v: expected "+
+			"key=value", setting)
+	}
+	return s[0], &backend.PluginSetting{
+		Key:   kv[0],
+		Value: kv[1],
+	}, nil
+}
+
 // newConfigParser returns a new command line flags parser.
 func newConfigParser(cfg *config, so *serviceOptions, options flags.Options) *flags.Parser {
 	parser := flags.NewParser(cfg, options)
@@ -287,6 +339,8 @@ func loadConfig() (*config, []string, error) {
 		HTTPSKey:   defaultHTTPSKeyFile,
 		HTTPSCert:  defaultHTTPSCertFile,
 		Version:    version.String(),
+		Backend:    defaultBackend,
+		CacheType:  defaultCacheType,
 	}
 
 	// Service options which are only added on Windows.
@@ -456,7 +510,12 @@ func loadConfig() (*config, []string, error) {
 	}
 
 	// Validate cache options.
-	if cfg.EnableCache {
+	switch cfg.CacheType {
+	case cacheTypeCockroachdb, cacheTypeLeveldb:
+	default:
+		return nil, nil, fmt.Errorf("invalid cachetype: %v", cfg.CacheType)
+	}
+	if cfg.EnableCache && cfg.CacheType == cacheTypeCockroachdb {
 		switch {
 		case cfg.CacheHost == "":
 			return nil, nil, fmt.Errorf("the enablecache param can " +
@@ -503,6 +562,85 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, fmt.Errorf("the buildcache param can " +
 			"not be used without the enablecache param")
 	}
+	if cfg.ReconcileCache && !cfg.EnableCache {
+		return nil, nil, fmt.Errorf("the reconcilecache param can " +
+			"not be used without the enablecache param")
+	}
+	if cfg.ReconcileCache && cfg.CacheType != cacheTypeCockroachdb {
+		return nil, nil, fmt.Errorf("the reconcilecache param " +
+			"requires the cockroachdb cache")
+	}
+
+	// Validate backend.
+	switch cfg.Backend {
+	case backendGit, backendTlog:
+	default:
+		return nil, nil, fmt.Errorf("invalid backend: %v", cfg.Backend)
+	}
+
+	// Validate replica options.
+	if cfg.ReplicaOf != "" {
+		switch {
+		case cfg.PrimaryIdentity == "":
+			return nil, nil, fmt.Errorf("the replicaof param can " +
+				"not be used without the primaryidentity param")
+		case cfg.Backend != backendGit:
+			return nil, nil, fmt.Errorf("the replicaof param " +
+				"requires the git backend")
+		case cfg.EnableCache:
+			return nil, nil, fmt.Errorf("the replicaof param can " +
+				"not be used with the enablecache param")
+		}
+		cfg.PrimaryIdentity = cleanAndExpandPath(cfg.PrimaryIdentity)
+		if cfg.PrimaryIdentityHistory != "" {
+			cfg.PrimaryIdentityHistory = cleanAndExpandPath(
+				cfg.PrimaryIdentityHistory)
+		}
+	} else if cfg.PrimaryIdentity != "" {
+		return nil, nil, fmt.Errorf("the primaryidentity param can " +
+			"not be used without the replicaof param")
+	} else if cfg.PrimaryIdentityHistory != "" {
+		return nil, nil, fmt.Errorf("the primaryidentityhistory param " +
+			"can not be used without the replicaof param")
+	}
+
+	// Validate plugins.  The decred plugin is enabled by default on the
+	// git backend.
+	if len(cfg.Plugins) == 0 && cfg.Backend == backendGit {
+		cfg.Plugins = []string{decredplugin.ID}
+	}
+	registered := backend.RegisteredPlugins()
+	enabled := make(map[string]bool, len(cfg.Plugins))
+	for _, id := range cfg.Plugins {
+		var found bool
+		for _, v := range registered {
+			if v == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("invalid plugin %v, "+
+				"available plugins: %v", id,
+				strings.Join(registered, ", "))
+		}
+		if enabled[id] {
+			return nil, nil, fmt.Errorf("duplicate plugin: %v", id)
+		}
+		enabled[id] = true
+	}
+	cfg.pluginSettings = make(map[string][]backend.PluginSetting)
+	for _, v := range cfg.PluginSettings {
+		id, setting, err := parsePluginSetting(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !enabled[id] {
+			return nil, nil, fmt.Errorf("pluginsetting %v: plugin "+
+				"%v is not enabled", v, id)
+		}
+		cfg.pluginSettings[id] = append(cfg.pluginSettings[id], *setting)
+	}
 
 	// Initialize log rotation.  After log rotation has been initialized,
 	// the logger variables may be used.
@@ -581,6 +719,11 @@ func loadConfig() (*config, []string, error) {
 	}
 	cfg.Identity = cleanAndExpandPath(cfg.Identity)
 
+	if cfg.IdentityHistory == "" {
+		cfg.IdentityHistory = defaultHistoryFile
+	}
+	cfg.IdentityHistory = cleanAndExpandPath(cfg.IdentityHistory)
+
 	// Set random username and password when not specified
 	if cfg.RPCUser == "" {
 		name, err := util.Random(32)
@@ -0,0 +1,166 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// diffNull is the diff name of a file that does not exist.
+	diffNull = "/dev/null"
+)
+
+// isTextMIME returns true if a unified diff can be created for files of the
+// provided MIME type.
+func isTextMIME(mime string) bool {
+	return strings.HasPrefix(mime, "text/")
+}
+
+// diffFile returns the diff of a single file. Either from or to may be nil to
+// indicate that the file was added or deleted.
+func diffFile(fromVersion, toVersion string, from, to *backend.File) (*v1.FileDiff, error) {
+	fd := v1.FileDiff{}
+	var (
+		fromPayload, toPayload []byte
+		fromName, toName       = diffNull, diffNull
+		err                    error
+	)
+	if from != nil {
+		fd.Name = from.Name
+		fd.MIME = from.MIME
+		fd.FromDigest = from.Digest
+		fromName = fromVersion + "/" + from.Name
+		fromPayload, err = base64.StdEncoding.DecodeString(from.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", fromName, err)
+		}
+	}
+	if to != nil {
+		fd.Name = to.Name
+		fd.MIME = to.MIME
+		fd.ToDigest = to.Digest
+		toName = toVersion + "/" + to.Name
+		toPayload, err = base64.StdEncoding.DecodeString(to.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", toName, err)
+		}
+	}
+
+	switch {
+	case from == nil:
+		fd.Action = v1.DiffActionAdded
+	case to == nil:
+		fd.Action = v1.DiffActionDeleted
+	case from.Digest == to.Digest:
+		return nil, nil
+	default:
+		fd.Action = v1.DiffActionModified
+	}
+
+	// Only create line diffs for text files. A file that changed MIME
+	// type is not diffed either.
+	if (from == nil || isTextMIME(from.MIME)) &&
+		(to == nil || isTextMIME(to.MIME)) {
+		fd.Diff = util.UnifiedDiff(fromName, toName, fromPayload,
+			toPayload)
+	}
+
+	return &fd, nil
+}
+
+// diffRecords returns the differences between the files and metadata streams
+// of two record versions.  The diffs are sorted by filename and metadata
+// stream ID respectively.
+func diffRecords(from, to *backend.Record) ([]v1.FileDiff, []v1.MetadataStreamDiff, error) {
+	// Files
+	fromFiles := make(map[string]*backend.File, len(from.Files))
+	for k := range from.Files {
+		fromFiles[from.Files[k].Name] = &from.Files[k]
+	}
+	toFiles := make(map[string]*backend.File, len(to.Files))
+	for k := range to.Files {
+		toFiles[to.Files[k].Name] = &to.Files[k]
+	}
+	names := make([]string, 0, len(fromFiles)+len(toFiles))
+	for name := range fromFiles {
+		names = append(names, name)
+	}
+	for name := range toFiles {
+		if _, ok := fromFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	files := make([]v1.FileDiff, 0, len(names))
+	for _, name := range names {
+		fd, err := diffFile(from.Version, to.Version, fromFiles[name],
+			toFiles[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		if fd == nil {
+			continue
+		}
+		files = append(files, *fd)
+	}
+
+	// Metadata streams
+	fromMD := make(map[uint64]string, len(from.Metadata))
+	for _, v := range from.Metadata {
+		fromMD[v.ID] = v.Payload
+	}
+	toMD := make(map[uint64]string, len(to.Metadata))
+	for _, v := range to.Metadata {
+		toMD[v.ID] = v.Payload
+	}
+	ids := make([]uint64, 0, len(fromMD)+len(toMD))
+	for id := range fromMD {
+		ids = append(ids, id)
+	}
+	for id := range toMD {
+		if _, ok := fromMD[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	mds := make([]v1.MetadataStreamDiff, 0, len(ids))
+	for _, id := range ids {
+		fromPayload, inFrom := fromMD[id]
+		toPayload, inTo := toMD[id]
+		fromName := fmt.Sprintf("%v/%v", from.Version, id)
+		toName := fmt.Sprintf("%v/%v", to.Version, id)
+		var action v1.DiffActionT
+		switch {
+		case !inFrom:
+			action = v1.DiffActionAdded
+			fromName = diffNull
+		case !inTo:
+			action = v1.DiffActionDeleted
+			toName = diffNull
+		case fromPayload == toPayload:
+			continue
+		default:
+			action = v1.DiffActionModified
+		}
+		mds = append(mds, v1.MetadataStreamDiff{
+			ID:     id,
+			Action: action,
+			Diff: util.UnifiedDiff(fromName, toName,
+				[]byte(fromPayload), []byte(toPayload)),
+		})
+	}
+
+	return files, mds, nil
+}
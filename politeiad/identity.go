@@ -0,0 +1,234 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	v1 "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/util"
+)
+
+// loadIdentityHistory loads an identity history from disk and verifies it.
+// The active key of the history must be the provided identity.
+func loadIdentityHistory(filename string, id identity.PublicIdentity) ([]v1.IdentityKey, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var keys []v1.IdentityKey
+	err = json.Unmarshal(b, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	err = v1.VerifyIdentityHistory(keys, id)
+	if err != nil {
+		return nil, err
+	}
+	if keys[len(keys)-1].PublicKey != hex.EncodeToString(id.Key[:]) {
+		return nil, fmt.Errorf("identity %x is not the active key of "+
+			"the history", id.Key)
+	}
+
+	return keys, nil
+}
+
+// saveIdentityHistory writes an identity history to disk.  The file is
+// replaced atomically.
+func saveIdentityHistory(filename string, keys []v1.IdentityKey) error {
+	b, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// signMessage signs a message with the active identity.
+func (p *politeia) signMessage(msg []byte) [identity.SignatureSize]byte {
+	p.identityMtx.RLock()
+	defer p.identityMtx.RUnlock()
+
+	return p.identity.SignMessage(msg)
+}
+
+// publicIdentity returns the public key of the active identity.
+func (p *politeia) publicIdentity() identity.PublicIdentity {
+	p.identityMtx.RLock()
+	defer p.identityMtx.RUnlock()
+
+	return p.identity.Public
+}
+
+// identityHistory returns a copy of the identity history.
+func (p *politeia) identityHistory() []v1.IdentityKey {
+	p.identityMtx.RLock()
+	defer p.identityMtx.RUnlock()
+
+	keys := make([]v1.IdentityKey, len(p.history))
+	copy(keys, p.history)
+	return keys
+}
+
+// censorshipSignature returns the censorship record signature of a record.
+// The signature that was stored by the backend is returned when present
+// because it was made by the identity that was active at the record
+// timestamp.  Otherwise the record is signed with the active identity.
+// Replicas only serve the stored signature of the primary.
+func (p *politeia) censorshipSignature(br backend.Record) string {
+	if br.Signature != "" || p.replica() {
+		return br.Signature
+	}
+
+	rm := br.RecordMetadata
+	s := p.signMessage([]byte(rm.Merkle + rm.Token))
+	return hex.EncodeToString(s[:])
+}
+
+// rotate replaces the active identity with a newly generated one and returns
+// the new history key.  The transition is signed by both the previous and
+// the new identity.  The new identity file is written next to the identity
+// file before the history is updated so that an interrupted rotation can be
+// recovered by hand.  The backend and its plugins only switch to the new
+// identity once it is valid, therefore every record is signed by the key
+// that was active at the record timestamp.
+func (p *politeia) rotate() (*v1.IdentityKey, error) {
+	p.identityMtx.Lock()
+	defer p.identityMtx.Unlock()
+
+	last := p.history[len(p.history)-1]
+	validFrom := time.Now().Unix() + 1
+	if validFrom <= last.ValidFrom {
+		return nil, fmt.Errorf("identity was rotated too recently")
+	}
+
+	id, err := identity.New()
+	if err != nil {
+		return nil, err
+	}
+	filename := p.cfg.Identity + ".new"
+	err = id.Save(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	key := v1.IdentityKey{
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		ValidFrom: validFrom,
+	}
+	msg := v1.KeyTransition(last.PublicKey, key.PublicKey, validFrom)
+	ps := p.identity.SignMessage(msg)
+	s := id.SignMessage(msg)
+	key.PreviousSignature = hex.EncodeToString(ps[:])
+	key.Signature = hex.EncodeToString(s[:])
+
+	history := make([]v1.IdentityKey, 0, len(p.history)+1)
+	history = append(history, p.history...)
+	history[len(history)-1].ValidUntil = validFrom
+	history = append(history, key)
+
+	err = saveIdentityHistory(p.cfg.IdentityHistory, history)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Rename(filename, p.cfg.Identity)
+	if err != nil {
+		// The history already contains the new key.
+		log.Criticalf("rotate: new identity %v could not be renamed "+
+			"to %v: %v", filename, p.cfg.Identity, err)
+		return nil, err
+	}
+
+	// Records that are signed until now carry a timestamp before
+	// validFrom and are verified with the previous identity.
+	time.Sleep(time.Until(time.Unix(validFrom, 0)))
+
+	p.identity = id
+	p.history = history
+
+	setter, ok := p.backend.(backend.IdentitySetter)
+	if ok {
+		err = setter.SetIdentity(id)
+		if err != nil {
+			// The identity files already contain the new identity.
+			log.Criticalf("rotate: backend identity could not be "+
+				"replaced: %v", err)
+			return nil, err
+		}
+	}
+
+	return &key, nil
+}
+
+func (p *politeia) getIdentityHistory(w http.ResponseWriter, r *http.Request) {
+	var t v1.IdentityHistory
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.signMessage(challenge)
+
+	reply := v1.IdentityHistoryReply{
+		Response: hex.EncodeToString(response[:]),
+		Keys:     p.identityHistory(),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeia) rotateIdentity(w http.ResponseWriter, r *http.Request) {
+	var t v1.RotateIdentity
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+
+	// The challenge is signed by the previous identity.
+	response := p.signMessage(challenge)
+
+	key, err := p.rotate()
+	if err != nil {
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Rotate identity error code %v: %v",
+			remoteAddr(r), errorCode, err)
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	log.Infof("Identity rotated %v: public key %v valid from %v",
+		remoteAddr(r), key.PublicKey, key.ValidFrom)
+
+	util.RespondWithJSON(w, http.StatusOK, v1.RotateIdentityReply{
+		Response: hex.EncodeToString(response[:]),
+		Key:      *key,
+	})
+}
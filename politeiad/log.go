@@ -40,16 +40,22 @@ var (
 	// application shutdown.
 	logRotator *rotator.Rotator
 
-	log            = backendLog.Logger("POLI")
-	gitbeLog       = backendLog.Logger("GITB")
-	cockroachdbLog = backendLog.Logger("CODB")
+	log             = backendLog.Logger("POLI")
+	gitbeLog        = backendLog.Logger("GITB")
+	decredvoteLog   = backendLog.Logger("DVOT")
+	tlogbeLog       = backendLog.Logger("TLOG")
+	cockroachdbLog  = backendLog.Logger("CODB")
+	leveldbcacheLog = backendLog.Logger("LDBC")
 )
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
 var subsystemLoggers = map[string]slog.Logger{
 	"POLI": log,
 	"GITB": gitbeLog,
+	"DVOT": decredvoteLog,
+	"TLOG": tlogbeLog,
 	"CODB": cockroachdbLog,
+	"LDBC": leveldbcacheLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -7,6 +7,7 @@ package main
 import (
 	"crypto/elliptic"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -17,17 +18,20 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/decred/politeia/decredplugin"
 	"github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/backend"
+	"github.com/decred/politeia/politeiad/backend/decredvote"
 	"github.com/decred/politeia/politeiad/backend/gitbe"
+	"github.com/decred/politeia/politeiad/backend/tlogbe"
 	"github.com/decred/politeia/politeiad/cache"
 	"github.com/decred/politeia/politeiad/cache/cachestub"
 	"github.com/decred/politeia/politeiad/cache/cockroachdb"
+	"github.com/decred/politeia/politeiad/cache/leveldbcache"
 	"github.com/decred/politeia/util"
 	"github.com/decred/politeia/util/version"
 	"github.com/gorilla/mux"
@@ -48,6 +52,20 @@ type politeia struct {
 	router   *mux.Router
 	identity *identity.FullIdentity
 	plugins  map[string]v1.Plugin
+
+	// pluginCmds maps the commands of the enabled plugins to the plugin
+	// that serves them.
+	pluginCmds map[string]string // [command]pluginID
+
+	// identityMtx protects identity and history, which are replaced when
+	// the identity is rotated.
+	identityMtx sync.RWMutex
+	history     []v1.IdentityKey // Identity history, oldest first
+
+	// primaryIdentity is the identity of the primary that signed the
+	// censorship records.  It is only set on read-only replicas.
+	primaryIdentity *identity.PublicIdentity
+	primaryHistory  []v1.IdentityKey // Optional primary identity history
 }
 
 func remoteAddr(r *http.Request) string {
@@ -124,6 +142,33 @@ func convertFrontendStatus(status v1.RecordStatusT) backend.MDStatusT {
 	return s
 }
 
+// convertFrontendInventoryFilter converts an API inventory filter to a
+// backend inventory filter.
+func convertFrontendInventoryFilter(f v1.InventoryFilter, includeFiles bool) backend.InventoryFilter {
+	statuses := make([]backend.MDStatusT, 0, len(f.Statuses))
+	for _, v := range f.Statuses {
+		// convertFrontendStatus only covers the statuses that can be
+		// set by the client.
+		if v == v1.RecordStatusUnreviewedChanges {
+			statuses = append(statuses,
+				backend.MDStatusIterationUnvetted)
+			continue
+		}
+		statuses = append(statuses, convertFrontendStatus(v))
+	}
+	return backend.InventoryFilter{
+		State:          backend.InventoryStateT(f.State),
+		Statuses:       statuses,
+		FromTimestamp:  f.FromTimestamp,
+		UntilTimestamp: f.UntilTimestamp,
+		MDStreams:      f.MDStreams,
+		IncludeFiles:   includeFiles,
+		Cursor:         f.Cursor,
+		Limit:          f.PageSize,
+		Reverse:        f.Reverse,
+	}
+}
+
 func convertFrontendFiles(f []v1.File) []backend.File {
 	files := make([]backend.File, 0, len(f))
 	for _, v := range f {
@@ -151,8 +196,8 @@ func convertFrontendMetadataStream(mds []v1.MetadataStream) []backend.MetadataSt
 func (p *politeia) convertBackendRecord(br backend.Record) v1.Record {
 	rm := br.RecordMetadata
 
-	// Calculate signature
-	signature := p.identity.SignMessage([]byte(rm.Merkle + rm.Token))
+	// Calculate signature.
+	signature := p.censorshipSignature(br)
 
 	// Convert MetadataStream
 	md := make([]v1.MetadataStream, 0, len(br.Metadata))
@@ -167,7 +212,7 @@ func (p *politeia) convertBackendRecord(br backend.Record) v1.Record {
 		CensorshipRecord: v1.CensorshipRecord{
 			Merkle:    rm.Merkle,
 			Token:     rm.Token,
-			Signature: hex.EncodeToString(signature[:]),
+			Signature: signature,
 		},
 		Version:  br.Version,
 		Metadata: md,
@@ -186,6 +231,26 @@ func (p *politeia) convertBackendRecord(br backend.Record) v1.Record {
 	return pr
 }
 
+func convertBackendAnchor(token string, ra backend.RecordAnchor) v1.RecordAnchor {
+	a := v1.RecordAnchor{
+		Token:            token,
+		Version:          ra.Version,
+		Commit:           base64.StdEncoding.EncodeToString(ra.Commit),
+		Trees:            make([]string, 0, len(ra.Trees)),
+		Path:             ra.Path,
+		Digests:          make([]string, 0, len(ra.Digests)),
+		Merkle:           hex.EncodeToString(ra.Merkle),
+		ChainInformation: ra.ChainInformation,
+	}
+	for _, v := range ra.Trees {
+		a.Trees = append(a.Trees, base64.StdEncoding.EncodeToString(v))
+	}
+	for _, v := range ra.Digests {
+		a.Digests = append(a.Digests, hex.EncodeToString(v))
+	}
+	return a
+}
+
 func convertBackendStatusToCache(status backend.MDStatusT) cache.RecordStatusT {
 	s := cache.RecordStatusInvalid
 	switch status {
@@ -232,12 +297,10 @@ func convertMDStreamsToCache(ms []backend.MetadataStream) []cache.MetadataStream
 }
 
 func (p *politeia) convertBackendRecordToCache(r backend.Record) cache.Record {
-	msg := []byte(r.RecordMetadata.Merkle + r.RecordMetadata.Token)
-	signature := p.identity.SignMessage(msg)
 	cr := cache.CensorshipRecord{
 		Token:     r.RecordMetadata.Token,
 		Merkle:    r.RecordMetadata.Merkle,
-		Signature: hex.EncodeToString(signature[:]),
+		Signature: p.censorshipSignature(r),
 	}
 
 	files := make([]cache.File, 0, len(r.Files))
@@ -289,12 +352,16 @@ func (p *politeia) getIdentity(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	reply := v1.IdentityReply{
-		PublicKey: hex.EncodeToString(p.identity.Public.Key[:]),
+		PublicKey: hex.EncodeToString(p.publicIdentity().Key[:]),
 		Response:  hex.EncodeToString(response[:]),
 	}
+	if p.replica() {
+		reply.PrimaryPublicKey = hex.EncodeToString(
+			p.primaryIdentity.Key[:])
+	}
 
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
@@ -351,9 +418,9 @@ func (p *politeia) newRecord(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Prepare reply.
-	signature := p.identity.SignMessage([]byte(rm.Merkle + rm.Token))
+	signature := p.signMessage([]byte(rm.Merkle + rm.Token))
 
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 	reply := v1.NewRecordReply{
 		Response: hex.EncodeToString(response[:]),
 		CensorshipRecord: v1.CensorshipRecord{
@@ -472,7 +539,7 @@ func (p *politeia) updateRecord(w http.ResponseWriter, r *http.Request, vetted b
 	}
 
 	// Prepare reply.
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 	reply := v1.UpdateRecordReply{
 		Response: hex.EncodeToString(response[:]),
 	}
@@ -505,7 +572,7 @@ func (p *politeia) updateReadme(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	reply := v1.UpdateReadmeReply{
 		Response: hex.EncodeToString(response[:]),
@@ -536,7 +603,7 @@ func (p *politeia) getUnvetted(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	reply := v1.GetUnvettedReply{
 		Response: hex.EncodeToString(response[:]),
@@ -567,8 +634,7 @@ func (p *politeia) getUnvetted(w http.ResponseWriter, r *http.Request) {
 		reply.Record = p.convertBackendRecord(*bpr)
 
 		// Double check record bits before sending them off
-		err := v1.Verify(p.identity.Public,
-			reply.Record.CensorshipRecord, reply.Record.Files)
+		err := p.verifyCensorshipRecord(reply.Record)
 		if err != nil {
 			// Generic internal error.
 			errorCode := time.Now().Unix()
@@ -580,6 +646,20 @@ func (p *politeia) getUnvetted(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Add file inclusion proofs
+		proofs, err := v1.FileProofs(reply.Record.Files)
+		if err != nil {
+			// Generic internal error.
+			errorCode := time.Now().Unix()
+			log.Errorf("%v Get unvetted record file proofs "+
+				"error code %v: %v", remoteAddr(r), errorCode,
+				err)
+
+			p.respondWithServerError(w, errorCode)
+			return
+		}
+		reply.Record.CensorshipRecord.FileProofs = proofs
+
 		log.Infof("Get unvetted record %v: token %v", remoteAddr(r),
 			t.Token)
 	}
@@ -600,7 +680,7 @@ func (p *politeia) getVetted(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	reply := v1.GetVettedReply{
 		Response: hex.EncodeToString(response[:]),
@@ -631,8 +711,7 @@ func (p *politeia) getVetted(w http.ResponseWriter, r *http.Request) {
 		reply.Record = p.convertBackendRecord(*bpr)
 
 		// Double check record bits before sending them off
-		err := v1.Verify(p.identity.Public,
-			reply.Record.CensorshipRecord, reply.Record.Files)
+		err := p.verifyCensorshipRecord(reply.Record)
 		if err != nil {
 			// Generic internal error.
 			errorCode := time.Now().Unix()
@@ -643,6 +722,20 @@ func (p *politeia) getVetted(w http.ResponseWriter, r *http.Request) {
 			p.respondWithServerError(w, errorCode)
 			return
 		}
+
+		// Add file inclusion proofs
+		proofs, err := v1.FileProofs(reply.Record.Files)
+		if err != nil {
+			// Generic internal error.
+			errorCode := time.Now().Unix()
+			log.Errorf("%v Get vetted record file proofs "+
+				"error code %v: %v", remoteAddr(r), errorCode,
+				err)
+
+			p.respondWithServerError(w, errorCode)
+			return
+		}
+		reply.Record.CensorshipRecord.FileProofs = proofs
 		log.Infof("Get vetted record %v: token %v", remoteAddr(r),
 			t.Token)
 	}
@@ -650,6 +743,214 @@ func (p *politeia) getVetted(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+func (p *politeia) getVettedVersions(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetVettedVersions
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.signMessage(challenge)
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Ask backend for all versions
+	records, err := p.backend.GetVettedVersions(token)
+	if err == backend.ErrRecordNotFound {
+		log.Errorf("Get vetted versions %v: token %v not found",
+			remoteAddr(r), t.Token)
+		p.respondWithUserError(w, v1.ErrorStatusRecordNotFound, nil)
+		return
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get vetted versions error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	versions := make([]v1.RecordVersion, 0, len(records))
+	for _, v := range records {
+		err := p.verifyReplicaRecord(v)
+		if err != nil {
+			// Generic internal error.
+			errorCode := time.Now().Unix()
+			log.Errorf("%v Get vetted versions CORRUPTION error "+
+				"code %v: %v", remoteAddr(r), errorCode, err)
+
+			p.respondWithServerError(w, errorCode)
+			return
+		}
+
+		versions = append(versions, v1.RecordVersion{
+			Version:   v.Version,
+			Status:    convertBackendStatus(v.RecordMetadata.Status),
+			Timestamp: v.RecordMetadata.Timestamp,
+			Merkle:    v.RecordMetadata.Merkle,
+		})
+	}
+
+	log.Infof("Get vetted versions %v: token %v", remoteAddr(r), t.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, v1.GetVettedVersionsReply{
+		Response: hex.EncodeToString(response[:]),
+		Versions: versions,
+	})
+}
+
+func (p *politeia) getVettedDiff(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetVettedDiff
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.signMessage(challenge)
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Ask backend for both versions
+	from, err := p.backend.GetVetted(token, t.FromVersion)
+	if err != nil {
+		p.respondWithVettedDiffError(w, r, t, err)
+		return
+	}
+	err = p.verifyReplicaRecord(*from)
+	if err != nil {
+		p.respondWithVettedDiffError(w, r, t, err)
+		return
+	}
+	to, err := p.backend.GetVetted(token, t.ToVersion)
+	if err != nil {
+		p.respondWithVettedDiffError(w, r, t, err)
+		return
+	}
+	err = p.verifyReplicaRecord(*to)
+	if err != nil {
+		p.respondWithVettedDiffError(w, r, t, err)
+		return
+	}
+
+	files, mds, err := diffRecords(from, to)
+	if err != nil {
+		p.respondWithVettedDiffError(w, r, t, err)
+		return
+	}
+
+	log.Infof("Get vetted diff %v: token %v %v..%v", remoteAddr(r),
+		t.Token, from.Version, to.Version)
+
+	util.RespondWithJSON(w, http.StatusOK, v1.GetVettedDiffReply{
+		Response: hex.EncodeToString(response[:]),
+		Files:    files,
+		Metadata: mds,
+	})
+}
+
+// respondWithVettedDiffError responds to a failed get vetted diff request.  A
+// missing record version is a user error, all other errors are internal
+// errors.
+func (p *politeia) respondWithVettedDiffError(w http.ResponseWriter, r *http.Request, t v1.GetVettedDiff, err error) {
+	if err == backend.ErrRecordNotFound {
+		log.Errorf("Get vetted diff %v: token %v versions %v..%v not "+
+			"found", remoteAddr(r), t.Token, t.FromVersion,
+			t.ToVersion)
+		p.respondWithUserError(w, v1.ErrorStatusRecordNotFound, nil)
+		return
+	}
+
+	// Generic internal error.
+	errorCode := time.Now().Unix()
+	log.Errorf("%v Get vetted diff error code %v: %v", remoteAddr(r),
+		errorCode, err)
+
+	p.respondWithServerError(w, errorCode)
+}
+
+func (p *politeia) getVettedAnchor(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetVettedAnchor
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.signMessage(challenge)
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Ask backend for the anchor
+	ra, err := p.backend.GetVettedAnchor(token, t.Version)
+	switch err {
+	case nil:
+	case backend.ErrRecordNotFound:
+		log.Errorf("Get vetted anchor %v: token %v version %v not found",
+			remoteAddr(r), t.Token, t.Version)
+		p.respondWithUserError(w, v1.ErrorStatusRecordNotFound, nil)
+		return
+	case backend.ErrRecordNotAnchored:
+		log.Errorf("Get vetted anchor %v: token %v version %v not "+
+			"anchored", remoteAddr(r), t.Token, t.Version)
+		p.respondWithUserError(w, v1.ErrorStatusRecordNotAnchored, nil)
+		return
+	case backend.ErrNotSupported:
+		log.Errorf("Get vetted anchor %v: not supported by backend",
+			remoteAddr(r))
+		p.respondWithUserError(w, v1.ErrorStatusNotSupported, nil)
+		return
+	default:
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get vetted anchor error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	log.Infof("Get vetted anchor %v: token %v version %v", remoteAddr(r),
+		t.Token, ra.Version)
+
+	util.RespondWithJSON(w, http.StatusOK, v1.GetVettedAnchorReply{
+		Response: hex.EncodeToString(response[:]),
+		Anchor:   convertBackendAnchor(t.Token, *ra),
+	})
+}
+
 func (p *politeia) inventory(w http.ResponseWriter, r *http.Request) {
 	var i v1.Inventory
 	decoder := json.NewDecoder(r.Body)
@@ -663,12 +964,17 @@ func (p *politeia) inventory(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	reply := v1.InventoryReply{
 		Response: hex.EncodeToString(response[:]),
 	}
 
+	if i.Filter != nil {
+		p.inventoryPage(w, r, i, reply)
+		return
+	}
+
 	// Ask backend for inventory
 	prs, brs, err := p.backend.Inventory(i.VettedCount, i.BranchesCount,
 		i.IncludeFiles, i.AllVersions)
@@ -685,6 +991,13 @@ func (p *politeia) inventory(w http.ResponseWriter, r *http.Request) {
 	// Convert backend records
 	vetted := make([]v1.Record, 0, len(prs))
 	for _, v := range prs {
+		err := p.verifyReplicaRecord(v)
+		if err != nil {
+			log.Errorf("%v Inventory skipping record %v version "+
+				"%v: %v", remoteAddr(r),
+				v.RecordMetadata.Token, v.Version, err)
+			continue
+		}
 		vetted = append(vetted, p.convertBackendRecord(v))
 	}
 	reply.Vetted = vetted
@@ -699,6 +1012,42 @@ func (p *politeia) inventory(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// inventoryPage replies with a filtered page of records.
+func (p *politeia) inventoryPage(w http.ResponseWriter, r *http.Request, i v1.Inventory, reply v1.InventoryReply) {
+	f := convertFrontendInventoryFilter(*i.Filter, i.IncludeFiles)
+	brs, cursor, err := p.backend.InventoryPage(f)
+	if err == backend.ErrInvalidCursor {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidCursor, nil)
+		return
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Inventory page error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	records := make([]v1.Record, 0, len(brs))
+	for _, v := range brs {
+		err := p.verifyReplicaRecord(v)
+		if err != nil {
+			log.Errorf("%v Inventory page skipping record %v "+
+				"version %v: %v", remoteAddr(r),
+				v.RecordMetadata.Token, v.Version, err)
+			continue
+		}
+		records = append(records, p.convertBackendRecord(v))
+	}
+	reply.Vetted = []v1.Record{}
+	reply.Branches = []v1.Record{}
+	reply.Records = records
+	reply.Cursor = cursor
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 func (p *politeia) check(user, pass string) bool {
 	if user != p.cfg.RPCUser || pass != p.cfg.RPCPass {
 		return false
@@ -737,7 +1086,7 @@ func (p *politeia) setVettedStatus(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	// Validate token
 	token, err := util.ConvertStringToken(t.Token)
@@ -765,6 +1114,15 @@ func (p *politeia) setVettedStatus(w http.ResponseWriter, r *http.Request) {
 			p.respondWithUserError(w, v1.ErrorStatusInvalidRecordStatusTransition, nil)
 			return
 		}
+		// Check for content error, which is returned by plugin
+		// hooks that reject the change.
+		if contentErr, ok := err.(backend.ContentVerificationError); ok {
+			log.Errorf("%v %v set status content error: %v",
+				remoteAddr(r), t.Token, contentErr)
+			p.respondWithUserError(w, contentErr.ErrorCode,
+				contentErr.ErrorContext)
+			return
+		}
 		// Generic internal error.
 		errorCode := time.Now().Unix()
 		log.Errorf("%v Set status error code %v: %v",
@@ -808,7 +1166,7 @@ func (p *politeia) setUnvettedStatus(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	// Validate token
 	token, err := util.ConvertStringToken(t.Token)
@@ -836,6 +1194,15 @@ func (p *politeia) setUnvettedStatus(w http.ResponseWriter, r *http.Request) {
 			p.respondWithUserError(w, v1.ErrorStatusInvalidRecordStatusTransition, nil)
 			return
 		}
+		// Check for content error, which is returned by plugin
+		// hooks that reject the change.
+		if contentErr, ok := err.(backend.ContentVerificationError); ok {
+			log.Errorf("%v %v set status content error: %v",
+				remoteAddr(r), t.Token, contentErr)
+			p.respondWithUserError(w, contentErr.ErrorCode,
+				contentErr.ErrorContext)
+			return
+		}
 		// Generic internal error.
 		errorCode := time.Now().Unix()
 		log.Errorf("%v Set unvetted status error code %v: %v",
@@ -890,7 +1257,7 @@ func (p *politeia) updateVettedMetadata(w http.ResponseWriter, r *http.Request)
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	// Validate token
 	token, err := util.ConvertStringToken(t.Token)
@@ -946,6 +1313,53 @@ func (p *politeia) updateVettedMetadata(w http.ResponseWriter, r *http.Request)
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// enablePlugins creates the configured plugins and enables them on the
+// backend.
+func (p *politeia) enablePlugins() error {
+	if len(p.cfg.Plugins) == 0 {
+		return nil
+	}
+	host, ok := p.backend.(backend.PluginHost)
+	if !ok {
+		return fmt.Errorf("backend %v does not support plugins",
+			p.cfg.Backend)
+	}
+
+	// Replicas do not sign plugin data.
+	id := p.identity
+	history := p.identityHistory
+	if p.replica() {
+		id = nil
+		history = nil
+	}
+	for _, v := range p.cfg.Plugins {
+		d, err := backend.NewPlugin(v, backend.PluginConfig{
+			Backend:         p.backend,
+			DataDir:         p.cfg.DataDir,
+			Identity:        id,
+			TestNet:         activeNetParams.Name != "mainnet",
+			Settings:        p.cfg.pluginSettings[v],
+			IdentityHistory: history,
+		})
+		if err == backend.ErrInvalidPlugin {
+			return fmt.Errorf("plugin %v is not supported by the %v "+
+				"backend", v, p.cfg.Backend)
+		} else if err != nil {
+			return fmt.Errorf("plugin %v: %v", v, err)
+		}
+		err = host.EnablePlugin(d)
+		if err != nil {
+			return err
+		}
+		for _, c := range d.Commands() {
+			p.pluginCmds[c.Command] = v
+		}
+		log.Infof("Enabled plugin: %v", v)
+	}
+
+	return nil
+}
+
 func (p *politeia) pluginInventory(w http.ResponseWriter, r *http.Request) {
 	var pi v1.PluginInventory
 	decoder := json.NewDecoder(r.Body)
@@ -960,7 +1374,7 @@ func (p *politeia) pluginInventory(w http.ResponseWriter, r *http.Request) {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
 	}
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 
 	reply := v1.PluginInventoryReply{
 		Response: hex.EncodeToString(response[:]),
@@ -989,7 +1403,16 @@ func (p *politeia) pluginCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	cid, payload, err := p.backend.Plugin(pc.Command, pc.Payload)
-	if err != nil {
+	if err == backend.ErrInvalidPluginCmd {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidPluginCommand,
+			[]string{pc.Command})
+		return
+	} else if err == backend.ErrReadOnly {
+		log.Errorf("%v Rejected plugin command on read-only replica: %v",
+			remoteAddr(r), pc.Command)
+		p.respondWithUserError(w, v1.ErrorStatusReadOnly, nil)
+		return
+	} else if err != nil {
 		// Generic internal error.
 		errorCode := time.Now().Unix()
 		log.Errorf("%v %v: backend plugin failed with "+
@@ -1001,18 +1424,18 @@ func (p *politeia) pluginCommand(w http.ResponseWriter, r *http.Request) {
 
 	// Send plugin command to cache
 	_, err = p.cache.PluginExec(cache.PluginCommand{
-		ID:             decredplugin.ID,
+		ID:             p.pluginCmds[pc.Command],
 		Command:        pc.Command,
 		CommandPayload: pc.Payload,
 		ReplyPayload:   payload,
 	})
-	if err != nil {
+	if err != nil && err != cache.ErrInvalidPlugin {
 		log.Criticalf("Cache plugin exec failed: command:%v"+
 			"commandPayload:%v replyPayload:%v error:%v",
 			pc.Command, pc.Payload, payload, err)
 	}
 
-	response := p.identity.SignMessage(challenge)
+	response := p.signMessage(challenge)
 	reply := v1.PluginCommandReply{
 		Response:  hex.EncodeToString(response[:]),
 		ID:        pc.ID,
@@ -1059,6 +1482,35 @@ func (p *politeia) addRoute(method string, route string, handler http.HandlerFun
 	p.router.StrictSlash(true).HandleFunc(route, handler).Methods(method)
 }
 
+// migrateCache applies the pending cache schema migrations.  The cache is
+// rebuilt when a part of it can not be migrated in place.
+func (p *politeia) migrateCache() error {
+	m, ok := p.cache.(cache.Migrator)
+	if !ok {
+		p.cfg.BuildCache = true
+		return nil
+	}
+
+	statuses, err := m.Migrate(false)
+	if err != nil {
+		return fmt.Errorf("migrate cache: %v", err)
+	}
+	for _, v := range statuses {
+		if v.Rebuild {
+			log.Infof("Cache %v can not be migrated from version %v "+
+				"to %v, rebuilding cache", v.ID, v.Version, v.Latest)
+			p.cfg.BuildCache = true
+			continue
+		}
+		if len(v.Pending) > 0 {
+			log.Infof("Cache %v migrated from version %v to %v",
+				v.ID, v.Version, v.Latest)
+		}
+	}
+
+	return nil
+}
+
 func _main() error {
 	// Load configuration and parse command line.  This function also
 	// initializes logging and configures it accordingly.
@@ -1114,9 +1566,10 @@ func _main() error {
 
 	// Setup application context.
 	p := &politeia{
-		cfg:     loadedCfg,
-		plugins: make(map[string]v1.Plugin),
-		cache:   cachestub.New(),
+		cfg:        loadedCfg,
+		plugins:    make(map[string]v1.Plugin),
+		pluginCmds: make(map[string]string),
+		cache:      cachestub.New(),
 	}
 
 	// Load identity.
@@ -1126,6 +1579,45 @@ func _main() error {
 	}
 	log.Infof("Public key: %x", p.identity.Public.Key)
 
+	// Load identity history.  A new history starts with the current
+	// identity.
+	if !util.FileExists(loadedCfg.IdentityHistory) {
+		log.Infof("Creating identity history...")
+		err = saveIdentityHistory(loadedCfg.IdentityHistory,
+			[]v1.IdentityKey{{
+				PublicKey: hex.EncodeToString(p.identity.Public.Key[:]),
+			}})
+		if err != nil {
+			return err
+		}
+	}
+	p.history, err = loadIdentityHistory(loadedCfg.IdentityHistory,
+		p.identity.Public)
+	if err != nil {
+		return fmt.Errorf("load identity history: %v", err)
+	}
+	log.Infof("Identity history: %v keys", len(p.history))
+
+	// Load the identity of the primary when running as a replica.
+	if loadedCfg.ReplicaOf != "" {
+		p.primaryIdentity, err = identity.LoadPublicIdentity(
+			loadedCfg.PrimaryIdentity)
+		if err != nil {
+			return fmt.Errorf("load primary identity: %v", err)
+		}
+		log.Infof("Primary public key: %x", p.primaryIdentity.Key)
+
+		if loadedCfg.PrimaryIdentityHistory != "" {
+			p.primaryHistory, err = loadIdentityHistory(
+				loadedCfg.PrimaryIdentityHistory,
+				*p.primaryIdentity)
+			if err != nil {
+				return fmt.Errorf("load primary identity "+
+					"history: %v", err)
+			}
+		}
+	}
+
 	// Load certs, if there.  If they aren't there assume OS is used to
 	// resolve cert validity.
 	if len(loadedCfg.DcrtimeCert) != 0 {
@@ -1146,30 +1638,75 @@ func _main() error {
 	}
 
 	// Setup backend.
-	gitbe.UseLogger(gitbeLog)
-	b, err := gitbe.New(activeNetParams.Params, loadedCfg.DataDir,
-		loadedCfg.DcrtimeHost, "", p.identity, loadedCfg.GitTrace)
+	decredvote.UseLogger(decredvoteLog)
+	switch loadedCfg.Backend {
+	case backendGit:
+		gitbe.UseLogger(gitbeLog)
+		if p.replica() {
+			b, err := gitbe.NewReplica(activeNetParams.Params,
+				loadedCfg.DataDir, loadedCfg.ReplicaOf, "",
+				loadedCfg.GitTrace)
+			if err != nil {
+				return err
+			}
+			p.backend = b
+			break
+		}
+		b, err := gitbe.New(activeNetParams.Params, loadedCfg.DataDir,
+			loadedCfg.DcrtimeHost, "", p.identity, loadedCfg.GitTrace)
+		if err != nil {
+			return err
+		}
+		p.backend = b
+	case backendTlog:
+		tlogbe.UseLogger(tlogbeLog)
+		b, err := tlogbe.New(loadedCfg.DataDir, loadedCfg.DcrtimeHost,
+			p.identity)
+		if err != nil {
+			return err
+		}
+		p.backend = b
+	default:
+		return fmt.Errorf("invalid backend: %v", loadedCfg.Backend)
+	}
+	log.Infof("Backend : %v", loadedCfg.Backend)
+
+	// Enable plugins
+	err = p.enablePlugins()
 	if err != nil {
 		return err
 	}
-	p.backend = b
 
 	// Setup cache
+	var migrateCache bool
 	if p.cfg.EnableCache {
 		// Create a new cache context
-		cockroachdb.UseLogger(cockroachdbLog)
-		net := filepath.Base(p.cfg.DataDir)
-		db, err := cockroachdb.New(cockroachdb.UserPoliteiad, p.cfg.CacheHost,
-			net, p.cfg.CacheRootCert, p.cfg.CacheCert, p.cfg.CacheKey)
-		if err == cache.ErrNoVersionRecord || err == cache.ErrWrongVersion {
-			// The cache version record was either not found or
-			// is the wrong version which means that the cache
-			// needs to be built/rebuilt.
+		var db cache.Cache
+		switch p.cfg.CacheType {
+		case cacheTypeLeveldb:
+			leveldbcache.UseLogger(leveldbcacheLog)
+			db, err = leveldbcache.New(p.cfg.DataDir)
+		default:
+			cockroachdb.UseLogger(cockroachdbLog)
+			net := filepath.Base(p.cfg.DataDir)
+			db, err = cockroachdb.New(cockroachdb.UserPoliteiad,
+				p.cfg.CacheHost, net, p.cfg.CacheRootCert,
+				p.cfg.CacheCert, p.cfg.CacheKey)
+		}
+		if err == cache.ErrNoVersionRecord {
+			// The cache version record was not found which
+			// means that the cache needs to be built.
 			p.cfg.BuildCache = true
+		} else if err == cache.ErrWrongVersion {
+			// The cache version record is the wrong version
+			// which means that the cache needs to be migrated
+			// or rebuilt.
+			migrateCache = true
 		} else if err != nil {
-			return fmt.Errorf("cockroachdb new: %v", err)
+			return fmt.Errorf("%v new: %v", p.cfg.CacheType, err)
 		}
 		p.cache = db
+		log.Infof("Cache   : %v", p.cfg.CacheType)
 
 		// Setup the cache tables
 		err = p.cache.Setup()
@@ -1184,28 +1721,38 @@ func _main() error {
 	// Unprivileged routes
 	p.addRoute(http.MethodPost, v1.IdentityRoute, p.getIdentity,
 		permissionPublic)
-	p.addRoute(http.MethodPost, v1.NewRecordRoute, p.newRecord,
-		permissionPublic)
-	p.addRoute(http.MethodPost, v1.UpdateUnvettedRoute, p.updateUnvetted,
-		permissionPublic)
-	p.addRoute(http.MethodPost, v1.UpdateVettedRoute, p.updateVetted,
-		permissionPublic)
+	p.addRoute(http.MethodPost, v1.IdentityHistoryRoute,
+		p.getIdentityHistory, permissionPublic)
+	p.addRoute(http.MethodPost, v1.NewRecordRoute,
+		p.writeRoute(p.newRecord), permissionPublic)
+	p.addRoute(http.MethodPost, v1.UpdateUnvettedRoute,
+		p.writeRoute(p.updateUnvetted), permissionPublic)
+	p.addRoute(http.MethodPost, v1.UpdateVettedRoute,
+		p.writeRoute(p.updateVetted), permissionPublic)
 	p.addRoute(http.MethodPost, v1.GetUnvettedRoute, p.getUnvetted,
 		permissionPublic)
 	p.addRoute(http.MethodPost, v1.GetVettedRoute, p.getVetted,
 		permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetVettedVersionsRoute,
+		p.getVettedVersions, permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetVettedDiffRoute, p.getVettedDiff,
+		permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetVettedAnchorRoute, p.getVettedAnchor,
+		permissionPublic)
 
 	// Routes that require auth
 	p.addRoute(http.MethodPost, v1.InventoryRoute, p.inventory,
 		permissionAuth)
 	p.addRoute(http.MethodPost, v1.SetUnvettedStatusRoute,
-		p.setUnvettedStatus, permissionAuth)
+		p.writeRoute(p.setUnvettedStatus), permissionAuth)
 	p.addRoute(http.MethodPost, v1.SetVettedStatusRoute,
-		p.setVettedStatus, permissionAuth)
+		p.writeRoute(p.setVettedStatus), permissionAuth)
 	p.addRoute(http.MethodPost, v1.UpdateVettedMetadataRoute,
-		p.updateVettedMetadata, permissionAuth)
+		p.writeRoute(p.updateVettedMetadata), permissionAuth)
 	p.addRoute(http.MethodPost, v1.UpdateReadmeRoute,
-		p.updateReadme, permissionAuth)
+		p.writeRoute(p.updateReadme), permissionAuth)
+	p.addRoute(http.MethodPost, v1.RotateIdentityRoute,
+		p.writeRoute(p.rotateIdentity), permissionAuth)
 
 	// Setup plugins
 	plugins, err := p.backend.GetPlugins()
@@ -1229,14 +1776,23 @@ func _main() error {
 			}
 			p.plugins[v.ID] = convertBackendPlugin(v)
 
-			// Register plugin with the cache
+			// Register plugin with the cache.  Plugins that do not
+			// provide a cache implementation are only served by the
+			// backend.
 			cp := convertBackendPluginToCache(v)
 			err := p.cache.RegisterPlugin(cp)
-			if err == cache.ErrNoVersionRecord || err == cache.ErrWrongVersion {
-				// The cache plugin version record was either not found
-				// or it is the wrong version which means that the cache
-				// needs to be built/rebuilt.
+			if err == cache.ErrInvalidPlugin {
+				log.Infof("Registered plugin without cache: %v", v.ID)
+				continue
+			} else if err == cache.ErrNoVersionRecord {
+				// The cache plugin version record was not found which
+				// means that the cache needs to be built.
 				p.cfg.BuildCache = true
+			} else if err == cache.ErrWrongVersion {
+				// The cache plugin version record is the wrong version
+				// which means that the cache needs to be migrated or
+				// rebuilt.
+				migrateCache = true
 			} else if err != nil {
 				return fmt.Errorf("cache register plugin '%v': %v",
 					cp.ID, err)
@@ -1253,6 +1809,24 @@ func _main() error {
 		}
 	}
 
+	// Migrate the cache schema in place unless the cache is rebuilt
+	// anyway.
+	if migrateCache && !p.cfg.BuildCache {
+		err = p.migrateCache()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reconcile the cache with the backend unless the cache is rebuilt
+	// anyway.
+	if p.cfg.ReconcileCache && !p.cfg.BuildCache {
+		err = p.reconcileCache()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Build the cache
 	if p.cfg.BuildCache {
 		// Fetch all versions of all records from the inventory and
@@ -1301,7 +1875,9 @@ func _main() error {
 
 			// Build plugin cache
 			err = p.cache.PluginBuild(v.ID, payload)
-			if err != nil {
+			if err == cache.ErrInvalidPlugin {
+				continue
+			} else if err != nil {
 				return fmt.Errorf("plugin '%v' build cache: %v", v.ID, err)
 			}
 		}
@@ -0,0 +1,219 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/decred/politeia/politeiad/cache"
+)
+
+// sortVersions sorts record versions numerically.  Versions that are not
+// numbers are ordered after all numeric versions.
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		a, erra := strconv.ParseUint(versions[i], 10, 64)
+		b, errb := strconv.ParseUint(versions[j], 10, 64)
+		switch {
+		case erra == nil && errb == nil:
+			return a < b
+		case erra == nil:
+			return true
+		case errb == nil:
+			return false
+		}
+		return versions[i] < versions[j]
+	})
+}
+
+// recordDrift compares all versions of the backend records with the cached
+// record summaries and returns the drift ordered by token.
+func recordDrift(backend map[string][]cache.Record, cached []cache.RecordSummary) []cache.Drift {
+	// Index the summaries by token and version
+	bs := make(map[string]map[string]cache.RecordSummary, len(backend))
+	for token, versions := range backend {
+		bs[token] = make(map[string]cache.RecordSummary, len(versions))
+		for _, v := range versions {
+			bs[token][v.Version] = cache.RecordSummary{
+				Token:    token,
+				Version:  v.Version,
+				Status:   v.Status,
+				Merkle:   v.CensorshipRecord.Merkle,
+				Metadata: cache.MetadataDigest(v.Metadata),
+			}
+		}
+	}
+	cs := make(map[string]map[string]cache.RecordSummary)
+	for _, v := range cached {
+		if _, ok := cs[v.Token]; !ok {
+			cs[v.Token] = make(map[string]cache.RecordSummary)
+		}
+		cs[v.Token][v.Version] = v
+	}
+
+	tokens := make([]string, 0, len(bs)+len(cs))
+	for k := range bs {
+		tokens = append(tokens, k)
+	}
+	for k := range cs {
+		if _, ok := bs[k]; !ok {
+			tokens = append(tokens, k)
+		}
+	}
+	sort.Strings(tokens)
+
+	var drift []cache.Drift
+	add := func(token, format string, args ...interface{}) {
+		drift = append(drift, cache.Drift{
+			Token:  token,
+			Reason: fmt.Sprintf(format, args...),
+		})
+	}
+	for _, token := range tokens {
+		b, ok := bs[token]
+		if !ok {
+			add(token, "record missing from backend")
+			continue
+		}
+		c, ok := cs[token]
+		if !ok {
+			add(token, "record missing from cache")
+			continue
+		}
+
+		versions := make([]string, 0, len(b)+len(c))
+		for k := range b {
+			versions = append(versions, k)
+		}
+		for k := range c {
+			if _, ok := b[k]; !ok {
+				versions = append(versions, k)
+			}
+		}
+		sortVersions(versions)
+
+		for _, version := range versions {
+			bv, ok := b[version]
+			if !ok {
+				add(token, "version %v missing from backend", version)
+				continue
+			}
+			cv, ok := c[version]
+			if !ok {
+				add(token, "version %v missing from cache", version)
+				continue
+			}
+			if bv.Status != cv.Status {
+				add(token, "version %v status: backend %v cache %v",
+					version, bv.Status, cv.Status)
+			}
+			if bv.Merkle != cv.Merkle {
+				add(token, "version %v merkle: backend %v cache %v",
+					version, bv.Merkle, cv.Merkle)
+			}
+			if bv.Metadata != cv.Metadata {
+				add(token, "version %v metadata: backend %v cache %v",
+					version, bv.Metadata, cv.Metadata)
+			}
+		}
+	}
+
+	return drift
+}
+
+// driftTokens returns the distinct tokens of the passed in drift, which must
+// be ordered by token.
+func driftTokens(drift []cache.Drift) []string {
+	tokens := make([]string, 0, len(drift))
+	for i, v := range drift {
+		if i > 0 && drift[i-1].Token == v.Token {
+			continue
+		}
+		tokens = append(tokens, v.Token)
+	}
+	return tokens
+}
+
+// reconcileCache compares the records and the plugin data of the backend
+// with the cache.  The drift is logged per token and only the records that
+// drifted are rebuilt.
+func (p *politeia) reconcileCache() error {
+	r, ok := p.cache.(cache.Reconciler)
+	if !ok {
+		return fmt.Errorf("cache can not be reconciled")
+	}
+
+	log.Infof("Reconciling cache")
+
+	// Reconcile records
+	vetted, unvetted, err := p.backend.Inventory(0, 0, true, true)
+	if err != nil {
+		return fmt.Errorf("backend inventory: %v", err)
+	}
+	records := make(map[string][]cache.Record, len(vetted)+len(unvetted))
+	for _, v := range append(vetted, unvetted...) {
+		cr := p.convertBackendRecordToCache(v)
+		token := cr.CensorshipRecord.Token
+		records[token] = append(records[token], cr)
+	}
+	summaries, err := r.RecordSummaries()
+	if err != nil {
+		return fmt.Errorf("cache record summaries: %v", err)
+	}
+
+	drift := recordDrift(records, summaries)
+	for _, v := range drift {
+		log.Infof("Cache drift %v: %v", v.Token, v.Reason)
+	}
+	tokens := driftTokens(drift)
+	for _, token := range tokens {
+		err := r.ReplaceRecord(token, records[token])
+		if err != nil {
+			return fmt.Errorf("replace record %v: %v", token, err)
+		}
+	}
+	log.Infof("Reconciled %v records", len(tokens))
+
+	// Reconcile plugins
+	for _, v := range p.plugins {
+		var cmd string
+		for _, s := range v.Settings {
+			if s.Key == "inventory" {
+				cmd = s.Value
+			}
+		}
+		if cmd == "" {
+			continue
+		}
+
+		_, payload, err := p.backend.Plugin(cmd, "")
+		if err != nil {
+			return fmt.Errorf("plugin %v inventory: %v", v.ID, err)
+		}
+		drift, err := r.PluginDrift(v.ID, payload)
+		if err == cache.ErrInvalidPlugin {
+			log.Infof("Plugin %v cache can not be reconciled", v.ID)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("plugin %v drift: %v", v.ID, err)
+		}
+		for _, d := range drift {
+			log.Infof("Cache drift %v %v: %v", v.ID, d.Token, d.Reason)
+		}
+		tokens := driftTokens(drift)
+		if len(tokens) == 0 {
+			continue
+		}
+		err = r.PluginReconcile(v.ID, tokens, payload)
+		if err != nil {
+			return fmt.Errorf("plugin %v reconcile: %v", v.ID, err)
+		}
+		log.Infof("Reconciled %v %v records", len(tokens), v.ID)
+	}
+
+	return nil
+}
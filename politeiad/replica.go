@@ -0,0 +1,67 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	v1 "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/backend"
+)
+
+// replica returns true if politeiad runs as a read-only replica.
+func (p *politeia) replica() bool {
+	return p.primaryIdentity != nil
+}
+
+// censorshipHistory returns the identity history of the identity that signed
+// the censorship records that are served.  Read-only replicas serve the
+// records of their primary.
+func (p *politeia) censorshipHistory() []v1.IdentityKey {
+	if !p.replica() {
+		return p.identityHistory()
+	}
+	if len(p.primaryHistory) > 0 {
+		return p.primaryHistory
+	}
+	return []v1.IdentityKey{{
+		PublicKey: hex.EncodeToString(p.primaryIdentity.Key[:]),
+	}}
+}
+
+// verifyCensorshipRecord verifies the censorship record signature of a record
+// against the identity that was active at the record timestamp.  The merkle
+// root is verified as well when the record files are present.
+func (p *politeia) verifyCensorshipRecord(r v1.Record) error {
+	return v1.VerifyWithHistory(p.censorshipHistory(), r.Timestamp,
+		r.CensorshipRecord, r.Files)
+}
+
+// verifyReplicaRecord verifies the censorship record of a backend record
+// before it is served by a read-only replica.  It is a no-op on the primary.
+func (p *politeia) verifyReplicaRecord(br backend.Record) error {
+	if !p.replica() {
+		return nil
+	}
+	return p.verifyCensorshipRecord(p.convertBackendRecord(br))
+}
+
+// readOnly rejects all requests that would modify records on a read-only
+// replica.
+func (p *politeia) readOnly(w http.ResponseWriter, r *http.Request) {
+	log.Errorf("%v Rejected write on read-only replica: %v", remoteAddr(r),
+		r.URL.Path)
+	p.respondWithUserError(w, v1.ErrorStatusReadOnly, nil)
+}
+
+// writeRoute returns the handler of a route that modifies records.  Read-only
+// replicas reject these routes.
+func (p *politeia) writeRoute(handler http.HandlerFunc) http.HandlerFunc {
+	if p.replica() {
+		return p.readOnly
+	}
+	return handler
+}
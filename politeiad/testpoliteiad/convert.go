@@ -6,6 +6,7 @@ package testpoliteiad
 
 import (
 	v1 "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/backend"
 	"github.com/decred/politeia/politeiad/cache"
 )
 
@@ -74,3 +75,61 @@ func convertRecordToCache(r v1.Record) cache.Record {
 		Files:            convertFilesToCache(r.Files),
 	}
 }
+
+func convertRecordStatusToBackend(status v1.RecordStatusT) backend.MDStatusT {
+	s := backend.MDStatusInvalid
+	switch status {
+	case v1.RecordStatusInvalid:
+		s = backend.MDStatusInvalid
+	case v1.RecordStatusNotReviewed:
+		s = backend.MDStatusUnvetted
+	case v1.RecordStatusPublic:
+		s = backend.MDStatusVetted
+	case v1.RecordStatusCensored:
+		s = backend.MDStatusCensored
+	case v1.RecordStatusUnreviewedChanges:
+		s = backend.MDStatusIterationUnvetted
+	case v1.RecordStatusArchived:
+		s = backend.MDStatusArchived
+	}
+	return s
+}
+
+func convertMetadataStreamsToBackend(m []v1.MetadataStream) []backend.MetadataStream {
+	bm := make([]backend.MetadataStream, 0, len(m))
+	for _, v := range m {
+		bm = append(bm, backend.MetadataStream{
+			ID:      v.ID,
+			Payload: v.Payload,
+		})
+	}
+	return bm
+}
+
+func convertFilesToBackend(f []v1.File) []backend.File {
+	files := make([]backend.File, 0, len(f))
+	for _, v := range f {
+		files = append(files, backend.File{
+			Name:    v.Name,
+			MIME:    v.MIME,
+			Digest:  v.Digest,
+			Payload: v.Payload,
+		})
+	}
+	return files
+}
+
+func convertRecordToBackend(r v1.Record) backend.Record {
+	return backend.Record{
+		RecordMetadata: backend.RecordMetadata{
+			Status:    convertRecordStatusToBackend(r.Status),
+			Merkle:    r.CensorshipRecord.Merkle,
+			Timestamp: r.Timestamp,
+			Token:     r.CensorshipRecord.Token,
+		},
+		Version:   r.Version,
+		Metadata:  convertMetadataStreamsToBackend(r.Metadata),
+		Files:     convertFilesToBackend(r.Files),
+		Signature: r.CensorshipRecord.Signature,
+	}
+}
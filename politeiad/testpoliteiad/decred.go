@@ -15,7 +15,8 @@ import (
 )
 
 const (
-	bestBlock uint32 = 1000
+	bestBlock      uint32 = 1000
+	ticketMaturity uint32 = 16
 )
 
 func (p *TestPoliteiad) authorizeVote(payload string) (string, error) {
@@ -61,6 +62,44 @@ func (p *TestPoliteiad) authorizeVote(payload string) (string, error) {
 	return string(avrb), nil
 }
 
+func (p *TestPoliteiad) scheduleVote(payload string) (string, error) {
+	sv, err := decred.DecodeScheduleVote([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	// Sign schedule vote
+	s := p.identity.SignMessage([]byte(sv.Signature))
+	sv.Receipt = hex.EncodeToString(s[:])
+	sv.Timestamp = time.Now().Unix()
+	sv.Version = decred.VersionScheduleVote
+
+	p.Lock()
+	defer p.Unlock()
+
+	// Store schedule vote.  A cancelled or failed schedule keeps the
+	// start vote of the schedule.
+	if sv.Action != decred.ScheduleVoteActionSchedule {
+		sv.StartVote = p.scheduleVotes[sv.Token].StartVote
+	}
+	p.scheduleVotes[sv.Token] = *sv
+
+	// Prepare reply
+	svrb, err := decred.EncodeScheduleVoteReply(
+		decred.ScheduleVoteReply{
+			Action:         sv.Action,
+			StartHeight:    sv.StartHeight,
+			SnapshotHeight: sv.StartHeight - ticketMaturity,
+			Receipt:        sv.Receipt,
+			Timestamp:      sv.Timestamp,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(svrb), nil
+}
+
 func (p *TestPoliteiad) startVote(payload string) (string, error) {
 	sv, err := decred.DecodeStartVote([]byte(payload))
 	if err != nil {
@@ -92,13 +131,54 @@ func (p *TestPoliteiad) startVote(payload string) (string, error) {
 	return string(svrb), nil
 }
 
+func (p *TestPoliteiad) startVoteRunoff(payload string) (string, error) {
+	svr, err := decred.DecodeStartVoteRunoff([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	if len(svr.StartVotes) == 0 {
+		return "", fmt.Errorf("no start votes")
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// All submissions share the same snapshot
+	endHeight := bestBlock + svr.StartVotes[0].Vote.Duration
+	sv := decred.StartVoteReply{
+		Version:          decred.VersionStartVoteReply,
+		StartBlockHeight: strconv.FormatUint(uint64(bestBlock), 10),
+		EndHeight:        strconv.FormatUint(uint64(endHeight), 10),
+		EligibleTickets:  []string{},
+	}
+	for _, v := range svr.StartVotes {
+		p.startVotes[v.Vote.Token] = v
+		p.startVoteReplies[v.Vote.Token] = sv
+	}
+
+	// Prepare reply
+	reply, err := decred.EncodeStartVoteRunoffReply(
+		decred.StartVoteRunoffReply{
+			StartVoteReply: sv,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
+
 // decredExec executes the passed in plugin command.
 func (p *TestPoliteiad) decredExec(pc v1.PluginCommand) (string, error) {
 	switch pc.Command {
 	case decred.CmdStartVote:
 		return p.startVote(pc.Payload)
+	case decred.CmdStartVoteRunoff:
+		return p.startVoteRunoff(pc.Payload)
 	case decred.CmdAuthorizeVote:
 		return p.authorizeVote(pc.Payload)
+	case decred.CmdScheduleVote:
+		return p.scheduleVote(pc.Payload)
 	}
 	return "", fmt.Errorf("invalid plugin command")
 }
@@ -24,6 +24,7 @@ import (
 	decred "github.com/decred/politeia/decredplugin"
 	v1 "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backend"
 	"github.com/decred/politeia/politeiad/cache"
 	"github.com/decred/politeia/util"
 	"github.com/gorilla/mux"
@@ -46,11 +47,13 @@ type TestPoliteiad struct {
 	server   *httptest.Server
 	cache    cache.Cache
 	records  map[string]map[string]v1.Record // [token][version]Record
+	plugins  *backend.Plugins                // Plugins whose hooks are called
 
 	// Decred plugin
 	authorizeVotes   map[string]map[string]decred.AuthorizeVote // [token][version]AuthorizeVote
 	startVotes       map[string]decred.StartVote                // [token]StartVote
 	startVoteReplies map[string]decred.StartVoteReply           // [token]StartVoteReply
+	scheduleVotes    map[string]decred.ScheduleVote             // [token]ScheduleVote
 }
 
 func respondWithUserError(w http.ResponseWriter,
@@ -61,6 +64,16 @@ func respondWithUserError(w http.ResponseWriter,
 	})
 }
 
+// respondWithHookError replies with the error of a failed pre-hook.  Content
+// verification errors are returned to the client.
+func respondWithHookError(w http.ResponseWriter, err error) {
+	if e, ok := err.(backend.ContentVerificationError); ok {
+		respondWithUserError(w, e.ErrorCode, e.ErrorContext)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusInternalServerError, err)
+}
+
 // merkleRoot returns a hex encoded merkle root of the passed in files.
 func merkleRoot(files []v1.File) (string, error) {
 	if len(files) == 0 {
@@ -142,6 +155,18 @@ func (p *TestPoliteiad) record(token string) (*v1.Record, error) {
 	return &r, nil
 }
 
+// postHook calls the post-hooks of the enabled plugins with the changed
+// record.  Like politeiad, a failed post-hook does not undo the change.
+func (p *TestPoliteiad) postHook(h backend.HookT, rc backend.RecordChange, r v1.Record) {
+	br := convertRecordToBackend(r)
+	err := p.plugins.Hook(h, rc, func() (*backend.Record, error) {
+		return &br, nil
+	})
+	if err != nil {
+		log.Printf("%v hook: %v", h, err)
+	}
+}
+
 func (p *TestPoliteiad) handleNewRecord(w http.ResponseWriter, r *http.Request) {
 	// Decode request
 	var t v1.NewRecord
@@ -171,6 +196,16 @@ func (p *TestPoliteiad) handleNewRecord(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Call pre-hooks
+	err = p.plugins.Hook(backend.HookPreNewRecord, backend.RecordChange{
+		MDAppend: convertMetadataStreamsToBackend(t.Metadata),
+		FilesAdd: convertFilesToBackend(t.Files),
+	}, nil)
+	if err != nil {
+		respondWithHookError(w, err)
+		return
+	}
+
 	token := hex.EncodeToString(tokenb)
 	sig := p.identity.SignMessage([]byte(merkle + token))
 	resp := p.identity.SignMessage(challenge)
@@ -181,14 +216,22 @@ func (p *TestPoliteiad) handleNewRecord(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Add record to politeiad store
-	p.addRecord(v1.Record{
+	record := v1.Record{
 		Status:           v1.RecordStatusNotReviewed,
 		Timestamp:        time.Now().Unix(),
 		Version:          "1",
 		Metadata:         t.Metadata,
 		Files:            t.Files,
 		CensorshipRecord: cr,
-	})
+	}
+	p.addRecord(record)
+
+	// Call post-hooks
+	p.postHook(backend.HookPostNewRecord, backend.RecordChange{
+		Token:    token,
+		MDAppend: convertMetadataStreamsToBackend(t.Metadata),
+		FilesAdd: convertFilesToBackend(t.Files),
+	}, record)
 
 	// Send response
 	util.RespondWithJSON(w, http.StatusOK, v1.NewRecordReply{
@@ -233,6 +276,24 @@ func (p *TestPoliteiad) handleSetUnvettedStatus(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// Call pre-hooks
+	change := backend.RecordChange{
+		Token:       t.Token,
+		Vetted:      false,
+		Status:      convertRecordStatusToBackend(t.Status),
+		MDAppend:    convertMetadataStreamsToBackend(t.MDAppend),
+		MDOverwrite: convertMetadataStreamsToBackend(t.MDOverwrite),
+	}
+	br := convertRecordToBackend(*rc)
+	err = p.plugins.Hook(backend.HookPreSetRecordStatus, change,
+		func() (*backend.Record, error) {
+			return &br, nil
+		})
+	if err != nil {
+		respondWithHookError(w, err)
+		return
+	}
+
 	// Overwrite specified metadata
 	for i, j := range rc.Metadata {
 		for _, v := range t.MDOverwrite {
@@ -247,6 +308,7 @@ func (p *TestPoliteiad) handleSetUnvettedStatus(w http.ResponseWriter, r *http.R
 	rc.Timestamp = time.Now().Unix()
 	rc.Metadata = append(rc.Metadata, t.MDAppend...)
 	p.addRecord(*rc)
+	p.postHook(backend.HookPostSetRecordStatus, change, *rc)
 
 	// Update cache
 	s := convertRecordStatusToCache(rc.Status)
@@ -300,6 +362,24 @@ func (p *TestPoliteiad) handleSetVettedStatus(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Call pre-hooks
+	change := backend.RecordChange{
+		Token:       t.Token,
+		Vetted:      true,
+		Status:      convertRecordStatusToBackend(t.Status),
+		MDAppend:    convertMetadataStreamsToBackend(t.MDAppend),
+		MDOverwrite: convertMetadataStreamsToBackend(t.MDOverwrite),
+	}
+	br := convertRecordToBackend(*rc)
+	err = p.plugins.Hook(backend.HookPreSetRecordStatus, change,
+		func() (*backend.Record, error) {
+			return &br, nil
+		})
+	if err != nil {
+		respondWithHookError(w, err)
+		return
+	}
+
 	// Overwrite specified metadata
 	for i, j := range rc.Metadata {
 		for _, v := range t.MDOverwrite {
@@ -314,6 +394,7 @@ func (p *TestPoliteiad) handleSetVettedStatus(w http.ResponseWriter, r *http.Req
 	rc.Timestamp = time.Now().Unix()
 	rc.Metadata = append(rc.Metadata, t.MDAppend...)
 	p.addRecord(*rc)
+	p.postHook(backend.HookPostSetRecordStatus, change, *rc)
 
 	// Update cache
 	s := convertRecordStatusToCache(rc.Status)
@@ -331,6 +412,98 @@ func (p *TestPoliteiad) handleSetVettedStatus(w http.ResponseWriter, r *http.Req
 		})
 }
 
+func (p *TestPoliteiad) handleUpdateVettedMetadata(w http.ResponseWriter, r *http.Request) {
+	// Decode request
+	var t v1.UpdateVettedMetadata
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Verify challenge
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	// Validate token
+	_, err = util.ConvertStringToken(t.Token)
+	if err != nil {
+		respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Lookup record
+	rc, err := p.record(t.Token)
+	if err != nil {
+		if err == errRecordNotFound {
+			respondWithUserError(w, v1.ErrorStatusRecordFound, nil)
+			return
+		}
+
+		util.RespondWithJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if rc.Status != v1.RecordStatusPublic {
+		respondWithUserError(w, v1.ErrorStatusInvalidRecordStatusTransition,
+			nil)
+		return
+	}
+
+	// Call pre-hooks
+	change := backend.RecordChange{
+		Token:       t.Token,
+		Vetted:      true,
+		MDAppend:    convertMetadataStreamsToBackend(t.MDAppend),
+		MDOverwrite: convertMetadataStreamsToBackend(t.MDOverwrite),
+	}
+	br := convertRecordToBackend(*rc)
+	err = p.plugins.Hook(backend.HookPreEditMetadata, change,
+		func() (*backend.Record, error) {
+			return &br, nil
+		})
+	if err != nil {
+		respondWithHookError(w, err)
+		return
+	}
+
+	// Overwrite specified metadata. Streams that do not exist yet
+	// are added.
+	for _, v := range t.MDOverwrite {
+		var found bool
+		for i, j := range rc.Metadata {
+			if j.ID == v.ID {
+				rc.Metadata[i] = v
+				found = true
+			}
+		}
+		if !found {
+			rc.Metadata = append(rc.Metadata, v)
+		}
+	}
+
+	// Update record
+	rc.Metadata = append(rc.Metadata, t.MDAppend...)
+	p.addRecord(*rc)
+	p.postHook(backend.HookPostEditMetadata, change, *rc)
+
+	// Update cache
+	m := convertMetadataStreamsToCache(rc.Metadata)
+	err = p.cache.UpdateRecordMetadata(t.Token, m)
+	if err != nil {
+		log.Printf("cache update record metadata: %v", err)
+	}
+
+	// Send response
+	util.RespondWithJSON(w, http.StatusOK,
+		v1.UpdateVettedMetadataReply{
+			Response: hex.EncodeToString(response[:]),
+		})
+}
+
 // Plugin is a pass through function for plugin commands. The plugin command
 // is executed in politeiad and is then passed to the cache. This function
 // is intended to be used as a way to setup test data.
@@ -379,9 +552,22 @@ func (p *TestPoliteiad) AddRecord(t *testing.T, r v1.Record) {
 	}
 }
 
-// Close shuts down the httptest server.
+// EnablePlugin enables a plugin on the test server.  The hooks of the plugin
+// are called when records are created and when their status changes.  This
+// function is intended to be used as a way to test plugin hooks.
+func (p *TestPoliteiad) EnablePlugin(t *testing.T, d backend.PluginDriver) {
+	t.Helper()
+
+	err := p.plugins.Add(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Close shuts down the httptest server and closes the enabled plugins.
 func (p *TestPoliteiad) Close() {
 	p.server.Close()
+	p.plugins.Close()
 }
 
 // New returns a new TestPoliteiad context.
@@ -400,9 +586,11 @@ func New(t *testing.T, c cache.Cache) *TestPoliteiad {
 		identity:         id,
 		cache:            c,
 		records:          make(map[string]map[string]v1.Record),
+		plugins:          &backend.Plugins{},
 		authorizeVotes:   make(map[string]map[string]decred.AuthorizeVote),
 		startVotes:       make(map[string]decred.StartVote),
 		startVoteReplies: make(map[string]decred.StartVoteReply),
+		scheduleVotes:    make(map[string]decred.ScheduleVote),
 	}
 
 	// Setup routes
@@ -410,6 +598,8 @@ func New(t *testing.T, c cache.Cache) *TestPoliteiad {
 	router.HandleFunc(v1.NewRecordRoute, p.handleNewRecord)
 	router.HandleFunc(v1.SetUnvettedStatusRoute, p.handleSetUnvettedStatus)
 	router.HandleFunc(v1.SetVettedStatusRoute, p.handleSetVettedStatus)
+	router.HandleFunc(v1.UpdateVettedMetadataRoute,
+		p.handleUpdateVettedMetadata)
 
 	// Setup the test server
 	p.server = httptest.NewServer(router)
@@ -58,6 +58,8 @@ func usage() {
 		" that are being voted on\n")
 	fmt.Fprintf(os.Stderr, "  vote               - Vote on a proposal\n")
 	fmt.Fprintf(os.Stderr, "  tally              - Tally votes on a proposal\n")
+	fmt.Fprintf(os.Stderr, "  runoff             - Show the runoff vote "+
+		"results of an RFP\n")
 	//fmt.Fprintf(os.Stderr, "  startvote          - Instruct vote to start "+
 	//	"(admin only)\n")
 	fmt.Fprintf(os.Stderr, "\n")
@@ -424,20 +426,36 @@ func (c *ctx) inventory() error {
 		// Display vote bits
 		fmt.Printf("Vote: %v\n", v.StartVote.Vote.Token)
 		fmt.Printf("  Proposal        : %v\n", v.Proposal.Name)
+		if v.Proposal.LinkTo != "" {
+			fmt.Printf("  RFP             : %v\n", v.Proposal.LinkTo)
+		}
 		fmt.Printf("  Start block     : %v\n", v.StartVoteReply.StartBlockHeight)
 		fmt.Printf("  End block       : %v\n", v.StartVoteReply.EndHeight)
+		fmt.Printf("  Vote type       : %v\n",
+			v1.VoteType[v.StartVote.Vote.Type])
 		fmt.Printf("  Mask            : %v\n", v.StartVote.Vote.Mask)
 		fmt.Printf("  Eligible tickets: %v\n", len(ctres.TicketAddresses))
+		ranked := v.StartVote.Vote.Type == v1.VoteTypeRankedChoice
+		ids := make([]string, 0, len(v.StartVote.Vote.Options))
 		for _, vo := range v.StartVote.Vote.Options {
+			ids = append(ids, vo.Id)
 			fmt.Printf("  Vote Option:\n")
 			fmt.Printf("    Id                   : %v\n", vo.Id)
 			fmt.Printf("    Description          : %v\n",
 				vo.Description)
 			fmt.Printf("    Bits                 : %v\n", vo.Bits)
+			if ranked {
+				continue
+			}
 			fmt.Printf("    To choose this option: "+
 				"politeiavoter vote %v %v\n", v.StartVote.Vote.Token,
 				vo.Id)
 		}
+		if ranked {
+			fmt.Printf("  To rank the options    : "+
+				"politeiavoter vote %v %v\n", v.StartVote.Vote.Token,
+				strings.Join(ids, ","))
+		}
 	}
 
 	return nil
@@ -659,6 +677,46 @@ func verifyV1Vote(address string, vote *v1.CastVote) bool {
 	return true
 }
 
+// voteBitFromID returns the hex encoded vote bit of the passed in vote option
+// ID.  The vote option IDs of a ranked choice vote are comma separated and
+// ordered by preference.
+func voteBitFromID(vote v1.Vote, voteId string) (string, error) {
+	bits := make(map[string]uint64, len(vote.Options))
+	for _, v := range vote.Options {
+		bits[v.Id] = v.Bits
+	}
+
+	if vote.Type != v1.VoteTypeRankedChoice {
+		b, ok := bits[voteId]
+		if !ok {
+			return "", fmt.Errorf("vote id not found: %v", voteId)
+		}
+		return strconv.FormatUint(b, 16), nil
+	}
+
+	// Pack the ranked options starting with the most preferred option
+	// in the least significant bits.
+	ids := strings.Split(voteId, ",")
+	if len(ids)*v1.VoteRankedChoiceBits > 64 {
+		return "", fmt.Errorf("too many preferences: %v", len(ids))
+	}
+	var voteBit uint64
+	ranked := make(map[string]bool, len(ids))
+	for i, id := range ids {
+		b, ok := bits[id]
+		if !ok {
+			return "", fmt.Errorf("vote id not found: %v", id)
+		}
+		if ranked[id] {
+			return "", fmt.Errorf("duplicate vote id: %v", id)
+		}
+		ranked[id] = true
+		voteBit |= b << uint(i*v1.VoteRankedChoiceBits)
+	}
+
+	return strconv.FormatUint(voteBit, 16), nil
+}
+
 func (c *ctx) _vote(seed int64, token, voteId string) ([]string, *v1.BallotReply, error) {
 	// _tally provides the eligible tickets snapshot as well as a list of
 	// the votes that have already been cast. We use these to filter out
@@ -669,20 +727,9 @@ func (c *ctx) _vote(seed int64, token, voteId string) ([]string, *v1.BallotReply
 	}
 
 	// Validate voteId
-	var (
-		voteBit string
-		found   bool
-	)
-	for _, vv := range vrr.StartVote.Vote.Options {
-		if vv.Id == voteId {
-			found = true
-			voteBit = strconv.FormatUint(vv.Bits, 16)
-			break
-		}
-	}
-	if !found {
-		return nil, nil, fmt.Errorf("vote id not found: %v",
-			voteId)
+	voteBit, err := voteBitFromID(vrr.StartVote.Vote, voteId)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Find eligble tickets
@@ -883,14 +930,18 @@ func (c *ctx) tally(args []string) error {
 		return err
 	}
 
-	// tally votes
+	// tally votes, ranked choice votes are counted by their first
+	// preference
 	count := make(map[uint64]uint)
 	var total uint
 	for _, v := range t.CastVotes {
-		bits, err := strconv.ParseUint(v.VoteBit, 10, 64)
+		bits, err := strconv.ParseUint(v.VoteBit, 16, 64)
 		if err != nil {
 			return err
 		}
+		if t.StartVote.Vote.Type == v1.VoteTypeRankedChoice {
+			bits &= 1<<v1.VoteRankedChoiceBits - 1
+		}
 		count[bits]++
 		total++
 	}
@@ -918,6 +969,58 @@ func (c *ctx) tally(args []string) error {
 	return nil
 }
 
+func (c *ctx) _runoff(token string) (*v1.VoteRunoffReply, error) {
+	responseBody, err := c.makeRequest("GET", "/proposals/"+token+"/runoff",
+		nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var vrr v1.VoteRunoffReply
+	err = json.Unmarshal(responseBody, &vrr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal "+
+			"VoteRunoffReply: %v", err)
+	}
+
+	return &vrr, nil
+}
+
+func (c *ctx) runoff(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("runoff: not enough arguments %v", args)
+	}
+
+	r, err := c._runoff(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Dump
+	fmt.Printf("Runoff: %v\n", r.Token)
+	fmt.Printf("  Status          : %v\n", v1.PropVoteStatus[r.Status])
+	fmt.Printf("  Start block     : %v\n", r.StartBlockHeight)
+	fmt.Printf("  End block       : %v\n", r.EndHeight)
+	fmt.Printf("  Eligible tickets: %v\n", r.NumOfEligibleVotes)
+	for _, v := range r.Submissions {
+		fmt.Printf("  Submission: %v\n", v.Token)
+		fmt.Printf("    Total votes          : %v\n", v.TotalVotes)
+		fmt.Printf("    Quorum met           : %v\n", v.QuorumMet)
+		fmt.Printf("    Approved             : %v\n", v.Approved)
+		for _, vo := range v.OptionsResult {
+			fmt.Printf("    Votes %-15v: %v\n", vo.Option.Id,
+				vo.VotesReceived)
+		}
+	}
+	winner := r.Winner
+	if winner == "" {
+		winner = "none"
+	}
+	fmt.Printf("  Winner          : %v\n", winner)
+
+	return nil
+}
+
 func (c *ctx) login(email, password string) (*v1.LoginReply, error) {
 	l := v1.Login{
 		Email:    email,
@@ -1053,6 +1156,8 @@ func _main() error {
 		err = c.startVote(args[1:])
 	case "tally":
 		err = c.tally(args[1:])
+	case "runoff":
+		err = c.runoff(args[1:])
 	case "vote":
 		err = c.vote(seed, args[1:])
 	default:
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -8,6 +9,7 @@ type ErrorStatusT int
 type PropStateT int
 type PropStatusT int
 type PropVoteStatusT int
+type VoteT int
 type UserManageActionT int
 type EmailNotificationT int
 
@@ -35,6 +37,7 @@ const (
 	RouteVerifyResetPassword      = "/user/password/reset/verify"
 	RouteUserProposals            = "/user/proposals"
 	RouteUserProposalCredits      = "/user/proposals/credits"
+	RouteUserDrafts               = "/user/drafts"
 	RouteUserCommentsLikes        = "/user/proposals/{token:[A-z0-9]{64}}/commentslikes"
 	RouteVerifyUserPayment        = "/user/verifypayment"
 	RouteUserPaymentsRescan       = "/user/payments/rescan"
@@ -42,13 +45,19 @@ const (
 	RouteEditUser                 = "/user/edit"
 	RouteUsers                    = "/users"
 	RouteTokenInventory           = "/proposals/tokeninventory"
+	RouteSearchProposals          = "/proposals/search"
 	RouteBatchProposals           = "/proposals/batch"
 	RouteAllVetted                = "/proposals/vetted"
 	RouteAllUnvetted              = "/proposals/unvetted"
 	RouteNewProposal              = "/proposals/new"
 	RouteEditProposal             = "/proposals/edit"
+	RouteInviteCoAuthor           = "/proposals/coauthors/invite"
+	RouteSignCoAuthor             = "/proposals/coauthors/sign"
 	RouteAuthorizeVote            = "/proposals/authorizevote"
 	RouteStartVote                = "/proposals/startvote"
+	RouteStartVoteRunoff          = "/proposals/startvoterunoff"
+	RouteScheduleVote             = "/proposals/schedulevote"
+	RouteCancelVote               = "/proposals/cancelvote"
 	RouteActiveVote               = "/proposals/activevote" // XXX rename to ActiveVotes
 	RouteCastVotes                = "/proposals/castvotes"
 	RouteAllVoteStatus            = "/proposals/votestatus"
@@ -60,9 +69,17 @@ const (
 	RouteCommentsGet              = "/proposals/{token:[A-z0-9]{64}}/comments"
 	RouteVoteResults              = "/proposals/{token:[A-z0-9]{64}}/votes"
 	RouteVoteStatus               = "/proposals/{token:[A-z0-9]{64}}/votestatus"
+	RouteVoteRunoff               = "/proposals/{token:[A-z0-9]{64}}/runoff"
+	RouteVoteBundle               = "/proposals/{token:[A-z0-9]{64}}/votebundle"
+	RouteProposalHistory          = "/proposals/{token:[A-z0-9]{64}}/history"
+	RouteProposalAnchor           = "/proposals/{token:[A-z0-9]{64}}/anchor"
 	RouteNewComment               = "/comments/new"
 	RouteLikeComment              = "/comments/like"
 	RouteCensorComment            = "/comments/censor"
+	RouteNewDraft                 = "/drafts/new"
+	RouteEditDraft                = "/drafts/edit"
+	RouteDeleteDraft              = "/drafts/delete"
+	RouteSubmitDraft              = "/drafts/submit"
 	RouteUnauthenticatedWebSocket = "/ws"
 	RouteAuthenticatedWebSocket   = "/aws"
 
@@ -117,6 +134,22 @@ const (
 	// for the routes that return lists of users
 	UserListPageSize = 20
 
+	// PolicyMaxDrafts is the maximum number of proposal drafts that
+	// a user can store
+	PolicyMaxDrafts = 10
+
+	// PolicyMaxCoAuthors is the maximum number of co-authors that can
+	// be invited to a proposal
+	PolicyMaxCoAuthors = 10
+
+	// PolicyLinkByMinPeriod is the minimum amount of time, in seconds,
+	// between the submission of an RFP and its linkby deadline
+	PolicyLinkByMinPeriod = 14 * 24 * 60 * 60 // 2 weeks
+
+	// PolicyLinkByMaxPeriod is the maximum amount of time, in seconds,
+	// between the submission of an RFP and its linkby deadline
+	PolicyLinkByMaxPeriod = 180 * 24 * 60 * 60 // 6 months
+
 	// Error status codes
 	ErrorStatusInvalid                     ErrorStatusT = 0
 	ErrorStatusInvalidPassword             ErrorStatusT = 1
@@ -181,6 +214,22 @@ const (
 	ErrorStatusMaxProposalsExceededPolicy  ErrorStatusT = 61
 	ErrorStatusDuplicateComment            ErrorStatusT = 62
 	ErrorStatusInvalidLogin                ErrorStatusT = 63
+	ErrorStatusProposalNotAnchored         ErrorStatusT = 64
+	ErrorStatusInvalidSearchQuery          ErrorStatusT = 65
+	ErrorStatusDraftNotFound               ErrorStatusT = 66
+	ErrorStatusMaxDraftsExceededPolicy     ErrorStatusT = 67
+	ErrorStatusUserNotCoAuthor             ErrorStatusT = 68
+	ErrorStatusCoAuthorAlreadyInvited      ErrorStatusT = 69
+	ErrorStatusMaxCoAuthorsExceededPolicy  ErrorStatusT = 70
+	ErrorStatusCoAuthorSignaturesMissing   ErrorStatusT = 71
+	ErrorStatusInvalidLinkTo               ErrorStatusT = 72
+	ErrorStatusInvalidLinkBy               ErrorStatusT = 73
+	ErrorStatusLinkByDeadlineExpired       ErrorStatusT = 74
+	ErrorStatusLinkByDeadlineNotExpired    ErrorStatusT = 75
+	ErrorStatusInvalidRunoffVote           ErrorStatusT = 76
+	ErrorStatusRunoffVoteNotFound          ErrorStatusT = 77
+	ErrorStatusInvalidVoteType             ErrorStatusT = 78
+	ErrorStatusInvalidStartHeight          ErrorStatusT = 79
 
 	// Proposal state codes
 	//
@@ -214,6 +263,29 @@ const (
 	PropVoteStatusStarted       PropVoteStatusT = 3 // Proposal vote has been started
 	PropVoteStatusFinished      PropVoteStatusT = 4 // Proposal vote has been finished
 	PropVoteStatusDoesntExist   PropVoteStatusT = 5 // Proposal doesn't exist
+	PropVoteStatusScheduled     PropVoteStatusT = 6 // Proposal vote has been scheduled
+
+	// Vote types
+	//
+	// An approval vote approves or rejects the proposal using the vote
+	// option IDs VoteOptionIDApprove and VoteOptionIDReject.  A multiple
+	// choice vote chooses a single option.  A ranked choice vote ranks
+	// the options by preference.  Votes that do not set a type are
+	// approval votes.
+	VoteTypeInvalid        VoteT = 0 // Not set, treated as approval
+	VoteTypeApproval       VoteT = 1 // Approve or reject the proposal
+	VoteTypeMultipleChoice VoteT = 2 // Choose a single option
+	VoteTypeRankedChoice   VoteT = 3 // Rank the options by preference
+
+	// Approval vote option IDs
+	VoteOptionIDApprove = "yes"
+	VoteOptionIDReject  = "no"
+
+	// VoteRankedChoiceBits is the number of vote bits that are used for
+	// a single preference of a ranked choice vote bit.  A ranked choice
+	// vote bit contains the bits of the ranked options, starting with
+	// the most preferred option in the least significant bits.
+	VoteRankedChoiceBits = 4
 
 	// User manage actions
 	UserManageInvalid                         UserManageActionT = 0 // Invalid action type
@@ -320,6 +392,22 @@ var (
 		ErrorStatusNoProposalChanges:           "no changes found in proposal",
 		ErrorStatusDuplicateComment:            "duplicate comment",
 		ErrorStatusInvalidLogin:                "invalid login credentials",
+		ErrorStatusProposalNotAnchored:         "proposal has not been anchored yet",
+		ErrorStatusInvalidSearchQuery:          "invalid search query",
+		ErrorStatusDraftNotFound:               "draft not found",
+		ErrorStatusMaxDraftsExceededPolicy:     "maximum number of drafts exceeded",
+		ErrorStatusUserNotCoAuthor:             "user is not a proposal co-author",
+		ErrorStatusCoAuthorAlreadyInvited:      "co-author has already been invited",
+		ErrorStatusMaxCoAuthorsExceededPolicy:  "maximum number of co-authors exceeded",
+		ErrorStatusCoAuthorSignaturesMissing:   "co-author signatures missing",
+		ErrorStatusInvalidLinkTo:               "invalid proposal linkto",
+		ErrorStatusInvalidLinkBy:               "invalid proposal linkby",
+		ErrorStatusLinkByDeadlineExpired:       "rfp linkby deadline has expired",
+		ErrorStatusLinkByDeadlineNotExpired:    "rfp linkby deadline has not expired",
+		ErrorStatusInvalidRunoffVote:           "invalid runoff vote",
+		ErrorStatusRunoffVoteNotFound:          "runoff vote not found",
+		ErrorStatusInvalidVoteType:             "invalid vote type",
+		ErrorStatusInvalidStartHeight:          "invalid vote start height",
 	}
 
 	// PropStatus converts propsal status codes to human readable text
@@ -340,6 +428,15 @@ var (
 		PropVoteStatusStarted:       "voting active",
 		PropVoteStatusFinished:      "voting finished",
 		PropVoteStatusDoesntExist:   "proposal does not exist",
+		PropVoteStatusScheduled:     "voting scheduled",
+	}
+
+	// VoteType converts vote types to human readable text
+	VoteType = map[VoteT]string{
+		VoteTypeInvalid:        "approval",
+		VoteTypeApproval:       "approval",
+		VoteTypeMultipleChoice: "multiple choice",
+		VoteTypeRankedChoice:   "ranked choice",
 	}
 
 	// UserManageAction converts user edit actions to human readable text
@@ -374,11 +471,26 @@ type File struct {
 // The Merkle field contains the ordered merkle root of all files in the proposal.
 // The Token field contains a random censorship token that is signed by the
 // server private key.  The token can be used on the client to verify the
-// authenticity of the CensorshipRecord.
+// authenticity of the CensorshipRecord.  The FileProofs field is only set
+// when the proposal files are returned and contains the merkle inclusion
+// proof of every file.
 type CensorshipRecord struct {
-	Token     string `json:"token"`     // Censorship token
-	Merkle    string `json:"merkle"`    // Merkle root of proposal
-	Signature string `json:"signature"` // Server side signature of []byte(Merkle+Token)
+	Token      string      `json:"token"`                // Censorship token
+	Merkle     string      `json:"merkle"`               // Merkle root of proposal
+	Signature  string      `json:"signature"`            // Server side signature of []byte(Merkle+Token)
+	FileProofs []FileProof `json:"fileproofs,omitempty"` // Inclusion proofs of the files
+}
+
+// FileProof is the merkle inclusion proof of a single file in the
+// CensorshipRecord merkle root.  Index refers to the position of the file
+// digest in the sorted digests and not to the position of the file in the
+// proposal.
+type FileProof struct {
+	Name      string   `json:"name"`      // Filename
+	Digest    string   `json:"digest"`    // SHA256 digest of the file payload
+	Index     uint32   `json:"index"`     // Index of the sorted digest
+	NumLeaves uint32   `json:"numleaves"` // Number of files in the proposal
+	Hashes    []string `json:"hashes"`    // Merkle branch, leaf to root
 }
 
 // ProposalRecord is an entire proposal and it's content.
@@ -398,10 +510,27 @@ type ProposalRecord struct {
 	PublishedAt         int64       `json:"publishedat,omitempty"`         // The timestamp of when the proposal has been published
 	CensoredAt          int64       `json:"censoredat,omitempty"`          // The timestamp of when the proposal has been censored
 	AbandonedAt         int64       `json:"abandonedat,omitempty"`         // The timestamp of when the proposal has been abandoned
+	CoAuthors           []CoAuthor  `json:"coauthors,omitempty"`           // Co-authors invited by the author
+	LinkTo              string      `json:"linkto,omitempty"`              // Token of the RFP that the proposal is submitted to
+	LinkBy              int64       `json:"linkby,omitempty"`              // RFP submission deadline, only set for RFPs
+	LinkedFrom          []string    `json:"linkedfrom,omitempty"`          // Tokens of the public submissions to the RFP
 
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
 
+// CoAuthor is a user that was invited by the proposal author to co-author
+// the proposal.  The co-author approves the proposal by signing the merkle
+// root of the proposal files.  Signed is only set when the signature covers
+// the current version of the proposal; edits require co-authors to sign
+// again.
+type CoAuthor struct {
+	UserID    string `json:"userid"`    // Co-author user ID
+	Username  string `json:"username"`  // Co-author username
+	PublicKey string `json:"publickey"` // Key used for signature
+	Signature string `json:"signature"` // Signature of merkle root
+	Signed    bool   `json:"signed"`    // Current version has been signed
+}
+
 // ProposalCredit contains the details of a proposal credit that has been
 // purchased by the user.
 type ProposalCredit struct {
@@ -717,10 +846,19 @@ type ProposalPaywallPaymentReply struct {
 }
 
 // NewProposal attempts to submit a new proposal.
+//
+// A proposal that sets LinkBy is a request for proposals (RFP).  LinkBy is
+// the deadline, as a unix timestamp, for submissions to the RFP.  A proposal
+// that sets LinkTo is submitted to the public RFP with that token and can
+// only be submitted before the RFP deadline.  A proposal can not set both.
+// LinkTo and LinkBy are not part of the signed merkle root; they are server
+// metadata that is validated and stored by the server.
 type NewProposal struct {
-	Files     []File `json:"files"`     // Proposal files
-	PublicKey string `json:"publickey"` // Key used for signature.
-	Signature string `json:"signature"` // Signature of merkle root
+	Files     []File `json:"files"`            // Proposal files
+	PublicKey string `json:"publickey"`        // Key used for signature.
+	Signature string `json:"signature"`        // Signature of merkle root
+	LinkTo    string `json:"linkto,omitempty"` // Token of the RFP to link to
+	LinkBy    int64  `json:"linkby,omitempty"` // RFP submission deadline
 }
 
 // NewProposalReply is used to reply to the NewProposal command
@@ -741,6 +879,62 @@ type ProposalDetailsReply struct {
 	Proposal ProposalRecord `json:"proposal"`
 }
 
+// ProposalFileDiff describes how a single proposal file changed between two
+// proposal versions.  Diff contains a unified diff and is only set for text
+// files.
+type ProposalFileDiff struct {
+	Name       string `json:"name"`                 // Filename
+	Action     string `json:"action"`               // added, deleted or modified
+	MIME       string `json:"mime"`                 // MIME type
+	FromDigest string `json:"fromdigest,omitempty"` // Digest in previous version
+	ToDigest   string `json:"todigest,omitempty"`   // Digest in this version
+	Diff       string `json:"diff,omitempty"`       // Unified diff
+}
+
+// ProposalVersion describes a single version of a proposal.  Files contains
+// the file changes with respect to the previous version and is empty for the
+// first version.
+type ProposalVersion struct {
+	Version   string             `json:"version"`   // Proposal version
+	Status    PropStatusT        `json:"status"`    // Status of this version
+	Timestamp int64              `json:"timestamp"` // Last update of this version
+	Merkle    string             `json:"merkle"`    // Merkle root of all files
+	Files     []ProposalFileDiff `json:"files"`     // Changes from previous version
+}
+
+// ProposalHistoryReply is used to reply to a proposal history command.  The
+// versions are ordered from oldest to newest.
+type ProposalHistoryReply struct {
+	Token    string            `json:"token"`    // Censorship token
+	Versions []ProposalVersion `json:"versions"` // All proposal versions
+}
+
+// ProposalAnchor is used to request the dcrtime anchor of a public proposal
+// version.  The latest version is used when Version is not provided.
+type ProposalAnchor struct {
+	Token   string `json:"token"`             // Censorship token
+	Version string `json:"version,omitempty"` // Proposal version
+}
+
+// ProposalAnchorReply contains the proof that a proposal version was
+// timestamped in dcrtime.  It is passed through unmodified from politeiad and
+// can be verified with politeia_verify.
+//
+// Commit is the git commit that contains the proposal version and Trees are
+// the git trees from the repository root down to the proposal files.  The
+// commit is one of Digests and Merkle is the merkle root of Digests.
+// ChainInformation is only set once the anchor has been confirmed.
+type ProposalAnchorReply struct {
+	Token            string          `json:"token"`                      // Censorship token
+	Version          string          `json:"version"`                    // Proposal version
+	Commit           string          `json:"commit"`                     // Base64 encoded git commit
+	Trees            []string        `json:"trees"`                      // Base64 encoded git trees
+	Path             []string        `json:"path"`                       // Tree entry names
+	Digests          []string        `json:"digests"`                    // Digests of all anchored commits
+	Merkle           string          `json:"merkle"`                     // Merkle root of digests
+	ChainInformation json.RawMessage `json:"chaininformation,omitempty"` // dcrtime proof
+}
+
 // BatchProposals is used to request the details of multiple proposals. The
 // returned proposals do not include the proposal files.
 type BatchProposals struct {
@@ -838,6 +1032,10 @@ type PolicyReply struct {
 	MaxProposalNameLength      uint     `json:"maxproposalnamelength"`
 	ProposalNameSupportedChars []string `json:"proposalnamesupportedchars"`
 	MaxCommentLength           uint     `json:"maxcommentlength"`
+	MaxDrafts                  uint     `json:"maxdrafts"`
+	MaxCoAuthors               uint     `json:"maxcoauthors"`
+	LinkByMinPeriod            int64    `json:"linkbyminperiod"`
+	LinkByMaxPeriod            int64    `json:"linkbymaxperiod"`
 	BackendPublicKey           string   `json:"backendpublickey"`
 }
 
@@ -849,8 +1047,11 @@ type VoteOption struct {
 }
 
 // Vote represents the vote options for vote that is identified by its token.
+// The vote type determines how the options are validated and how the votes
+// are tallied.
 type Vote struct {
 	Token            string       `json:"token"`            // Token that identifies vote
+	Type             VoteT        `json:"type"`             // Vote type
 	Mask             uint64       `json:"mask"`             // Valid votebits
 	Duration         uint32       `json:"duration"`         // Duration in blocks
 	QuorumPercentage uint32       `json:"quorumpercentage"` // Percent of eligible votes required for quorum
@@ -868,6 +1069,19 @@ type ProposalVoteTuple struct {
 	StartVoteReply StartVoteReply `json:"startvotereply"` // Eligible tickets and other details
 }
 
+// VoteBundle retrieves the signed vote bundle of a single proposal from the
+// server.
+type VoteBundle struct{}
+
+// VoteBundleReply returns the vote bundle of a proposal.  The bundle contains
+// the vote authorizations, the start vote, the ticket snapshot and the cast
+// votes along with the ticket commitment addresses.  It is signed by
+// politeiad so that the vote can be verified and tallied offline, see
+// politeiatally.
+type VoteBundleReply struct {
+	Bundle json.RawMessage `json:"bundle"` // decredplugin VoteBundleReply
+}
+
 // ActiveVoteReply returns all proposals that have active votes.
 type ActiveVoteReply struct {
 	Votes []ProposalVoteTuple `json:"votes"` // Active votes
@@ -877,7 +1091,7 @@ type ActiveVoteReply struct {
 
 // AuthorizeVote is used to indicate that a proposal has been finalized and
 // is ready to be voted on.  The signature and public key are from the
-// proposal author.  The author can revoke a previously sent vote authorization
+// proposal author or one of its co-authors.  The author can revoke a previously sent vote authorization
 // by setting the Action field to revoke.
 type AuthorizeVote struct {
 	Action    string `json:"action"`    // Authorize or revoke
@@ -893,6 +1107,29 @@ type AuthorizeVoteReply struct {
 	Receipt string `json:"receipt"` // Server signature of client signature
 }
 
+// InviteCoAuthor is used by the proposal author to invite a user to co-author
+// the proposal.  Co-authors can only be invited before the vote has been
+// authorized.
+type InviteCoAuthor struct {
+	Token  string `json:"token"`  // Proposal token
+	UserID string `json:"userid"` // User ID of the co-author
+}
+
+// InviteCoAuthorReply is used to reply to the InviteCoAuthor command.
+type InviteCoAuthorReply struct{}
+
+// SignCoAuthor is used by an invited co-author to approve the current
+// version of a proposal.  The signature is of the proposal merkle root, the
+// same message that is signed by the proposal author.
+type SignCoAuthor struct {
+	Token     string `json:"token"`     // Proposal token
+	PublicKey string `json:"publickey"` // Key used for signature
+	Signature string `json:"signature"` // Signature of merkle root
+}
+
+// SignCoAuthorReply is used to reply to the SignCoAuthor command.
+type SignCoAuthorReply struct{}
+
 // StartVote starts the voting process for a proposal.
 type StartVote struct {
 	PublicKey string `json:"publickey"` // Key used for signature.
@@ -908,6 +1145,60 @@ type StartVoteReply struct {
 	EligibleTickets  []string `json:"eligibletickets"`  // Valid voting tickets
 }
 
+// ScheduleVote schedules the start of a proposal vote at a future block
+// height.  The vote starts once the chain reaches the start height and its
+// ticket snapshot is taken at the start height minus the ticket maturity.  A
+// scheduled vote may be rescheduled by an admin and cancelled by the author
+// of the proposal until the start height has been reached.
+type ScheduleVote struct {
+	StartVote   StartVote `json:"startvote"`   // Vote to start
+	StartHeight uint32    `json:"startheight"` // Block height the vote starts at
+	Signature   string    `json:"signature"`   // Signature of token+startheight+"schedule"
+	PublicKey   string    `json:"publickey"`   // Key used for signature
+}
+
+// ScheduleVoteReply returns the start height and the ticket snapshot height
+// of the scheduled vote.
+type ScheduleVoteReply struct {
+	StartHeight    uint32 `json:"startheight"`    // Block height the vote starts at
+	SnapshotHeight uint32 `json:"snapshotheight"` // Block height of the ticket snapshot
+	Receipt        string `json:"receipt"`        // Server signature of client signature
+}
+
+// CancelVote cancels a scheduled vote before its start height has been
+// reached.  Only the author of the proposal may cancel a scheduled vote.
+type CancelVote struct {
+	Token       string `json:"token"`       // Proposal token
+	StartHeight uint32 `json:"startheight"` // Start height of the scheduled vote
+	Signature   string `json:"signature"`   // Signature of token+startheight+"cancel"
+	PublicKey   string `json:"publickey"`   // Key used for signature
+}
+
+// CancelVoteReply returns a receipt if the scheduled vote was successfully
+// cancelled.
+type CancelVoteReply struct {
+	Receipt string `json:"receipt"` // Server signature of client signature
+}
+
+// StartVoteRunoff starts a runoff vote between the public submissions of an
+// RFP once the linkby deadline of the RFP has expired.  The votes of all
+// submissions share the same ticket snapshot and start and end heights.  The
+// vote of every submission must have been authorized by its author and all
+// votes must have the same duration.
+type StartVoteRunoff struct {
+	Token string      `json:"token"` // RFP token
+	Votes []StartVote `json:"votes"` // Votes of the submissions
+}
+
+// StartVoteRunoffReply returns the eligible ticket pool that is shared by
+// all runoff submissions.
+type StartVoteRunoffReply struct {
+	StartBlockHeight string   `json:"startblockheight"` // Block height
+	StartBlockHash   string   `json:"startblockhash"`   // Block hash
+	EndHeight        string   `json:"endheight"`        // Height of vote end
+	EligibleTickets  []string `json:"eligibletickets"`  // Valid voting tickets
+}
+
 // CastVote is a signed vote.
 type CastVote struct {
 	Token     string `json:"token"`     // Proposal ID
@@ -1058,17 +1349,63 @@ type VoteOptionResult struct {
 // public proposal
 type VoteStatus struct{}
 
-// VoteStatusReply describes the vote status for a given proposal
+// VoteRound describes a single counting round of a ranked choice vote.  Each
+// ballot counts towards its most preferred option that has not been
+// eliminated yet.
+type VoteRound struct {
+	OptionsResult []VoteOptionResult `json:"optionsresult"` // VoteOptionResult for each remaining option
+	Eliminated    []string           `json:"eliminated"`    // Options eliminated after the round
+}
+
+// VoteStatusReply describes the vote status for a given proposal.  The
+// results are tallied according to the vote type.  The options result of a
+// ranked choice vote contains the first preferences and the rounds contain
+// the instant runoff count.  Winner is the ID of the winning option and is
+// set while the vote is active as well.
 type VoteStatusReply struct {
-	Token              string             `json:"token"`              // Censorship token
+	Token              string             `json:"token"`                     // Censorship token
+	Type               VoteT              `json:"type"`                      // Vote type
+	Status             PropVoteStatusT    `json:"status"`                    // Vote status (finished, started, etc)
+	TotalVotes         uint64             `json:"totalvotes"`                // Proposal's total number of votes
+	OptionsResult      []VoteOptionResult `json:"optionsresult"`             // VoteOptionResult for each option
+	EndHeight          string             `json:"endheight"`                 // Vote end height
+	BestBlock          string             `json:"bestblock"`                 // Current best block height
+	NumOfEligibleVotes int                `json:"numofeligiblevotes"`        // Total number of eligible votes
+	QuorumPercentage   uint32             `json:"quorumpercentage"`          // Percent of eligible votes required for quorum
+	PassPercentage     uint32             `json:"passpercentage"`            // Percent of total votes required to pass
+	QuorumMet          bool               `json:"quorummet"`                 // Quorum has been met
+	Winner             string             `json:"winner,omitempty"`          // ID of the winning option
+	Approved           bool               `json:"approved"`                  // Quorum met and a winner was found
+	Rounds             []VoteRound        `json:"rounds,omitempty"`          // Ranked choice rounds
+	ScheduledHeight    uint32             `json:"scheduledheight,omitempty"` // Start height of a scheduled vote
+}
+
+// VoteRunoff is a command to fetch the runoff vote results of an RFP.
+type VoteRunoff struct{}
+
+// RunoffSubmission describes the vote results of a single runoff submission.
+type RunoffSubmission struct {
+	Token         string             `json:"token"`         // Censorship token
+	TotalVotes    uint64             `json:"totalvotes"`    // Total number of votes
+	OptionsResult []VoteOptionResult `json:"optionsresult"` // VoteOptionResult for each option
+	QuorumMet     bool               `json:"quorummet"`     // Quorum has been met
+	Approved      bool               `json:"approved"`      // Quorum and pass percentage have been met
+}
+
+// VoteRunoffReply describes the runoff vote of an RFP.  The outcomes of the
+// submissions are computed together: the winner is the approved submission
+// with the largest number of approving votes minus rejecting votes.  Winner
+// is only set once the vote has finished and is left empty when no
+// submission was approved or when the top submissions are tied.
+type VoteRunoffReply struct {
+	Token              string             `json:"token"`              // RFP censorship token
 	Status             PropVoteStatusT    `json:"status"`             // Vote status (finished, started, etc)
-	TotalVotes         uint64             `json:"totalvotes"`         // Proposal's total number of votes
-	OptionsResult      []VoteOptionResult `json:"optionsresult"`      // VoteOptionResult for each option
+	StartBlockHeight   string             `json:"startblockheight"`   // Vote start height
 	EndHeight          string             `json:"endheight"`          // Vote end height
 	BestBlock          string             `json:"bestblock"`          // Current best block height
 	NumOfEligibleVotes int                `json:"numofeligiblevotes"` // Total number of eligible votes
-	QuorumPercentage   uint32             `json:"quorumpercentage"`   // Percent of eligible votes required for quorum
-	PassPercentage     uint32             `json:"passpercentage"`     // Percent of total votes required to pass
+	Submissions        []RunoffSubmission `json:"submissions"`        // Vote results of the submissions
+	Winner             string             `json:"winner,omitempty"`   // Token of the winning submission
 }
 
 // GetAllVoteStatus attempts to fetch the vote status of all public propsals
@@ -1139,12 +1476,18 @@ type UserIdentity struct {
 	Active bool   `json:"isactive"`
 }
 
-// EditProposal attempts to edit a proposal
+// EditProposal attempts to edit a proposal. The LinkTo and LinkBy fields
+// replace the existing proposal links and follow the NewProposal rules.  Links
+// that are not changed are kept as is, even when the RFP deadline has passed.
+// The LinkBy deadline of an RFP that has public submissions can not be
+// changed.
 type EditProposal struct {
 	Token     string `json:"token"`
 	Files     []File `json:"files"`
 	PublicKey string `json:"publickey"`
 	Signature string `json:"signature"`
+	LinkTo    string `json:"linkto,omitempty"`
+	LinkBy    int64  `json:"linkby,omitempty"`
 }
 
 // EditProposalReply is used to reply to the EditProposal command
@@ -1191,6 +1534,101 @@ type TokenInventoryReply struct {
 	Censored   []string `json:"censored,omitempty"`   // Tokens of all censored props
 }
 
+// SearchProposals searches the name, the index file and the comments of the
+// vetted proposals.  Words that are surrounded by double quotes are matched as
+// a phrase.  A proposal is returned when its name, its index file or one of
+// its comments contains all of the words and phrases of the query.  Censored
+// comments are never searched.
+//
+// The results can be filtered by proposal status and by vote status.  The
+// number of results is limited by ProposalListPageSize.  If After is
+// specified, the page starts after the position of the provided vetted
+// proposal in the sort order, even if that proposal no longer matches.
+type SearchProposals struct {
+	Query        string            `schema:"query"`        // Search query
+	Statuses     []PropStatusT     `schema:"statuses"`     // Filter by proposal status
+	VoteStatuses []PropVoteStatusT `schema:"votestatuses"` // Filter by vote status
+	After        string            `schema:"after"`        // Censorship token of the last result of the previous page
+}
+
+// SearchResult is a proposal that matched the search query.  The proposal
+// does not include its files.
+type SearchResult struct {
+	Proposal   ProposalRecord `json:"proposal"`   // Proposal without files
+	Name       bool           `json:"name"`       // Proposal name matched
+	Index      bool           `json:"index"`      // Index file matched
+	CommentIDs []string       `json:"commentids"` // IDs of the matching comments
+}
+
+// SearchProposalsReply is used to reply to the SearchProposals command.  The
+// results are sorted by proposal timestamp in descending order and then by
+// censorship token.
+type SearchProposalsReply struct {
+	Results []SearchResult `json:"results"`
+}
+
+// Draft is a proposal that is being composed by its author.  Drafts are
+// stored in the user database and are never sent to politeiad.  Name is
+// empty until the index file contains a proposal name.
+type Draft struct {
+	DraftID   string `json:"draftid"`   // Draft UUID
+	Name      string `json:"name"`      // Proposal name
+	Files     []File `json:"files"`     // Proposal files
+	CreatedAt int64  `json:"createdat"` // Unix timestamp of creation
+	UpdatedAt int64  `json:"updatedat"` // Unix timestamp of last update
+}
+
+// NewDraft saves a new proposal draft.  The files follow the same policy as
+// the files of a new proposal except that the index file is optional.
+type NewDraft struct {
+	Files []File `json:"files"` // Proposal files
+}
+
+// NewDraftReply is used to reply to the NewDraft command.
+type NewDraftReply struct {
+	DraftID string `json:"draftid"` // Draft UUID
+}
+
+// EditDraft replaces the files of a proposal draft.
+type EditDraft struct {
+	DraftID string `json:"draftid"` // Draft UUID
+	Files   []File `json:"files"`   // Proposal files
+}
+
+// EditDraftReply is used to reply to the EditDraft command.
+type EditDraftReply struct{}
+
+// DeleteDraft deletes a proposal draft.
+type DeleteDraft struct {
+	DraftID string `json:"draftid"` // Draft UUID
+}
+
+// DeleteDraftReply is used to reply to the DeleteDraft command.
+type DeleteDraftReply struct{}
+
+// UserDrafts retrieves the proposal drafts of the logged in user.
+type UserDrafts struct{}
+
+// UserDraftsReply is used to reply to the UserDrafts command.  The drafts
+// are sorted by creation time.
+type UserDraftsReply struct {
+	Drafts []Draft `json:"drafts"`
+}
+
+// SubmitDraft submits a proposal draft as a new proposal.  Signature is the
+// signature of the merkle root of the draft files, exactly as in NewProposal.
+// The draft is deleted once the proposal has been submitted.
+type SubmitDraft struct {
+	DraftID   string `json:"draftid"`   // Draft UUID
+	PublicKey string `json:"publickey"` // Key used for signature
+	Signature string `json:"signature"` // Signature of merkle root
+}
+
+// SubmitDraftReply is used to reply to the SubmitDraft command.
+type SubmitDraftReply struct {
+	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
+}
+
 // Websocket commands
 const (
 	WSCError     = "error"
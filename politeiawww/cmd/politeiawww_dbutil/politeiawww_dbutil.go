@@ -196,6 +196,17 @@ func cmdDump() error {
 			fmt.Printf("Key    : %v\n", string(key))
 			fmt.Printf("Record : %v\n", binary.LittleEndian.Uint64(value))
 		default:
+			if strings.HasPrefix(string(key), localdb.DraftPrefix) {
+				d, err := user.DecodeDraft(value)
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Key    : %v\n", string(key))
+				fmt.Printf("Record : %v", spew.Sdump(d))
+				continue
+			}
+
 			u, err := user.DecodeUser(value)
 			if err != nil {
 				return err
@@ -594,7 +605,7 @@ func cmdMigrate() error {
 
 	// Migrate LevelDB records to CockroachDB
 	var paywallIndex uint64
-	var userCount int
+	var userCount, draftCount int
 	iter := ldb.NewIterator(nil, nil)
 	for iter.Next() {
 		key := iter.Key()
@@ -611,6 +622,22 @@ func cmdMigrate() error {
 				return fmt.Errorf("set paywall index: %v", err)
 			}
 		default:
+			if strings.HasPrefix(string(key), localdb.DraftPrefix) {
+				// Proposal draft record
+				d, err := user.DecodeDraft(value)
+				if err != nil {
+					return fmt.Errorf("decode draft '%v': %v",
+						string(key), err)
+				}
+				err = cdb.InsertDraft(*d)
+				if err != nil {
+					return fmt.Errorf("migrate draft '%v': %v",
+						d.ID, err)
+				}
+				draftCount++
+				continue
+			}
+
 			// User record
 			u, err := user.DecodeUser(value)
 			if err != nil {
@@ -676,6 +703,7 @@ func cmdMigrate() error {
 	}
 
 	fmt.Printf("Users migrated : %v\n", userCount)
+	fmt.Printf("Drafts migrated: %v\n", draftCount)
 	fmt.Printf("Paywall index  : %v\n", paywallIndex)
 	fmt.Printf("Done!\n")
 
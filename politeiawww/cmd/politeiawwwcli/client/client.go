@@ -1162,6 +1162,54 @@ func (c *Client) StartVote(sv *v1.StartVote) (*v1.StartVoteReply, error) {
 	return &svr, nil
 }
 
+// ScheduleVote schedules the voting period for the specified proposal to
+// start at a future block height.
+func (c *Client) ScheduleVote(sv *v1.ScheduleVote) (*v1.ScheduleVoteReply, error) {
+	responseBody, err := c.makeRequest("POST", v1.RouteScheduleVote, sv)
+	if err != nil {
+		return nil, err
+	}
+
+	var svr v1.ScheduleVoteReply
+	err = json.Unmarshal(responseBody, &svr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal ScheduleVoteReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(svr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &svr, nil
+}
+
+// CancelVote cancels the scheduled vote of the specified proposal using the
+// logged in user.
+func (c *Client) CancelVote(cv *v1.CancelVote) (*v1.CancelVoteReply, error) {
+	responseBody, err := c.makeRequest("POST", v1.RouteCancelVote, cv)
+	if err != nil {
+		return nil, err
+	}
+
+	var cvr v1.CancelVoteReply
+	err = json.Unmarshal(responseBody, &cvr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal CancelVoteReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(cvr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &cvr, nil
+}
+
 // VerifyUserPayment checks whether the logged in user has paid their user
 // registration fee.
 func (c *Client) VerifyUserPayment() (*v1.VerifyUserPaymentReply, error) {
@@ -1209,6 +1257,30 @@ func (c *Client) VoteResults(token string) (*v1.VoteResultsReply, error) {
 	return &vrr, nil
 }
 
+// VoteBundle retrieves the signed vote bundle of the specified proposal.
+func (c *Client) VoteBundle(token string) (*v1.VoteBundleReply, error) {
+	responseBody, err := c.makeRequest("GET", "/proposals/"+token+
+		"/votebundle", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var vbr v1.VoteBundleReply
+	err = json.Unmarshal(responseBody, &vbr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal VoteBundleReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(vbr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &vbr, nil
+}
+
 // UserDetails retrieves the user details for the specified user.
 func (c *Client) UserDetails(userID string) (*v1.UserDetailsReply, error) {
 	responseBody, err := c.makeRequest("GET", "/user/"+userID, nil)
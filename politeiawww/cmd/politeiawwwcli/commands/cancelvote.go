@@ -0,0 +1,100 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+// CancelVoteCmd cancels the scheduled vote of a proposal.  The CancelVoteCmd
+// must be sent by the proposal author to be valid.
+type CancelVoteCmd struct {
+	Args struct {
+		Token       string `positional-arg-name:"token" required:"true"`       // Censorship token
+		StartHeight string `positional-arg-name:"startheight" required:"true"` // Scheduled start height
+	} `positional-args:"true"`
+}
+
+// Execute executes the cancel vote command.
+func (cmd *CancelVoteCmd) Execute(args []string) error {
+	token := cmd.Args.Token
+
+	// Check for user identity
+	if cfg.Identity == nil {
+		return errUserIdentityNotFound
+	}
+
+	startHeight, err := strconv.ParseUint(cmd.Args.StartHeight, 10, 32)
+	if err != nil {
+		return fmt.Errorf("parsing StartHeight: %v", err)
+	}
+
+	// Get server public key
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+
+	// Setup cancel vote request
+	sig := cfg.Identity.SignMessage([]byte(token + cmd.Args.StartHeight +
+		decredplugin.ScheduleVoteActionCancel))
+	cv := &v1.CancelVote{
+		Token:       token,
+		StartHeight: uint32(startHeight),
+		Signature:   hex.EncodeToString(sig[:]),
+		PublicKey:   hex.EncodeToString(cfg.Identity.Public.Key[:]),
+	}
+
+	// Print request details
+	err = printJSON(cv)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	cvr, err := client.CancelVote(cv)
+	if err != nil {
+		return err
+	}
+
+	// Validate cancel vote receipt
+	serverID, err := util.IdentityFromString(vr.PubKey)
+	if err != nil {
+		return err
+	}
+	s, err := util.ConvertSignature(cvr.Receipt)
+	if err != nil {
+		return err
+	}
+	if !serverID.VerifyMessage([]byte(cv.Signature), s) {
+		return fmt.Errorf("could not verify cancel vote receipt")
+	}
+
+	// Print response details
+	return printJSON(cvr)
+}
+
+// cancelVoteHelpMsg is the output of the help command when 'cancelvote' is
+// specified.
+const cancelVoteHelpMsg = `cancelvote "token" "startheight"
+
+Cancel the scheduled vote of a proposal.  Only the proposal author can cancel
+the vote and only before the start height has been reached.
+
+Arguments:
+1. token        (string, required)   Proposal censorship token
+2. startheight  (string, required)   Start height of the scheduled vote
+
+Result:
+{
+  "receipt":   (string)  Server signature of client signature
+                         (signed token+startheight+"cancel")
+}`
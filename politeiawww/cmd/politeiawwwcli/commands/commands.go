@@ -62,6 +62,7 @@ type Cmds struct {
 	ActiveVotes         ActiveVotesCmd         `command:"activevotes" description:"(public) get the proposals that are being voted on"`
 	AuthorizeVote       AuthorizeVoteCmd       `command:"authorizevote" description:"(user)   authorize a proposal vote (must be proposal author)"`
 	BatchProposals      BatchProposalsCmd      `command:"batchproposals" description:"(user) retrieve a set of proposals"`
+	CancelVote          CancelVoteCmd          `command:"cancelvote" description:"(user)   cancel a scheduled proposal vote (must be proposal author)"`
 	CensorComment       CensorCommentCmd       `command:"censorcomment" description:"(admin)  censor a proposal comment"`
 	ChangePassword      ChangePasswordCmd      `command:"changepassword" description:"(user)   change the password for the logged in user"`
 	ChangeUsername      ChangeUsernameCmd      `command:"changeusername" description:"(user)   change the username for the logged in user"`
@@ -99,6 +100,7 @@ type Cmds struct {
 	RescanUserPayments  RescanUserPaymentsCmd  `command:"rescanuserpayments" description:"(admin)  rescan a user's payments to check for missed payments"`
 	ResendVerification  ResendVerificationCmd  `command:"resendverification" description:"(public) resend the user verification email"`
 	ResetPassword       ResetPasswordCmd       `command:"resetpassword" description:"(public) reset the password for a user that is not logged in"`
+	ScheduleVote        ScheduleVoteCmd        `command:"schedulevote" description:"(admin)  schedule the voting period of a proposal to start at a future block"`
 	Secret              SecretCmd              `command:"secret" description:"(user)   ping politeiawww"`
 	SendFaucetTx        SendFaucetTxCmd        `command:"sendfaucettx" description:"         send a DCR transaction using the Decred testnet faucet"`
 	SetInvoiceStatus    SetInvoiceStatusCmd    `command:"setinvoicestatus" description:"(admin)  set the status of an invoice"`
@@ -119,6 +121,7 @@ type Cmds struct {
 	VerifyUserPayment   VerifyUserPaymentCmd   `command:"verifyuserpayment" description:"(user)   check if the logged in user has paid their user registration fee"`
 	Version             VersionCmd             `command:"version" description:"(public) get server info and CSRF token"`
 	Vote                VoteCmd                `command:"vote" description:"(public) cast votes for a proposal"`
+	VoteBundle          VoteBundleCmd          `command:"votebundle" description:"(public) get the signed vote bundle of a proposal"`
 	VoteResults         VoteResultsCmd         `command:"voteresults" description:"(public) get vote results for a proposal"`
 	VoteStatus          VoteStatusCmd          `command:"votestatus" description:"(public) get the vote status of a proposal"`
 	VoteStatuses        VoteStatusesCmd        `command:"votestatuses" description:"(public) get the vote status for all public proposals"`
@@ -90,8 +90,14 @@ func (cmd *HelpCmd) Execute(args []string) error {
 		fmt.Printf("%s\n", verifyUserPaymentHelpMsg)
 	case "startvote":
 		fmt.Printf("%s\n", startVoteHelpMsg)
+	case "schedulevote":
+		fmt.Printf("%s\n", scheduleVoteHelpMsg)
+	case "cancelvote":
+		fmt.Printf("%s\n", cancelVoteHelpMsg)
 	case "voteresults":
 		fmt.Printf("%s\n", voteResultsHelpMsg)
+	case "votebundle":
+		fmt.Printf("%s\n", voteBundleHelpMsg)
 	case "inventory":
 		fmt.Printf("%s\n", inventoryHelpMsg)
 	case "tally":
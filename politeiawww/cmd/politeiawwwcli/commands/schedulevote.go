@@ -0,0 +1,133 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/decred/politeia/decredplugin"
+	"github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+// ScheduleVoteCmd schedules the voting period of the specified proposal to
+// start at a future block height.
+type ScheduleVoteCmd struct {
+	Args struct {
+		Token            string `positional-arg-name:"token" required:"true"`       // Censorship token
+		StartHeight      string `positional-arg-name:"startheight" required:"true"` // Vote start height
+		Duration         string `positional-arg-name:"duration"`                    // Vote duration
+		QuorumPercentage string `positional-arg-name:"quorumpercentage"`            // Quorum percentage
+		PassPercentage   string `positional-arg-name:"passpercentage"`              // Pass percentage
+	} `positional-args:"true"`
+	Type    string   `long:"type" optional:"true" description:"Vote type: approval, multiplechoice or rankedchoice"`
+	Options []string `long:"option" optional:"true" description:"Vote option ID of a multiple or ranked choice vote"`
+}
+
+// Execute executes the schedule vote command.
+func (cmd *ScheduleVoteCmd) Execute(args []string) error {
+	// Check for user identity
+	if cfg.Identity == nil {
+		return errUserIdentityNotFound
+	}
+
+	startHeight, err := strconv.ParseUint(cmd.Args.StartHeight, 10, 32)
+	if err != nil {
+		return fmt.Errorf("parsing StartHeight: %v", err)
+	}
+
+	// Setup the start vote using the start vote command
+	var svc StartVoteCmd
+	svc.Args.Token = cmd.Args.Token
+	svc.Args.Duration = cmd.Args.Duration
+	svc.Args.QuorumPercentage = cmd.Args.QuorumPercentage
+	svc.Args.PassPercentage = cmd.Args.PassPercentage
+	svc.Type = cmd.Type
+	svc.Options = cmd.Options
+	sv, err := svc.startVote()
+	if err != nil {
+		return err
+	}
+
+	// Get server public key
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+
+	// Setup schedule vote request
+	sig := cfg.Identity.SignMessage([]byte(cmd.Args.Token +
+		cmd.Args.StartHeight + decredplugin.ScheduleVoteActionSchedule))
+	schedule := &v1.ScheduleVote{
+		StartVote:   *sv,
+		StartHeight: uint32(startHeight),
+		Signature:   hex.EncodeToString(sig[:]),
+		PublicKey:   hex.EncodeToString(cfg.Identity.Public.Key[:]),
+	}
+
+	// Print request details
+	err = printJSON(schedule)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	svr, err := client.ScheduleVote(schedule)
+	if err != nil {
+		return err
+	}
+
+	// Validate schedule vote receipt
+	serverID, err := util.IdentityFromString(vr.PubKey)
+	if err != nil {
+		return err
+	}
+	s, err := util.ConvertSignature(svr.Receipt)
+	if err != nil {
+		return err
+	}
+	if !serverID.VerifyMessage([]byte(schedule.Signature), s) {
+		return fmt.Errorf("could not verify schedule vote receipt")
+	}
+
+	// Print response details
+	return printJSON(svr)
+}
+
+// scheduleVoteHelpMsg is the output of the help command when 'schedulevote'
+// is specified.
+var scheduleVoteHelpMsg = `schedulevote "token" "startheight" "duration" "quorumpercentage" "passpercentage"
+
+Schedule the voting period of a proposal to start at a future block height.
+Requires admin privileges.  The vote is started by politeiawww once the start
+height has been reached.  The ticket snapshot is taken at the start height
+minus the ticket maturity.  A scheduled vote can be rescheduled until the
+start height has been reached.  The optional arguments must either all be used
+or none be used.
+
+Arguments:
+1. token              (string, required)  Proposal censorship token
+2. startheight        (string, required)  Block height the vote starts at
+3. duration           (string, optional)  Duration of vote in blocks
+4. quorumpercentage   (string, optional)  Percent of votes required for quorum
+5. passpercentage     (string, optional)  Percent of votes required to pass
+
+Flags:
+  --type              (string, optional)  Vote type: approval, multiplechoice
+                                          or rankedchoice (default: approval)
+  --option            (string, optional)  Vote option ID of a multiple or
+                                          ranked choice vote.  Use the flag
+                                          once for every option.
+
+Result:
+
+{
+  "startheight"          (uint32)    Block height the vote starts at
+  "snapshotheight"       (uint32)    Block height of the ticket snapshot
+  "receipt"              (string)    Server signature of client signature
+                                     (signed token+startheight+"schedule")
+}`
@@ -20,15 +20,68 @@ type StartVoteCmd struct {
 		QuorumPercentage string `positional-arg-name:"quorumpercentage"`      // Quorum percentage
 		PassPercentage   string `positional-arg-name:"passpercentage"`        // Pass percentage
 	} `positional-args:"true"`
+	Type    string   `long:"type" optional:"true" description:"Vote type: approval, multiplechoice or rankedchoice"`
+	Options []string `long:"option" optional:"true" description:"Vote option ID of a multiple or ranked choice vote"`
 }
 
-// Execute executes the start vote command.
-func (cmd *StartVoteCmd) Execute(args []string) error {
-	// Check for user identity
-	if cfg.Identity == nil {
-		return errUserIdentityNotFound
+// voteOptions returns the vote type, mask and options of the start vote
+// command.  Approval votes use the yes and no vote options.
+func (cmd *StartVoteCmd) voteOptions() (v1.VoteT, uint64, []v1.VoteOption, error) {
+	var t v1.VoteT
+	switch cmd.Type {
+	case "", "approval":
+		if len(cmd.Options) != 0 {
+			return 0, 0, nil, fmt.Errorf("approval votes do not " +
+				"take vote options")
+		}
+		return v1.VoteTypeApproval, 0x03, // bit 0 no, bit 1 yes
+			[]v1.VoteOption{
+				{
+					Id:          v1.VoteOptionIDReject,
+					Description: "Don't approve proposal",
+					Bits:        0x01,
+				},
+				{
+					Id:          v1.VoteOptionIDApprove,
+					Description: "Approve proposal",
+					Bits:        0x02,
+				},
+			}, nil
+	case "multiplechoice":
+		t = v1.VoteTypeMultipleChoice
+	case "rankedchoice":
+		t = v1.VoteTypeRankedChoice
+	default:
+		return 0, 0, nil, fmt.Errorf("invalid vote type: %v", cmd.Type)
+	}
+
+	if len(cmd.Options) < 2 {
+		return 0, 0, nil, fmt.Errorf("at least 2 vote options are " +
+			"required")
+	}
+
+	// Multiple choice options use a bit each.  Ranked choice options
+	// are numbered so that they fit in a single ranked preference.
+	var mask uint64
+	opts := make([]v1.VoteOption, 0, len(cmd.Options))
+	for i, v := range cmd.Options {
+		bits := uint64(1) << uint(i)
+		if t == v1.VoteTypeRankedChoice {
+			bits = uint64(i + 1)
+		}
+		mask |= bits
+		opts = append(opts, v1.VoteOption{
+			Id:          v,
+			Description: v,
+			Bits:        bits,
+		})
 	}
 
+	return t, mask, opts, nil
+}
+
+// startVote returns the signed start vote of the start vote command.
+func (cmd *StartVoteCmd) startVote() (*v1.StartVote, error) {
 	// Set vote parameter defaults
 	if cmd.Args.Duration == "" {
 		cmd.Args.Duration = "2016"
@@ -43,41 +96,49 @@ func (cmd *StartVoteCmd) Execute(args []string) error {
 	// Convert vote parameters
 	duration, err := strconv.ParseUint(cmd.Args.Duration, 10, 32)
 	if err != nil {
-		return fmt.Errorf("parsing Duration: %v", err)
+		return nil, fmt.Errorf("parsing Duration: %v", err)
 	}
 	quorum, err := strconv.ParseUint(cmd.Args.QuorumPercentage, 10, 32)
 	if err != nil {
-		return fmt.Errorf("parsing QuorumPercentage: %v", err)
+		return nil, fmt.Errorf("parsing QuorumPercentage: %v", err)
 	}
 	pass, err := strconv.ParseUint(cmd.Args.PassPercentage, 10, 32)
 	if err != nil {
-		return fmt.Errorf("parsing PassPercentage: %v", err)
+		return nil, fmt.Errorf("parsing PassPercentage: %v", err)
+	}
+
+	voteType, mask, opts, err := cmd.voteOptions()
+	if err != nil {
+		return nil, err
 	}
 
-	// Setup start vote request
 	sig := cfg.Identity.SignMessage([]byte(cmd.Args.Token))
-	sv := &v1.StartVote{
+	return &v1.StartVote{
 		Signature: hex.EncodeToString(sig[:]),
 		PublicKey: hex.EncodeToString(cfg.Identity.Public.Key[:]),
 		Vote: v1.Vote{
 			Token:            cmd.Args.Token,
-			Mask:             0x03, // bit 0 no, bit 1 yes
+			Type:             voteType,
+			Mask:             mask,
 			Duration:         uint32(duration),
 			QuorumPercentage: uint32(quorum),
 			PassPercentage:   uint32(pass),
-			Options: []v1.VoteOption{
-				{
-					Id:          "no",
-					Description: "Don't approve proposal",
-					Bits:        0x01,
-				},
-				{
-					Id:          "yes",
-					Description: "Approve proposal",
-					Bits:        0x02,
-				},
-			},
+			Options:          opts,
 		},
+	}, nil
+}
+
+// Execute executes the start vote command.
+func (cmd *StartVoteCmd) Execute(args []string) error {
+	// Check for user identity
+	if cfg.Identity == nil {
+		return errUserIdentityNotFound
+	}
+
+	// Setup start vote request
+	sv, err := cmd.startVote()
+	if err != nil {
+		return err
 	}
 
 	// Print request details
@@ -113,6 +174,13 @@ Arguments:
 3. quorumpercentage   (string, optional)  Percent of votes required for quorum
 4. passpercentage     (string, optional)  Percent of votes required to pass
 
+Flags:
+  --type              (string, optional)  Vote type: approval, multiplechoice
+                                          or rankedchoice (default: approval)
+  --option            (string, optional)  Vote option ID of a multiple or
+                                          ranked choice vote.  Use the flag
+                                          once for every option.
+
 Result:
 
 {
@@ -0,0 +1,68 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+
+	"github.com/decred/politeia/util"
+)
+
+// VoteBundleCmd gets the signed vote bundle of the specified proposal.
+type VoteBundleCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token"` // Censorship token
+	} `positional-args:"true" required:"true"`
+	Output string `long:"output" optional:"true" description:"Write the bundle to a file"`
+}
+
+// Execute executes the vote bundle command.
+func (cmd *VoteBundleCmd) Execute(args []string) error {
+	vbr, err := client.VoteBundle(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+
+	// Save the bundle so that it can be verified using politeiatally
+	if cmd.Output != "" {
+		return ioutil.WriteFile(util.CleanAndExpandPath(cmd.Output),
+			vbr.Bundle, 0600)
+	}
+
+	return printJSON(vbr)
+}
+
+// voteBundleHelpMsg is the output of the help command when 'votebundle' is
+// specified.
+const voteBundleHelpMsg = `votebundle "token"
+
+Fetch the signed vote bundle of a proposal.  The bundle can be verified and
+tallied offline using politeiatally.
+
+Arguments:
+1. token       (string, required)  Proposal censorship token
+
+Flags:
+  --output     (string, optional)  Write the bundle to the specified file
+
+Request:
+{
+  "token":     (string)  Proposal censorship token
+}
+
+Response:
+{
+  "bundle": {
+    "version":           (uint)      Version of the bundle structure
+    "timestamp":         (int64)     Time the bundle was created
+    "token":             (string)    Censorship token
+    "authorizevotes":    ([]object)  Vote authorizations of all versions
+    "startvote":         (object)    Start vote
+    "startvotereply":    (object)    Start block and eligible tickets
+    "castvotes":         ([]object)  Cast votes, receipts and addresses
+    "publickey":         (string)    politeiad public key
+    "signature":         (string)    politeiad signature of the bundle
+  }
+}`
@@ -342,7 +342,7 @@ func (p *politeiawww) invoiceStatusPaid(token string) error {
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.verifyChallenge(challenge, pdReply.Response)
 	if err != nil {
 		return err
 	}
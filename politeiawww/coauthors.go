@@ -0,0 +1,381 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/cache"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/user"
+	"github.com/decred/politeia/util"
+	"github.com/google/uuid"
+)
+
+// fillCoAuthors fills in the usernames of the proposal co-authors.
+func (p *politeiawww) fillCoAuthors(pr *www.ProposalRecord) {
+	for i, v := range pr.CoAuthors {
+		id, err := uuid.Parse(v.UserID)
+		if err != nil {
+			log.Errorf("fillCoAuthors: invalid user ID: token:%v "+
+				"userID:%v", pr.CensorshipRecord.Token, v.UserID)
+			continue
+		}
+		u, err := p.db.UserGetById(id)
+		if err != nil {
+			log.Errorf("fillCoAuthors: UserGetById: token:%v "+
+				"userID:%v err:%v", pr.CensorshipRecord.Token, v.UserID, err)
+			continue
+		}
+		pr.CoAuthors[i].Username = u.Username
+	}
+}
+
+// getProposalCoAuthors returns the users that were invited to co-author the
+// proposal.  Co-authors that can not be found are logged and skipped.
+func (p *politeiawww) getProposalCoAuthors(pr *www.ProposalRecord) []*user.User {
+	users := make([]*user.User, 0, len(pr.CoAuthors))
+	for _, v := range pr.CoAuthors {
+		id, err := uuid.Parse(v.UserID)
+		if err != nil {
+			log.Errorf("getProposalCoAuthors: invalid user ID: "+
+				"token:%v userID:%v", pr.CensorshipRecord.Token, v.UserID)
+			continue
+		}
+		u, err := p.db.UserGetById(id)
+		if err != nil {
+			log.Errorf("getProposalCoAuthors: UserGetById: token:%v "+
+				"userID:%v err:%v", pr.CensorshipRecord.Token, v.UserID, err)
+			continue
+		}
+		users = append(users, u)
+	}
+	return users
+}
+
+// isProposalCoAuthor returns whether the user has been invited to co-author
+// the proposal.
+func isProposalCoAuthor(pr *www.ProposalRecord, u *user.User) bool {
+	for _, v := range pr.CoAuthors {
+		if v.UserID == u.ID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// coAuthorsSigned returns whether all co-authors have signed the current
+// version of the proposal.
+func coAuthorsSigned(pr *www.ProposalRecord) bool {
+	for _, v := range pr.CoAuthors {
+		if !v.Signed {
+			return false
+		}
+	}
+	return true
+}
+
+// coAuthorLock serializes the changes to the co-authors of a proposal.  refs
+// counts the callers that hold or wait for the lock so that the lock can be
+// dropped once it is no longer used.  refs is protected by the politeiawww
+// lock.
+type coAuthorLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockCoAuthors locks the co-authors of a proposal and returns the function
+// that unlocks them.  The co-authors metadata stream is overwritten as a
+// whole so concurrent changes to the co-authors of the same proposal must be
+// serialized to prevent one from silently dropping the other.
+func (p *politeiawww) lockCoAuthors(token string) func() {
+	p.Lock()
+	l, ok := p.coAuthorLocks[token]
+	if !ok {
+		l = &coAuthorLock{}
+		p.coAuthorLocks[token] = l
+	}
+	l.refs++
+	p.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		p.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(p.coAuthorLocks, token)
+		}
+		p.Unlock()
+	}
+}
+
+// getCoAuthorsMD returns the co-authors metadata stream of the most recent
+// version of a proposal.  An empty stream is returned when no co-authors
+// have been invited yet.
+func (p *politeiawww) getCoAuthorsMD(token string) (*MDStreamCoAuthors, error) {
+	r, err := p.cache.Record(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range r.Metadata {
+		if v.ID == mdStreamCoAuthors {
+			return decodeMDStreamCoAuthors([]byte(v.Payload))
+		}
+	}
+
+	return &MDStreamCoAuthors{
+		Version: VersionMDStreamCoAuthors,
+	}, nil
+}
+
+// setCoAuthorsMD overwrites the co-authors metadata stream of a proposal in
+// politeiad.  The proposal files are not changed.
+func (p *politeiawww) setCoAuthorsMD(pr *www.ProposalRecord, m MDStreamCoAuthors) error {
+	payload, err := encodeMDStreamCoAuthors(m)
+	if err != nil {
+		return err
+	}
+	mds := []pd.MetadataStream{{
+		ID:      mdStreamCoAuthors,
+		Payload: string(payload),
+	}}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return err
+	}
+
+	var (
+		route string
+		req   interface{}
+	)
+	switch pr.Status {
+	case www.PropStatusNotReviewed, www.PropStatusUnreviewedChanges:
+		route = pd.UpdateUnvettedRoute
+		req = pd.UpdateRecord{
+			Token:       pr.CensorshipRecord.Token,
+			Challenge:   hex.EncodeToString(challenge),
+			MDOverwrite: mds,
+		}
+	case www.PropStatusPublic:
+		route = pd.UpdateVettedMetadataRoute
+		req = pd.UpdateVettedMetadata{
+			Token:       pr.CensorshipRecord.Token,
+			Challenge:   hex.EncodeToString(challenge),
+			MDOverwrite: mds,
+		}
+	default:
+		return www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	// Send politeiad request
+	responseBody, err := p.makeRequest(http.MethodPost, route, req)
+	if err != nil {
+		return err
+	}
+
+	// Both replies only contain the challenge response
+	var reply pd.UpdateVettedMetadataReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return fmt.Errorf("Unmarshal UpdateVettedMetadataReply: %v", err)
+	}
+
+	return p.verifyChallenge(challenge, reply.Response)
+}
+
+// validateCoAuthorChange ensures that the co-authors of the proposal can
+// still be changed.  Co-authors can not change once the vote has been
+// authorized.
+func (p *politeiawww) validateCoAuthorChange(pr *www.ProposalRecord) error {
+	switch pr.Status {
+	case www.PropStatusNotReviewed, www.PropStatusUnreviewedChanges,
+		www.PropStatusPublic:
+	default:
+		return www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	vdr, err := p.decredVoteDetails(pr.CensorshipRecord.Token)
+	if err != nil {
+		return fmt.Errorf("decredVoteDetails: %v", err)
+	}
+	vd := convertVoteDetailsReplyFromDecred(*vdr)
+	if voteIsAuthorized(vd.AuthorizeVoteReply) ||
+		vd.StartVoteReply.StartBlockHeight != "" {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusWrongVoteStatus,
+		}
+	}
+
+	return nil
+}
+
+// processInviteCoAuthor invites a user to co-author a proposal.  Only the
+// proposal author can invite co-authors.
+func (p *politeiawww) processInviteCoAuthor(ic www.InviteCoAuthor, u *user.User) (*www.InviteCoAuthorReply, error) {
+	log.Tracef("processInviteCoAuthor: %v %v", ic.Token, ic.UserID)
+
+	// The proposal is looked up after the lock is taken so that the
+	// validation uses the most recent co-authors.
+	unlock := p.lockCoAuthors(ic.Token)
+	defer unlock()
+
+	pr, err := p.getProp(ic.Token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	// Ensure user is the proposal author
+	if pr.UserId != u.ID.String() {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserNotAuthor,
+		}
+	}
+
+	err = p.validateCoAuthorChange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup co-author
+	id, err := uuid.Parse(ic.UserID)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidUUID,
+		}
+	}
+	coAuthor, err := p.db.UserGetById(id)
+	if err != nil {
+		if err == user.ErrUserNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		return nil, err
+	}
+	if coAuthor.ID == u.ID || isProposalCoAuthor(pr, coAuthor) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCoAuthorAlreadyInvited,
+		}
+	}
+	if coAuthor.Deactivated {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserActionNotAllowed,
+		}
+	}
+	if len(pr.CoAuthors) >= www.PolicyMaxCoAuthors {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusMaxCoAuthorsExceededPolicy,
+		}
+	}
+
+	// Add co-author
+	m, err := p.getCoAuthorsMD(ic.Token)
+	if err != nil {
+		return nil, err
+	}
+	m.Version = VersionMDStreamCoAuthors
+	m.CoAuthors = append(m.CoAuthors, BackendCoAuthor{
+		UserID: coAuthor.ID.String(),
+	})
+	err = p.setCoAuthorsMD(pr, *m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fire off co-author invited event
+	p.fireEvent(EventTypeCoAuthorInvited,
+		EventDataCoAuthorInvited{
+			Proposal: pr,
+			Author:   u,
+			CoAuthor: coAuthor,
+		},
+	)
+
+	return &www.InviteCoAuthorReply{}, nil
+}
+
+// processSignCoAuthor adds the signature of an invited co-author of the
+// merkle root of the current version of the proposal.
+func (p *politeiawww) processSignCoAuthor(sc www.SignCoAuthor, u *user.User) (*www.SignCoAuthorReply, error) {
+	log.Tracef("processSignCoAuthor: %v", sc.Token)
+
+	unlock := p.lockCoAuthors(sc.Token)
+	defer unlock()
+
+	pr, err := p.getProp(sc.Token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	if !isProposalCoAuthor(pr, u) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserNotCoAuthor,
+		}
+	}
+
+	err = p.validateCoAuthorChange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure the public key is the user's active key
+	if sc.PublicKey != u.PublicKey() {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSigningKey,
+		}
+	}
+
+	// Validate signature
+	err = validateSignature(sc.PublicKey, sc.Signature,
+		pr.CensorshipRecord.Merkle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update co-author signature
+	m, err := p.getCoAuthorsMD(sc.Token)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range m.CoAuthors {
+		if v.UserID != u.ID.String() {
+			continue
+		}
+		m.CoAuthors[i].PublicKey = sc.PublicKey
+		m.CoAuthors[i].Signature = sc.Signature
+		m.CoAuthors[i].Merkle = pr.CensorshipRecord.Merkle
+		m.CoAuthors[i].Timestamp = time.Now().Unix()
+	}
+	err = p.setCoAuthorsMD(pr, *m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.SignCoAuthorReply{}, nil
+}
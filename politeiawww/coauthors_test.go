@@ -0,0 +1,335 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/decred/politeia/politeiad/cache"
+	"github.com/decred/politeia/politeiad/testpoliteiad"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/user"
+)
+
+func TestProcessCoAuthors(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	p.cfg.RPCHost = td.URL
+	p.cfg.Identity = td.PublicIdentity
+
+	// Create a public proposal
+	author, authorID := newUser(t, p, true, false)
+	coAuthor, coAuthorID := newUser(t, p, true, false)
+	other, _ := newUser(t, p, true, false)
+
+	pr := newProposalRecord(t, author, authorID, www.PropStatusPublic)
+	token := pr.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, pr))
+
+	// Setup invite tests
+	var inviteTests = []struct {
+		name string
+		ic   www.InviteCoAuthor
+		usr  *user.User
+		want error
+	}{
+		{"user not author",
+			www.InviteCoAuthor{
+				Token:  token,
+				UserID: coAuthor.ID.String(),
+			},
+			other,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotAuthor,
+			}},
+
+		{"invalid user id",
+			www.InviteCoAuthor{
+				Token:  token,
+				UserID: "invalid",
+			},
+			author,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidUUID,
+			}},
+
+		{"invite author",
+			www.InviteCoAuthor{
+				Token:  token,
+				UserID: author.ID.String(),
+			},
+			author,
+			www.UserError{
+				ErrorCode: www.ErrorStatusCoAuthorAlreadyInvited,
+			}},
+
+		{"success",
+			www.InviteCoAuthor{
+				Token:  token,
+				UserID: coAuthor.ID.String(),
+			},
+			author,
+			nil},
+
+		{"already invited",
+			www.InviteCoAuthor{
+				Token:  token,
+				UserID: coAuthor.ID.String(),
+			},
+			author,
+			www.UserError{
+				ErrorCode: www.ErrorStatusCoAuthorAlreadyInvited,
+			}},
+	}
+
+	// Run invite tests
+	for _, v := range inviteTests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processInviteCoAuthor(v.ic, v.usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+
+	// The co-author is listed but has not signed yet
+	prop, err := p.getProp(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prop.CoAuthors) != 1 {
+		t.Fatalf("got %v co-authors, want 1", len(prop.CoAuthors))
+	}
+	if prop.CoAuthors[0].UserID != coAuthor.ID.String() ||
+		prop.CoAuthors[0].Username != coAuthor.Username ||
+		prop.CoAuthors[0].Signed {
+		t.Fatalf("unexpected co-author %v", prop.CoAuthors[0])
+	}
+	if coAuthorsSigned(prop) {
+		t.Fatalf("co-authors signed before signing")
+	}
+
+	// Setup sign tests
+	sig := coAuthorID.SignMessage([]byte(pr.CensorshipRecord.Merkle))
+	badSig := authorID.SignMessage([]byte(pr.CensorshipRecord.Merkle))
+	var signTests = []struct {
+		name string
+		sc   www.SignCoAuthor
+		usr  *user.User
+		want error
+	}{
+		{"user not co-author",
+			www.SignCoAuthor{
+				Token:     token,
+				PublicKey: other.PublicKey(),
+				Signature: hex.EncodeToString(sig[:]),
+			},
+			other,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotCoAuthor,
+			}},
+
+		{"invalid signing key",
+			www.SignCoAuthor{
+				Token:     token,
+				PublicKey: author.PublicKey(),
+				Signature: hex.EncodeToString(sig[:]),
+			},
+			coAuthor,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSigningKey,
+			}},
+
+		{"invalid signature",
+			www.SignCoAuthor{
+				Token:     token,
+				PublicKey: coAuthor.PublicKey(),
+				Signature: hex.EncodeToString(badSig[:]),
+			},
+			coAuthor,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSignature,
+			}},
+
+		{"success",
+			www.SignCoAuthor{
+				Token:     token,
+				PublicKey: coAuthor.PublicKey(),
+				Signature: hex.EncodeToString(sig[:]),
+			},
+			coAuthor,
+			nil},
+	}
+
+	// Run sign tests
+	for _, v := range signTests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processSignCoAuthor(v.sc, v.usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+
+	// The co-author signature covers the current version
+	prop, err = p.getProp(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prop.CoAuthors[0].Signed ||
+		prop.CoAuthors[0].PublicKey != coAuthor.PublicKey() {
+		t.Fatalf("unexpected co-author %v", prop.CoAuthors[0])
+	}
+	if !coAuthorsSigned(prop) {
+		t.Fatalf("co-authors not signed")
+	}
+	if !isProposalCoAuthor(prop, coAuthor) || isProposalCoAuthor(prop, other) {
+		t.Fatalf("unexpected co-author check")
+	}
+}
+
+func TestProcessInviteCoAuthorConcurrent(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	p.cfg.RPCHost = td.URL
+	p.cfg.Identity = td.PublicIdentity
+
+	author, authorID := newUser(t, p, true, false)
+	pr := newProposalRecord(t, author, authorID, www.PropStatusPublic)
+	token := pr.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, pr))
+
+	coAuthors := make([]*user.User, 0, www.PolicyMaxCoAuthors)
+	for i := 0; i < www.PolicyMaxCoAuthors; i++ {
+		u, _ := newUser(t, p, true, false)
+		coAuthors = append(coAuthors, u)
+	}
+
+	// Invite all co-authors at once.  None of the invites may be lost.
+	var wg sync.WaitGroup
+	errC := make(chan error, len(coAuthors))
+	for _, v := range coAuthors {
+		wg.Add(1)
+		go func(u *user.User) {
+			defer wg.Done()
+			_, err := p.processInviteCoAuthor(www.InviteCoAuthor{
+				Token:  token,
+				UserID: u.ID.String(),
+			}, author)
+			errC <- err
+		}(v)
+	}
+	wg.Wait()
+	close(errC)
+	for err := range errC {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prop, err := p.getProp(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prop.CoAuthors) != len(coAuthors) {
+		t.Fatalf("got %v co-authors, want %v", len(prop.CoAuthors),
+			len(coAuthors))
+	}
+	for _, v := range coAuthors {
+		if !isProposalCoAuthor(prop, v) {
+			t.Fatalf("co-author %v not invited", v.ID)
+		}
+	}
+
+	// The co-author locks are dropped once they are released
+	p.Lock()
+	locks := len(p.coAuthorLocks)
+	p.Unlock()
+	if locks != 0 {
+		t.Fatalf("got %v co-author locks, want 0", locks)
+	}
+}
+
+func TestConvertPropFromCacheCoAuthors(t *testing.T) {
+	merkle := "merkle"
+	bpm, err := encodeBackendProposalMetadata(BackendProposalMetadata{
+		Version: BackendProposalMetadataVersion,
+		Name:    "name",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A signature of a different merkle root is not valid for the
+	// current version of the proposal.
+	mca, err := encodeMDStreamCoAuthors(MDStreamCoAuthors{
+		Version: VersionMDStreamCoAuthors,
+		CoAuthors: []BackendCoAuthor{
+			{
+				UserID:    "a",
+				Signature: "signature",
+				Merkle:    merkle,
+			},
+			{
+				UserID:    "b",
+				Signature: "signature",
+				Merkle:    "old",
+			},
+			{
+				UserID: "c",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := cache.Record{
+		Metadata: []cache.MetadataStream{
+			{
+				ID:      mdStreamGeneral,
+				Payload: string(bpm),
+			},
+			{
+				ID:      mdStreamCoAuthors,
+				Payload: string(mca),
+			},
+		},
+		CensorshipRecord: cache.CensorshipRecord{
+			Merkle: merkle,
+		},
+	}
+
+	pr := convertPropFromCache(r)
+	want := []bool{true, false, false}
+	if len(pr.CoAuthors) != len(want) {
+		t.Fatalf("got %v co-authors, want %v", len(pr.CoAuthors),
+			len(want))
+	}
+	for i, v := range pr.CoAuthors {
+		if v.Signed != want[i] {
+			t.Errorf("co-author %v: got signed %v, want %v",
+				v.UserID, v.Signed, want[i])
+		}
+	}
+}
@@ -285,7 +285,7 @@ func (p *politeiawww) processNewComment(nc www.NewComment, u *user.User) (*www.N
 			"PluginCommandReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -401,7 +401,7 @@ func (p *politeiawww) processNewCommentInvoice(nc www.NewComment, u *user.User)
 			"PluginCommandReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -560,7 +560,7 @@ func (p *politeiawww) processLikeComment(lc www.LikeComment, u *user.User) (*www
 			"PluginCommandReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -675,7 +675,7 @@ func (p *politeiawww) processCensorComment(cc www.CensorComment, u *user.User) (
 		return nil, err
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
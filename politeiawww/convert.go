@@ -72,8 +72,14 @@ func convertVoteOptionsFromWWW(vo []www.VoteOption) []decredplugin.VoteOption {
 }
 
 func convertVoteFromWWW(v www.Vote) decredplugin.Vote {
+	// Votes that do not set a type are approval votes
+	t := decredplugin.VoteT(v.Type)
+	if t == decredplugin.VoteTypeInvalid {
+		t = decredplugin.VoteTypeApproval
+	}
 	return decredplugin.Vote{
 		Token:            v.Token,
+		Type:             t,
 		Mask:             v.Mask,
 		Duration:         v.Duration,
 		QuorumPercentage: v.QuorumPercentage,
@@ -150,11 +156,40 @@ func convertPropStatusFromPD(s pd.RecordStatusT) www.PropStatusT {
 	return www.PropStatusInvalid
 }
 
+func convertPropFileDiffFromPD(f pd.FileDiff) www.ProposalFileDiff {
+	return www.ProposalFileDiff{
+		Name:       f.Name,
+		Action:     pd.DiffAction[f.Action],
+		MIME:       f.MIME,
+		FromDigest: f.FromDigest,
+		ToDigest:   f.ToDigest,
+		Diff:       f.Diff,
+	}
+}
+
+func convertFileProofsFromPD(fp []pd.FileProof) []www.FileProof {
+	if len(fp) == 0 {
+		return nil
+	}
+	proofs := make([]www.FileProof, 0, len(fp))
+	for _, v := range fp {
+		proofs = append(proofs, www.FileProof{
+			Name:      v.Name,
+			Digest:    v.Digest,
+			Index:     v.Index,
+			NumLeaves: v.NumLeaves,
+			Hashes:    v.Hashes,
+		})
+	}
+	return proofs
+}
+
 func convertPropCensorFromPD(f pd.CensorshipRecord) www.CensorshipRecord {
 	return www.CensorshipRecord{
-		Token:     f.Token,
-		Merkle:    f.Merkle,
-		Signature: f.Signature,
+		Token:      f.Token,
+		Merkle:     f.Merkle,
+		Signature:  f.Signature,
+		FileProofs: convertFileProofsFromPD(f.FileProofs),
 	}
 }
 
@@ -180,6 +215,10 @@ func convertErrorStatusFromPD(s int) www.ErrorStatusT {
 		return www.ErrorStatusInvalidPropStatusTransition
 	case pd.ErrorStatusInvalidFilename:
 		return www.ErrorStatusInvalidFilename
+	case pd.ErrorStatusRecordNotFound:
+		return www.ErrorStatusProposalNotFound
+	case pd.ErrorStatusRecordNotAnchored:
+		return www.ErrorStatusProposalNotAnchored
 
 		// These cases are intentionally omitted because
 		// they are indicative of some internal server error,
@@ -224,6 +263,7 @@ func convertPropFromCache(r cache.Record) www.ProposalRecord {
 	// Decode markdown stream payloads
 	var bpm *BackendProposalMetadata
 	var msc []MDStreamChanges
+	var mca *MDStreamCoAuthors
 	for _, ms := range r.Metadata {
 		// General metadata
 		if ms.ID == mdStreamGeneral {
@@ -244,6 +284,16 @@ func convertPropFromCache(r cache.Record) www.ProposalRecord {
 			}
 			msc = md
 		}
+
+		// Co-authors metadata
+		if ms.ID == mdStreamCoAuthors {
+			md, err := decodeMDStreamCoAuthors([]byte(ms.Payload))
+			if err != nil {
+				log.Errorf("convertPropFromCache: decode MDStreamCoAuthors "+
+					"'%v' token '%v': %v", ms, r.CensorshipRecord.Token, err)
+			}
+			mca = md
+		}
 	}
 
 	// Compile proposal status change metadata
@@ -270,6 +320,7 @@ func convertPropFromCache(r cache.Record) www.ProposalRecord {
 
 	// Convert files
 	files := make([]www.File, 0, len(r.Files))
+	pdFiles := make([]pd.File, 0, len(r.Files))
 	for _, f := range r.Files {
 		files = append(files,
 			www.File{
@@ -278,13 +329,47 @@ func convertPropFromCache(r cache.Record) www.ProposalRecord {
 				Digest:  f.Digest,
 				Payload: f.Payload,
 			})
+		pdFiles = append(pdFiles,
+			pd.File{
+				Name:   f.Name,
+				MIME:   f.MIME,
+				Digest: f.Digest,
+			})
+	}
+
+	// The cache does not store the file proofs so they are calculated
+	// from the file digests when the files are returned.
+	var proofs []www.FileProof
+	if len(pdFiles) > 0 {
+		fp, err := pd.FileProofs(pdFiles)
+		if err != nil {
+			log.Errorf("convertPropFromCache: file proofs token '%v': %v",
+				r.CensorshipRecord.Token, err)
+		}
+		proofs = convertFileProofsFromPD(fp)
+	}
+
+	// Convert co-authors. A signature only counts when it is of the
+	// merkle root of this version of the proposal.
+	var coAuthors []www.CoAuthor
+	if mca != nil {
+		coAuthors = make([]www.CoAuthor, 0, len(mca.CoAuthors))
+		for _, v := range mca.CoAuthors {
+			coAuthors = append(coAuthors, www.CoAuthor{
+				UserID:    v.UserID,
+				PublicKey: v.PublicKey,
+				Signature: v.Signature,
+				Signed: v.Signature != "" &&
+					v.Merkle == r.CensorshipRecord.Merkle,
+			})
+		}
 	}
 
 	status := convertPropStatusFromCache(r.Status)
 
-	// The UserId, Username, and NumComments fields are returned
-	// as zero values since a cache record does not contain that
-	// data.
+	// The UserId, Username, NumComments, LinkedFrom and co-author
+	// Username fields are returned as zero values since a cache
+	// record does not contain that data.
 	return www.ProposalRecord{
 		Name:                bpm.Name,
 		State:               convertPropStatusToState(status),
@@ -301,10 +386,14 @@ func convertPropFromCache(r cache.Record) www.ProposalRecord {
 		PublishedAt:         publishedAt,
 		CensoredAt:          censoredAt,
 		AbandonedAt:         abandonedAt,
+		CoAuthors:           coAuthors,
+		LinkTo:              bpm.LinkTo,
+		LinkBy:              bpm.LinkBy,
 		CensorshipRecord: www.CensorshipRecord{
-			Token:     r.CensorshipRecord.Token,
-			Merkle:    r.CensorshipRecord.Merkle,
-			Signature: r.CensorshipRecord.Signature,
+			Token:      r.CensorshipRecord.Token,
+			Merkle:     r.CensorshipRecord.Merkle,
+			Signature:  r.CensorshipRecord.Signature,
+			FileProofs: proofs,
 		},
 	}
 }
@@ -412,6 +501,7 @@ func convertStartVoteFromDecred(sv decredplugin.StartVote) www.StartVote {
 		PublicKey: sv.PublicKey,
 		Vote: www.Vote{
 			Token:            sv.Vote.Token,
+			Type:             www.VoteT(decredplugin.VoteType(sv.Vote)),
 			Mask:             sv.Vote.Mask,
 			Duration:         sv.Vote.Duration,
 			QuorumPercentage: sv.Vote.QuorumPercentage,
@@ -431,14 +521,60 @@ func convertStartVoteReplyFromDecred(svr decredplugin.StartVoteReply) www.StartV
 	}
 }
 
+func convertStartVoteRunoffFromWWW(svr www.StartVoteRunoff) decredplugin.StartVoteRunoff {
+	svs := make([]decredplugin.StartVote, 0, len(svr.Votes))
+	for _, v := range svr.Votes {
+		svs = append(svs, convertStartVoteFromWWW(v))
+	}
+	return decredplugin.StartVoteRunoff{
+		Token:      svr.Token,
+		StartVotes: svs,
+	}
+}
+
+func convertScheduleVoteFromWWW(sv www.ScheduleVote) decredplugin.ScheduleVote {
+	return decredplugin.ScheduleVote{
+		Action:      decredplugin.ScheduleVoteActionSchedule,
+		Token:       sv.StartVote.Vote.Token,
+		StartHeight: sv.StartHeight,
+		StartVote:   convertStartVoteFromWWW(sv.StartVote),
+		Signature:   sv.Signature,
+		PublicKey:   sv.PublicKey,
+	}
+}
+
+func convertCancelVoteFromWWW(cv www.CancelVote) decredplugin.ScheduleVote {
+	return decredplugin.ScheduleVote{
+		Action:      decredplugin.ScheduleVoteActionCancel,
+		Token:       cv.Token,
+		StartHeight: cv.StartHeight,
+		Signature:   cv.Signature,
+		PublicKey:   cv.PublicKey,
+	}
+}
+
+func convertStartVoteRunoffReplyFromDecred(svrr decredplugin.StartVoteRunoffReply) www.StartVoteRunoffReply {
+	return www.StartVoteRunoffReply{
+		StartBlockHeight: svrr.StartVoteReply.StartBlockHeight,
+		StartBlockHash:   svrr.StartVoteReply.StartBlockHash,
+		EndHeight:        svrr.StartVoteReply.EndHeight,
+		EligibleTickets:  svrr.StartVoteReply.EligibleTickets,
+	}
+}
+
 func convertVoteDetailsReplyFromDecred(vdr decredplugin.VoteDetailsReply) VoteDetails {
 	av, avr := convertAuthVoteFromDecred(vdr.AuthorizeVote)
-	return VoteDetails{
+	vd := VoteDetails{
 		AuthorizeVote:      av,
 		AuthorizeVoteReply: avr,
 		StartVote:          convertStartVoteFromDecred(vdr.StartVote),
 		StartVoteReply:     convertStartVoteReplyFromDecred(vdr.StartVoteReply),
 	}
+	if vdr.ScheduleVote.Action == decredplugin.ScheduleVoteActionSchedule &&
+		vdr.StartVoteReply.StartBlockHeight == "" {
+		vd.ScheduledHeight = vdr.ScheduleVote.StartHeight
+	}
+	return vd
 }
 
 func convertCastVoteFromDecred(cv decredplugin.CastVote) www.CastVote {
@@ -497,6 +633,20 @@ func convertVoteOptionResultsFromDecred(vor []decredplugin.VoteOptionResult) []w
 	return r
 }
 
+func convertVoteRoundsFromDecred(rounds []decredplugin.VoteRound) []www.VoteRound {
+	if len(rounds) == 0 {
+		return nil
+	}
+	r := make([]www.VoteRound, 0, len(rounds))
+	for _, v := range rounds {
+		r = append(r, www.VoteRound{
+			OptionsResult: convertVoteOptionResultsFromDecred(v.Results),
+			Eliminated:    v.Eliminated,
+		})
+	}
+	return r
+}
+
 func convertTokenInventoryReplyFromDecred(r decredplugin.TokenInventoryReply) www.TokenInventoryReply {
 	return www.TokenInventoryReply{
 		Pre:        r.Pre,
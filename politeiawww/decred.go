@@ -236,6 +236,39 @@ func (p *politeiawww) decredVoteDetails(token string) (*decredplugin.VoteDetails
 	return vdr, nil
 }
 
+// decredVoteRunoffDetails sends the decred plugin voterunoffdetails command
+// to the cache and returns the runoff vote of the passed in RFP.
+func (p *politeiawww) decredVoteRunoffDetails(token string) (*decredplugin.VoteRunoffDetailsReply, error) {
+	// Setup plugin command
+	vrd := decredplugin.VoteRunoffDetails{
+		Token: token,
+	}
+
+	payload, err := decredplugin.EncodeVoteRunoffDetails(vrd)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdVoteRunoffDetails,
+		CommandPayload: string(payload),
+	}
+
+	// Get runoff vote from cache
+	reply, err := p.cache.PluginExec(pc)
+	if err != nil {
+		return nil, err
+	}
+
+	vrdr, err := decredplugin.DecodeVoteRunoffDetailsReply([]byte(reply.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	return vrdr, nil
+}
+
 // decredProposalVotes sends the decred plugin proposalvotes command to the
 // cache and returns the vote results for the passed in proposal.
 func (p *politeiawww) decredProposalVotes(token string) (*decredplugin.VoteResultsReply, error) {
@@ -368,7 +401,7 @@ func (p *politeiawww) decredLoadVoteResults(bestBlock uint64) (*decredplugin.Loa
 		return nil, err
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pcr.Response)
+	err = p.verifyChallenge(challenge, pcr.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -412,3 +445,66 @@ func (p *politeiawww) decredVoteSummary(token string) (*decredplugin.VoteSummary
 
 	return reply, nil
 }
+
+// decredSearch sends the decred plugin search command to the cache.  The
+// results start after the provided censorship token if one is specified.
+// Only records with one of the provided statuses are returned, unless no
+// statuses are specified, and at most limit results are returned.
+func (p *politeiawww) decredSearch(query, after string, statuses []int, limit int) (*decredplugin.SearchReply, error) {
+	payload, err := decredplugin.EncodeSearch(
+		decredplugin.Search{
+			Query:    query,
+			After:    after,
+			Statuses: statuses,
+			Limit:    limit,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	pc := cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdSearch,
+		CommandPayload: string(payload),
+	}
+
+	resp, err := p.cache.PluginExec(pc)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := decredplugin.DecodeSearchReply([]byte(resp.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// decredScheduledVotes uses the decred plugin scheduled votes command to
+// request the scheduled votes that have not been started yet from the cache.
+func (p *politeiawww) decredScheduledVotes() (*decredplugin.ScheduledVotesReply, error) {
+	payload, err := decredplugin.EncodeScheduledVotes(
+		decredplugin.ScheduledVotes{})
+	if err != nil {
+		return nil, err
+	}
+
+	pc := cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdScheduledVotes,
+		CommandPayload: string(payload),
+	}
+
+	resp, err := p.cache.PluginExec(pc)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := decredplugin.DecodeScheduledVotesReply([]byte(resp.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/user"
+	"github.com/google/uuid"
+)
+
+// convertDraftFilesFromWWW converts www files into user database draft files.
+func convertDraftFilesFromWWW(files []www.File) []user.DraftFile {
+	df := make([]user.DraftFile, 0, len(files))
+	for _, v := range files {
+		df = append(df, user.DraftFile{
+			Name:    v.Name,
+			MIME:    v.MIME,
+			Digest:  v.Digest,
+			Payload: v.Payload,
+		})
+	}
+	return df
+}
+
+// convertDraftFilesToWWW converts user database draft files into www files.
+func convertDraftFilesToWWW(files []user.DraftFile) []www.File {
+	f := make([]www.File, 0, len(files))
+	for _, v := range files {
+		f = append(f, www.File{
+			Name:    v.Name,
+			MIME:    v.MIME,
+			Digest:  v.Digest,
+			Payload: v.Payload,
+		})
+	}
+	return f
+}
+
+// convertDraftToWWW converts a user database draft into a www draft.  The
+// proposal name is left empty when the draft does not contain a valid index
+// file yet.
+func convertDraftToWWW(d user.Draft) www.Draft {
+	files := convertDraftFilesToWWW(d.Files)
+	name, err := getProposalName(files)
+	if err != nil {
+		name = ""
+	}
+	return www.Draft{
+		DraftID:   d.ID.String(),
+		Name:      name,
+		Files:     files,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// getUserDraft retrieves the specified draft and ensures that it belongs to
+// the provided user.  Drafts of other users are reported as not found so that
+// draft IDs can not be probed.
+func (p *politeiawww) getUserDraft(draftID string, u *user.User) (*user.Draft, error) {
+	id, err := uuid.Parse(draftID)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDraftNotFound,
+		}
+	}
+
+	d, err := p.db.DraftGetById(id)
+	if err == user.ErrDraftNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDraftNotFound,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if d.UserID != u.ID {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusDraftNotFound,
+		}
+	}
+
+	return d, nil
+}
+
+// processNewDraft saves a new proposal draft for the provided user.  Drafts
+// are stored in the user database and are never sent to politeiad.
+func (p *politeiawww) processNewDraft(nd www.NewDraft, u *user.User) (*www.NewDraftReply, error) {
+	log.Tracef("processNewDraft")
+
+	_, err := validateProposalFiles(nd.Files, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	d := user.Draft{
+		ID:        uuid.New(),
+		UserID:    u.ID,
+		Files:     convertDraftFilesFromWWW(nd.Files),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = p.db.DraftNew(d, www.PolicyMaxDrafts)
+	if err == user.ErrMaxDrafts {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusMaxDraftsExceededPolicy,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.NewDraftReply{
+		DraftID: d.ID.String(),
+	}, nil
+}
+
+// processEditDraft replaces the files of an existing proposal draft.
+func (p *politeiawww) processEditDraft(ed www.EditDraft, u *user.User) (*www.EditDraftReply, error) {
+	log.Tracef("processEditDraft: %v", ed.DraftID)
+
+	d, err := p.getUserDraft(ed.DraftID, u)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = validateProposalFiles(ed.Files, false)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Files = convertDraftFilesFromWWW(ed.Files)
+	d.UpdatedAt = time.Now().Unix()
+	err = p.db.DraftUpdate(*d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.EditDraftReply{}, nil
+}
+
+// processDeleteDraft deletes a proposal draft.
+func (p *politeiawww) processDeleteDraft(dd www.DeleteDraft, u *user.User) (*www.DeleteDraftReply, error) {
+	log.Tracef("processDeleteDraft: %v", dd.DraftID)
+
+	d, err := p.getUserDraft(dd.DraftID, u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.db.DraftDelete(d.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.DeleteDraftReply{}, nil
+}
+
+// processUserDrafts returns the proposal drafts of the provided user.
+func (p *politeiawww) processUserDrafts(u *user.User) (*www.UserDraftsReply, error) {
+	log.Tracef("processUserDrafts")
+
+	drafts, err := p.db.DraftsGetByUserId(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	wd := make([]www.Draft, 0, len(drafts))
+	for _, v := range drafts {
+		wd = append(wd, convertDraftToWWW(v))
+	}
+
+	return &www.UserDraftsReply{
+		Drafts: wd,
+	}, nil
+}
+
+// processSubmitDraft promotes a proposal draft to a new proposal.  The draft
+// files are submitted as a regular NewProposal, signed by the user, so all of
+// the new proposal requirements apply.  The draft is deleted once the
+// proposal has been submitted to politeiad.
+func (p *politeiawww) processSubmitDraft(sd www.SubmitDraft, u *user.User) (*www.SubmitDraftReply, error) {
+	log.Tracef("processSubmitDraft: %v", sd.DraftID)
+
+	d, err := p.getUserDraft(sd.DraftID, u)
+	if err != nil {
+		return nil, err
+	}
+
+	npr, err := p.processNewProposal(www.NewProposal{
+		Files:     convertDraftFilesToWWW(d.Files),
+		PublicKey: sd.PublicKey,
+		Signature: sd.Signature,
+	}, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// The proposal has been submitted at this point so failing to
+	// delete the draft must not fail the request.
+	err = p.db.DraftDelete(d.ID)
+	if err != nil {
+		log.Errorf("processSubmitDraft: DraftDelete %v: %v", d.ID, err)
+	}
+
+	return &www.SubmitDraftReply{
+		CensorshipRecord: npr.CensorshipRecord,
+	}, nil
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/decred/politeia/politeiad/testpoliteiad"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/google/uuid"
+)
+
+func TestProcessDrafts(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	usr, _ := newUser(t, p, true, false)
+	other, _ := newUser(t, p, true, false)
+
+	f := newFileRandomMD(t)
+	nd := www.NewDraft{
+		Files: []www.File{f},
+	}
+
+	// Drafts without an index file are allowed
+	md := createFileMD(t, 8, "Draft without index")
+	md.Name = "notes.md"
+	ndr, err := p.processNewDraft(www.NewDraft{
+		Files: []www.File{*md},
+	}, usr)
+	if err != nil {
+		t.Fatalf("draft without index: %v", err)
+	}
+	_, err = p.processDeleteDraft(www.DeleteDraft{
+		DraftID: ndr.DraftID,
+	}, usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup tests
+	var tests = []struct {
+		name string
+		nd   www.NewDraft
+		want error
+	}{
+		{"no files", www.NewDraft{},
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalMissingFiles,
+			}},
+
+		{"duplicate filenames",
+			www.NewDraft{
+				Files: []www.File{f, f},
+			},
+			www.UserError{
+				ErrorCode:    www.ErrorStatusProposalDuplicateFilenames,
+				ErrorContext: []string{f.Name},
+			}},
+
+		{"success", nd, nil},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processNewDraft(v.nd, usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+
+	// List drafts
+	udr, err := p.processUserDrafts(usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(udr.Drafts) != 1 {
+		t.Fatalf("got %v drafts, want 1", len(udr.Drafts))
+	}
+	d := udr.Drafts[0]
+	if !reflect.DeepEqual(d.Files, nd.Files) {
+		t.Fatalf("got files %v, want %v", d.Files, nd.Files)
+	}
+	if d.Name == "" {
+		t.Fatalf("draft name not set")
+	}
+
+	// Drafts are private to their author
+	udr, err = p.processUserDrafts(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(udr.Drafts) != 0 {
+		t.Fatalf("got %v drafts of other user, want 0", len(udr.Drafts))
+	}
+	_, err = p.processEditDraft(www.EditDraft{
+		DraftID: d.DraftID,
+		Files:   nd.Files,
+	}, other)
+	want := errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusDraftNotFound,
+	})
+	if got := errToStr(err); got != want {
+		t.Fatalf("got error %v, want %v", got, want)
+	}
+	_, err = p.processDeleteDraft(www.DeleteDraft{
+		DraftID: uuid.New().String(),
+	}, usr)
+	if got := errToStr(err); got != want {
+		t.Fatalf("got error %v, want %v", got, want)
+	}
+
+	// Edit draft
+	f2 := newFileRandomMD(t)
+	_, err = p.processEditDraft(www.EditDraft{
+		DraftID: d.DraftID,
+		Files:   []www.File{f2},
+	}, usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	udr, err = p.processUserDrafts(usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(udr.Drafts[0].Files, []www.File{f2}) {
+		t.Fatalf("draft files not updated")
+	}
+
+	// Max drafts policy
+	for i := 1; i < www.PolicyMaxDrafts; i++ {
+		_, err = p.processNewDraft(nd, usr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = p.processNewDraft(nd, usr)
+	want = errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusMaxDraftsExceededPolicy,
+	})
+	if got := errToStr(err); got != want {
+		t.Fatalf("got error %v, want %v", got, want)
+	}
+}
+
+func TestProcessNewDraftConcurrent(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	usr, _ := newUser(t, p, true, false)
+	nd := www.NewDraft{
+		Files: []www.File{newFileRandomMD(t)},
+	}
+
+	// Submit more drafts than allowed at the same time
+	var wg sync.WaitGroup
+	errs := make(chan error, www.PolicyMaxDrafts*2)
+	for i := 0; i < www.PolicyMaxDrafts*2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.processNewDraft(nd, usr)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	want := errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusMaxDraftsExceededPolicy,
+	})
+	var saved int
+	for err := range errs {
+		if err == nil {
+			saved++
+			continue
+		}
+		if got := errToStr(err); got != want {
+			t.Fatalf("got error %v, want %v", got, want)
+		}
+	}
+	if saved != www.PolicyMaxDrafts {
+		t.Fatalf("got %v drafts saved, want %v", saved, www.PolicyMaxDrafts)
+	}
+
+	udr, err := p.processUserDrafts(usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(udr.Drafts) != www.PolicyMaxDrafts {
+		t.Fatalf("got %v drafts, want %v", len(udr.Drafts),
+			www.PolicyMaxDrafts)
+	}
+}
+
+func TestProcessSubmitDraft(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	p.cfg.RPCHost = td.URL
+	p.cfg.Identity = td.PublicIdentity
+
+	usr, id := newUser(t, p, true, false)
+	payRegistrationFee(t, p, usr)
+	addProposalCredits(t, p, usr, 10)
+
+	f := newFileRandomMD(t)
+	ndr, err := p.processNewDraft(www.NewDraft{
+		Files: []www.File{f},
+	}, usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	np := createNewProposal(t, id, []www.File{f})
+
+	// Setup tests
+	var tests = []struct {
+		name string
+		sd   www.SubmitDraft
+		want error
+	}{
+		{"invalid draft id",
+			www.SubmitDraft{
+				DraftID:   "invalid",
+				PublicKey: np.PublicKey,
+				Signature: np.Signature,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusDraftNotFound,
+			}},
+
+		{"invalid signature",
+			www.SubmitDraft{
+				DraftID:   ndr.DraftID,
+				PublicKey: np.PublicKey,
+				Signature: "",
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSignature,
+			}},
+
+		{"success",
+			www.SubmitDraft{
+				DraftID:   ndr.DraftID,
+				PublicKey: np.PublicKey,
+				Signature: np.Signature,
+			},
+			nil},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			sdr, err := p.processSubmitDraft(v.sd, usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+
+			if v.want != nil {
+				// Test case passes
+				return
+			}
+
+			// Validate success case
+			if sdr.CensorshipRecord.Token == "" {
+				t.Errorf("censorship token not set")
+			}
+		})
+	}
+
+	// The draft is deleted once it has been submitted
+	udr, err := p.processUserDrafts(usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(udr.Drafts) != 0 {
+		t.Fatalf("got %v drafts, want 0", len(udr.Drafts))
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/dajohi/goemail"
+	"github.com/google/uuid"
 
 	www "github.com/decred/politeia/politeiawww/api/www/v1"
 	"github.com/decred/politeia/politeiawww/user"
@@ -287,8 +288,9 @@ func (p *politeiawww) emailUsersForEditedProposal(proposal *www.ProposalRecord,
 }
 
 // emailUsersForProposalVoteStarted sends an email notification for a proposal
-// entering the voting state.
-func (p *politeiawww) emailUsersForProposalVoteStarted(proposal *www.ProposalRecord, authorUser *user.User, adminUser *user.User) error {
+// entering the voting state.  The proposal author and co-authors are sent the
+// author notification; authorUsers must start with the proposal author.
+func (p *politeiawww) emailUsersForProposalVoteStarted(proposal *www.ProposalRecord, authorUsers []*user.User, adminUser *user.User) error {
 	if p.smtp.disabled {
 		return nil
 	}
@@ -303,12 +305,17 @@ func (p *politeiawww) emailUsersForProposalVoteStarted(proposal *www.ProposalRec
 	tplData := proposalVoteStartedTemplateData{
 		Link:     l.String(),
 		Name:     proposal.Name,
-		Username: authorUser.Username,
+		Username: authorUsers[0].Username,
 	}
 
-	// Send email to author.
-	if authorUser.EmailNotifications&
-		uint64(www.NotificationEmailMyProposalVoteStarted) != 0 {
+	// Send email to authors.
+	authors := make(map[uuid.UUID]struct{}, len(authorUsers))
+	for _, authorUser := range authorUsers {
+		authors[authorUser.ID] = struct{}{}
+		if authorUser.EmailNotifications&
+			uint64(www.NotificationEmailMyProposalVoteStarted) == 0 {
+			continue
+		}
 
 		subject := "Your Proposal Has Started Voting"
 		body, err := createBody(templateProposalVoteStartedForAuthor, &tplData)
@@ -332,9 +339,9 @@ func (p *politeiawww) emailUsersForProposalVoteStarted(proposal *www.ProposalRec
 		// Add user emails to the goemail.Message
 		return p.db.AllUsers(func(u *user.User) {
 			// Don't notify the user under certain conditions.
+			_, isAuthor := authors[u.ID]
 			if u.NewUserPaywallTx == "" || u.Deactivated ||
-				u.ID == adminUser.ID ||
-				u.ID == authorUser.ID ||
+				u.ID == adminUser.ID || isAuthor ||
 				(u.EmailNotifications&
 					uint64(www.NotificationEmailRegularProposalVoteStarted)) == 0 {
 				return
@@ -494,6 +501,62 @@ func (p *politeiawww) emailAuthorForCommentOnComment(proposal *www.ProposalRecor
 	return p.sendEmailTo(subject, body, authorUser.Email)
 }
 
+// emailCoAuthorForInvite sends an email notification to a user that has been
+// invited to co-author a proposal.
+func (p *politeiawww) emailCoAuthorForInvite(proposal *www.ProposalRecord, authorUser *user.User, coAuthorUser *user.User) error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	l, err := url.Parse(p.cfg.WebServerAddress + "/proposals/" +
+		proposal.CensorshipRecord.Token)
+	if err != nil {
+		return err
+	}
+
+	tplData := coAuthorTemplateData{
+		Link:     l.String(),
+		Name:     proposal.Name,
+		Username: authorUser.Username,
+	}
+
+	subject := "You Have Been Invited To Co-Author A Proposal"
+	body, err := createBody(templateCoAuthorInvited, &tplData)
+	if err != nil {
+		return err
+	}
+
+	return p.sendEmailTo(subject, body, coAuthorUser.Email)
+}
+
+// emailCoAuthorForEditedProposal sends an email notification to a proposal
+// co-author that the proposal has been edited and must be signed again.
+func (p *politeiawww) emailCoAuthorForEditedProposal(proposal *www.ProposalRecord, authorUser *user.User, coAuthorUser *user.User) error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	l, err := url.Parse(p.cfg.WebServerAddress + "/proposals/" +
+		proposal.CensorshipRecord.Token)
+	if err != nil {
+		return err
+	}
+
+	tplData := coAuthorTemplateData{
+		Link:     l.String(),
+		Name:     proposal.Name,
+		Username: authorUser.Username,
+	}
+
+	subject := "A Proposal You Co-Author Has Been Edited"
+	body, err := createBody(templateProposalEditedForCoAuthor, &tplData)
+	if err != nil {
+		return err
+	}
+
+	return p.sendEmailTo(subject, body, coAuthorUser.Email)
+}
+
 // emailUpdateUserKeyVerificationLink emails the link with the verification
 // token used for setting a new key pair if the email server is set up.
 func (p *politeiawww) emailUpdateUserKeyVerificationLink(email, publicKey, token string) error {
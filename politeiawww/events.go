@@ -29,6 +29,7 @@ const (
 	EventTypeProposalVoteStarted
 	EventTypeProposalVoteAuthorized
 	EventTypeProposalVoteFinished
+	EventTypeCoAuthorInvited
 	EventTypeComment
 	EventTypeUserManage
 	EventTypeInvoiceComment      // CMS Type
@@ -61,6 +62,12 @@ type EventDataProposalVoteAuthorized struct {
 	User          *user.User
 }
 
+type EventDataCoAuthorInvited struct {
+	Proposal *www.ProposalRecord
+	Author   *user.User
+	CoAuthor *user.User
+}
+
 type EventDataComment struct {
 	Comment *www.Comment
 }
@@ -137,6 +144,7 @@ func (p *politeiawww) initEventManager() {
 	p._setupProposalVoteStartedEmailNotification()
 	p._setupProposalVoteAuthorizedEmailNotification()
 	p._setupCommentReplyEmailNotifications()
+	p._setupCoAuthorInvitedEmailNotification()
 }
 
 func (p *politeiawww) initCMSEventManager() {
@@ -238,13 +246,18 @@ func (p *politeiawww) _setupProposalStatusChangeEmailNotification() {
 				continue
 			}
 
+			authors := append([]*user.User{author},
+				p.getProposalCoAuthors(psc.Proposal)...)
+
 			switch psc.SetProposalStatus.ProposalStatus {
 			case www.PropStatusPublic:
-				err = p.emailAuthorForVettedProposal(psc.Proposal, author,
-					psc.AdminUser)
-				if err != nil {
-					log.Errorf("email author for vetted proposal %v: %v",
-						psc.Proposal.CensorshipRecord.Token, err)
+				for _, v := range authors {
+					err = p.emailAuthorForVettedProposal(psc.Proposal, v,
+						psc.AdminUser)
+					if err != nil {
+						log.Errorf("email author for vetted proposal %v: %v",
+							psc.Proposal.CensorshipRecord.Token, err)
+					}
 				}
 				err = p.emailUsersForVettedProposal(psc.Proposal, author,
 					psc.AdminUser)
@@ -253,11 +266,13 @@ func (p *politeiawww) _setupProposalStatusChangeEmailNotification() {
 						psc.Proposal.CensorshipRecord.Token, err)
 				}
 			case www.PropStatusCensored:
-				err = p.emailAuthorForCensoredProposal(psc.Proposal, author,
-					psc.AdminUser)
-				if err != nil {
-					log.Errorf("email author for censored proposal %v: %v",
-						psc.Proposal.CensorshipRecord.Token, err)
+				for _, v := range authors {
+					err = p.emailAuthorForCensoredProposal(psc.Proposal, v,
+						psc.AdminUser)
+					if err != nil {
+						log.Errorf("email author for censored proposal %v: %v",
+							psc.Proposal.CensorshipRecord.Token, err)
+					}
 				}
 			default:
 			}
@@ -301,16 +316,25 @@ func (p *politeiawww) _setupProposalEditedEmailNotification() {
 				continue
 			}
 
-			if pe.Proposal.Status != www.PropStatusPublic {
-				continue
-			}
-
 			author, err := p.db.UserGetByPubKey(pe.Proposal.PublicKey)
 			if err != nil {
 				log.Errorf("cannot fetch author for proposal: %v", err)
 				continue
 			}
 
+			// Co-authors must sign the edited proposal again
+			for _, v := range p.getProposalCoAuthors(pe.Proposal) {
+				err = p.emailCoAuthorForEditedProposal(pe.Proposal, author, v)
+				if err != nil {
+					log.Errorf("email co-author for edited proposal %v: %v",
+						pe.Proposal.CensorshipRecord.Token, err)
+				}
+			}
+
+			if pe.Proposal.Status != www.PropStatusPublic {
+				continue
+			}
+
 			err = p.emailUsersForEditedProposal(pe.Proposal, author)
 			if err != nil {
 				log.Errorf("email users for edited proposal %v: %v",
@@ -339,7 +363,9 @@ func (p *politeiawww) _setupProposalVoteStartedEmailNotification() {
 				continue
 			}
 
-			err = p.emailUsersForProposalVoteStarted(proposal, author,
+			authors := append([]*user.User{author},
+				p.getProposalCoAuthors(proposal)...)
+			err = p.emailUsersForProposalVoteStarted(proposal, authors,
 				pvs.AdminUser)
 			if err != nil {
 				log.Errorf("email all admins for new submitted proposal %v: %v",
@@ -418,11 +444,15 @@ func (p *politeiawww) _setupCommentReplyEmailNotifications() {
 
 			if c.Comment.ParentID == "0" {
 				// Top-level comment
-				err := p.emailAuthorForCommentOnProposal(proposal, author,
-					c.Comment.CommentID, c.Comment.Username)
-				if err != nil {
-					log.Errorf("email author of proposal %v for new comment %v: %v",
-						c.Comment.Token, c.Comment.CommentID, err)
+				authors := append([]*user.User{author},
+					p.getProposalCoAuthors(proposal)...)
+				for _, v := range authors {
+					err := p.emailAuthorForCommentOnProposal(proposal, v,
+						c.Comment.CommentID, c.Comment.Username)
+					if err != nil {
+						log.Errorf("email author of proposal %v for new comment %v: %v",
+							c.Comment.Token, c.Comment.CommentID, err)
+					}
 				}
 			} else {
 				parent, err := p.decredCommentGetByID(token, c.Comment.ParentID)
@@ -451,6 +481,27 @@ func (p *politeiawww) _setupCommentReplyEmailNotifications() {
 	p.eventManager._register(EventTypeComment, ch)
 }
 
+func (p *politeiawww) _setupCoAuthorInvitedEmailNotification() {
+	ch := make(chan interface{})
+	go func() {
+		for data := range ch {
+			ci, ok := data.(EventDataCoAuthorInvited)
+			if !ok {
+				log.Errorf("invalid event data")
+				continue
+			}
+
+			err := p.emailCoAuthorForInvite(ci.Proposal, ci.Author,
+				ci.CoAuthor)
+			if err != nil {
+				log.Errorf("email co-author %v for proposal %v: %v",
+					ci.CoAuthor.ID, ci.Proposal.CensorshipRecord.Token, err)
+			}
+		}
+	}()
+	p.eventManager._register(EventTypeCoAuthorInvited, ch)
+}
+
 func (p *politeiawww) _setupUserManageLogging() {
 	ch := make(chan interface{})
 	go func() {
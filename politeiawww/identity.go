@@ -0,0 +1,128 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/util"
+)
+
+// politeiadIdentity returns the active politeiad identity.
+func (p *politeiawww) politeiadIdentity() *identity.PublicIdentity {
+	p.identityMtx.RLock()
+	defer p.identityMtx.RUnlock()
+
+	return p.cfg.Identity
+}
+
+// getIdentityHistory retrieves the politeiad identity history and verifies
+// that it is authenticated by the trusted identity.  The challenge must be
+// signed by the active key of the history.
+func (p *politeiawww) getIdentityHistory(trusted *identity.PublicIdentity) ([]pd.IdentityKey, error) {
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	ih := pd.IdentityHistory{
+		Challenge: hex.EncodeToString(challenge),
+	}
+
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.IdentityHistoryRoute, ih)
+	if err != nil {
+		return nil, err
+	}
+
+	var ihr pd.IdentityHistoryReply
+	err = json.Unmarshal(responseBody, &ihr)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal IdentityHistoryReply: %v",
+			err)
+	}
+
+	err = pd.VerifyIdentityHistory(ihr.Keys, *trusted)
+	if err != nil {
+		return nil, err
+	}
+	active, err := pd.IdentityKeyPublic(ihr.Keys[len(ihr.Keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	err = util.VerifyChallenge(active, challenge, ihr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return ihr.Keys, nil
+}
+
+// updateIdentity adopts the active key of the politeiad identity history when
+// politeiad rotated its identity.  The history must be authenticated by the
+// identity that failed verification.  The new identity is saved to the
+// politeiad identity file so that it is trusted after a restart.  It returns
+// true if the identity was replaced.
+func (p *politeiawww) updateIdentity(failed *identity.PublicIdentity) (bool, error) {
+	p.identityMtx.Lock()
+	defer p.identityMtx.Unlock()
+
+	// Another request may have updated the identity already.
+	if p.cfg.Identity.Key != failed.Key {
+		return true, nil
+	}
+
+	keys, err := p.getIdentityHistory(failed)
+	if err != nil {
+		return false, err
+	}
+	active, err := pd.IdentityKeyPublic(keys[len(keys)-1])
+	if err != nil {
+		return false, err
+	}
+	if active.Key == failed.Key {
+		return false, nil
+	}
+
+	if !p.test {
+		err = active.SavePublicIdentity(p.cfg.RPCIdentityFile)
+		if err != nil {
+			return false, err
+		}
+	}
+	p.cfg.Identity = active
+
+	log.Infof("Politeiad identity rotated")
+	log.Infof("Key        : %x", active.Key)
+	log.Infof("Fingerprint: %v", active.Fingerprint())
+
+	return true, nil
+}
+
+// verifyChallenge verifies a politeiad challenge response with the politeiad
+// identity.  When verification fails the identity history is consulted to
+// find out whether politeiad rotated its identity.
+func (p *politeiawww) verifyChallenge(challenge []byte, response string) error {
+	id := p.politeiadIdentity()
+	err := util.VerifyChallenge(id, challenge, response)
+	if err == nil {
+		return nil
+	}
+
+	updated, err2 := p.updateIdentity(id)
+	if err2 != nil {
+		log.Errorf("verifyChallenge: updateIdentity: %v", err2)
+		return err
+	}
+	if !updated {
+		return err
+	}
+
+	return util.VerifyChallenge(p.politeiadIdentity(), challenge, response)
+}
@@ -364,7 +364,7 @@ func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.N
 	}
 
 	// Verify NewRecord challenge
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.verifyChallenge(challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -414,7 +414,7 @@ func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.N
 	}
 
 	// Verify the SetUnvettedStatus challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge,
+	err = p.verifyChallenge(challenge,
 		pdSetUnvettedStatusReply.Response)
 	if err != nil {
 		return nil, err
@@ -860,7 +860,7 @@ func (p *politeiawww) processSetInvoiceStatus(sis cms.SetInvoiceStatus, u *user.
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.verifyChallenge(challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1103,7 +1103,7 @@ func (p *politeiawww) processEditInvoice(ei cms.EditInvoice, u *user.User) (*cms
 		return nil, fmt.Errorf("Unmarshal UpdateUnvettedReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.verifyChallenge(challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1150,7 +1150,7 @@ func (p *politeiawww) processEditInvoice(ei cms.EditInvoice, u *user.User) (*cms
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, updateMetaReply.Response)
+	err = p.verifyChallenge(challenge, updateMetaReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1498,7 +1498,7 @@ func (p *politeiawww) processPayInvoices(u *user.User) (*cms.PayInvoicesReply, e
 		}
 
 		// Verify the UpdateVettedMetadata challenge.
-		err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+		err = p.verifyChallenge(challenge, pdReply.Response)
 		if err != nil {
 			return nil, err
 		}
@@ -58,7 +58,7 @@ func (p *politeiawww) getBestBlock() (uint64, error) {
 	}
 
 	// Verify the challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return 0, err
 	}
@@ -99,7 +99,7 @@ func (p *politeiawww) getPluginInventory() ([]Plugin, error) {
 		return nil, err
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
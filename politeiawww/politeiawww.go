@@ -51,6 +51,10 @@ var (
 		template.New("comment_reply_on_proposal").Parse(templateCommentReplyOnProposalRaw))
 	templateCommentReplyOnComment = template.Must(
 		template.New("comment_reply_on_comment").Parse(templateCommentReplyOnCommentRaw))
+	templateCoAuthorInvited = template.Must(
+		template.New("coauthor_invited_template").Parse(templateCoAuthorInvitedRaw))
+	templateProposalEditedForCoAuthor = template.Must(
+		template.New("proposal_edited_for_coauthor_template").Parse(templateProposalEditedForCoAuthorRaw))
 )
 
 // wsContext is the websocket context. If uuid == "" then it is an
@@ -96,6 +100,10 @@ type politeiawww struct {
 	// Politeiad client
 	client *http.Client
 
+	// identityMtx protects the politeiad identity, cfg.Identity, which is
+	// replaced when politeiad rotates its identity.
+	identityMtx sync.RWMutex
+
 	// SMTP client
 	smtp *smtp
 
@@ -120,6 +128,14 @@ type politeiawww struct {
 	// proposals whose voting period has ended.
 	voteStatuses map[string]www.VoteStatusReply // [token]VoteStatusReply
 
+	// linkedFrom contains the tokens of the public proposals that
+	// have been submitted to an RFP.
+	linkedFrom map[string]map[string]bool // [rfpToken][token]
+
+	// coAuthorLocks serializes the read-modify-write of the co-authors
+	// metadata stream of a proposal.
+	coAuthorLocks map[string]*coAuthorLock // [token]lock
+
 	// XXX userEmails is a temporary measure until the user by email
 	// lookups are completely removed from politeiawww.
 	userEmails map[string]uuid.UUID // [email]userID
@@ -157,7 +173,7 @@ func (p *politeiawww) handleVersion(w http.ResponseWriter, r *http.Request) {
 	versionReply := www.VersionReply{
 		Version: www.PoliteiaWWWAPIVersion,
 		Route:   www.PoliteiaWWWAPIRoute,
-		PubKey:  hex.EncodeToString(p.cfg.Identity.Key[:]),
+		PubKey:  hex.EncodeToString(p.politeiadIdentity().Key[:]),
 		TestNet: p.cfg.TestNet,
 		Mode:    p.cfg.Mode,
 	}
@@ -334,6 +350,10 @@ func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
 		MaxProposalNameLength:      www.PolicyMaxProposalNameLength,
 		ProposalNameSupportedChars: www.PolicyProposalNameSupportedChars,
 		MaxCommentLength:           www.PolicyMaxCommentLength,
+		MaxDrafts:                  www.PolicyMaxDrafts,
+		MaxCoAuthors:               www.PolicyMaxCoAuthors,
+		LinkByMinPeriod:            www.PolicyLinkByMinPeriod,
+		LinkByMaxPeriod:            www.PolicyLinkByMaxPeriod,
 	}
 
 	util.RespondWithJSON(w, http.StatusOK, reply)
@@ -484,6 +504,68 @@ func (p *politeiawww) handleVoteStatus(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, vsr)
 }
 
+// handleVoteRunoff returns the runoff vote results of an RFP.
+func (p *politeiawww) handleVoteRunoff(w http.ResponseWriter, r *http.Request) {
+	pathParams := mux.Vars(r)
+	vrr, err := p.processVoteRunoff(pathParams["token"])
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVoteRunoff: processVoteRunoff: %v", err)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, vrr)
+}
+
+// handleVoteBundle returns the signed vote bundle of a public proposal.
+func (p *politeiawww) handleVoteBundle(w http.ResponseWriter, r *http.Request) {
+	pathParams := mux.Vars(r)
+	vbr, err := p.processVoteBundle(pathParams["token"])
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleVoteBundle: processVoteBundle: %v", err)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, vbr)
+}
+
+// handleProposalHistory returns all versions of a public proposal along with
+// the changes that were made in each version.
+func (p *politeiawww) handleProposalHistory(w http.ResponseWriter, r *http.Request) {
+	pathParams := mux.Vars(r)
+	phr, err := p.processProposalHistory(pathParams["token"])
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalHistory: processProposalHistory: %v", err)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, phr)
+}
+
+// handleProposalAnchor returns the dcrtime anchor of a public proposal
+// version.
+func (p *politeiawww) handleProposalAnchor(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalAnchor")
+
+	var pa www.ProposalAnchor
+	err := util.ParseGetParams(r, &pa)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleProposalAnchor: ParseGetParams",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+	pa.Token = mux.Vars(r)["token"]
+
+	par, err := p.processProposalAnchor(pa)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalAnchor: processProposalAnchor: %v", err)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, par)
+}
+
 // handleProposalsStats returns the counting of proposals aggrouped by each proposal status
 func (p *politeiawww) handleProposalsStats(w http.ResponseWriter, r *http.Request) {
 	psr, err := p.processProposalsStats()
@@ -517,6 +599,30 @@ func (p *politeiawww) handleTokenInventory(w http.ResponseWriter, r *http.Reques
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// handleSearchProposals handles the incoming search proposals command.
+func (p *politeiawww) handleSearchProposals(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSearchProposals")
+
+	var sp www.SearchProposals
+	err := util.ParseGetParams(r, &sp)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleSearchProposals: ParseGetParams",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	reply, err := p.processSearchProposals(sp)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSearchProposals: processSearchProposals: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 // handleProposalPaywallDetails returns paywall details that allows the user to
 // purchase proposal credits.
 func (p *politeiawww) handleProposalPaywallDetails(w http.ResponseWriter, r *http.Request) {
@@ -570,6 +676,192 @@ func (p *politeiawww) handleNewProposal(w http.ResponseWriter, r *http.Request)
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// handleInviteCoAuthor invites a user to co-author a proposal.
+func (p *politeiawww) handleInviteCoAuthor(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleInviteCoAuthor")
+
+	var v www.InviteCoAuthor
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&v); err != nil {
+		RespondWithError(w, r, 0, "handleInviteCoAuthor: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleInviteCoAuthor: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processInviteCoAuthor(v, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleInviteCoAuthor: processInviteCoAuthor %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSignCoAuthor adds the signature of a co-author to a proposal.
+func (p *politeiawww) handleSignCoAuthor(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSignCoAuthor")
+
+	var v www.SignCoAuthor
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&v); err != nil {
+		RespondWithError(w, r, 0, "handleSignCoAuthor: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSignCoAuthor: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processSignCoAuthor(v, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSignCoAuthor: processSignCoAuthor %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleNewDraft saves a new proposal draft for the logged in user.
+func (p *politeiawww) handleNewDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleNewDraft")
+
+	var v www.NewDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&v); err != nil {
+		RespondWithError(w, r, 0, "handleNewDraft: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processNewDraft(v, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewDraft: processNewDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleEditDraft replaces the files of a proposal draft.
+func (p *politeiawww) handleEditDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleEditDraft")
+
+	var v www.EditDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&v); err != nil {
+		RespondWithError(w, r, 0, "handleEditDraft: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processEditDraft(v, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditDraft: processEditDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleDeleteDraft deletes a proposal draft.
+func (p *politeiawww) handleDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDeleteDraft")
+
+	var v www.DeleteDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&v); err != nil {
+		RespondWithError(w, r, 0, "handleDeleteDraft: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeleteDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processDeleteDraft(v, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeleteDraft: processDeleteDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleSubmitDraft submits a proposal draft as a new proposal.
+func (p *politeiawww) handleSubmitDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSubmitDraft")
+
+	var v www.SubmitDraft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&v); err != nil {
+		RespondWithError(w, r, 0, "handleSubmitDraft: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSubmitDraft: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processSubmitDraft(v, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleSubmitDraft: processSubmitDraft %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 // handleNewComment handles incomming comments.
 func (p *politeiawww) handleNewComment(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("handleNewComment")
@@ -690,6 +982,34 @@ func (p *politeiawww) handleAuthorizeVote(w http.ResponseWriter, r *http.Request
 	util.RespondWithJSON(w, http.StatusOK, avr)
 }
 
+// handleCancelVote handles the cancellation of a scheduled vote by the
+// proposal author.
+func (p *politeiawww) handleCancelVote(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleCancelVote")
+	var cv www.CancelVote
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&cv); err != nil {
+		RespondWithError(w, r, 0, "handleCancelVote: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleCancelVote: getSessionUser %v", err)
+		return
+	}
+	cvr, err := p.processCancelVote(cv, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleCancelVote: processCancelVote %v", err)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, cvr)
+}
+
 // handleProposalPaywallPayment returns the payment details for a pending
 // proposal paywall payment.
 func (p *politeiawww) handleProposalPaywallPayment(w http.ResponseWriter, r *http.Request) {
@@ -1053,6 +1373,84 @@ func (p *politeiawww) handleStartVote(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, svr)
 }
 
+// handleStartVoteRunoff handles starting a runoff vote between the
+// submissions of an RFP.
+func (p *politeiawww) handleStartVoteRunoff(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleStartVoteRunoff")
+
+	var svr www.StartVoteRunoff
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&svr); err != nil {
+		RespondWithError(w, r, 0, "handleStartVoteRunoff: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleStartVoteRunoff: getSessionUser %v", err)
+		return
+	}
+
+	// Sanity
+	if !user.Admin {
+		RespondWithError(w, r, 0,
+			"handleStartVoteRunoff: admin %v", user.Admin)
+		return
+	}
+
+	svrr, err := p.processStartVoteRunoff(svr, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleStartVoteRunoff: processStartVoteRunoff %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, svrr)
+}
+
+// handleScheduleVote handles scheduling the start of a proposal vote at a
+// future block height.
+func (p *politeiawww) handleScheduleVote(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleScheduleVote")
+
+	var sv www.ScheduleVote
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&sv); err != nil {
+		RespondWithError(w, r, 0, "handleScheduleVote: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleScheduleVote: getSessionUser %v", err)
+		return
+	}
+
+	// Sanity
+	if !user.Admin {
+		RespondWithError(w, r, 0,
+			"handleScheduleVote: admin %v", user.Admin)
+		return
+	}
+
+	svr, err := p.processScheduleVote(sv, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleScheduleVote: processScheduleVote %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, svr)
+}
+
 // handleCensorComment handles the censoring of a comment by an admin.
 func (p *politeiawww) handleCensorComment(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("handleCensorComment")
@@ -1123,10 +1521,20 @@ func (p *politeiawww) setPoliteiaWWWRoutes() {
 		p.handleGetAllVoteStatus, permissionPublic)
 	p.addRoute(http.MethodGet, www.RouteVoteStatus,
 		p.handleVoteStatus, permissionPublic)
+	p.addRoute(http.MethodGet, www.RouteVoteRunoff,
+		p.handleVoteRunoff, permissionPublic)
+	p.addRoute(http.MethodGet, www.RouteVoteBundle,
+		p.handleVoteBundle, permissionPublic)
+	p.addRoute(http.MethodGet, www.RouteProposalHistory,
+		p.handleProposalHistory, permissionPublic)
+	p.addRoute(http.MethodGet, www.RouteProposalAnchor,
+		p.handleProposalAnchor, permissionPublic)
 	p.addRoute(http.MethodGet, www.RoutePropsStats,
 		p.handleProposalsStats, permissionPublic)
 	p.addRoute(http.MethodGet, www.RouteTokenInventory,
 		p.handleTokenInventory, permissionPublic)
+	p.addRoute(http.MethodGet, www.RouteSearchProposals,
+		p.handleSearchProposals, permissionPublic)
 	p.addRoute(http.MethodPost, www.RouteBatchProposals,
 		p.handleBatchProposals, permissionPublic)
 
@@ -1141,10 +1549,24 @@ func (p *politeiawww) setPoliteiaWWWRoutes() {
 		p.handleLikeComment, permissionLogin) // XXX comments need to become a setting
 	p.addRoute(http.MethodPost, www.RouteEditProposal,
 		p.handleEditProposal, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteInviteCoAuthor,
+		p.handleInviteCoAuthor, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteSignCoAuthor,
+		p.handleSignCoAuthor, permissionLogin)
 	p.addRoute(http.MethodPost, www.RouteAuthorizeVote,
 		p.handleAuthorizeVote, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteCancelVote,
+		p.handleCancelVote, permissionLogin)
 	p.addRoute(http.MethodGet, www.RouteProposalPaywallPayment,
 		p.handleProposalPaywallPayment, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteNewDraft,
+		p.handleNewDraft, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteEditDraft,
+		p.handleEditDraft, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteDeleteDraft,
+		p.handleDeleteDraft, permissionLogin)
+	p.addRoute(http.MethodPost, www.RouteSubmitDraft,
+		p.handleSubmitDraft, permissionLogin)
 
 	// Unauthenticated websocket
 	p.addRoute("", www.RouteUnauthenticatedWebSocket,
@@ -1160,6 +1582,10 @@ func (p *politeiawww) setPoliteiaWWWRoutes() {
 		p.handleSetProposalStatus, permissionAdmin)
 	p.addRoute(http.MethodPost, www.RouteStartVote,
 		p.handleStartVote, permissionAdmin)
+	p.addRoute(http.MethodPost, www.RouteStartVoteRunoff,
+		p.handleStartVoteRunoff, permissionAdmin)
+	p.addRoute(http.MethodPost, www.RouteScheduleVote,
+		p.handleScheduleVote, permissionAdmin)
 	p.addRoute(http.MethodPost, www.RouteCensorComment,
 		p.handleCensorComment, permissionAdmin)
 }
@@ -32,12 +32,14 @@ const (
 	indexFile = "index.md"
 
 	// mdStream* indicate the metadata stream used for various types
-	mdStreamGeneral = 0 // General information for this proposal
-	mdStreamChanges = 2 // Changes to record
+	mdStreamGeneral   = 0 // General information for this proposal
+	mdStreamChanges   = 2 // Changes to record
+	mdStreamCoAuthors = 5 // Co-authors of the proposal
 	// Note that 14 is in use by the decred plugin
 	// Note that 15 is in use by the decred plugin
 
 	VersionMDStreamChanges         = 1
+	VersionMDStreamCoAuthors       = 1
 	BackendProposalMetadataVersion = 1
 )
 
@@ -49,12 +51,37 @@ type MDStreamChanges struct {
 	Timestamp           int64            `json:"timestamp"`                     // Timestamp of the change
 }
 
+// BackendProposalMetadata is the general metadata stream of a proposal.
+// LinkTo and LinkBy are not covered by the signature of the merkle root.  They
+// are server metadata that is validated by politeiawww and stored by
+// politeiad, so they are only as trustworthy as the server.
 type BackendProposalMetadata struct {
-	Version   uint64 `json:"version"`   // BackendProposalMetadata version
-	Timestamp int64  `json:"timestamp"` // Last update of proposal
-	Name      string `json:"name"`      // Generated proposal name
-	PublicKey string `json:"publickey"` // Key used for signature.
-	Signature string `json:"signature"` // Signature of merkle root
+	Version   uint64 `json:"version"`          // BackendProposalMetadata version
+	Timestamp int64  `json:"timestamp"`        // Last update of proposal
+	Name      string `json:"name"`             // Generated proposal name
+	PublicKey string `json:"publickey"`        // Key used for signature.
+	Signature string `json:"signature"`        // Signature of merkle root
+	LinkTo    string `json:"linkto,omitempty"` // Token of the linked RFP
+	LinkBy    int64  `json:"linkby,omitempty"` // RFP submission deadline
+}
+
+// BackendCoAuthor is a co-author of a proposal.  PublicKey, Signature and
+// Merkle are set once the co-author has signed the merkle root of the
+// proposal.  A signature only applies to the version of the proposal whose
+// merkle root was signed.
+type BackendCoAuthor struct {
+	UserID    string `json:"userid"`              // Co-author user ID
+	PublicKey string `json:"publickey,omitempty"` // Key used for signature
+	Signature string `json:"signature,omitempty"` // Signature of merkle root
+	Merkle    string `json:"merkle,omitempty"`    // Merkle root that was signed
+	Timestamp int64  `json:"timestamp,omitempty"` // Timestamp of the signature
+}
+
+// MDStreamCoAuthors contains the co-authors that were invited by the proposal
+// author.
+type MDStreamCoAuthors struct {
+	Version   uint              `json:"version"`   // Version of the struct
+	CoAuthors []BackendCoAuthor `json:"coauthors"` // Invited co-authors
 }
 
 // proposalStats is used to provide a summary of the number of proposals
@@ -82,6 +109,7 @@ type VoteDetails struct {
 	AuthorizeVoteReply www.AuthorizeVoteReply // Authorize vote reply
 	StartVote          www.StartVote          // Start vote
 	StartVoteReply     www.StartVoteReply     // Start vote reply
+	ScheduledHeight    uint32                 // Start height of a scheduled vote
 }
 
 // encodeBackendProposalMetadata encodes BackendProposalMetadata into a JSON
@@ -108,6 +136,30 @@ func decodeBackendProposalMetadata(payload []byte) (*BackendProposalMetadata, er
 	return &md, nil
 }
 
+// encodeMDStreamCoAuthors encodes an MDStreamCoAuthors into a JSON byte
+// slice.
+func encodeMDStreamCoAuthors(m MDStreamCoAuthors) ([]byte, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// decodeMDStreamCoAuthors decodes a JSON byte slice into an
+// MDStreamCoAuthors.
+func decodeMDStreamCoAuthors(payload []byte) (*MDStreamCoAuthors, error) {
+	var m MDStreamCoAuthors
+
+	err := json.Unmarshal(payload, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
 // encodeMDStreamChanges encodes an MDStreamChanges into a JSON byte slice.
 func encodeMDStreamChanges(m MDStreamChanges) ([]byte, error) {
 	b, err := json.Marshal(m)
@@ -152,31 +204,10 @@ func tokenIsValid(token string) bool {
 	return true
 }
 
-// validateVoteBit ensures that bit is a valid vote bit.
-func validateVoteBit(vote www.Vote, bit uint64) error {
-	if len(vote.Options) == 0 {
-		return fmt.Errorf("vote corrupt")
-	}
-	if bit == 0 {
-		return fmt.Errorf("invalid bit 0x%x", bit)
-	}
-	if vote.Mask&bit != bit {
-		return fmt.Errorf("invalid mask 0x%x bit 0x%x",
-			vote.Mask, bit)
-	}
-
-	for _, v := range vote.Options {
-		if v.Bits == bit {
-			return nil
-		}
-	}
-
-	return fmt.Errorf("bit not found 0x%x", bit)
-}
-
 // validateProposal ensures that a submitted proposal hashes, merkle and
-// signarures are valid.
-func validateProposal(np www.NewProposal, u *user.User) error {
+// signarures are valid.  The proposal links are not covered by the signature
+// and are validated separately, see validateProposalLinks.
+func (p *politeiawww) validateProposal(np www.NewProposal, u *user.User) error {
 	log.Tracef("validateProposal")
 
 	// Obtain signature
@@ -199,22 +230,55 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 		return err
 	}
 
-	// Check for at least 1 markdown file with a non-empty payload.
-	if len(np.Files) == 0 || np.Files[0].Payload == "" {
+	hashes, err := validateProposalFiles(np.Files, true)
+	if err != nil {
+		return err
+	}
+
+	// proposal title validation
+	name, err := getProposalName(np.Files)
+	if err != nil {
+		return err
+	}
+	if !util.IsValidProposalName(name) {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusProposalInvalidTitle,
+			ErrorContext: []string{util.CreateProposalNameRegex()},
+		}
+	}
+
+	// Note that we need validate the string representation of the merkle
+	mr := merkle.Root(hashes)
+	if !pk.VerifyMessage([]byte(hex.EncodeToString(mr[:])), sig) {
 		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSignature,
+		}
+	}
+
+	return nil
+}
+
+// validateProposalFiles ensures that the proposal files follow the file
+// policy and returns the file digests for the merkle root calculation.  The
+// index file is only required when requireIndex is set so that incomplete
+// proposal drafts can be validated as well.
+func validateProposalFiles(files []www.File, requireIndex bool) ([]*[sha256.Size]byte, error) {
+	// Check for at least 1 markdown file with a non-empty payload.
+	if len(files) == 0 || files[0].Payload == "" {
+		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusProposalMissingFiles,
 		}
 	}
 
 	// verify if there are duplicate names
-	filenames := make(map[string]int, len(np.Files))
+	filenames := make(map[string]int, len(files))
 	// Check that the file number policy is followed.
 	var (
 		numMDs, numImages, numIndexFiles      int
 		mdExceedsMaxSize, imageExceedsMaxSize bool
 		hashes                                []*[sha256.Size]byte
 	)
-	for _, v := range np.Files {
+	for _, v := range files {
 		filenames[v.Name]++
 		var (
 			data []byte
@@ -224,7 +288,7 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 			numImages++
 			data, err = base64.StdEncoding.DecodeString(v.Payload)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if len(data) > www.PolicyMaxImageSize {
 				imageExceedsMaxSize = true
@@ -238,7 +302,7 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 
 			data, err = base64.StdEncoding.DecodeString(v.Payload)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if len(data) > www.PolicyMaxMDSize {
 				mdExceedsMaxSize = true
@@ -253,7 +317,7 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 	}
 
 	// verify duplicate file names
-	if len(np.Files) > 1 {
+	if len(files) > 1 {
 		var repeated []string
 		for name, count := range filenames {
 			if count > 1 {
@@ -261,7 +325,7 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 			}
 		}
 		if len(repeated) > 0 {
-			return www.UserError{
+			return nil, www.UserError{
 				ErrorCode:    www.ErrorStatusProposalDuplicateFilenames,
 				ErrorContext: repeated,
 			}
@@ -269,58 +333,38 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 	}
 
 	// we expect one index file
-	if numIndexFiles == 0 {
-		return www.UserError{
+	if requireIndex && numIndexFiles == 0 {
+		return nil, www.UserError{
 			ErrorCode:    www.ErrorStatusProposalMissingFiles,
 			ErrorContext: []string{indexFile},
 		}
 	}
 
 	if numMDs > www.PolicyMaxMDs {
-		return www.UserError{
+		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusMaxMDsExceededPolicy,
 		}
 	}
 
 	if numImages > www.PolicyMaxImages {
-		return www.UserError{
+		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusMaxImagesExceededPolicy,
 		}
 	}
 
 	if mdExceedsMaxSize {
-		return www.UserError{
+		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusMaxMDSizeExceededPolicy,
 		}
 	}
 
 	if imageExceedsMaxSize {
-		return www.UserError{
+		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusMaxImageSizeExceededPolicy,
 		}
 	}
 
-	// proposal title validation
-	name, err := getProposalName(np.Files)
-	if err != nil {
-		return err
-	}
-	if !util.IsValidProposalName(name) {
-		return www.UserError{
-			ErrorCode:    www.ErrorStatusProposalInvalidTitle,
-			ErrorContext: []string{util.CreateProposalNameRegex()},
-		}
-	}
-
-	// Note that we need validate the string representation of the merkle
-	mr := merkle.Root(hashes)
-	if !pk.VerifyMessage([]byte(hex.EncodeToString(mr[:])), sig) {
-		return www.UserError{
-			ErrorCode: www.ErrorStatusInvalidSignature,
-		}
-	}
-
-	return nil
+	return hashes, nil
 }
 
 // voteIsAuthorized returns whether the author of the proposal has authorized
@@ -366,6 +410,8 @@ func voteStatusFromVoteSummary(r decredplugin.VoteSummaryReply, bestBlock uint64
 	switch {
 	case !r.Authorized:
 		return www.PropVoteStatusNotAuthorized
+	case r.EndHeight == "" && r.ScheduledHeight != 0:
+		return www.PropVoteStatusScheduled
 	case r.EndHeight == "":
 		return www.PropVoteStatusAuthorized
 	default:
@@ -433,6 +479,8 @@ func (p *politeiawww) getProp(token string) (*www.ProposalRecord, error) {
 		pr.UserId = u.ID.String()
 		pr.Username = u.Username
 	}
+	p.fillCoAuthors(&pr)
+	p.fillLinkedFrom(&pr)
 
 	return &pr, nil
 }
@@ -485,6 +533,10 @@ func (p *politeiawww) getProps(tokens []string) (*[]www.ProposalRecord, error) {
 				users[proposalRecords[i].PublicKey].Username
 		}
 	}
+	for i := range proposalRecords {
+		p.fillCoAuthors(&proposalRecords[i])
+		p.fillLinkedFrom(&proposalRecords[i])
+	}
 
 	return &proposalRecords, nil
 }
@@ -517,22 +569,14 @@ func (p *politeiawww) getPropVersion(token, version string) (*www.ProposalRecord
 		pr.UserId = u.ID.String()
 		pr.Username = u.Username
 	}
+	p.fillCoAuthors(&pr)
+	p.fillLinkedFrom(&pr)
 
 	return &pr, nil
 }
 
-// getAllProps gets the latest version of all proposals from the cache then
-// fills any missing fields before returning the proposals.
-func (p *politeiawww) getAllProps() ([]www.ProposalRecord, error) {
-	log.Tracef("getAllProps")
-
-	// Get proposals from cache
-	records, err := p.cache.Inventory()
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert props and fill in missing info
+// fillProps converts cache records to proposals and fills any missing fields.
+func (p *politeiawww) fillProps(records []cache.Record) []www.ProposalRecord {
 	props := make([]www.ProposalRecord, 0, len(records))
 	for _, v := range records {
 		pr := convertPropFromCache(v)
@@ -540,7 +584,7 @@ func (p *politeiawww) getAllProps() ([]www.ProposalRecord, error) {
 		// Fill in num comments
 		dc, err := p.decredGetComments(pr.CensorshipRecord.Token)
 		if err != nil {
-			log.Errorf("getAllProps: decredGetComments failed "+
+			log.Errorf("fillProps: decredGetComments failed "+
 				"for token %v", pr.CensorshipRecord.Token)
 		}
 		pr.NumComments = uint(len(dc))
@@ -548,17 +592,75 @@ func (p *politeiawww) getAllProps() ([]www.ProposalRecord, error) {
 		// Fill in author info
 		u, err := p.db.UserGetByPubKey(pr.PublicKey)
 		if err != nil {
-			log.Errorf("getAllProps: UserGetByPubKey: token:%v "+
+			log.Errorf("fillProps: UserGetByPubKey: token:%v "+
 				"pubKey:%v", pr.CensorshipRecord.Token, pr.PublicKey)
 		} else {
 			pr.UserId = u.ID.String()
 			pr.Username = u.Username
 		}
+		p.fillCoAuthors(&pr)
+		p.fillLinkedFrom(&pr)
 
 		props = append(props, pr)
 	}
 
-	return props, nil
+	return props
+}
+
+// getAllProps gets the latest version of all proposals from the cache then
+// fills any missing fields before returning the proposals.
+func (p *politeiawww) getAllProps() ([]www.ProposalRecord, error) {
+	log.Tracef("getAllProps")
+
+	// Get proposals from cache
+	records, err := p.cache.Inventory()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.fillProps(records), nil
+}
+
+// getPropsPage returns a single page of the latest version of the proposals
+// in the provided state, most recent first.  The page starts after the
+// proposal with the after token, or ends before the proposal with the before
+// token.  The cache applies the filter so only a single page of proposals is
+// loaded.  Files are not included.
+func (p *politeiawww) getPropsPage(state cache.InventoryStateT, after, before string) ([]www.ProposalRecord, error) {
+	log.Tracef("getPropsPage: %v %v %v", state, after, before)
+
+	f := cache.InventoryFilter{
+		State: state,
+		Limit: www.ProposalListPageSize,
+	}
+	token := after
+	if before != "" {
+		token = before
+		f.Reverse = true
+	}
+	if token != "" {
+		r, err := p.cache.Record(token)
+		if err == cache.ErrRecordNotFound {
+			return []www.ProposalRecord{}, nil
+		} else if err != nil {
+			return nil, err
+		}
+		f.Cursor = cache.InventoryCursor(*r)
+	}
+
+	records, _, err := p.cache.InventoryPage(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverse pages are returned oldest first
+	if f.Reverse {
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+	}
+
+	return p.fillProps(records), nil
 }
 
 // filterProps filters the given proposals according to the filtering
@@ -757,7 +859,11 @@ func (p *politeiawww) processNewProposal(np www.NewProposal, user *user.User) (*
 		}
 	}
 
-	err := validateProposal(np, user)
+	err := p.validateProposal(np, user)
+	if err != nil {
+		return nil, err
+	}
+	err = p.validateProposalLinks(np.LinkTo, np.LinkBy, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -773,6 +879,8 @@ func (p *politeiawww) processNewProposal(np www.NewProposal, user *user.User) (*
 		Name:      name,
 		PublicKey: np.PublicKey,
 		Signature: np.Signature,
+		LinkTo:    np.LinkTo,
+		LinkBy:    np.LinkBy,
 	})
 	if err != nil {
 		return nil, err
@@ -811,7 +919,7 @@ func (p *politeiawww) processNewProposal(np www.NewProposal, user *user.User) (*
 		return nil, fmt.Errorf("Unmarshal NewProposalReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.verifyChallenge(challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1137,8 +1245,7 @@ func (p *politeiawww) processSetProposalStatus(sps www.SetProposalStatus, u *use
 	}
 
 	// Verify the challenge
-	err = util.VerifyChallenge(p.cfg.Identity, challenge,
-		challengeResponse)
+	err = p.verifyChallenge(challenge, challengeResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -1149,6 +1256,14 @@ func (p *politeiawww) processSetProposalStatus(sps www.SetProposalStatus, u *use
 		return nil, err
 	}
 
+	// Only public proposals are listed as RFP submissions
+	switch updatedProp.Status {
+	case www.PropStatusPublic:
+		p.addLinkedFrom(updatedProp.LinkTo, updatedProp.CensorshipRecord.Token)
+	case www.PropStatusCensored, www.PropStatusAbandoned:
+		p.delLinkedFrom(updatedProp.LinkTo, updatedProp.CensorshipRecord.Token)
+	}
+
 	// Fire off proposal status change event
 	p.fireEvent(EventTypeProposalStatusChange,
 		EventDataProposalStatusChange{
@@ -1217,8 +1332,14 @@ func (p *politeiawww) processEditProposal(ep www.EditProposal, u *user.User) (*w
 		Files:     ep.Files,
 		PublicKey: ep.PublicKey,
 		Signature: ep.Signature,
+		LinkTo:    ep.LinkTo,
+		LinkBy:    ep.LinkBy,
+	}
+	err = p.validateProposal(np, u)
+	if err != nil {
+		return nil, err
 	}
-	err = validateProposal(np, u)
+	err = p.validateProposalLinks(ep.LinkTo, ep.LinkBy, cachedProp)
 	if err != nil {
 		return nil, err
 	}
@@ -1235,6 +1356,8 @@ func (p *politeiawww) processEditProposal(ep www.EditProposal, u *user.User) (*w
 		Name:      name,
 		PublicKey: ep.PublicKey,
 		Signature: ep.Signature,
+		LinkTo:    ep.LinkTo,
+		LinkBy:    ep.LinkBy,
 	}
 	md, err := encodeBackendProposalMetadata(backendMetadata)
 	if err != nil {
@@ -1246,6 +1369,33 @@ func (p *politeiawww) processEditProposal(ep www.EditProposal, u *user.User) (*w
 		Payload: string(md),
 	}}
 
+	// Edits invalidate the co-author signatures. The co-authors
+	// remain invited but must sign the new version. The co-authors
+	// stream is read under the co-authors lock so that a concurrent
+	// invite or signature is not dropped.
+	unlock := p.lockCoAuthors(ep.Token)
+	defer unlock()
+
+	mca, err := p.getCoAuthorsMD(ep.Token)
+	if err != nil {
+		return nil, err
+	}
+	if len(mca.CoAuthors) > 0 {
+		for k, v := range mca.CoAuthors {
+			mca.CoAuthors[k] = BackendCoAuthor{
+				UserID: v.UserID,
+			}
+		}
+		b, err := encodeMDStreamCoAuthors(*mca)
+		if err != nil {
+			return nil, err
+		}
+		mds = append(mds, pd.MetadataStream{
+			ID:      mdStreamCoAuthors,
+			Payload: string(b),
+		})
+	}
+
 	// Check if any files need to be deleted
 	var delFiles []string
 	for _, v := range cachedProp.Files {
@@ -1276,9 +1426,11 @@ func (p *politeiawww) processEditProposal(ep www.EditProposal, u *user.User) (*w
 	}
 
 	mdChanges := newMDFile.Payload != oldMDFile.Payload
+	linkChanges := ep.LinkTo != cachedProp.LinkTo ||
+		ep.LinkBy != cachedProp.LinkBy
 
 	// Check that the proposal has been changed
-	if !mdChanges && len(delFiles) == 0 &&
+	if !mdChanges && !linkChanges && len(delFiles) == 0 &&
 		len(cachedProp.Files) == len(ep.Files) {
 		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusNoProposalChanges,
@@ -1324,11 +1476,17 @@ func (p *politeiawww) processEditProposal(ep www.EditProposal, u *user.User) (*w
 		return nil, fmt.Errorf("Unmarshal UpdateUnvettedReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.verifyChallenge(challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
 
+	// Update the RFP submissions of a public proposal
+	if cachedProp.Status == www.PropStatusPublic {
+		p.delLinkedFrom(cachedProp.LinkTo, ep.Token)
+		p.addLinkedFrom(ep.LinkTo, ep.Token)
+	}
+
 	// Get proposal from the cache
 	updatedProp, err := p.getProp(ep.Token)
 	if err != nil {
@@ -1360,21 +1518,12 @@ func (p *politeiawww) processAllVetted(v www.GetAllVetted) (*www.GetAllVettedRep
 		}
 	}
 
-	// Fetch all proposals from the cache
-	all, err := p.getAllProps()
+	// Fetch a page of vetted proposals from the cache
+	props, err := p.getPropsPage(cache.InventoryStateVetted, v.After,
+		v.Before)
 	if err != nil {
-		return nil, fmt.Errorf("getAllProps: %v", err)
-	}
-
-	// Filter for vetted proposals
-	filter := proposalsFilter{
-		After:  v.After,
-		Before: v.Before,
-		StateMap: map[www.PropStateT]bool{
-			www.PropStateVetted: true,
-		},
+		return nil, fmt.Errorf("getPropsPage: %v", err)
 	}
-	props := filterProps(filter, all)
 
 	// Remove files from proposals
 	for i, p := range props {
@@ -1400,23 +1549,14 @@ func (p *politeiawww) processAllUnvetted(u www.GetAllUnvetted) (*www.GetAllUnvet
 		}
 	}
 
-	// Fetch all proposals from the cache
-	all, err := p.getAllProps()
+	// Fetch a page of unvetted proposals from the cache
+	props, err := p.getPropsPage(cache.InventoryStateUnvetted, u.After,
+		u.Before)
 	if err != nil {
-		return nil, fmt.Errorf("processAllUnvetted getAllProps: %v",
+		return nil, fmt.Errorf("processAllUnvetted getPropsPage: %v",
 			err)
 	}
 
-	// Filter for unvetted proposals
-	filter := proposalsFilter{
-		After:  u.After,
-		Before: u.Before,
-		StateMap: map[www.PropStateT]bool{
-			www.PropStateUnvetted: true,
-		},
-	}
-	props := filterProps(filter, all)
-
 	// Remove files from proposals
 	for i, p := range props {
 		p.Files = make([]www.File, 0)
@@ -1483,25 +1623,25 @@ func (p *politeiawww) processCommentsGet(token string, u *user.User) (*www.GetCo
 	}, nil
 }
 
+// voteResults returns the number of votes of each vote option.  The results
+// of a ranked choice vote contain the first preferences of the votes.
 func voteResults(sv www.StartVote, cv []www.CastVote) []www.VoteOptionResult {
 	log.Tracef("voteResults: %v", sv.Vote.Token)
 
 	// Tally votes
-	votes := make(map[string]uint64)
+	ballots := make(map[uint64]uint64)
 	for _, v := range cv {
-		votes[v.VoteBit]++
-	}
-
-	// Prepare vote option results
-	results := make([]www.VoteOptionResult, 0, len(sv.Vote.Options))
-	for _, v := range sv.Vote.Options {
-		results = append(results, www.VoteOptionResult{
-			Option:        v,
-			VotesReceived: votes[strconv.FormatUint(v.Bits, 10)],
-		})
+		bit, err := strconv.ParseUint(v.VoteBit, 16, 64)
+		if err != nil {
+			log.Errorf("voteResults: invalid vote bit %v %v",
+				v.Ticket, v.VoteBit)
+			continue
+		}
+		ballots[bit]++
 	}
+	t := decredplugin.TallyVote(convertVoteFromWWW(sv.Vote), 0, ballots)
 
-	return results
+	return convertVoteOptionResultsFromDecred(t.Results)
 }
 
 // setVoteStatusReply stores the given VoteStatusReply in memory.  This is to
@@ -1532,22 +1672,22 @@ func (p *politeiawww) voteStatusReply(token string, bestBlock uint64) (*www.Vote
 		return nil, err
 	}
 
-	results := convertVoteOptionResultsFromDecred(r.Results)
-	var total uint64
-	for _, v := range results {
-		total += v.VotesReceived
-	}
-
 	vsr = www.VoteStatusReply{
 		Token:              token,
+		Type:               www.VoteT(r.Type),
 		Status:             voteStatusFromVoteSummary(*r, bestBlock),
-		TotalVotes:         total,
-		OptionsResult:      results,
+		TotalVotes:         r.TotalVotes,
+		OptionsResult:      convertVoteOptionResultsFromDecred(r.Results),
 		EndHeight:          r.EndHeight,
 		BestBlock:          strconv.Itoa(int(bestBlock)),
 		NumOfEligibleVotes: r.EligibleTicketCount,
 		QuorumPercentage:   r.QuorumPercentage,
 		PassPercentage:     r.PassPercentage,
+		QuorumMet:          r.QuorumMet,
+		Winner:             r.Winner,
+		Approved:           r.Approved,
+		Rounds:             convertVoteRoundsFromDecred(r.Rounds),
+		ScheduledHeight:    r.ScheduledHeight,
 	}
 
 	// If the voting period has ended the vote status
@@ -1596,6 +1736,196 @@ func (p *politeiawww) processVoteStatus(token string) (*www.VoteStatusReply, err
 	return vsr, nil
 }
 
+// getVettedVersions returns the version history of a vetted record from
+// politeiad.
+func (p *politeiawww) getVettedVersions(token string) ([]pd.RecordVersion, error) {
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	gvv := pd.GetVettedVersions{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+	}
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.GetVettedVersionsRoute, gvv)
+	if err != nil {
+		return nil, err
+	}
+
+	var gvvr pd.GetVettedVersionsReply
+	err = json.Unmarshal(responseBody, &gvvr)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"GetVettedVersionsReply: %v", err)
+	}
+
+	err = p.verifyChallenge(challenge, gvvr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return gvvr.Versions, nil
+}
+
+// getVettedDiff returns the file and metadata stream changes between two
+// versions of a vetted record from politeiad.
+func (p *politeiawww) getVettedDiff(token, fromVersion, toVersion string) (*pd.GetVettedDiffReply, error) {
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	gvd := pd.GetVettedDiff{
+		Challenge:   hex.EncodeToString(challenge),
+		Token:       token,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+	}
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.GetVettedDiffRoute, gvd)
+	if err != nil {
+		return nil, err
+	}
+
+	var gvdr pd.GetVettedDiffReply
+	err = json.Unmarshal(responseBody, &gvdr)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"GetVettedDiffReply: %v", err)
+	}
+
+	err = p.verifyChallenge(challenge, gvdr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gvdr, nil
+}
+
+// processProposalHistory returns all versions of a vetted proposal along with
+// the file changes that were made in each version.
+func (p *politeiawww) processProposalHistory(token string) (*www.ProposalHistoryReply, error) {
+	log.Tracef("processProposalHistory: %v", token)
+
+	// Ensure proposal is vetted
+	pr, err := p.getProp(token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+	if pr.State != www.PropStateVetted {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	versions, err := p.getVettedVersions(token)
+	if err != nil {
+		return nil, err
+	}
+
+	pv := make([]www.ProposalVersion, 0, len(versions))
+	for k, v := range versions {
+		files := []www.ProposalFileDiff{}
+		if k > 0 {
+			d, err := p.getVettedDiff(token, versions[k-1].Version,
+				v.Version)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range d.Files {
+				files = append(files, convertPropFileDiffFromPD(f))
+			}
+		}
+		pv = append(pv, www.ProposalVersion{
+			Version:   v.Version,
+			Status:    convertPropStatusFromPD(v.Status),
+			Timestamp: v.Timestamp,
+			Merkle:    v.Merkle,
+			Files:     files,
+		})
+	}
+
+	return &www.ProposalHistoryReply{
+		Token:    token,
+		Versions: pv,
+	}, nil
+}
+
+// processProposalAnchor returns the dcrtime anchor of a public proposal
+// version as returned by politeiad.
+func (p *politeiawww) processProposalAnchor(pa www.ProposalAnchor) (*www.ProposalAnchorReply, error) {
+	log.Tracef("processProposalAnchor: %v %v", pa.Token, pa.Version)
+
+	// Ensure proposal is vetted
+	pr, err := p.getProp(pa.Token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+	if pr.State != www.PropStateVetted {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	gva := pd.GetVettedAnchor{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     pa.Token,
+		Version:   pa.Version,
+	}
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.GetVettedAnchorRoute, gva)
+	if err != nil {
+		return nil, err
+	}
+
+	var gvar pd.GetVettedAnchorReply
+	err = json.Unmarshal(responseBody, &gvar)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"GetVettedAnchorReply: %v", err)
+	}
+
+	err = p.verifyChallenge(challenge, gvar.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	a := gvar.Anchor
+	par := www.ProposalAnchorReply{
+		Token:   a.Token,
+		Version: a.Version,
+		Commit:  a.Commit,
+		Trees:   a.Trees,
+		Path:    a.Path,
+		Digests: a.Digests,
+		Merkle:  a.Merkle,
+	}
+	if a.ChainInformation != nil {
+		par.ChainInformation, err = json.Marshal(a.ChainInformation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &par, nil
+}
+
 // processGetAllVoteStatus returns the vote status of all public proposals.
 func (p *politeiawww) processGetAllVoteStatus() (*www.GetAllVoteStatusReply, error) {
 	log.Tracef("processGetAllVoteStatus")
@@ -1607,27 +1937,32 @@ func (p *politeiawww) processGetAllVoteStatus() (*www.GetAllVoteStatusReply, err
 		return nil, fmt.Errorf("bestBlock: %v", err)
 	}
 
-	// Get all proposals from cache
-	all, err := p.getAllProps()
-	if err != nil {
-		return nil, fmt.Errorf("getAllProps: %v", err)
+	// Compile votes statuses.  We only need public proposals so
+	// the cache is paged through for them.
+	vrr := make([]www.VoteStatusReply, 0, 1024)
+	f := cache.InventoryFilter{
+		Statuses: []cache.RecordStatusT{cache.RecordStatusPublic},
 	}
-
-	// Compile votes statuses
-	vrr := make([]www.VoteStatusReply, 0, len(all))
-	for _, v := range all {
-		// We only need public proposals
-		if v.Status != www.PropStatusPublic {
-			continue
-		}
-
-		// Get vote status for proposal
-		vs, err := p.voteStatusReply(v.CensorshipRecord.Token, bestBlock)
+	for {
+		records, cursor, err := p.cache.InventoryPage(f)
 		if err != nil {
-			return nil, fmt.Errorf("voteStatusReply: %v", err)
+			return nil, fmt.Errorf("InventoryPage: %v", err)
 		}
+		for _, v := range records {
+			// Get vote status for proposal
+			vs, err := p.voteStatusReply(v.CensorshipRecord.Token,
+				bestBlock)
+			if err != nil {
+				return nil, fmt.Errorf("voteStatusReply: %v",
+					err)
+			}
 
-		vrr = append(vrr, *vs)
+			vrr = append(vrr, *vs)
+		}
+		if cursor == "" {
+			break
+		}
+		f.Cursor = cursor
 	}
 
 	return &www.GetAllVoteStatusReply{
@@ -1722,6 +2057,79 @@ func (p *politeiawww) processVoteResults(token string) (*www.VoteResultsReply, e
 	}, nil
 }
 
+// processVoteBundle returns the signed vote bundle of a public proposal whose
+// vote has started.  The bundle is requested directly from politeiad since it
+// is not cached.
+func (p *politeiawww) processVoteBundle(token string) (*www.VoteBundleReply, error) {
+	log.Tracef("processVoteBundle: %v", token)
+
+	// Ensure proposal is vetted
+	pr, err := p.getProp(token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+	if pr.State != www.PropStateVetted {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongStatus,
+		}
+	}
+
+	// Ensure vote has started
+	vdr, err := p.decredVoteDetails(token)
+	if err != nil {
+		return nil, fmt.Errorf("decredVoteDetails: %v", err)
+	}
+	if vdr.StartVoteReply.StartBlockHeight == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongVoteStatus,
+		}
+	}
+
+	payload, err := decredplugin.EncodeVoteBundle(decredplugin.VoteBundle{
+		Token: token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	pc := pd.PluginCommand{
+		Challenge: hex.EncodeToString(challenge),
+		ID:        decredplugin.ID,
+		Command:   decredplugin.CmdVoteBundle,
+		CommandID: decredplugin.CmdVoteBundle,
+		Payload:   string(payload),
+	}
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.PluginCommandRoute, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply pd.PluginCommandReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"PluginCommandReply: %v", err)
+	}
+
+	err = p.verifyChallenge(challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.VoteBundleReply{
+		Bundle: json.RawMessage(reply.Payload),
+	}, nil
+}
+
 // processCastVotes handles the www.Ballot call
 func (p *politeiawww) processCastVotes(ballot *www.Ballot) (*www.BallotReply, error) {
 	log.Tracef("processCastVotes")
@@ -1757,7 +2165,7 @@ func (p *politeiawww) processCastVotes(ballot *www.Ballot) (*www.BallotReply, er
 	}
 
 	// Verify the challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1890,6 +2298,13 @@ func (p *politeiawww) processAuthorizeVote(av www.AuthorizeVote, u *user.User) (
 		return nil, www.UserError{
 			ErrorCode: www.ErrorStatusWrongVoteStatus,
 		}
+	case av.Action == decredplugin.AuthVoteActionRevoke &&
+		vd.ScheduledHeight != 0:
+		// Vote has been scheduled. The scheduled vote must
+		// be cancelled before the authorization is revoked.
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongVoteStatus,
+		}
 	case av.Action != decredplugin.AuthVoteActionAuthorize &&
 		av.Action != decredplugin.AuthVoteActionRevoke:
 		// Invalid authorize vote action
@@ -1912,18 +2327,29 @@ func (p *politeiawww) processAuthorizeVote(av www.AuthorizeVote, u *user.User) (
 		}
 	case pr.PublicKey != av.PublicKey:
 		// User is not the author. First make sure the author didn't
-		// submit the proposal using an old identity.
+		// submit the proposal using an old identity. Co-authors are
+		// allowed to authorize the vote as well.
 		usr, err := p.db.UserGetByPubKey(pr.PublicKey)
 		if err != nil {
 			return nil, err
 		}
-		if u.ID.String() != usr.ID.String() {
+		if u.ID.String() != usr.ID.String() &&
+			!isProposalCoAuthor(pr, u) {
 			return nil, www.UserError{
 				ErrorCode: www.ErrorStatusUserNotAuthor,
 			}
 		}
 	}
 
+	// All co-authors must have signed the current version of the
+	// proposal before the vote can be authorized.
+	if av.Action == decredplugin.AuthVoteActionAuthorize &&
+		!coAuthorsSigned(pr) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusCoAuthorSignaturesMissing,
+		}
+	}
+
 	// Setup plugin command
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
@@ -1958,7 +2384,7 @@ func (p *politeiawww) processAuthorizeVote(av www.AuthorizeVote, u *user.User) (
 	}
 
 	// Verify challenge
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, fmt.Errorf("VerifyChallenge: %v", err)
 	}
@@ -1984,13 +2410,15 @@ func (p *politeiawww) processAuthorizeVote(av www.AuthorizeVote, u *user.User) (
 	}, nil
 }
 
-// processStartVote handles the www.StartVote call.
-func (p *politeiawww) processStartVote(sv www.StartVote, u *user.User) (*www.StartVoteReply, error) {
-	log.Tracef("processStartVote %v", sv.Vote.Token)
-
+// validateStartVote ensures that the start vote is signed by the admin user,
+// that its vote parameters are valid and that the proposal vote has been
+// authorized and has not been started yet.  The proposal and its vote
+// details are returned.  A scheduled vote is not rejected here since it may
+// be rescheduled; callers that start the vote right away must check it.
+func (p *politeiawww) validateStartVote(sv www.StartVote, u *user.User) (*www.ProposalRecord, *VoteDetails, error) {
 	// Ensure the public key is the user's active key
 	if sv.PublicKey != u.PublicKey() {
-		return nil, www.UserError{
+		return nil, nil, www.UserError{
 			ErrorCode: www.ErrorStatusInvalidSigningKey,
 		}
 	}
@@ -1998,33 +2426,30 @@ func (p *politeiawww) processStartVote(sv www.StartVote, u *user.User) (*www.Sta
 	// Validate signature
 	err := validateSignature(sv.PublicKey, sv.Signature, sv.Vote.Token)
 	if err != nil {
-		return nil, err
-	}
-
-	// Validate vote bits
-	for _, v := range sv.Vote.Options {
-		err = validateVoteBit(sv.Vote, v.Bits)
-		if err != nil {
-			return nil, www.UserError{
-				ErrorCode: www.ErrorStatusInvalidPropVoteBits,
-			}
-		}
+		return nil, nil, err
 	}
 
 	// Validate vote parameters
 	if sv.Vote.Duration < p.cfg.VoteDurationMin ||
 		sv.Vote.Duration > p.cfg.VoteDurationMax ||
 		sv.Vote.QuorumPercentage > 100 || sv.Vote.PassPercentage > 100 {
-		return nil, www.UserError{
+		return nil, nil, www.UserError{
 			ErrorCode: www.ErrorStatusInvalidPropVoteParams,
 		}
 	}
 
-	// Create vote bits as plugin payload
-	dsv := convertStartVoteFromWWW(sv)
-	payload, err := decredplugin.EncodeStartVote(dsv)
+	// Validate vote type and options
+	if _, ok := www.VoteType[sv.Vote.Type]; !ok {
+		return nil, nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidVoteType,
+		}
+	}
+	err = decredplugin.ValidateVote(convertVoteFromWWW(sv.Vote))
 	if err != nil {
-		return nil, err
+		return nil, nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidPropVoteBits,
+			ErrorContext: []string{err.Error()},
+		}
 	}
 
 	// Get proposal from the cache
@@ -2035,34 +2460,81 @@ func (p *politeiawww) processStartVote(sv www.StartVote, u *user.User) (*www.Sta
 				ErrorCode: www.ErrorStatusProposalNotFound,
 			}
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Get vote details from cache
 	vdr, err := p.decredVoteDetails(sv.Vote.Token)
 	if err != nil {
-		return nil, fmt.Errorf("decredVoteDetails: %v", err)
+		return nil, nil, fmt.Errorf("decredVoteDetails: %v", err)
 	}
 	vd := convertVoteDetailsReplyFromDecred(*vdr)
 
 	// Ensure record is public, vote has been authorized,
 	// and vote has not already started.
 	if pr.Status != www.PropStatusPublic {
-		return nil, www.UserError{
+		return nil, nil, www.UserError{
 			ErrorCode: www.ErrorStatusWrongStatus,
 		}
 	}
 	if !voteIsAuthorized(vd.AuthorizeVoteReply) {
-		return nil, www.UserError{
+		return nil, nil, www.UserError{
 			ErrorCode: www.ErrorStatusVoteNotAuthorized,
 		}
 	}
 	if vd.StartVoteReply.StartBlockHeight != "" {
-		return nil, www.UserError{
+		return nil, nil, www.UserError{
 			ErrorCode: www.ErrorStatusWrongVoteStatus,
 		}
 	}
 
+	return pr, &vd, nil
+}
+
+// processStartVote handles the www.StartVote call.
+func (p *politeiawww) processStartVote(sv www.StartVote, u *user.User) (*www.StartVoteReply, error) {
+	log.Tracef("processStartVote %v", sv.Vote.Token)
+
+	_, vd, err := p.validateStartVote(sv, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// A scheduled vote is started by the vote scheduler once
+	// its start height has been reached.
+	if vd.ScheduledHeight != 0 {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusWrongVoteStatus,
+			ErrorContext: []string{"vote is scheduled"},
+		}
+	}
+
+	vr, err := p.startVote(convertStartVoteFromWWW(sv))
+	if err != nil {
+		return nil, err
+	}
+
+	p.fireEvent(EventTypeProposalVoteStarted,
+		EventDataProposalVoteStarted{
+			AdminUser: u,
+			StartVote: &sv,
+		},
+	)
+
+	// return a copy
+	rv := convertStartVoteReplyFromDecred(*vr)
+	return &rv, nil
+}
+
+// startVote sends the start vote to the decred plugin and returns the plugin
+// reply.
+func (p *politeiawww) startVote(dsv decredplugin.StartVote) (*decredplugin.StartVoteReply, error) {
+	// Create vote bits as plugin payload
+	payload, err := decredplugin.EncodeStartVote(dsv)
+	if err != nil {
+		return nil, err
+	}
+
 	// Tell decred plugin to start voting
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
@@ -2073,7 +2545,7 @@ func (p *politeiawww) processStartVote(sv www.StartVote, u *user.User) (*www.Sta
 		Challenge: hex.EncodeToString(challenge),
 		ID:        decredplugin.ID,
 		Command:   decredplugin.CmdStartVote,
-		CommandID: decredplugin.CmdStartVote + " " + sv.Vote.Token,
+		CommandID: decredplugin.CmdStartVote + " " + dsv.Vote.Token,
 		Payload:   string(payload),
 	}
 
@@ -2091,26 +2563,12 @@ func (p *politeiawww) processStartVote(sv www.StartVote, u *user.User) (*www.Sta
 	}
 
 	// Verify the challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
-	if err != nil {
-		return nil, err
-	}
-
-	vr, err := decredplugin.DecodeStartVoteReply([]byte(reply.Payload))
+	err = p.verifyChallenge(challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	p.fireEvent(EventTypeProposalVoteStarted,
-		EventDataProposalVoteStarted{
-			AdminUser: u,
-			StartVote: &sv,
-		},
-	)
-
-	// return a copy
-	rv := convertStartVoteReplyFromDecred(*vr)
-	return &rv, nil
+	return decredplugin.DecodeStartVoteReply([]byte(reply.Payload))
 }
 
 // processTokenInventory returns the tokens of all proposals in the inventory,
@@ -2155,3 +2613,136 @@ func (p *politeiawww) processTokenInventory(isAdmin bool) (*www.TokenInventoryRe
 
 	return &r, err
 }
+
+// processSearchProposals returns a page of the vetted proposals whose name,
+// index file or non-censored comments match the search query.
+func (p *politeiawww) processSearchProposals(sp www.SearchProposals) (*www.SearchProposalsReply, error) {
+	log.Tracef("processSearchProposals: %v", sp.Query)
+
+	// Validate query params
+	_, err := cache.ParseSearchQuery(sp.Query)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSearchQuery,
+		}
+	}
+	if sp.After != "" && !tokenIsValid(sp.After) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidCensorshipToken,
+		}
+	}
+	for _, v := range sp.Statuses {
+		if convertPropStatusToState(v) != www.PropStateVetted {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			}
+		}
+	}
+	for _, v := range sp.VoteStatuses {
+		if v < www.PropVoteStatusNotAuthorized ||
+			v > www.PropVoteStatusScheduled ||
+			v == www.PropVoteStatusDoesntExist {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusInvalidPropVoteStatus,
+			}
+		}
+	}
+
+	// The proposal statuses are filtered by the cache. The vote
+	// statuses are filtered here, so pages of search results are
+	// requested until a full page of proposals is found.
+	statuses := make([]int, 0, len(sp.Statuses))
+	for _, v := range sp.Statuses {
+		statuses = append(statuses, int(convertPropStatusFromWWW(v)))
+	}
+	var bb uint64
+	if len(sp.VoteStatuses) > 0 {
+		bb, err = p.getBestBlock()
+		if err != nil {
+			return nil, fmt.Errorf("getBestBlock: %v", err)
+		}
+	}
+
+	reply := www.SearchProposalsReply{
+		Results: make([]www.SearchResult, 0, www.ProposalListPageSize),
+	}
+	after := sp.After
+	for len(reply.Results) < www.ProposalListPageSize {
+		// The cache returns the results that are ordered after
+		// the after token, whether or not that proposal still
+		// matches.
+		sr, err := p.decredSearch(sp.Query, after, statuses,
+			www.ProposalListPageSize)
+		if err == cache.ErrRecordNotFound {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("decredSearch: %v", err)
+		}
+		if len(sr.Results) == 0 {
+			break
+		}
+
+		tokens := make([]string, 0, len(sr.Results))
+		for _, v := range sr.Results {
+			tokens = append(tokens, v.Token)
+		}
+		props, err := p.getProps(tokens)
+		if err != nil {
+			return nil, fmt.Errorf("getProps: %v", err)
+		}
+		pm := make(map[string]www.ProposalRecord, len(*props))
+		for _, v := range *props {
+			pm[v.CensorshipRecord.Token] = v
+		}
+
+		for _, v := range sr.Results {
+			if len(reply.Results) == www.ProposalListPageSize {
+				break
+			}
+
+			// The cache only searches vetted proposals. The
+			// state is checked again so that unvetted
+			// proposals can never be returned.
+			pr, ok := pm[v.Token]
+			if !ok || pr.State != www.PropStateVetted {
+				continue
+			}
+
+			if len(sp.VoteStatuses) > 0 {
+				vsr, err := p.voteStatusReply(v.Token, bb)
+				if err != nil {
+					return nil, fmt.Errorf("voteStatusReply %v: %v",
+						v.Token, err)
+				}
+				var found bool
+				for _, s := range sp.VoteStatuses {
+					if s == vsr.Status {
+						found = true
+						break
+					}
+				}
+				if !found {
+					continue
+				}
+			}
+
+			pr.Files = make([]www.File, 0)
+			reply.Results = append(reply.Results, www.SearchResult{
+				Proposal:   pr,
+				Name:       v.Name,
+				Index:      v.Index,
+				CommentIDs: v.CommentIDs,
+			})
+		}
+
+		// A short page is the last page of search results
+		if len(sr.Results) < www.ProposalListPageSize {
+			break
+		}
+		after = sr.Results[len(sr.Results)-1].Token
+	}
+
+	return &reply, nil
+}
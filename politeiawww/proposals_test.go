@@ -23,6 +23,7 @@ import (
 	pd "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/politeiad/cache"
 	"github.com/decred/politeia/politeiad/testpoliteiad"
 	www "github.com/decred/politeia/politeiawww/api/www/v1"
 	"github.com/decred/politeia/politeiawww/user"
@@ -350,6 +351,8 @@ func convertPropToPD(t *testing.T, p www.ProposalRecord) pd.Record {
 		Name:      name,
 		PublicKey: p.PublicKey,
 		Signature: p.Signature,
+		LinkTo:    p.LinkTo,
+		LinkBy:    p.LinkBy,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -506,7 +509,7 @@ func TestValidateProposal(t *testing.T) {
 	// Run test cases
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := validateProposal(test.newProposal, test.user)
+			err := p.validateProposal(test.newProposal, test.user)
 			got := errToStr(err)
 			want := errToStr(test.want)
 			if got != want {
@@ -1297,3 +1300,287 @@ func TestProcessAllUnvetted(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessSearchProposals(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	d := newTestPoliteiad(t, p)
+	defer d.Close()
+
+	// Create test data
+	u, id := newUser(t, p, true, false)
+
+	newProp := func(s www.PropStatusT) www.ProposalRecord {
+		pr := newProposalRecord(t, u, id, s)
+		pr.Files = []www.File{*createFileMD(t, 8, "Bug bounty program")}
+		d.AddRecord(t, convertPropToPD(t, pr))
+		return pr
+	}
+	propPublic := newProp(www.PropStatusPublic)
+	propAbandoned := newProp(www.PropStatusAbandoned)
+	propUnvetted := newProp(www.PropStatusNotReviewed)
+	newProp(www.PropStatusCensored)
+
+	tokenPublic := propPublic.CensorshipRecord.Token
+	tokenAbandoned := propAbandoned.CensorshipRecord.Token
+	tokenUnvetted := propUnvetted.CensorshipRecord.Token
+	tokenNotHex := "3575a65bbc3616c939acf6edf801e1168485dc864efef910034268f695351zzz"
+	tokenNotFound := "3575a65bbc3616c939acf6edf801e1168485dc864efef910034268f695351b3a"
+
+	// The results are ordered by timestamp in descending order and
+	// then by token.
+	first, second := tokenPublic, tokenAbandoned
+	if propAbandoned.Timestamp > propPublic.Timestamp ||
+		(propAbandoned.Timestamp == propPublic.Timestamp &&
+			tokenAbandoned < tokenPublic) {
+		first, second = second, first
+	}
+
+	// The page after the public proposal still starts at its position
+	// when the proposal itself is filtered out.
+	afterPublic := []string{}
+	if first == tokenPublic {
+		afterPublic = []string{tokenAbandoned}
+	}
+
+	// Setup tests
+	var tests = []struct {
+		name   string
+		sp     www.SearchProposals
+		tokens []string
+		want   error
+	}{
+		{"empty query",
+			www.SearchProposals{},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSearchQuery,
+			},
+		},
+		{"unbalanced quotes",
+			www.SearchProposals{
+				Query: `"bug bounty`,
+			},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSearchQuery,
+			},
+		},
+		{"invalid after token",
+			www.SearchProposals{
+				Query: "bounty",
+				After: tokenNotHex,
+			},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidCensorshipToken,
+			},
+		},
+		{"after proposal not found",
+			www.SearchProposals{
+				Query: "bounty",
+				After: tokenNotFound,
+			},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			},
+		},
+		{"after unvetted proposal",
+			www.SearchProposals{
+				Query: "bounty",
+				After: tokenUnvetted,
+			},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			},
+		},
+		{"unvetted status",
+			www.SearchProposals{
+				Query:    "bounty",
+				Statuses: []www.PropStatusT{www.PropStatusCensored},
+			},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			},
+		},
+		{"invalid vote status",
+			www.SearchProposals{
+				Query: "bounty",
+				VoteStatuses: []www.PropVoteStatusT{
+					www.PropVoteStatusDoesntExist,
+				},
+			},
+			nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidPropVoteStatus,
+			},
+		},
+		{"vetted proposals only",
+			www.SearchProposals{
+				Query: "BOUNTY bug",
+			},
+			[]string{tokenPublic, tokenAbandoned},
+			nil,
+		},
+		{"phrase",
+			www.SearchProposals{
+				Query: `"bug bounty" program`,
+			},
+			[]string{tokenPublic, tokenAbandoned},
+			nil,
+		},
+		{"phrase out of order",
+			www.SearchProposals{
+				Query: `"bounty bug"`,
+			},
+			[]string{},
+			nil,
+		},
+		{"status filter",
+			www.SearchProposals{
+				Query:    "bounty",
+				Statuses: []www.PropStatusT{www.PropStatusAbandoned},
+			},
+			[]string{tokenAbandoned},
+			nil,
+		},
+		{"page after first result",
+			www.SearchProposals{
+				Query: "bounty",
+				After: first,
+			},
+			[]string{second},
+			nil,
+		},
+		{"page after last result",
+			www.SearchProposals{
+				Query: "bounty",
+				After: second,
+			},
+			[]string{},
+			nil,
+		},
+		{"page after filtered result",
+			www.SearchProposals{
+				Query:    "bounty",
+				Statuses: []www.PropStatusT{www.PropStatusAbandoned},
+				After:    tokenPublic,
+			},
+			afterPublic,
+			nil,
+		},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			reply, err := p.processSearchProposals(v.sp)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Fatalf("got error %v, want %v",
+					got, want)
+			}
+			if err != nil {
+				return
+			}
+
+			tokens := make(map[string]bool, len(reply.Results))
+			for _, r := range reply.Results {
+				tokens[r.Proposal.CensorshipRecord.Token] = true
+				if !r.Name || !r.Index {
+					t.Errorf("%v: got name %v index %v, want "+
+						"both matched", r.Proposal.CensorshipRecord.Token,
+						r.Name, r.Index)
+				}
+			}
+			if len(tokens) != len(v.tokens) {
+				t.Fatalf("got %v results, want %v",
+					len(tokens), len(v.tokens))
+			}
+			for _, token := range v.tokens {
+				if !tokens[token] {
+					t.Errorf("token %v not found", token)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertPropFromCacheFileProofs(t *testing.T) {
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []www.File{
+		newFileRandomMD(t),
+		*createFileMD(t, 8, "a"),
+		*createFileMD(t, 8, "b"),
+	}
+	files[1].Name = "a.md"
+	files[2].Name = "b.md"
+	cacheFiles := make([]cache.File, 0, len(files))
+	for _, v := range files {
+		cacheFiles = append(cacheFiles, cache.File{
+			Name:    v.Name,
+			MIME:    v.MIME,
+			Digest:  v.Digest,
+			Payload: v.Payload,
+		})
+	}
+
+	tokenb, err := util.Random(pd.TokenSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := hex.EncodeToString(tokenb)
+	m := merkleRoot(t, files)
+	sig := id.SignMessage([]byte(m + token))
+	r := cache.Record{
+		CensorshipRecord: cache.CensorshipRecord{
+			Token:     token,
+			Merkle:    m,
+			Signature: hex.EncodeToString(sig[:]),
+		},
+		Files: cacheFiles,
+	}
+
+	// Every file has a proof that verifies against the censorship
+	// record
+	pr := convertPropFromCache(r)
+	fp := pr.CensorshipRecord.FileProofs
+	if len(fp) != len(files) {
+		t.Fatalf("got %v file proofs, want %v", len(fp), len(files))
+	}
+	csr := convertPropCensorFromWWW(pr.CensorshipRecord)
+	for i, v := range fp {
+		if v.Name != files[i].Name || v.Digest != files[i].Digest {
+			t.Fatalf("got proof %v %v, want %v %v", v.Name, v.Digest,
+				files[i].Name, files[i].Digest)
+		}
+		err := pd.VerifyFileProof(id.Public, csr, pd.FileProof{
+			Name:      v.Name,
+			Digest:    v.Digest,
+			Index:     v.Index,
+			NumLeaves: v.NumLeaves,
+			Hashes:    v.Hashes,
+		})
+		if err != nil {
+			t.Fatalf("verify proof %v: %v", v.Name, err)
+		}
+	}
+
+	// No proofs are returned when the files are not
+	r.Files = nil
+	pr = convertPropFromCache(r)
+	if pr.CensorshipRecord.FileProofs != nil {
+		t.Fatalf("got file proofs %v, want nil",
+			pr.CensorshipRecord.FileProofs)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/decred/politeia/politeiad/cache"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+)
+
+// isRFP returns whether the proposal is a request for proposals.
+func isRFP(pr www.ProposalRecord) bool {
+	return pr.LinkBy != 0
+}
+
+// validateProposalLinks ensures that the linkto and linkby fields of a
+// proposal are valid.  A proposal can either be an RFP, which sets the linkby
+// deadline, or a submission to an RFP, which sets the linkto token of a
+// public RFP whose deadline has not expired yet.
+//
+// prev is the proposal that is being edited and is nil for new proposals.  A
+// link that is not changed by an edit is not validated again so that RFPs and
+// their submissions remain editable once the deadline has passed.  The
+// deadline of an RFP that has public submissions can not be changed.
+func (p *politeiawww) validateProposalLinks(linkTo string, linkBy int64, prev *www.ProposalRecord) error {
+	if linkBy != 0 && linkTo != "" {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidLinkTo,
+			ErrorContext: []string{"an rfp can not link to a proposal"},
+		}
+	}
+
+	switch {
+	case prev != nil && linkBy == prev.LinkBy:
+		// Unchanged deadline
+	case prev != nil && len(prev.LinkedFrom) > 0:
+		return www.UserError{
+			ErrorCode: www.ErrorStatusInvalidLinkBy,
+			ErrorContext: []string{"the linkby deadline of an rfp " +
+				"with submissions can not be changed"},
+		}
+	case linkBy != 0:
+		now := time.Now().Unix()
+		min := now + www.PolicyLinkByMinPeriod
+		max := now + www.PolicyLinkByMaxPeriod
+		if linkBy < min || linkBy > max {
+			return www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkBy,
+				ErrorContext: []string{fmt.Sprintf("linkby must be "+
+					"between %v and %v", min, max)},
+			}
+		}
+	}
+
+	if linkTo == "" || (prev != nil && linkTo == prev.LinkTo) {
+		return nil
+	}
+	if prev != nil && linkTo == prev.CensorshipRecord.Token {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidLinkTo,
+			ErrorContext: []string{"a proposal can not link to itself"},
+		}
+	}
+
+	if !tokenIsValid(linkTo) {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidLinkTo,
+			ErrorContext: []string{"invalid token"},
+		}
+	}
+
+	r, err := p.cache.Record(linkTo)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode:    www.ErrorStatusInvalidLinkTo,
+				ErrorContext: []string{"rfp not found"},
+			}
+		}
+		return err
+	}
+	rfp := convertPropFromCache(*r)
+
+	if rfp.Status != www.PropStatusPublic || !isRFP(rfp) {
+		return www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidLinkTo,
+			ErrorContext: []string{"proposal is not a public rfp"},
+		}
+	}
+	if time.Now().Unix() > rfp.LinkBy {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusLinkByDeadlineExpired,
+		}
+	}
+
+	return nil
+}
+
+// initLinkedFrom initializes the linkedFrom cache by iterating through all
+// of the proposals in the cache and adding the public proposals that link to
+// an RFP.
+//
+// This function must be called WITHOUT the lock held.
+func (p *politeiawww) initLinkedFrom() error {
+	records, err := p.cache.Inventory()
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	for _, v := range records {
+		pr := convertPropFromCache(v)
+		if pr.Status != www.PropStatusPublic || pr.LinkTo == "" {
+			continue
+		}
+		if _, ok := p.linkedFrom[pr.LinkTo]; !ok {
+			p.linkedFrom[pr.LinkTo] = make(map[string]bool)
+		}
+		p.linkedFrom[pr.LinkTo][pr.CensorshipRecord.Token] = true
+	}
+
+	return nil
+}
+
+// addLinkedFrom adds a public submission to the linkedFrom cache of an RFP.
+// Nothing is done when the proposal does not link to an RFP.
+//
+// This function must be called WITHOUT the lock held.
+func (p *politeiawww) addLinkedFrom(linkTo, token string) {
+	if linkTo == "" {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.linkedFrom[linkTo]; !ok {
+		p.linkedFrom[linkTo] = make(map[string]bool)
+	}
+	p.linkedFrom[linkTo][token] = true
+}
+
+// delLinkedFrom removes a submission from the linkedFrom cache of an RFP.
+//
+// This function must be called WITHOUT the lock held.
+func (p *politeiawww) delLinkedFrom(linkTo, token string) {
+	if linkTo == "" {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	delete(p.linkedFrom[linkTo], token)
+	if len(p.linkedFrom[linkTo]) == 0 {
+		delete(p.linkedFrom, linkTo)
+	}
+}
+
+// fillLinkedFrom fills in the tokens of the public submissions of an RFP.
+//
+// This function must be called WITHOUT the lock held.
+func (p *politeiawww) fillLinkedFrom(pr *www.ProposalRecord) {
+	if !isRFP(*pr) {
+		return
+	}
+
+	p.RLock()
+	defer p.RUnlock()
+
+	submissions := p.linkedFrom[pr.CensorshipRecord.Token]
+	if len(submissions) == 0 {
+		return
+	}
+	pr.LinkedFrom = make([]string, 0, len(submissions))
+	for k := range submissions {
+		pr.LinkedFrom = append(pr.LinkedFrom, k)
+	}
+	sort.Strings(pr.LinkedFrom)
+}
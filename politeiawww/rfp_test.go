@@ -0,0 +1,245 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/testpoliteiad"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+func TestValidateProposalLinks(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	usr, id := newUser(t, p, true, false)
+	now := time.Now().Unix()
+	linkBy := now + www.PolicyLinkByMinPeriod + 3600
+
+	// Create proposals to link to
+	rfp := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	rfp.LinkBy = linkBy
+	td.AddRecord(t, convertPropToPD(t, rfp))
+
+	rfpUnvetted := newProposalRecord(t, usr, id, www.PropStatusNotReviewed)
+	rfpUnvetted.LinkBy = linkBy
+	td.AddRecord(t, convertPropToPD(t, rfpUnvetted))
+
+	rfpExpired := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	rfpExpired.LinkBy = now - 1
+	td.AddRecord(t, convertPropToPD(t, rfpExpired))
+
+	prop := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	td.AddRecord(t, convertPropToPD(t, prop))
+
+	tokenb, err := util.Random(pd.TokenSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenNotFound := hex.EncodeToString(tokenb)
+
+	// Proposals that are being edited
+	submission := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	submission.LinkTo = rfpExpired.CensorshipRecord.Token
+
+	rfpSubmissions := rfp
+	rfpSubmissions.LinkedFrom = []string{
+		submission.CensorshipRecord.Token,
+	}
+
+	// Setup tests
+	var tests = []struct {
+		name   string
+		linkTo string
+		linkBy int64
+		prev   *www.ProposalRecord
+		want   error
+	}{
+		{"no links", "", 0, nil, nil},
+
+		{"rfp with linkto", rfp.CensorshipRecord.Token, linkBy, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkTo,
+			}},
+
+		{"linkby too soon", "", now + 60, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkBy,
+			}},
+
+		{"linkby too late", "", now + www.PolicyLinkByMaxPeriod + 3600, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkBy,
+			}},
+
+		{"valid rfp", "", linkBy, nil, nil},
+
+		{"invalid token", "abc", 0, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkTo,
+			}},
+
+		{"rfp not found", tokenNotFound, 0, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkTo,
+			}},
+
+		{"proposal is not an rfp", prop.CensorshipRecord.Token, 0, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkTo,
+			}},
+
+		{"rfp not public", rfpUnvetted.CensorshipRecord.Token, 0, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkTo,
+			}},
+
+		{"rfp deadline expired", rfpExpired.CensorshipRecord.Token, 0, nil,
+			www.UserError{
+				ErrorCode: www.ErrorStatusLinkByDeadlineExpired,
+			}},
+
+		{"valid submission", rfp.CensorshipRecord.Token, 0, nil, nil},
+
+		{"edit keeps expired linkby", "", rfpExpired.LinkBy, &rfpExpired,
+			nil},
+
+		{"edit keeps expired linkto", submission.LinkTo, 0, &submission,
+			nil},
+
+		{"edit moves linkby", "", linkBy + 3600, &rfp, nil},
+
+		{"edit moves linkby with submissions", "", linkBy + 3600,
+			&rfpSubmissions,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkBy,
+			}},
+
+		{"edit removes linkby with submissions", "", 0, &rfpSubmissions,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkBy,
+			}},
+
+		{"edit links to itself", prop.CensorshipRecord.Token, 0, &prop,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidLinkTo,
+			}},
+
+		{"edit links to expired rfp", rfpExpired.CensorshipRecord.Token, 0,
+			&prop,
+			www.UserError{
+				ErrorCode: www.ErrorStatusLinkByDeadlineExpired,
+			}},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			err := p.validateProposalLinks(v.linkTo, v.linkBy, v.prev)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+}
+
+func TestProcessRFPSubmission(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	p.cfg.RPCHost = td.URL
+	p.cfg.Identity = td.PublicIdentity
+
+	admin, adminID := newUser(t, p, true, true)
+	usr, id := newUser(t, p, true, false)
+	payRegistrationFee(t, p, usr)
+	addProposalCredits(t, p, usr, 10)
+
+	// Create a public RFP
+	rfp := newProposalRecord(t, admin, adminID, www.PropStatusPublic)
+	rfp.LinkBy = time.Now().Unix() + www.PolicyLinkByMinPeriod + 3600
+	rfpToken := rfp.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, rfp))
+
+	// Submit a proposal to the RFP
+	np := createNewProposal(t, id, []www.File{newFileRandomMD(t)})
+	np.LinkTo = rfpToken
+	npr, err := p.processNewProposal(*np, usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := npr.CensorshipRecord.Token
+
+	pr, err := p.getProp(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.LinkTo != rfpToken {
+		t.Fatalf("got linkto %v, want %v", pr.LinkTo, rfpToken)
+	}
+
+	// Unvetted submissions are not linked from the RFP
+	pr, err = p.getProp(rfpToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr.LinkedFrom) != 0 {
+		t.Fatalf("got linkedfrom %v, want none", pr.LinkedFrom)
+	}
+
+	// Make the submission public
+	status := strconv.Itoa(int(www.PropStatusPublic))
+	s := adminID.SignMessage([]byte(fmt.Sprintf("%s%s", token, status)))
+	_, err = p.processSetProposalStatus(www.SetProposalStatus{
+		Token:          token,
+		ProposalStatus: www.PropStatusPublic,
+		Signature:      hex.EncodeToString(s[:]),
+		PublicKey:      admin.PublicKey(),
+	}, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The submission is listed in both the proposal details and the
+	// batch proposals replies.
+	want := []string{token}
+	pr, err = p.getProp(rfpToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pr.LinkedFrom, want) {
+		t.Fatalf("got linkedfrom %v, want %v", pr.LinkedFrom, want)
+	}
+
+	bpr, err := p.processBatchProposals(www.BatchProposals{
+		Tokens: []string{rfpToken},
+	}, usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bpr.Proposals[0].LinkedFrom, want) {
+		t.Fatalf("got batch linkedfrom %v, want %v",
+			bpr.Proposals[0].LinkedFrom, want)
+	}
+}
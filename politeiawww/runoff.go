@@ -0,0 +1,301 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/cache"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/user"
+	"github.com/decred/politeia/util"
+)
+
+// runoffResults computes the outcome of a runoff vote from the vote statuses
+// of its submissions.  A submission is approved when it meets both the quorum
+// and the pass percentage of its vote.  The submission votes are tallied with
+// decredplugin.TallyVote so that a submission is approved exactly like a
+// standalone vote.  The winner is the approved submission
+// with the largest number of approving votes minus rejecting votes.  A winner
+// is only returned once all submission votes have finished and is empty when
+// no submission was approved or when the top submissions are tied.
+func runoffResults(vsrs []www.VoteStatusReply) ([]www.RunoffSubmission, string) {
+	var (
+		submissions = make([]www.RunoffSubmission, 0, len(vsrs))
+		finished    = len(vsrs) > 0
+		winner      string
+		best        int64
+		top         int // Number of approved submissions with the best result
+	)
+	for _, v := range vsrs {
+		var approveVotes, rejectVotes uint64
+		opts := make([]decredplugin.VoteOption, 0, len(v.OptionsResult))
+		ballots := make(map[uint64]uint64, len(v.OptionsResult))
+		for _, r := range v.OptionsResult {
+			switch r.Option.Id {
+			case www.VoteOptionIDApprove:
+				approveVotes = r.VotesReceived
+			case www.VoteOptionIDReject:
+				rejectVotes = r.VotesReceived
+			}
+			opts = append(opts, convertVoteOptionFromWWW(r.Option))
+			ballots[r.Option.Bits] = r.VotesReceived
+		}
+
+		// Runoff votes are approval votes
+		t := decredplugin.TallyVote(decredplugin.Vote{
+			Type:             decredplugin.VoteTypeApproval,
+			QuorumPercentage: v.QuorumPercentage,
+			PassPercentage:   v.PassPercentage,
+			Options:          opts,
+		}, v.NumOfEligibleVotes, ballots)
+
+		submissions = append(submissions, www.RunoffSubmission{
+			Token:         v.Token,
+			TotalVotes:    t.TotalVotes,
+			OptionsResult: v.OptionsResult,
+			QuorumMet:     t.QuorumMet,
+			Approved:      t.Approved,
+		})
+
+		if v.Status != www.PropVoteStatusFinished {
+			finished = false
+		}
+		if !t.Approved {
+			continue
+		}
+
+		net := int64(approveVotes) - int64(rejectVotes)
+		switch {
+		case top == 0 || net > best:
+			winner = v.Token
+			best = net
+			top = 1
+		case net == best:
+			top++
+		}
+	}
+
+	if !finished || top != 1 {
+		winner = ""
+	}
+
+	return submissions, winner
+}
+
+// processStartVoteRunoff starts a runoff vote between the public submissions
+// of an RFP.  All submission votes are started by a single plugin command so
+// that they share the same ticket snapshot and start and end heights.
+func (p *politeiawww) processStartVoteRunoff(svr www.StartVoteRunoff, u *user.User) (*www.StartVoteRunoffReply, error) {
+	log.Tracef("processStartVoteRunoff %v", svr.Token)
+
+	// Ensure the RFP is public and its deadline has expired
+	rfp, err := p.getProp(svr.Token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+	if rfp.Status != www.PropStatusPublic || !isRFP(*rfp) {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidRunoffVote,
+			ErrorContext: []string{"proposal is not a public rfp"},
+		}
+	}
+	if time.Now().Unix() <= rfp.LinkBy {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusLinkByDeadlineNotExpired,
+		}
+	}
+	if len(svr.Votes) < 2 {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			ErrorContext: []string{"a runoff requires at least 2 " +
+				"submissions"},
+		}
+	}
+
+	// Ensure a runoff vote has not been started yet
+	vrdr, err := p.decredVoteRunoffDetails(svr.Token)
+	if err != nil {
+		return nil, fmt.Errorf("decredVoteRunoffDetails: %v", err)
+	}
+	if vrdr.VoteRunoff.Token != "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongVoteStatus,
+		}
+	}
+
+	// Validate the submission votes
+	submissions := make(map[string]bool, len(rfp.LinkedFrom))
+	for _, v := range rfp.LinkedFrom {
+		submissions[v] = true
+	}
+	duration := svr.Votes[0].Vote.Duration
+	for _, v := range svr.Votes {
+		token := v.Vote.Token
+		if !submissions[token] {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+				ErrorContext: []string{fmt.Sprintf("%v is not a "+
+					"public submission of the rfp", token)},
+			}
+		}
+		// Remove the token so duplicates are rejected
+		delete(submissions, token)
+
+		if v.Vote.Type != www.VoteTypeInvalid &&
+			v.Vote.Type != www.VoteTypeApproval {
+			return nil, www.UserError{
+				ErrorCode:    www.ErrorStatusInvalidRunoffVote,
+				ErrorContext: []string{"runoff votes must be approval votes"},
+			}
+		}
+		if v.Vote.Duration != duration {
+			return nil, www.UserError{
+				ErrorCode:    www.ErrorStatusInvalidRunoffVote,
+				ErrorContext: []string{"vote durations differ"},
+			}
+		}
+
+		_, vd, err := p.validateStartVote(v, u)
+		if err != nil {
+			return nil, err
+		}
+		if vd.ScheduledHeight != 0 {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusWrongVoteStatus,
+				ErrorContext: []string{fmt.Sprintf("%v vote is "+
+					"scheduled", token)},
+			}
+		}
+	}
+
+	// Tell decred plugin to start the runoff vote
+	payload, err := decredplugin.EncodeStartVoteRunoff(
+		convertStartVoteRunoffFromWWW(svr))
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := pd.PluginCommand{
+		Challenge: hex.EncodeToString(challenge),
+		ID:        decredplugin.ID,
+		Command:   decredplugin.CmdStartVoteRunoff,
+		CommandID: decredplugin.CmdStartVoteRunoff + " " + svr.Token,
+		Payload:   string(payload),
+	}
+
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.PluginCommandRoute, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply pd.PluginCommandReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal "+
+			"PluginCommandReply: %v", err)
+	}
+
+	// Verify the challenge.
+	err = p.verifyChallenge(challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	svrr, err := decredplugin.DecodeStartVoteRunoffReply([]byte(reply.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range svr.Votes {
+		p.fireEvent(EventTypeProposalVoteStarted,
+			EventDataProposalVoteStarted{
+				AdminUser: u,
+				StartVote: &svr.Votes[i],
+			},
+		)
+	}
+
+	rv := convertStartVoteRunoffReplyFromDecred(*svrr)
+	return &rv, nil
+}
+
+// processVoteRunoff returns the runoff vote of an RFP along with the vote
+// results of its submissions and the runoff winner.
+func (p *politeiawww) processVoteRunoff(token string) (*www.VoteRunoffReply, error) {
+	log.Tracef("processVoteRunoff: %v", token)
+
+	_, err := p.getProp(token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	vrdr, err := p.decredVoteRunoffDetails(token)
+	if err != nil {
+		return nil, fmt.Errorf("decredVoteRunoffDetails: %v", err)
+	}
+	vr := vrdr.VoteRunoff
+	if vr.Token == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusRunoffVoteNotFound,
+		}
+	}
+
+	// Get best block
+	bestBlock, err := p.getBestBlock()
+	if err != nil {
+		return nil, fmt.Errorf("bestBlock: %v", err)
+	}
+
+	// Get the vote status of the submissions
+	vsrs := make([]www.VoteStatusReply, 0, len(vr.Tokens))
+	for _, v := range vr.Tokens {
+		vsr, err := p.voteStatusReply(v, bestBlock)
+		if err != nil {
+			return nil, fmt.Errorf("voteStatusReply %v: %v", v, err)
+		}
+		vsrs = append(vsrs, *vsr)
+	}
+
+	submissions, winner := runoffResults(vsrs)
+	reply := www.VoteRunoffReply{
+		Token:            token,
+		StartBlockHeight: vr.StartBlockHeight,
+		EndHeight:        vr.EndHeight,
+		BestBlock:        strconv.FormatUint(bestBlock, 10),
+		Submissions:      submissions,
+		Winner:           winner,
+	}
+	// All submissions share the same snapshot and voting period
+	if len(vsrs) > 0 {
+		reply.Status = vsrs[0].Status
+		reply.NumOfEligibleVotes = vsrs[0].NumOfEligibleVotes
+	}
+
+	return &reply, nil
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/testpoliteiad"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+func newVoteStatusReply(token string, status www.PropVoteStatusT, yes, no uint64) www.VoteStatusReply {
+	return www.VoteStatusReply{
+		Token:              token,
+		Status:             status,
+		TotalVotes:         yes + no,
+		NumOfEligibleVotes: 100,
+		QuorumPercentage:   20,
+		PassPercentage:     60,
+		OptionsResult: []www.VoteOptionResult{
+			{
+				Option: www.VoteOption{
+					Id:   www.VoteOptionIDReject,
+					Bits: 0x01,
+				},
+				VotesReceived: no,
+			},
+			{
+				Option: www.VoteOption{
+					Id:   www.VoteOptionIDApprove,
+					Bits: 0x02,
+				},
+				VotesReceived: yes,
+			},
+		},
+	}
+}
+
+func TestRunoffResults(t *testing.T) {
+	finished := www.PropVoteStatusFinished
+	started := www.PropVoteStatusStarted
+
+	// Setup tests
+	var tests = []struct {
+		name         string
+		vsrs         []www.VoteStatusReply
+		wantApproved []bool
+		wantWinner   string
+	}{
+		{"no submissions", []www.VoteStatusReply{}, []bool{}, ""},
+
+		{"winner by net votes",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", finished, 30, 10),
+				newVoteStatusReply("b", finished, 40, 15),
+			},
+			[]bool{true, true}, "b"},
+
+		{"tied submissions",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", finished, 30, 10),
+				newVoteStatusReply("b", finished, 35, 15),
+			},
+			[]bool{true, true}, ""},
+
+		{"vote not finished",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", started, 30, 10),
+				newVoteStatusReply("b", started, 40, 15),
+			},
+			[]bool{true, true}, ""},
+
+		{"quorum not met",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", finished, 30, 10),
+				newVoteStatusReply("b", finished, 15, 0),
+			},
+			[]bool{true, false}, "a"},
+
+		{"pass not met",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", finished, 20, 20),
+				newVoteStatusReply("b", finished, 25, 5),
+			},
+			[]bool{false, true}, "b"},
+
+		// A truncated pass threshold of 12 votes would approve
+		// a with 57% of the votes.
+		{"pass not met exactly",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", finished, 12, 9),
+				newVoteStatusReply("b", finished, 25, 5),
+			},
+			[]bool{false, true}, "b"},
+
+		{"no submission approved",
+			[]www.VoteStatusReply{
+				newVoteStatusReply("a", finished, 20, 20),
+				newVoteStatusReply("b", finished, 10, 0),
+			},
+			[]bool{false, false}, ""},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			submissions, winner := runoffResults(v.vsrs)
+			approved := make([]bool, 0, len(submissions))
+			for _, s := range submissions {
+				approved = append(approved, s.Approved)
+			}
+			if !reflect.DeepEqual(approved, v.wantApproved) {
+				t.Errorf("got approved %v, want %v",
+					approved, v.wantApproved)
+			}
+			if winner != v.wantWinner {
+				t.Errorf("got winner %v, want %v",
+					winner, v.wantWinner)
+			}
+		})
+	}
+}
+
+func TestProcessStartVoteRunoff(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	admin, adminID := newUser(t, p, true, true)
+	usr, id := newUser(t, p, true, false)
+	now := time.Now().Unix()
+
+	// Create RFPs and their submissions
+	rfp := newProposalRecord(t, admin, adminID, www.PropStatusPublic)
+	rfp.LinkBy = now - 1
+	rfpToken := rfp.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, rfp))
+
+	rfpOpen := newProposalRecord(t, admin, adminID, www.PropStatusPublic)
+	rfpOpen.LinkBy = now + www.PolicyLinkByMinPeriod
+	td.AddRecord(t, convertPropToPD(t, rfpOpen))
+
+	rfpStarted := newProposalRecord(t, admin, adminID, www.PropStatusPublic)
+	rfpStarted.LinkBy = now - 1
+	td.AddRecord(t, convertPropToPD(t, rfpStarted))
+
+	prop := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	td.AddRecord(t, convertPropToPD(t, prop))
+
+	sub1 := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	sub1.LinkTo = rfpToken
+	sub1Token := sub1.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, sub1))
+
+	sub2 := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	sub2.LinkTo = rfpToken
+	sub2Token := sub2.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, sub2))
+
+	sub3 := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	sub3.LinkTo = rfpStarted.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, sub3))
+
+	sub4 := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	sub4.LinkTo = rfpStarted.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, sub4))
+
+	err := p.initLinkedFrom()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a runoff vote on the started RFP
+	svr := decredplugin.StartVoteRunoff{
+		Version: decredplugin.VersionStartVoteRunoff,
+		Token:   rfpStarted.CensorshipRecord.Token,
+		StartVotes: []decredplugin.StartVote{
+			convertStartVoteFromWWW(newStartVote(t,
+				sub3.CensorshipRecord.Token, adminID)),
+			convertStartVoteFromWWW(newStartVote(t,
+				sub4.CensorshipRecord.Token, adminID)),
+		},
+	}
+	payload, err := decredplugin.EncodeStartVoteRunoff(svr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	td.Plugin(t, pd.PluginCommand{
+		Challenge: hex.EncodeToString(challenge),
+		ID:        decredplugin.ID,
+		Command:   decredplugin.CmdStartVoteRunoff,
+		CommandID: decredplugin.CmdStartVoteRunoff + " " + svr.Token,
+		Payload:   string(payload),
+	})
+
+	tokenb, err := util.Random(pd.TokenSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenNotFound := hex.EncodeToString(tokenb)
+
+	sv1 := newStartVote(t, sub1Token, adminID)
+	sv2 := newStartVote(t, sub2Token, adminID)
+	svDuration := newStartVote(t, sub2Token, adminID)
+	svDuration.Vote.Duration++
+	svType := newStartVote(t, sub2Token, adminID)
+	svType.Vote.Type = www.VoteTypeMultipleChoice
+	svProp := newStartVote(t, prop.CensorshipRecord.Token, adminID)
+
+	// Setup tests
+	var tests = []struct {
+		name string
+		svr  www.StartVoteRunoff
+		want error
+	}{
+		{"rfp not found",
+			www.StartVoteRunoff{
+				Token: tokenNotFound,
+				Votes: []www.StartVote{sv1, sv2},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}},
+
+		{"proposal is not an rfp",
+			www.StartVoteRunoff{
+				Token: prop.CensorshipRecord.Token,
+				Votes: []www.StartVote{sv1, sv2},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			}},
+
+		{"rfp deadline not expired",
+			www.StartVoteRunoff{
+				Token: rfpOpen.CensorshipRecord.Token,
+				Votes: []www.StartVote{sv1, sv2},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusLinkByDeadlineNotExpired,
+			}},
+
+		{"not enough submissions",
+			www.StartVoteRunoff{
+				Token: rfpToken,
+				Votes: []www.StartVote{sv1},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			}},
+
+		{"runoff already started",
+			www.StartVoteRunoff{
+				Token: rfpStarted.CensorshipRecord.Token,
+				Votes: []www.StartVote{sv1, sv2},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusWrongVoteStatus,
+			}},
+
+		{"proposal is not a submission",
+			www.StartVoteRunoff{
+				Token: rfpToken,
+				Votes: []www.StartVote{sv1, svProp},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			}},
+
+		{"duplicate submission",
+			www.StartVoteRunoff{
+				Token: rfpToken,
+				Votes: []www.StartVote{sv1, sv1},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			}},
+
+		{"vote is not an approval vote",
+			www.StartVoteRunoff{
+				Token: rfpToken,
+				Votes: []www.StartVote{sv1, svType},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			}},
+
+		{"vote durations differ",
+			www.StartVoteRunoff{
+				Token: rfpToken,
+				Votes: []www.StartVote{sv1, svDuration},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidRunoffVote,
+			}},
+
+		{"vote not authorized",
+			www.StartVoteRunoff{
+				Token: rfpToken,
+				Votes: []www.StartVote{sv1, sv2},
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusVoteNotAuthorized,
+			}},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processStartVoteRunoff(v.svr, admin)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+
+	// A runoff vote that has not been started can not be found
+	_, err = p.processVoteRunoff(rfpToken)
+	got := errToStr(err)
+	want := errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusRunoffVoteNotFound,
+	})
+	if got != want {
+		t.Errorf("got error %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/cache"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/user"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// voteSchedulerInterval is the amount of time the vote scheduler
+	// sleeps between checks for scheduled votes that have reached
+	// their start height.
+	voteSchedulerInterval = time.Minute
+)
+
+// scheduleVote sends the schedule vote command to the decred plugin and
+// returns the plugin reply.  It is used to both schedule and cancel votes.
+func (p *politeiawww) scheduleVote(dsv decredplugin.ScheduleVote) (*decredplugin.ScheduleVoteReply, error) {
+	payload, err := decredplugin.EncodeScheduleVote(dsv)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := pd.PluginCommand{
+		Challenge: hex.EncodeToString(challenge),
+		ID:        decredplugin.ID,
+		Command:   decredplugin.CmdScheduleVote,
+		CommandID: decredplugin.CmdScheduleVote + " " + dsv.Token,
+		Payload:   string(payload),
+	}
+
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.PluginCommandRoute, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply pd.PluginCommandReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal PluginCommandReply: %v", err)
+	}
+
+	// Verify the challenge
+	err = p.verifyChallenge(challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return decredplugin.DecodeScheduleVoteReply([]byte(reply.Payload))
+}
+
+// processScheduleVote schedules the start of a proposal vote at a future
+// block height.  The vote must be valid to be started right away.  A vote
+// that is already scheduled is rescheduled.
+func (p *politeiawww) processScheduleVote(sv www.ScheduleVote, u *user.User) (*www.ScheduleVoteReply, error) {
+	log.Tracef("processScheduleVote %v", sv.StartVote.Vote.Token)
+
+	// Ensure the public key is the user's active key
+	if sv.PublicKey != u.PublicKey() {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSigningKey,
+		}
+	}
+
+	// Validate signature
+	msg := sv.StartVote.Vote.Token +
+		strconv.FormatUint(uint64(sv.StartHeight), 10) +
+		decredplugin.ScheduleVoteActionSchedule
+	err := validateSignature(sv.PublicKey, sv.Signature, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = p.validateStartVote(sv.StartVote, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure the start height is in the future
+	bb, err := p.getBestBlock()
+	if err != nil {
+		return nil, fmt.Errorf("getBestBlock: %v", err)
+	}
+	if uint64(sv.StartHeight) <= bb {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidStartHeight,
+			ErrorContext: []string{fmt.Sprintf("start height must "+
+				"be greater than the best block %v", bb)},
+		}
+	}
+
+	svr, err := p.scheduleVote(convertScheduleVoteFromWWW(sv))
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.ScheduleVoteReply{
+		StartHeight:    svr.StartHeight,
+		SnapshotHeight: svr.SnapshotHeight,
+		Receipt:        svr.Receipt,
+	}, nil
+}
+
+// processCancelVote cancels a scheduled vote.  Only the proposal author may
+// cancel the vote and only before its start height has been reached.
+func (p *politeiawww) processCancelVote(cv www.CancelVote, u *user.User) (*www.CancelVoteReply, error) {
+	log.Tracef("processCancelVote %v", cv.Token)
+
+	// Get proposal from the cache
+	pr, err := p.getProp(cv.Token)
+	if err != nil {
+		if err == cache.ErrRecordNotFound {
+			err = www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	// Ensure the public key is the user's active key
+	if cv.PublicKey != u.PublicKey() {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidSigningKey,
+		}
+	}
+
+	// Validate signature
+	msg := cv.Token + strconv.FormatUint(uint64(cv.StartHeight), 10) +
+		decredplugin.ScheduleVoteActionCancel
+	err = validateSignature(cv.PublicKey, cv.Signature, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure the user is the author. The author may have
+	// submitted the proposal using an old identity.
+	if pr.PublicKey != cv.PublicKey {
+		usr, err := p.db.UserGetByPubKey(pr.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if u.ID.String() != usr.ID.String() {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotAuthor,
+			}
+		}
+	}
+
+	// Get vote details from cache
+	vdr, err := p.decredVoteDetails(cv.Token)
+	if err != nil {
+		return nil, fmt.Errorf("decredVoteDetails: %v", err)
+	}
+	vd := convertVoteDetailsReplyFromDecred(*vdr)
+
+	// Ensure the vote is scheduled at the provided height
+	switch {
+	case vd.ScheduledHeight == 0:
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWrongVoteStatus,
+		}
+	case vd.ScheduledHeight != cv.StartHeight:
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidStartHeight,
+			ErrorContext: []string{fmt.Sprintf("vote is scheduled "+
+				"at %v", vd.ScheduledHeight)},
+		}
+	}
+
+	// Ensure the start height has not been reached yet
+	bb, err := p.getBestBlock()
+	if err != nil {
+		return nil, fmt.Errorf("getBestBlock: %v", err)
+	}
+	if bb >= uint64(cv.StartHeight) {
+		return nil, www.UserError{
+			ErrorCode:    www.ErrorStatusInvalidStartHeight,
+			ErrorContext: []string{"start height has been reached"},
+		}
+	}
+
+	svr, err := p.scheduleVote(convertCancelVoteFromWWW(cv))
+	if err != nil {
+		return nil, err
+	}
+
+	return &www.CancelVoteReply{
+		Receipt: svr.Receipt,
+	}, nil
+}
+
+// startScheduledVotes starts the scheduled votes whose start height has been
+// reached.  A vote that fails to start is logged and retried on the next
+// call.  A vote that can no longer be started within
+// decredplugin.ScheduleVoteMaxDelay blocks of its start height is failed
+// instead so that its voting period is not shortened.  An admin may
+// reschedule or start a failed vote.
+func (p *politeiawww) startScheduledVotes() error {
+	svr, err := p.decredScheduledVotes()
+	if err != nil {
+		return fmt.Errorf("decredScheduledVotes: %v", err)
+	}
+	if len(svr.ScheduleVotes) == 0 {
+		return nil
+	}
+
+	bb, err := p.getBestBlock()
+	if err != nil {
+		return fmt.Errorf("getBestBlock: %v", err)
+	}
+
+	// The scheduled votes are ordered by start height
+	for _, v := range svr.ScheduleVotes {
+		if uint64(v.StartHeight) > bb {
+			break
+		}
+
+		if bb > uint64(v.StartHeight)+decredplugin.ScheduleVoteMaxDelay {
+			_, err := p.scheduleVote(decredplugin.ScheduleVote{
+				Action:      decredplugin.ScheduleVoteActionFail,
+				Token:       v.Token,
+				StartHeight: v.StartHeight,
+			})
+			if err != nil {
+				log.Errorf("startScheduledVotes: scheduleVote %v: %v",
+					v.Token, err)
+				continue
+			}
+
+			log.Warnf("Scheduled vote failed: %v start height %v "+
+				"best block %v", v.Token, v.StartHeight, bb)
+			continue
+		}
+
+		_, err := p.startVote(v.StartVote)
+		if err != nil {
+			log.Errorf("startScheduledVotes: startVote %v: %v",
+				v.Token, err)
+			continue
+		}
+
+		log.Infof("Scheduled vote started: %v", v.Token)
+
+		// Notify as if the admin that scheduled the
+		// vote started it.
+		admin, err := p.db.UserGetByPubKey(v.StartVote.PublicKey)
+		if err != nil {
+			log.Errorf("startScheduledVotes: UserGetByPubKey %v: %v",
+				v.Token, err)
+			continue
+		}
+		sv := convertStartVoteFromDecred(v.StartVote)
+		p.fireEvent(EventTypeProposalVoteStarted,
+			EventDataProposalVoteStarted{
+				AdminUser: admin,
+				StartVote: &sv,
+			},
+		)
+	}
+
+	return nil
+}
+
+// voteScheduler periodically starts the scheduled votes that have reached
+// their start height.  It never returns.
+func (p *politeiawww) voteScheduler() {
+	for {
+		err := p.startScheduledVotes()
+		if err != nil {
+			log.Errorf("voteScheduler: %v", err)
+		}
+
+		time.Sleep(voteSchedulerInterval)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/decred/politeia/decredplugin"
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/testpoliteiad"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/user"
+	"github.com/decred/politeia/util"
+)
+
+func newScheduleVote(t *testing.T, token string, height uint32, id *identity.FullIdentity) www.ScheduleVote {
+	msg := token + strconv.FormatUint(uint64(height), 10) +
+		decredplugin.ScheduleVoteActionSchedule
+	sig := id.SignMessage([]byte(msg))
+	return www.ScheduleVote{
+		StartVote:   newStartVote(t, token, id),
+		StartHeight: height,
+		Signature:   hex.EncodeToString(sig[:]),
+		PublicKey:   hex.EncodeToString(id.Public.Key[:]),
+	}
+}
+
+func newScheduleVoteCmd(t *testing.T, token string, height uint32, id *identity.FullIdentity) pd.PluginCommand {
+	sv := newScheduleVote(t, token, height, id)
+	payload, err := decredplugin.EncodeScheduleVote(
+		convertScheduleVoteFromWWW(sv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pd.PluginCommand{
+		Challenge: hex.EncodeToString(challenge),
+		ID:        decredplugin.ID,
+		Command:   decredplugin.CmdScheduleVote,
+		CommandID: decredplugin.CmdScheduleVote + " " + token,
+		Payload:   string(payload),
+	}
+}
+
+func newCancelVote(token string, height uint32, id *identity.FullIdentity) www.CancelVote {
+	msg := token + strconv.FormatUint(uint64(height), 10) +
+		decredplugin.ScheduleVoteActionCancel
+	sig := id.SignMessage([]byte(msg))
+	return www.CancelVote{
+		Token:       token,
+		StartHeight: height,
+		Signature:   hex.EncodeToString(sig[:]),
+		PublicKey:   hex.EncodeToString(id.Public.Key[:]),
+	}
+}
+
+func TestProcessScheduleVote(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	admin, adminID := newUser(t, p, true, true)
+	usr, id := newUser(t, p, true, false)
+
+	// Create a proposal whose vote has not been authorized
+	propNotAuthorized := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	tokenNotAuthorized := propNotAuthorized.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, propNotAuthorized))
+
+	// Create a proposal whose vote has started
+	propStarted := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	tokenStarted := propStarted.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, propStarted))
+	td.Plugin(t, newAuthorizeVoteCmd(t, tokenStarted, propStarted.Version,
+		decredplugin.AuthVoteActionAuthorize, id))
+	td.Plugin(t, newStartVoteCmd(t, tokenStarted, adminID))
+
+	tokenb, err := util.Random(pd.TokenSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenNotFound := hex.EncodeToString(tokenb)
+
+	const height = 1000
+	svWrongKey := newScheduleVote(t, tokenNotAuthorized, height, id)
+	svBadSig := newScheduleVote(t, tokenNotAuthorized, height, adminID)
+	svBadSig.Signature = newScheduleVote(t, tokenNotAuthorized,
+		height+1, adminID).Signature
+	svBadParams := newScheduleVote(t, tokenNotAuthorized, height, adminID)
+	svBadParams.StartVote.Vote.QuorumPercentage = 101
+
+	// Setup tests
+	var tests = []struct {
+		name string
+		sv   www.ScheduleVote
+		want error
+	}{
+		{"invalid signing key", svWrongKey,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSigningKey,
+			}},
+
+		{"invalid signature", svBadSig,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSignature,
+			}},
+
+		{"invalid vote params", svBadParams,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidPropVoteParams,
+			}},
+
+		{"proposal not found",
+			newScheduleVote(t, tokenNotFound, height, adminID),
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}},
+
+		{"vote not authorized",
+			newScheduleVote(t, tokenNotAuthorized, height, adminID),
+			www.UserError{
+				ErrorCode: www.ErrorStatusVoteNotAuthorized,
+			}},
+
+		{"vote already started",
+			newScheduleVote(t, tokenStarted, height, adminID),
+			www.UserError{
+				ErrorCode: www.ErrorStatusWrongVoteStatus,
+			}},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processScheduleVote(v.sv, admin)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+}
+
+func TestProcessCancelVote(t *testing.T) {
+	// Setup test environment
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	td := testpoliteiad.New(t, p.cache)
+	defer td.Close()
+
+	admin, adminID := newUser(t, p, true, true)
+	usr, id := newUser(t, p, true, false)
+	other, otherID := newUser(t, p, true, false)
+
+	// Create a proposal whose vote has been authorized
+	propAuthorized := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	tokenAuthorized := propAuthorized.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, propAuthorized))
+	td.Plugin(t, newAuthorizeVoteCmd(t, tokenAuthorized,
+		propAuthorized.Version, decredplugin.AuthVoteActionAuthorize, id))
+
+	// Create a proposal whose vote has been scheduled
+	const height = 1000
+	propScheduled := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	tokenScheduled := propScheduled.CensorshipRecord.Token
+	td.AddRecord(t, convertPropToPD(t, propScheduled))
+	td.Plugin(t, newAuthorizeVoteCmd(t, tokenScheduled,
+		propScheduled.Version, decredplugin.AuthVoteActionAuthorize, id))
+	td.Plugin(t, newScheduleVoteCmd(t, tokenScheduled, height, adminID))
+
+	tokenb, err := util.Random(pd.TokenSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenNotFound := hex.EncodeToString(tokenb)
+
+	cvBadSig := newCancelVote(tokenScheduled, height, id)
+	cvBadSig.Signature = newCancelVote(tokenScheduled, height+1,
+		id).Signature
+
+	// Setup tests
+	var tests = []struct {
+		name string
+		usr  *user.User
+		cv   www.CancelVote
+		want error
+	}{
+		{"proposal not found", usr,
+			newCancelVote(tokenNotFound, height, id),
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}},
+
+		{"invalid signing key", usr,
+			newCancelVote(tokenScheduled, height, otherID),
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSigningKey,
+			}},
+
+		{"invalid signature", usr, cvBadSig,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidSignature,
+			}},
+
+		{"user not author", other,
+			newCancelVote(tokenScheduled, height, otherID),
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotAuthor,
+			}},
+
+		{"vote not scheduled", usr,
+			newCancelVote(tokenAuthorized, height, id),
+			www.UserError{
+				ErrorCode: www.ErrorStatusWrongVoteStatus,
+			}},
+
+		{"wrong start height", usr,
+			newCancelVote(tokenScheduled, height+1, id),
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidStartHeight,
+			}},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processCancelVote(v.cv, v.usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v",
+					got, want)
+			}
+		})
+	}
+
+	// A scheduled vote can not be started right away
+	_, err = p.processStartVote(newStartVote(t, tokenScheduled, adminID),
+		admin)
+	got := errToStr(err)
+	want := errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusWrongVoteStatus,
+	})
+	if got != want {
+		t.Errorf("start vote: got error %v, want %v", got, want)
+	}
+
+	// The vote authorization of a scheduled vote can not be
+	// revoked
+	_, err = p.processAuthorizeVote(newAuthorizeVote(tokenScheduled,
+		propScheduled.Version, decredplugin.AuthVoteActionRevoke, id), usr)
+	got = errToStr(err)
+	if got != want {
+		t.Errorf("revoke vote: got error %v, want %v", got, want)
+	}
+}
@@ -87,6 +87,12 @@ type commentReplyOnCommentTemplateData struct {
 	CommentLink  string
 }
 
+type coAuthorTemplateData struct {
+	Link     string
+	Name     string
+	Username string
+}
+
 type newInvoiceCommentTemplateData struct {
 }
 
@@ -207,6 +213,27 @@ Voting has just started for your proposal on Politeia!
 {{.Link}}
 `
 
+const templateCoAuthorInvitedRaw = `
+{{.Username}} has invited you to co-author a proposal on Politeia!
+
+You can approve the proposal by opening the proposal page and signing it.
+The vote on the proposal can only be authorized once all co-authors have
+signed it.
+
+{{.Name}}
+{{.Link}}
+`
+
+const templateProposalEditedForCoAuthorRaw = `
+{{.Username}} has edited a proposal that you co-author on Politeia.
+
+Edits invalidate the co-author signatures.  You need to sign the new version
+of the proposal before the vote can be authorized.
+
+{{.Name}}
+{{.Link}}
+`
+
 const templateCommentReplyOnProposalRaw = `
 {{.Commenter}} has commented on your proposal!
 
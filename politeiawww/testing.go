@@ -245,6 +245,8 @@ func newTestPoliteiawww(t *testing.T) (*politeiawww, func()) {
 		userEmails:      make(map[string]uuid.UUID),
 		userPaywallPool: make(map[uuid.UUID]paywallPoolMember),
 		commentScores:   make(map[string]int64),
+		linkedFrom:      make(map[string]map[string]bool),
+		coAuthorLocks:   make(map[string]*coAuthorLock),
 	}
 
 	// Setup routes
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/decred/politeia/politeiawww/user"
 	"github.com/decred/politeia/util"
@@ -28,6 +29,7 @@ const (
 	tableKeyValue   = "key_value"
 	tableUsers      = "users"
 	tableIdentities = "identities"
+	tableDrafts     = "drafts"
 
 	// Database user (read/write access)
 	userPoliteiawww = "politeiawww"
@@ -395,6 +397,201 @@ func (c *cockroachdb) AllUsers(callback func(u *user.User)) error {
 	return nil
 }
 
+// draftNew inserts a draft record into the database.
+func (c *cockroachdb) draftNew(d user.Draft) error {
+	b, err := user.EncodeDraft(d)
+	if err != nil {
+		return err
+	}
+
+	eb, err := c.encrypt(user.VersionDraft, b)
+	if err != nil {
+		return err
+	}
+
+	dr := convertDraftFromUser(d, eb)
+	return c.userDB.Create(&dr).Error
+}
+
+// DraftNew creates a new proposal draft record in the database unless the
+// user already has maxDrafts drafts, in which case user.ErrMaxDrafts is
+// returned.  The drafts are counted by the insert statement itself so that
+// concurrent requests, which cockroachdb runs serializably, can not push the
+// user past the maximum.
+//
+// DraftNew satisfies the Database interface.
+func (c *cockroachdb) DraftNew(d user.Draft, maxDrafts int) error {
+	log.Tracef("DraftNew: %v", d.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	b, err := user.EncodeDraft(d)
+	if err != nil {
+		return err
+	}
+
+	eb, err := c.encrypt(user.VersionDraft, b)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	q := `INSERT INTO ` + tableDrafts + `
+        (id, user_id, blob, created_at, updated_at)
+        SELECT ?, ?, ?, ?, ?
+        WHERE (SELECT COUNT(*) FROM ` + tableDrafts + ` WHERE user_id = ?) < ?`
+	db := c.userDB.Exec(q, d.ID, d.UserID, eb, now, now, d.UserID, maxDrafts)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return user.ErrMaxDrafts
+	}
+
+	return nil
+}
+
+// DraftUpdate updates an existing proposal draft record in the database.
+//
+// DraftUpdate satisfies the Database interface.
+func (c *cockroachdb) DraftUpdate(d user.Draft) error {
+	log.Tracef("DraftUpdate: %v", d.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	b, err := user.EncodeDraft(d)
+	if err != nil {
+		return err
+	}
+
+	eb, err := c.encrypt(user.VersionDraft, b)
+	if err != nil {
+		return err
+	}
+
+	// Only update existing drafts
+	dr := convertDraftFromUser(d, eb)
+	db := c.userDB.
+		Model(&Draft{}).
+		Where("id = ?", d.ID).
+		Updates(Draft{Blob: dr.Blob})
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return user.ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// DraftGetById returns a proposal draft record given its UUID, if found in
+// the database.
+//
+// DraftGetById satisfies the Database interface.
+func (c *cockroachdb) DraftGetById(id uuid.UUID) (*user.Draft, error) {
+	log.Tracef("DraftGetById: %v", id)
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var d Draft
+	err := c.userDB.
+		Where("id = ?", id).
+		Find(&d).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			err = user.ErrDraftNotFound
+		}
+		return nil, err
+	}
+
+	b, _, err := c.decrypt(d.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.DecodeDraft(b)
+}
+
+// DraftsGetByUserId returns all proposal draft records of the user with the
+// provided UUID.
+//
+// DraftsGetByUserId satisfies the Database interface.
+func (c *cockroachdb) DraftsGetByUserId(userID uuid.UUID) ([]user.Draft, error) {
+	log.Tracef("DraftsGetByUserId: %v", userID)
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var drafts []Draft
+	err := c.userDB.
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&drafts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	ds := make([]user.Draft, 0, len(drafts))
+	for _, v := range drafts {
+		b, _, err := c.decrypt(v.Blob)
+		if err != nil {
+			return nil, err
+		}
+		d, err := user.DecodeDraft(b)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, *d)
+	}
+
+	return ds, nil
+}
+
+// DraftDelete deletes a proposal draft record given its UUID.
+//
+// DraftDelete satisfies the Database interface.
+func (c *cockroachdb) DraftDelete(id uuid.UUID) error {
+	log.Tracef("DraftDelete: %v", id)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	db := c.userDB.
+		Where("id = ?", id).
+		Delete(Draft{})
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return user.ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// InsertDraft inserts a proposal draft record into the database. This
+// function is intended to be used for migrations between databases.
+func (c *cockroachdb) InsertDraft(d user.Draft) error {
+	log.Tracef("InsertDraft: %v", d.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	return c.draftNew(d)
+}
+
 // rotateKeys rotates the existing database encryption key with the given new
 // key.
 //
@@ -429,6 +626,35 @@ func rotateKeys(tx *gorm.DB, oldKey *[32]byte, newKey *[32]byte) error {
 		}
 	}
 
+	// Lookup all drafts
+	var drafts []Draft
+	err = tx.Find(&drafts).Error
+	if err != nil {
+		return err
+	}
+
+	// Rotate keys
+	for _, v := range drafts {
+		b, _, err := sbox.Decrypt(oldKey, v.Blob)
+		if err != nil {
+			return fmt.Errorf("decrypt draft '%v': %v",
+				v.ID, err)
+		}
+
+		eb, err := sbox.Encrypt(user.VersionDraft, newKey, b)
+		if err != nil {
+			return fmt.Errorf("encrypt draft '%v': %v",
+				v.ID, err)
+		}
+
+		v.Blob = eb
+		err = tx.Save(&v).Error
+		if err != nil {
+			return fmt.Errorf("save draft '%v': %v",
+				v.ID, err)
+		}
+	}
+
 	return nil
 }
 
@@ -588,6 +814,12 @@ func (c *cockroachdb) createTables(tx *gorm.DB) error {
 			return err
 		}
 	}
+	if !tx.HasTable(tableDrafts) {
+		err := tx.CreateTable(&Draft{}).Error
+		if err != nil {
+			return err
+		}
+	}
 
 	// Insert version record
 	kv := KeyValue{
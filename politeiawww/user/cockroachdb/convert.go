@@ -26,3 +26,11 @@ func convertUserFromUser(u user.User, blob []byte) User {
 		Blob:       blob,
 	}
 }
+
+func convertDraftFromUser(d user.Draft, blob []byte) Draft {
+	return Draft{
+		ID:     d.ID,
+		UserID: d.UserID,
+		Blob:   blob,
+	}
+}
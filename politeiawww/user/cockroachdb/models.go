@@ -48,6 +48,23 @@ func (User) TableName() string {
 	return tableUsers
 }
 
+// Draft represents a proposal draft.  Blob is an encrypted blob of the full
+// draft object.
+type Draft struct {
+	ID     uuid.UUID `gorm:"primary_key"`    // UUID
+	UserID uuid.UUID `gorm:"not null;index"` // User UUID (User foreign key)
+	Blob   []byte    `gorm:"not null"`       // Encrypted blob of draft data
+
+	// Set by gorm
+	CreatedAt time.Time // Time of record creation
+	UpdatedAt time.Time // Time of last record update
+}
+
+// TableName returns the table name of the Draft table.
+func (Draft) TableName() string {
+	return tableDrafts
+}
+
 // CMSUser represents a CMS user. A CMS user includes the politeiawww User
 // object as well as CMS specific user fields. A CMS user must correspond to
 // a politeiawww User.
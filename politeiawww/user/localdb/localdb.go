@@ -3,12 +3,14 @@ package localdb
 import (
 	"encoding/binary"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/decred/politeia/politeiawww/user"
 	"github.com/google/uuid"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
@@ -17,6 +19,9 @@ const (
 
 	UserVersion    uint32 = 1
 	UserVersionKey        = "userversion"
+
+	// DraftPrefix is the key prefix of proposal draft records.
+	DraftPrefix = "draft:"
 )
 
 var (
@@ -42,7 +47,13 @@ type Version struct {
 // and false otherwise. This is helpful when iterating the user records
 // because the DB contains some non-user records.
 func isUserRecord(key string) bool {
-	return key != UserVersionKey && key != LastPaywallAddressIndex
+	return key != UserVersionKey && key != LastPaywallAddressIndex &&
+		!strings.HasPrefix(key, DraftPrefix)
+}
+
+// draftKey returns the key of a proposal draft record.
+func draftKey(id uuid.UUID) []byte {
+	return []byte(DraftPrefix + id.String())
 }
 
 // Store new user.
@@ -362,6 +373,164 @@ func (l *localdb) AllUsers(callbackFn func(u *user.User)) error {
 	return iter.Error()
 }
 
+// DraftNew stores a new proposal draft.  It returns user.ErrMaxDrafts if the
+// user already has maxDrafts drafts.  The drafts are counted and stored with
+// the lock held so that concurrent calls can not exceed the maximum.
+//
+// DraftNew satisfies the Database interface.
+func (l *localdb) DraftNew(d user.Draft, maxDrafts int) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	log.Debugf("DraftNew: %v", d.ID)
+
+	drafts, err := l.draftsByUserID(d.UserID)
+	if err != nil {
+		return err
+	}
+	if len(drafts) >= maxDrafts {
+		return user.ErrMaxDrafts
+	}
+
+	payload, err := user.EncodeDraft(d)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put(draftKey(d.ID), payload, nil)
+}
+
+// DraftUpdate updates an existing proposal draft.
+//
+// DraftUpdate satisfies the Database interface.
+func (l *localdb) DraftUpdate(d user.Draft) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	log.Debugf("DraftUpdate: %v", d.ID)
+
+	// Make sure draft already exists
+	exists, err := l.userdb.Has(draftKey(d.ID), nil)
+	if err != nil {
+		return err
+	} else if !exists {
+		return user.ErrDraftNotFound
+	}
+
+	payload, err := user.EncodeDraft(d)
+	if err != nil {
+		return err
+	}
+
+	return l.userdb.Put(draftKey(d.ID), payload, nil)
+}
+
+// DraftGetById returns a proposal draft given its id, if found in the
+// database.
+//
+// DraftGetById satisfies the Database interface.
+func (l *localdb) DraftGetById(id uuid.UUID) (*user.Draft, error) {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	log.Debugf("DraftGetById: %v", id)
+
+	payload, err := l.userdb.Get(draftKey(id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, user.ErrDraftNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user.DecodeDraft(payload)
+}
+
+// DraftsGetByUserId returns all proposal drafts of a user sorted by creation
+// time.
+//
+// DraftsGetByUserId satisfies the Database interface.
+func (l *localdb) DraftsGetByUserId(userID uuid.UUID) ([]user.Draft, error) {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	log.Debugf("DraftsGetByUserId: %v", userID)
+
+	drafts, err := l.draftsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(drafts, func(i, j int) bool {
+		return drafts[i].CreatedAt < drafts[j].CreatedAt
+	})
+
+	return drafts, nil
+}
+
+// draftsByUserID returns all proposal drafts of a user in key order.
+//
+// This function must be called with the lock held.
+func (l *localdb) draftsByUserID(userID uuid.UUID) ([]user.Draft, error) {
+	drafts := make([]user.Draft, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(DraftPrefix)), nil)
+	for iter.Next() {
+		d, err := user.DecodeDraft(iter.Value())
+		if err != nil {
+			iter.Release()
+			return nil, err
+		}
+		if d.UserID == userID {
+			drafts = append(drafts, *d)
+		}
+	}
+	iter.Release()
+
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+
+	return drafts, nil
+}
+
+// DraftDelete deletes a proposal draft given its id.
+//
+// DraftDelete satisfies the Database interface.
+func (l *localdb) DraftDelete(id uuid.UUID) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	log.Debugf("DraftDelete: %v", id)
+
+	exists, err := l.userdb.Has(draftKey(id), nil)
+	if err != nil {
+		return err
+	} else if !exists {
+		return user.ErrDraftNotFound
+	}
+
+	return l.userdb.Delete(draftKey(id), nil)
+}
+
 // PluginExec executes the provided plugin command.
 func (l *localdb) PluginExec(pc user.PluginCommand) (*user.PluginCommandReply, error) {
 	return nil, user.ErrInvalidPlugin
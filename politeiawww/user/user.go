@@ -24,6 +24,14 @@ var (
 	// ErrUserExists indicates that a user already exists in the database.
 	ErrUserExists = errors.New("user already exists")
 
+	// ErrDraftNotFound indicates that a proposal draft was not found in
+	// the database.
+	ErrDraftNotFound = errors.New("draft not found")
+
+	// ErrMaxDrafts indicates that a user already has the maximum number
+	// of proposal drafts.
+	ErrMaxDrafts = errors.New("maximum number of drafts reached")
+
 	// ErrShutdown is emitted when the database is shutting down.
 	ErrShutdown = errors.New("database is shutting down")
 
@@ -346,6 +354,44 @@ func DecodeUser(payload []byte) (*User, error) {
 	return &u, nil
 }
 
+// VersionDraft is the version of the Draft struct.
+const VersionDraft uint32 = 1
+
+// DraftFile is a file of a proposal draft.
+type DraftFile struct {
+	Name    string `json:"name"`    // Filename
+	MIME    string `json:"mime"`    // Mime type
+	Digest  string `json:"digest"`  // SHA256 digest of unencoded payload
+	Payload string `json:"payload"` // base64 encoded file
+}
+
+// Draft is a proposal that is being composed by its author.  Drafts only
+// reside in the user database and are never sent to politeiad.
+type Draft struct {
+	ID        uuid.UUID   `json:"id"`        // Unique draft uuid
+	UserID    uuid.UUID   `json:"userid"`    // Author uuid
+	Files     []DraftFile `json:"files"`     // Proposal files
+	CreatedAt int64       `json:"createdat"` // Unix timestamp of creation
+	UpdatedAt int64       `json:"updatedat"` // Unix timestamp of last update
+}
+
+// EncodeDraft encodes Draft into a JSON byte slice.
+func EncodeDraft(d Draft) ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// DecodeDraft decodes a JSON byte slice into a Draft.
+func DecodeDraft(payload []byte) (*Draft, error) {
+	var d Draft
+
+	err := json.Unmarshal(payload, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
 // PluginCommand is used to execute a plugin command.
 type PluginCommand struct {
 	ID      string // Plugin identifier
@@ -397,6 +443,22 @@ type Database interface {
 	// Iterate over all users
 	AllUsers(callbackFn func(u *User)) error
 
+	// Add a new proposal draft unless the user already has the
+	// provided maximum number of drafts
+	DraftNew(Draft, int) error
+
+	// Update an existing proposal draft
+	DraftUpdate(Draft) error
+
+	// Return proposal draft given its id
+	DraftGetById(uuid.UUID) (*Draft, error)
+
+	// Return all proposal drafts of a user given the user id
+	DraftsGetByUserId(uuid.UUID) ([]Draft, error)
+
+	// Delete a proposal draft given its id
+	DraftDelete(uuid.UUID) error
+
 	// Register a plugin
 	RegisterPlugin(Plugin) error
 
@@ -730,6 +730,27 @@ func (p *politeiawww) handleUserProposalCredits(w http.ResponseWriter, r *http.R
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// handleUserDrafts returns the proposal drafts of the logged in user.
+func (p *politeiawww) handleUserDrafts(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserDrafts")
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserDrafts: getSessionUser %v", err)
+		return
+	}
+
+	reply, err := p.processUserDrafts(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserDrafts: processUserDrafts %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 // handleRegisterUser handles the completion of registration by invited users of
 // the Contractor Management System.
 func (p *politeiawww) handleRegisterUser(w http.ResponseWriter, r *http.Request) {
@@ -797,6 +818,8 @@ func (p *politeiawww) setUserWWWRoutes() {
 		p.handleUserCommentsLikes, permissionLogin)
 	p.addRoute(http.MethodGet, www.RouteUserProposalCredits,
 		p.handleUserProposalCredits, permissionLogin)
+	p.addRoute(http.MethodGet, www.RouteUserDrafts,
+		p.handleUserDrafts, permissionLogin)
 
 	// Routes that require being logged in as an admin user.
 	p.addRoute(http.MethodPut, www.RouteUserPaymentsRescan,
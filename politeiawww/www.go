@@ -328,6 +328,8 @@ func _main() error {
 		userPaywallPool: make(map[uuid.UUID]paywallPoolMember),
 		commentScores:   make(map[string]int64),
 		voteStatuses:    make(map[string]www.VoteStatusReply),
+		linkedFrom:      make(map[string]map[string]bool),
+		coAuthorLocks:   make(map[string]*coAuthorLock),
 		params:          activeNetParams.Params,
 	}
 
@@ -450,7 +452,17 @@ func _main() error {
 		if err != nil {
 			return err
 		}
+
+		// Setup RFP submissions map
+		err = p.initLinkedFrom()
+		if err != nil {
+			return fmt.Errorf("initLinkedFrom: %v", err)
+		}
 		p.initEventManager()
+
+		// Start the scheduled votes once their start height
+		// has been reached.
+		go p.voteScheduler()
 	} else if p.cfg.Mode == "cmswww" {
 		p.initCMSEventManager()
 	}
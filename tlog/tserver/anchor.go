@@ -34,15 +34,12 @@ func (t *tserver) findLeafAnchor(treeId int64, leaf *trillian.LogLeaf) (*v1.Data
 	log.Tracef("findAnchorByLeafHash %v %x", treeId, leaf.MerkleLeafHash)
 
 	// Retrieve STH
-	reply, err := t.client.GetLatestSignedLogRoot(t.ctx,
-		&trillian.GetLatestSignedLogRootRequest{
-			LogId: treeId,
-		})
+	slr, err := t.tlog.signedLogRoot(treeId)
 	if err != nil {
 		return nil, err
 	}
 	var lrv1 types.LogRootV1
-	err = lrv1.UnmarshalBinary(reply.SignedLogRoot.LogRoot)
+	err = lrv1.UnmarshalBinary(slr.LogRoot)
 	if err != nil {
 		return nil, err
 	}
@@ -56,18 +53,14 @@ func (t *tserver) findLeafAnchor(treeId int64, leaf *trillian.LogLeaf) (*v1.Data
 	)
 	for anchor == nil && startIndex < int64(lrv1.TreeSize) {
 		// Retrieve a page of leaves
-		glbrr, err := t.client.GetLeavesByRange(t.ctx,
-			&trillian.GetLeavesByRangeRequest{
-				LogId:      treeId,
-				StartIndex: startIndex,
-				Count:      pageSize,
-			})
+		leaves, err := t.tlog.leavesByRange(treeId, startIndex,
+			pageSize)
 		if err != nil {
 			return nil, err
 		}
 
 		// Scan leaves for an anchor
-		for _, v := range glbrr.Leaves {
+		for _, v := range leaves {
 			// Retrieve leaf payload from backend
 			payload, err := t.s.Get(v.ExtraData)
 			if err != nil {
@@ -120,25 +113,23 @@ func (t *tserver) findLeafAnchor(treeId int64, leaf *trillian.LogLeaf) (*v1.Data
 func (t *tserver) findLatestAnchor(tree *trillian.Tree, lrv1 *types.LogRootV1) (*v1.DataAnchor, error) {
 	log.Tracef("findLatestAnchor")
 
+	// An empty tree can't have an anchor
+	if lrv1.TreeSize == 0 {
+		return nil, nil
+	}
+
 	// Get leaves
 	startIndex := int64(lrv1.TreeSize) - 1
-	if startIndex < 0 {
-		startIndex = 1
-	}
-	glbrr, err := t.client.GetLeavesByRange(t.ctx,
-		&trillian.GetLeavesByRangeRequest{
-			LogId:      tree.TreeId,
-			StartIndex: startIndex,
-			Count:      int64(lrv1.TreeSize),
-		})
+	leaves, err := t.tlog.leavesByRange(tree.TreeId, startIndex,
+		int64(lrv1.TreeSize))
 	if err != nil {
 		return nil, err
 	}
 
 	// We can be clever and request only the top leaf and see if it is an
 	// anchor. Note that the FSCK code must walk the entire tree backwards.
-	log.Tracef("findLatestAnchor get: %s", glbrr.Leaves[0].ExtraData)
-	payload, err := t.s.Get(glbrr.Leaves[0].ExtraData)
+	log.Tracef("findLatestAnchor get: %s", leaves[0].ExtraData)
+	payload, err := t.s.Get(leaves[0].ExtraData)
 	if err != nil {
 		return nil, err
 	}
@@ -182,19 +173,19 @@ func (t *tserver) findLatestAnchor(tree *trillian.Tree, lrv1 *types.LogRootV1) (
 func (t *tserver) scanAllRecords() error {
 	log.Tracef("scanAllRecords")
 	// List trees
-	ltr, err := t.admin.ListTrees(t.ctx, &trillian.ListTreesRequest{})
+	trees, err := t.tlog.treesAll()
 	if err != nil {
 		return err
 	}
 
-	if len(ltr.Tree) == 0 {
+	if len(trees) == 0 {
 		log.Infof("scanAllRecords: nothing dirty")
 		return nil
 	}
 
 	// Get all records
-	log.Debugf("scanAllRecords scanning records: %v", len(ltr.Tree))
-	for _, tree := range ltr.Tree {
+	log.Debugf("scanAllRecords scanning records: %v", len(trees))
+	for _, tree := range trees {
 		// Retrieve STH
 		_, lrv1, err := t.getLatestSignedLogRoot(tree)
 		if err != nil {
@@ -486,23 +477,15 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 	log.Tracef("fsckRecord")
 
 	// Get leaves
-	glbrr, err := t.client.GetLeavesByRange(t.ctx,
-		&trillian.GetLeavesByRangeRequest{
-			LogId:      tree.TreeId,
-			StartIndex: 0,
-			Count:      int64(lrv1.TreeSize),
-		})
+	leaves, err := t.tlog.leavesByRange(tree.TreeId, 0,
+		int64(lrv1.TreeSize))
 	if err != nil {
 		return err
 	}
 
 	// Perform tree coherency test
-	gcprr, err := t.client.GetConsistencyProof(t.ctx,
-		&trillian.GetConsistencyProofRequest{
-			LogId:          tree.TreeId,
-			FirstTreeSize:  1,
-			SecondTreeSize: int64(lrv1.TreeSize),
-		})
+	proof, err := t.tlog.consistencyProof(tree.TreeId, 1,
+		int64(lrv1.TreeSize))
 	if err != nil {
 		return err
 	}
@@ -514,7 +497,7 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 	// The log root hash when TreeSize=1 is just the MerkleLeafHash
 	// of the single leaf.
 	var root1 []byte
-	for _, v := range glbrr.Leaves {
+	for _, v := range leaves {
 		if v.LeafIndex == 0 {
 			root1 = v.MerkleLeafHash
 			break
@@ -524,23 +507,23 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 		return fmt.Errorf("leaf index 0 not found")
 	}
 	err = verifier.VerifyConsistencyProof(1, int64(lrv1.TreeSize),
-		root1, lrv1.RootHash, gcprr.Proof.Hashes)
+		root1, lrv1.RootHash, proof.Hashes)
 	if err != nil {
 		return err
 	}
 
 	// Walk leaves backwards
-	for x := len(glbrr.Leaves) - 1; x >= 0; x-- {
-		log.Tracef("fsckRecord get: %s", glbrr.Leaves[x].ExtraData)
-		payload, err := t.s.Get(glbrr.Leaves[x].ExtraData)
+	for x := len(leaves) - 1; x >= 0; x-- {
+		log.Tracef("fsckRecord get: %s", leaves[x].ExtraData)
+		payload, err := t.s.Get(leaves[x].ExtraData)
 		if err != nil {
 			// Absence of a record entry does not necessarily mean something
 			// is wrong. If there was an error during the record append call
 			// the trillian leaves will still exist but the record entry
 			// blobs would have been unwound.
 			log.Debugf("Record entry not found %v %v: %v",
-				glbrr.Leaves[x].MerkleLeafHash,
-				glbrr.Leaves[x].ExtraData, err)
+				leaves[x].MerkleLeafHash,
+				leaves[x].ExtraData, err)
 			continue
 		}
 		re, err := deblob(payload)
@@ -569,13 +552,13 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 			}
 			log.Tracef("fsckRecord kv: %s", data)
 			hash := NewSHA256(data)
-			if !bytes.Equal(hash, glbrr.Leaves[x].LeafValue) {
+			if !bytes.Equal(hash, leaves[x].LeafValue) {
 				return fmt.Errorf("fsckRecord data key/value "+
 					"corruption %s",
-					glbrr.Leaves[x].ExtraData)
+					leaves[x].ExtraData)
 			}
 			log.Tracef("fsckRecord %s Data integrity OK",
-				glbrr.Leaves[x].ExtraData)
+				leaves[x].ExtraData)
 			continue
 		case v1.DataTypeMime:
 			log.Tracef("fsckRecord mime: %v", dh.Descriptor)
@@ -586,12 +569,12 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 					x, err)
 			}
 			hash := NewSHA256(data)
-			if !bytes.Equal(hash, glbrr.Leaves[x].LeafValue) {
+			if !bytes.Equal(hash, leaves[x].LeafValue) {
 				return fmt.Errorf("fsckRecord data corruption %s",
-					glbrr.Leaves[x].ExtraData)
+					leaves[x].ExtraData)
 			}
 			log.Tracef("fsckRecord %s Data integrity OK",
-				glbrr.Leaves[x].ExtraData)
+				leaves[x].ExtraData)
 			continue
 		case v1.DataTypeStructure:
 			if !(dh.Descriptor == v1.DataDescriptorAnchor) {
@@ -614,13 +597,13 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 
 			// Verify hash
 			hash := NewSHA256(data)
-			if !bytes.Equal(hash, glbrr.Leaves[x].LeafValue) {
+			if !bytes.Equal(hash, leaves[x].LeafValue) {
 				return fmt.Errorf("fsckRecord data structure "+
 					"corruption %s",
-					glbrr.Leaves[x].ExtraData)
+					leaves[x].ExtraData)
 			}
 			log.Tracef("fsckRecord %s Data integrity OK",
-				glbrr.Leaves[x].ExtraData)
+				leaves[x].ExtraData)
 
 			// Verify anchor
 			_, err = util.Verify("tserver", t.cfg.DcrtimeHost,
@@ -631,7 +614,7 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 			}
 
 			log.Tracef("fsckRecord %s Anchor OK",
-				glbrr.Leaves[x].ExtraData)
+				leaves[x].ExtraData)
 			continue
 		default:
 			return fmt.Errorf("fsckRecord unknown type: %v", dh.Type)
@@ -32,6 +32,10 @@ const (
 	defaultLogFilename    = "tserver.log"
 	defaultTrillianHost   = "localhost:8090"
 
+	// defaultEmbeddedLogDirname is the directory, relative to the home
+	// directory, where the embedded log keeps its trees.
+	defaultEmbeddedLogDirname = "embeddedlog"
+
 	defaultMainnetPort = "65535"
 	defaultTestnetPort = "65534"
 )
@@ -75,6 +79,7 @@ type config struct {
 	RPCUser       string `long:"rpcuser" description:"RPC user name for privileged commands"`
 	RPCPass       string `long:"rpcpass" description:"RPC password for privileged commands"`
 	TrillianHost  string `long:"trillianhost" description:"Trillian log host"`
+	EmbeddedLog   bool   `long:"embeddedlog" description:"Use an in-process log instead of a trillian log server"`
 	DcrtimeHost   string `long:"dcrtimehost" description:"Dcrtime ip:port"`
 	DcrtimeCert   string `long:"dcrtimecert" description:"File containing the https certificate file for dcrtimehost"`
 }
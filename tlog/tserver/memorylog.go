@@ -0,0 +1,488 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/politeia/util"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	tcrypto "github.com/google/trillian/crypto"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// memoryTreeExtension is the file extension of a persisted tree.
+	memoryTreeExtension = ".json"
+)
+
+var (
+	_ trillianClient = (*memoryLog)(nil)
+)
+
+// memoryLeaf is a single persisted leaf of a memoryTree.
+type memoryLeaf struct {
+	LeafValue []byte `json:"leafvalue"` // Leaf value
+	ExtraData []byte `json:"extradata"` // Blob ID
+}
+
+// memoryTree is a single RFC 6962 log. The leaves are kept in memory in their
+// entirety and the tree is written to disk every time it changes.
+type memoryTree struct {
+	TreeID     int64        `json:"treeid"`     // Tree ID
+	CreateTime int64        `json:"createtime"` // Creation time in nanoseconds
+	Timestamp  int64        `json:"timestamp"`  // Last root update in nanoseconds
+	Revision   uint64       `json:"revision"`   // Number of root updates
+	Leaves     []memoryLeaf `json:"leaves"`     // Sequenced leaves
+
+	leafHashes [][]byte         // Merkle leaf hashes in leaf order
+	hashes     map[string]int64 // [leafHash]leafIndex
+}
+
+// logLeaf returns the trillian representation of the leaf at index.
+func (mt *memoryTree) logLeaf(index int64) *trillian.LogLeaf {
+	return &trillian.LogLeaf{
+		MerkleLeafHash:   mt.leafHashes[index],
+		LeafValue:        mt.Leaves[index].LeafValue,
+		ExtraData:        mt.Leaves[index].ExtraData,
+		LeafIndex:        index,
+		LeafIdentityHash: mt.leafHashes[index],
+	}
+}
+
+// rehash recreates the in memory hash indexes of the tree.
+func (mt *memoryTree) rehash() {
+	mt.leafHashes = make([][]byte, 0, len(mt.Leaves))
+	mt.hashes = make(map[string]int64, len(mt.Leaves))
+	for k, v := range mt.Leaves {
+		h := util.MerkleLeafHash(v.LeafValue)
+		mt.leafHashes = append(mt.leafHashes, h)
+		mt.hashes[string(h)] = int64(k)
+	}
+}
+
+// memoryLog implements the trillianClient interface with an embedded log. It
+// produces the same RFC 6962 roots and proofs as a trillian log server and
+// signs log roots with the tserver signing key, which makes it a drop-in
+// replacement for development and small deployments.
+type memoryLog struct {
+	sync.Mutex
+
+	path      string            // Location of persisted trees
+	signer    *tcrypto.Signer   // Log root signer
+	publicKey *keyspb.PublicKey // DER encoded signer public key
+
+	trees map[int64]*memoryTree // [treeID]tree
+}
+
+// treeProto returns the trillian tree of a memoryTree.
+func (m *memoryLog) treeProto(mt *memoryTree) (*trillian.Tree, error) {
+	ct, err := ptypes.TimestampProto(time.Unix(0, mt.CreateTime))
+	if err != nil {
+		return nil, err
+	}
+	ut, err := ptypes.TimestampProto(time.Unix(0, mt.Timestamp))
+	if err != nil {
+		return nil, err
+	}
+	return &trillian.Tree{
+		TreeId:             mt.TreeID,
+		TreeState:          trillian.TreeState_ACTIVE,
+		TreeType:           trillian.TreeType_LOG,
+		HashStrategy:       trillian.HashStrategy_RFC6962_SHA256,
+		HashAlgorithm:      sigpb.DigitallySigned_SHA256,
+		SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
+		PublicKey:          m.publicKey,
+		MaxRootDuration:    ptypes.DurationProto(0),
+		CreateTime:         ct,
+		UpdateTime:         ut,
+	}, nil
+}
+
+// getTree returns the tree with the provided id.
+//
+// This function must be called with the lock held.
+func (m *memoryLog) getTree(treeID int64) (*memoryTree, error) {
+	mt, ok := m.trees[treeID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found",
+			treeID)
+	}
+	return mt, nil
+}
+
+// save writes a tree to disk. The tree is written to a temporary file first
+// so that a crash can not leave a partially written tree behind.
+//
+// This function must be called with the lock held.
+func (m *memoryLog) save(mt *memoryTree) error {
+	b, err := json.Marshal(mt)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(m.path,
+		strconv.FormatInt(mt.TreeID, 10)+memoryTreeExtension)
+	tmp := filename + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// signRoot returns the signed log root of a tree at its current size.
+//
+// This function must be called with the lock held.
+func (m *memoryLog) signRoot(mt *memoryTree) (*trillian.SignedLogRoot, error) {
+	return m.signer.SignLogRoot(&types.LogRootV1{
+		TreeSize:       uint64(len(mt.leafHashes)),
+		RootHash:       util.MerkleRootHash(mt.leafHashes),
+		TimestampNanos: uint64(mt.Timestamp),
+		Revision:       mt.Revision,
+	})
+}
+
+func (m *memoryLog) treeNew() (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	// Pick an unused, positive, tree id
+	var treeID int64
+	for treeID == 0 {
+		var b [8]byte
+		_, err := rand.Read(b[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		treeID = int64(binary.LittleEndian.Uint64(b[:]) >> 1)
+		if _, ok := m.trees[treeID]; ok {
+			treeID = 0
+		}
+	}
+
+	now := time.Now().UnixNano()
+	mt := &memoryTree{
+		TreeID:     treeID,
+		CreateTime: now,
+		Timestamp:  now,
+		Leaves:     []memoryLeaf{},
+	}
+	mt.rehash()
+	err := m.save(mt)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.trees[treeID] = mt
+
+	tree, err := m.treeProto(mt)
+	if err != nil {
+		return nil, nil, err
+	}
+	slr, err := m.signRoot(mt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Debugf("Initialised Log: %v", treeID)
+
+	return tree, slr, nil
+}
+
+func (m *memoryLog) tree(treeID int64) (*trillian.Tree, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	return m.treeProto(mt)
+}
+
+func (m *memoryLog) treesAll() ([]*trillian.Tree, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	trees := make([]*trillian.Tree, 0, len(m.trees))
+	for _, mt := range m.trees {
+		tree, err := m.treeProto(mt)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+	return trees, nil
+}
+
+// leavesAppend sequences the leaves immediately so there is no need to wait
+// for a root update.
+func (m *memoryLog) leavesAppend(tree *trillian.Tree, root *trillian.SignedLogRoot, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(tree.TreeId)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := make([]*trillian.QueuedLogLeaf, 0, len(leaves))
+	var n int
+	for _, v := range leaves {
+		h := util.MerkleLeafHash(v.LeafValue)
+		if _, ok := mt.hashes[string(h)]; ok {
+			// Return the caller's leaf so that it knows which
+			// data to unwind.
+			queued = append(queued, &trillian.QueuedLogLeaf{
+				Leaf: &trillian.LogLeaf{
+					MerkleLeafHash:   h,
+					LeafValue:        v.LeafValue,
+					ExtraData:        v.ExtraData,
+					LeafIdentityHash: h,
+				},
+				Status: status.New(codes.AlreadyExists,
+					"duplicate leaf").Proto(),
+			})
+			continue
+		}
+
+		index := int64(len(mt.Leaves))
+		mt.Leaves = append(mt.Leaves, memoryLeaf{
+			LeafValue: v.LeafValue,
+			ExtraData: v.ExtraData,
+		})
+		mt.leafHashes = append(mt.leafHashes, h)
+		mt.hashes[string(h)] = index
+		n++
+
+		// A queued leaf does not have an index, just like
+		// trillian.
+		ll := mt.logLeaf(index)
+		ll.LeafIndex = 0
+		queued = append(queued, &trillian.QueuedLogLeaf{
+			Leaf:   ll,
+			Status: status.New(codes.OK, "").Proto(),
+		})
+	}
+
+	if n != 0 {
+		mt.Timestamp = time.Now().UnixNano()
+		mt.Revision++
+		err = m.save(mt)
+		if err != nil {
+			// Reload the tree from disk to undo the append.
+			delete(m.trees, mt.TreeID)
+			if lerr := m.load(mt.TreeID); lerr != nil {
+				log.Criticalf("memoryLog reload %v: %v",
+					mt.TreeID, lerr)
+			}
+			return nil, err
+		}
+	}
+
+	return queued, nil
+}
+
+func (m *memoryLog) signedLogRoot(treeID int64) (*trillian.SignedLogRoot, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	return m.signRoot(mt)
+}
+
+// proof returns the inclusion proof of the leaf at leafIndex.
+//
+// This function must be called with the lock held.
+func (m *memoryLog) proof(mt *memoryTree, leafIndex, treeSize int64) (*trillian.Proof, error) {
+	if treeSize <= 0 || treeSize > int64(len(mt.leafHashes)) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid tree size: %v", treeSize)
+	}
+	if leafIndex < 0 || leafIndex >= treeSize {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid leaf index: %v", leafIndex)
+	}
+
+	return &trillian.Proof{
+		LeafIndex: leafIndex,
+		Hashes: util.MerkleInclusionProof(int(leafIndex),
+			mt.leafHashes[:treeSize]),
+	}, nil
+}
+
+func (m *memoryLog) inclusionProof(treeID, leafIndex, treeSize int64) (*trillian.Proof, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	return m.proof(mt, leafIndex, treeSize)
+}
+
+func (m *memoryLog) inclusionProofByHash(treeID int64, leafHash []byte, treeSize int64) (*trillian.Proof, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	index, ok := mt.hashes[string(leafHash)]
+	if !ok || index >= treeSize {
+		return nil, status.Errorf(codes.NotFound,
+			"leaf %x not found at tree size %v", leafHash, treeSize)
+	}
+	return m.proof(mt, index, treeSize)
+}
+
+func (m *memoryLog) consistencyProof(treeID, first, second int64) (*trillian.Proof, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	if first <= 0 || first > second || second > int64(len(mt.leafHashes)) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid tree sizes: %v %v", first, second)
+	}
+
+	return &trillian.Proof{
+		Hashes: util.MerkleConsistencyProof(int(first),
+			mt.leafHashes[:second]),
+	}, nil
+}
+
+func (m *memoryLog) leavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(mt.Leaves))
+	if startIndex < 0 || startIndex >= size || count <= 0 {
+		return nil, status.Errorf(codes.OutOfRange,
+			"invalid range: %v %v", startIndex, count)
+	}
+
+	end := startIndex + count
+	if end > size {
+		end = size
+	}
+	leaves := make([]*trillian.LogLeaf, 0, end-startIndex)
+	for i := startIndex; i < end; i++ {
+		leaves = append(leaves, mt.logLeaf(i))
+	}
+	return leaves, nil
+}
+
+func (m *memoryLog) leavesByHash(treeID int64, leafHashes [][]byte) ([]*trillian.LogLeaf, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	mt, err := m.getTree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	leaves := make([]*trillian.LogLeaf, 0, len(leafHashes))
+	for _, v := range leafHashes {
+		index, ok := mt.hashes[string(v)]
+		if !ok {
+			continue
+		}
+		leaves = append(leaves, mt.logLeaf(index))
+	}
+	return leaves, nil
+}
+
+func (m *memoryLog) close() {}
+
+// load reads a single tree from disk.
+//
+// This function must be called with the lock held.
+func (m *memoryLog) load(treeID int64) error {
+	filename := filepath.Join(m.path,
+		strconv.FormatInt(treeID, 10)+memoryTreeExtension)
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var mt memoryTree
+	err = json.Unmarshal(b, &mt)
+	if err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	if mt.TreeID != treeID {
+		return fmt.Errorf("%v: invalid tree id %v", filename, mt.TreeID)
+	}
+	mt.rehash()
+	m.trees[treeID] = &mt
+	return nil
+}
+
+// memoryLogNew returns an embedded log that persists its trees in path and
+// signs log roots with signingKey.
+func memoryLogNew(path string, signingKey crypto.Signer) (*memoryLog, error) {
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := der.MarshalPublicKey(signingKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	m := &memoryLog{
+		path:      path,
+		signer:    tcrypto.NewSigner(0, signingKey, crypto.SHA256),
+		publicKey: &keyspb.PublicKey{Der: pk},
+		trees:     make(map[int64]*memoryTree),
+	}
+
+	// Load existing trees
+	fi, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range fi {
+		name := v.Name()
+		if v.IsDir() || !strings.HasSuffix(name, memoryTreeExtension) {
+			continue
+		}
+		treeID, err := strconv.ParseInt(strings.TrimSuffix(name,
+			memoryTreeExtension), 10, 64)
+		if err != nil {
+			log.Warnf("memoryLogNew: ignoring %v", name)
+			continue
+		}
+		err = m.load(treeID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Infof("Embedded log trees: %v", len(m.trees))
+
+	return m, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	v1 "github.com/decred/politeia/tlog/api/v1"
+	"github.com/decred/politeia/util"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	tcrypto "github.com/google/trillian/crypto"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc/codes"
+)
+
+func newTestLeaves(start, count int) []*trillian.LogLeaf {
+	leaves := make([]*trillian.LogLeaf, 0, count)
+	for i := start; i < start+count; i++ {
+		h := sha256.Sum256([]byte(strconv.Itoa(i)))
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafValue: h[:],
+			ExtraData: []byte("blob" + strconv.Itoa(i)),
+		})
+	}
+	return leaves
+}
+
+func TestMemoryLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memorylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	signingKey, err := keys.NewFromSpec(&keyspb.Specification{
+		Params: &keyspb.Specification_EcdsaParams{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ml, err := memoryLogNew(dir, signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create tree and verify its initial root
+	tree, root, err := ml.treeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lrv1, err := tcrypto.VerifySignedLogRoot(verifier.PubKey,
+		crypto.SHA256, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lrv1.TreeSize != 0 {
+		t.Fatalf("invalid initial tree size %v", lrv1.TreeSize)
+	}
+
+	// appendAndVerify appends the leaves and verifies the queued leaf
+	// proofs the same way tclient does.
+	appendAndVerify := func(leaves []*trillian.LogLeaf) (*types.LogRootV1, []*trillian.QueuedLogLeaf) {
+		t.Helper()
+
+		queued, err := ml.leavesAppend(tree, root, leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err = ml.signedLogRoot(tree.TreeId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lrv1, err := tcrypto.VerifySignedLogRoot(verifier.PubKey,
+			crypto.SHA256, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range queued {
+			if codes.Code(v.GetStatus().GetCode()) != codes.OK {
+				continue
+			}
+			proof, err := ml.inclusionProofByHash(tree.TreeId,
+				v.Leaf.MerkleLeafHash, int64(lrv1.TreeSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = util.QueuedLeafProofVerify(verifier.PubKey, lrv1,
+				v1.QueuedLeafProof{
+					QueuedLeaf: *v,
+					Proof:      proof,
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return lrv1, queued
+	}
+
+	lrv1First, _ := appendAndVerify(newTestLeaves(0, 5))
+	if lrv1First.TreeSize != 5 {
+		t.Fatalf("invalid tree size got %v want 5", lrv1First.TreeSize)
+	}
+
+	// Duplicates must not be appended
+	leaves := newTestLeaves(5, 3)
+	leaves = append(leaves, newTestLeaves(0, 1)...)
+	lrv1, queued := appendAndVerify(leaves)
+	if lrv1.TreeSize != 8 {
+		t.Fatalf("invalid tree size got %v want 8", lrv1.TreeSize)
+	}
+	dup := queued[len(queued)-1]
+	if codes.Code(dup.GetStatus().GetCode()) != codes.AlreadyExists {
+		t.Fatalf("duplicate leaf was appended")
+	}
+	if !bytes.Equal(dup.Leaf.ExtraData, leaves[3].ExtraData) {
+		t.Fatalf("duplicate leaf returned wrong extra data")
+	}
+
+	// Verify all leaves by index
+	all, err := ml.leavesByRange(tree.TreeId, 0, int64(lrv1.TreeSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 8 {
+		t.Fatalf("invalid number of leaves got %v want 8", len(all))
+	}
+	for _, v := range all {
+		proof, err := ml.inclusionProof(tree.TreeId, v.LeafIndex,
+			int64(lrv1.TreeSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = verifier.VerifyInclusionAtIndex(lrv1, v.LeafValue,
+			v.LeafIndex, proof.Hashes)
+		if err != nil {
+			t.Fatalf("leaf %v: %v", v.LeafIndex, err)
+		}
+	}
+
+	// Verify consistency
+	lh, err := hashers.NewLogHasher(trillian.HashStrategy_RFC6962_SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mv := merkle.NewLogVerifier(lh)
+	proof, err := ml.consistencyProof(tree.TreeId, 1, int64(lrv1.TreeSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mv.VerifyConsistencyProof(1, int64(lrv1.TreeSize),
+		all[0].MerkleLeafHash, lrv1.RootHash, proof.Hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err = ml.consistencyProof(tree.TreeId,
+		int64(lrv1First.TreeSize), int64(lrv1.TreeSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mv.VerifyConsistencyProof(int64(lrv1First.TreeSize),
+		int64(lrv1.TreeSize), lrv1First.RootHash, lrv1.RootHash,
+		proof.Hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload from disk and make sure nothing changed
+	ml, err = memoryLogNew(dir, signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trees, err := ml.treesAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trees) != 1 || trees[0].TreeId != tree.TreeId {
+		t.Fatalf("invalid trees after reload: %v", trees)
+	}
+	slr, err := ml.signedLogRoot(tree.TreeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lrv1Reload, err := tcrypto.VerifySignedLogRoot(verifier.PubKey,
+		crypto.SHA256, slr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lrv1Reload.TreeSize != lrv1.TreeSize ||
+		!bytes.Equal(lrv1Reload.RootHash, lrv1.RootHash) {
+		t.Fatalf("root changed after reload")
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// trillianClient is the set of log operations that tserver relies on. It is
+// implemented by a connection to a trillian log server and by an embedded
+// in-process log that does not require trillian or MySQL.
+type trillianClient interface {
+	// treeNew creates a new, initialized, tree and returns it along with
+	// its initial signed log root.
+	treeNew() (*trillian.Tree, *trillian.SignedLogRoot, error)
+
+	// tree returns the tree with the provided id.
+	tree(treeID int64) (*trillian.Tree, error)
+
+	// treesAll returns all trees.
+	treesAll() ([]*trillian.Tree, error)
+
+	// leavesAppend appends the leaves to the tree and waits until they
+	// have been sequenced. Leaves that could not be appended, e.g.
+	// duplicates, are returned with a non OK status.
+	leavesAppend(tree *trillian.Tree, root *trillian.SignedLogRoot, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error)
+
+	// signedLogRoot returns the latest signed log root of a tree.
+	signedLogRoot(treeID int64) (*trillian.SignedLogRoot, error)
+
+	// inclusionProof returns the inclusion proof of a leaf at the
+	// provided tree size.
+	inclusionProof(treeID, leafIndex, treeSize int64) (*trillian.Proof, error)
+
+	// inclusionProofByHash returns the inclusion proof of the leaf with
+	// the provided merkle leaf hash at the provided tree size.
+	inclusionProofByHash(treeID int64, leafHash []byte, treeSize int64) (*trillian.Proof, error)
+
+	// consistencyProof returns the proof that the tree at size first is
+	// a prefix of the tree at size second.
+	consistencyProof(treeID, first, second int64) (*trillian.Proof, error)
+
+	// leavesByRange returns up to count leaves starting at startIndex.
+	leavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error)
+
+	// leavesByHash returns the leaves with the provided merkle leaf
+	// hashes. Unknown hashes are ignored.
+	leavesByHash(treeID int64, leafHashes [][]byte) ([]*trillian.LogLeaf, error)
+
+	// close releases all resources.
+	close()
+}
+
+var (
+	_ trillianClient = (*trillianLog)(nil)
+)
+
+// trillianLog implements the trillianClient interface using a trillian log
+// server.
+type trillianLog struct {
+	grpc       *grpc.ClientConn
+	client     trillian.TrillianLogClient
+	admin      trillian.TrillianAdminClient
+	ctx        context.Context
+	signingKey *keyspb.PrivateKey // trillian signing key
+}
+
+func (t *trillianLog) treeNew() (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	k, err := ptypes.MarshalAny(t.signingKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create new trillian tree
+	tree, err := t.admin.CreateTree(t.ctx, &trillian.CreateTreeRequest{
+		Tree: &trillian.Tree{
+			TreeState:          trillian.TreeState_ACTIVE,
+			TreeType:           trillian.TreeType_LOG,
+			HashStrategy:       trillian.HashStrategy_RFC6962_SHA256,
+			HashAlgorithm:      sigpb.DigitallySigned_SHA256,
+			SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
+			//SignatureAlgorithm: sigpb.DigitallySigned_ED25519,
+			DisplayName:     "",
+			Description:     "",
+			MaxRootDuration: ptypes.DurationProto(0),
+			PrivateKey:      k,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Init tree or signer goes bananas
+	ilr, err := t.client.InitLog(t.ctx, &trillian.InitLogRequest{
+		LogId: tree.TreeId,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check trillian errors
+	switch code := status.Code(err); code {
+	case codes.Unavailable:
+		err = fmt.Errorf("log server unavailable: %v", err)
+	case codes.AlreadyExists:
+		err = fmt.Errorf("just-created Log (%v) is already initialised: %v",
+			tree.TreeId, err)
+	case codes.OK:
+		log.Debugf("Initialised Log: %v", tree.TreeId)
+	default:
+		err = fmt.Errorf("failed to InitLog (unknown error)")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tree, ilr.Created, nil
+}
+
+func (t *trillianLog) tree(treeID int64) (*trillian.Tree, error) {
+	return t.admin.GetTree(t.ctx, &trillian.GetTreeRequest{
+		TreeId: treeID,
+	})
+}
+
+func (t *trillianLog) treesAll() ([]*trillian.Tree, error) {
+	ltr, err := t.admin.ListTrees(t.ctx, &trillian.ListTreesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return ltr.Tree, nil
+}
+
+// waitForRootUpdate waits until the trillian root is updated. This code is
+// clunky because we need a trillian.client context which we have to construct
+// from known information. We probably should create our own client structure
+// that does this with a saner API.
+func (t *trillianLog) waitForRootUpdate(tree *trillian.Tree, root *trillian.SignedLogRoot) error {
+	// Wait for update
+	var logRoot types.LogRootV1
+	err := logRoot.UnmarshalBinary(root.LogRoot)
+	if err != nil {
+		return err
+	}
+	c, err := client.NewFromTree(t.client, tree, logRoot)
+	if err != nil {
+		return err
+	}
+	_, err = c.WaitForRootUpdate(t.ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *trillianLog) leavesAppend(tree *trillian.Tree, root *trillian.SignedLogRoot, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
+	qlr, err := t.client.QueueLeaves(t.ctx, &trillian.QueueLeavesRequest{
+		LogId:  tree.TreeId,
+		Leaves: leaves,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("QueueLeaves: %v", err)
+	}
+
+	// Only wait if we actually updated the tree
+	var n int
+	for _, v := range qlr.QueuedLeaves {
+		if codes.Code(v.GetStatus().GetCode()) == codes.OK {
+			n++
+		}
+	}
+	if n != 0 {
+		log.Debugf("Waiting for update: %v", tree.TreeId)
+		err = t.waitForRootUpdate(tree, root)
+		if err != nil {
+			return nil, fmt.Errorf("waitForRootUpdate: %v", err)
+		}
+	}
+
+	return qlr.QueuedLeaves, nil
+}
+
+func (t *trillianLog) signedLogRoot(treeID int64) (*trillian.SignedLogRoot, error) {
+	resp, err := t.client.GetLatestSignedLogRoot(t.ctx,
+		&trillian.GetLatestSignedLogRootRequest{LogId: treeID})
+	if err != nil {
+		return nil, err
+	}
+	return resp.SignedLogRoot, nil
+}
+
+func (t *trillianLog) inclusionProof(treeID, leafIndex, treeSize int64) (*trillian.Proof, error) {
+	resp, err := t.client.GetInclusionProof(t.ctx,
+		&trillian.GetInclusionProofRequest{
+			LogId:     treeID,
+			LeafIndex: leafIndex,
+			TreeSize:  treeSize,
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Proof, nil
+}
+
+func (t *trillianLog) inclusionProofByHash(treeID int64, leafHash []byte, treeSize int64) (*trillian.Proof, error) {
+	resp, err := t.client.GetInclusionProofByHash(t.ctx,
+		&trillian.GetInclusionProofByHashRequest{
+			LogId:    treeID,
+			LeafHash: leafHash,
+			TreeSize: treeSize,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Proof) != 1 {
+		return nil, fmt.Errorf("invalid number of proofs for leaf %x: "+
+			"got %v, want 1", leafHash, len(resp.Proof))
+	}
+	return resp.Proof[0], nil
+}
+
+func (t *trillianLog) consistencyProof(treeID, first, second int64) (*trillian.Proof, error) {
+	resp, err := t.client.GetConsistencyProof(t.ctx,
+		&trillian.GetConsistencyProofRequest{
+			LogId:          treeID,
+			FirstTreeSize:  first,
+			SecondTreeSize: second,
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Proof, nil
+}
+
+func (t *trillianLog) leavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error) {
+	resp, err := t.client.GetLeavesByRange(t.ctx,
+		&trillian.GetLeavesByRangeRequest{
+			LogId:      treeID,
+			StartIndex: startIndex,
+			Count:      count,
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Leaves, nil
+}
+
+func (t *trillianLog) leavesByHash(treeID int64, leafHashes [][]byte) ([]*trillian.LogLeaf, error) {
+	resp, err := t.client.GetLeavesByHash(t.ctx,
+		&trillian.GetLeavesByHashRequest{
+			LogId:    treeID,
+			LeafHash: leafHashes,
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Leaves, nil
+}
+
+func (t *trillianLog) close() {
+	t.grpc.Close()
+}
+
+// trillianLogNew connects to the trillian log server at host. Trees are
+// created with the provided signing key.
+func trillianLogNew(host string, signingKey *keyspb.PrivateKey) (*trillianLog, error) {
+	g, err := grpc.Dial(host, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	return &trillianLog{
+		grpc:       g,
+		client:     trillian.NewTrillianLogClient(g),
+		admin:      trillian.NewTrillianAdminClient(g),
+		ctx:        context.Background(),
+		signingKey: signingKey,
+	}, nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"crypto"
 	"crypto/elliptic"
 	"crypto/x509"
@@ -14,6 +13,7 @@ import (
 	"net/http/httputil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime/debug"
 	"strings"
 	"sync"
@@ -23,21 +23,17 @@ import (
 	v1 "github.com/decred/politeia/tlog/api/v1"
 	"github.com/decred/politeia/util"
 	"github.com/decred/politeia/util/version"
-	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"github.com/google/trillian/client"
 	tcrypto "github.com/google/trillian/crypto"
 	"github.com/google/trillian/crypto/keys"
 	"github.com/google/trillian/crypto/keys/der"
 	"github.com/google/trillian/crypto/keyspb"
-	"github.com/google/trillian/crypto/sigpb"
 	_ "github.com/google/trillian/merkle/rfc6962"
 	"github.com/google/trillian/types"
 	"github.com/gorilla/mux"
 	"github.com/robfig/cron"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type tserver struct {
@@ -58,9 +54,7 @@ type tserver struct {
 
 	cfg    *config
 	router *mux.Router
-	client trillian.TrillianLogClient
-	admin  trillian.TrillianAdminClient
-	ctx    context.Context
+	tlog   trillianClient // Trillian or embedded log
 
 	signingKey    *keyspb.PrivateKey // trillian signing key
 	publicKeyDER  []byte             // DER encoded public key
@@ -175,9 +169,7 @@ func (t *tserver) addRoute(method string, route string, handler http.HandlerFunc
 
 func (t *tserver) getTree(treeId int64) (*trillian.Tree, error) {
 	// Verify tree exists
-	tree, err := t.admin.GetTree(t.ctx, &trillian.GetTreeRequest{
-		TreeId: treeId,
-	})
+	tree, err := t.tlog.tree(treeId)
 	if err != nil {
 		return nil, err
 	}
@@ -191,21 +183,20 @@ func (t *tserver) getTree(treeId int64) (*trillian.Tree, error) {
 
 func (t *tserver) list(w http.ResponseWriter, r *http.Request) {
 	// Ignore structure since it is empty
-	ltr, err := t.admin.ListTrees(t.ctx, &trillian.ListTreesRequest{})
+	trees, err := t.tlog.treesAll()
 	if err != nil {
 		RespondWithError(w, r, 0, "list: %v", err)
 		return
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, v1.ListReply{Trees: ltr.Tree})
+	util.RespondWithJSON(w, http.StatusOK, v1.ListReply{Trees: trees})
 }
 
 // getLatestSignedLogRoot retrieves the latest signed root and verifies the
 // signatures.
 func (t *tserver) getLatestSignedLogRoot(tree *trillian.Tree) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
 	// get latest signed root
-	resp, err := t.client.GetLatestSignedLogRoot(t.ctx,
-		&trillian.GetLatestSignedLogRootRequest{LogId: tree.TreeId})
+	slr, err := t.tlog.signedLogRoot(tree.TreeId)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -216,62 +207,19 @@ func (t *tserver) getLatestSignedLogRoot(tree *trillian.Tree) (*trillian.SignedL
 		return nil, nil, err
 	}
 	lrv1, err := tcrypto.VerifySignedLogRoot(verifier.PubKey,
-		crypto.SHA256, resp.SignedLogRoot)
+		crypto.SHA256, slr)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return resp.SignedLogRoot, lrv1, nil
+	return slr, lrv1, nil
 }
 
-// createTree creates a new trillian tree and verifies that the signatures are
-// correct. It returns the tree and the signed log root which can be externally
+// createTree creates a new tree and verifies that the signatures are correct.
+// It returns the tree and the signed log root which can be externally
 // verified.
 func (t *tserver) createTree() (*trillian.Tree, *trillian.SignedLogRoot, error) {
-	k, err := ptypes.MarshalAny(t.signingKey)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Create new trillian tree
-	tree, err := t.admin.CreateTree(t.ctx, &trillian.CreateTreeRequest{
-		Tree: &trillian.Tree{
-			TreeState:          trillian.TreeState_ACTIVE,
-			TreeType:           trillian.TreeType_LOG,
-			HashStrategy:       trillian.HashStrategy_RFC6962_SHA256,
-			HashAlgorithm:      sigpb.DigitallySigned_SHA256,
-			SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
-			//SignatureAlgorithm: sigpb.DigitallySigned_ED25519,
-			DisplayName:     "",
-			Description:     "",
-			MaxRootDuration: ptypes.DurationProto(0),
-			PrivateKey:      k,
-		},
-	})
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Init tree or signer goes bananas
-	ilr, err := t.client.InitLog(t.ctx, &trillian.InitLogRequest{
-		LogId: tree.TreeId,
-	})
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Check trillian errors
-	switch code := status.Code(err); code {
-	case codes.Unavailable:
-		err = fmt.Errorf("log server unavailable: %v", err)
-	case codes.AlreadyExists:
-		err = fmt.Errorf("just-created Log (%v) is already initialised: %v",
-			tree.TreeId, err)
-	case codes.OK:
-		log.Debugf("Initialised Log: %v", tree.TreeId)
-	default:
-		err = fmt.Errorf("failed to InitLog (unknown error)")
-	}
+	tree, root, err := t.tlog.treeNew()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -282,40 +230,18 @@ func (t *tserver) createTree() (*trillian.Tree, *trillian.SignedLogRoot, error)
 		return nil, nil, err
 	}
 	_, err = tcrypto.VerifySignedLogRoot(verifier.PubKey,
-		crypto.SHA256, ilr.Created)
+		crypto.SHA256, root)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return tree, ilr.Created, nil
+	return tree, root, nil
 }
 
-// waitForRootUpdate waits until the trillian root is updated. This code is
-// clunky because we need a trillian.client context which we have to construct
-// from known information. We probably should create our own client structure
-// that does this with a saner API.
-func (t *tserver) waitForRootUpdate(tree *trillian.Tree, root *trillian.SignedLogRoot) error {
-	// Wait for update
-	var logRoot types.LogRootV1
-	err := logRoot.UnmarshalBinary(root.LogRoot)
-	if err != nil {
-		return err
-	}
-	c, err := client.NewFromTree(t.client, tree, logRoot)
-	if err != nil {
-		return err
-	}
-	_, err = c.WaitForRootUpdate(t.ctx)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (t *tserver) countErrors(qlr *trillian.QueueLeavesResponse) int {
+func (t *tserver) countErrors(qll []*trillian.QueuedLogLeaf) int {
 	var n int
-	for k := range qlr.QueuedLeaves {
-		c := codes.Code(qlr.QueuedLeaves[k].GetStatus().GetCode())
+	for k := range qll {
+		c := codes.Code(qll[k].GetStatus().GetCode())
 		if c != codes.OK {
 			n++
 		}
@@ -359,12 +285,8 @@ func (t *tserver) getEntry(id int64, leaf *trillian.LogLeaf) (re v1.RecordEntryP
 	}
 
 	// Retrieve inclusion proof for anchored LogRoot
-	gipr, err := t.client.GetInclusionProof(t.ctx,
-		&trillian.GetInclusionProofRequest{
-			LogId:     id,
-			LeafIndex: leaf.LeafIndex,
-			TreeSize:  int64(lrv1.TreeSize), // tree size when anchor was dropped
-		})
+	proof, err := t.tlog.inclusionProof(id, leaf.LeafIndex,
+		int64(lrv1.TreeSize)) // tree size when anchor was dropped
 	if err != nil {
 		re.Error = fmt.Sprintf("GetInclusionProof: %v", err)
 		return
@@ -374,7 +296,7 @@ func (t *tserver) getEntry(id int64, leaf *trillian.LogLeaf) (re v1.RecordEntryP
 	re.RecordEntry = entry
 	re.Leaf = leaf
 	re.STH = &da.STH
-	re.Proof = gipr.Proof
+	re.Proof = proof
 	re.Anchor = &da.VerifyDigest.ChainInformation
 
 	return
@@ -385,18 +307,14 @@ func (t *tserver) getEntries(id int64, leafHashes [][]byte) ([]v1.RecordEntryPro
 	log.Tracef("getEntries: %v %x", id, leafHashes)
 
 	// Retrieve leaves
-	glbhr, err := t.client.GetLeavesByHash(t.ctx,
-		&trillian.GetLeavesByHashRequest{
-			LogId:    id,
-			LeafHash: leafHashes,
-		})
+	leaves, err := t.tlog.leavesByHash(id, leafHashes)
 	if err != nil {
-		return nil, fmt.Errorf("GetLeavesByHashRequest: %v", err)
+		return nil, fmt.Errorf("leavesByHash: %v", err)
 	}
 
 	// Retrieve record entry proofs
-	rep := make([]v1.RecordEntryProof, 0, len(glbhr.Leaves))
-	for _, v := range glbhr.Leaves {
+	rep := make([]v1.RecordEntryProof, 0, len(leaves))
+	for _, v := range leaves {
 		rep = append(rep, t.getEntry(id, v))
 	}
 
@@ -421,7 +339,7 @@ func (t *tserver) unwindBlobs(blobIDs map[string]struct{}) {
 	}
 }
 
-// appendRecord stores pointers to record entries into trillian and data into a
+// appendRecord stores pointers to record entries into the log and data into a
 // backend.
 func (t *tserver) appendRecord(tree *trillian.Tree, root *trillian.SignedLogRoot, re []v1.RecordEntry) ([]v1.QueuedLeafProof, *trillian.SignedLogRoot, error) {
 	ll := make([]*trillian.LogLeaf, 0, len(re))
@@ -454,23 +372,20 @@ func (t *tserver) appendRecord(tree *trillian.Tree, root *trillian.SignedLogRoot
 	log.Debugf("Stored record entries: %v %v", len(ll), tree.TreeId)
 
 	// Store all records as leafs
-	qlr, err := t.client.QueueLeaves(t.ctx, &trillian.QueueLeavesRequest{
-		LogId:  tree.TreeId,
-		Leaves: ll,
-	})
+	queued, err := t.tlog.leavesAppend(tree, root, ll)
 	if err != nil {
 		t.unwindBlobs(blobIDs)
-		return nil, nil, fmt.Errorf("QueueLeaves: %v", err)
+		return nil, nil, fmt.Errorf("leavesAppend: %v", err)
 	}
 
-	// Count errors to see if we need to wait
-	n := t.countErrors(qlr)
+	// Count errors
+	n := t.countErrors(queued)
 	log.Debugf("Stored/Ignored leaves: %v/%v %v", len(ll)-n, n, tree.TreeId)
 
 	// Unwind blobs of ignored leaves
 	if n != 0 {
 		ignored := make(map[string]struct{})
-		for _, v := range qlr.QueuedLeaves {
+		for _, v := range queued {
 			c := codes.Code(v.GetStatus().GetCode())
 			if c != codes.OK {
 				// Blob ID is stored in Leaf.ExtraData
@@ -481,17 +396,6 @@ func (t *tserver) appendRecord(tree *trillian.Tree, root *trillian.SignedLogRoot
 		t.unwindBlobs(ignored)
 	}
 
-	// Only wait if we actually updated the tree
-	if len(ll)-n != 0 {
-		// Wait for update
-		log.Debugf("Waiting for update: %v", tree.TreeId)
-		err = t.waitForRootUpdate(tree, root)
-		if err != nil {
-			t.unwindBlobs(blobIDs)
-			return nil, nil, fmt.Errorf("waitForRootUpdate: %v", err)
-		}
-	}
-
 	// Get latest signed tree head
 	sth, lrv1, err := t.getLatestSignedLogRoot(tree)
 	if err != nil {
@@ -500,8 +404,8 @@ func (t *tserver) appendRecord(tree *trillian.Tree, root *trillian.SignedLogRoot
 	}
 
 	// Get inclusion proofs
-	proofs := make([]v1.QueuedLeafProof, 0, len(qlr.QueuedLeaves))
-	for _, v := range qlr.QueuedLeaves {
+	proofs := make([]v1.QueuedLeafProof, 0, len(queued))
+	for _, v := range queued {
 		qllp := v1.QueuedLeafProof{
 			QueuedLeaf: *v,
 		}
@@ -509,23 +413,14 @@ func (t *tserver) appendRecord(tree *trillian.Tree, root *trillian.SignedLogRoot
 		if c == codes.OK {
 			// LeafIndex of a QueuedLogLeaf will not be set so
 			// get the inclusion proof by hash.
-			resp, err := t.client.GetInclusionProofByHash(t.ctx,
-				&trillian.GetInclusionProofByHashRequest{
-					LogId:    tree.TreeId,
-					LeafHash: v.Leaf.MerkleLeafHash,
-					TreeSize: int64(lrv1.TreeSize),
-				})
+			proof, err := t.tlog.inclusionProofByHash(tree.TreeId,
+				v.Leaf.MerkleLeafHash, int64(lrv1.TreeSize))
 			if err != nil {
 				t.unwindBlobs(blobIDs)
-				return nil, nil, fmt.Errorf("GetInclusionProof: %v", err)
+				return nil, nil, fmt.Errorf("inclusionProofByHash: %v",
+					err)
 			}
-			if len(resp.Proof) != 1 {
-				t.unwindBlobs(blobIDs)
-				return nil, nil, fmt.Errorf("invalid number of proofs "+
-					"for leaf %v: got %v, want 1", v.Leaf.MerkleLeafHash,
-					len(resp.Proof))
-			}
-			qllp.Proof = resp.Proof[0]
+			qllp.Proof = proof
 		}
 		proofs = append(proofs, qllp)
 	}
@@ -684,7 +579,7 @@ func (t *tserver) recordAppend(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// recordGet returns the entire trillian tree and corresponding data.
+// recordGet returns the entire tree and corresponding data.
 func (t *tserver) recordGet(w http.ResponseWriter, r *http.Request) {
 	// Decode incoming record
 	var rg v1.RecordGet
@@ -718,14 +613,10 @@ func (t *tserver) recordGet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get leaves
-	glbrr, err := t.client.GetLeavesByRange(t.ctx,
-		&trillian.GetLeavesByRangeRequest{
-			LogId:      tree.TreeId,
-			StartIndex: 0,
-			Count:      int64(lrv1.TreeSize),
-		})
+	leaves, err := t.tlog.leavesByRange(tree.TreeId, 0,
+		int64(lrv1.TreeSize))
 	if err != nil {
-		e := fmt.Sprintf("GetLeavesByRange: %v", err)
+		e := fmt.Sprintf("leavesByRange: %v", err)
 		RespondWithError(w, r, 0, "recordGet: getTree",
 			v1.UserError{
 				ErrorCode:    v1.ErrorStatusInvalidInput,
@@ -735,8 +626,8 @@ func (t *tserver) recordGet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Retrieve record entry proofs
-	rep := make([]v1.RecordEntryProof, 0, len(glbrr.Leaves))
-	for _, v := range glbrr.Leaves {
+	rep := make([]v1.RecordEntryProof, 0, len(leaves))
+	for _, v := range leaves {
 		rep = append(rep, t.getEntry(tree.TreeId, v))
 	}
 
@@ -901,14 +792,6 @@ func _main() error {
 		log.Infof("EncryptionKey Key created...")
 	}
 
-	// Connect to trillian
-	log.Infof("Trillian log server: %v", loadedCfg.TrillianHost)
-	g, err := grpc.Dial(loadedCfg.TrillianHost, grpc.WithInsecure())
-	if err != nil {
-		return err
-	}
-	defer g.Close()
-
 	// Dcrtime host
 	log.Infof("Anchor host: %v", loadedCfg.DcrtimeHost)
 
@@ -916,9 +799,6 @@ func _main() error {
 	t := &tserver{
 		cfg:        loadedCfg,
 		cron:       cron.New(),
-		client:     trillian.NewTrillianLogClient(g),
-		admin:      trillian.NewTrillianAdminClient(g),
-		ctx:        context.Background(),
 		signingKey: &keyspb.PrivateKey{},
 		dirty:      make(map[int64]int64),
 	}
@@ -957,6 +837,22 @@ func _main() error {
 		return err
 	}
 
+	// Setup log
+	if loadedCfg.EmbeddedLog {
+		path := filepath.Join(loadedCfg.HomeDir,
+			defaultEmbeddedLogDirname, netName(activeNetParams))
+		log.Infof("Embedded log: %v", path)
+		t.tlog, err = memoryLogNew(path, privKey)
+	} else {
+		log.Infof("Trillian log server: %v", loadedCfg.TrillianHost)
+		t.tlog, err = trillianLogNew(loadedCfg.TrillianHost,
+			t.signingKey)
+	}
+	if err != nil {
+		return err
+	}
+	defer t.tlog.close()
+
 	// Load encryption key
 	f, err := os.Open(loadedCfg.EncryptionKey)
 	if err != nil {
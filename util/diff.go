@@ -0,0 +1,231 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffOpT is the operation of a single line in a line diff.
+type DiffOpT int
+
+const (
+	DiffOpEqual  DiffOpT = 0 // Line is present in both inputs
+	DiffOpDelete DiffOpT = 1 // Line is only present in the first input
+	DiffOpInsert DiffOpT = 2 // Line is only present in the second input
+
+	// diffContext is the number of unchanged lines that surround a change
+	// in a unified diff.
+	diffContext = 3
+)
+
+// DiffLine is a single line of a line diff.
+type DiffLine struct {
+	Op   DiffOpT // Line operation
+	Text string  // Line content without the line ending
+}
+
+// splitLines splits b into lines. A trailing line ending does not result in
+// an additional empty line.
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return []string{}
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.Split(s, "\n")
+}
+
+// myersDiff returns the shortest edit script that turns a into b using the
+// algorithm described in "An O(ND) Difference Algorithm and Its Variations"
+// by Eugene W. Myers.
+func myersDiff(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return []DiffLine{}
+	}
+
+	// Find the furthest reaching path for each number of edits d and
+	// remember the state before every round so that the path can be
+	// walked back.
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := make([][]int, 0, 16)
+found:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break found
+			}
+		}
+	}
+
+	// Walk the path backwards
+	lines := make([]DiffLine, 0, max)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			lines = append(lines, DiffLine{Op: DiffOpEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, DiffLine{
+					Op:   DiffOpInsert,
+					Text: b[y-1],
+				})
+			} else {
+				lines = append(lines, DiffLine{
+					Op:   DiffOpDelete,
+					Text: a[x-1],
+				})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// Reverse
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
+
+// DiffLines returns a minimal line diff that turns a into b.
+func DiffLines(a, b []string) []DiffLine {
+	// Strip the common prefix and suffix to keep the edit graph small.
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	var suffix int
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for _, v := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffOpEqual, Text: v})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix],
+		b[prefix:len(b)-suffix])...)
+	for _, v := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffOpEqual, Text: v})
+	}
+
+	return lines
+}
+
+// UnifiedDiff returns the unified diff that turns from into to. An empty
+// string is returned when both inputs contain the same lines.
+func UnifiedDiff(fromName, toName string, from, to []byte) string {
+	lines := DiffLines(splitLines(from), splitLines(to))
+
+	// Find the ranges of lines that make up the hunks.
+	type hunk struct {
+		start, end int // Range in lines
+	}
+	hunks := make([]hunk, 0, 8)
+	for i, v := range lines {
+		if v.Op == DiffOpEqual {
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i + diffContext + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1].end {
+			hunks[len(hunks)-1].end = end
+			continue
+		}
+		hunks = append(hunks, hunk{start: start, end: end})
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %v\n+++ %v\n", fromName, toName)
+	var fromLine, toLine, next int // Lines seen before the hunk
+	for _, h := range hunks {
+		for ; next < h.start; next++ {
+			fromLine++
+			toLine++
+		}
+		var fromCount, toCount int
+		for _, v := range lines[h.start:h.end] {
+			switch v.Op {
+			case DiffOpEqual:
+				fromCount++
+				toCount++
+			case DiffOpDelete:
+				fromCount++
+			case DiffOpInsert:
+				toCount++
+			}
+		}
+
+		// An empty range refers to the line before the hunk.
+		fromStart, toStart := fromLine+1, toLine+1
+		if fromCount == 0 {
+			fromStart = fromLine
+		}
+		if toCount == 0 {
+			toStart = toLine
+		}
+		fmt.Fprintf(&b, "@@ -%v,%v +%v,%v @@\n", fromStart, fromCount,
+			toStart, toCount)
+		for _, v := range lines[h.start:h.end] {
+			switch v.Op {
+			case DiffOpEqual:
+				b.WriteString(" ")
+			case DiffOpDelete:
+				b.WriteString("-")
+			case DiffOpInsert:
+				b.WriteString("+")
+			}
+			b.WriteString(v.Text)
+			b.WriteString("\n")
+		}
+
+		fromLine += fromCount
+		toLine += toCount
+		next = h.end
+	}
+
+	return b.String()
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = strconv.Itoa(r.Intn(5))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a := randomLines()
+		b := randomLines()
+		lines := DiffLines(a, b)
+
+		// The diff must reproduce both inputs.
+		gotA := make([]string, 0, len(a))
+		gotB := make([]string, 0, len(b))
+		var equal int
+		for _, v := range lines {
+			switch v.Op {
+			case DiffOpEqual:
+				gotA = append(gotA, v.Text)
+				gotB = append(gotB, v.Text)
+				equal++
+			case DiffOpDelete:
+				gotA = append(gotA, v.Text)
+			case DiffOpInsert:
+				gotB = append(gotB, v.Text)
+			}
+		}
+		if !reflect.DeepEqual(gotA, a) || !reflect.DeepEqual(gotB, b) {
+			t.Fatalf("diff does not reproduce input: %v %v", a, b)
+		}
+
+		// The diff must be minimal.
+		if want := lcsLength(a, b); equal != want {
+			t.Fatalf("diff not minimal got %v want %v: %v %v",
+				equal, want, a, b)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n")
+	to := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+	want := "--- 1/file\n" +
+		"+++ 2/file\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" a\n" +
+		"-b\n" +
+		"+B\n" +
+		" c\n" +
+		" d\n" +
+		" e\n" +
+		"@@ -9,3 +9,4 @@\n" +
+		" i\n" +
+		" j\n" +
+		" k\n" +
+		"+l\n"
+	got := UnifiedDiff("1/file", "2/file", from, to)
+	if got != want {
+		t.Fatalf("unexpected diff got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// Added file
+	want = "--- /dev/null\n" +
+		"+++ 2/file\n" +
+		"@@ -0,0 +1,2 @@\n" +
+		"+a\n" +
+		"+b\n"
+	got = UnifiedDiff("/dev/null", "2/file", nil, []byte("a\nb\n"))
+	if got != want {
+		t.Fatalf("unexpected diff got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// No changes
+	if got := UnifiedDiff("1/file", "2/file", from, from); got != "" {
+		t.Fatalf("expected empty diff got %v", got)
+	}
+}
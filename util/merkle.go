@@ -0,0 +1,246 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+)
+
+// The functions in this file implement the RFC 6962 Merkle tree hashing that
+// is used by trillian.  They allow callers that keep the leaves of a small log
+// around to calculate roots and proofs on demand instead of running a
+// separate log server.
+
+const (
+	rfc6962LeafHashPrefix = 0
+	rfc6962NodeHashPrefix = 1
+)
+
+var (
+	// ErrInvalidMerkleProof is returned when an inclusion or consistency
+	// proof does not verify.
+	ErrInvalidMerkleProof = errors.New("invalid merkle proof")
+
+	// ErrInvalidMerkleFrontier is returned when a frontier does not match
+	// the tree size.
+	ErrInvalidMerkleFrontier = errors.New("invalid merkle frontier")
+)
+
+// MerkleLeafHash returns the RFC 6962 leaf hash of the provided leaf value.
+func MerkleLeafHash(leafValue []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{rfc6962LeafHashPrefix})
+	h.Write(leafValue)
+	return h.Sum(nil)
+}
+
+// merkleNodeHash returns the RFC 6962 hash of an interior node.
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{rfc6962NodeHashPrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleSplitPoint returns the largest power of two that is smaller than n.
+func merkleSplitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// MerkleRootHash returns the Merkle tree hash of the provided leaf hashes.
+func MerkleRootHash(leafHashes [][]byte) []byte {
+	switch len(leafHashes) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leafHashes[0]
+	}
+	k := merkleSplitPoint(len(leafHashes))
+	return merkleNodeHash(MerkleRootHash(leafHashes[:k]),
+		MerkleRootHash(leafHashes[k:]))
+}
+
+// MerkleFrontierRoot returns the Merkle tree hash of the tree that is
+// described by the provided frontier.  The frontier contains the roots of the
+// perfect subtrees that make up the tree, ordered from largest to smallest.
+func MerkleFrontierRoot(frontier [][]byte) []byte {
+	if len(frontier) == 0 {
+		return MerkleRootHash(nil)
+	}
+	r := frontier[len(frontier)-1]
+	for i := len(frontier) - 2; i >= 0; i-- {
+		r = merkleNodeHash(frontier[i], r)
+	}
+	return r
+}
+
+// MerkleAppend appends a leaf hash to the tree of the provided size that is
+// described by its frontier, see MerkleFrontierRoot.  It returns the frontier
+// of the new tree and the audit path of the new leaf.  Only the hashes on the
+// path of the new leaf are calculated so the leaves of the tree are not
+// required.
+func MerkleAppend(frontier [][]byte, size int64, leafHash []byte) ([][]byte, [][]byte, error) {
+	if size < 0 || bits.OnesCount64(uint64(size)) != len(frontier) {
+		return nil, nil, ErrInvalidMerkleFrontier
+	}
+
+	// The perfect subtrees of the same size as the new subtree are its
+	// left siblings.
+	f := make([][]byte, len(frontier), len(frontier)+1)
+	copy(f, frontier)
+	proof := make([][]byte, 0, len(frontier))
+	h := leafHash
+	for s := size; s&1 == 1; s >>= 1 {
+		left := f[len(f)-1]
+		f = f[:len(f)-1]
+		proof = append(proof, left)
+		h = merkleNodeHash(left, h)
+	}
+
+	// The remaining perfect subtrees are the left siblings of the
+	// subtrees on the path to the root.
+	for i := len(f) - 1; i >= 0; i-- {
+		proof = append(proof, f[i])
+	}
+
+	return append(f, h), proof, nil
+}
+
+// MerkleInclusionProof returns the audit path of the leaf at the provided
+// index.
+func MerkleInclusionProof(index int, leafHashes [][]byte) [][]byte {
+	if len(leafHashes) <= 1 {
+		return [][]byte{}
+	}
+	k := merkleSplitPoint(len(leafHashes))
+	if index < k {
+		return append(MerkleInclusionProof(index, leafHashes[:k]),
+			MerkleRootHash(leafHashes[k:]))
+	}
+	return append(MerkleInclusionProof(index-k, leafHashes[k:]),
+		MerkleRootHash(leafHashes[:k]))
+}
+
+// merkleSubProof implements the SUBPROOF function of RFC 6962 section 2.1.2.
+func merkleSubProof(first int, leafHashes [][]byte, complete bool) [][]byte {
+	n := len(leafHashes)
+	if first == n {
+		if complete {
+			return [][]byte{}
+		}
+		return [][]byte{MerkleRootHash(leafHashes)}
+	}
+	k := merkleSplitPoint(n)
+	if first <= k {
+		return append(merkleSubProof(first, leafHashes[:k], complete),
+			MerkleRootHash(leafHashes[k:]))
+	}
+	return append(merkleSubProof(first-k, leafHashes[k:], false),
+		MerkleRootHash(leafHashes[:k]))
+}
+
+// MerkleConsistencyProof returns the proof that the tree made up of the first
+// leaves is a prefix of the tree made up of all provided leaf hashes.
+func MerkleConsistencyProof(first int, leafHashes [][]byte) [][]byte {
+	if first <= 0 || first >= len(leafHashes) {
+		return [][]byte{}
+	}
+	return merkleSubProof(first, leafHashes, true)
+}
+
+// MerkleInclusionProofVerify verifies that the provided leaf hash is included
+// at leafIndex in the tree of size treeSize with the provided root.
+func MerkleInclusionProofVerify(leafIndex, treeSize int64, proof [][]byte, root, leafHash []byte) error {
+	if leafIndex < 0 || leafIndex >= treeSize {
+		return ErrInvalidMerkleProof
+	}
+
+	fn := leafIndex
+	sn := treeSize - 1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidMerkleProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(r, root) {
+		return ErrInvalidMerkleProof
+	}
+
+	return nil
+}
+
+// MerkleConsistencyProofVerify verifies that the tree of size first with root
+// firstRoot is a prefix of the tree of size second with root secondRoot.
+func MerkleConsistencyProofVerify(first, second int64, proof [][]byte, firstRoot, secondRoot []byte) error {
+	switch {
+	case first <= 0 || first > second:
+		return ErrInvalidMerkleProof
+	case first == second:
+		if len(proof) != 0 || !bytes.Equal(firstRoot, secondRoot) {
+			return ErrInvalidMerkleProof
+		}
+		return nil
+	case len(proof) == 0:
+		return ErrInvalidMerkleProof
+	}
+
+	// A first tree that is a complete subtree is not part of the proof.
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+
+	fn := first - 1
+	sn := second - 1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr := proof[0]
+	sr := proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return ErrInvalidMerkleProof
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = merkleNodeHash(c, fr)
+			sr = merkleNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = merkleNodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(fr, firstRoot) ||
+		!bytes.Equal(sr, secondRoot) {
+		return ErrInvalidMerkleProof
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"testing"
+)
+
+func TestMerkleRootHash(t *testing.T) {
+	// Empty tree hash as defined in RFC 6962.
+	empty := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if hex.EncodeToString(MerkleRootHash(nil)) != empty {
+		t.Fatalf("invalid empty root got %x", MerkleRootHash(nil))
+	}
+
+	// A three leaf tree is hashed as ((0, 1), 2).
+	lh := [][]byte{
+		MerkleLeafHash([]byte("0")),
+		MerkleLeafHash([]byte("1")),
+		MerkleLeafHash([]byte("2")),
+	}
+	want := merkleNodeHash(merkleNodeHash(lh[0], lh[1]), lh[2])
+	if !bytes.Equal(MerkleRootHash(lh), want) {
+		t.Fatalf("invalid root got %x want %x", MerkleRootHash(lh), want)
+	}
+}
+
+func TestMerkleInclusionProof(t *testing.T) {
+	lh := make([][]byte, 0, 33)
+	for i := 0; i < 33; i++ {
+		lh = append(lh, MerkleLeafHash([]byte(strconv.Itoa(i))))
+
+		// Verify every leaf in the tree at this size.
+		size := int64(len(lh))
+		root := MerkleRootHash(lh)
+		for j := range lh {
+			proof := MerkleInclusionProof(j, lh)
+			err := MerkleInclusionProofVerify(int64(j), size, proof,
+				root, lh[j])
+			if err != nil {
+				t.Fatalf("leaf %v size %v: %v", j, size, err)
+			}
+
+			// Make sure the proof does not verify another leaf.
+			if j == 0 {
+				continue
+			}
+			err = MerkleInclusionProofVerify(int64(j), size, proof,
+				root, lh[j-1])
+			if err != ErrInvalidMerkleProof {
+				t.Fatalf("leaf %v size %v: expected invalid proof",
+					j, size)
+			}
+		}
+	}
+}
+
+func TestMerkleConsistencyProof(t *testing.T) {
+	lh := make([][]byte, 0, 33)
+	for i := 0; i < 33; i++ {
+		lh = append(lh, MerkleLeafHash([]byte(strconv.Itoa(i))))
+	}
+
+	for second := 1; second <= len(lh); second++ {
+		secondRoot := MerkleRootHash(lh[:second])
+		for first := 1; first <= second; first++ {
+			firstRoot := MerkleRootHash(lh[:first])
+			proof := MerkleConsistencyProof(first, lh[:second])
+			err := MerkleConsistencyProofVerify(int64(first),
+				int64(second), proof, firstRoot, secondRoot)
+			if err != nil {
+				t.Fatalf("first %v second %v: %v", first, second,
+					err)
+			}
+
+			// Make sure the proof does not verify another root.
+			if first == second {
+				continue
+			}
+			err = MerkleConsistencyProofVerify(int64(first),
+				int64(second), proof, lh[first], secondRoot)
+			if err != ErrInvalidMerkleProof {
+				t.Fatalf("first %v second %v: expected invalid "+
+					"proof", first, second)
+			}
+		}
+	}
+}
+
+func TestMerkleAppend(t *testing.T) {
+	var (
+		frontier [][]byte
+		err      error
+	)
+	lh := make([][]byte, 0, 33)
+	for i := 0; i < 33; i++ {
+		leaf := MerkleLeafHash([]byte(strconv.Itoa(i)))
+		size := int64(len(lh))
+
+		var proof [][]byte
+		frontier, proof, err = MerkleAppend(frontier, size, leaf)
+		if err != nil {
+			t.Fatalf("size %v: %v", size, err)
+		}
+		lh = append(lh, leaf)
+
+		// The frontier and the proof must match the full tree.
+		root := MerkleRootHash(lh)
+		if !bytes.Equal(MerkleFrontierRoot(frontier), root) {
+			t.Fatalf("size %v: invalid root got %x want %x", size+1,
+				MerkleFrontierRoot(frontier), root)
+		}
+		want := MerkleInclusionProof(int(size), lh)
+		if len(proof) != len(want) {
+			t.Fatalf("size %v: got proof length %v want %v", size+1,
+				len(proof), len(want))
+		}
+		for k := range proof {
+			if !bytes.Equal(proof[k], want[k]) {
+				t.Fatalf("size %v: invalid proof hash %v", size+1, k)
+			}
+		}
+		err = MerkleInclusionProofVerify(size, size+1, proof, root, leaf)
+		if err != nil {
+			t.Fatalf("size %v: %v", size+1, err)
+		}
+	}
+
+	// The frontier must match the tree size.
+	_, _, err = MerkleAppend(frontier, 0, lh[0])
+	if err != ErrInvalidMerkleFrontier {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidMerkleFrontier)
+	}
+}